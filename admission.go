@@ -0,0 +1,285 @@
+package operator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrOverloaded is returned, wrapped with ErrUnavailable, when an invocation
+// is rejected by the hub's admission control; see WithAdmissionControl().
+var ErrOverloaded = errors.New("hub overloaded")
+
+// PriorityClass determines the order in which invocations waiting for
+// admission are admitted; see WithAdmissionControl(). Invocations are
+// assigned a class with WithPriorityClass(), and are NormalPriority by
+// default.
+type PriorityClass int
+
+const (
+	// Background work, such as imports and batch jobs, that may be delayed
+	// in favour of other invocations.
+	BatchPriority PriorityClass = iota
+
+	// The default class.
+	NormalPriority
+
+	// Work a user is waiting for, such as API requests.
+	InteractivePriority
+
+	numPriorityClasses = int(InteractivePriority) + 1
+)
+
+func (c PriorityClass) String() string {
+	switch c {
+	case BatchPriority:
+		return "batch"
+	case NormalPriority:
+		return "normal"
+	case InteractivePriority:
+		return "interactive"
+	default:
+		return fmt.Sprintf("PriorityClass(%d)", int(c))
+	}
+}
+
+type priorityClassKey struct{}
+
+// WithPriorityClass() returns a copy of ctx in which operations, and any
+// operations they invoke in turn, are admitted as class c.
+func WithPriorityClass(ctx context.Context, c PriorityClass) context.Context {
+	return context.WithValue(ctx, priorityClassKey{}, c)
+}
+
+// PriorityClassFrom() returns the priority class with which operations
+// invoked with ctx are admitted.
+func PriorityClassFrom(ctx context.Context) PriorityClass {
+	if c, ok := ctx.Value(priorityClassKey{}).(PriorityClass); ok && c >= 0 && int(c) < numPriorityClasses {
+		return c
+	}
+	return NormalPriority
+}
+
+// AdmissionControl configures the hub's admission queue; see
+// WithAdmissionControl().
+type AdmissionControl struct {
+	// Maximum number of invocations running at once, typically somewhat
+	// less than the size of the database connection pool
+	MaxConcurrent int
+
+	// Maximum number of invocations of each class running at once; classes
+	// without a limit may use all MaxConcurrent. Limiting BatchPriority
+	// reserves capacity for other classes however much batch work is
+	// queued.
+	ClassLimits map[PriorityClass]int
+
+	// Maximum number of invocations, of all classes, waiting for admission;
+	// further invocations are rejected. If zero, invocations are rejected as
+	// soon as MaxConcurrent are running.
+	MaxQueue int
+
+	// Maximum time an invocation may wait; if zero, invocations wait until
+	// their context is done.
+	Timeout time.Duration
+
+	// OnWait, if set, is called with the class and time waited of each
+	// invocation that had to wait for admission, and the error with which
+	// it was rejected, if any.
+	OnWait func(c PriorityClass, waited time.Duration, err error)
+}
+
+// WithAdmissionControl() places a queue in front of every operation invoked
+// on the hub, limiting the number running at once so that they do not
+// exhaust resources - such as a database connection pool - shared by every
+// operation. Invocations beyond the limit wait, before their transaction
+// begins, and are admitted as running invocations finish: those of higher
+// priority classes first, and in arrival order within a class. Invocations
+// that cannot wait are rejected with an error wrapping ErrUnavailable and
+// ErrOverloaded.
+//
+// Follow-up operations, and those invoked by other operations, are admitted
+// like any other, so operations that invoke others synchronously need
+// sufficient MaxConcurrent to avoid waiting on themselves. Queue depths and
+// wait times are available from Hub.AdmissionStats().
+func WithAdmissionControl(ac AdmissionControl) HubOption {
+	return func(o *hubOptions) { o.admission = &ac }
+}
+
+// AdmissionStats describes the admission of one priority class of
+// invocations; see Hub.AdmissionStats().
+type AdmissionStats struct {
+	Class PriorityClass `json:"class"`
+
+	// Number of invocations currently running and waiting
+	Running int `json:"running"`
+	Waiting int `json:"waiting"`
+
+	// Number of invocations admitted and rejected since the hub was created
+	Admitted int64 `json:"admitted"`
+	Rejected int64 `json:"rejected"`
+
+	// Total and longest time waited by invocations of the class
+	TotalWait time.Duration `json:"total_wait"`
+	MaxWait   time.Duration `json:"max_wait"`
+}
+
+// AdmissionStats() returns statistics for each priority class, from
+// highest to lowest priority, or nil if the hub has no admission control.
+func (h *Hub[Tx]) AdmissionStats() []AdmissionStats {
+	if h.admission == nil {
+		return nil
+	}
+	return h.admission.stats()
+}
+
+type admissionQueue struct {
+	config AdmissionControl
+
+	mu      sync.Mutex
+	running int
+	waiting int
+	classes [numPriorityClasses]admissionClass
+}
+
+type admissionClass struct {
+	limit   int
+	waiters []*admissionWaiter
+	stats   AdmissionStats
+}
+
+type admissionWaiter struct {
+	ready    chan struct{}
+	admitted bool
+}
+
+func newAdmissionQueue(ac *AdmissionControl) *admissionQueue {
+	q := &admissionQueue{config: *ac}
+	for i := range q.classes {
+		c := &q.classes[i]
+		c.stats.Class = PriorityClass(i)
+		c.limit = ac.MaxConcurrent
+		if limit, ok := ac.ClassLimits[PriorityClass(i)]; ok && limit < c.limit {
+			c.limit = limit
+		}
+	}
+	return q
+}
+
+// acquire waits for admission, returning a function that must be called
+// once the invocation has finished.
+func (q *admissionQueue) acquire(ctx context.Context, name string) (func(), error) {
+	class := PriorityClassFrom(ctx)
+	c := &q.classes[class]
+	release := func() { q.release(class) }
+
+	q.mu.Lock()
+	if q.running < q.config.MaxConcurrent && c.stats.Running < c.limit {
+		q.admit(c)
+		q.mu.Unlock()
+		return release, nil
+	}
+	if q.waiting >= q.config.MaxQueue {
+		c.stats.Rejected++
+		q.mu.Unlock()
+		return nil, q.reject(class, name, 0, errors.New("queue full"))
+	}
+	w := &admissionWaiter{ready: make(chan struct{})}
+	c.waiters = append(c.waiters, w)
+	q.waiting++
+	c.stats.Waiting++
+	q.mu.Unlock()
+
+	start := time.Now()
+	var timeout <-chan time.Time
+	if q.config.Timeout > 0 {
+		t := time.NewTimer(q.config.Timeout)
+		defer t.Stop()
+		timeout = t.C
+	}
+
+	var err error
+	select {
+	case <-w.ready:
+	case <-timeout:
+		err = errors.New("timed out waiting")
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	waited := time.Since(start)
+
+	q.mu.Lock()
+	if err != nil && w.admitted {
+		// admitted as we gave up; accept rather than waste the slot
+		err = nil
+	}
+	c.stats.TotalWait += waited
+	c.stats.MaxWait = max(c.stats.MaxWait, waited)
+	if err != nil {
+		q.dequeue(c, w)
+		c.stats.Rejected++
+	}
+	q.mu.Unlock()
+
+	if err != nil {
+		return nil, q.reject(class, name, waited, err)
+	}
+	if q.config.OnWait != nil {
+		q.config.OnWait(class, waited, nil)
+	}
+	return release, nil
+}
+
+func (q *admissionQueue) reject(class PriorityClass, name string, waited time.Duration, err error) error {
+	if q.config.OnWait != nil {
+		q.config.OnWait(class, waited, err)
+	}
+	return fmt.Errorf("%w: %w: operation %s (%w)", ErrUnavailable, ErrOverloaded, name, err)
+}
+
+func (q *admissionQueue) admit(c *admissionClass) {
+	q.running++
+	c.stats.Running++
+	c.stats.Admitted++
+}
+
+func (q *admissionQueue) dequeue(c *admissionClass, w *admissionWaiter) {
+	for i, cw := range c.waiters {
+		if cw == w {
+			c.waiters = append(c.waiters[:i], c.waiters[i+1:]...)
+			q.waiting--
+			c.stats.Waiting--
+			return
+		}
+	}
+}
+
+func (q *admissionQueue) release(class PriorityClass) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.running--
+	q.classes[class].stats.Running--
+
+	// admit waiters, highest class first, while there is capacity
+	for i := len(q.classes) - 1; i >= 0 && q.running < q.config.MaxConcurrent; i-- {
+		c := &q.classes[i]
+		for len(c.waiters) > 0 && c.stats.Running < c.limit && q.running < q.config.MaxConcurrent {
+			w := c.waiters[0]
+			q.dequeue(c, w)
+			q.admit(c)
+			w.admitted = true
+			close(w.ready)
+		}
+	}
+}
+
+func (q *admissionQueue) stats() []AdmissionStats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	out := make([]AdmissionStats, 0, len(q.classes))
+	for i := len(q.classes) - 1; i >= 0; i-- {
+		out = append(out, q.classes[i].stats)
+	}
+	return out
+}
@@ -0,0 +1,163 @@
+package operator
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type admissionInput struct {
+	Name    string
+	Unblock chan struct{}
+}
+
+func waiting(hub *Hub[*TxTest]) int {
+	n := 0
+	for _, s := range hub.AdmissionStats() {
+		n += s.Waiting
+	}
+	return n
+}
+
+func TestAdmissionControl_Priority(t *testing.T) {
+	var waits []PriorityClass
+	hub := newTestHub(WithAdmissionControl(AdmissionControl{
+		MaxConcurrent: 1,
+		MaxQueue:      10,
+		OnWait:        func(c PriorityClass, _ time.Duration, err error) { waits = append(waits, c) },
+	}))
+
+	var order []string
+	op := func(ctx *OpContext[*TxTest], in *admissionInput) (*struct{}, error) {
+		order = append(order, in.Name)
+		if in.Unblock != nil {
+			<-in.Unblock
+		}
+		return &struct{}{}, nil
+	}
+
+	unblock := make(chan struct{})
+	var wg sync.WaitGroup
+	invoke := func(c PriorityClass, in *admissionInput) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := Invoke(WithPriorityClass(context.Background(), c), hub, op, in)
+			assert.NoError(t, err)
+		}()
+	}
+
+	invoke(BatchPriority, &admissionInput{Name: "running", Unblock: unblock})
+	assert.Eventually(t, func() bool { return hub.AdmissionStats()[2].Running == 1 }, time.Second, time.Millisecond)
+	for i, c := range []PriorityClass{BatchPriority, NormalPriority, InteractivePriority} {
+		invoke(c, &admissionInput{Name: c.String()})
+		assert.Eventually(t, func() bool { return waiting(hub) == i+1 }, time.Second, time.Millisecond)
+	}
+
+	close(unblock)
+	wg.Wait()
+	assert.Equal(t, []string{"running", "interactive", "normal", "batch"}, order)
+	assert.Equal(t, []PriorityClass{InteractivePriority, NormalPriority, BatchPriority}, waits)
+
+	stats := hub.AdmissionStats()
+	assert.Equal(t, []PriorityClass{InteractivePriority, NormalPriority, BatchPriority},
+		[]PriorityClass{stats[0].Class, stats[1].Class, stats[2].Class})
+	assert.Equal(t, int64(2), stats[2].Admitted)
+	assert.Equal(t, int64(1), stats[0].Admitted)
+	assert.Greater(t, stats[2].MaxWait, time.Duration(0))
+	assert.GreaterOrEqual(t, stats[2].TotalWait, stats[2].MaxWait)
+	for _, s := range stats {
+		assert.Zero(t, s.Running)
+		assert.Zero(t, s.Waiting)
+	}
+}
+
+func TestAdmissionControl_ClassLimits(t *testing.T) {
+	hub := newTestHub(WithAdmissionControl(AdmissionControl{
+		MaxConcurrent: 2,
+		ClassLimits:   map[PriorityClass]int{BatchPriority: 1},
+		MaxQueue:      1,
+	}))
+
+	op := func(ctx *OpContext[*TxTest], in *admissionInput) (*struct{}, error) {
+		if in.Unblock != nil {
+			<-in.Unblock
+		}
+		return &struct{}{}, nil
+	}
+	batch := WithPriorityClass(context.Background(), BatchPriority)
+
+	unblock := make(chan struct{})
+	done := make(chan error, 2)
+	go func() {
+		_, err := Invoke(batch, hub, op, &admissionInput{Unblock: unblock})
+		done <- err
+	}()
+	assert.Eventually(t, func() bool { return hub.AdmissionStats()[2].Running == 1 }, time.Second, time.Millisecond)
+
+	go func() {
+		_, err := Invoke(batch, hub, op, &admissionInput{})
+		done <- err
+	}()
+	assert.Eventually(t, func() bool { return waiting(hub) == 1 }, time.Second, time.Millisecond)
+
+	// capacity beyond the batch limit remains available to other classes
+	_, err := Invoke(context.Background(), hub, op, &admissionInput{})
+	assert.NoError(t, err)
+
+	// the queue is full
+	_, err = Invoke(batch, hub, op, &admissionInput{})
+	assert.ErrorIs(t, err, ErrUnavailable)
+	assert.ErrorIs(t, err, ErrOverloaded)
+	assert.Equal(t, int64(1), hub.AdmissionStats()[2].Rejected)
+
+	close(unblock)
+	assert.NoError(t, <-done)
+	assert.NoError(t, <-done)
+}
+
+func TestAdmissionControl_Timeout(t *testing.T) {
+	var rejected error
+	hub := newTestHub(WithAdmissionControl(AdmissionControl{
+		MaxConcurrent: 1,
+		MaxQueue:      5,
+		Timeout:       10 * time.Millisecond,
+		OnWait:        func(_ PriorityClass, _ time.Duration, err error) { rejected = err },
+	}))
+
+	unblock := make(chan struct{})
+	op := func(ctx *OpContext[*TxTest], in *admissionInput) (*struct{}, error) {
+		if in.Unblock != nil {
+			<-in.Unblock
+		}
+		return &struct{}{}, nil
+	}
+	done := make(chan struct{})
+	go func() {
+		Invoke(context.Background(), hub, op, &admissionInput{Unblock: unblock})
+		close(done)
+	}()
+	assert.Eventually(t, func() bool { return hub.AdmissionStats()[1].Running == 1 }, time.Second, time.Millisecond)
+
+	_, err := Invoke(context.Background(), hub, op, &admissionInput{})
+	assert.ErrorIs(t, err, ErrOverloaded)
+	assert.ErrorContains(t, rejected, "timed out waiting")
+	assert.Zero(t, waiting(hub))
+
+	close(unblock)
+	<-done
+}
+
+func TestAdmissionControl_Validate(t *testing.T) {
+	hub := newTestHub(WithAdmissionControl(AdmissionControl{
+		MaxQueue:    -1,
+		ClassLimits: map[PriorityClass]int{BatchPriority: 0},
+	}))
+	assert.EqualError(t, hub.Validate(), "admission control allows 0 concurrent operations, must allow at least 1\n"+
+		"admission queue length -1 is negative\n"+
+		"admission limit for batch priority is 0, must be at least 1")
+	assert.Nil(t, newTestHub().AdmissionStats())
+}
@@ -0,0 +1,225 @@
+// Package asyncop runs long-running operations in the background, so that
+// clients need not hold a connection open for the minutes an import or
+// export may take.
+//
+// A Runner invokes operations on behalf of a hub, recording each as a Job in
+// a Store. The job's status, the progress reported by the operation with
+// OpContext.ReportProgress(), and eventually its output or error are
+// available from the store while and after it runs:
+//
+//	jobs := asyncop.New(hub, asyncop.NewMemoryStore())
+//	mux.Handle("POST /imports", asyncop.Handler(jobs, users.Import,
+//		func(id string) string { return "/jobs/" + id }))
+//	mux.Handle("GET /jobs/{id}", jobs.StatusHandler("id"))
+//
+// Handler responds with 202 Accepted as soon as the job is recorded, with a
+// Location header giving the URL at which the client can poll its status.
+//
+// Jobs run with a context derived from the one passed to Start(), retaining
+// its values (principal, tenant etc.) but not its cancellation or deadline.
+// They count towards the hub's in-flight operations, so Hub.Shutdown() waits
+// for running jobs to finish. A job may instead be cancelled with
+// Runner.Cancel(), or over HTTP with CancelHandler:
+//
+//	mux.Handle("DELETE /jobs/{id}", jobs.CancelHandler("id"))
+//
+// Cancelling a job cancels its operation's context, rolling back its
+// transaction, and records the job as StatusCancelled.
+package asyncop
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/jaz303/operator"
+)
+
+var (
+	ErrNotFound = errors.New("job not found")
+
+	// ErrJobDone is returned when cancelling a job that has already finished.
+	ErrJobDone = errors.New("job already finished")
+)
+
+// Status is the state of a job.
+type Status string
+
+const (
+	StatusPending   Status = "pending"
+	StatusRunning   Status = "running"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+	StatusCancelled Status = "cancelled"
+)
+
+// Done reports whether s is a terminal status.
+func (s Status) Done() bool {
+	return s == StatusSucceeded || s == StatusFailed || s == StatusCancelled
+}
+
+// Job records an operation running, or run, in the background.
+type Job struct {
+	ID     string `json:"id"`
+	Status Status `json:"status"`
+
+	// Most recent progress reported by the operation
+	Progress operator.Progress `json:"progress"`
+
+	// Output of the operation, once it has succeeded, or its error, once it
+	// has failed or been cancelled
+	Output any    `json:"output,omitempty"`
+	Error  string `json:"error,omitempty"`
+
+	Created time.Time `json:"created"`
+	Updated time.Time `json:"updated"`
+}
+
+// Runner runs operations of a hub in the background.
+type Runner[Tx operator.Transaction] struct {
+	hub   *operator.Hub[Tx]
+	store Store
+	ids   operator.IDGenerator
+
+	// onError is called with errors recording a job's progress or outcome
+	onError func(error)
+
+	mu      sync.Mutex
+	running map[string]*tracker
+}
+
+// Option configures a Runner.
+type Option func(*options)
+
+type options struct {
+	ids     operator.IDGenerator
+	onError func(error)
+}
+
+// WithIDGenerator sets the generator of job IDs. The default is
+// operator.UUIDv7().
+func WithIDGenerator(g operator.IDGenerator) Option {
+	return func(o *options) { o.ids = g }
+}
+
+// WithErrorHandler sets a function called with errors returned by the store
+// while recording a job's progress or outcome, which would otherwise be
+// discarded.
+func WithErrorHandler(fn func(error)) Option {
+	return func(o *options) { o.onError = fn }
+}
+
+// New returns a Runner invoking operations of hub and recording their jobs
+// in store.
+func New[Tx operator.Transaction](hub *operator.Hub[Tx], store Store, opts ...Option) *Runner[Tx] {
+	o := options{ids: operator.UUIDv7(), onError: func(error) {}}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &Runner[Tx]{hub: hub, store: store, ids: o.ids, onError: o.onError, running: map[string]*tracker{}}
+}
+
+// Store returns the runner's store.
+func (r *Runner[Tx]) Store() Store { return r.store }
+
+// Start records a new job invoking op with input, starts it in the
+// background and returns it, with status StatusPending. An error is
+// returned only if the job could not be recorded, in which case op is not
+// invoked; the operation's own error is recorded in the job.
+func Start[Tx operator.Transaction, I any, O any](ctx context.Context, r *Runner[Tx], op operator.Operation[Tx, I, O], input *I) (*Job, error) {
+	now := time.Now().UTC()
+	t := &tracker{store: r.store, onError: r.onError, job: Job{
+		ID:      r.ids.NewID(),
+		Status:  StatusPending,
+		Created: now,
+		Updated: now,
+	}}
+	job := t.job
+	if err := r.store.Put(ctx, &job); err != nil {
+		return nil, err
+	}
+
+	ctx, t.cancel = context.WithCancel(operator.WithProgressSink(context.WithoutCancel(ctx), t))
+	r.mu.Lock()
+	r.running[job.ID] = t
+	r.mu.Unlock()
+
+	go func() {
+		defer func() {
+			r.mu.Lock()
+			delete(r.running, job.ID)
+			r.mu.Unlock()
+			t.cancel()
+		}()
+		t.update(ctx, func(j *Job) { j.Status = StatusRunning })
+		out, err := operator.Invoke(ctx, r.hub, op, input)
+		t.update(ctx, func(j *Job) {
+			switch {
+			case err != nil && t.cancelled:
+				j.Status, j.Error = StatusCancelled, err.Error()
+			case err != nil:
+				j.Status, j.Error = StatusFailed, err.Error()
+			default:
+				j.Status, j.Output = StatusSucceeded, out
+			}
+		})
+	}()
+
+	return &job, nil
+}
+
+// Cancel cancels the job with the given ID, which must have been started by
+// r. The job's operation is cancelled immediately, but its status only
+// becomes StatusCancelled once the operation has returned and its
+// transaction has been rolled back; an operation that succeeds despite the
+// cancellation is recorded as such. Cancel returns ErrJobDone if the job has
+// already finished, or ErrNotFound if r is not running it.
+func (r *Runner[Tx]) Cancel(ctx context.Context, id string) error {
+	r.mu.Lock()
+	t, ok := r.running[id]
+	r.mu.Unlock()
+	if ok {
+		t.mu.Lock()
+		t.cancelled = true
+		t.mu.Unlock()
+		t.cancel()
+		return nil
+	}
+
+	job, err := r.store.Get(ctx, id)
+	if err != nil {
+		return err
+	} else if job.Status.Done() {
+		return ErrJobDone
+	}
+	return ErrNotFound
+}
+
+// tracker records the progress and outcome of a job in its store.
+type tracker struct {
+	store   Store
+	onError func(error)
+
+	cancel context.CancelFunc
+
+	mu        sync.Mutex
+	job       Job
+	cancelled bool
+}
+
+func (t *tracker) ReportProgress(ctx context.Context, p operator.Progress) {
+	t.update(ctx, func(j *Job) { j.Progress = p })
+}
+
+func (t *tracker) update(ctx context.Context, fn func(j *Job)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	fn(&t.job)
+	t.job.Updated = time.Now().UTC()
+	job := t.job
+	// The job's context is done if it has been cancelled
+	if err := t.store.Put(context.WithoutCancel(ctx), &job); err != nil {
+		t.onError(err)
+	}
+}
@@ -0,0 +1,237 @@
+package asyncop
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jaz303/operator"
+	"github.com/jaz303/operator/httpbind"
+	"github.com/stretchr/testify/assert"
+)
+
+type nopTx struct{}
+
+func (nopTx) Commit(context.Context) error   { return nil }
+func (nopTx) Rollback(context.Context) error { return nil }
+
+type importInput struct {
+	Rows int  `json:"rows"`
+	Fail bool `json:"fail"`
+}
+
+type importOutput struct {
+	Imported int `json:"imported"`
+}
+
+func newTestRunner() (*Runner[nopTx], chan struct{}, func(*operator.OpContext[nopTx], *importInput) (*importOutput, error)) {
+	hub := operator.NewHub(func(context.Context) (nopTx, error) { return nopTx{}, nil })
+	proceed := make(chan struct{})
+	op := func(ctx *operator.OpContext[nopTx], in *importInput) (*importOutput, error) {
+		ctx.ReportProgress(0, int64(in.Rows), "started")
+		<-proceed
+		if in.Fail {
+			return nil, errors.New("bad row")
+		}
+		ctx.ReportProgress(int64(in.Rows), int64(in.Rows), "")
+		return &importOutput{Imported: in.Rows}, nil
+	}
+	return New(hub, NewMemoryStore()), proceed, op
+}
+
+func waitFor(t *testing.T, s Store, id string, cond func(*Job) bool) *Job {
+	t.Helper()
+	var job *Job
+	assert.Eventually(t, func() bool {
+		job, _ = s.Get(context.Background(), id)
+		return job != nil && cond(job)
+	}, time.Second, time.Millisecond)
+	return job
+}
+
+func TestStart(t *testing.T) {
+	r, proceed, op := newTestRunner()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	job, err := Start(ctx, r, op, &importInput{Rows: 3})
+	cancel()
+	assert.NoError(t, err)
+	assert.Equal(t, StatusPending, job.Status)
+
+	running := waitFor(t, r.Store(), job.ID, func(j *Job) bool { return j.Progress.Message == "started" })
+	assert.Equal(t, StatusRunning, running.Status)
+	assert.Equal(t, operator.Progress{Total: 3, Message: "started"}, running.Progress)
+
+	close(proceed)
+	done := waitFor(t, r.Store(), job.ID, func(j *Job) bool { return j.Status.Done() })
+	assert.Equal(t, StatusSucceeded, done.Status, "cancelling the starting context does not cancel the job")
+	assert.Equal(t, &importOutput{Imported: 3}, done.Output)
+	assert.Equal(t, operator.Progress{Done: 3, Total: 3}, done.Progress)
+	assert.False(t, done.Updated.Before(done.Created))
+}
+
+func TestStart_Failure(t *testing.T) {
+	r, proceed, op := newTestRunner()
+	close(proceed)
+
+	job, err := Start(context.Background(), r, op, &importInput{Fail: true})
+	assert.NoError(t, err)
+
+	done := waitFor(t, r.Store(), job.ID, func(j *Job) bool { return j.Status.Done() })
+	assert.Equal(t, StatusFailed, done.Status)
+	assert.Contains(t, done.Error, "bad row")
+	assert.Nil(t, done.Output)
+}
+
+func TestHandler(t *testing.T) {
+	r, proceed, op := newTestRunner()
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /imports", Handler(r, op, func(id string) string { return "/jobs/" + id }).Go)
+	mux.Handle("GET /jobs/{id}", r.StatusHandler("id"))
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("POST", "/imports", strings.NewReader(`{"rows":2}`)))
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+
+	var accepted Job
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &accepted))
+	assert.Equal(t, StatusPending, accepted.Status)
+	assert.Equal(t, "/jobs/"+accepted.ID, rec.Header().Get("Location"))
+
+	close(proceed)
+	waitFor(t, r.Store(), accepted.ID, func(j *Job) bool { return j.Status.Done() })
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/jobs/"+accepted.ID, nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"imported":2}`, string(field(t, rec.Body.Bytes(), "output")))
+	assert.JSONEq(t, `"succeeded"`, string(field(t, rec.Body.Bytes(), "status")))
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/jobs/missing", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("POST", "/imports", strings.NewReader(`{`)))
+	assert.NotEqual(t, http.StatusAccepted, rec.Code, "no job is started for malformed input")
+}
+
+func field(t *testing.T, body []byte, name string) json.RawMessage {
+	t.Helper()
+	var fields map[string]json.RawMessage
+	assert.NoError(t, json.Unmarshal(body, &fields))
+	return fields[name]
+}
+
+func TestCancel(t *testing.T) {
+	hub := operator.NewHub(func(context.Context) (nopTx, error) { return nopTx{}, nil })
+	r := New(hub, NewMemoryStore())
+	started := make(chan struct{})
+	op := func(ctx *operator.OpContext[nopTx], in *importInput) (*importOutput, error) {
+		close(started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+
+	job, err := Start(context.Background(), r, op, &importInput{})
+	assert.NoError(t, err)
+	<-started
+
+	assert.NoError(t, r.Cancel(context.Background(), job.ID))
+	done := waitFor(t, r.Store(), job.ID, func(j *Job) bool { return j.Status.Done() })
+	assert.Equal(t, StatusCancelled, done.Status)
+	assert.Contains(t, done.Error, context.Canceled.Error())
+
+	assert.ErrorIs(t, r.Cancel(context.Background(), job.ID), ErrJobDone)
+	assert.ErrorIs(t, r.Cancel(context.Background(), "missing"), ErrNotFound)
+}
+
+func TestCancel_RollsBack(t *testing.T) {
+	var rolledBack atomic.Bool
+	hub := operator.NewHub(func(context.Context) (*spyTx, error) { return &spyTx{rolledBack: &rolledBack}, nil })
+	r := New(hub, NewMemoryStore())
+	started := make(chan struct{})
+	proceed := make(chan struct{})
+	op := func(ctx *operator.OpContext[*spyTx], in *importInput) (*importOutput, error) {
+		if _, err := ctx.Tx(); err != nil {
+			return nil, err
+		}
+		close(started)
+		<-proceed
+		return &importOutput{}, nil
+	}
+
+	job, err := Start(context.Background(), r, op, &importInput{})
+	assert.NoError(t, err)
+	<-started
+	assert.NoError(t, r.Cancel(context.Background(), job.ID))
+	close(proceed)
+
+	done := waitFor(t, r.Store(), job.ID, func(j *Job) bool { return j.Status.Done() })
+	assert.Equal(t, StatusCancelled, done.Status, "an operation ignoring cancellation is not committed")
+	assert.True(t, rolledBack.Load())
+}
+
+type spyTx struct{ rolledBack *atomic.Bool }
+
+func (t *spyTx) Commit(context.Context) error   { return nil }
+func (t *spyTx) Rollback(context.Context) error { t.rolledBack.Store(true); return nil }
+
+func TestCancelHandler(t *testing.T) {
+	r := New(operator.NewHub(func(context.Context) (nopTx, error) { return nopTx{}, nil }), NewMemoryStore())
+	mux := http.NewServeMux()
+	mux.Handle("DELETE /jobs/{id}", r.CancelHandler("id"))
+
+	job, err := Start(context.Background(), r, func(ctx *operator.OpContext[nopTx], in *importInput) (*importOutput, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}, &importInput{})
+	assert.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("DELETE", "/jobs/"+job.ID, nil))
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	waitFor(t, r.Store(), job.ID, func(j *Job) bool { return j.Status == StatusCancelled })
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("DELETE", "/jobs/"+job.ID, nil))
+	assert.Equal(t, http.StatusConflict, rec.Code)
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("DELETE", "/jobs/missing", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestHandlerWith(t *testing.T) {
+	type ctxKey struct{}
+	hub := operator.NewHub(func(context.Context) (nopTx, error) { return nopTx{}, nil })
+	r := New(hub, NewMemoryStore())
+	values := make(chan any, 1)
+	op := func(ctx *operator.OpContext[nopTx], in *importInput) (*importOutput, error) {
+		values <- ctx.Value(ctxKey{})
+		return &importOutput{Imported: in.Rows}, nil
+	}
+
+	cfg := httpbind.Config{
+		ContextPolicy: func(ctx context.Context) (context.Context, context.CancelFunc) {
+			return context.WithValue(ctx, ctxKey{}, "policy"), func() {}
+		},
+		ErrorMapper: func(w http.ResponseWriter, err error) { w.WriteHeader(http.StatusTeapot) },
+	}
+	h := HandlerWith(cfg, r, op, func(id string) string { return "/jobs/" + id })
+
+	rec := httptest.NewRecorder()
+	h.Go(rec, httptest.NewRequest("POST", "/imports", strings.NewReader(`{"rows":2}`)))
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	assert.Equal(t, "policy", <-values)
+
+	rec = httptest.NewRecorder()
+	h.Go(rec, httptest.NewRequest("POST", "/imports", strings.NewReader(`{`)))
+	assert.Equal(t, http.StatusTeapot, rec.Code)
+}
@@ -0,0 +1,96 @@
+package asyncop
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/jaz303/operator"
+	"github.com/jaz303/operator/codec"
+	"github.com/jaz303/operator/httpbind"
+	"github.com/jaz303/operator/operr"
+)
+
+// Handler returns an Invoker that parses the request body as an I in JSON
+// and starts a job invoking op with it. It responds with 202 Accepted, the
+// job as JSON, and a Location header set to statusURL(job.ID).
+//
+// The job is started in the context derived by the hub's context policy,
+// and errors are written by operr.DefaultErrorMapper; customise the Invoker,
+// or use HandlerWith(), to change either. Mount it with its Go method:
+//
+//	mux.HandleFunc("POST /imports", asyncop.Handler(jobs, ImportUsers, statusURL).Go)
+func Handler[Tx operator.Transaction, I any, O any](r *Runner[Tx], op operator.Operation[Tx, I, O], statusURL func(id string) string) *httpbind.Invoker[Tx, I, Job] {
+	return HandlerWith(httpbind.Config{}, r, op, statusURL)
+}
+
+// HandlerWith is like Handler, applying the defaults in cfg to the returned
+// Invoker; cfg.OutputMapper is ignored.
+func HandlerWith[Tx operator.Transaction, I any, O any](cfg httpbind.Config, r *Runner[Tx], op operator.Operation[Tx, I, O], statusURL func(id string) string) *httpbind.Invoker[Tx, I, Job] {
+	start := func(ctx context.Context, input *I) (*Job, error) {
+		return Start(ctx, r, op, input)
+	}
+	return httpbind.BindFuncWith(cfg, r.hub, start).
+		WithInputMapper(httpbind.ParseJSON[I]).
+		WithOutputMapper(func(w http.ResponseWriter, job *Job) {
+			w.Header().Set("Location", statusURL(job.ID))
+			writeJob(w, http.StatusAccepted, job)
+		})
+}
+
+// StatusHandler returns an HTTP handler that writes the job whose ID is
+// given by the path parameter param as JSON, or responds with 404 Not Found
+// if there is no such job.
+func (r *Runner[Tx]) StatusHandler(param string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		job, err := r.store.Get(req.Context(), req.PathValue(param))
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJob(w, http.StatusOK, job)
+	})
+}
+
+// CancelHandler returns an HTTP handler that cancels the job whose ID is
+// given by the path parameter param, responding with 202 Accepted and the
+// job as JSON; its status becomes StatusCancelled once its operation has
+// returned. It responds with 409 Conflict if the job has already finished,
+// or 404 Not Found if there is no such running job.
+func (r *Runner[Tx]) CancelHandler(param string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		id := req.PathValue(param)
+		if err := r.Cancel(req.Context(), id); err != nil {
+			writeError(w, err)
+			return
+		}
+		job, err := r.store.Get(req.Context(), id)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJob(w, http.StatusAccepted, job)
+	})
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	var status int
+	switch {
+	case errors.Is(err, ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, ErrJobDone):
+		status = http.StatusConflict
+	default:
+		operr.DefaultErrorMapper(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", codec.JSON.ContentType())
+	w.WriteHeader(status)
+	codec.JSON.Encode(w, map[string]any{"error": err.Error()})
+}
+
+func writeJob(w http.ResponseWriter, status int, job *Job) {
+	w.Header().Set("Content-Type", codec.JSON.ContentType())
+	w.WriteHeader(status)
+	codec.JSON.Encode(w, job)
+}
@@ -0,0 +1,53 @@
+package asyncop
+
+import (
+	"context"
+	"sync"
+)
+
+// Store records jobs. Put is called each time a job's operation reports
+// progress, so implementations should be quick to write.
+type Store interface {
+	// Put creates or replaces the job with job.ID.
+	Put(ctx context.Context, job *Job) error
+
+	// Get returns the job with the given ID, or ErrNotFound.
+	Get(ctx context.Context, id string) (*Job, error)
+}
+
+// MemoryStore is a Store holding jobs in memory. Jobs are retained until
+// deleted.
+type MemoryStore struct {
+	mu   sync.Mutex
+	jobs map[string]Job
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{jobs: map[string]Job{}}
+}
+
+func (s *MemoryStore) Put(ctx context.Context, job *Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs[job.ID] = *job
+	return nil
+}
+
+func (s *MemoryStore) Get(ctx context.Context, id string) (*Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &job, nil
+}
+
+// Delete removes the job with the given ID. Deleting a job that does not
+// exist is not an error.
+func (s *MemoryStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.jobs, id)
+	return nil
+}
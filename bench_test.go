@@ -0,0 +1,157 @@
+package operator
+
+import (
+	"context"
+	"testing"
+)
+
+func benchNoopOperation(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) {
+	return in, nil
+}
+
+func benchEmitOperation(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) {
+	return in, ctx.Emit(&testEvent{})
+}
+
+func benchEmitStaticOperation(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) {
+	return in, ctx.Emit(&dispatchEvent{})
+}
+
+func BenchmarkInvoke(b *testing.B) {
+	hub := newTestHub()
+	hub.Freeze()
+	ctx := context.Background()
+	in := &struct{}{}
+
+	b.ReportAllocs()
+	for b.Loop() {
+		Invoke(ctx, hub, benchNoopOperation, in)
+	}
+}
+
+func BenchmarkInvoke_Emit(b *testing.B) {
+	hub := newTestHub()
+	hub.RegisterEventHandler(&testEvent{}, func(ctx *OpContext[*TxTest], evt *testEvent) {})
+	hub.RegisterEventHandler(&testEvent{}, func(evt *testEvent) error { return nil })
+	hub.Freeze()
+	ctx := context.Background()
+	in := &struct{}{}
+
+	b.ReportAllocs()
+	for b.Loop() {
+		Invoke(ctx, hub, benchEmitOperation, in)
+	}
+}
+
+func BenchmarkInvoke_Parallel(b *testing.B) {
+	hub := newTestHub()
+	hub.RegisterEventHandler(&testEvent{}, func(ctx *OpContext[*TxTest], evt *testEvent) {})
+	hub.Freeze()
+	in := &struct{}{}
+
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		ctx := context.Background()
+		for pb.Next() {
+			Invoke(ctx, hub, benchEmitOperation, in)
+		}
+	})
+}
+
+func benchNoopTxOperation(ctx *OpContext[*TxTest], tx *TxTest, in *struct{}) (*struct{}, error) {
+	return in, nil
+}
+
+func benchAfterFuncOperation(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) {
+	return in, ctx.AfterFunc(benchAfterFunc)
+}
+
+func benchAfterFunc(ctx *OpContext[*TxTest]) {}
+
+func benchNoopOperationV(ctx *OpContext[*TxTest], in int) (int, error) {
+	return in, nil
+}
+
+func BenchmarkInvokeV(b *testing.B) {
+	hub := newTestHub()
+	hub.Freeze()
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	for b.Loop() {
+		InvokeV(ctx, hub, benchNoopOperationV, 1)
+	}
+}
+
+func BenchmarkInvokeTx(b *testing.B) {
+	tx := &TxTest{}
+	hub := NewHub(func(ctx context.Context) (*TxTest, error) { return tx, nil })
+	hub.Freeze()
+	ctx := context.Background()
+	in := &struct{}{}
+
+	b.ReportAllocs()
+	for b.Loop() {
+		InvokeTx(ctx, hub, benchNoopTxOperation, in)
+	}
+}
+
+func BenchmarkInvoke_AfterFunc(b *testing.B) {
+	hub := newTestHub()
+	hub.Freeze()
+	ctx := context.Background()
+	in := &struct{}{}
+
+	b.ReportAllocs()
+	for b.Loop() {
+		Invoke(ctx, hub, benchAfterFuncOperation, in)
+	}
+}
+
+// TestAllocationBudget guards the documented per-invocation allocation
+// budget; see "Performance" in README.md. Raise a budget only deliberately.
+func TestAllocationBudget(t *testing.T) {
+	if raceEnabled {
+		t.Skip("allocation counts are not meaningful under the race detector")
+	}
+
+	emitHub := newTestHub()
+	emitHub.RegisterEventHandler(&testEvent{}, func(ctx *OpContext[*TxTest], evt *testEvent) {})
+	emitHub.Freeze()
+
+	// dispatchHandler has a dispatcher; see dispatch_test.go
+	staticHub := newTestHub()
+	staticHub.RegisterEventHandler(&dispatchEvent{}, dispatchHandler)
+	staticHub.Freeze()
+
+	tx := &TxTest{}
+	txHub := NewHub(func(ctx context.Context) (*TxTest, error) { return tx, nil })
+	txHub.Freeze()
+
+	hub := newTestHub()
+	hub.Freeze()
+
+	ctx := context.Background()
+	in := &struct{}{}
+
+	budgets := []struct {
+		name   string
+		budget float64
+		fn     func()
+	}{
+		{"Invoke", 3, func() { Invoke(ctx, hub, benchNoopOperation, in) }},
+		{"InvokeV", 3, func() { InvokeV(ctx, hub, benchNoopOperationV, 1) }},
+		{"InvokeTx", 3, func() { InvokeTx(ctx, txHub, benchNoopTxOperation, in) }},
+		{"Invoke_AfterFunc", 3, func() { Invoke(ctx, hub, benchAfterFuncOperation, in) }},
+		{"Invoke_Emit", 5, func() { Invoke(ctx, emitHub, benchEmitOperation, in) }},
+		{"Invoke_Emit_Static", 3, func() { Invoke(ctx, staticHub, benchEmitStaticOperation, in) }},
+	}
+
+	for _, b := range budgets {
+		t.Run(b.name, func(t *testing.T) {
+			if allocs := testing.AllocsPerRun(100, b.fn); allocs > b.budget {
+				t.Errorf("%s: %.0f allocs per invocation, budget is %.0f", b.name, allocs, b.budget)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package blob
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDirStore_SignedURL(t *testing.T) {
+	store := NewDirStore(t.TempDir(), "/exports", []byte("secret"))
+	ctx := context.Background()
+
+	assert.NoError(t, store.Put(ctx, "reports/1.csv", strings.NewReader("a,b\n1,2\n"), "text/csv"))
+
+	url, err := store.SignedURL(ctx, "reports/1.csv", time.Minute)
+	assert.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	store.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url, nil))
+	body, _ := io.ReadAll(w.Body)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/csv", w.Header().Get("Content-Type"))
+	assert.Equal(t, "a,b\n1,2\n", string(body))
+}
+
+func TestDirStore_RejectsBadSignature(t *testing.T) {
+	store := NewDirStore(t.TempDir(), "/exports", []byte("secret"))
+	ctx := context.Background()
+
+	assert.NoError(t, store.Put(ctx, "1.csv", strings.NewReader("x"), "text/csv"))
+
+	expired, _ := store.SignedURL(ctx, "1.csv", -time.Minute)
+	tampered, _ := store.SignedURL(ctx, "1.csv", time.Minute)
+	tampered = strings.Replace(tampered, "/1.csv", "/2.csv", 1)
+
+	for _, url := range []string{expired, tampered, "/exports/1.csv"} {
+		w := httptest.NewRecorder()
+		store.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url, nil))
+		assert.Equal(t, http.StatusForbidden, w.Code, url)
+	}
+}
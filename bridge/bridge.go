@@ -0,0 +1,357 @@
+// Package bridge forwards hub events to external message brokers (Kafka,
+// NATS, AMQP etc.) and defines how forwarding behaves when the broker is
+// unreachable.
+//
+// Broker integrations implement Publisher. A Bridge wraps a Publisher with a
+// Fallback which determines what happens to messages that cannot be
+// delivered: by default the publish error is returned to the event handler,
+// failing the operation; alternatively messages can be buffered in memory
+// (Buffer) or spilled to disk (SpillToDisk) and redelivered in order once the
+// broker recovers.
+package bridge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jaz303/operator"
+)
+
+// ErrFallbackFull is returned by a Fallback that has no capacity to retain
+// further messages.
+var ErrFallbackFull = errors.New("bridge fallback is full")
+
+// Message is an encoded event to be published to a broker.
+type Message struct {
+	Name string          `json:"name"`
+	Data json.RawMessage `json:"data"`
+}
+
+// Publisher delivers messages to a broker.
+type Publisher interface {
+	Publish(ctx context.Context, msg *Message) error
+}
+
+// Fallback retains messages that could not be published so they can be
+// redelivered once the broker is reachable again.
+type Fallback interface {
+	// Store retains msg for later delivery.
+	Store(msg *Message) error
+
+	// Drain calls fn for each retained message, in the order they were
+	// stored, discarding each once fn succeeds. Drain stops at the first
+	// error returned by fn and returns it.
+	Drain(fn func(*Message) error) error
+
+	// Len returns the number of retained messages.
+	Len() int
+}
+
+// State is the health of a bridge.
+type State int
+
+const (
+	// Healthy bridges are publishing directly to the broker.
+	Healthy State = iota
+
+	// Degraded bridges cannot reach the broker and are retaining messages
+	// in their fallback.
+	Degraded
+
+	// Failing bridges cannot reach the broker and have no fallback, or
+	// their fallback is unable to retain messages; events are being lost or
+	// operations are failing.
+	Failing
+)
+
+func (s State) String() string {
+	switch s {
+	case Healthy:
+		return "healthy"
+	case Degraded:
+		return "degraded"
+	case Failing:
+		return "failing"
+	default:
+		return fmt.Sprintf("State(%d)", int(s))
+	}
+}
+
+// Status is a snapshot of a bridge's health.
+type Status struct {
+	Name      string
+	State     State
+	Since     time.Time
+	Pending   int
+	LastError error
+}
+
+// Bridge publishes messages to a broker, falling back to a Fallback while the
+// broker is unreachable.
+type Bridge struct {
+	name           string
+	pub            Publisher
+	fallback       Fallback
+	retryInterval  time.Duration
+	publishTimeout time.Duration
+	onStatus       func(Status)
+
+	mu         sync.Mutex
+	state      State
+	since      time.Time
+	lastErr    error
+	recovering bool
+	closed     chan struct{}
+	closeOnce  sync.Once
+}
+
+// Option configures a Bridge.
+type Option func(b *Bridge)
+
+// WithFallback sets the bridge's fallback. Without a fallback, publish errors
+// are returned to the caller.
+func WithFallback(f Fallback) Option {
+	return func(b *Bridge) { b.fallback = f }
+}
+
+// WithRetryInterval sets how often a degraded bridge attempts to redeliver
+// retained messages. The default is 5 seconds.
+func WithRetryInterval(d time.Duration) Option {
+	return func(b *Bridge) { b.retryInterval = d }
+}
+
+// WithPublishTimeout sets how long the bridge waits for the broker to accept
+// each message before treating it as unreachable. The default is 10 seconds.
+func WithPublishTimeout(d time.Duration) Option {
+	return func(b *Bridge) { b.publishTimeout = d }
+}
+
+// WithStatusHandler registers fn to be called whenever the bridge's state
+// changes, for example to update a health check or raise an alert.
+func WithStatusHandler(fn func(Status)) Option {
+	return func(b *Bridge) { b.onStatus = fn }
+}
+
+// New returns a bridge named name that publishes to pub. If its fallback
+// already retains messages - such as a SpillToDisk journal written by a
+// previous process - the bridge starts Degraded, and redelivers them before
+// any new message.
+func New(name string, pub Publisher, opts ...Option) *Bridge {
+	b := &Bridge{
+		name:           name,
+		pub:            pub,
+		retryInterval:  5 * time.Second,
+		publishTimeout: 10 * time.Second,
+		since:          time.Now(),
+		closed:         make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	if b.fallback != nil && b.fallback.Len() > 0 {
+		b.state = Degraded
+		b.startRecovery()
+	}
+	return b
+}
+
+type shutdownKey struct{ b *Bridge }
+
+// Forward registers an event handler on hub that publishes events of the same
+// type as event via b. The event is encoded as JSON. The handler's
+// destination, reported by Hub.EventTopology(), is "bridge:" followed by the
+// bridge's name.
+//
+// Forwarding happens during event dispatch, before the operation's
+// transaction commits; if the bridge returns an error the operation fails and
+// is rolled back. The bridge is closed by the hub's Shutdown(); its health
+// can be reported by registering Check with a health.Checker.
+func Forward[Tx operator.Transaction](hub *operator.Hub[Tx], event operator.Event, b *Bridge) error {
+	if _, ok := hub.Attribute(shutdownKey{b}); !ok {
+		if err := hub.SetAttribute(shutdownKey{b}, true); err != nil {
+			return err
+		}
+		hub.OnShutdown(func(context.Context) error {
+			b.Close()
+			return nil
+		})
+	}
+	return hub.RegisterEventHandler(event, func(ctx context.Context, evt operator.Event) error {
+		data, err := json.Marshal(evt)
+		if err != nil {
+			return err
+		}
+		return b.Publish(ctx, &Message{Name: evt.EventName(), Data: data})
+	}, operator.Destination("bridge:"+b.name))
+}
+
+// Publish publishes msg to the broker. If the broker is unreachable, or
+// messages retained by the fallback are still awaiting redelivery, msg is
+// stored in the fallback instead so that ordering is preserved. Bridges
+// without a fallback attempt every message, even while Failing, so that they
+// recover as soon as the broker does.
+//
+// An error is returned only if msg could be neither published nor retained.
+func (b *Bridge) Publish(ctx context.Context, msg *Message) error {
+	b.mu.Lock()
+	pending := b.recovering
+	b.mu.Unlock()
+
+	if !pending {
+		err := b.publish(ctx, msg)
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if err == nil {
+			if b.state == Failing {
+				b.setState(Healthy)
+			}
+			return nil
+		}
+		b.lastErr = err
+		if b.fallback == nil {
+			b.setState(Failing)
+			return fmt.Errorf("bridge %s: publish failed (%w)", b.name, err)
+		}
+		return b.retain(msg)
+	}
+
+	// Fallbacks may block while they are being drained, so msg is stored
+	// outside b.mu; recovery is then restarted in case it finished in the
+	// meantime, as msg would otherwise never be redelivered.
+	err := b.fallback.Store(msg)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err != nil {
+		return b.retainFailed(err)
+	}
+	b.startRecovery()
+	return nil
+}
+
+// publish publishes msg to the broker, allowing it the bridge's timeout.
+func (b *Bridge) publish(ctx context.Context, msg *Message) error {
+	ctx, cancel := context.WithTimeout(ctx, b.publishTimeout)
+	defer cancel()
+	return b.pub.Publish(ctx, msg)
+}
+
+// retain stores msg in the fallback. It must be called with b.mu held.
+func (b *Bridge) retain(msg *Message) error {
+	if err := b.fallback.Store(msg); err != nil {
+		return b.retainFailed(err)
+	}
+	b.setState(Degraded)
+	b.startRecovery()
+	return nil
+}
+
+// retainFailed must be called with b.mu held.
+func (b *Bridge) retainFailed(err error) error {
+	b.setState(Failing)
+	return fmt.Errorf("bridge %s: publish failed and message could not be retained (%w)", b.name, err)
+}
+
+// Check returns an error if the bridge is Failing, so that it can be
+// registered as a health check. Degraded bridges are still retaining
+// messages, so are considered healthy.
+func (b *Bridge) Check(ctx context.Context) error {
+	st := b.Status()
+	if st.State == Failing {
+		return fmt.Errorf("bridge %s is failing (%w)", b.name, st.LastError)
+	}
+	return nil
+}
+
+// Status returns the bridge's current health.
+func (b *Bridge) Status() Status {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.status()
+}
+
+// Close stops redelivery of retained messages. Messages retained by a
+// SpillToDisk fallback survive and will be redelivered by a bridge created
+// with the same directory.
+func (b *Bridge) Close() {
+	b.closeOnce.Do(func() { close(b.closed) })
+}
+
+func (b *Bridge) status() Status {
+	st := Status{
+		Name:      b.name,
+		State:     b.state,
+		Since:     b.since,
+		LastError: b.lastErr,
+	}
+	if b.fallback != nil {
+		st.Pending = b.fallback.Len()
+	}
+	return st
+}
+
+// setState must be called with b.mu held.
+func (b *Bridge) setState(s State) {
+	if s == b.state {
+		return
+	}
+	b.state = s
+	b.since = time.Now()
+	if s == Healthy {
+		b.lastErr = nil
+	}
+	if b.onStatus != nil {
+		b.onStatus(b.status())
+	}
+}
+
+// startRecovery must be called with b.mu held.
+func (b *Bridge) startRecovery() {
+	if b.recovering {
+		return
+	}
+	b.recovering = true
+	go b.recover()
+}
+
+func (b *Bridge) recover() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-b.closed:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	ticker := time.NewTicker(b.retryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-b.closed:
+			return
+		case <-ticker.C:
+		}
+
+		err := b.fallback.Drain(func(msg *Message) error {
+			return b.publish(ctx, msg)
+		})
+
+		b.mu.Lock()
+		if err == nil && b.fallback.Len() == 0 {
+			b.setState(Healthy)
+			b.recovering = false
+			b.mu.Unlock()
+			return
+		}
+		if err != nil {
+			b.lastErr = err
+		}
+		b.mu.Unlock()
+	}
+}
@@ -0,0 +1,210 @@
+package bridge
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jaz303/operator"
+	"github.com/stretchr/testify/assert"
+)
+
+type nopTx struct{}
+
+func (*nopTx) Commit(context.Context) error   { return nil }
+func (*nopTx) Rollback(context.Context) error { return nil }
+
+type testEvent struct{}
+
+func (*testEvent) EventName() string { return "test" }
+
+type otherEvent struct{}
+
+func (*otherEvent) EventName() string { return "other" }
+
+type flakyPublisher struct {
+	mu   sync.Mutex
+	down bool
+	sent []string
+}
+
+func (p *flakyPublisher) Publish(ctx context.Context, msg *Message) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.down {
+		return errors.New("broker unreachable")
+	}
+	p.sent = append(p.sent, msg.Name)
+	return nil
+}
+
+func (p *flakyPublisher) setDown(down bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.down = down
+}
+
+func (p *flakyPublisher) messages() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.sent...)
+}
+
+func TestBridge_NoFallbackFails(t *testing.T) {
+	pub := &flakyPublisher{down: true}
+	b := New("test", pub)
+	defer b.Close()
+
+	assert.Error(t, b.Publish(context.Background(), &Message{Name: "a"}))
+	assert.Equal(t, Failing, b.Status().State)
+	assert.ErrorContains(t, b.Check(context.Background()), "bridge test is failing (broker unreachable)")
+
+	// the broker is still attempted while failing
+	pub.setDown(false)
+	assert.NoError(t, b.Publish(context.Background(), &Message{Name: "b"}))
+	assert.Equal(t, Healthy, b.Status().State)
+	assert.NoError(t, b.Check(context.Background()))
+	assert.Equal(t, []string{"b"}, pub.messages())
+}
+
+type hungPublisher struct{ started chan string }
+
+func (p hungPublisher) Publish(ctx context.Context, msg *Message) error {
+	p.started <- msg.Name
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestBridge_PublishTimeout(t *testing.T) {
+	pub := hungPublisher{started: make(chan string, 10)}
+	b := New("test", pub, WithFallback(Buffer(10)), WithPublishTimeout(50*time.Millisecond), WithRetryInterval(time.Hour))
+	defer b.Close()
+
+	done := make(chan error)
+	go func() { done <- b.Publish(context.Background(), &Message{Name: "a"}) }()
+
+	// the bridge is not locked while the broker is unresponsive
+	assert.Equal(t, "a", <-pub.started)
+	assert.Equal(t, Healthy, b.Status().State)
+	assert.NoError(t, <-done)
+
+	assert.NoError(t, b.Publish(context.Background(), &Message{Name: "b"}))
+	st := b.Status()
+	assert.Equal(t, Degraded, st.State)
+	assert.Equal(t, 2, st.Pending)
+	assert.ErrorIs(t, st.LastError, context.DeadlineExceeded)
+}
+
+func testFallback(t *testing.T, fallback Fallback) {
+	pub := &flakyPublisher{down: true}
+
+	var mu sync.Mutex
+	var states []State
+	b := New("test", pub,
+		WithFallback(fallback),
+		WithRetryInterval(5*time.Millisecond),
+		WithStatusHandler(func(s Status) {
+			mu.Lock()
+			states = append(states, s.State)
+			mu.Unlock()
+		}),
+	)
+	defer b.Close()
+
+	ctx := context.Background()
+	assert.NoError(t, b.Publish(ctx, &Message{Name: "a"}))
+	assert.NoError(t, b.Publish(ctx, &Message{Name: "b"}))
+
+	st := b.Status()
+	assert.Equal(t, Degraded, st.State)
+	assert.Equal(t, 2, st.Pending)
+	assert.Error(t, st.LastError)
+
+	pub.setDown(false)
+	assert.Eventually(t, func() bool { return b.Status().State == Healthy }, time.Second, time.Millisecond)
+
+	assert.NoError(t, b.Publish(ctx, &Message{Name: "c"}))
+	assert.Equal(t, []string{"a", "b", "c"}, pub.messages())
+
+	mu.Lock()
+	assert.Equal(t, []State{Degraded, Healthy}, states)
+	mu.Unlock()
+}
+
+func TestBridge_Buffer(t *testing.T) {
+	testFallback(t, Buffer(10))
+}
+
+func TestBridge_SpillToDisk(t *testing.T) {
+	f, err := SpillToDisk(t.TempDir())
+	assert.NoError(t, err)
+	testFallback(t, f)
+}
+
+func TestBridge_BufferFull(t *testing.T) {
+	pub := &flakyPublisher{down: true}
+	b := New("test", pub, WithFallback(Buffer(1)), WithRetryInterval(time.Hour))
+	defer b.Close()
+
+	assert.NoError(t, b.Publish(context.Background(), &Message{Name: "a"}))
+	assert.ErrorIs(t, b.Publish(context.Background(), &Message{Name: "b"}), ErrFallbackFull)
+	assert.Equal(t, Failing, b.Status().State)
+}
+
+func TestBridge_RedeliversJournalFirst(t *testing.T) {
+	dir := t.TempDir()
+	f, _ := SpillToDisk(dir)
+	f.Store(&Message{Name: "a"})
+
+	f, _ = SpillToDisk(dir)
+	pub := &flakyPublisher{}
+	b := New("test", pub, WithFallback(f), WithRetryInterval(5*time.Millisecond))
+	defer b.Close()
+	assert.Equal(t, Degraded, b.Status().State)
+
+	assert.NoError(t, b.Publish(context.Background(), &Message{Name: "b"}))
+	assert.Eventually(t, func() bool { return b.Status().State == Healthy }, time.Second, time.Millisecond)
+	assert.Equal(t, []string{"a", "b"}, pub.messages())
+}
+
+func TestForward_Destination(t *testing.T) {
+	hub := operator.NewHub(func(context.Context) (*nopTx, error) { return &nopTx{}, nil })
+	assert.NoError(t, Forward(hub, &testEvent{}, New("orders", &flakyPublisher{})))
+
+	topo := hub.EventTopology()
+	if assert.Len(t, topo, 1) && assert.Len(t, topo[0].Handlers, 1) {
+		assert.Equal(t, "bridge:orders", topo[0].Handlers[0].Destination)
+	}
+}
+
+func TestForward_ClosedOnShutdown(t *testing.T) {
+	hub := operator.NewHub(func(context.Context) (*nopTx, error) { return &nopTx{}, nil })
+	b := New("test", &flakyPublisher{})
+	assert.NoError(t, Forward(hub, &testEvent{}, b))
+	assert.NoError(t, Forward(hub, &otherEvent{}, b))
+
+	assert.NoError(t, hub.Shutdown(context.Background()))
+	select {
+	case <-b.closed:
+	default:
+		t.Fatal("bridge was not closed")
+	}
+}
+
+func TestSpillToDisk_SurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+	f, _ := SpillToDisk(dir)
+	f.Store(&Message{Name: "a", Data: []byte(`{"x":1}`)})
+
+	f, err := SpillToDisk(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, f.Len())
+
+	var got []*Message
+	assert.NoError(t, f.Drain(func(m *Message) error { got = append(got, m); return nil }))
+	assert.Equal(t, "a", got[0].Name)
+	assert.JSONEq(t, `{"x":1}`, string(got[0].Data))
+	assert.Equal(t, 0, f.Len())
+}
@@ -0,0 +1,180 @@
+package bridge
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Buffer returns a Fallback that retains up to size messages in memory.
+// Retained messages are lost if the process exits.
+func Buffer(size int) Fallback {
+	return &memoryFallback{size: size}
+}
+
+type memoryFallback struct {
+	mu   sync.Mutex
+	size int
+	msgs []*Message
+}
+
+func (f *memoryFallback) Store(msg *Message) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.msgs) >= f.size {
+		return ErrFallbackFull
+	}
+	f.msgs = append(f.msgs, msg)
+	return nil
+}
+
+func (f *memoryFallback) Drain(fn func(*Message) error) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for len(f.msgs) > 0 {
+		if err := fn(f.msgs[0]); err != nil {
+			return err
+		}
+		f.msgs[0] = nil
+		f.msgs = f.msgs[1:]
+	}
+	f.msgs = nil
+	return nil
+}
+
+func (f *memoryFallback) Len() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.msgs)
+}
+
+// SpillToDisk returns a Fallback that appends messages to a journal file in
+// dir, which is created if it does not exist. Messages already present in the
+// journal, for example from a previous process, are redelivered first.
+func SpillToDisk(dir string) (Fallback, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	f := &diskFallback{path: filepath.Join(dir, "spill.jsonl")}
+	msgs, err := f.load()
+	if err != nil {
+		return nil, err
+	}
+	f.count = len(msgs)
+	return f, nil
+}
+
+type diskFallback struct {
+	mu    sync.Mutex
+	path  string
+	count int
+}
+
+func (f *diskFallback) Store(msg *Message) error {
+	line, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	fd, err := os.OpenFile(f.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := fd.Write(append(line, '\n')); err != nil {
+		fd.Close()
+		return err
+	}
+	if err := fd.Sync(); err != nil {
+		fd.Close()
+		return err
+	}
+	if err := fd.Close(); err != nil {
+		return err
+	}
+
+	f.count++
+	return nil
+}
+
+func (f *diskFallback) Drain(fn func(*Message) error) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	msgs, err := f.load()
+	if err != nil {
+		return err
+	}
+
+	for i, msg := range msgs {
+		if err := fn(msg); err != nil {
+			return errors.Join(err, f.rewrite(msgs[i:]))
+		}
+	}
+
+	f.count = 0
+	if err := os.Remove(f.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (f *diskFallback) Len() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.count
+}
+
+func (f *diskFallback) load() ([]*Message, error) {
+	fd, err := os.Open(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+
+	var msgs []*Message
+	r := bufio.NewReader(fd)
+	for {
+		line, err := r.ReadBytes('\n')
+		if len(line) > 1 {
+			var msg Message
+			if jerr := json.Unmarshal(line, &msg); jerr == nil {
+				msgs = append(msgs, &msg)
+			}
+		}
+		if err == io.EOF {
+			return msgs, nil
+		} else if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// rewrite replaces the journal with msgs, which have not yet been delivered.
+func (f *diskFallback) rewrite(msgs []*Message) error {
+	tmp := f.path + ".tmp"
+	fd, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(fd)
+	for _, msg := range msgs {
+		if err := enc.Encode(msg); err != nil {
+			fd.Close()
+			return err
+		}
+	}
+	if err := fd.Close(); err != nil {
+		return err
+	}
+	f.count = len(msgs)
+	return os.Rename(tmp, f.path)
+}
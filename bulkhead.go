@@ -0,0 +1,97 @@
+package operator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// ErrConcurrencyLimit is returned, wrapped with ErrUnavailable, when an
+// invocation is rejected because its operation is at its concurrency limit.
+var ErrConcurrencyLimit = errors.New("concurrency limit reached")
+
+// ConcurrencyLimit bounds the number of concurrent invocations of an
+// operation; see Hub.SetConcurrencyLimit().
+type ConcurrencyLimit struct {
+	// Maximum number of invocations running at once
+	Max int
+
+	// Number of invocations that may wait for a running invocation to
+	// finish; further invocations are rejected. If zero, invocations are
+	// rejected as soon as Max are running.
+	Queue int
+
+	// Maximum time an invocation may wait; if zero, invocations wait until
+	// their context is done.
+	Timeout time.Duration
+}
+
+// SetConcurrencyLimit() limits the number of concurrent invocations of the
+// named operation, so that a heavy operation cannot exhaust resources - such
+// as a database connection pool - shared by every operation. Invocations
+// beyond the limit wait, as configured by limit, and are otherwise rejected
+// with an error wrapping ErrUnavailable and ErrConcurrencyLimit. Waiting
+// happens before the operation's transaction begins.
+//
+// Replaces any existing limit. Returns an error if limit.Max is less than
+// 1, or ErrHubFrozen if the hub has been frozen.
+func (h *Hub[Tx]) SetConcurrencyLimit(operation string, limit ConcurrencyLimit) error {
+	if h.frozen.Load() {
+		return ErrHubFrozen
+	}
+	if limit.Max < 1 {
+		return fmt.Errorf("concurrency limit for operation %s must be at least 1", operation)
+	}
+	return h.operations.update(operation, func(_ *operationTable, info *operationInfo) error {
+		info.bulkhead = &bulkhead{
+			limit: limit,
+			slots: make(chan struct{}, limit.Max),
+		}
+		return nil
+	})
+}
+
+type bulkhead struct {
+	limit   ConcurrencyLimit
+	slots   chan struct{}
+	waiting atomic.Int32
+}
+
+// acquire waits for a slot, returning a function that releases it.
+func (b *bulkhead) acquire(ctx context.Context, name string) (func(), error) {
+	select {
+	case b.slots <- struct{}{}:
+		return b.release, nil
+	default:
+	}
+
+	reject := func(err error) (func(), error) {
+		return nil, fmt.Errorf("%w: %w: operation %s (%w)", ErrUnavailable, ErrConcurrencyLimit, name, err)
+	}
+
+	if int(b.waiting.Add(1)) > b.limit.Queue {
+		b.waiting.Add(-1)
+		return reject(errors.New("queue full"))
+	}
+	defer b.waiting.Add(-1)
+
+	var timeout <-chan time.Time
+	if b.limit.Timeout > 0 {
+		t := time.NewTimer(b.limit.Timeout)
+		defer t.Stop()
+		timeout = t.C
+	}
+
+	select {
+	case b.slots <- struct{}{}:
+		return b.release, nil
+	case <-timeout:
+		return reject(errors.New("timed out waiting"))
+	case <-ctx.Done():
+		return reject(ctx.Err())
+	}
+}
+
+func (b *bulkhead) release() { <-b.slots }
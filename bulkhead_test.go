@@ -0,0 +1,132 @@
+package operator
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConcurrencyLimit(t *testing.T) {
+	hub := newTestHub()
+
+	started := make(chan struct{})
+	unblock := make(chan struct{})
+	op := func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) {
+		started <- struct{}{}
+		<-unblock
+		return in, nil
+	}
+	assert.NoError(t, RegisterOperation(hub, "heavy", op))
+	assert.NoError(t, hub.SetConcurrencyLimit("heavy", ConcurrencyLimit{Max: 1, Queue: 1}))
+
+	var wg sync.WaitGroup
+	results := make(chan error, 2)
+	for range 2 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := Invoke(context.Background(), hub, op, &struct{}{})
+			results <- err
+		}()
+	}
+
+	// one invocation runs, one waits in the queue
+	<-started
+	assert.Eventually(t, func() bool {
+		return hub.operations.lookup("heavy").bulkhead.waiting.Load() == 1
+	}, time.Second, time.Millisecond)
+
+	// the queue is full, so a third is rejected immediately
+	_, err := Invoke(context.Background(), hub, op, &struct{}{})
+	assert.ErrorIs(t, err, ErrUnavailable)
+	assert.ErrorIs(t, err, ErrConcurrencyLimit)
+
+	unblock <- struct{}{}
+	<-started
+	unblock <- struct{}{}
+	wg.Wait()
+	close(results)
+	for err := range results {
+		assert.NoError(t, err)
+	}
+}
+
+func TestConcurrencyLimit_Timeout(t *testing.T) {
+	hub := newTestHub()
+
+	unblock := make(chan struct{})
+	op := func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) {
+		<-unblock
+		return in, nil
+	}
+	assert.NoError(t, RegisterOperation(hub, "heavy", op))
+	assert.NoError(t, hub.SetConcurrencyLimit("heavy", ConcurrencyLimit{Max: 1, Queue: 5, Timeout: 10 * time.Millisecond}))
+
+	done := make(chan struct{})
+	go func() {
+		Invoke(context.Background(), hub, op, &struct{}{})
+		close(done)
+	}()
+	assert.Eventually(t, func() bool {
+		return len(hub.operations.lookup("heavy").bulkhead.slots) == 1
+	}, time.Second, time.Millisecond)
+
+	_, err := Invoke(context.Background(), hub, op, &struct{}{})
+	assert.ErrorIs(t, err, ErrConcurrencyLimit)
+
+	close(unblock)
+	<-done
+}
+
+func TestConcurrencyLimit_Invalid(t *testing.T) {
+	hub := newTestHub()
+	assert.Error(t, hub.SetConcurrencyLimit("heavy", ConcurrencyLimit{Max: 0}))
+
+	hub.Freeze()
+	assert.ErrorIs(t, hub.SetConcurrencyLimit("heavy", ConcurrencyLimit{Max: 1}), ErrHubFrozen)
+}
+
+func TestConcurrencyLimit_WaitsBeforeAdmission(t *testing.T) {
+	hub := newTestHub(WithAdmissionControl(AdmissionControl{MaxConcurrent: 2, MaxQueue: 5}))
+
+	started := make(chan struct{})
+	unblock := make(chan struct{})
+	heavy := func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) {
+		started <- struct{}{}
+		<-unblock
+		return in, nil
+	}
+	light := func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) { return in, nil }
+	assert.NoError(t, RegisterOperation(hub, "heavy", heavy))
+	assert.NoError(t, hub.SetConcurrencyLimit("heavy", ConcurrencyLimit{Max: 1, Queue: 5}))
+
+	var wg sync.WaitGroup
+	for range 3 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := Invoke(context.Background(), hub, heavy, &struct{}{})
+			assert.NoError(t, err)
+		}()
+	}
+	<-started
+	assert.Eventually(t, func() bool {
+		return hub.operations.lookup("heavy").bulkhead.waiting.Load() == 2
+	}, time.Second, time.Millisecond)
+
+	// invocations waiting for the concurrency limit hold no admission slot
+	assert.Equal(t, 1, hub.AdmissionStats()[1].Running)
+	assert.Zero(t, waiting(hub))
+	_, err := Invoke(context.Background(), hub, light, &struct{}{})
+	assert.NoError(t, err)
+
+	for range 2 {
+		unblock <- struct{}{}
+		<-started
+	}
+	unblock <- struct{}{}
+	wg.Wait()
+}
@@ -0,0 +1,110 @@
+package operator
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/jaz303/operator/cache"
+)
+
+// CacheOption configures the caching applied by WithCache().
+type CacheOption func(*cacheConfig)
+
+type cacheConfig struct {
+	invalidations []cacheInvalidation
+}
+
+type cacheInvalidation struct {
+	event Event
+	keys  func(Event) []string
+}
+
+// InvalidateOn() removes cached outputs when an event of the same type as
+// event is emitted. keys returns the keys, as returned by the cache's key
+// function, whose outputs are stale once the emitting operation commits.
+func InvalidateOn(event Event, keys func(evt Event) []string) CacheOption {
+	return func(c *cacheConfig) {
+		c.invalidations = append(c.invalidations, cacheInvalidation{event: event, keys: keys})
+	}
+}
+
+// WithCache() returns an operation that caches the outputs of op in c for
+// ttl, keyed by key(input). When the output for an input is cached it is
+// returned without invoking op; otherwise op is invoked, and its output is
+// stored - as JSON - once the operation has committed. Keys are prefixed
+// with op's name, so that one cache can be shared by several operations.
+//
+// Cached outputs are removed by the events declared with InvalidateOn(),
+// once the operation that emitted them has committed:
+//
+//	getUser, err := operator.WithCache(hub, GetUser, cache.NewLRU(10_000),
+//		func(in *GetUserInput) string { return in.ID },
+//		time.Hour,
+//		operator.InvalidateOn(&UserUpdated{}, func(evt operator.Event) []string {
+//			return []string{evt.(*UserUpdated).ID}
+//		}))
+//
+// Caching is best-effort: cache errors are logged via the hub's logger, and
+// the operation proceeds as though the output was not cached. ttl bounds how
+// long an output may be stale if an invalidation is missed.
+//
+// Unless registered, the returned operation is named after op, e.g.
+// "WithCache(users.GetUser)". Returns ErrHubFrozen
+// if the hub has been frozen.
+func WithCache[Tx Transaction, I any, O any](hub *Hub[Tx], op Operation[Tx, I, O], c cache.Cache, key func(*I) string, ttl time.Duration, opts ...CacheOption) (Operation[Tx, I, O], error) {
+	var cfg cacheConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	name, _ := hub.lookupOperation(op)
+	prefix := name + ":"
+	logger := hub.opts.logger
+
+	for _, inv := range cfg.invalidations {
+		err := hub.RegisterEventHandler(inv.event, func(ctx *OpContext[Tx], evt Event) error {
+			keys := inv.keys(evt)
+			for i, k := range keys {
+				keys[i] = prefix + k
+			}
+			return ctx.AfterFunc(func(ctx *OpContext[Tx]) {
+				if err := c.Delete(context.WithoutCancel(ctx), keys...); err != nil {
+					logger.Warn("operator: cache invalidation failed", "operation", name, "error", err)
+				}
+			})
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return wrapOperation("WithCache", []any{op}, []any{key, ttl}, func(ctx *OpContext[Tx], input *I) (*O, error) {
+		k := prefix + key(input)
+
+		data, ok, err := c.Get(ctx, k)
+		if err != nil {
+			logger.Warn("operator: cache lookup failed", "operation", name, "error", err)
+		} else if ok {
+			var out O
+			if err := json.Unmarshal(data, &out); err == nil {
+				return &out, nil
+			}
+		}
+
+		out, err := op(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+
+		if data, err := json.Marshal(out); err == nil {
+			ctx.AfterFunc(func(ctx *OpContext[Tx]) {
+				if err := c.Set(context.WithoutCancel(ctx), k, data, ttl); err != nil {
+					logger.Warn("operator: cache store failed", "operation", name, "error", err)
+				}
+			})
+		}
+
+		return out, nil
+	}), nil
+}
@@ -0,0 +1,105 @@
+package operator
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/jaz303/operator/cache"
+	"github.com/stretchr/testify/assert"
+)
+
+type cacheInput struct{ ID int }
+
+type cacheOutput struct{ Name string }
+
+func TestWithCache(t *testing.T) {
+	hub := newTestHub()
+	c := cache.NewLRU(10)
+
+	calls := 0
+	get := func(ctx *OpContext[*TxTest], in *cacheInput) (*cacheOutput, error) {
+		calls++
+		return &cacheOutput{Name: "user" + strconv.Itoa(in.ID) + "." + strconv.Itoa(calls)}, nil
+	}
+	assert.NoError(t, RegisterOperation(hub, "users.Get", get))
+
+	cached, err := WithCache(hub, get, c, func(in *cacheInput) string { return strconv.Itoa(in.ID) }, time.Minute,
+		InvalidateOn(&testEvent{}, func(evt Event) []string {
+			return []string{strconv.Itoa(evt.(*testEvent).Val)}
+		}))
+	assert.NoError(t, err)
+
+	invoke := func(id int) string {
+		out, err := Invoke(context.Background(), hub, cached, &cacheInput{ID: id})
+		assert.NoError(t, err)
+		return out.Name
+	}
+
+	assert.Equal(t, "user1.1", invoke(1))
+	assert.Equal(t, "user1.1", invoke(1))
+	assert.Equal(t, "user2.2", invoke(2))
+	assert.Equal(t, 2, calls)
+
+	_, ok, _ := c.Get(context.Background(), "users.Get:1")
+	assert.True(t, ok, "keys are prefixed with the operation name")
+
+	_, err = Invoke(context.Background(), hub, func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) {
+		return in, ctx.Emit(&testEvent{Val: 1})
+	}, &struct{}{})
+	assert.NoError(t, err)
+
+	assert.Equal(t, "user1.3", invoke(1))
+	assert.Equal(t, "user2.2", invoke(2))
+}
+
+func TestWithCache_FailuresAreNotCached(t *testing.T) {
+	hub := newTestHub()
+	c := cache.NewLRU(10)
+
+	calls := 0
+	get := func(ctx *OpContext[*TxTest], in *cacheInput) (*cacheOutput, error) {
+		calls++
+		return nil, errors.New("boom")
+	}
+	cached, err := WithCache(hub, get, c, func(in *cacheInput) string { return "k" }, time.Minute)
+	assert.NoError(t, err)
+
+	for range 2 {
+		_, err := Invoke(context.Background(), hub, cached, &cacheInput{})
+		assert.EqualError(t, err, "boom")
+	}
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, 0, c.Len())
+}
+
+func TestWithCache_NotStoredOnRollback(t *testing.T) {
+	hub := newTestHub()
+	c := cache.NewLRU(10)
+
+	get := func(ctx *OpContext[*TxTest], in *cacheInput) (*cacheOutput, error) {
+		return &cacheOutput{Name: "x"}, nil
+	}
+	cached, err := WithCache(hub, get, c, func(in *cacheInput) string { return "k" }, time.Minute)
+	assert.NoError(t, err)
+
+	// composed with a failing operation, the cached operation's output is
+	// discarded along with the transaction
+	op := Compose(cached, func(*cacheOutput) (*struct{}, error) { return nil, errors.New("later failure") },
+		func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) { return in, nil })
+	_, err = Invoke(context.Background(), hub, op, &cacheInput{})
+	assert.Error(t, err)
+	assert.Equal(t, 0, c.Len())
+}
+
+func TestWithCache_HubFrozen(t *testing.T) {
+	hub := newTestHub()
+	hub.Freeze()
+
+	get := func(ctx *OpContext[*TxTest], in *cacheInput) (*cacheOutput, error) { return nil, nil }
+	_, err := WithCache(hub, get, cache.NewLRU(1), func(in *cacheInput) string { return "k" }, time.Minute,
+		InvalidateOn(&testEvent{}, func(Event) []string { return nil }))
+	assert.ErrorIs(t, err, ErrHubFrozen)
+}
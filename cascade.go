@@ -0,0 +1,88 @@
+package operator
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+var (
+	// ErrCascadeLimit is returned by Invoke(), and by OpContext.Emit(), when
+	// an operation's events exceed the hub's CascadeLimits.
+	ErrCascadeLimit = errors.New("event cascade limit exceeded")
+
+	// ErrEventCycle is returned by Invoke(), and by OpContext.Emit(), when an
+	// event handler emits an event of the same type as one of the events
+	// that caused it, if the hub detects cycles; see CascadeLimits.
+	ErrEventCycle = errors.New("event cycle")
+)
+
+// CascadeLimits bounds the events dispatched by a single operation. Event
+// handlers can emit further events, whose handlers can emit more, so a
+// mistake can otherwise make an operation dispatch events until it runs out
+// of memory. An operation exceeding its limits fails, and is rolled back,
+// with an error describing the chain of events that led to it.
+type CascadeLimits struct {
+	// Maximum depth of the events emitted by event handlers; events emitted
+	// by the operation itself are at depth 0, those emitted by their
+	// handlers at depth 1, and so on. The default is 32; negative values
+	// impose no limit.
+	MaxDepth int
+
+	// Maximum number of events emitted by the operation and its event
+	// handlers. The default is 10000; negative values impose no limit.
+	MaxEvents int
+
+	// If true, an event handler emitting an event with the same type and
+	// name as an event that caused it fails with ErrEventCycle, rather than
+	// when MaxDepth is reached. Enable this unless handlers legitimately
+	// emit events recursively, when processing a tree say.
+	DetectCycles bool
+}
+
+// WithCascadeLimits sets the limits on the events dispatched by each
+// operation.
+func WithCascadeLimits(l CascadeLimits) HubOption {
+	return func(o *hubOptions) {
+		o.cascade = l
+		if o.cascade.MaxDepth == 0 {
+			o.cascade.MaxDepth = 32
+		}
+		if o.cascade.MaxEvents == 0 {
+			o.cascade.MaxEvents = 10000
+		}
+	}
+}
+
+// checkCascade returns an error if the operation emitting qe, its
+// emitted'th event, exceeds limits l.
+func checkCascade(l *CascadeLimits, qe *queuedEvent, emitted int) error {
+	if l.MaxEvents >= 0 && emitted > l.MaxEvents {
+		return fmt.Errorf("%w: more than %d events emitted (%s)", ErrCascadeLimit, l.MaxEvents, eventChain(qe))
+	}
+	if l.MaxDepth >= 0 && qe.depth > l.MaxDepth {
+		return fmt.Errorf("%w: events nested deeper than %d (%s)", ErrCascadeLimit, l.MaxDepth, eventChain(qe))
+	}
+	if l.DetectCycles {
+		ty, name := reflect.TypeOf(qe.evt), qe.evt.EventName()
+		for c := qe.cause; c != nil; c = c.cause {
+			if reflect.TypeOf(c.evt) == ty && c.evt.EventName() == name {
+				return fmt.Errorf("%w: %s", ErrEventCycle, eventChain(qe))
+			}
+		}
+	}
+	return nil
+}
+
+// eventChain describes qe and the events that caused it, e.g.
+// "UserCreated -> WelcomeSent".
+func eventChain(qe *queuedEvent) string {
+	var names []string
+	for c := qe; c != nil; c = c.cause {
+		names = append(names, c.evt.EventName())
+	}
+	slices.Reverse(names)
+	return strings.Join(names, " -> ")
+}
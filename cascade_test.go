@@ -0,0 +1,89 @@
+package operator
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func emitTestEvent(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) {
+	return in, ctx.Emit(&testEvent{})
+}
+
+func TestCascade_MaxDepth(t *testing.T) {
+	hub := newTestHub(WithCascadeLimits(CascadeLimits{MaxDepth: 3}))
+	assert.NoError(t, hub.RegisterEventHandler(&testEvent{}, func(ctx *OpContext[*TxTest], evt *testEvent) error {
+		return ctx.Emit(&otherEvent{})
+	}))
+	assert.NoError(t, hub.RegisterEventHandler(&otherEvent{}, func(ctx *OpContext[*TxTest], evt *otherEvent) {
+		// the error is ignored, but the operation fails regardless
+		ctx.Emit(&testEvent{})
+	}))
+
+	var tx *TxTest
+	_, err := Invoke(context.Background(), hub, func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) {
+		tx, _ = ctx.Tx()
+		return emitTestEvent(ctx, in)
+	}, &struct{}{})
+	assert.ErrorIs(t, err, ErrCascadeLimit)
+	assert.ErrorContains(t, err, "events nested deeper than 3 (testEvent -> otherEvent -> testEvent -> otherEvent -> testEvent)")
+	assert.True(t, tx.RolledBack)
+}
+
+func TestCascade_MaxEvents(t *testing.T) {
+	hub := newTestHub(WithCascadeLimits(CascadeLimits{MaxEvents: 5}))
+	assert.NoError(t, hub.RegisterEventHandler(&testEvent{}, func(ctx *OpContext[*TxTest], evt *testEvent) error {
+		if evt.Val == 0 {
+			for range 5 {
+				if err := ctx.Emit(&testEvent{Val: 1}); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}))
+
+	_, err := Invoke(context.Background(), hub, emitTestEvent, &struct{}{})
+	assert.ErrorIs(t, err, ErrCascadeLimit)
+	assert.ErrorContains(t, err, "more than 5 events emitted (testEvent -> testEvent)")
+}
+
+func TestCascade_DetectCycles(t *testing.T) {
+	hub := newTestHub(WithCascadeLimits(CascadeLimits{DetectCycles: true}))
+	assert.NoError(t, hub.RegisterEventHandler(&testEvent{}, func(ctx *OpContext[*TxTest], evt *testEvent) error {
+		return ctx.EmitNamed("relayed", nil)
+	}))
+	assert.NoError(t, hub.RegisterNamedEventHandler("relayed", func(ctx *OpContext[*TxTest], evt *NamedEvent) error {
+		if err := ctx.EmitNamed("audited", nil); err != nil {
+			return err
+		}
+		return ctx.Emit(&testEvent{})
+	}))
+
+	_, err := Invoke(context.Background(), hub, emitTestEvent, &struct{}{})
+	assert.ErrorIs(t, err, ErrEventCycle)
+	assert.ErrorContains(t, err, "event cycle: testEvent -> relayed -> testEvent")
+}
+
+func TestCascade_WithinLimits(t *testing.T) {
+	hub := newTestHub(WithCascadeLimits(CascadeLimits{MaxDepth: -1, MaxEvents: -1, DetectCycles: true}))
+	var seen []int
+	assert.NoError(t, hub.RegisterEventHandler(&testEvent{}, func(ctx *OpContext[*TxTest], evt *testEvent) error {
+		seen = append(seen, evt.Val)
+		if evt.Val == 0 {
+			// siblings are not cycles
+			ctx.Emit(&otherEvent{})
+			ctx.Emit(&otherEvent{})
+		}
+		return nil
+	}))
+	assert.NoError(t, hub.RegisterEventHandler(&otherEvent{}, func(ctx *OpContext[*TxTest], evt *otherEvent) {}))
+
+	_, err := Invoke(context.Background(), hub, func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) {
+		ctx.Emit(&testEvent{})
+		return in, ctx.Emit(&testEvent{Val: 1})
+	}, &struct{}{})
+	assert.NoError(t, err)
+	assert.Equal(t, []int{0, 1}, seen)
+}
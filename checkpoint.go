@@ -0,0 +1,185 @@
+package operator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+var (
+	// ErrNoCheckpointStore is returned by InvokeResumable() if the hub has
+	// no CheckpointStore.
+	ErrNoCheckpointStore = errors.New("no checkpoint store")
+
+	// ErrNotResumable is returned by OpContext.Checkpoint() if the operation
+	// was not invoked with InvokeResumable().
+	ErrNotResumable = errors.New("operation is not resumable")
+)
+
+// CheckpointStore persists the checkpoints of resumable operations; see
+// InvokeResumable(). Implementations must be safe for concurrent use.
+type CheckpointStore interface {
+	// LoadCheckpoint returns the state most recently saved under key, or
+	// nil if there is none.
+	LoadCheckpoint(ctx context.Context, key string) ([]byte, error)
+
+	// SaveCheckpoint saves state under key, replacing any previous state.
+	SaveCheckpoint(ctx context.Context, key string, state []byte) error
+
+	// DeleteCheckpoint removes the state saved under key. Deleting a key
+	// with no state is not an error.
+	DeleteCheckpoint(ctx context.Context, key string) error
+}
+
+// WithCheckpointStore sets the store in which resumable operations save
+// their checkpoints.
+func WithCheckpointStore(s CheckpointStore) HubOption {
+	return func(o *hubOptions) { o.checkpoints = s }
+}
+
+// checkpoint is the resumable state of an operation invoked with
+// InvokeResumable().
+type checkpoint struct {
+	key   string
+	state []byte
+}
+
+// InvokeResumable() invokes op as a resumable operation, identified by
+// key, that commits its work in chunks. The operation calls
+// OpContext.Checkpoint() at the end of each chunk, committing the work done
+// so far and saving its progress in the hub's CheckpointStore; when started,
+// it calls OpContext.Resume() to restore the progress saved by an earlier
+// invocation with the same key that failed, or was interrupted by a crash,
+// and picks up where it left off:
+//
+//	func Import(ctx *OpContext[Tx], in *ImportInput) (*ImportOutput, error) {
+//		var next int
+//		if _, err := ctx.Resume(&next); err != nil {
+//			return nil, err
+//		}
+//		for ; next < len(in.Rows); next++ {
+//			// ... import in.Rows[next]
+//			if next%1000 == 999 {
+//				if err := ctx.Checkpoint(next + 1); err != nil {
+//					return nil, err
+//				}
+//			}
+//		}
+//		return &ImportOutput{}, nil
+//	}
+//
+// Events, AfterFuncs and follow-ups are dispatched with each chunk's
+// commit. A failure rolls back only the current chunk. Checkpoints are
+// saved after their chunk commits, so a crash in between repeats the chunk
+// on resumption; chunks should be idempotent.
+//
+// Keys are prefixed with op's name, so distinct operations may use the same
+// key. The checkpoint is deleted once the operation succeeds. Returns
+// ErrNoCheckpointStore if the hub has no CheckpointStore.
+func InvokeResumable[Tx Transaction, I any, O any](ctx context.Context, hub *Hub[Tx], op Operation[Tx, I, O], key string, input *I) (*O, error) {
+	store := hub.opts.checkpoints
+	if store == nil {
+		return nil, ErrNoCheckpointStore
+	}
+
+	name, info := hub.lookupOperation(op)
+	key = name + ":" + key
+	state, err := store.LoadCheckpoint(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("load checkpoint failed (%w)", err)
+	}
+
+	opCtx := hub.beginOperation(ctx, name)
+	opCtx.checkpoint = &checkpoint{key: key, state: state}
+	out, err := runOperation(opCtx, info, op, input)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := store.DeleteCheckpoint(context.WithoutCancel(ctx), key); err != nil {
+		hub.opts.onBackgroundError(fmt.Errorf("delete checkpoint %q failed (%w)", key, err))
+	}
+	return out, nil
+}
+
+// Resume() restores the state saved by the most recent call to Checkpoint()
+// under the operation's key, unmarshalling it from JSON into state, and
+// returns true. If there is no saved state, it leaves state unchanged and
+// returns false. Returns ErrNotResumable unless the operation was invoked
+// with InvokeResumable().
+func (o *OpContext[T]) Resume(state any) (bool, error) {
+	if o.checkpoint == nil {
+		return false, ErrNotResumable
+	}
+	if o.checkpoint.state == nil {
+		return false, nil
+	}
+	if err := json.Unmarshal(o.checkpoint.state, state); err != nil {
+		return false, fmt.Errorf("restore checkpoint failed (%w)", err)
+	}
+	return true, nil
+}
+
+// Checkpoint() ends the current chunk of a resumable operation: it
+// dispatches the events emitted so far and commits the transaction, then
+// saves state, as JSON, in the hub's CheckpointStore. The operation
+// continues in a new transaction, begun by its next call to Tx().
+//
+// Locks acquired with Lock() remain held, unless released by the
+// transaction's commit, in which case the operation must lock them again.
+// Values stored with Provide(), or constructed by providers, are discarded,
+// so that repositories bound to the committed transaction are constructed
+// afresh by the next Use(). If the commit fails, the operation can not continue
+// and must return the error. Returns ErrNotResumable unless the operation
+// was invoked with InvokeResumable().
+func (o *OpContext[T]) Checkpoint(state any) error {
+	if o.checkpoint == nil {
+		return ErrNotResumable
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("checkpoint failed (%w)", err)
+	}
+	if err := o.commitChunk(false); err != nil {
+		return fmt.Errorf("commit chunk failed (%w)", err)
+	}
+	if err := o.hub.opts.checkpoints.SaveCheckpoint(context.WithoutCancel(o), o.checkpoint.key, data); err != nil {
+		return fmt.Errorf("save checkpoint failed (%w)", err)
+	}
+	o.checkpoint.state = data
+	return nil
+}
+
+// MemoryCheckpointStore is a CheckpointStore holding checkpoints in memory.
+// It is suitable for tests; checkpoints do not survive a restart.
+type MemoryCheckpointStore struct {
+	mu     sync.Mutex
+	states map[string][]byte
+}
+
+// NewMemoryCheckpointStore() returns an empty MemoryCheckpointStore.
+func NewMemoryCheckpointStore() *MemoryCheckpointStore {
+	return &MemoryCheckpointStore{states: map[string][]byte{}}
+}
+
+func (s *MemoryCheckpointStore) LoadCheckpoint(ctx context.Context, key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.states[key], nil
+}
+
+func (s *MemoryCheckpointStore) SaveCheckpoint(ctx context.Context, key string, state []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.states[key] = state
+	return nil
+}
+
+func (s *MemoryCheckpointStore) DeleteCheckpoint(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.states, key)
+	return nil
+}
@@ -0,0 +1,146 @@
+package operator
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type importRows struct {
+	Rows   int
+	FailAt int
+}
+
+type chunkEvent struct{ Next int }
+
+func (*chunkEvent) EventName() string { return "chunk" }
+
+func TestInvokeResumable(t *testing.T) {
+	var txs []*TxTest
+	store := NewMemoryCheckpointStore()
+	hub := NewHub(func(ctx context.Context) (*TxTest, error) {
+		tx := &TxTest{}
+		txs = append(txs, tx)
+		return tx, nil
+	}, WithCheckpointStore(store))
+
+	var imported, events []int
+	var after int
+	assert.NoError(t, hub.RegisterEventHandler(&chunkEvent{}, func(ctx *OpContext[*TxTest], evt *chunkEvent) error {
+		events = append(events, evt.Next)
+		return nil
+	}))
+
+	op := func(ctx *OpContext[*TxTest], in *importRows) (*struct{}, error) {
+		next := 0
+		if _, err := ctx.Resume(&next); err != nil {
+			return nil, err
+		}
+		for ; next < in.Rows; next++ {
+			if next == in.FailAt {
+				return nil, errors.New("crash")
+			}
+			if _, err := ctx.Tx(); err != nil {
+				return nil, err
+			}
+			imported = append(imported, next)
+			if next%2 == 1 {
+				ctx.Emit(&chunkEvent{Next: next + 1})
+				ctx.AfterFunc(func(*OpContext[*TxTest]) { after++ })
+				if err := ctx.Checkpoint(next + 1); err != nil {
+					return nil, err
+				}
+			}
+		}
+		return &struct{}{}, nil
+	}
+
+	_, err := InvokeResumable(context.Background(), hub, op, "batch-1", &importRows{Rows: 5, FailAt: 3})
+	assert.EqualError(t, err, "crash")
+	assert.Equal(t, []int{0, 1, 2}, imported)
+	assert.Equal(t, []int{2}, events, "events are dispatched with each chunk")
+	assert.Equal(t, 1, after, "AfterFuncs run with each chunk")
+	if assert.Len(t, txs, 2) {
+		assert.True(t, txs[0].Committed)
+		assert.True(t, txs[1].RolledBack, "only the failing chunk is rolled back")
+	}
+
+	imported = nil
+	_, err = InvokeResumable(context.Background(), hub, op, "batch-1", &importRows{Rows: 5, FailAt: -1})
+	assert.NoError(t, err)
+	assert.Equal(t, []int{2, 3, 4}, imported, "resumes from the last checkpoint")
+	assert.Equal(t, []int{2, 4}, events)
+	assert.Equal(t, 2, after)
+	for _, tx := range txs[2:] {
+		assert.True(t, tx.Committed)
+	}
+
+	assert.Empty(t, store.states, "the checkpoint is deleted on success")
+}
+
+func TestInvokeResumable_NoStore(t *testing.T) {
+	op := func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) { return in, nil }
+	_, err := InvokeResumable(context.Background(), newTestHub(), op, "k", &struct{}{})
+	assert.ErrorIs(t, err, ErrNoCheckpointStore)
+}
+
+func TestCheckpoint_NotResumable(t *testing.T) {
+	op := func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) {
+		_, err := ctx.Resume(&struct{}{})
+		assert.ErrorIs(t, err, ErrNotResumable)
+		return nil, ctx.Checkpoint(1)
+	}
+	_, err := Invoke(context.Background(), newTestHub(), op, &struct{}{})
+	assert.ErrorIs(t, err, ErrNotResumable)
+}
+
+type chunkRepo struct{ tx *TxTest }
+
+// chunkLocks locks keys prefixed "tx:" for the duration of the transaction,
+// and others until unlocked.
+type chunkLocks struct{ locked []string }
+
+func (l *chunkLocks) Lock(ctx *OpContext[*TxTest], key string) (Unlock, error) {
+	l.locked = append(l.locked, key)
+	if strings.HasPrefix(key, "tx:") {
+		return nil, nil
+	}
+	return func(context.Context) error { return nil }, nil
+}
+
+func TestCheckpoint_ValuesAndLocks(t *testing.T) {
+	hub := newTestHub(WithCheckpointStore(NewMemoryCheckpointStore()))
+	locks := &chunkLocks{}
+	assert.NoError(t, hub.SetLockProvider(locks))
+	assert.NoError(t, RegisterProvider(hub, func(ctx *OpContext[*TxTest]) (*chunkRepo, error) {
+		tx, err := ctx.Tx()
+		return &chunkRepo{tx: tx}, err
+	}))
+
+	var repos []*chunkRepo
+	op := func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) {
+		for i := range 2 {
+			if err := ctx.Lock("session", "tx:row"); err != nil {
+				return nil, err
+			}
+			repos = append(repos, Use[*chunkRepo](ctx))
+			if err := ctx.Checkpoint(i); err != nil {
+				return nil, err
+			}
+		}
+		return in, nil
+	}
+	_, err := InvokeResumable(context.Background(), hub, op, "k", &struct{}{})
+	assert.NoError(t, err)
+
+	if assert.Len(t, repos, 2) {
+		assert.NotSame(t, repos[0], repos[1], "values are constructed afresh for each chunk")
+		assert.NotSame(t, repos[0].tx, repos[1].tx)
+		assert.True(t, repos[0].tx.Committed)
+	}
+	assert.Equal(t, []string{"session", "tx:row", "tx:row"}, locks.locked,
+		"transaction-scoped locks are acquired again after a checkpoint")
+}
@@ -0,0 +1,193 @@
+package operator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrUnavailable is returned by Invoke() when an operation is rejected
+// without being run, because a dependency is known to be failing.
+var ErrUnavailable = errors.New("operation unavailable")
+
+// BreakerState is the state of a circuit breaker.
+type BreakerState int
+
+const (
+	// Closed breakers allow operations to run.
+	BreakerClosed BreakerState = iota
+
+	// Open breakers reject operations with ErrUnavailable.
+	BreakerOpen
+
+	// Half-open breakers allow a single trial invocation, which closes the
+	// breaker if it succeeds, and re-opens it if it fails.
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return fmt.Sprintf("BreakerState(%d)", int(s))
+	}
+}
+
+// BreakerConfig configures the circuit breakers installed by
+// WithCircuitBreaker().
+type BreakerConfig struct {
+	// Number of consecutive failures after which an operation's breaker
+	// opens. The default is 5.
+	Failures int
+
+	// Time for which an open breaker rejects invocations before allowing a
+	// trial. The default is 30 seconds.
+	CoolDown time.Duration
+
+	// IsFailure reports whether err counts as a failure. The default counts
+	// every error except context cancellation, ErrForbidden and
+	// ErrUnavailable, so that callers giving up, being refused, or being
+	// turned away by a concurrency limit or admission control does not open
+	// the breaker.
+	IsFailure func(err error) bool
+
+	// Operations to which breakers are applied; if empty, every operation
+	// has a breaker.
+	Operations []string
+
+	// OnStateChange, if non-nil, is called whenever an operation's breaker
+	// changes state. It is called synchronously and must not block.
+	OnStateChange func(operation string, state BreakerState)
+}
+
+// WithCircuitBreaker installs middleware giving each operation, by name, its
+// own circuit breaker. After cfg.Failures consecutive failures, invocations
+// of the operation fail immediately with ErrUnavailable - without beginning
+// a transaction - until cfg.CoolDown has elapsed. This prevents an outage of
+// a downstream dependency from piling up doomed transactions.
+//
+// The breaker runs inside any middleware installed before it, and outside
+// middleware installed after it.
+func WithCircuitBreaker(cfg BreakerConfig) HubOption {
+	if cfg.Failures <= 0 {
+		cfg.Failures = 5
+	}
+	if cfg.CoolDown <= 0 {
+		cfg.CoolDown = 30 * time.Second
+	}
+	if cfg.IsFailure == nil {
+		cfg.IsFailure = func(err error) bool {
+			return !errors.Is(err, context.Canceled) && !errors.Is(err, ErrForbidden) && !errors.Is(err, ErrUnavailable)
+		}
+	}
+
+	return func(o *hubOptions) {
+		b := &breakers{cfg: cfg, opts: o, states: map[string]*breaker{}}
+		for _, name := range cfg.Operations {
+			b.states[name] = &breaker{}
+		}
+		o.middleware = append(o.middleware, b.middleware)
+	}
+}
+
+type breakers struct {
+	cfg BreakerConfig
+
+	// the hub's options, for its clock; read at invocation time, once the
+	// options are complete
+	opts *hubOptions
+
+	mu     sync.Mutex
+	states map[string]*breaker
+}
+
+type breaker struct {
+	state    BreakerState
+	failures int
+	openedAt time.Time
+	trial    bool
+}
+
+func (b *breakers) middleware(ctx context.Context, info OperationInfo, next func(context.Context) error) error {
+	name := info.Name()
+	if !b.allow(name) {
+		return fmt.Errorf("%w: circuit breaker for %s is open", ErrUnavailable, name)
+	}
+	err := next(ctx)
+	b.record(name, err)
+	return err
+}
+
+func (b *breakers) allow(name string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	br, ok := b.states[name]
+	if !ok {
+		if len(b.cfg.Operations) > 0 {
+			return true
+		}
+		br = &breaker{}
+		b.states[name] = br
+	}
+
+	switch br.state {
+	case BreakerOpen:
+		if b.opts.clock.Now().Sub(br.openedAt) < b.cfg.CoolDown {
+			return false
+		}
+		b.transition(name, br, BreakerHalfOpen)
+		br.trial = true
+		return true
+	case BreakerHalfOpen:
+		// only one trial at a time
+		if br.trial {
+			return false
+		}
+		br.trial = true
+		return true
+	default:
+		return true
+	}
+}
+
+func (b *breakers) record(name string, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	br, ok := b.states[name]
+	if !ok {
+		return
+	}
+	br.trial = false
+
+	if err == nil || !b.cfg.IsFailure(err) {
+		br.failures = 0
+		if br.state != BreakerClosed {
+			b.transition(name, br, BreakerClosed)
+		}
+		return
+	}
+
+	br.failures++
+	if br.state == BreakerHalfOpen || br.failures >= b.cfg.Failures {
+		br.openedAt = b.opts.clock.Now()
+		if br.state != BreakerOpen {
+			b.transition(name, br, BreakerOpen)
+		}
+	}
+}
+
+func (b *breakers) transition(name string, br *breaker, state BreakerState) {
+	br.state = state
+	if b.cfg.OnStateChange != nil {
+		b.cfg.OnStateChange(name, state)
+	}
+}
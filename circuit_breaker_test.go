@@ -0,0 +1,110 @@
+package operator
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type manualClock struct {
+	now time.Time
+}
+
+func (c *manualClock) Now() time.Time { return c.now }
+
+func TestCircuitBreaker(t *testing.T) {
+	clock := &manualClock{now: time.Now()}
+	var states []string
+	hub := newTestHub(WithClock(clock), WithCircuitBreaker(BreakerConfig{
+		Failures: 2,
+		CoolDown: time.Minute,
+		OnStateChange: func(op string, s BreakerState) {
+			states = append(states, s.String())
+		},
+	}))
+
+	fail := true
+	calls := 0
+	op := func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) {
+		calls++
+		if fail {
+			return nil, errors.New("downstream down")
+		}
+		return in, nil
+	}
+	assert.NoError(t, RegisterOperation(hub, "flaky", op))
+	invoke := func() error {
+		_, err := Invoke(context.Background(), hub, op, &struct{}{})
+		return err
+	}
+
+	assert.NotErrorIs(t, invoke(), ErrUnavailable)
+	assert.NotErrorIs(t, invoke(), ErrUnavailable)
+	assert.ErrorIs(t, invoke(), ErrUnavailable)
+	assert.Equal(t, 2, calls)
+
+	// after cool-down, a failed trial re-opens the breaker
+	clock.now = clock.now.Add(time.Minute)
+	assert.NotErrorIs(t, invoke(), ErrUnavailable)
+	assert.ErrorIs(t, invoke(), ErrUnavailable)
+	assert.Equal(t, 3, calls)
+
+	// a successful trial closes it
+	clock.now = clock.now.Add(time.Minute)
+	fail = false
+	assert.NoError(t, invoke())
+	assert.NoError(t, invoke())
+	assert.Equal(t, 5, calls)
+
+	assert.Equal(t, []string{"open", "half-open", "open", "half-open", "closed"}, states)
+}
+
+func TestCircuitBreaker_SelectedOperations(t *testing.T) {
+	hub := newTestHub(WithCircuitBreaker(BreakerConfig{Failures: 1, Operations: []string{"guarded"}}))
+
+	failing := func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) {
+		return nil, errors.New("failed")
+	}
+	assert.NoError(t, RegisterOperation(hub, "unguarded", failing))
+
+	for range 3 {
+		_, err := Invoke(context.Background(), hub, failing, &struct{}{})
+		assert.NotErrorIs(t, err, ErrUnavailable)
+	}
+}
+
+func TestCircuitBreaker_IgnoresConcurrencyLimit(t *testing.T) {
+	hub := newTestHub(WithCircuitBreaker(BreakerConfig{Failures: 1, CoolDown: time.Minute}))
+
+	var once sync.Once
+	started := make(chan struct{})
+	unblock := make(chan struct{})
+	op := func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) {
+		once.Do(func() { close(started) })
+		<-unblock
+		return in, nil
+	}
+	assert.NoError(t, RegisterOperation(hub, "heavy", op))
+	assert.NoError(t, hub.SetConcurrencyLimit("heavy", ConcurrencyLimit{Max: 1}))
+
+	done := make(chan error)
+	go func() {
+		_, err := Invoke(context.Background(), hub, op, &struct{}{})
+		done <- err
+	}()
+	<-started
+
+	_, err := Invoke(context.Background(), hub, op, &struct{}{})
+	assert.ErrorIs(t, err, ErrConcurrencyLimit)
+
+	close(unblock)
+	assert.NoError(t, <-done)
+
+	// the rejection did not open the breaker
+	_, err = Invoke(context.Background(), hub, op, &struct{}{})
+	assert.NoError(t, err)
+}
@@ -0,0 +1,186 @@
+// Package cloudevents publishes hub events as CloudEvents once the operation
+// that emitted them has committed.
+//
+// Select the events to publish with Publish(); they are serialized with an
+// eventcodec.Serializer when emitted, and delivered to a Sink by a background
+// queue after commit, so that delivery never delays or fails the operation:
+//
+//	p := cloudevents.New(eventcodec.New(hub), cloudevents.HTTPSink(url, nil),
+//		cloudevents.WithSource("/users"))
+//	defer p.Close(context.Background())
+//	cloudevents.Publish(hub, p, &UserCreated{}, &UserDeleted{})
+//
+// HTTPSink posts events in structured JSON mode. To publish to Kafka, NATS or
+// any other broker, wrap a bridge.Publisher (or a *bridge.Bridge, to inherit
+// its fallback behaviour) with PublisherSink.
+package cloudevents
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/jaz303/operator"
+	"github.com/jaz303/operator/eventcodec"
+)
+
+var (
+	ErrQueueFull = errors.New("cloudevents queue is full")
+	ErrClosed    = errors.New("cloudevents publisher is closed")
+)
+
+// Sink delivers CloudEvents to their destination.
+type Sink interface {
+	Send(ctx context.Context, ce *eventcodec.CloudEvent) error
+}
+
+// SinkFunc adapts a function to the Sink interface.
+type SinkFunc func(ctx context.Context, ce *eventcodec.CloudEvent) error
+
+func (fn SinkFunc) Send(ctx context.Context, ce *eventcodec.CloudEvent) error { return fn(ctx, ce) }
+
+// Publisher delivers CloudEvents to a Sink from an asynchronous queue.
+type Publisher struct {
+	serializer *eventcodec.Serializer
+	sink       Sink
+	source     string
+	queueSize  int
+	timeout    time.Duration
+	onError    func(*eventcodec.CloudEvent, error)
+
+	mu     sync.RWMutex
+	closed bool
+	queue  chan *eventcodec.CloudEvent
+	done   chan struct{}
+}
+
+// Option configures a Publisher.
+type Option func(p *Publisher)
+
+// WithSource sets the source attribute of published events. The default is
+// "operator".
+func WithSource(source string) Option {
+	return func(p *Publisher) { p.source = source }
+}
+
+// WithQueueSize sets the number of events that may await delivery. Events
+// published while the queue is full are dropped and reported to the error
+// handler with ErrQueueFull. The default is 1024.
+func WithQueueSize(n int) Option {
+	return func(p *Publisher) { p.queueSize = n }
+}
+
+// WithSendTimeout limits the time allowed for each delivery. The default is
+// 10 seconds.
+func WithSendTimeout(d time.Duration) Option {
+	return func(p *Publisher) { p.timeout = d }
+}
+
+// WithErrorHandler sets a function to be called when an event cannot be
+// delivered. The default logs the error with slog.Default().
+func WithErrorHandler(fn func(ce *eventcodec.CloudEvent, err error)) Option {
+	return func(p *Publisher) { p.onError = fn }
+}
+
+// New returns a publisher that serializes events with s and delivers them to
+// sink. Call Close() to stop the publisher once it is no longer needed.
+func New(s *eventcodec.Serializer, sink Sink, opts ...Option) *Publisher {
+	p := &Publisher{
+		serializer: s,
+		sink:       sink,
+		source:     "operator",
+		queueSize:  1024,
+		timeout:    10 * time.Second,
+		onError: func(ce *eventcodec.CloudEvent, err error) {
+			slog.Error("cloudevents delivery failed", "type", ce.Type, "id", ce.ID, "error", err)
+		},
+		done: make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	p.queue = make(chan *eventcodec.CloudEvent, p.queueSize)
+	go p.run()
+	return p
+}
+
+// Publish registers an event handler on hub for each of events' types, which
+// publishes matching events via p once the emitting operation has committed.
+// Events are serialized when emitted; if serialization fails, the operation
+// fails. Each event's time is that of the emitting operation; see
+// operator.OpContext.Now.
+//
+// p is closed by hub.Shutdown once the hub's operations have drained.
+func Publish[Tx operator.Transaction](hub *operator.Hub[Tx], p *Publisher, events ...operator.Event) error {
+	for _, evt := range events {
+		err := hub.RegisterEventHandler(evt, func(ctx *operator.OpContext[Tx], evt operator.Event) error {
+			ce, err := p.serializer.EncodeCloudEvent(evt, p.source, newID())
+			if err != nil {
+				return err
+			}
+			now := ctx.Now().UTC()
+			ce.Time = &now
+			return ctx.AfterFunc(func(*operator.OpContext[Tx]) { p.Enqueue(ce) })
+		})
+		if err != nil {
+			return err
+		}
+	}
+	hub.OnShutdown(p.Close)
+	return nil
+}
+
+// Enqueue queues ce for delivery without blocking.
+func (p *Publisher) Enqueue(ce *eventcodec.CloudEvent) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.closed {
+		p.onError(ce, ErrClosed)
+		return
+	}
+	select {
+	case p.queue <- ce:
+	default:
+		p.onError(ce, ErrQueueFull)
+	}
+}
+
+// Close stops accepting events and waits for queued events to be delivered,
+// or for ctx to be done.
+func (p *Publisher) Close(ctx context.Context) error {
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.queue)
+	}
+	p.mu.Unlock()
+
+	select {
+	case <-p.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *Publisher) run() {
+	defer close(p.done)
+	for ce := range p.queue {
+		ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+		if err := p.sink.Send(ctx, ce); err != nil {
+			p.onError(ce, err)
+		}
+		cancel()
+	}
+}
+
+func newID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
@@ -0,0 +1,110 @@
+package cloudevents
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/jaz303/operator"
+	"github.com/jaz303/operator/bridge"
+	"github.com/jaz303/operator/eventcodec"
+	"github.com/stretchr/testify/assert"
+)
+
+type nopTx struct{}
+
+func (nopTx) Commit(context.Context) error   { return nil }
+func (nopTx) Rollback(context.Context) error { return nil }
+
+type userCreated struct {
+	ID int `json:"id"`
+}
+
+func (e *userCreated) EventName() string { return "user.created" }
+
+type userDeleted struct{}
+
+func (e *userDeleted) EventName() string { return "user.deleted" }
+
+func emit(hub *operator.Hub[nopTx], fail bool, events ...operator.Event) error {
+	_, err := operator.Invoke(context.Background(), hub, func(ctx *operator.OpContext[nopTx], in *struct{}) (*struct{}, error) {
+		for _, evt := range events {
+			if err := ctx.Emit(evt); err != nil {
+				return nil, err
+			}
+		}
+		if fail {
+			return nil, errors.New("failed")
+		}
+		return in, nil
+	}, &struct{}{})
+	return err
+}
+
+func TestPublish_HTTP(t *testing.T) {
+	var mu sync.Mutex
+	var received []map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, eventcodec.CloudEventsContentType, r.Header.Get("Content-Type"))
+		body, _ := io.ReadAll(r.Body)
+		var ce map[string]any
+		assert.NoError(t, json.Unmarshal(body, &ce))
+		mu.Lock()
+		received = append(received, ce)
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	hub := operator.NewHub(func(context.Context) (nopTx, error) { return nopTx{}, nil })
+	p := New(eventcodec.New(hub), HTTPSink(srv.URL, nil), WithSource("/users"))
+	assert.NoError(t, Publish(hub, p, &userCreated{}))
+
+	assert.NoError(t, emit(hub, false, &userCreated{ID: 1}, &userDeleted{}))
+	assert.Error(t, emit(hub, true, &userCreated{ID: 2}))
+	assert.NoError(t, p.Close(context.Background()))
+
+	// only the committed, selected event is published
+	assert.Len(t, received, 1)
+	assert.Equal(t, "user.created", received[0]["type"])
+	assert.Equal(t, "/users", received[0]["source"])
+	assert.Equal(t, map[string]any{"id": float64(1)}, received[0]["data"])
+	assert.NotEmpty(t, received[0]["id"])
+	assert.NotEmpty(t, received[0]["time"])
+}
+
+type testPublisher struct {
+	msgs []*bridge.Message
+	err  error
+}
+
+func (p *testPublisher) Publish(ctx context.Context, msg *bridge.Message) error {
+	p.msgs = append(p.msgs, msg)
+	return p.err
+}
+
+func TestPublish_PublisherSinkErrors(t *testing.T) {
+	pub := &testPublisher{err: errors.New("broker down")}
+	var errs []error
+
+	hub := operator.NewHub(func(context.Context) (nopTx, error) { return nopTx{}, nil })
+	p := New(eventcodec.New(hub), PublisherSink(pub), WithErrorHandler(func(ce *eventcodec.CloudEvent, err error) {
+		errs = append(errs, err)
+	}))
+	assert.NoError(t, Publish(hub, p, &userCreated{}))
+
+	assert.NoError(t, emit(hub, false, &userCreated{ID: 1}))
+	assert.NoError(t, p.Close(context.Background()))
+
+	assert.Len(t, pub.msgs, 1)
+	assert.Equal(t, "user.created", pub.msgs[0].Name)
+	assert.Len(t, errs, 1)
+
+	// publishing after close is reported, not delivered
+	assert.NoError(t, emit(hub, false, &userCreated{ID: 2}))
+	assert.ErrorIs(t, errs[1], ErrClosed)
+}
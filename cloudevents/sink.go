@@ -0,0 +1,55 @@
+package cloudevents
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/jaz303/operator/bridge"
+	"github.com/jaz303/operator/eventcodec"
+)
+
+// HTTPSink returns a sink that POSTs events to url in structured JSON mode.
+// Any response other than 2xx is an error. If client is nil,
+// http.DefaultClient is used.
+func HTTPSink(url string, client *http.Client) Sink {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return SinkFunc(func(ctx context.Context, ce *eventcodec.CloudEvent) error {
+		body, err := json.Marshal(ce)
+		if err != nil {
+			return err
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", eventcodec.CloudEventsContentType)
+
+		res, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		res.Body.Close()
+		if res.StatusCode < 200 || res.StatusCode > 299 {
+			return fmt.Errorf("cloudevents sink responded %s", res.Status)
+		}
+		return nil
+	})
+}
+
+// PublisherSink returns a sink that publishes events to a broker via pub.
+// Each event is published as a bridge.Message named after the event's type,
+// whose data is the event in structured JSON mode.
+func PublisherSink(pub bridge.Publisher) Sink {
+	return SinkFunc(func(ctx context.Context, ce *eventcodec.CloudEvent) error {
+		data, err := json.Marshal(ce)
+		if err != nil {
+			return err
+		}
+		return pub.Publish(ctx, &bridge.Message{Name: ce.Type, Data: data})
+	})
+}
@@ -0,0 +1,166 @@
+// Command operator-gen generates code for operator applications.
+//
+// Usage:
+//
+//	operator-gen ts [-manifest file] [-out file]
+//	operator-gen go [-manifest file] [-out file] [-package name]
+//	operator-gen handlers [packages]
+//
+// The ts and go commands read a codegen.Manifest describing an API's routes,
+// as JSON, and write a typed TypeScript or Go client for it. The manifest is
+// read from standard input, and the client written to standard output,
+// unless files are given. Go clients are written to the package named by
+// -package, which defaults to the name of the output file's directory.
+//
+// The handlers command writes, to each of the given packages (default
+// "./..."), a file registering dispatchers for the event handlers the
+// package registers, so that they are dispatched without reflection; see
+// operator.RegisterDispatcher() and codegen.GenerateDispatchers. Handlers
+// that cannot be dispatched statically are reported. Files generated for
+// packages that no longer register any handlers are removed.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/token"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/jaz303/operator/codegen"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	var err error
+	switch os.Args[1] {
+	case "ts":
+		err = generate(os.Args[2:], "ts", func(fs *flag.FlagSet) generator {
+			return func(m *codegen.Manifest, _ string) ([]byte, error) { return codegen.TypeScript(m) }
+		})
+	case "go":
+		err = generate(os.Args[2:], "go", func(fs *flag.FlagSet) generator {
+			pkg := fs.String("package", "", "package `name` (default the output directory's name)")
+			return func(m *codegen.Manifest, out string) ([]byte, error) {
+				return codegen.Go(m, packageName(*pkg, out))
+			}
+		})
+	case "handlers":
+		err = handlers(os.Args[2:])
+	default:
+		usage()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "operator-gen: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: operator-gen ts [-manifest file] [-out file]\n")
+	fmt.Fprintf(os.Stderr, "       operator-gen go [-manifest file] [-out file] [-package name]\n")
+	fmt.Fprintf(os.Stderr, "       operator-gen handlers [packages]\n")
+	os.Exit(2)
+}
+
+// generator generates code from m, to be written to the file out.
+type generator func(m *codegen.Manifest, out string) ([]byte, error)
+
+// generate reads a manifest and writes the output of a generator, according
+// to the flags in args. The generator is returned by newGen, which may
+// define additional flags.
+func generate(args []string, name string, newGen func(fs *flag.FlagSet) generator) error {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	manifest := fs.String("manifest", "", "manifest `file` (default standard input)")
+	out := fs.String("out", "", "output `file` (default standard output)")
+	gen := newGen(fs)
+	fs.Parse(args)
+
+	m, err := readManifest(*manifest)
+	if err != nil {
+		return err
+	}
+	src, err := gen(m, *out)
+	if err != nil {
+		return err
+	}
+	if *out == "" {
+		_, err = os.Stdout.Write(src)
+		return err
+	}
+	return os.WriteFile(*out, src, 0o644)
+}
+
+func readManifest(path string) (*codegen.Manifest, error) {
+	var r io.Reader = os.Stdin
+	if path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+	var m codegen.Manifest
+	if err := json.NewDecoder(r).Decode(&m); err != nil {
+		return nil, fmt.Errorf("reading manifest failed (%w)", err)
+	}
+	return &m, nil
+}
+
+// packageName returns pkg, or if empty, the name of out's directory, or
+// failing that, "client".
+func packageName(pkg, out string) string {
+	if pkg != "" {
+		return pkg
+	}
+	if out != "" {
+		abs, err := filepath.Abs(out)
+		if err == nil {
+			if dir := filepath.Base(filepath.Dir(abs)); token.IsIdentifier(dir) {
+				return dir
+			}
+		}
+	}
+	return "client"
+}
+
+// generatedHeader is the first line of the files operator-gen writes.
+const generatedHeader = "// Code generated by operator-gen"
+
+func handlers(args []string) error {
+	fs := flag.NewFlagSet("handlers", flag.ExitOnError)
+	fs.Parse(args)
+	patterns := fs.Args()
+	if len(patterns) == 0 {
+		patterns = []string{"./..."}
+	}
+
+	pkgs, err := codegen.GenerateDispatchers(".", patterns...)
+	if err != nil {
+		return err
+	}
+	for _, pkg := range pkgs {
+		for _, s := range pkg.Skipped {
+			fmt.Fprintln(os.Stderr, filepath.Join(pkg.Dir, s))
+		}
+		path := filepath.Join(pkg.Dir, codegen.DispatcherFile)
+		if pkg.Source != nil {
+			if err := os.WriteFile(path, pkg.Source, 0o644); err != nil {
+				return err
+			}
+			continue
+		}
+		if old, err := os.ReadFile(path); err == nil && bytes.HasPrefix(old, []byte(generatedHeader)) {
+			if err := os.Remove(path); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
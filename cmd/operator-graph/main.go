@@ -0,0 +1,71 @@
+// Command operator-graph draws the operations, events and event handlers of
+// an operator application, showing which events each operation emits, the
+// handlers that receive them, and the events those handlers emit in turn.
+//
+// Usage:
+//
+//	operator-graph [-format mermaid|dot] [-out file] [packages]
+//
+// The graph is found by analyzing the source of the given packages (default
+// "./..."); see opgraph.Analyze. It is written to standard output as a
+// Mermaid flowchart, or with -format dot, in the Graphviz DOT language:
+//
+//	operator-graph -format dot ./... | dot -Tsvg > events.svg
+//
+// Registrations that can only be resolved at run time are not shown;
+// programs can include them by rendering opgraph.FromHub merged with the
+// analyzed graph.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/jaz303/operator/opgraph"
+)
+
+func main() {
+	format := flag.String("format", "mermaid", "output `format`: mermaid or dot")
+	out := flag.String("out", "", "output `file` (default standard output)")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: operator-graph [-format mermaid|dot] [-out file] [packages]\n")
+		flag.PrintDefaults()
+		os.Exit(2)
+	}
+	flag.Parse()
+	if *format != "mermaid" && *format != "dot" {
+		flag.Usage()
+	}
+	patterns := flag.Args()
+	if len(patterns) == 0 {
+		patterns = []string{"./..."}
+	}
+
+	if err := run(patterns, *format, *out); err != nil {
+		fmt.Fprintf(os.Stderr, "operator-graph: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(patterns []string, format, out string) error {
+	g, err := opgraph.Analyze(".", patterns...)
+	if err != nil {
+		return err
+	}
+	var b bytes.Buffer
+	if format == "dot" {
+		err = g.DOT(&b)
+	} else {
+		err = g.Mermaid(&b)
+	}
+	if err != nil {
+		return err
+	}
+	if out == "" {
+		_, err = os.Stdout.Write(b.Bytes())
+		return err
+	}
+	return os.WriteFile(out, b.Bytes(), 0o644)
+}
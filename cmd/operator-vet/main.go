@@ -0,0 +1,13 @@
+// Command operator-vet checks the signatures of event handlers registered
+// with operator hubs. It is run by go vet:
+//
+//	go vet -vettool=$(which operator-vet) ./...
+//
+// See package opvet for the checks made.
+package main
+
+import "github.com/jaz303/operator/opvet"
+
+func main() {
+	opvet.Main()
+}
@@ -0,0 +1,156 @@
+// Package codec provides a registry of encoders/decoders keyed by content
+// type, shared by operator's bindings and background subsystems so that a
+// wire format registered once is available everywhere.
+package codec
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"mime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrUnsupportedMediaType = errors.New("unsupported media type")
+	ErrNotAcceptable        = errors.New("not acceptable")
+)
+
+// Codec encodes and decodes values for a single content type.
+type Codec interface {
+	// ContentType returns the media type handled by this codec,
+	// e.g. "application/json"
+	ContentType() string
+
+	Encode(w io.Writer, v any) error
+	Decode(r io.Reader, v any) error
+}
+
+// Registry maps content types to codecs. A Registry is safe for concurrent use.
+type Registry struct {
+	mu     sync.RWMutex
+	codecs map[string]Codec
+}
+
+// NewRegistry returns a registry containing the supplied codecs.
+func NewRegistry(codecs ...Codec) *Registry {
+	r := &Registry{codecs: map[string]Codec{}}
+	for _, c := range codecs {
+		r.Register(c)
+	}
+	return r
+}
+
+// Register adds c to the registry, replacing any existing codec for the same
+// content type.
+func (r *Registry) Register(c Codec) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.codecs[normalize(c.ContentType())] = c
+}
+
+// Lookup returns the codec registered for contentType. Media type parameters
+// (e.g. "; charset=utf-8") are ignored.
+func (r *Registry) Lookup(contentType string) (Codec, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	c, ok := r.codecs[normalize(contentType)]
+	return c, ok
+}
+
+// Find returns the codec registered for contentType, or an
+// *UnsupportedError wrapping ErrUnsupportedMediaType.
+func (r *Registry) Find(contentType string) (Codec, error) {
+	if c, ok := r.Lookup(contentType); ok {
+		return c, nil
+	}
+	return nil, &UnsupportedError{ContentType: contentType}
+}
+
+// ContentTypes returns the registered content types, sorted.
+func (r *Registry) ContentTypes() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]string, 0, len(r.codecs))
+	for ct := range r.codecs {
+		out = append(out, ct)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// UnsupportedError is returned when no codec is registered for a content type.
+type UnsupportedError struct {
+	ContentType string
+}
+
+func (e *UnsupportedError) Error() string {
+	return "unsupported media type: " + e.ContentType
+}
+
+func (e *UnsupportedError) Unwrap() error { return ErrUnsupportedMediaType }
+
+func normalize(contentType string) string {
+	if mt, _, err := mime.ParseMediaType(contentType); err == nil {
+		return mt
+	}
+	return strings.ToLower(strings.TrimSpace(contentType))
+}
+
+// Default is the process-wide registry used by bindings when no other
+// registry is configured. It initially contains JSON and XML.
+var Default = NewRegistry(JSON, XML)
+
+// Register adds c to the Default registry.
+func Register(c Codec) { Default.Register(c) }
+
+// Lookup returns the codec registered for contentType in the Default registry.
+func Lookup(contentType string) (Codec, bool) { return Default.Lookup(contentType) }
+
+// Marshal encodes v to a byte slice using c.
+func Marshal(c Codec, v any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := c.Encode(&buf, v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Unmarshal decodes data into v using c.
+func Unmarshal(c Codec, data []byte, v any) error {
+	return c.Decode(bytes.NewReader(data), v)
+}
+
+// JSON is a codec for application/json, using encoding/json.
+var JSON Codec = jsonCodec{}
+
+type jsonCodec struct{}
+
+func (jsonCodec) ContentType() string { return "application/json" }
+
+func (jsonCodec) Encode(w io.Writer, v any) error {
+	return json.NewEncoder(w).Encode(v)
+}
+
+func (jsonCodec) Decode(r io.Reader, v any) error {
+	return json.NewDecoder(r).Decode(v)
+}
+
+// XML is a codec for application/xml, using encoding/xml.
+var XML Codec = xmlCodec{}
+
+type xmlCodec struct{}
+
+func (xmlCodec) ContentType() string { return "application/xml" }
+
+func (xmlCodec) Encode(w io.Writer, v any) error {
+	return xml.NewEncoder(w).Encode(v)
+}
+
+func (xmlCodec) Decode(r io.Reader, v any) error {
+	return xml.NewDecoder(r).Decode(v)
+}
@@ -0,0 +1,75 @@
+package codec
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistry_Lookup(t *testing.T) {
+	reg := NewRegistry(JSON, fakeCodec("application/msgpack"))
+
+	for _, ct := range []string{"application/json", "application/json; charset=utf-8", "Application/JSON", " application/json "} {
+		c, ok := reg.Lookup(ct)
+		if assert.True(t, ok, ct) {
+			assert.Equal(t, JSON, c, ct)
+		}
+	}
+
+	_, ok := reg.Lookup("application/xml")
+	assert.False(t, ok)
+}
+
+func TestRegistry_Register_Replaces(t *testing.T) {
+	reg := NewRegistry(JSON)
+	replacement := fakeCodec("application/json; charset=utf-8")
+	reg.Register(replacement)
+
+	c, _ := reg.Lookup("application/json")
+	assert.Equal(t, replacement, c)
+	assert.Equal(t, []string{"application/json"}, reg.ContentTypes())
+}
+
+func TestRegistry_Find(t *testing.T) {
+	reg := NewRegistry(JSON)
+
+	c, err := reg.Find("application/json")
+	assert.NoError(t, err)
+	assert.Equal(t, JSON, c)
+
+	_, err = reg.Find("text/csv")
+	assert.ErrorIs(t, err, ErrUnsupportedMediaType)
+	var unsupported *UnsupportedError
+	if assert.ErrorAs(t, err, &unsupported) {
+		assert.Equal(t, "text/csv", unsupported.ContentType)
+	}
+}
+
+func TestRegistry_ContentTypes(t *testing.T) {
+	reg := NewRegistry(XML, fakeCodec("application/msgpack"), JSON)
+	assert.Equal(t, []string{"application/json", "application/msgpack", "application/xml"}, reg.ContentTypes())
+}
+
+func TestDefault(t *testing.T) {
+	for _, ct := range []string{"application/json", "application/xml"} {
+		_, ok := Lookup(ct)
+		assert.True(t, ok, ct)
+	}
+}
+
+func TestMarshalUnmarshal(t *testing.T) {
+	type item struct {
+		Name string `json:"name" xml:"name"`
+		Qty  int    `json:"qty" xml:"qty"`
+	}
+
+	for _, c := range []Codec{JSON, XML} {
+		data, err := Marshal(c, &item{Name: "widget", Qty: 2})
+		if !assert.NoError(t, err, c.ContentType()) {
+			continue
+		}
+		var out item
+		assert.NoError(t, Unmarshal(c, data, &out), c.ContentType())
+		assert.Equal(t, item{Name: "widget", Qty: 2}, out, c.ContentType())
+	}
+}
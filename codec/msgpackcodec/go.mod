@@ -0,0 +1,11 @@
+module github.com/jaz303/operator/codec/msgpackcodec
+
+go 1.25.1
+
+require (
+	github.com/jaz303/operator v0.0.0
+	github.com/vmihailenco/msgpack/v5 v5.4.1
+)
+
+require github.com/vmihailenco/tagparser/v2 v2.0.0 // indirect
+
@@ -0,0 +1,35 @@
+// Package msgpackcodec provides a MessagePack codec for operator's codec
+// registry. It is distributed as a separate module to avoid imposing its
+// dependencies on users who do not need it.
+//
+// Register it at startup to make MessagePack available to every binding:
+//
+//	codec.Register(msgpackcodec.Codec)
+package msgpackcodec
+
+import (
+	"io"
+
+	"github.com/jaz303/operator/codec"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Codec encodes and decodes application/msgpack. Struct fields are named
+// according to their `msgpack` tags, falling back to `json` tags.
+var Codec codec.Codec = msgpackCodec{}
+
+type msgpackCodec struct{}
+
+func (msgpackCodec) ContentType() string { return "application/msgpack" }
+
+func (msgpackCodec) Encode(w io.Writer, v any) error {
+	enc := msgpack.NewEncoder(w)
+	enc.SetCustomStructTag("json")
+	return enc.Encode(v)
+}
+
+func (msgpackCodec) Decode(r io.Reader, v any) error {
+	dec := msgpack.NewDecoder(r)
+	dec.SetCustomStructTag("json")
+	return dec.Decode(v)
+}
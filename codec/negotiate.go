@@ -0,0 +1,141 @@
+package codec
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Negotiate selects the codec best matching an HTTP Accept header, honouring
+// quality values and wildcards. An empty header, or one accepting */*, selects
+// JSON if registered. Media types excluded with q=0, such as
+// "application/json;q=0" or "text/*;q=0", are never selected by a wildcard.
+//
+// Returns an error wrapping ErrNotAcceptable if no registered codec is
+// acceptable.
+func (r *Registry) Negotiate(accept string) (Codec, error) {
+	if strings.TrimSpace(accept) == "" {
+		accept = "*/*"
+	}
+
+	ranges := parseAccept(accept)
+	excluded := map[string]bool{}
+	for _, mr := range ranges {
+		if mr.q <= 0 {
+			excluded[mr.mediaType] = true
+		}
+	}
+
+	for _, mr := range ranges {
+		if mr.q <= 0 {
+			continue
+		}
+		if c := r.match(mr.mediaType, excluded); c != nil {
+			return c, nil
+		}
+	}
+
+	return nil, &NotAcceptableError{Accept: accept}
+}
+
+// match returns the codec for the media range pattern, which, if a wildcard,
+// matches no media type in excluded, nor any type matching an excluded
+// subtype wildcard such as "text/*".
+func (r *Registry) match(pattern string, excluded map[string]bool) Codec {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if c, ok := r.codecs[pattern]; ok {
+		return c
+	}
+
+	major, minor, _ := strings.Cut(pattern, "/")
+	if minor != "*" {
+		return nil
+	}
+
+	acceptable := func(ct string) bool {
+		m, _, _ := strings.Cut(ct, "/")
+		return !excluded[ct] && !excluded[m+"/*"]
+	}
+
+	if major == "*" {
+		if c, ok := r.codecs[JSON.ContentType()]; ok && acceptable(JSON.ContentType()) {
+			return c
+		}
+	}
+
+	// Deterministic choice among multiple candidates
+	var candidates []string
+	for ct := range r.codecs {
+		if (major == "*" || strings.HasPrefix(ct, major+"/")) && acceptable(ct) {
+			candidates = append(candidates, ct)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	sort.Strings(candidates)
+	return r.codecs[candidates[0]]
+}
+
+// NotAcceptableError is returned when no codec satisfies an Accept header.
+type NotAcceptableError struct {
+	Accept string
+}
+
+func (e *NotAcceptableError) Error() string {
+	return "no acceptable media type for: " + e.Accept
+}
+
+func (e *NotAcceptableError) Unwrap() error { return ErrNotAcceptable }
+
+type mediaRange struct {
+	mediaType   string
+	q           float64
+	specificity int
+	order       int
+}
+
+// parseAccept parses an Accept header into media ranges, ordered by
+// preference.
+func parseAccept(accept string) []mediaRange {
+	var out []mediaRange
+	for ix, part := range strings.Split(accept, ",") {
+		fields := strings.Split(part, ";")
+		mt := strings.ToLower(strings.TrimSpace(fields[0]))
+		if mt == "" {
+			continue
+		}
+		mr := mediaRange{mediaType: mt, q: 1, order: ix}
+		for _, p := range fields[1:] {
+			k, v, _ := strings.Cut(strings.TrimSpace(p), "=")
+			if strings.EqualFold(k, "q") {
+				if q, err := strconv.ParseFloat(v, 64); err == nil {
+					mr.q = q
+				}
+			}
+		}
+		switch {
+		case mt == "*/*":
+			mr.specificity = 0
+		case strings.HasSuffix(mt, "/*"):
+			mr.specificity = 1
+		default:
+			mr.specificity = 2
+		}
+		out = append(out, mr)
+	}
+
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].q != out[j].q {
+			return out[i].q > out[j].q
+		}
+		if out[i].specificity != out[j].specificity {
+			return out[i].specificity > out[j].specificity
+		}
+		return out[i].order < out[j].order
+	})
+
+	return out
+}
@@ -0,0 +1,67 @@
+package codec
+
+import (
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeCodec string
+
+func (c fakeCodec) ContentType() string           { return string(c) }
+func (fakeCodec) Encode(w io.Writer, v any) error { return nil }
+func (fakeCodec) Decode(r io.Reader, v any) error { return nil }
+
+func TestNegotiate(t *testing.T) {
+	reg := NewRegistry(JSON, XML, fakeCodec("application/msgpack"))
+
+	cases := map[string]string{
+		"":                                      "application/json",
+		"*/*":                                   "application/json",
+		"application/xml":                       "application/xml",
+		"application/msgpack, application/json": "application/msgpack",
+		"application/json;q=0.5, application/msgpack;q=0.9": "application/msgpack",
+		"text/html, application/*;q=0.2":                    "application/json",
+		"text/html, application/xml;q=0.1, */*;q=0.05":      "application/xml",
+		"*/*, application/json;q=0":                         "application/msgpack",
+		"application/*, application/json;q=0":               "application/msgpack",
+		"*/*, application/*;q=0":                            "",
+	}
+
+	for accept, expected := range cases {
+		c, err := reg.Negotiate(accept)
+		if expected == "" {
+			assert.ErrorIs(t, err, ErrNotAcceptable, accept)
+		} else if assert.Nil(t, err, accept) {
+			assert.Equal(t, expected, c.ContentType(), accept)
+		}
+	}
+}
+
+func TestNegotiate_NotAcceptable(t *testing.T) {
+	reg := NewRegistry(JSON)
+
+	_, err := reg.Negotiate("text/html, application/json;q=0")
+	assert.ErrorIs(t, err, ErrNotAcceptable)
+}
+
+func TestNegotiate_WildcardWithoutJSON(t *testing.T) {
+	reg := NewRegistry(fakeCodec("application/xml"), fakeCodec("application/msgpack"))
+
+	c, err := reg.Negotiate("*/*")
+	if assert.Nil(t, err) {
+		assert.Equal(t, "application/msgpack", c.ContentType(), "the first candidate, in order, is chosen")
+	}
+
+	c, err = reg.Negotiate("TEXT/HTML, Application/XML;Q=0.9")
+	if assert.Nil(t, err) {
+		assert.Equal(t, "application/xml", c.ContentType())
+	}
+
+	var notAcceptable *NotAcceptableError
+	_, err = reg.Negotiate("text/*")
+	if assert.ErrorAs(t, err, &notAcceptable) {
+		assert.Equal(t, "text/*", notAcceptable.Accept)
+	}
+}
@@ -0,0 +1,8 @@
+module github.com/jaz303/operator/codec/protocodec
+
+go 1.25.1
+
+require (
+	github.com/jaz303/operator v0.0.0
+	google.golang.org/protobuf v1.36.6
+)
@@ -0,0 +1,53 @@
+// Package protocodec provides a Protocol Buffers codec for operator's codec
+// registry. It is distributed as a separate module to avoid imposing its
+// dependencies on users who do not need it.
+//
+// Register it at startup to make Protobuf available to every binding:
+//
+//	codec.Register(protocodec.Codec)
+//
+// Only values implementing proto.Message can be encoded or decoded; for
+// bindings, this means the operation's input/output types must be generated
+// protobuf message types.
+package protocodec
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/jaz303/operator/codec"
+	"google.golang.org/protobuf/proto"
+)
+
+// Codec encodes and decodes application/x-protobuf using the binary wire
+// format.
+var Codec codec.Codec = protoCodec{}
+
+type protoCodec struct{}
+
+func (protoCodec) ContentType() string { return "application/x-protobuf" }
+
+func (protoCodec) Encode(w io.Writer, v any) error {
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("protocodec: %T does not implement proto.Message", v)
+	}
+	data, err := proto.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+func (protoCodec) Decode(r io.Reader, v any) error {
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("protocodec: %T does not implement proto.Message", v)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	return proto.Unmarshal(data, msg)
+}
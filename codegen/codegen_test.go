@@ -0,0 +1,206 @@
+package codegen
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jaz303/operator"
+	"github.com/jaz303/operator/httpbind"
+	"github.com/jaz303/operator/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+type nopTx struct{}
+
+func (nopTx) Commit(context.Context) error   { return nil }
+func (nopTx) Rollback(context.Context) error { return nil }
+
+// User is a registered user.
+type User struct {
+	ID    int64  `json:"id"`
+	Email string `json:"email"`
+	Role  string `json:"role,omitempty" validate:"oneof=admin member"`
+}
+
+type UpdateUser struct {
+	ID      int64    `json:"id"`
+	Email   string   `json:"email,omitempty"`
+	Tags    []string `json:"tags,omitempty" query:"tag"`
+	TraceID string   `json:"-" header:"X-Trace-ID"`
+	Version string   `json:"version,omitempty" header:"If-Match"`
+}
+
+type GetUser struct {
+	ID int64 `path:"id" json:"userId"`
+}
+
+func UpdateUserOp(ctx *operator.OpContext[nopTx], in *UpdateUser) (*User, error) { return nil, nil }
+func GetUserOp(ctx *operator.OpContext[nopTx], in *GetUser) (*User, error)       { return nil, nil }
+func Ping(ctx *operator.OpContext[nopTx], in *struct{}) (*struct{}, error)       { return in, nil }
+
+func routes() []httpbind.Route {
+	hub := operator.NewHub(func(context.Context) (nopTx, error) { return nopTx{}, nil })
+	svc := httpbind.NewService(hub, "/api")
+	httpbind.Handle(svc, "PUT /users/{id}", UpdateUserOp)
+	httpbind.Handle(svc, "GET /users/{id}", GetUserOp)
+	httpbind.Handle(svc, "/ping", Ping)
+	httpbind.Handle(svc, "POST /anon", func(ctx *operator.OpContext[nopTx], in *string) (*int, error) { return nil, nil })
+	return svc.Routes()
+}
+
+func TestNewManifest(t *testing.T) {
+	m := NewManifest(routes())
+	assert.Len(t, m.Routes, 4)
+
+	update := m.Routes[0]
+	assert.Equal(t, "updateUserOp", update.Name)
+	assert.Equal(t, "PUT", update.Method)
+	assert.Equal(t, "/api/users/{id}", update.Path)
+	assert.Equal(t, []Param{
+		{In: "query", Name: "tag", Property: "tags", Field: "Tags"},
+		{In: "header", Name: "If-Match", Property: "version", Field: "Version"},
+		{In: "path", Name: "id", Property: "id", Field: "ID"},
+	}, update.Params)
+	assert.Equal(t, &schema.Schema{Ref: "#/$defs/UpdateUser"}, update.Input)
+	assert.Equal(t, &schema.Schema{Ref: "#/$defs/User"}, update.Output)
+
+	assert.Equal(t, []Param{{In: "path", Name: "id", Property: "userId", Field: "ID"}}, m.Routes[1].Params)
+	assert.Equal(t, "ping", m.Routes[2].Name)
+	assert.Equal(t, "postApiAnon", m.Routes[3].Name)
+	assert.Contains(t, m.Defs, "User")
+
+	data, err := json.Marshal(m)
+	assert.Nil(t, err)
+	var decoded Manifest
+	assert.Nil(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, m.Defs["User"].PropertyOrder, decoded.Defs["User"].PropertyOrder)
+}
+
+func TestNewManifest_DuplicateNames(t *testing.T) {
+	r := httpbind.Route{Method: "GET", Path: "/a", Operation: "x.Get", Input: reflect.TypeFor[struct{}](), Output: reflect.TypeFor[struct{}]()}
+	m := NewManifest([]httpbind.Route{r, r})
+	assert.Equal(t, "get", m.Routes[0].Name)
+	assert.Equal(t, "get2", m.Routes[1].Name)
+}
+
+func TestTypeScript(t *testing.T) {
+	src, err := TypeScript(NewManifest(routes()))
+	assert.Nil(t, err)
+	ts := string(src)
+
+	assert.Contains(t, ts, "// Code generated by operator-gen. DO NOT EDIT.")
+	assert.Contains(t, ts, "export interface User {\n\tid: number;\n\temail: string;\n\trole?: \"admin\" | \"member\";\n}")
+	assert.Contains(t, ts, "export interface UpdateUser {\n\tid: number;\n\temail?: string;\n\ttags?: string[];\n\tversion?: string;\n}")
+
+	assert.Contains(t, ts, "\t/** PUT /api/users/{id} (github.com/jaz303/operator/codegen.UpdateUserOp) */\n\tupdateUserOp(input: UpdateUser): Promise<User> {")
+	assert.Contains(t, ts, "\t\tappendQuery(query, \"tag\", input[\"tags\"]);\n\t\tdelete body[\"tags\"];")
+	assert.Contains(t, ts, "\t\tif (input[\"version\"] !== undefined) headers[\"If-Match\"] = String(input[\"version\"]);")
+	assert.Contains(t, ts, "return this.request(\"PUT\", `/api/users/${encodeURIComponent(String(input[\"id\"]))}`, query, headers, body);")
+
+	assert.Contains(t, ts, "\tping(): Promise<Record<string, never>> {\n\t\treturn this.request(\"POST\", `/api/ping`, new URLSearchParams(), {}, undefined);")
+	assert.Contains(t, ts, "\tpostApiAnon(input: string): Promise<number> {\n\t\treturn this.request(\"POST\", `/api/anon`, new URLSearchParams(), {}, input);")
+}
+
+func TestTSPath(t *testing.T) {
+	params := []Param{{In: "path", Name: "p", Property: "p"}}
+	assert.Equal(t, "`/files/${String(input[\"p\"]).split(\"/\").map(encodeURIComponent).join(\"/\")}`", tsPath("/files/{p...}", params))
+	assert.Equal(t, "`/a/`", tsPath("/a/{$}", nil))
+}
+
+type page[T any] struct {
+	Items []T `json:"items"`
+}
+
+func TestGoTypes(t *testing.T) {
+	g := newGoTypes()
+	assert.Equal(t, "codegen.User", g.expr(reflect.TypeFor[User]()))
+	assert.Equal(t, "[]*codegen.User", g.expr(reflect.TypeFor[[]*User]()))
+	assert.Equal(t, "map[string][2]int", g.expr(reflect.TypeFor[map[string][2]int]()))
+	assert.Equal(t, "codegen.page[codegen.User]", g.expr(reflect.TypeFor[page[User]]()))
+	assert.Equal(t, "schema.Schema", g.expr(reflect.TypeFor[schema.Schema]()))
+	assert.Equal(t, "struct {\nID int `json:\"id\"`\n}", g.expr(reflect.TypeFor[struct {
+		ID int `json:"id"`
+	}]()))
+	assert.Equal(t, map[string]string{
+		"codegen": "github.com/jaz303/operator/codegen",
+		"schema":  "github.com/jaz303/operator/schema",
+	}, g.imports)
+
+	assert.Equal(t, "context2", g.qualify("example.com/context", "context"))
+	assert.Equal(t, "users", packageName("example.com/go-users/v2"))
+}
+
+func TestGo(t *testing.T) {
+	m := NewManifest(routes())
+	assert.Equal(t, "codegen.UpdateUser", m.Routes[0].GoInput)
+	assert.Equal(t, "codegen.User", m.Routes[0].GoOutput)
+	assert.Equal(t, map[string]string{"codegen": "github.com/jaz303/operator/codegen"}, m.GoImports)
+
+	src, err := Go(m, "usersapi")
+	assert.Nil(t, err)
+	code := string(src)
+
+	assert.Contains(t, code, "package usersapi\n")
+	assert.Contains(t, code, "\t\"github.com/jaz303/operator/codegen\"\n")
+	assert.Contains(t, code, "\tUpdateUser = codegen.UpdateUser\n")
+	assert.Contains(t, code, "// UpdateUserOp calls PUT /api/users/{id} (github.com/jaz303/operator/codegen.UpdateUserOp).\n"+
+		"func (c *Client) UpdateUserOp(ctx context.Context, in *UpdateUser) (*User, error) {\n"+
+		"\treq := &opclient.Request{Method: \"PUT\", Path: \"/api/users/\" + opclient.PathValue(in.ID, false), Body: in, Query: url.Values{}, Header: http.Header{}}\n"+
+		"\tfor _, v := range opclient.Values(in.Tags) {\n\t\treq.Query.Add(\"tag\", v)\n\t}\n"+
+		"\tfor _, v := range opclient.Values(in.Version) {\n\t\treq.Header.Add(\"If-Match\", v)\n\t}\n")
+	assert.Contains(t, code, "func (c *Client) Ping(ctx context.Context) error {\n")
+	assert.Contains(t, code, "func (c *Client) PostApiAnon(ctx context.Context, in string) (int, error) {\n")
+
+	m.GoImports["codegen"] = "main"
+	_, err = Go(m, "usersapi")
+	assert.NotNil(t, err)
+}
+
+func TestGoPath(t *testing.T) {
+	r := &Route{Path: "/files/{p...}", Params: []Param{{In: "path", Name: "p", Field: "P"}}}
+	path, err := goPath(r)
+	assert.Nil(t, err)
+	assert.Equal(t, `"/files/" + opclient.PathValue(in.P, true)`, path)
+
+	path, err = goPath(&Route{Path: "/a/{$}"})
+	assert.Nil(t, err)
+	assert.Equal(t, `"/a/"`, path)
+
+	_, err = goPath(&Route{Path: "/a/{id}"})
+	assert.NotNil(t, err)
+}
+
+func TestGenerateDispatchers(t *testing.T) {
+	if testing.Short() {
+		t.Skip("type-checks dependencies from source")
+	}
+
+	out, err := GenerateDispatchers(".", "./testdata/dispatch")
+	assert.Nil(t, err)
+	assert.Len(t, out, 1)
+	d := out[0]
+	assert.Equal(t, "app", d.Package)
+
+	src := string(d.Source)
+	assert.Contains(t, src, "// Code generated by operator-gen handlers. DO NOT EDIT.\n\npackage app\n")
+	assert.Contains(t, src, "import (\n\t\"context\"\n\n\t\"github.com/jaz303/operator\"\n)")
+	assert.Contains(t, src, "\toperator.RegisterDispatcher(func(hnd func(*operator.OpContext[Tx], *UserCreated) error) func(context.Context, any) error {\n"+
+		"\t\treturn func(ctx context.Context, evt any) error {\n"+
+		"\t\t\te, _ := evt.(*UserCreated)\n"+
+		"\t\t\treturn hnd(ctx.(*operator.OpContext[Tx]), e)\n")
+	assert.Contains(t, src, "func(hnd func(*UserCreated)) func(context.Context, any) error {\n"+
+		"\t\treturn func(ctx context.Context, evt any) error {\n"+
+		"\t\t\te, _ := evt.(*UserCreated)\n"+
+		"\t\t\thnd(e)\n\t\t\treturn nil\n")
+	assert.Contains(t, src, "func(hnd func(context.Context, operator.Event) error)")
+	assert.Contains(t, src, "func(hnd Handler)")
+	assert.Equal(t, 1, strings.Count(src, "func(hnd func(*operator.OpContext[Tx], *UserCreated) error)"))
+
+	assert.Equal(t, []string{
+		"app.go:30: handler of type func(evt *UserCreated) local returns a type other than error",
+		"app.go:34: handler of type func(ctx *github.com/jaz303/operator.OpContext[Tx], evt *UserCreated) involves type parameter Tx",
+	}, d.Skipped)
+}
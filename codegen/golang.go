@@ -0,0 +1,245 @@
+package codegen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Go generates the source of a Go package named pkg, declaring a Client type
+// with a method for each of m's routes, which sends requests with
+// opclient.Client. The named types of the routes' inputs and outputs are
+// re-exported from the package as aliases.
+func Go(m *Manifest, pkg string) ([]byte, error) {
+	w := &goWriter{
+		m:        m,
+		aliases:  map[string]string{},
+		taken:    map[string]bool{"Client": true, "NewClient": true},
+		imported: map[string]bool{},
+	}
+	for path := range maps.Values(m.GoImports) {
+		if path == "main" {
+			return nil, fmt.Errorf("operation types are declared in package main, which cannot be imported")
+		}
+	}
+
+	var methods bytes.Buffer
+	for _, r := range m.Routes {
+		if err := w.route(&methods, &r); err != nil {
+			return nil, err
+		}
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by operator-gen. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&b, "package %s\n\n", pkg)
+	b.WriteString("import (\n\t\"context\"\n")
+	if w.usesHeader {
+		b.WriteString("\t\"net/http\"\n")
+	}
+	if w.usesQuery {
+		b.WriteString("\t\"net/url\"\n")
+	}
+	b.WriteString("\n\t\"github.com/jaz303/operator/opclient\"\n")
+	for _, name := range slices.Sorted(maps.Keys(m.GoImports)) {
+		if !w.imported[name] {
+			continue
+		}
+		if path := m.GoImports[name]; name == packageName(path) {
+			fmt.Fprintf(&b, "\t%q\n", path)
+		} else {
+			fmt.Fprintf(&b, "\t%s %q\n", name, path)
+		}
+	}
+	b.WriteString(")\n\n")
+
+	if len(w.aliases) > 0 {
+		b.WriteString("type (\n")
+		for _, name := range slices.Sorted(maps.Keys(w.aliases)) {
+			fmt.Fprintf(&b, "\t%s = %s\n", name, w.aliases[name])
+		}
+		b.WriteString(")\n\n")
+	}
+
+	b.WriteString(goClient)
+	b.Write(methods.Bytes())
+
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting client failed (%w)", err)
+	}
+	return src, nil
+}
+
+type goWriter struct {
+	m *Manifest
+
+	// Aliases declared for named types, keyed by name, and the names taken
+	aliases map[string]string
+	taken   map[string]bool
+
+	imported              map[string]bool
+	usesQuery, usesHeader bool
+}
+
+var namedGoType = regexp.MustCompile(`^(\w+)\.(\w+)(\[.*\])?$`)
+
+// typ returns the type to use for the Go type expression expr, aliasing it
+// if named, and reports whether it was.
+func (w *goWriter) typ(expr string) (string, bool) {
+	for _, m := range qualifiedImport.FindAllStringSubmatch(expr, -1) {
+		if _, ok := w.m.GoImports[m[1]]; ok {
+			w.imported[m[1]] = true
+		}
+	}
+
+	m := namedGoType.FindStringSubmatch(expr)
+	if m == nil {
+		return expr, false
+	}
+	for name, target := range w.aliases {
+		if target == expr {
+			return name, true
+		}
+	}
+
+	base := identifier(m[2] + qualifiedImport.ReplaceAllString(m[3], "$2"))
+	name := base
+	if w.taken[name] {
+		name = identifier(m[1]) + base
+	}
+	for i := 2; w.taken[name]; i++ {
+		name = identifier(m[1]) + base + strconv.Itoa(i)
+	}
+	w.taken[name] = true
+	w.aliases[name] = expr
+	return name, true
+}
+
+var qualifiedImport = regexp.MustCompile(`\b(\w+)\.(\w+)`)
+
+func (w *goWriter) route(b *bytes.Buffer, r *Route) error {
+	if r.GoInput == "" || r.GoOutput == "" {
+		return fmt.Errorf("route %s: manifest has no Go types", r.Name)
+	}
+	method := r.Method
+	if method == "" {
+		method = "POST"
+	}
+
+	in, named := w.typ(r.GoInput)
+	if named {
+		in = "*" + in
+	}
+	out, named := w.typ(r.GoOutput)
+	returnsValue := out != "struct{}"
+
+	name := identifier(r.Name)
+	fmt.Fprintf(b, "\n// %s calls %s %s (%s).\n", name, method, r.Path, r.Operation)
+	fmt.Fprintf(b, "func (c *Client) %s(ctx context.Context", name)
+	if hasInput(r.Input) {
+		fmt.Fprintf(b, ", in %s", in)
+	}
+	if returnsValue {
+		ret := out
+		if named {
+			ret = "*" + out
+		}
+		fmt.Fprintf(b, ") (%s, error) {\n", ret)
+	} else {
+		b.WriteString(") error {\n")
+	}
+
+	path, err := goPath(r)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(b, "\treq := &opclient.Request{Method: %q, Path: %s", method, path)
+	if hasInput(r.Input) {
+		b.WriteString(", Body: in")
+	}
+	if slices.ContainsFunc(r.Params, func(p Param) bool { return p.In == "query" }) {
+		b.WriteString(", Query: url.Values{}")
+		w.usesQuery = true
+	}
+	if slices.ContainsFunc(r.Params, func(p Param) bool { return p.In == "header" }) {
+		b.WriteString(", Header: http.Header{}")
+		w.usesHeader = true
+	}
+	b.WriteString("}\n")
+
+	for _, p := range r.Params {
+		if p.In == "path" {
+			continue
+		}
+		target := map[string]string{"query": "req.Query", "header": "req.Header"}[p.In]
+		if p.Field == "" || target == "" {
+			return fmt.Errorf("route %s: cannot send %s parameter %s", r.Name, p.In, p.Name)
+		}
+		fmt.Fprintf(b, "\tfor _, v := range opclient.Values(in.%s) {\n\t\t%s.Add(%q, v)\n\t}\n", p.Field, target, p.Name)
+	}
+
+	switch {
+	case !returnsValue:
+		b.WriteString("\treturn c.c.Do(ctx, req, nil)\n}\n")
+	case named:
+		fmt.Fprintf(b, "\tvar out %s\n", out)
+		b.WriteString("\tif err := c.c.Do(ctx, req, &out); err != nil {\n\t\treturn nil, err\n\t}\n")
+		b.WriteString("\treturn &out, nil\n}\n")
+	default:
+		fmt.Fprintf(b, "\tvar out %s\n", out)
+		b.WriteString("\terr := c.c.Do(ctx, req, &out)\n\treturn out, err\n}\n")
+	}
+	return nil
+}
+
+// goPath returns an expression producing r's path with its parameters
+// substituted from the input.
+func goPath(r *Route) (string, error) {
+	path := r.Path
+	var parts []string
+	for {
+		start := strings.IndexByte(path, '{')
+		end := strings.IndexByte(path[max(start, 0):], '}')
+		if start < 0 || end < 0 {
+			break
+		}
+		literal := path[:start]
+		name := strings.TrimSuffix(path[start+1:start+end], "...")
+		wildcard := strings.HasSuffix(path[start+1:start+end], "...")
+		path = path[start+end+1:]
+		if name == "$" {
+			parts = append(parts, strconv.Quote(literal))
+			continue
+		}
+		i := slices.IndexFunc(r.Params, func(p Param) bool { return p.In == "path" && p.Name == name })
+		if i < 0 || r.Params[i].Field == "" {
+			return "", fmt.Errorf("route %s: no input field for path parameter %s", r.Name, name)
+		}
+		parts = append(parts, strconv.Quote(literal), fmt.Sprintf("opclient.PathValue(in.%s, %t)", r.Params[i].Field, wildcard))
+	}
+	if path != "" || len(parts) == 0 {
+		parts = append(parts, strconv.Quote(path))
+	}
+	parts = slices.DeleteFunc(parts, func(s string) bool { return s == `""` })
+	if len(parts) == 0 {
+		return `""`, nil
+	}
+	return strings.Join(parts, " + "), nil
+}
+
+const goClient = `// Client calls the API's operations.
+type Client struct {
+	c *opclient.Client
+}
+
+// NewClient returns a Client that sends requests with c.
+func NewClient(c *opclient.Client) *Client {
+	return &Client{c: c}
+}
+`
@@ -0,0 +1,146 @@
+package codegen
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// goTypes formats Go types as source expressions, qualifying named types
+// with import names it assigns to their packages.
+type goTypes struct {
+	imports map[string]string // name -> path
+	names   map[string]string // path -> name
+}
+
+// reservedImports are the names of the packages generated clients import
+// themselves.
+var reservedImports = []string{"context", "http", "url", "opclient"}
+
+func newGoTypes() *goTypes {
+	return &goTypes{imports: map[string]string{}, names: map[string]string{}}
+}
+
+// expr returns the Go expression for t.
+func (g *goTypes) expr(t reflect.Type) string {
+	if t.Name() != "" {
+		if t.PkgPath() == "" {
+			return t.Name()
+		}
+		pkg, _, _ := strings.Cut(t.String(), ".")
+		name, args, generic := strings.Cut(t.Name(), "[")
+		s := g.qualify(t.PkgPath(), pkg) + "." + name
+		if generic {
+			s += "[" + g.typeArgs(strings.TrimSuffix(args, "]")) + "]"
+		}
+		return s
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return "*" + g.expr(t.Elem())
+	case reflect.Slice:
+		return "[]" + g.expr(t.Elem())
+	case reflect.Array:
+		return fmt.Sprintf("[%d]%s", t.Len(), g.expr(t.Elem()))
+	case reflect.Map:
+		return "map[" + g.expr(t.Key()) + "]" + g.expr(t.Elem())
+	case reflect.Struct:
+		return g.structExpr(t)
+	case reflect.Interface:
+		if t.NumMethod() == 0 {
+			return "any"
+		}
+	}
+	return t.String()
+}
+
+func (g *goTypes) structExpr(t reflect.Type) string {
+	if t.NumField() == 0 {
+		return "struct{}"
+	}
+	var b strings.Builder
+	b.WriteString("struct {\n")
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if !f.Anonymous {
+			b.WriteString(f.Name + " ")
+		}
+		b.WriteString(g.expr(f.Type))
+		if f.Tag != "" {
+			tag := string(f.Tag)
+			if strings.Contains(tag, "`") {
+				tag = strconv.Quote(tag)
+			} else {
+				tag = "`" + tag + "`"
+			}
+			b.WriteString(" " + tag)
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("}")
+	return b.String()
+}
+
+// qualifiedName matches the package-qualified names in reflect's names of
+// instantiated generic types, e.g. "example.com/users.User".
+var qualifiedName = regexp.MustCompile(`([\w.~/-]+)\.(\w+)`)
+
+// typeArgs qualifies the type arguments in the name of a generic type.
+func (g *goTypes) typeArgs(args string) string {
+	return qualifiedName.ReplaceAllStringFunc(args, func(s string) string {
+		m := qualifiedName.FindStringSubmatch(s)
+		return g.qualify(m[1], packageName(m[1])) + "." + m[2]
+	})
+}
+
+// qualify returns the import name for path, assigning one based on pkg if
+// it has none.
+func (g *goTypes) qualify(path, pkg string) string {
+	if name, ok := g.names[path]; ok {
+		return name
+	}
+	name := pkg
+	for i := 2; g.taken(name); i++ {
+		name = pkg + strconv.Itoa(i)
+	}
+	g.imports[name] = path
+	g.names[path] = name
+	return name
+}
+
+func (g *goTypes) taken(name string) bool {
+	if _, ok := g.imports[name]; ok {
+		return true
+	}
+	for _, r := range reservedImports {
+		if r == name {
+			return true
+		}
+	}
+	return false
+}
+
+var majorVersion = regexp.MustCompile(`^v\d+$`)
+
+// packageName guesses the name of the package at path from its last
+// element, skipping major version suffixes.
+func packageName(path string) string {
+	elems := strings.Split(path, "/")
+	name := elems[len(elems)-1]
+	if majorVersion.MatchString(name) && len(elems) > 1 {
+		name = elems[len(elems)-2]
+	}
+	name = strings.Map(func(r rune) rune {
+		if r == '_' || 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9' {
+			return r
+		}
+		return -1
+	}, strings.TrimPrefix(name, "go-"))
+	if name == "" {
+		return "pkg"
+	}
+	return name
+}
@@ -0,0 +1,426 @@
+package codegen
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// DispatcherFile is the name of the files generated by GenerateDispatchers.
+const DispatcherFile = "operator_dispatch.go"
+
+const operatorPath = "github.com/jaz303/operator"
+
+// Dispatchers is the generated dispatcher file of a package.
+type Dispatchers struct {
+	// Directory and name of the package
+	Dir     string
+	Package string
+
+	// Source of the file; nil if the package registers no handlers that can
+	// be dispatched statically
+	Source []byte
+
+	// Handlers that cannot be dispatched statically, as "file:line: reason"
+	Skipped []string
+}
+
+// GenerateDispatchers loads the packages matching patterns, as understood by
+// "go list", relative to dir, and generates for each a file registering,
+// with operator.RegisterDispatcher(), a dispatcher for each type of event
+// handler the package passes to Hub.RegisterEventHandler() or
+// Hub.AttachEventHandler(). Handlers registered from test files are not
+// included.
+//
+// Handlers whose types cannot be named in the package - those involving type
+// parameters, or unexported types of other packages - and handlers returning
+// a type other than error, are skipped, and continue to be dispatched with
+// reflection.
+func GenerateDispatchers(dir string, patterns ...string) ([]Dispatchers, error) {
+	pkgs, err := listPackages(dir, patterns)
+	if err != nil {
+		return nil, err
+	}
+
+	fset := token.NewFileSet()
+	imp := importer.ForCompiler(fset, "source", nil)
+	var out []Dispatchers
+	for _, lp := range pkgs {
+		d, err := generateDispatchers(fset, imp, lp)
+		if err != nil {
+			return nil, fmt.Errorf("package %s: %w", lp.ImportPath, err)
+		}
+		out = append(out, *d)
+	}
+	return out, nil
+}
+
+type listedPackage struct {
+	Dir        string
+	ImportPath string
+	Name       string
+	GoFiles    []string
+	Error      *struct{ Err string }
+}
+
+func listPackages(dir string, patterns []string) ([]listedPackage, error) {
+	cmd := exec.Command("go", append([]string{"list", "-e", "-json=Dir,ImportPath,Name,GoFiles,Error", "--"}, patterns...)...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	data, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("go list failed (%w): %s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+
+	var pkgs []listedPackage
+	dec := json.NewDecoder(bytes.NewReader(data))
+	for {
+		var p listedPackage
+		if err := dec.Decode(&p); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		if p.Error != nil {
+			return nil, fmt.Errorf("package %s: %s", p.ImportPath, p.Error.Err)
+		}
+		pkgs = append(pkgs, p)
+	}
+	return pkgs, nil
+}
+
+func generateDispatchers(fset *token.FileSet, imp types.Importer, lp listedPackage) (*Dispatchers, error) {
+	d := &Dispatchers{Dir: lp.Dir, Package: lp.Name}
+	if lp.ImportPath == operatorPath {
+		// RegisterDispatcher() cannot be called qualified from within
+		// package operator itself
+		return d, nil
+	}
+
+	var files []*ast.File
+	for _, name := range lp.GoFiles {
+		if name == DispatcherFile {
+			// may be stale, and is regenerated
+			continue
+		}
+		f, err := parser.ParseFile(fset, filepath.Join(lp.Dir, name), nil, parser.SkipObjectResolution)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+
+	info := &types.Info{Types: map[ast.Expr]types.TypeAndValue{}}
+	conf := types.Config{Importer: imp}
+	pkg, err := conf.Check(lp.ImportPath, fset, files, info)
+	if err != nil {
+		return nil, err
+	}
+
+	w := newDispatchWriter(pkg)
+	for _, f := range files {
+		ast.Inspect(f, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok || len(call.Args) < 2 || !isHandlerRegistration(info, call) {
+				return true
+			}
+			if reason := w.add(info.TypeOf(call.Args[1])); reason != "" {
+				pos := fset.Position(call.Args[1].Pos())
+				d.Skipped = append(d.Skipped, fmt.Sprintf("%s:%d: %s", filepath.Base(pos.Filename), pos.Line, reason))
+			}
+			return true
+		})
+	}
+
+	if len(w.shims) > 0 {
+		if d.Source, err = w.source(); err != nil {
+			return nil, err
+		}
+	}
+	return d, nil
+}
+
+// isHandlerRegistration returns true if call is a call to
+// Hub.RegisterEventHandler() or Hub.AttachEventHandler().
+func isHandlerRegistration(info *types.Info, call *ast.CallExpr) bool {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || (sel.Sel.Name != "RegisterEventHandler" && sel.Sel.Name != "AttachEventHandler") {
+		return false
+	}
+	return isNamed(info.TypeOf(sel.X), operatorPath, "Hub")
+}
+
+type dispatchWriter struct {
+	pkg     *types.Package
+	imports map[string]string // path -> name
+	names   map[string]bool
+	pkgs    map[string]string // path -> package name
+	shims   []string
+	seen    []types.Type
+}
+
+func newDispatchWriter(pkg *types.Package) *dispatchWriter {
+	return &dispatchWriter{
+		pkg:     pkg,
+		imports: map[string]string{"context": "context", operatorPath: "operator"},
+		names:   map[string]bool{"context": true, "operator": true},
+		pkgs:    map[string]string{"context": "context", operatorPath: "operator"},
+	}
+}
+
+var errorType = types.Universe.Lookup("error").Type()
+
+// add adds a dispatcher for handlers of type t, if it does not already have
+// one, or returns the reason it cannot.
+func (w *dispatchWriter) add(t types.Type) string {
+	name := types.TypeString(t, types.RelativeTo(w.pkg))
+	sig, ok := t.Underlying().(*types.Signature)
+	if !ok {
+		return fmt.Sprintf("handler of type %s is not a function", name)
+	}
+	if slices.ContainsFunc(w.seen, func(s types.Type) bool { return types.Identical(s, t) }) {
+		return ""
+	}
+	if sig.Variadic() || sig.Params().Len() < 1 || sig.Params().Len() > 2 || sig.Results().Len() > 1 {
+		return fmt.Sprintf("handler of type %s has an invalid signature", name)
+	}
+	if sig.Results().Len() == 1 && !types.Identical(sig.Results().At(0).Type(), errorType) {
+		return fmt.Sprintf("handler of type %s returns a type other than error", name)
+	}
+	if reason := w.nameable(t); reason != "" {
+		return fmt.Sprintf("handler of type %s %s", name, reason)
+	}
+	if ctx := sig.Params().At(0).Type(); sig.Params().Len() == 2 && !types.IsInterface(ctx) && !isNamed(ctx, operatorPath, "OpContext") {
+		return fmt.Sprintf("handler of type %s has context parameter of type %s", name, ctx)
+	}
+
+	qual := w.qualifier()
+	params := sig.Params()
+	evt := types.TypeString(params.At(params.Len()-1).Type(), qual)
+
+	var b bytes.Buffer
+	hnd := funcType(sig, qual)
+	if _, named := t.(*types.Named); named {
+		// dispatchers are looked up by the handler's exact type
+		hnd = types.TypeString(t, qual)
+	}
+	fmt.Fprintf(&b, "operator.RegisterDispatcher(func(hnd %s) func(context.Context, any) error {\n", hnd)
+	b.WriteString("return func(ctx context.Context, evt any) error {\n")
+	fmt.Fprintf(&b, "e, _ := evt.(%s)\n", evt)
+	args := "e"
+	if params.Len() == 2 {
+		if ctx := params.At(0).Type(); isNamed(ctx, "context", "Context") {
+			args = "ctx, e"
+		} else {
+			args = fmt.Sprintf("ctx.(%s), e", types.TypeString(ctx, qual))
+		}
+	}
+	if sig.Results().Len() == 1 {
+		fmt.Fprintf(&b, "return hnd(%s)\n", args)
+	} else {
+		fmt.Fprintf(&b, "hnd(%s)\nreturn nil\n", args)
+	}
+	b.WriteString("}\n})\n")
+
+	w.seen = append(w.seen, t)
+	w.shims = append(w.shims, b.String())
+	return ""
+}
+
+// funcType returns the source of the function type of sig, without
+// parameter names.
+func funcType(sig *types.Signature, qual types.Qualifier) string {
+	var b bytes.Buffer
+	b.WriteString("func(")
+	for i := range sig.Params().Len() {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(types.TypeString(sig.Params().At(i).Type(), qual))
+	}
+	b.WriteString(")")
+	if sig.Results().Len() == 1 {
+		b.WriteString(" error")
+	}
+	return b.String()
+}
+
+// isNamed returns true if t is the named type, or a pointer to the named
+// type, pkg.name.
+func isNamed(t types.Type, pkg, name string) bool {
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	named, ok := t.(*types.Named)
+	if !ok {
+		return false
+	}
+	obj := named.Obj()
+	return obj.Pkg() != nil && obj.Pkg().Path() == pkg && obj.Name() == name
+}
+
+// qualifier returns a qualifier naming packages other than w.pkg with the
+// names they are imported as, assigning names as necessary.
+func (w *dispatchWriter) qualifier() types.Qualifier {
+	return func(p *types.Package) string {
+		if p == w.pkg {
+			return ""
+		}
+		if name, ok := w.imports[p.Path()]; ok {
+			return name
+		}
+		name := p.Name()
+		for i := 2; w.names[name] || w.pkg.Scope().Lookup(name) != nil; i++ {
+			name = p.Name() + strconv.Itoa(i)
+		}
+		w.imports[p.Path()] = name
+		w.names[name] = true
+		w.pkgs[p.Path()] = p.Name()
+		return name
+	}
+}
+
+// nameable returns the reason t cannot be named in w.pkg, if it cannot.
+func (w *dispatchWriter) nameable(t types.Type) string {
+	switch t := t.(type) {
+	case *types.Basic:
+		return ""
+	case *types.Named:
+		obj := t.Obj()
+		if obj.Pkg() != nil {
+			if obj.Parent() != obj.Pkg().Scope() {
+				return "involves type " + obj.Name() + ", which is declared in a function"
+			} else if obj.Pkg() != w.pkg && !obj.Exported() {
+				return "involves unexported type " + obj.Pkg().Name() + "." + obj.Name()
+			}
+		}
+		for i := range t.TypeArgs().Len() {
+			if reason := w.nameable(t.TypeArgs().At(i)); reason != "" {
+				return reason
+			}
+		}
+		return ""
+	case *types.Alias:
+		return w.nameable(types.Unalias(t))
+	case *types.TypeParam:
+		return "involves type parameter " + t.Obj().Name()
+	case *types.Pointer:
+		return w.nameable(t.Elem())
+	case *types.Slice:
+		return w.nameable(t.Elem())
+	case *types.Array:
+		return w.nameable(t.Elem())
+	case *types.Chan:
+		return w.nameable(t.Elem())
+	case *types.Map:
+		if reason := w.nameable(t.Key()); reason != "" {
+			return reason
+		}
+		return w.nameable(t.Elem())
+	case *types.Signature:
+		for _, tuple := range []*types.Tuple{t.Params(), t.Results()} {
+			for i := range tuple.Len() {
+				if reason := w.nameable(tuple.At(i).Type()); reason != "" {
+					return reason
+				}
+			}
+		}
+		return ""
+	case *types.Struct:
+		for i := range t.NumFields() {
+			f := t.Field(i)
+			if !f.Exported() && f.Pkg() != w.pkg {
+				return "involves a struct with unexported field " + f.Name()
+			}
+			if reason := w.nameable(f.Type()); reason != "" {
+				return reason
+			}
+		}
+		return ""
+	case *types.Interface:
+		for i := range t.NumMethods() {
+			m := t.Method(i)
+			if !m.Exported() && m.Pkg() != w.pkg {
+				return "involves an interface with unexported method " + m.Name()
+			}
+			if reason := w.nameable(m.Type()); reason != "" {
+				return reason
+			}
+		}
+		return ""
+	default:
+		return "involves unsupported type " + t.String()
+	}
+}
+
+func (w *dispatchWriter) source() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteString("// Code generated by operator-gen handlers. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&b, "package %s\n\nimport (\n", w.pkg.Name())
+	paths := make([]string, 0, len(w.imports))
+	for path := range w.imports {
+		paths = append(paths, path)
+	}
+	slices.Sort(paths)
+	// standard library packages first
+	slices.SortStableFunc(paths, func(a, b string) int {
+		return cmpBool(!isStdlib(a), !isStdlib(b))
+	})
+	for i, path := range paths {
+		if i > 0 && isStdlib(paths[i-1]) && !isStdlib(path) {
+			b.WriteString("\n")
+		}
+		if name := w.imports[path]; name == w.pkgs[path] {
+			fmt.Fprintf(&b, "%q\n", path)
+		} else {
+			fmt.Fprintf(&b, "%s %q\n", name, path)
+		}
+	}
+	b.WriteString(")\n\n")
+	b.WriteString("// init registers dispatchers for the package's event handlers, so that\n")
+	b.WriteString("// they are dispatched without reflection.\n")
+	b.WriteString("func init() {\n")
+	for i, shim := range w.shims {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(shim)
+	}
+	b.WriteString("}\n")
+
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting dispatchers failed (%w)", err)
+	}
+	return src, nil
+}
+
+func isStdlib(path string) bool {
+	first, _, _ := strings.Cut(path, "/")
+	return !strings.Contains(first, ".")
+}
+
+func cmpBool(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case a:
+		return 1
+	default:
+		return -1
+	}
+}
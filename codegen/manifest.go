@@ -0,0 +1,261 @@
+// Package codegen generates typed API clients for operations bound to HTTP
+// routes, so that clients' request and response types cannot drift from the
+// operations' input and output structs.
+//
+// Generation runs in two steps. First, the application describes its routes
+// in a Manifest - typically from a test, or a flag that dumps it and exits -
+// since route registrations only exist in the running program:
+//
+//	m := codegen.NewManifest(svc.Routes())
+//	json.NewEncoder(f).Encode(m)
+//
+// Then the operator-gen command (cmd/operator-gen) reads the manifest and
+// writes a client, in TypeScript or Go:
+//
+//	//go:generate go run github.com/jaz303/operator/cmd/operator-gen ts -manifest api.json -out web/src/api.ts
+//	//go:generate go run github.com/jaz303/operator/cmd/operator-gen go -manifest api.json -package usersapi -out usersapi/client.go
+//
+// Go clients use the operations' own input and output types, re-exported
+// from the generated package, and send requests with package opclient.
+// Those types must therefore be importable, rather than declared in package
+// main.
+//
+// Routes bound with echobind are described with its Invoker.Route method.
+//
+// Clients send requests the way httpbind.BindRequest reads them: fields
+// tagged `path`, `query` and `header` are sent as path parameters, query
+// parameters and headers, and the remaining fields as a JSON body. Path
+// parameters with no tagged field are taken from the input property of the
+// same name.
+//
+// The package also generates event handler dispatchers, which let a hub
+// invoke handlers without reflection; see GenerateDispatchers.
+package codegen
+
+import (
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/jaz303/operator/httpbind"
+	"github.com/jaz303/operator/schema"
+)
+
+// Manifest describes an API's routes and the types they use.
+type Manifest struct {
+	Routes []Route `json:"routes"`
+
+	// Named types referenced by the routes' schemas
+	Defs map[string]*schema.Schema `json:"$defs,omitempty"`
+
+	// Import paths of the packages referenced by the routes' Go types, keyed
+	// by the names they are qualified with
+	GoImports map[string]string `json:"goImports,omitempty"`
+}
+
+// Route describes a single route.
+type Route struct {
+	// Name of the client method for the route
+	Name string `json:"name"`
+
+	// Fully-qualified name of the operation's Go function
+	Operation string `json:"operation"`
+
+	// HTTP method; empty if the route matches any method
+	Method string `json:"method,omitempty"`
+
+	// Path pattern, in http.ServeMux syntax
+	Path string `json:"path"`
+
+	// Input fields sent other than in the body
+	Params []Param `json:"params,omitempty"`
+
+	Input  *schema.Schema `json:"input"`
+	Output *schema.Schema `json:"output"`
+	Tags   []string       `json:"tags,omitempty"`
+
+	// Go types of the input and output, e.g. "users.User"
+	GoInput  string `json:"goInput,omitempty"`
+	GoOutput string `json:"goOutput,omitempty"`
+}
+
+// Param is an input field sent as a path parameter, query parameter or
+// header.
+type Param struct {
+	// "path", "query" or "header"
+	In string `json:"in"`
+
+	// Name of the parameter or header
+	Name string `json:"name"`
+
+	// Name of the input property holding the value
+	Property string `json:"property"`
+
+	// Selector of the Go input field holding the value, e.g. "Page.Limit"
+	Field string `json:"field,omitempty"`
+}
+
+// NewManifest returns a manifest describing routes.
+func NewManifest(routes []httpbind.Route, opts ...schema.Option) *Manifest {
+	g := schema.NewGenerator(opts...)
+	types := newGoTypes()
+	m := &Manifest{Routes: []Route{}}
+	names := map[string]int{}
+	for _, r := range routes {
+		route := Route{
+			Operation: r.Operation,
+			Method:    r.Method,
+			Path:      r.Path,
+			Params:    params(r.Input, r.Path),
+			Input:     g.Schema(r.Input),
+			Output:    g.Schema(r.Output),
+			Tags:      r.Tags,
+			GoInput:   types.expr(deref(r.Input)),
+			GoOutput:  types.expr(deref(r.Output)),
+		}
+		route.Name = methodName(r)
+		if n := names[route.Name]; n > 0 {
+			names[route.Name]++
+			route.Name += strconv.Itoa(n + 1)
+		} else {
+			names[route.Name] = 1
+		}
+		m.Routes = append(m.Routes, route)
+	}
+	m.Defs = g.Defs()
+	if len(types.imports) > 0 {
+		m.GoImports = types.imports
+	}
+	return m
+}
+
+var anonymousFunc = regexp.MustCompile(`^func\d+$`)
+
+// methodName derives a client method name from the operation's function
+// name, e.g. "users.CreateUser" becomes "createUser", or for anonymous
+// functions, from the route; e.g. "GET /users/{id}" becomes "getUsersId".
+func methodName(r httpbind.Route) string {
+	name := r.Operation[strings.LastIndex(r.Operation, "/")+1:]
+	parts := strings.Split(name, ".")
+	name = strings.TrimSuffix(parts[len(parts)-1], "-fm")
+	if name != "" && !anonymousFunc.MatchString(name) {
+		return lowerFirst(identifier(name))
+	}
+
+	method := strings.ToLower(r.Method)
+	if method == "" {
+		method = "call"
+	}
+	return method + identifier(r.Path)
+}
+
+// identifier converts s to an exported camel-case Go/TypeScript identifier,
+// dropping non-alphanumeric characters.
+func identifier(s string) string {
+	var b strings.Builder
+	for _, word := range strings.FieldsFunc(s, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) }) {
+		r := []rune(word)
+		r[0] = unicode.ToUpper(r[0])
+		b.WriteString(string(r))
+	}
+	return b.String()
+}
+
+func lowerFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	r := []rune(s)
+	r[0] = unicode.ToLower(r[0])
+	return string(r)
+}
+
+// pathParams returns the names of the parameters in path.
+func pathParams(path string) []string {
+	var out []string
+	for {
+		start := strings.IndexByte(path, '{')
+		if start < 0 {
+			return out
+		}
+		end := strings.IndexByte(path[start:], '}')
+		if end < 0 {
+			return out
+		}
+		name := strings.TrimSuffix(path[start+1:start+end], "...")
+		if name != "$" {
+			out = append(out, name)
+		}
+		path = path[start+end+1:]
+	}
+}
+
+// params returns the input fields of t sent other than in the body: fields
+// tagged `path`, `query` or `header`, and for path parameters with no tagged
+// field, the field whose JSON name matches the parameter.
+func params(t reflect.Type, path string) []Param {
+	t = deref(t)
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var out []Param
+	var untagged []Param
+	var walk func(t reflect.Type, prefix string)
+	walk = func(t reflect.Type, prefix string) {
+		for i := range t.NumField() {
+			f := t.Field(i)
+			if f.Anonymous && f.Type.Kind() == reflect.Struct {
+				walk(f.Type, prefix+f.Name+".")
+				continue
+			} else if !f.IsExported() {
+				continue
+			}
+			property, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if property == "-" {
+				continue
+			} else if property == "" {
+				property = f.Name
+			}
+			tagged := false
+			for _, in := range []string{"path", "query", "header"} {
+				if name, ok := f.Tag.Lookup(in); ok && name != "" && name != "-" {
+					name, _, _ = strings.Cut(name, ",")
+					out = append(out, Param{In: in, Name: name, Property: property, Field: prefix + f.Name})
+					tagged = true
+					break
+				}
+			}
+			if !tagged {
+				untagged = append(untagged, Param{In: "path", Property: property, Field: prefix + f.Name})
+			}
+		}
+	}
+	walk(t, "")
+
+next:
+	for _, name := range pathParams(path) {
+		for _, p := range out {
+			if p.In == "path" && p.Name == name {
+				continue next
+			}
+		}
+		for _, p := range untagged {
+			if strings.EqualFold(p.Property, name) {
+				p.Name = name
+				out = append(out, p)
+				continue next
+			}
+		}
+	}
+	return out
+}
+
+func deref(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
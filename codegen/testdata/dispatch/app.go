@@ -0,0 +1,35 @@
+package app
+
+import (
+	"context"
+
+	"github.com/jaz303/operator"
+)
+
+type Tx struct{}
+
+func (Tx) Commit(context.Context) error   { return nil }
+func (Tx) Rollback(context.Context) error { return nil }
+
+type UserCreated struct{ ID int }
+
+func (*UserCreated) EventName() string { return "user.created" }
+
+type Handler func(ctx context.Context, evt *UserCreated) error
+
+func audit(ctx *operator.OpContext[Tx], evt *UserCreated) error { return nil }
+
+func Register(hub *operator.Hub[Tx]) {
+	hub.RegisterEventHandler(&UserCreated{}, audit)
+	hub.RegisterEventHandler(&UserCreated{}, audit)
+	hub.RegisterEventHandler(&UserCreated{}, func(evt *UserCreated) {})
+	hub.RegisterEventHandler(&UserCreated{}, func(ctx context.Context, evt operator.Event) error { return nil })
+	hub.AttachEventHandler(&UserCreated{}, Handler(func(ctx context.Context, evt *UserCreated) error { return nil }))
+
+	type local struct{}
+	hub.RegisterEventHandler(&UserCreated{}, func(evt *UserCreated) local { return local{} })
+}
+
+func RegisterGeneric[Tx operator.Transaction](hub *operator.Hub[Tx]) {
+	hub.RegisterEventHandler(&UserCreated{}, func(ctx *operator.OpContext[Tx], evt *UserCreated) {})
+}
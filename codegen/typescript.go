@@ -0,0 +1,330 @@
+package codegen
+
+import (
+	"bytes"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/jaz303/operator/schema"
+)
+
+// TypeScript generates a TypeScript module declaring an interface for each of
+// m's named types, and a Client class with a method for each route, which
+// sends requests with fetch.
+func TypeScript(m *Manifest) ([]byte, error) {
+	w := &tsWriter{m: m}
+	w.printf("// Code generated by operator-gen. DO NOT EDIT.\n\n")
+
+	for _, name := range slices.Sorted(maps.Keys(m.Defs)) {
+		if err := w.def(name, m.Defs[name]); err != nil {
+			return nil, err
+		}
+	}
+
+	w.printf("%s", tsRuntime)
+	w.printf("export class Client {\n")
+	w.printf("\tconstructor(private readonly options: ClientOptions = {}) {}\n")
+	for _, r := range m.Routes {
+		if err := w.route(&r); err != nil {
+			return nil, err
+		}
+	}
+	w.printf("%s}\n", tsRequest)
+	return w.buf.Bytes(), nil
+}
+
+type tsWriter struct {
+	m   *Manifest
+	buf bytes.Buffer
+}
+
+func (w *tsWriter) printf(format string, args ...any) {
+	fmt.Fprintf(&w.buf, format, args...)
+}
+
+func (w *tsWriter) doc(indent, text string) {
+	if text == "" {
+		return
+	}
+	lines := strings.Split(strings.ReplaceAll(text, "*/", "* /"), "\n")
+	if len(lines) == 1 {
+		w.printf("%s/** %s */\n", indent, lines[0])
+		return
+	}
+	w.printf("%s/**\n", indent)
+	for _, l := range lines {
+		w.printf("%s * %s\n", indent, l)
+	}
+	w.printf("%s */\n", indent)
+}
+
+func (w *tsWriter) def(name string, s *schema.Schema) error {
+	w.doc("", s.Description)
+	if s.Type == "object" && s.AdditionalProperties == nil {
+		w.printf("export interface %s ", name)
+	} else {
+		w.printf("export type %s = ", name)
+	}
+	t, err := w.typ(s, "")
+	if err != nil {
+		return fmt.Errorf("type %s: %w", name, err)
+	}
+	w.printf("%s\n\n", t)
+	return nil
+}
+
+// typ returns the TypeScript type for s, indented for nesting beneath indent.
+func (w *tsWriter) typ(s *schema.Schema, indent string) (string, error) {
+	if s.Ref != "" {
+		name, ok := strings.CutPrefix(s.Ref, "#/$defs/")
+		if !ok {
+			return "", fmt.Errorf("unsupported reference %s", s.Ref)
+		}
+		return name, nil
+	}
+	if len(s.Enum) > 0 {
+		vals := make([]string, len(s.Enum))
+		for i, v := range s.Enum {
+			b, err := jsonLiteral(v)
+			if err != nil {
+				return "", err
+			}
+			vals[i] = b
+		}
+		return strings.Join(vals, " | "), nil
+	}
+
+	switch s.Type {
+	case "string":
+		return "string", nil
+	case "integer", "number":
+		return "number", nil
+	case "boolean":
+		return "boolean", nil
+	case "array":
+		item, err := w.typ(s.Items, indent)
+		if err != nil {
+			return "", err
+		}
+		if strings.ContainsAny(item, " |") {
+			item = "(" + item + ")"
+		}
+		return item + "[]", nil
+	case "object":
+		if s.AdditionalProperties != nil {
+			v, err := w.typ(s.AdditionalProperties, indent)
+			if err != nil {
+				return "", err
+			}
+			return "Record<string, " + v + ">", nil
+		}
+		if len(s.Properties) == 0 {
+			return "Record<string, never>", nil
+		}
+		return w.object(s, indent)
+	case "":
+		return "unknown", nil
+	default:
+		return "", fmt.Errorf("unsupported type %s", s.Type)
+	}
+}
+
+func (w *tsWriter) object(s *schema.Schema, indent string) (string, error) {
+	var b strings.Builder
+	b.WriteString("{\n")
+	for _, name := range propertyOrder(s) {
+		prop := s.Properties[name]
+		t, err := w.typ(prop, indent+"\t")
+		if err != nil {
+			return "", fmt.Errorf("property %s: %w", name, err)
+		}
+		if prop.Description != "" {
+			fmt.Fprintf(&b, "%s\t/** %s */\n", indent, strings.ReplaceAll(strings.ReplaceAll(prop.Description, "*/", "* /"), "\n", " "))
+		}
+		opt := "?"
+		if slices.Contains(s.Required, name) {
+			opt = ""
+		}
+		fmt.Fprintf(&b, "%s\t%s%s: %s;\n", indent, tsKey(name), opt, t)
+	}
+	b.WriteString(indent + "}")
+	return b.String(), nil
+}
+
+// propertyOrder returns the names of s's properties in declaration order if
+// known, and otherwise sorted.
+func propertyOrder(s *schema.Schema) []string {
+	if len(s.PropertyOrder) == len(s.Properties) {
+		return s.PropertyOrder
+	}
+	return slices.Sorted(maps.Keys(s.Properties))
+}
+
+var tsIdentifier = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+func tsKey(name string) string {
+	if tsIdentifier.MatchString(name) {
+		return name
+	}
+	return strconv.Quote(name)
+}
+
+func jsonLiteral(v any) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return strconv.Quote(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	default:
+		return "", fmt.Errorf("unsupported enum value %v", v)
+	}
+}
+
+// hasInput returns true if s describes an input the client must supply.
+func hasInput(s *schema.Schema) bool {
+	return s.Ref != "" || s.Type != "object" || len(s.Properties) > 0 || s.AdditionalProperties != nil
+}
+
+func (w *tsWriter) route(r *Route) error {
+	in, err := w.typ(r.Input, "\t")
+	if err != nil {
+		return fmt.Errorf("route %s: %w", r.Name, err)
+	}
+	out, err := w.typ(r.Output, "\t")
+	if err != nil {
+		return fmt.Errorf("route %s: %w", r.Name, err)
+	}
+
+	method := r.Method
+	if method == "" {
+		method = "POST"
+	}
+
+	w.printf("\n")
+	w.doc("\t", strings.TrimSpace(method+" "+r.Path+" ("+r.Operation+")"))
+	if !hasInput(r.Input) {
+		w.printf("\t%s(): Promise<%s> {\n", r.Name, out)
+		w.printf("\t\treturn this.request(%q, %s, new URLSearchParams(), {}, undefined);\n", method, tsPath(r.Path, nil))
+		w.printf("\t}\n")
+		return nil
+	}
+
+	w.printf("\t%s(input: %s): Promise<%s> {\n", r.Name, in, out)
+	if len(r.Params) == 0 {
+		w.printf("\t\treturn this.request(%q, %s, new URLSearchParams(), {}, input);\n", method, tsPath(r.Path, nil))
+		w.printf("\t}\n")
+		return nil
+	}
+
+	w.printf("\t\tconst body: Record<string, unknown> = { ...input };\n")
+	w.printf("\t\tconst query = new URLSearchParams();\n")
+	w.printf("\t\tconst headers: Record<string, string> = {};\n")
+	for _, p := range r.Params {
+		prop := "input[" + strconv.Quote(p.Property) + "]"
+		switch p.In {
+		case "query":
+			w.printf("\t\tappendQuery(query, %q, %s);\n", p.Name, prop)
+		case "header":
+			w.printf("\t\tif (%s !== undefined) headers[%q] = String(%s);\n", prop, p.Name, prop)
+		}
+		w.printf("\t\tdelete body[%q];\n", p.Property)
+	}
+	w.printf("\t\treturn this.request(%q, %s, query, headers, body);\n", method, tsPath(r.Path, r.Params))
+	w.printf("\t}\n")
+	return nil
+}
+
+// tsPath returns a template literal producing path with its parameters
+// substituted from the input.
+func tsPath(path string, params []Param) string {
+	var b strings.Builder
+	b.WriteByte('`')
+	for {
+		start := strings.IndexByte(path, '{')
+		end := strings.IndexByte(path[max(start, 0):], '}')
+		if start < 0 || end < 0 {
+			break
+		}
+		b.WriteString(escapeTemplate(path[:start]))
+		name := strings.TrimSuffix(path[start+1:start+end], "...")
+		wildcard := strings.HasSuffix(path[start+1:start+end], "...")
+		path = path[start+end+1:]
+		if name == "$" {
+			continue
+		}
+		for _, p := range params {
+			if p.In == "path" && p.Name == name {
+				prop := "input[" + strconv.Quote(p.Property) + "]"
+				if wildcard {
+					fmt.Fprintf(&b, "${String(%s).split(\"/\").map(encodeURIComponent).join(\"/\")}", prop)
+				} else {
+					fmt.Fprintf(&b, "${encodeURIComponent(String(%s))}", prop)
+				}
+			}
+		}
+	}
+	b.WriteString(escapeTemplate(path))
+	b.WriteByte('`')
+	return b.String()
+}
+
+func escapeTemplate(s string) string {
+	return strings.NewReplacer("\\", "\\\\", "`", "\\`", "${", "\\${").Replace(s)
+}
+
+const tsRuntime = `export interface ClientOptions {
+	/** Base URL of the API, e.g. "https://api.example.com" */
+	baseUrl?: string;
+	/** fetch implementation; defaults to the global fetch */
+	fetch?: typeof fetch;
+	/** Headers sent with every request */
+	headers?: Record<string, string>;
+}
+
+/** Error thrown when the API responds with a non-2xx status. */
+export class OperatorError extends Error {
+	constructor(
+		readonly status: number,
+		readonly body: unknown,
+	) {
+		super(` + "`request failed with status ${status}`" + `);
+	}
+}
+
+function appendQuery(query: URLSearchParams, name: string, value: unknown) {
+	if (value === undefined || value === null) return;
+	for (const v of Array.isArray(value) ? value : [value]) query.append(name, String(v));
+}
+
+`
+
+const tsRequest = `
+	private async request<O>(
+		method: string,
+		path: string,
+		query: URLSearchParams,
+		headers: Record<string, string>,
+		body: unknown,
+	): Promise<O> {
+		const qs = query.toString();
+		const init: RequestInit = { method, headers: { ...this.options.headers, ...headers } };
+		if (body !== undefined && method !== "GET" && method !== "HEAD") {
+			init.body = JSON.stringify(body);
+			(init.headers as Record<string, string>)["Content-Type"] = "application/json";
+		}
+		const res = await (this.options.fetch ?? fetch)((this.options.baseUrl ?? "") + path + (qs ? "?" + qs : ""), init);
+		const text = await res.text();
+		let data: unknown = text;
+		try {
+			data = text ? JSON.parse(text) : undefined;
+		} catch {}
+		if (!res.ok) throw new OperatorError(res.status, data);
+		return data as O;
+	}
+`
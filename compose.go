@@ -0,0 +1,80 @@
+package operator
+
+// Compose() returns an operation that invokes a, converts its output to b's
+// input with glue, and invokes b. Both operations run within the same
+// OpContext and so share its transaction, events and AfterFuncs; if either
+// fails, or glue returns an error, the composed operation fails.
+//
+// Unless registered, the composed operation is named after a and b, e.g.
+// "Compose(users.CreateUser, mail.SendWelcome)".
+func Compose[Tx Transaction, A any, B any, C any, D any](a Operation[Tx, A, B], glue func(*B) (*C, error), b Operation[Tx, C, D]) Operation[Tx, A, D] {
+	return wrapOperation("Compose", []any{a, b}, []any{glue}, func(ctx *OpContext[Tx], input *A) (*D, error) {
+		mid, err := a(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		next, err := glue(mid)
+		if err != nil {
+			return nil, err
+		}
+		return b(ctx, next)
+	})
+}
+
+// AsOperation() adapts a TxOperation to an Operation that begins the
+// transaction before invoking op, for use with Compose() and Pipeline.
+func AsOperation[Tx Transaction, I any, O any](op TxOperation[Tx, I, O]) Operation[Tx, I, O] {
+	return wrapOperation("AsOperation", []any{op}, nil, func(ctx *OpContext[Tx], input *I) (*O, error) {
+		tx, err := ctx.Tx()
+		if err != nil {
+			return nil, err
+		}
+		return op(ctx, tx, input)
+	})
+}
+
+// Pipeline builds an operation from a sequence of stages, each receiving the
+// output of the previous one, all running within a single OpContext:
+//
+//	op := operator.Then(
+//		operator.Map(operator.Pipe(CreateUser), func(ctx *operator.OpContext[*sql.Tx], u *User) (*WelcomeInput, error) {
+//			return &WelcomeInput{UserID: u.ID}, nil
+//		}),
+//		SendWelcome,
+//	).Operation()
+//
+// Go does not permit methods with type parameters, so stages are appended
+// with the package-level Then() and Map() functions.
+type Pipeline[Tx Transaction, I any, O any] struct {
+	run Operation[Tx, I, O]
+}
+
+// Pipe() starts a pipeline with op.
+func Pipe[Tx Transaction, I any, O any](op Operation[Tx, I, O]) *Pipeline[Tx, I, O] {
+	return &Pipeline[Tx, I, O]{run: op}
+}
+
+// Then() returns a pipeline that passes p's output to op.
+func Then[Tx Transaction, I any, M any, O any](p *Pipeline[Tx, I, M], op Operation[Tx, M, O]) *Pipeline[Tx, I, O] {
+	run := p.run
+	return &Pipeline[Tx, I, O]{run: wrapOperation("Then", []any{run, op}, nil, func(ctx *OpContext[Tx], input *I) (*O, error) {
+		mid, err := run(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		return op(ctx, mid)
+	})}
+}
+
+// Map() returns a pipeline that converts p's output with fn. Unlike the glue
+// passed to Compose(), fn receives the OpContext.
+func Map[Tx Transaction, I any, M any, O any](p *Pipeline[Tx, I, M], fn func(ctx *OpContext[Tx], v *M) (*O, error)) *Pipeline[Tx, I, O] {
+	return Then(p, Operation[Tx, M, O](fn))
+}
+
+// Operation() returns the pipeline as an operation, which can be invoked or
+// registered like any other. Unless registered, it is named after its
+// stages, e.g. "Then(users.CreateUser, mail.SendWelcome)".
+func (p *Pipeline[Tx, I, O]) Operation() Operation[Tx, I, O] {
+	return p.run
+}
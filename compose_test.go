@@ -0,0 +1,70 @@
+package operator
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func double(ctx *OpContext[*TxTest], in *int) (*int, error) {
+	out := *in * 2
+	return &out, ctx.Emit(&testEvent{Val: out})
+}
+
+func format(ctx *OpContext[*TxTest], tx *TxTest, in *int) (*string, error) {
+	out := "n=" + strconv.Itoa(*in)
+	return &out, nil
+}
+
+func TestCompose(t *testing.T) {
+	var tx *TxTest
+	hub := NewHub(func(context.Context) (*TxTest, error) {
+		tx = &TxTest{}
+		return tx, nil
+	})
+	var events []int
+	hub.RegisterEventHandler(&testEvent{}, func(evt *testEvent) { events = append(events, evt.Val) })
+
+	op := Compose(double, func(v *int) (*int, error) {
+		out := *v + 1
+		return &out, nil
+	}, AsOperation(format))
+
+	in := 4
+	out, err := Invoke(context.Background(), hub, op, &in)
+	assert.NoError(t, err)
+	assert.Equal(t, "n=9", *out)
+	assert.Equal(t, []int{8}, events)
+	assert.True(t, tx.Committed)
+}
+
+func TestCompose_GlueError(t *testing.T) {
+	hub := newTestHub()
+	failed := errors.New("bad glue")
+
+	op := Compose(double, func(v *int) (*int, error) { return nil, failed }, double)
+
+	in := 1
+	_, err := Invoke(context.Background(), hub, op, &in)
+	assert.ErrorIs(t, err, failed)
+}
+
+func TestPipeline(t *testing.T) {
+	hub := newTestHub()
+
+	p := Then(
+		Map(Then(Pipe(double), double), func(ctx *OpContext[*TxTest], v *int) (*int, error) {
+			out := *v - 1
+			return &out, nil
+		}),
+		AsOperation(format),
+	)
+
+	in := 3
+	out, err := Invoke(context.Background(), hub, p.Operation(), &in)
+	assert.NoError(t, err)
+	assert.Equal(t, "n=11", *out)
+}
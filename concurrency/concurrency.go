@@ -0,0 +1,85 @@
+// Package concurrency helps operations implement optimistic concurrency
+// control: an update states the version of the resource it was based on, and
+// is rejected with an *operr.ConflictError if the resource has since changed.
+//
+//	type UpdateUser struct {
+//		concurrency.ExpectedVersion
+//		ID   int64
+//		Name string
+//	}
+//
+//	func UpdateUserOp(ctx *operator.OpContext[*opsql.Tx], in *UpdateUser) (*User, error) {
+//		user, err := loadUser(ctx, in.ID)
+//		if err != nil {
+//			return nil, err
+//		}
+//		if err := in.Check(user.Version); err != nil {
+//			return nil, err
+//		}
+//		...
+//	}
+//
+// Conflicts are mapped to 409 Conflict. When bound with httpbind, an
+// ExpectedVersion is also populated from the request's If-Match header, and
+// mismatches are reported as 412 Precondition Failed.
+package concurrency
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/jaz303/operator/operr"
+)
+
+// CheckVersion returns an *operr.ConflictError if expected is non-zero and
+// differs from current, and nil otherwise.
+func CheckVersion[V comparable](current, expected V) error {
+	var zero V
+	if expected == zero || current == expected {
+		return nil
+	}
+	return &operr.ConflictError{Current: fmt.Sprint(current), Expected: fmt.Sprint(expected)}
+}
+
+// ExpectedVersion can be embedded in an operation's input to carry the
+// version of the resource the client expects to modify. Versions are
+// compared in their string form, so any version type - an integer, a
+// timestamp, a content hash - may be checked.
+type ExpectedVersion struct {
+	// Expected version; if empty, any version is accepted
+	ExpectedVersion string `json:"expectedVersion,omitempty"`
+
+	// entity tags from If-Match, any of which is accepted
+	ifMatch []string
+}
+
+// SetIfMatch implements httpbind.IfMatchSetter. The tags listed in the
+// If-Match header replace ExpectedVersion; a wildcard accepts any version.
+func (e *ExpectedVersion) SetIfMatch(etags []string) {
+	if slices.Contains(etags, "*") {
+		e.ExpectedVersion, e.ifMatch = "", nil
+		return
+	}
+	e.ExpectedVersion, e.ifMatch = "", etags
+}
+
+// Expected reports whether a version is expected.
+func (e *ExpectedVersion) Expected() bool {
+	return e.ExpectedVersion != "" || len(e.ifMatch) > 0
+}
+
+// Check returns an *operr.ConflictError if a version is expected and current,
+// formatted with fmt.Sprint, is not it.
+func (e *ExpectedVersion) Check(current any) error {
+	if !e.Expected() {
+		return nil
+	}
+	cur := fmt.Sprint(current)
+	if len(e.ifMatch) > 0 {
+		if slices.Contains(e.ifMatch, cur) {
+			return nil
+		}
+		return &operr.ConflictError{Current: cur, Expected: e.ifMatch[0], IfMatch: true}
+	}
+	return CheckVersion(cur, e.ExpectedVersion)
+}
@@ -0,0 +1,38 @@
+package concurrency
+
+import (
+	"testing"
+
+	"github.com/jaz303/operator/operr"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckVersion(t *testing.T) {
+	assert.Nil(t, CheckVersion(3, 3))
+	assert.Nil(t, CheckVersion(3, 0), "zero expected version accepts any")
+
+	err := CheckVersion(int64(4), int64(3))
+	assert.ErrorIs(t, err, operr.ErrConflict)
+	assert.NotErrorIs(t, err, operr.ErrPreconditionFailed)
+	assert.Equal(t, 409, operr.StatusCode(err))
+	assert.EqualError(t, err, "version conflict; expected version 3, current version is 4")
+}
+
+func TestExpectedVersion(t *testing.T) {
+	var e ExpectedVersion
+	assert.False(t, e.Expected())
+	assert.Nil(t, e.Check(7))
+
+	e.ExpectedVersion = "7"
+	assert.Nil(t, e.Check(7))
+	assert.Equal(t, 409, operr.StatusCode(e.Check(8)))
+
+	e.SetIfMatch([]string{"5", "6"})
+	assert.Nil(t, e.Check(6))
+	err := e.Check(7)
+	assert.ErrorIs(t, err, operr.ErrConflict)
+	assert.Equal(t, 412, operr.StatusCode(err))
+
+	e.SetIfMatch([]string{"*"})
+	assert.False(t, e.Expected())
+}
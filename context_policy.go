@@ -0,0 +1,83 @@
+package operator
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// ContextPolicy determines how an operation's context is derived from the
+// context of the inbound request that triggered it. Bindings call the policy
+// with the request context before invoking the operation, and call the
+// returned CancelFunc once the operation has completed.
+type ContextPolicy func(parent context.Context) (context.Context, context.CancelFunc)
+
+// Background returns a policy that ignores the request context entirely and
+// runs the operation under context.Background(). Neither values nor
+// cancellation are propagated. This is the default policy for bindings,
+// unless the hub specifies another with WithContextPolicy().
+func Background() ContextPolicy {
+	return func(parent context.Context) (context.Context, context.CancelFunc) {
+		return context.Background(), func() {}
+	}
+}
+
+// Inherit returns a policy that runs the operation directly under the request
+// context. Request-scoped values are visible to the operation, and if the
+// client disconnects or the request deadline passes, the operation's context
+// is cancelled immediately.
+func Inherit() ContextPolicy {
+	return func(parent context.Context) (context.Context, context.CancelFunc) {
+		return context.WithCancel(parent)
+	}
+}
+
+// Detach returns a policy that propagates request-scoped values (trace IDs,
+// authentication etc.) to the operation but not cancellation; the operation
+// runs to completion even if the client goes away.
+func Detach() ContextPolicy {
+	return func(parent context.Context) (context.Context, context.CancelFunc) {
+		return context.WithCancel(context.WithoutCancel(parent))
+	}
+}
+
+// InheritWithGrace returns a policy that propagates request-scoped values,
+// and propagates cancellation only after the grace period has elapsed. This
+// gives an operation that is nearly complete the opportunity to commit when
+// the client disconnects, while still bounding runaway work.
+//
+// If the request context has a deadline, the operation's deadline is the
+// request deadline plus the grace period.
+func InheritWithGrace(grace time.Duration) ContextPolicy {
+	return func(parent context.Context) (context.Context, context.CancelFunc) {
+		ctx := context.WithoutCancel(parent)
+
+		cancelDeadline := context.CancelFunc(func() {})
+		if dl, ok := parent.Deadline(); ok {
+			ctx, cancelDeadline = context.WithDeadline(ctx, dl.Add(grace))
+		}
+
+		ctx, cancel := context.WithCancelCause(ctx)
+
+		var mu sync.Mutex
+		var timer *time.Timer
+		stop := context.AfterFunc(parent, func() {
+			mu.Lock()
+			defer mu.Unlock()
+			timer = time.AfterFunc(grace, func() {
+				cancel(context.Cause(parent))
+			})
+		})
+
+		return ctx, func() {
+			stop()
+			mu.Lock()
+			if timer != nil {
+				timer.Stop()
+			}
+			mu.Unlock()
+			cancel(context.Canceled)
+			cancelDeadline()
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package operator
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type ctxKey struct{}
+
+func TestContextPolicy_Background(t *testing.T) {
+	parent, cancel := context.WithCancel(context.WithValue(context.Background(), ctxKey{}, 1))
+	ctx, done := Background()(parent)
+	defer done()
+
+	cancel()
+	assert.Nil(t, ctx.Value(ctxKey{}))
+	assert.Nil(t, ctx.Err())
+}
+
+func TestContextPolicy_Inherit(t *testing.T) {
+	parent, cancel := context.WithCancel(context.WithValue(context.Background(), ctxKey{}, 1))
+	ctx, done := Inherit()(parent)
+	defer done()
+
+	assert.Equal(t, 1, ctx.Value(ctxKey{}))
+	cancel()
+	assert.ErrorIs(t, ctx.Err(), context.Canceled)
+}
+
+func TestContextPolicy_Detach(t *testing.T) {
+	parent, cancel := context.WithCancel(context.WithValue(context.Background(), ctxKey{}, 1))
+	ctx, done := Detach()(parent)
+
+	assert.Equal(t, 1, ctx.Value(ctxKey{}))
+	cancel()
+	assert.Nil(t, ctx.Err())
+
+	done()
+	assert.ErrorIs(t, ctx.Err(), context.Canceled)
+}
+
+func TestContextPolicy_InheritWithGrace(t *testing.T) {
+	parent, cancel := context.WithCancel(context.WithValue(context.Background(), ctxKey{}, 1))
+	ctx, done := InheritWithGrace(20 * time.Millisecond)(parent)
+	defer done()
+
+	assert.Equal(t, 1, ctx.Value(ctxKey{}))
+	cancel()
+	assert.Nil(t, ctx.Err())
+
+	select {
+	case <-ctx.Done():
+		assert.ErrorIs(t, ctx.Err(), context.Canceled)
+	case <-time.After(time.Second):
+		t.Fatal("context was not cancelled after grace period")
+	}
+}
+
+func TestContextPolicy_InheritWithGrace_ExtendsDeadline(t *testing.T) {
+	dl := time.Now().Add(time.Hour)
+	parent, cancel := context.WithDeadline(context.Background(), dl)
+	defer cancel()
+
+	ctx, done := InheritWithGrace(time.Minute)(parent)
+	defer done()
+
+	actual, ok := ctx.Deadline()
+	assert.True(t, ok)
+	assert.Equal(t, dl.Add(time.Minute), actual)
+}
+
+func TestHubContextPolicy(t *testing.T) {
+	parent := context.WithValue(context.Background(), ctxKey{}, 1)
+
+	ctx, done := newTestHub().ContextPolicy()(parent)
+	defer done()
+	assert.Nil(t, ctx.Value(ctxKey{}), "the default policy is Background()")
+
+	ctx, done = newTestHub(WithContextPolicy(Detach())).ContextPolicy()(parent)
+	defer done()
+	assert.Equal(t, 1, ctx.Value(ctxKey{}))
+}
@@ -0,0 +1,137 @@
+package operator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/jaz303/operator/deadletter"
+)
+
+// DeadLetterSource is the source of dead letters produced by a hub.
+const DeadLetterSource = "operator"
+
+var ErrNotRequeueable = errors.New("dead letter cannot be requeued")
+
+// Dead letter metadata keys, and values of metaKind.
+const (
+	metaKind      = "kind"
+	metaHandler   = "handler"
+	kindEvent     = "event"
+	kindOperation = "operation"
+)
+
+// followUp is background work scheduled by an operation.
+type followUp struct {
+	run func(context.Context) error
+
+	// event emitted with EmitAfterCommit(), or the name of, and input to, an
+	// operation scheduled with InvokeAfterCommit(); used to describe the work
+	// if it fails
+	event     Event
+	operation string
+	input     any
+
+	// name of the handler receiving event, if it was dispatched to a
+	// single handler in the Async phase
+	handler string
+}
+
+// deadLetter passes fu, which failed with err, to the hub's dead letter sink.
+func (h *Hub[Tx]) deadLetter(ctx context.Context, fu *followUp, attempts int, err error) {
+	if h.opts.deadLetters == nil {
+		return
+	}
+
+	l := &deadletter.Letter{
+		Source:      DeadLetterSource,
+		ContentType: "application/json",
+		Error:       err.Error(),
+		Attempts:    attempts,
+	}
+	var payload any
+	if fu.event != nil {
+		l.Name, l.Version = fu.event.EventName(), EventVersion(fu.event)
+		l.Metadata = map[string]string{metaKind: kindEvent}
+		if fu.handler != "" {
+			l.Metadata[metaHandler] = fu.handler
+		}
+		payload = fu.event
+	} else {
+		l.Name = fu.operation
+		l.Metadata = map[string]string{metaKind: kindOperation}
+		payload = fu.input
+	}
+
+	data, merr := json.Marshal(payload)
+	if merr != nil {
+		h.opts.onBackgroundError(fmt.Errorf("serializing dead letter %s failed (%w)", l.Name, merr))
+	}
+	l.Data = data
+
+	if perr := h.opts.deadLetters.Put(ctx, l); perr != nil {
+		h.opts.onBackgroundError(fmt.Errorf("storing dead letter %s failed (%w)", l.Name, perr))
+	}
+}
+
+// RequeueDeadLetter() schedules the work described by l, a dead letter
+// produced by this hub, to be attempted again on the hub's worker pool.
+//
+// Events are decoded with DecodeEvent(), so their types must be registered
+// with the hub. Operations can only be requeued if they were registered with
+// RegisterOperation() or RegisterTxOperation(); their input is decoded from
+// JSON. Returns ErrNotRequeueable if l cannot be requeued.
+//
+// Returns ErrShuttingDown once Shutdown() has been called.
+//
+// Use deadletter.Requeue() to requeue a letter and remove it from its store.
+func (h *Hub[Tx]) RequeueDeadLetter(ctx context.Context, l *deadletter.Letter) error {
+	if l.Source != DeadLetterSource {
+		return fmt.Errorf("%w: source is %q", ErrNotRequeueable, l.Source)
+	}
+
+	var fu *followUp
+	switch l.Metadata[metaKind] {
+	case kindEvent:
+		evt, err := h.DecodeEvent(l.Name, l.Version, l.Data)
+		if err != nil {
+			return err
+		}
+		fu = &followUp{
+			run: func(ctx context.Context) error {
+				_, err := Invoke(ctx, h, emitEvents[Tx], &[]Event{evt})
+				return err
+			},
+			event: evt,
+		}
+		if name := l.Metadata[metaHandler]; name != "" {
+			hnd, ok := h.asyncHandler(evt, name)
+			if !ok {
+				return fmt.Errorf("%w: event handler %s is not registered for event %s", ErrNotRequeueable, name, l.Name)
+			}
+			fu.run, fu.handler = h.asyncDispatch(hnd, evt), name
+		}
+	case kindOperation:
+		info := h.operations.lookup(l.Name)
+		if info == nil || info.invokeJSON == nil {
+			return fmt.Errorf("%w: operation %s is not registered", ErrNotRequeueable, l.Name)
+		}
+		data := l.Data
+		fu = &followUp{
+			run: func(ctx context.Context) error {
+				return info.invokeJSON(ctx, data)
+			},
+			operation: l.Name,
+			input:     json.RawMessage(data),
+		}
+	default:
+		return ErrNotRequeueable
+	}
+
+	if !h.enter(ctx) {
+		return ErrShuttingDown
+	}
+	h.runBackground(context.WithValue(context.WithoutCancel(ctx), backgroundKey{}, true), fu, 1)
+	return nil
+}
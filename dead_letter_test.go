@@ -0,0 +1,99 @@
+package operator
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jaz303/operator/deadletter"
+	"github.com/stretchr/testify/assert"
+)
+
+type requeueInput struct {
+	N int `json:"n"`
+}
+
+func TestDeadLetter_Event(t *testing.T) {
+	store := deadletter.NewMemoryStore()
+	hub := newTestHub(WithBackgroundRetry(3, time.Millisecond), WithDeadLetterSink(store), WithBackgroundErrorHandler(func(error) {}))
+
+	var attempts, fail atomic.Int32
+	fail.Store(1)
+	assert.NoError(t, hub.RegisterEventHandler(&testEvent{}, func(evt *testEvent) error {
+		attempts.Add(1)
+		if fail.Load() == 1 {
+			return errors.New("handler failed")
+		}
+		return nil
+	}))
+
+	_, err := Invoke(context.Background(), hub, func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) {
+		return in, ctx.EmitAfterCommit(&testEvent{Val: 9})
+	}, &struct{}{})
+	assert.NoError(t, err)
+
+	var letters []deadletter.Letter
+	assert.Eventually(t, func() bool {
+		letters, _ = store.List(context.Background(), deadletter.Filter{})
+		return len(letters) == 1
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, int32(3), attempts.Load())
+	assert.Equal(t, DeadLetterSource, letters[0].Source)
+	assert.Equal(t, "testEvent", letters[0].Name)
+	assert.Equal(t, 3, letters[0].Attempts)
+	assert.JSONEq(t, `{"Val":9}`, string(letters[0].Data))
+
+	fail.Store(0)
+	assert.NoError(t, deadletter.Requeue(context.Background(), store, letters[0].ID, hub.RequeueDeadLetter))
+	assert.Eventually(t, func() bool { return attempts.Load() == 4 }, time.Second, time.Millisecond)
+}
+
+func TestDeadLetter_Operation(t *testing.T) {
+	store := deadletter.NewMemoryStore()
+	hub := newTestHub(WithDeadLetterSink(store), WithBackgroundErrorHandler(func(error) {}))
+
+	var got atomic.Int32
+	var fail atomic.Bool
+	fail.Store(true)
+	followUp := func(ctx *OpContext[*TxTest], in *requeueInput) (*struct{}, error) {
+		if fail.Load() {
+			return nil, errors.New("follow-up failed")
+		}
+		got.Store(int32(in.N))
+		return &struct{}{}, nil
+	}
+	assert.NoError(t, RegisterOperation(hub, "follow-up", followUp))
+
+	_, err := Invoke(context.Background(), hub, func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) {
+		return in, InvokeAfterCommit(ctx, followUp, &requeueInput{N: 5})
+	}, &struct{}{})
+	assert.NoError(t, err)
+
+	var letters []deadletter.Letter
+	assert.Eventually(t, func() bool {
+		letters, _ = store.List(context.Background(), deadletter.Filter{})
+		return len(letters) == 1
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, "follow-up", letters[0].Name)
+	assert.Equal(t, 1, letters[0].Attempts)
+
+	fail.Store(false)
+	assert.NoError(t, hub.RequeueDeadLetter(context.Background(), &letters[0]))
+	assert.Eventually(t, func() bool { return got.Load() == 5 }, time.Second, time.Millisecond)
+}
+
+func TestRequeueDeadLetter_NotRequeueable(t *testing.T) {
+	hub := newTestHub()
+
+	err := hub.RequeueDeadLetter(context.Background(), &deadletter.Letter{Source: "webhook"})
+	assert.ErrorIs(t, err, ErrNotRequeueable)
+
+	err = hub.RequeueDeadLetter(context.Background(), &deadletter.Letter{
+		Source:   DeadLetterSource,
+		Name:     "unregistered",
+		Metadata: map[string]string{"kind": "operation"},
+	})
+	assert.ErrorIs(t, err, ErrNotRequeueable)
+}
@@ -0,0 +1,166 @@
+// Package deadletter retains work that failed permanently - background event
+// dispatch whose retries are exhausted, undeliverable webhooks - so that it
+// can be inspected and requeued rather than lost.
+//
+// Producers write to a Sink. A Store is a Sink that can also list, fetch and
+// delete letters; MemoryStore is suitable for tests and single-process
+// deployments, and PostgresStore keeps letters in a database table.
+//
+// Each producer provides a way to requeue its letters, e.g.
+// operator.Hub.RequeueDeadLetter() and webhook.Dispatcher.Requeue(); Requeue()
+// combines this with removing the letter from its store.
+package deadletter
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+var ErrNotFound = errors.New("dead letter not found")
+
+// Letter is a unit of work that failed permanently.
+type Letter struct {
+	// Unique ID, assigned by Put() if empty
+	ID string `json:"id"`
+
+	// Subsystem that produced the letter, e.g. "operator" or "webhook";
+	// identifies how the letter can be requeued
+	Source string `json:"source"`
+
+	// Name and version of the event, or the name of the operation, that
+	// failed
+	Name    string `json:"name"`
+	Version int    `json:"version,omitempty"`
+
+	// Serialized payload
+	ContentType string `json:"content_type,omitempty"`
+	Data        []byte `json:"data,omitempty"`
+
+	// Source-specific details, e.g. a webhook endpoint ID
+	Metadata map[string]string `json:"metadata,omitempty"`
+
+	Error    string    `json:"error"`
+	Attempts int       `json:"attempts"`
+	Time     time.Time `json:"time"`
+}
+
+// Sink receives letters.
+type Sink interface {
+	Put(ctx context.Context, l *Letter) error
+}
+
+// SinkFunc adapts a function to the Sink interface.
+type SinkFunc func(ctx context.Context, l *Letter) error
+
+func (fn SinkFunc) Put(ctx context.Context, l *Letter) error { return fn(ctx, l) }
+
+// Filter selects letters to list. Zero-valued fields match every letter.
+type Filter struct {
+	Source string
+	Name   string
+
+	// Maximum number of letters to return; 0 for no limit
+	Limit int
+}
+
+// Store is a Sink from which letters can be listed and removed.
+type Store interface {
+	Sink
+
+	// List returns letters matching f, oldest first.
+	List(ctx context.Context, f Filter) ([]Letter, error)
+
+	// Get returns the letter with the given ID, or ErrNotFound.
+	Get(ctx context.Context, id string) (*Letter, error)
+
+	// Delete removes the letter with the given ID. Deleting a letter that
+	// does not exist is not an error.
+	Delete(ctx context.Context, id string) error
+}
+
+// Requeue fetches the letter with the given ID from s, passes it to
+// redeliver, and deletes it once redeliver succeeds.
+func Requeue(ctx context.Context, s Store, id string, redeliver func(context.Context, *Letter) error) error {
+	l, err := s.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := redeliver(ctx, l); err != nil {
+		return err
+	}
+	return s.Delete(ctx, id)
+}
+
+// MemoryStore is a Store holding letters in memory.
+type MemoryStore struct {
+	mu      sync.Mutex
+	letters map[string]Letter
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{letters: map[string]Letter{}}
+}
+
+func (s *MemoryStore) Put(ctx context.Context, l *Letter) error {
+	prepare(l)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.letters[l.ID] = *l
+	return nil
+}
+
+func (s *MemoryStore) List(ctx context.Context, f Filter) ([]Letter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := []Letter{}
+	for _, l := range s.letters {
+		if (f.Source == "" || l.Source == f.Source) && (f.Name == "" || l.Name == f.Name) {
+			out = append(out, l)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].Time.Equal(out[j].Time) {
+			return out[i].Time.Before(out[j].Time)
+		}
+		return out[i].ID < out[j].ID
+	})
+	if f.Limit > 0 && len(out) > f.Limit {
+		out = out[:f.Limit]
+	}
+	return out, nil
+}
+
+func (s *MemoryStore) Get(ctx context.Context, id string) (*Letter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	l, ok := s.letters[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &l, nil
+}
+
+func (s *MemoryStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.letters, id)
+	return nil
+}
+
+// prepare assigns l an ID and time, if not already set.
+func prepare(l *Letter) {
+	if l.ID == "" {
+		var b [16]byte
+		rand.Read(b[:])
+		l.ID = hex.EncodeToString(b[:])
+	}
+	if l.Time.IsZero() {
+		l.Time = time.Now().UTC()
+	}
+}
@@ -0,0 +1,59 @@
+package deadletter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryStore(t *testing.T) {
+	s := NewMemoryStore()
+	ctx := context.Background()
+	now := time.Now()
+
+	assert.NoError(t, s.Put(ctx, &Letter{ID: "b", Source: "webhook", Name: "x", Time: now.Add(time.Second)}))
+	assert.NoError(t, s.Put(ctx, &Letter{ID: "a", Source: "operator", Name: "x", Time: now}))
+	l := &Letter{Source: "operator", Name: "y"}
+	assert.NoError(t, s.Put(ctx, l))
+	assert.NotEmpty(t, l.ID)
+	assert.False(t, l.Time.IsZero())
+
+	all, err := s.List(ctx, Filter{})
+	assert.NoError(t, err)
+	assert.Len(t, all, 3)
+	assert.Equal(t, "a", all[0].ID)
+
+	filtered, err := s.List(ctx, Filter{Source: "operator", Name: "x"})
+	assert.NoError(t, err)
+	assert.Len(t, filtered, 1)
+
+	limited, err := s.List(ctx, Filter{Limit: 2})
+	assert.NoError(t, err)
+	assert.Len(t, limited, 2)
+
+	_, err = s.Get(ctx, "missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestRequeue(t *testing.T) {
+	s := NewMemoryStore()
+	ctx := context.Background()
+	assert.NoError(t, s.Put(ctx, &Letter{ID: "a"}))
+
+	fail := errors.New("still broken")
+	assert.ErrorIs(t, Requeue(ctx, s, "a", func(context.Context, *Letter) error { return fail }), fail)
+	_, err := s.Get(ctx, "a")
+	assert.NoError(t, err)
+
+	var requeued *Letter
+	assert.NoError(t, Requeue(ctx, s, "a", func(_ context.Context, l *Letter) error {
+		requeued = l
+		return nil
+	}))
+	assert.Equal(t, "a", requeued.ID)
+	_, err = s.Get(ctx, "a")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
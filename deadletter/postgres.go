@@ -0,0 +1,129 @@
+package deadletter
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Schema is the DDL for the table used by PostgresStore; replace
+// "dead_letters" if using a different table name.
+const Schema = `CREATE TABLE IF NOT EXISTS dead_letters (
+	id           TEXT PRIMARY KEY,
+	source       TEXT NOT NULL,
+	name         TEXT NOT NULL,
+	version      INTEGER NOT NULL DEFAULT 0,
+	content_type TEXT NOT NULL DEFAULT '',
+	data         BYTEA,
+	metadata     JSONB,
+	error        TEXT NOT NULL,
+	attempts     INTEGER NOT NULL,
+	created_at   TIMESTAMPTZ NOT NULL
+)`
+
+// PostgresStore is a Store keeping letters in a PostgreSQL table created with
+// Schema.
+type PostgresStore struct {
+	db    *sql.DB
+	table string
+}
+
+// NewPostgresStore returns a store using the named table in db. If table is
+// empty, "dead_letters" is used.
+func NewPostgresStore(db *sql.DB, table string) *PostgresStore {
+	if table == "" {
+		table = "dead_letters"
+	}
+	return &PostgresStore{db: db, table: table}
+}
+
+const columns = "id, source, name, version, content_type, data, metadata, error, attempts, created_at"
+
+func (s *PostgresStore) Put(ctx context.Context, l *Letter) error {
+	prepare(l)
+	var metadata []byte
+	if l.Metadata != nil {
+		var err error
+		if metadata, err = json.Marshal(l.Metadata); err != nil {
+			return err
+		}
+	}
+	_, err := s.db.ExecContext(ctx,
+		"INSERT INTO "+s.table+" ("+columns+") VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)",
+		l.ID, l.Source, l.Name, l.Version, l.ContentType, l.Data, metadata, l.Error, l.Attempts, l.Time)
+	if err != nil {
+		return fmt.Errorf("storing dead letter failed (%w)", err)
+	}
+	return nil
+}
+
+func (s *PostgresStore) List(ctx context.Context, f Filter) ([]Letter, error) {
+	var where []string
+	var args []any
+	if f.Source != "" {
+		args = append(args, f.Source)
+		where = append(where, "source = $"+strconv.Itoa(len(args)))
+	}
+	if f.Name != "" {
+		args = append(args, f.Name)
+		where = append(where, "name = $"+strconv.Itoa(len(args)))
+	}
+
+	q := "SELECT " + columns + " FROM " + s.table
+	if len(where) > 0 {
+		q += " WHERE " + strings.Join(where, " AND ")
+	}
+	q += " ORDER BY created_at, id"
+	if f.Limit > 0 {
+		q += " LIMIT " + strconv.Itoa(f.Limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []Letter{}
+	for rows.Next() {
+		l, err := scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *l)
+	}
+	return out, rows.Err()
+}
+
+func (s *PostgresStore) Get(ctx context.Context, id string) (*Letter, error) {
+	row := s.db.QueryRowContext(ctx, "SELECT "+columns+" FROM "+s.table+" WHERE id = $1", id)
+	l, err := scan(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return l, err
+}
+
+func (s *PostgresStore) Delete(ctx context.Context, id string) error {
+	_, err := s.db.ExecContext(ctx, "DELETE FROM "+s.table+" WHERE id = $1", id)
+	return err
+}
+
+func scan(row interface{ Scan(...any) error }) (*Letter, error) {
+	var l Letter
+	var metadata []byte
+	err := row.Scan(&l.ID, &l.Source, &l.Name, &l.Version, &l.ContentType, &l.Data, &metadata, &l.Error, &l.Attempts, &l.Time)
+	if err != nil {
+		return nil, err
+	}
+	if metadata != nil {
+		if err := json.Unmarshal(metadata, &l.Metadata); err != nil {
+			return nil, err
+		}
+	}
+	return &l, nil
+}
@@ -0,0 +1,58 @@
+package operator
+
+import (
+	"context"
+	"reflect"
+	"sync"
+)
+
+// dispatchers maps event handler function types to their dispatchers.
+var dispatchers sync.Map // reflect.Type -> func(any) func(context.Context, any) error
+
+// RegisterDispatcher() registers a dispatcher for event handlers of the
+// function type H. Handlers of type H subsequently registered with
+// Hub.RegisterEventHandler() are invoked through the function shim returns
+// for them, rather than with reflection, so that dispatching an event to
+// them does not allocate.
+//
+// The function returned by shim is called with the operation's
+// *OpContext[Tx] as ctx, and an event of the type the handler was registered
+// for, or nil. Dispatchers are not normally written by hand, but generated
+// for a program's handlers by "operator-gen handlers" (see cmd/operator-gen)
+// and registered from init functions, e.g.:
+//
+//	operator.RegisterDispatcher(func(hnd func(context.Context, *UserCreated) error) func(context.Context, any) error {
+//		return func(ctx context.Context, evt any) error {
+//			e, _ := evt.(*UserCreated)
+//			return hnd(ctx, e)
+//		}
+//	})
+//
+// Registering a dispatcher for a type that already has one replaces it.
+// Handlers already registered are unaffected.
+func RegisterDispatcher[H any](shim func(hnd H) func(ctx context.Context, evt any) error) {
+	dispatchers.Store(reflect.TypeFor[H](), func(hnd any) func(context.Context, any) error {
+		return shim(hnd.(H))
+	})
+}
+
+// dispatcherFor returns the dispatch function for hnd, if a dispatcher is
+// registered for its type.
+func dispatcherFor(hnd reflect.Value) (func(context.Context, any) error, bool) {
+	shim, ok := dispatchers.Load(hnd.Type())
+	if !ok {
+		return nil, false
+	}
+	return shim.(func(any) func(context.Context, any) error)(hnd.Interface()), true
+}
+
+type staticEventHandler[Tx Transaction] struct {
+	fn   func(context.Context, any) error
+	name string
+}
+
+func (h *staticEventHandler[Tx]) Name() string { return h.name }
+
+func (h *staticEventHandler[Tx]) Dispatch(op *OpContext[Tx], evt any) error {
+	return h.fn(op, evt)
+}
@@ -0,0 +1,66 @@
+package operator
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type dispatchEvent struct {
+	Val int
+}
+
+func (e *dispatchEvent) EventName() string { return "dispatchEvent" }
+
+func init() {
+	RegisterDispatcher(func(hnd func(*OpContext[*TxTest], *dispatchEvent) error) func(context.Context, any) error {
+		return func(ctx context.Context, evt any) error {
+			e, _ := evt.(*dispatchEvent)
+			return hnd(ctx.(*OpContext[*TxTest]), e)
+		}
+	})
+}
+
+func dispatchHandler(ctx *OpContext[*TxTest], evt *dispatchEvent) error {
+	if evt.Val < 0 {
+		return errors.New("negative")
+	}
+	return nil
+}
+
+func TestStaticDispatch(t *testing.T) {
+	opCtx := &OpContext[*TxTest]{Context: context.Background()}
+
+	hnd := makeEventHandler[*TxTest](reflect.TypeOf(&dispatchEvent{}), dispatchHandler)
+	assert.IsType(t, &staticEventHandler[*TxTest]{}, hnd)
+	assert.Equal(t, "github.com/jaz303/operator.dispatchHandler", hnd.Name())
+	assert.Nil(t, hnd.Dispatch(opCtx, &dispatchEvent{Val: 1}))
+	assert.EqualError(t, hnd.Dispatch(opCtx, &dispatchEvent{Val: -1}), "negative")
+
+	if !raceEnabled {
+		evt := any(&dispatchEvent{Val: 1})
+		allocs := testing.AllocsPerRun(100, func() { hnd.Dispatch(opCtx, evt) })
+		assert.Zero(t, allocs)
+	}
+
+	// handlers of other types are dispatched with reflection
+	hnd = makeEventHandler[*TxTest](reflect.TypeOf(&dispatchEvent{}), func(evt *dispatchEvent) {})
+	assert.IsType(t, &genericEventHandler[*TxTest]{}, hnd)
+}
+
+func TestWithStaticDispatch(t *testing.T) {
+	hub := newTestHub(WithStaticDispatch())
+
+	assert.NoError(t, hub.RegisterEventHandler(&dispatchEvent{}, dispatchHandler))
+	assert.Panics(t, func() {
+		hub.RegisterEventHandler(&dispatchEvent{}, func(evt *dispatchEvent) {})
+	})
+
+	_, err := Invoke(context.Background(), hub, func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) {
+		return in, ctx.Emit(&dispatchEvent{Val: -1})
+	}, &struct{}{})
+	assert.ErrorContains(t, err, "negative")
+}
@@ -0,0 +1,77 @@
+package echobind
+
+import (
+	"net/http"
+
+	"github.com/jaz303/operator"
+	"github.com/jaz303/operator/codec"
+	"github.com/jaz303/operator/httpbind"
+	"github.com/labstack/echo/v5"
+)
+
+// Decode parses the request body into a *P using the codec registered in
+// codec.Default for the request's Content-Type. Requests with no
+// Content-Type are decoded as JSON.
+func Decode[P any](c *echo.Context) (*P, error) {
+	return DecodeWith[P](codec.Default)(c)
+}
+
+// DecodeWith returns an input mapper that parses the request body into a *P
+// using the codec registered in reg for the request's Content-Type.
+func DecodeWith[P any](reg *codec.Registry) func(c *echo.Context) (*P, error) {
+	return func(c *echo.Context) (*P, error) {
+		ct := c.Request().Header.Get(echo.HeaderContentType)
+		if ct == "" {
+			ct = codec.JSON.ContentType()
+		}
+		dec, err := reg.Find(ct)
+		if err != nil {
+			return nil, echo.ErrUnsupportedMediaType.Wrap(err)
+		}
+		var out P
+		if err := dec.Decode(c.Request().Body, &out); err != nil {
+			return nil, echo.ErrBadRequest.Wrap(err)
+		}
+		return &out, nil
+	}
+}
+
+// Encode returns an output mapper that writes a *T with status 200 using the
+// codec registered in codec.Default for contentType. The codec is resolved
+// when Encode is called; it panics if no such codec is registered.
+func Encode[T any](contentType string) func(c *echo.Context, val *T) error {
+	enc, err := codec.Default.Find(contentType)
+	if err != nil {
+		panic(err)
+	}
+	return EncodeWith[T](enc)
+}
+
+// EncodeWith returns an output mapper that writes a *T with status 200 using
+// enc. The status code and headers may be customised by implementing
+// httpbind.StatusCoder, httpbind.HeaderSetter or httpbind.Redirector on T.
+// Nil and *operator.Empty values are written as 204 No Content.
+func EncodeWith[T any](enc codec.Codec) func(c *echo.Context, val *T) error {
+	return func(c *echo.Context, val *T) error {
+		if val == nil {
+			return writeEncoded(c, enc, nil)
+		}
+		return writeEncoded(c, enc, val)
+	}
+}
+
+func writeEncoded(c *echo.Context, enc codec.Codec, val any) error {
+	switch val.(type) {
+	case nil, *operator.Empty, operator.Empty:
+		return c.NoContent(http.StatusNoContent)
+	}
+	status, body := httpbind.PrepareResponse(c.Response().Header(), val, http.StatusOK)
+	if !body {
+		return c.NoContent(status)
+	}
+	data, err := codec.Marshal(enc, val)
+	if err != nil {
+		return err
+	}
+	return c.Blob(status, enc.ContentType(), data)
+}
@@ -0,0 +1,92 @@
+package echobind
+
+import (
+	"time"
+
+	"github.com/jaz303/operator"
+	"github.com/labstack/echo/v5"
+)
+
+// Config holds defaults shared by a group of bindings; it is the Echo
+// equivalent of httpbind.Config. Bindings are created with BindWith and
+// BindTxWith, and Configs may be combined with Merge.
+type Config struct {
+	// ContextPolicy derives each operation's context from the request's
+	// context; if nil, the Invoker default is used.
+	ContextPolicy operator.ContextPolicy
+
+	// ErrorMapper, if non-nil, replaces the default error mapper.
+	ErrorMapper func(c *echo.Context, err error) error
+
+	// OutputMapper, if non-nil, writes each operation's output, which is
+	// passed as a pointer, or nil.
+	OutputMapper func(c *echo.Context, o any) error
+
+	// Timeout, if positive, bounds the execution time of each operation.
+	Timeout time.Duration
+
+	// Middleware wraps each binding's handler, as if added with
+	// Invoker.Use; the first is outermost.
+	Middleware []echo.MiddlewareFunc
+}
+
+// Merge returns a copy of c with the non-zero fields of o applied; o's
+// middleware runs inside c's.
+func (c Config) Merge(o Config) Config {
+	if o.ContextPolicy != nil {
+		c.ContextPolicy = o.ContextPolicy
+	}
+	if o.ErrorMapper != nil {
+		c.ErrorMapper = o.ErrorMapper
+	}
+	if o.OutputMapper != nil {
+		c.OutputMapper = o.OutputMapper
+	}
+	if o.Timeout > 0 {
+		c.Timeout = o.Timeout
+	}
+	c.Middleware = append(c.Middleware[:len(c.Middleware):len(c.Middleware)], o.Middleware...)
+	return c
+}
+
+// BindWith is like Bind, applying the defaults in cfg to the returned
+// Invoker, which can be further customised.
+func BindWith[Tx operator.Transaction, I any, O any](
+	cfg Config,
+	hub *operator.Hub[Tx],
+	op func(*operator.OpContext[Tx], *I) (*O, error),
+) *Invoker[Tx, I, O] {
+	return applyConfig(cfg, Bind(hub, op))
+}
+
+// BindTxWith is like BindTx, applying the defaults in cfg to the returned
+// Invoker, which can be further customised.
+func BindTxWith[Tx operator.Transaction, I any, O any](
+	cfg Config,
+	hub *operator.Hub[Tx],
+	op func(*operator.OpContext[Tx], Tx, *I) (*O, error),
+) *Invoker[Tx, I, O] {
+	return applyConfig(cfg, BindTx(hub, op))
+}
+
+func applyConfig[Tx operator.Transaction, I any, O any](cfg Config, inv *Invoker[Tx, I, O]) *Invoker[Tx, I, O] {
+	if cfg.ContextPolicy != nil {
+		inv.WithContextPolicy(cfg.ContextPolicy)
+	}
+	if cfg.ErrorMapper != nil {
+		inv.WithErrorMapper(cfg.ErrorMapper)
+	}
+	if fn := cfg.OutputMapper; fn != nil {
+		inv.WithOutputMapper(func(c *echo.Context, o *O) error {
+			if o == nil {
+				return fn(c, nil)
+			}
+			return fn(c, o)
+		})
+	}
+	if cfg.Timeout > 0 {
+		inv.WithTimeout(cfg.Timeout)
+	}
+	inv.middleware = append(inv.middleware, cfg.Middleware...)
+	return inv
+}
@@ -0,0 +1,37 @@
+package echobind
+
+import (
+	"errors"
+	"math"
+	"strconv"
+
+	"github.com/jaz303/operator"
+	"github.com/jaz303/operator/operr"
+	"github.com/labstack/echo/v5"
+)
+
+type errorMapperKey struct{}
+
+// SetDefaultErrorMapper sets the error mapper used by every binding to hub
+// that does not set its own with WithErrorMapper. Returns
+// operator.ErrHubFrozen if the hub has been frozen.
+func SetDefaultErrorMapper[Tx operator.Transaction](hub *operator.Hub[Tx], fn func(c *echo.Context, err error) error) error {
+	return hub.SetAttribute(errorMapperKey{}, fn)
+}
+
+// DefaultErrorMapper converts err to an *echo.HTTPError whose status code is
+// given by operr.StatusCode(), for Echo's error handler to write. Errors
+// that already wrap an *echo.HTTPError, such as those returned by Echo's
+// binder, are returned unchanged. Rate limited requests additionally receive
+// a Retry-After header.
+func DefaultErrorMapper(c *echo.Context, err error) error {
+	var httpErr *echo.HTTPError
+	if errors.As(err, &httpErr) {
+		return err
+	}
+	var rateLimited *operr.RateLimitError
+	if errors.As(err, &rateLimited) {
+		c.Response().Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(rateLimited.RetryAfter.Seconds()))))
+	}
+	return (&echo.HTTPError{Code: operr.StatusCode(err), Message: err.Error()}).Wrap(err)
+}
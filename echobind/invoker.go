@@ -2,8 +2,15 @@ package echobind
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"time"
 
 	"github.com/jaz303/operator"
+	"github.com/jaz303/operator/codec"
+	"github.com/jaz303/operator/httpbind"
+	"github.com/jaz303/operator/operr"
+	"github.com/jaz303/operator/tenancy"
 	"github.com/labstack/echo/v5"
 )
 
@@ -16,8 +23,6 @@ func Bind[Tx operator.Transaction, I any, O any](
 	return &Invoker[Tx, I, O]{
 		hub: hub,
 		op:  op,
-
-		ctx: func(c *echo.Context) context.Context { return context.Background() },
 	}
 }
 
@@ -30,8 +35,6 @@ func BindTx[Tx operator.Transaction, I any, O any](
 	return &Invoker[Tx, I, O]{
 		hub:  hub,
 		txOp: op,
-
-		ctx: func(c *echo.Context) context.Context { return context.Background() },
 	}
 }
 
@@ -43,20 +46,72 @@ type Invoker[Tx operator.Transaction, I any, O any] struct {
 	op   func(*operator.OpContext[Tx], *I) (*O, error)
 	txOp func(*operator.OpContext[Tx], Tx, *I) (*O, error)
 
-	ctx          func(c *echo.Context) context.Context
+	ctx          func(c *echo.Context) (context.Context, context.CancelFunc)
+	timeout      time.Duration
+	tenant       tenancy.Resolver
+	auth         func(c *echo.Context) (operator.Principal, error)
+	csrf         *httpbind.CSRFConfig
 	inputMapper  func(c *echo.Context) (*I, error)
 	outputMapper func(c *echo.Context, o *O) error
+	errorMapper  func(c *echo.Context, err error) error
+	middleware   []echo.MiddlewareFunc
+	requestInfo  *[]string
 }
 
 // WithContext sets a static context for the operation
 func (i *Invoker[Tx, I, O]) WithContext(ctx context.Context) *Invoker[Tx, I, O] {
-	i.ctx = func(c *echo.Context) context.Context { return ctx }
+	i.ctx = func(c *echo.Context) (context.Context, context.CancelFunc) { return ctx, func() {} }
 	return i
 }
 
 // WithContextFunc sets fn as a context factory for the operation.
+// Prefer WithContextPolicy unless the operation context must be derived
+// from something other than the request's context.
 func (i *Invoker[Tx, I, O]) WithContextFunc(fn func(*echo.Context) context.Context) *Invoker[Tx, I, O] {
-	i.ctx = fn
+	i.ctx = func(c *echo.Context) (context.Context, context.CancelFunc) { return fn(c), func() {} }
+	return i
+}
+
+// WithContextPolicy sets the policy used to derive the operation context
+// from the request's context; see operator.Inherit, operator.Detach and
+// operator.InheritWithGrace. The default is the hub's policy; see
+// operator.WithContextPolicy.
+func (i *Invoker[Tx, I, O]) WithContextPolicy(p operator.ContextPolicy) *Invoker[Tx, I, O] {
+	i.ctx = policyContext(p)
+	return i
+}
+
+// WithTimeout bounds the operation's execution time; see
+// httpbind.Invoker.WithTimeout.
+func (i *Invoker[Tx, I, O]) WithTimeout(d time.Duration) *Invoker[Tx, I, O] {
+	i.timeout = d
+	return i
+}
+
+// WithTenantResolver registers a resolver that identifies the tenant to which
+// each request belongs before authentication; see httpbind.Invoker.WithTenantResolver.
+// Errors are wrapped in echo.ErrBadRequest and passed to the error mapper.
+func (i *Invoker[Tx, I, O]) WithTenantResolver(res tenancy.Resolver) *Invoker[Tx, I, O] {
+	i.tenant = res
+	return i
+}
+
+// WithAuth registers a function that authenticates each request before the
+// operation is invoked. The resulting Principal is available to the operation
+// and its event handlers via OpContext.Principal(). Errors are wrapped in
+// echo.ErrUnauthorized, or echo.ErrForbidden if they wrap
+// operr.ErrAuthorizationFailed, and passed to the error mapper.
+func (i *Invoker[Tx, I, O]) WithAuth(fn func(c *echo.Context) (operator.Principal, error)) *Invoker[Tx, I, O] {
+	i.auth = fn
+	return i
+}
+
+// WithCSRF requires state-changing requests to carry a CSRF token matching
+// the token cookie, as described by cfg; see httpbind.CSRFConfig. The check
+// happens before input mapping; failures are wrapped in echo.ErrForbidden and
+// passed to the error mapper.
+func (i *Invoker[Tx, I, O]) WithCSRF(cfg httpbind.CSRFConfig) *Invoker[Tx, I, O] {
+	i.csrf = &cfg
 	return i
 }
 
@@ -72,6 +127,34 @@ func (i *Invoker[Tx, I, O]) WithOutputMapper(fn func(c *echo.Context, o *O) erro
 	return i
 }
 
+// WithErrorMapper registers the binding's error mapper, which converts any
+// error arising from the request into the error returned to Echo. Errors
+// from input mapping wrap operr.ErrInputMappingFailed, and errors from the
+// operation wrap operr.ErrOperationFailed. The default is the hub's default,
+// set with SetDefaultErrorMapper, or else DefaultErrorMapper.
+func (i *Invoker[Tx, I, O]) WithErrorMapper(fn func(c *echo.Context, err error) error) *Invoker[Tx, I, O] {
+	i.errorMapper = fn
+	return i
+}
+
+// WithRequestInfo attaches an operator.RequestInfo describing each request to
+// the operation's context, capturing only the named headers; see
+// httpbind.Invoker.WithRequestInfo. The remote IP is taken from Echo's
+// RealIP.
+func (i *Invoker[Tx, I, O]) WithRequestInfo(headers ...string) *Invoker[Tx, I, O] {
+	i.requestInfo = &headers
+	return i
+}
+
+// Use appends Echo middleware wrapping the binding's handler, for
+// per-endpoint concerns such as request logging, header checks or response
+// shaping. Middleware runs in the order added, outermost first, before the
+// request is mapped to the operation's input.
+func (i *Invoker[Tx, I, O]) Use(mw ...echo.MiddlewareFunc) *Invoker[Tx, I, O] {
+	i.middleware = append(i.middleware, mw...)
+	return i
+}
+
 // WithJSONOutputFunc sets an output mapper that calls fn after setting the JSON content type
 func (i *Invoker[Tx, I, O]) WithJSONOutputFunc(fn func(c *echo.Context, o *O) error) *Invoker[Tx, I, O] {
 	i.outputMapper = fn
@@ -81,7 +164,7 @@ func (i *Invoker[Tx, I, O]) WithJSONOutputFunc(fn func(c *echo.Context, o *O) er
 // WithJSONOutput sets an output mapper that writes the result of fn as JSON
 func (i *Invoker[Tx, I, O]) WithJSONOutput(fn func(c *echo.Context, o *O) any) *Invoker[Tx, I, O] {
 	i.outputMapper = func(c *echo.Context, o *O) error {
-		return c.JSON(200, fn(c, o))
+		return writeEncoded(c, codec.JSON, fn(c, o))
 	}
 	return i
 }
@@ -89,27 +172,103 @@ func (i *Invoker[Tx, I, O]) WithJSONOutput(fn func(c *echo.Context, o *O) any) *
 // Go invokes the bound operation in the context of the supplied Echo request.
 // Its signature matches echo.HandlerFunc.
 func (i *Invoker[Tx, I, O]) Go(c *echo.Context) error {
+	if len(i.middleware) == 0 {
+		return i.serve(c)
+	}
+	h := echo.HandlerFunc(i.serve)
+	for ix := len(i.middleware) - 1; ix >= 0; ix-- {
+		h = i.middleware[ix](h)
+	}
+	return h(c)
+}
+
+func (i *Invoker[Tx, I, O]) serve(c *echo.Context) error {
+	mapError := i.getErrorMapper()
+
+	var tenant string
+	if i.tenant != nil {
+		t, err := i.tenant(c.Request())
+		if err != nil {
+			return mapError(c, echo.ErrBadRequest.Wrap(err))
+		}
+		tenant = t
+		c.SetRequest(c.Request().WithContext(operator.WithTenant(c.Request().Context(), t)))
+	}
+
+	var principal operator.Principal
+	if i.auth != nil {
+		p, err := i.auth(c)
+		if errors.Is(err, operr.ErrAuthorizationFailed) {
+			return mapError(c, echo.ErrForbidden.Wrap(err))
+		} else if err != nil {
+			return mapError(c, echo.ErrUnauthorized.Wrap(err))
+		}
+		principal = p
+		c.SetRequest(c.Request().WithContext(operator.WithPrincipal(c.Request().Context(), p)))
+	}
+
+	if i.csrf != nil {
+		if err := i.csrf.Verify(c.Request()); err != nil {
+			return mapError(c, echo.ErrForbidden.Wrap(err))
+		}
+	}
+
 	input, err := i.getInputMapper()(c)
 	if err != nil {
-		return err
+		return mapError(c, fmt.Errorf("%w: %w", operr.ErrInputMappingFailed, err))
+	}
+
+	ctx, cancel := i.getContext(c)
+	defer cancel()
+	if tenant != "" {
+		ctx = operator.WithTenant(ctx, tenant)
+	}
+	if principal != nil {
+		ctx = operator.WithPrincipal(ctx, principal)
+	}
+	if i.requestInfo != nil {
+		ri := httpbind.NewRequestInfo(c.Request(), *i.requestInfo)
+		ri.RemoteIP = c.RealIP()
+		ctx = operator.WithRequestInfo(ctx, ri)
 	}
 
 	var output *O
 	if i.txOp != nil {
-		output, err = operator.InvokeTx(i.getContext(c), i.hub, i.txOp, input)
+		output, err = operator.InvokeTx(ctx, i.hub, i.txOp, input)
 	} else {
-		output, err = operator.Invoke(i.getContext(c), i.hub, i.op, input)
+		output, err = operator.Invoke(ctx, i.hub, i.op, input)
 	}
 
 	if err != nil {
-		return err
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			err = fmt.Errorf("%w: %w", operr.ErrTimeout, err)
+		}
+		return mapError(c, fmt.Errorf("%w: %w", operr.ErrOperationFailed, err))
 	}
 
 	return i.getOutputMapper()(c, output)
 }
 
-func (i *Invoker[Tx, I, O]) getContext(c *echo.Context) context.Context {
-	return i.ctx(c)
+func (i *Invoker[Tx, I, O]) getContext(c *echo.Context) (context.Context, context.CancelFunc) {
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if i.ctx != nil {
+		ctx, cancel = i.ctx(c)
+	} else {
+		ctx, cancel = i.hub.ContextPolicy()(c.Request().Context())
+	}
+	if i.timeout <= 0 {
+		return ctx, cancel
+	}
+	ctx, cancelTimeout := context.WithTimeout(ctx, i.timeout)
+	return ctx, func() {
+		cancelTimeout()
+		cancel()
+	}
+}
+
+func policyContext(p operator.ContextPolicy) func(c *echo.Context) (context.Context, context.CancelFunc) {
+	return func(c *echo.Context) (context.Context, context.CancelFunc) { return p(c.Request().Context()) }
 }
 
 func (i *Invoker[Tx, I, O]) getInputMapper() func(c *echo.Context) (*I, error) {
@@ -125,3 +284,12 @@ func (i *Invoker[Tx, I, O]) getOutputMapper() func(*echo.Context, *O) error {
 	}
 	return i.outputMapper
 }
+
+func (i *Invoker[Tx, I, O]) getErrorMapper() func(*echo.Context, error) error {
+	if i.errorMapper != nil {
+		return i.errorMapper
+	} else if fn, ok := i.hub.Attribute(errorMapperKey{}); ok {
+		return fn.(func(*echo.Context, error) error)
+	}
+	return DefaultErrorMapper
+}
@@ -0,0 +1,43 @@
+package echobind
+
+import (
+	"reflect"
+	"runtime"
+	"strings"
+
+	"github.com/jaz303/operator/httpbind"
+)
+
+// Route describes the invoker's binding to an Echo route at method and path,
+// for tooling such as client code generation (see codegen.NewManifest).
+// Echo path parameters (":id") and wildcards ("*") are converted to
+// http.ServeMux syntax ("{id}", "{path...}").
+func (i *Invoker[Tx, I, O]) Route(method, path string) httpbind.Route {
+	var fn any = i.op
+	if i.op == nil {
+		fn = i.txOp
+	}
+	var name string
+	if f := runtime.FuncForPC(reflect.ValueOf(fn).Pointer()); f != nil {
+		name = f.Name()
+	}
+	return httpbind.Route{
+		Method:    method,
+		Path:      muxPath(path),
+		Operation: name,
+		Input:     reflect.TypeFor[I](),
+		Output:    reflect.TypeFor[O](),
+	}
+}
+
+func muxPath(path string) string {
+	segments := strings.Split(path, "/")
+	for ix, s := range segments {
+		if name, ok := strings.CutPrefix(s, ":"); ok {
+			segments[ix] = "{" + name + "}"
+		} else if s == "*" {
+			segments[ix] = "{path...}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
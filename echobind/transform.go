@@ -1,8 +1,7 @@
 package echobind
 
 import (
-	"net/http"
-
+	"github.com/jaz303/operator/codec"
 	"github.com/labstack/echo/v5"
 )
 
@@ -27,9 +26,12 @@ func IndirectJSONInput[P any, I any](t func(*P) (*I, error)) func(c *echo.Contex
 	}
 }
 
-// WriteJSON writes a *T to the response as JSON with status 200
+// WriteJSON writes a *T to the response as JSON with status 200. The status
+// code and headers may be customised by implementing httpbind.StatusCoder,
+// httpbind.HeaderSetter or httpbind.Redirector on T. Nil and *operator.Empty
+// values are written as 204 No Content.
 func WriteJSON[T any](c *echo.Context, val *T) error {
-	return c.JSON(http.StatusOK, val)
+	return EncodeWith[T](codec.JSON)(c, val)
 }
 
 // Transform returns a function that reads input from an Echo context as an *I
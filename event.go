@@ -3,6 +3,7 @@ package operator
 import (
 	"fmt"
 	"reflect"
+	"runtime"
 )
 
 var (
@@ -13,11 +14,34 @@ type Event interface {
 	EventName() string
 }
 
+// NamedEvent is an event identified only by its name, carrying an arbitrary
+// payload. It is emitted by OpContext.EmitNamed() and is typically used for
+// events whose concrete Go type is not known, such as those relayed from
+// external systems. See Hub.RegisterNamedEventHandler().
+type NamedEvent struct {
+	Name    string
+	Payload any
+}
+
+func (e *NamedEvent) EventName() string { return e.Name }
+
 type eventHandler[Tx Transaction] interface {
+	Name() string
 	Dispatch(op *OpContext[Tx], evt any) error
 }
 
+// makeEventHandler creates a handler for events of eventType, which is
+// dispatched by the dispatcher registered for fn's type, if any, and
+// otherwise with reflection.
 func makeEventHandler[Tx Transaction](eventType reflect.Type, fn any) eventHandler[Tx] {
+	hnd := newGenericEventHandler[Tx](eventType, fn)
+	if dispatch, ok := dispatcherFor(hnd.fn); ok {
+		return &staticEventHandler[Tx]{fn: dispatch, name: hnd.name}
+	}
+	return hnd
+}
+
+func newGenericEventHandler[Tx Transaction](eventType reflect.Type, fn any) *genericEventHandler[Tx] {
 	val := reflect.ValueOf(fn)
 	if val.Kind() != reflect.Func {
 		panic(fmt.Errorf("event handler type %T is not a function", fn))
@@ -35,8 +59,9 @@ func makeEventHandler[Tx Transaction](eventType reflect.Type, fn any) eventHandl
 		panic(fmt.Errorf("event handler must return 0..1 values"))
 	}
 
-	hnd := genericEventHandler[Tx]{
+	hnd := &genericEventHandler[Tx]{
 		fn:               val,
+		name:             funcName(val),
 		evtParameterType: eventType,
 	}
 
@@ -59,15 +84,18 @@ func makeEventHandler[Tx Transaction](eventType reflect.Type, fn any) eventHandl
 		panic(fmt.Errorf("event handler must declare 1..2 parameters"))
 	}
 
-	return &hnd
+	return hnd
 }
 
 type genericEventHandler[Tx Transaction] struct {
 	fn               reflect.Value
+	name             string
 	evtParameterType reflect.Type
 	hasContext       bool
 }
 
+func (h *genericEventHandler[Tx]) Name() string { return h.name }
+
 func (h *genericEventHandler[Tx]) Dispatch(op *OpContext[Tx], evt any) error {
 	args := make([]reflect.Value, 0, 2)
 
@@ -76,7 +104,9 @@ func (h *genericEventHandler[Tx]) Dispatch(op *OpContext[Tx], evt any) error {
 	}
 
 	rEvt := reflect.ValueOf(evt)
-	if !rEvt.Type().AssignableTo(h.evtParameterType) {
+	if !rEvt.IsValid() && canBeNil(h.evtParameterType) {
+		args = append(args, reflect.Zero(h.evtParameterType))
+	} else if !rEvt.IsValid() || !rEvt.Type().AssignableTo(h.evtParameterType) {
 		return fmt.Errorf("event type %T is not assignable to handler parameter type %s", evt, h.evtParameterType)
 	} else {
 		args = append(args, rEvt)
@@ -90,3 +120,44 @@ func (h *genericEventHandler[Tx]) Dispatch(op *OpContext[Tx], evt any) error {
 		return out[0].Interface().(error)
 	}
 }
+
+// makeNamedEventHandler creates a handler for events registered by name; the
+// handler's event parameter type is taken from fn.
+func makeNamedEventHandler[Tx Transaction](fn any) eventHandler[Tx] {
+	ty := reflect.TypeOf(fn)
+	if ty == nil || ty.Kind() != reflect.Func {
+		panic(fmt.Errorf("event handler type %T is not a function", fn))
+	} else if ty.NumIn() < 1 {
+		panic(fmt.Errorf("event handler must declare 1..2 parameters"))
+	}
+	hnd := newGenericEventHandler[Tx](ty.In(ty.NumIn()-1), fn)
+	return &payloadEventHandler[Tx]{genericEventHandler: hnd}
+}
+
+// payloadEventHandler passes the payload of a *NamedEvent to handlers whose
+// parameter does not accept the event itself.
+type payloadEventHandler[Tx Transaction] struct {
+	*genericEventHandler[Tx]
+}
+
+func (h *payloadEventHandler[Tx]) Dispatch(op *OpContext[Tx], evt any) error {
+	if ne, ok := evt.(*NamedEvent); ok && !reflect.TypeOf(evt).AssignableTo(h.evtParameterType) {
+		evt = ne.Payload
+	}
+	return h.genericEventHandler.Dispatch(op, evt)
+}
+
+func canBeNil(ty reflect.Type) bool {
+	switch ty.Kind() {
+	case reflect.Pointer, reflect.Interface, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan:
+		return true
+	}
+	return false
+}
+
+func funcName(fn reflect.Value) string {
+	if f := runtime.FuncForPC(fn.Pointer()); f != nil {
+		return f.Name()
+	}
+	return fn.Type().String()
+}
@@ -0,0 +1,118 @@
+package operator
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// RegisterBatchEventHandler() registers a handler receiving, in a single
+// call, every event of the same type as event dispatched by an operation.
+// The handler is called once the operation's event queue is empty, before
+// its transaction commits, so can replace many small writes with one bulk
+// write; it fails the operation by returning an error, as with
+// RegisterEventHandler().
+//
+// hnd must have one of the following forms, where E is assignable from the
+// event's type:
+//
+//	func([]E)
+//	func([]E) error
+//	func(*OpContext[Tx], []E)
+//	func(*OpContext[Tx], []E) error
+//
+// Events are passed in the order they were dispatched. Events emitted by a
+// batch handler are dispatched as usual, and any further events of the
+// batched type are passed to the handler in another call. Handler options
+// apply as they do to RegisterEventHandler(); the handler's position
+// determines only when each event is added to its batch.
+func (h *Hub[Tx]) RegisterBatchEventHandler(event Event, hnd any, opts ...HandlerOption) error {
+	_, err := h.attachBatchEventHandler(callSite(0), event, hnd, opts)
+	return err
+}
+
+// AttachBatchEventHandler() is like RegisterBatchEventHandler(), but returns
+// a *Registration through which the handler can later be removed.
+func (h *Hub[Tx]) AttachBatchEventHandler(event Event, hnd any, opts ...HandlerOption) (*Registration, error) {
+	return h.attachBatchEventHandler(callSite(0), event, hnd, opts)
+}
+
+func (h *Hub[Tx]) attachBatchEventHandler(site string, event Event, hnd any, opts []HandlerOption) (*Registration, error) {
+	if h.frozen.Load() {
+		return nil, ErrHubFrozen
+	}
+	ty := reflect.TypeOf(event)
+	return h.attach(site, eventKey{ty: ty}, event.EventName(), hnd, makeBatchEventHandler[Tx](ty, hnd), opts)
+}
+
+// makeBatchEventHandler creates a batch handler for events of eventType.
+func makeBatchEventHandler[Tx Transaction](eventType reflect.Type, fn any) *batchEventHandler[Tx] {
+	ty := reflect.TypeOf(fn)
+	if ty == nil || ty.Kind() != reflect.Func {
+		panic(fmt.Errorf("event handler type %T is not a function", fn))
+	} else if ty.NumIn() < 1 {
+		panic(fmt.Errorf("event handler must declare 1..2 parameters"))
+	}
+	param := ty.In(ty.NumIn() - 1)
+	if param.Kind() != reflect.Slice || !eventType.AssignableTo(param.Elem()) {
+		panic(fmt.Errorf("concrete event type %s is not assignable to elements of batch event handler parameter %s", eventType, param))
+	}
+	return &batchEventHandler[Tx]{genericEventHandler: newGenericEventHandler[Tx](param, fn)}
+}
+
+// batchEventHandler adds each event dispatched to it to the operation's
+// batch for the handler, passing the batch to its function when the
+// operation flushes its batches.
+type batchEventHandler[Tx Transaction] struct {
+	*genericEventHandler[Tx]
+}
+
+func (h *batchEventHandler[Tx]) Dispatch(op *OpContext[Tx], evt any) error {
+	op.addToBatch(h)
+	return nil
+}
+
+func (h *batchEventHandler[Tx]) flush(op *OpContext[Tx], evts []queuedEvent) error {
+	batch := reflect.MakeSlice(h.evtParameterType, len(evts), len(evts))
+	for i, qe := range evts {
+		batch.Index(i).Set(reflect.ValueOf(qe.evt))
+	}
+	return h.genericEventHandler.Dispatch(op, batch.Interface())
+}
+
+// eventBatch holds the events dispatched to a batch handler by an operation,
+// pending the handler's call.
+type eventBatch[Tx Transaction] struct {
+	hnd  *batchEventHandler[Tx]
+	evts []queuedEvent
+}
+
+// addToBatch adds the event being dispatched to the operation's batch for
+// hnd.
+func (o *OpContext[T]) addToBatch(hnd *batchEventHandler[T]) {
+	for i := range o.batches {
+		if o.batches[i].hnd == hnd {
+			o.batches[i].evts = append(o.batches[i].evts, o.dispatching)
+			return
+		}
+	}
+	o.batches = append(o.batches, eventBatch[T]{hnd: hnd, evts: []queuedEvent{o.dispatching}})
+}
+
+// flushBatches passes each of the operation's batches to its handler, in the
+// order in which the batches were started.
+func (o *OpContext[T]) flushBatches() error {
+	batches := o.batches
+	o.batches = nil
+	for _, b := range batches {
+		last := b.evts[len(b.evts)-1]
+		o.emitDepth = last.depth + 1
+		o.dispatching, o.cause = last, nil
+		err := b.hnd.flush(o, b.evts)
+		if o.cascadeErr != nil {
+			return o.cascadeErr
+		} else if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package operator
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatchEventHandler(t *testing.T) {
+	hub := newTestHub()
+	var log []string
+	var batches [][]int
+	assert.NoError(t, hub.RegisterBatchEventHandler(&testEvent{}, func(ctx *OpContext[*TxTest], evts []*testEvent) error {
+		var vals []int
+		for _, evt := range evts {
+			vals = append(vals, evt.Val)
+		}
+		batches = append(batches, vals)
+		log = append(log, "batch")
+		if len(batches) == 1 {
+			return ctx.Emit(&otherEvent{})
+		}
+		return nil
+	}))
+	assert.NoError(t, hub.RegisterEventHandler(&testEvent{}, func(evt *testEvent) { log = append(log, "single") }))
+	assert.NoError(t, hub.RegisterEventHandler(&otherEvent{}, func(ctx *OpContext[*TxTest], evt *otherEvent) error {
+		log = append(log, "other")
+		return ctx.Emit(&testEvent{Val: 9})
+	}))
+
+	var tx *TxTest
+	_, err := Invoke(context.Background(), hub, func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) {
+		tx, _ = ctx.Tx()
+		for i := range 3 {
+			ctx.Emit(&testEvent{Val: i})
+		}
+		return in, nil
+	}, &struct{}{})
+	assert.NoError(t, err)
+	assert.True(t, tx.Committed)
+	assert.Equal(t, [][]int{{0, 1, 2}, {9}}, batches)
+	assert.Equal(t, []string{"single", "single", "single", "batch", "other", "single", "batch"}, log)
+
+	info := hub.EventTopology()[1].Handlers[0]
+	assert.True(t, info.Batch)
+}
+
+func TestBatchEventHandler_Error(t *testing.T) {
+	hub := newTestHub()
+	assert.NoError(t, hub.RegisterBatchEventHandler(&testEvent{}, func(evts []Event) error { return assert.AnError }))
+
+	var tx *TxTest
+	_, err := Invoke(context.Background(), hub, func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) {
+		tx, _ = ctx.Tx()
+		return emitTestEvent(ctx, in)
+	}, &struct{}{})
+	assert.ErrorIs(t, err, assert.AnError)
+	assert.True(t, tx.RolledBack)
+
+	// no events, no call
+	_, err = Invoke(context.Background(), hub, func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) { return in, nil }, &struct{}{})
+	assert.NoError(t, err)
+}
+
+func TestBatchEventHandler_Signature(t *testing.T) {
+	hub := newTestHub()
+	assert.PanicsWithError(t, "concrete event type *operator.testEvent is not assignable to elements of batch event handler parameter *operator.testEvent", func() {
+		hub.RegisterBatchEventHandler(&testEvent{}, func(evt *testEvent) {})
+	})
+	assert.PanicsWithError(t, "concrete event type *operator.testEvent is not assignable to elements of batch event handler parameter []*operator.otherEvent", func() {
+		hub.RegisterBatchEventHandler(&testEvent{}, func(evts []*otherEvent) {})
+	})
+}
@@ -0,0 +1,52 @@
+package operator
+
+import "reflect"
+
+// DedupEvent may be implemented by an event to collapse duplicates emitted
+// within a single operation: while an event is queued for dispatch, further
+// events of the same type with the same DedupKey() are discarded, so
+// handlers receive it once. Events emitted after it has been dispatched are
+// queued as usual.
+type DedupEvent interface {
+	Event
+	DedupKey() string
+}
+
+// WithEventDedup collapses duplicate events of the same types as evts within
+// each operation, as if each implemented DedupEvent with a constant key:
+// while an event of one of these types is queued for dispatch, further
+// events of that type are discarded. Events implementing DedupEvent are
+// collapsed by their DedupKey() instead.
+//
+// Use this for events signalling that something needs to be done, such as
+// recalculating totals, which an operation may emit many times but whose
+// handlers need to run only once.
+func WithEventDedup(evts ...Event) HubOption {
+	return func(o *hubOptions) {
+		if o.dedup == nil {
+			o.dedup = map[reflect.Type]bool{}
+		}
+		for _, evt := range evts {
+			o.dedup[reflect.TypeOf(evt)] = true
+		}
+	}
+}
+
+type dedupKey struct {
+	ty  reflect.Type
+	key string
+}
+
+// dedupKeyOf returns the key by which evt is deduplicated, and false if it
+// is not.
+func (h *Hub[Tx]) dedupKeyOf(evt Event) (dedupKey, bool) {
+	if d, ok := evt.(DedupEvent); ok {
+		return dedupKey{ty: reflect.TypeOf(evt), key: d.DedupKey()}, true
+	}
+	if len(h.opts.dedup) > 0 {
+		if ty := reflect.TypeOf(evt); h.opts.dedup[ty] {
+			return dedupKey{ty: ty}, true
+		}
+	}
+	return dedupKey{}, false
+}
@@ -0,0 +1,54 @@
+package operator
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type totalsEvent struct{ Order int }
+
+func (*totalsEvent) EventName() string  { return "totalsEvent" }
+func (e *totalsEvent) DedupKey() string { return strconv.Itoa(e.Order) }
+
+func TestEventDedup_Key(t *testing.T) {
+	hub := newTestHub()
+	var orders []int
+	assert.NoError(t, hub.RegisterEventHandler(&totalsEvent{}, func(evt *totalsEvent) { orders = append(orders, evt.Order) }))
+
+	_, err := Invoke(context.Background(), hub, func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) {
+		for _, o := range []int{1, 2, 1, 1, 2, 3} {
+			ctx.Emit(&totalsEvent{Order: o})
+		}
+		return in, nil
+	}, &struct{}{})
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 2, 3}, orders)
+}
+
+func TestEventDedup_Type(t *testing.T) {
+	hub := newTestHub(WithEventDedup(&otherEvent{}))
+	var tests, others int
+	assert.NoError(t, hub.RegisterEventHandler(&testEvent{}, func(ctx *OpContext[*TxTest], evt *testEvent) {
+		tests++
+		ctx.Emit(&otherEvent{})
+	}))
+	assert.NoError(t, hub.RegisterEventHandler(&otherEvent{}, func(ctx *OpContext[*TxTest], evt *otherEvent) {
+		others++
+		if others == 1 {
+			// queued again once dispatched
+			ctx.Emit(&otherEvent{})
+		}
+	}))
+
+	_, err := Invoke(context.Background(), hub, func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) {
+		ctx.Emit(&testEvent{})
+		ctx.Emit(&testEvent{})
+		return in, ctx.Emit(&otherEvent{})
+	}, &struct{}{})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, tests)
+	assert.Equal(t, 2, others)
+}
@@ -0,0 +1,95 @@
+package operator
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"runtime"
+)
+
+// ErrDuplicateHandler is returned when registering an event handler whose
+// name is already registered for the same event, if the hub's
+// DuplicateHandlerPolicy is RejectDuplicateHandlers.
+var ErrDuplicateHandler = errors.New("duplicate event handler")
+
+// DuplicateHandlerPolicy determines how a hub treats an event handler
+// registered for an event under a name already registered for that event:
+// either the same function registered twice, or two handlers given the same
+// name with HandlerName(). Either is usually a mistake, causing an event's
+// side effects to happen twice, or making After() and Before() ambiguous.
+//
+// Closures created from the same function literal share its default name,
+// but may well be distinct handlers - one per cache or message publisher,
+// say - so are not treated as duplicates unless named with HandlerName().
+type DuplicateHandlerPolicy int
+
+const (
+	// Duplicate handlers are logged as a warning, via the hub's logger, and
+	// registered.
+	WarnDuplicateHandlers DuplicateHandlerPolicy = iota
+
+	// Duplicate handlers are rejected with ErrDuplicateHandler.
+	RejectDuplicateHandlers
+
+	// Duplicate handlers are registered silently, and not reported by
+	// Hub.Validate().
+	AllowDuplicateHandlers
+)
+
+func (p DuplicateHandlerPolicy) String() string {
+	switch p {
+	case WarnDuplicateHandlers:
+		return "warn"
+	case RejectDuplicateHandlers:
+		return "reject"
+	case AllowDuplicateHandlers:
+		return "allow"
+	default:
+		return fmt.Sprintf("DuplicateHandlerPolicy(%d)", int(p))
+	}
+}
+
+// WithDuplicateHandlers sets the hub's policy for duplicate event handlers.
+// The default is WarnDuplicateHandlers.
+func WithDuplicateHandlers(p DuplicateHandlerPolicy) HubOption {
+	return func(o *hubOptions) { o.duplicateHandlers = p }
+}
+
+// duplicateHandlerError describes the registration of reg for the events
+// described by event, whose name was already registered by prev.
+func duplicateHandlerError[Tx Transaction](event string, prev, reg *orderedHandler[Tx]) error {
+	if prev.fn == reg.fn {
+		return fmt.Errorf("%w: handler %s for %s registered at %s was already registered at %s",
+			ErrDuplicateHandler, reg.name, event, reg.site, prev.site)
+	}
+	return fmt.Errorf("%w: handler %s for %s registered at %s has the same name as the handler registered at %s",
+		ErrDuplicateHandler, reg.name, event, reg.site, prev.site)
+}
+
+// checkDuplicate applies the hub's duplicate handler policy to the
+// registration of reg for event, whose name was already registered by prev.
+func (h *Hub[Tx]) checkDuplicate(event string, prev, reg *orderedHandler[Tx]) error {
+	switch h.opts.duplicateHandlers {
+	case AllowDuplicateHandlers:
+		return nil
+	case RejectDuplicateHandlers:
+		return duplicateHandlerError(event, prev, reg)
+	}
+	h.opts.logger.Warn("operator: duplicate event handler", "error", duplicateHandlerError(event, prev, reg))
+	return nil
+}
+
+// funcLiteralName matches the names the runtime gives to function literals,
+// e.g. "main.main.func1", or "main.main.func1.2" for nested literals.
+var funcLiteralName = regexp.MustCompile(`\.func\d+(\.\d+)*$`)
+
+// callSite returns the file and line from which the function calling
+// callSite was called, skipping skip further frames, for reporting where a
+// registration was made.
+func callSite(skip int) string {
+	_, file, line, ok := runtime.Caller(skip + 2)
+	if !ok {
+		return "unknown"
+	}
+	return fmt.Sprintf("%s:%d", file, line)
+}
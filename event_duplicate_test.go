@@ -0,0 +1,62 @@
+package operator
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDuplicateHandlers_Reject(t *testing.T) {
+	hub := newTestHub(WithDuplicateHandlers(RejectDuplicateHandlers))
+	_, file, line, _ := runtime.Caller(0)
+
+	assert.NoError(t, hub.RegisterEventHandler(&testEvent{}, auditTestEvent))
+	err := hub.RegisterEventHandler(&testEvent{}, auditTestEvent)
+	assert.ErrorIs(t, err, ErrDuplicateHandler)
+	assert.EqualError(t, err, fmt.Sprintf("duplicate event handler: handler github.com/jaz303/operator.auditTestEvent "+
+		"for event testEvent (*operator.testEvent) registered at %s:%d was already registered at %s:%d", file, line+3, file, line+2))
+
+	// the handler may be registered for other events
+	assert.NoError(t, hub.RegisterNamedEventHandler("testEvent", auditTestEvent))
+
+	assert.NoError(t, hub.RegisterEventHandler(&testEvent{}, func(evt *testEvent) {}, HandlerName("audit")))
+	_, err = hub.AttachEventHandler(&testEvent{}, func(evt *testEvent) {}, HandlerName("audit"))
+	assert.EqualError(t, err, fmt.Sprintf("duplicate event handler: handler audit for event testEvent (*operator.testEvent) "+
+		"registered at %s:%d has the same name as the handler registered at %s:%d", file, line+12, file, line+11))
+
+	assert.Equal(t, 2, len(hub.EventTopology()[1].Handlers))
+	assert.NoError(t, hub.Validate())
+}
+
+func TestDuplicateHandlers_Closures(t *testing.T) {
+	hub := newTestHub(WithDuplicateHandlers(RejectDuplicateHandlers))
+	for i := range 2 {
+		assert.NoError(t, hub.RegisterEventHandler(&testEvent{}, func(evt *testEvent) { evt.Val += i }))
+	}
+	assert.NoError(t, hub.Validate())
+}
+
+func TestDuplicateHandlers_Warn(t *testing.T) {
+	var logs bytes.Buffer
+	hub := newTestHub(WithLogger(slog.New(slog.NewTextHandler(&logs, nil))))
+
+	assert.NoError(t, hub.RegisterEventHandler(&testEvent{}, auditTestEvent))
+	assert.NoError(t, hub.RegisterEventHandler(&testEvent{}, auditTestEvent))
+	assert.Contains(t, logs.String(), "operator: duplicate event handler")
+	assert.Contains(t, logs.String(), "handler github.com/jaz303/operator.auditTestEvent for event testEvent")
+	assert.ErrorIs(t, hub.Validate(), ErrDuplicateHandler)
+}
+
+func TestDuplicateHandlers_Allow(t *testing.T) {
+	var logs bytes.Buffer
+	hub := newTestHub(WithDuplicateHandlers(AllowDuplicateHandlers), WithLogger(slog.New(slog.NewTextHandler(&logs, nil))))
+
+	assert.NoError(t, hub.RegisterEventHandler(&testEvent{}, auditTestEvent))
+	assert.NoError(t, hub.RegisterEventHandler(&testEvent{}, auditTestEvent))
+	assert.Empty(t, logs.String())
+	assert.NoError(t, hub.Validate())
+}
@@ -0,0 +1,64 @@
+package operator
+
+import (
+	"fmt"
+	"path"
+)
+
+// ForOps restricts the handler to events emitted by operations whose names
+// match any of patterns, which use the syntax of path.Match; e.g. "orders.*"
+// matches every operation in package orders. Events emitted by event
+// handlers are attributed to the operation that dispatches them. Events
+// emitted with EmitAfterCommit() are dispatched by a separate operation, so
+// are not matched by the name of the operation that emitted them.
+//
+// ForOps panics if a pattern is malformed.
+func ForOps(patterns ...string) HandlerOption {
+	checkOpPatterns(patterns)
+	return func(r *handlerRegistration) { r.ops = append(r.ops, patterns...) }
+}
+
+// ExceptOps prevents the handler from receiving events emitted by operations
+// whose names match any of patterns; see ForOps() for the syntax. ExceptOps
+// takes precedence over ForOps.
+//
+// ExceptOps panics if a pattern is malformed.
+func ExceptOps(patterns ...string) HandlerOption {
+	checkOpPatterns(patterns)
+	return func(r *handlerRegistration) { r.exceptOps = append(r.exceptOps, patterns...) }
+}
+
+func checkOpPatterns(patterns []string) {
+	for _, p := range patterns {
+		if _, err := path.Match(p, ""); err != nil {
+			panic(fmt.Errorf("invalid operation pattern %q (%w)", p, err))
+		}
+	}
+}
+
+// filteredEventHandler dispatches to its handler only for matching
+// operations.
+type filteredEventHandler[Tx Transaction] struct {
+	eventHandler[Tx]
+	ops       []string
+	exceptOps []string
+}
+
+func (h *filteredEventHandler[Tx]) Dispatch(op *OpContext[Tx], evt any) error {
+	if matchesOp(h.exceptOps, op.name) {
+		return nil
+	}
+	if len(h.ops) > 0 && !matchesOp(h.ops, op.name) {
+		return nil
+	}
+	return h.eventHandler.Dispatch(op, evt)
+}
+
+func matchesOp(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,46 @@
+package operator
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestForOps(t *testing.T) {
+	hub := newTestHub()
+
+	var audit, metrics, all []string
+	hub.RegisterEventHandler(&testEvent{}, func(ctx *OpContext[*TxTest], evt *testEvent) error {
+		audit = append(audit, ctx.Name())
+		return nil
+	}, ForOps("orders.*", "users.Delete"))
+	hub.RegisterEventHandler(&testEvent{}, func(ctx *OpContext[*TxTest], evt *testEvent) error {
+		metrics = append(metrics, ctx.Name())
+		return nil
+	}, ExceptOps("orders.Internal*"), HandlerName("metrics"))
+	hub.RegisterEventHandler(&testEvent{}, func(ctx *OpContext[*TxTest], evt *testEvent) error {
+		all = append(all, ctx.Name())
+		return nil
+	}, ForOps("*"), ExceptOps("users.*"))
+
+	ops := map[string]Operation[*TxTest, struct{}, struct{}]{
+		"orders.Create":       func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) { return in, ctx.Emit(&testEvent{}) },
+		"orders.InternalSync": func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) { return in, ctx.Emit(&testEvent{}) },
+		"users.Delete":        func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) { return in, ctx.Emit(&testEvent{}) },
+		"users.Create":        func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) { return in, ctx.Emit(&testEvent{}) },
+	}
+	for _, name := range []string{"orders.Create", "orders.InternalSync", "users.Delete", "users.Create"} {
+		assert.NoError(t, RegisterOperation(hub, name, ops[name]))
+		_, err := Invoke(context.Background(), hub, ops[name], &struct{}{})
+		assert.Nil(t, err)
+	}
+
+	assert.Equal(t, []string{"orders.Create", "orders.InternalSync", "users.Delete"}, audit)
+	assert.Equal(t, []string{"orders.Create", "users.Delete", "users.Create"}, metrics)
+	assert.Equal(t, []string{"orders.Create", "orders.InternalSync"}, all)
+}
+
+func TestForOps_InvalidPattern(t *testing.T) {
+	assert.Panics(t, func() { ForOps("orders.[") })
+}
@@ -0,0 +1,171 @@
+package operator
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+var ErrHandlerCycle = errors.New("event handler ordering cycle")
+
+// HandlerOption configures the registration of an event handler; pass options
+// to Hub.RegisterEventHandler().
+type HandlerOption func(r *handlerRegistration)
+
+type handlerRegistration struct {
+	name     string
+	priority int
+	after    []string
+	before   []string
+
+	// operation name patterns; see ForOps() and ExceptOps()
+	ops       []string
+	exceptOps []string
+
+	phase DispatchPhase
+
+	// where the handler sends events; see Destination()
+	destination string
+}
+
+// HandlerName names the handler for the purposes of ordering, overriding the
+// default name (the handler function's fully-qualified name). Other handlers
+// can then refer to it by name in After() and Before().
+func HandlerName(name string) HandlerOption {
+	return func(r *handlerRegistration) { r.name = name }
+}
+
+// Destination records where the handler sends the events it receives, such
+// as a message broker subject or topic, for reporting by
+// Hub.EventTopology(). It has no effect on dispatch.
+func Destination(dest string) HandlerOption {
+	return func(r *handlerRegistration) { r.destination = dest }
+}
+
+// Priority sets the handler's priority. Among handlers whose ordering is not
+// otherwise constrained by After() or Before(), higher priorities are
+// dispatched first. The default priority is 0.
+func Priority(p int) HandlerOption {
+	return func(r *handlerRegistration) { r.priority = p }
+}
+
+// After declares that the handler must be dispatched after the named handlers
+// of the same event type. Names that do not match a registered handler are
+// ignored, so handlers may be registered in any order.
+func After(names ...string) HandlerOption {
+	return func(r *handlerRegistration) { r.after = append(r.after, names...) }
+}
+
+// Before declares that the handler must be dispatched before the named
+// handlers of the same event type. Names that do not match a registered
+// handler are ignored.
+func Before(names ...string) HandlerOption {
+	return func(r *handlerRegistration) { r.before = append(r.before, names...) }
+}
+
+type namedEventHandler[Tx Transaction] struct {
+	eventHandler[Tx]
+	name string
+}
+
+func (h *namedEventHandler[Tx]) Name() string { return h.name }
+
+type orderedHandler[Tx Transaction] struct {
+	handlerRegistration
+	hnd          eventHandler[Tx]
+	registration *Registration
+
+	// event parameter type of a handler registered by name
+	param reflect.Type
+
+	// entry point of the handler function, and where it was registered
+	fn   uintptr
+	site string
+
+	// true if the handler is dispatched by a registered dispatcher
+	static bool
+
+	// true if the handler receives events in batches
+	batch bool
+
+	// true if the handler has the default name of a function literal, which
+	// is shared by every closure created from it, so does not identify the
+	// handler
+	anonymous bool
+}
+
+// orderHandlers returns regs in dispatch order: a topological sort of the
+// After()/Before() constraints, choosing the highest priority, then earliest
+// registered, handler whenever more than one is eligible. The result is
+// deterministic for a given set of registrations, and is registration order
+// when no options are used. Returns ErrHandlerCycle if the constraints cannot
+// be satisfied.
+func orderHandlers[Tx Transaction](regs []orderedHandler[Tx]) ([]eventHandler[Tx], error) {
+	ordered, err := orderRegistrations(regs)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]eventHandler[Tx], len(ordered))
+	for i, r := range ordered {
+		out[i] = r.hnd
+	}
+	return out, nil
+}
+
+// orderRegistrations returns regs in dispatch order; see orderHandlers.
+func orderRegistrations[Tx Transaction](regs []orderedHandler[Tx]) ([]orderedHandler[Tx], error) {
+	byName := make(map[string][]int, len(regs))
+	for i, r := range regs {
+		byName[r.name] = append(byName[r.name], i)
+	}
+
+	succ := make([][]int, len(regs))
+	indeg := make([]int, len(regs))
+	edge := func(from, to int) {
+		succ[from] = append(succ[from], to)
+		indeg[to]++
+	}
+	for i, r := range regs {
+		for _, n := range r.after {
+			for _, j := range byName[n] {
+				edge(j, i)
+			}
+		}
+		for _, n := range r.before {
+			for _, j := range byName[n] {
+				edge(i, j)
+			}
+		}
+	}
+
+	out := make([]orderedHandler[Tx], 0, len(regs))
+	done := make([]bool, len(regs))
+	for len(out) < len(regs) {
+		next := -1
+		for i, r := range regs {
+			if done[i] || indeg[i] > 0 {
+				continue
+			}
+			if next < 0 || r.priority > regs[next].priority {
+				next = i
+			}
+		}
+		if next < 0 {
+			var names []string
+			for i, r := range regs {
+				if !done[i] {
+					names = append(names, r.name)
+				}
+			}
+			return nil, fmt.Errorf("%w between handlers %s", ErrHandlerCycle, strings.Join(names, ", "))
+		}
+		done[next] = true
+		out = append(out, regs[next])
+		for _, j := range succ[next] {
+			indeg[j]--
+		}
+	}
+
+	return out, nil
+}
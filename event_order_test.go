@@ -0,0 +1,45 @@
+package operator
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventHandlerOrdering(t *testing.T) {
+	hub := newTestHub()
+
+	var order []string
+	record := func(name string) func(*testEvent) {
+		return func(*testEvent) { order = append(order, name) }
+	}
+
+	assert.NoError(t, hub.RegisterEventHandler(&testEvent{}, record("notify"), HandlerName("notify"), After("audit")))
+	assert.NoError(t, hub.RegisterEventHandler(&testEvent{}, record("plain"), HandlerName("plain")))
+	assert.NoError(t, hub.RegisterEventHandler(&testEvent{}, record("urgent"), HandlerName("urgent"), Priority(10)))
+	assert.NoError(t, hub.RegisterEventHandler(&testEvent{}, record("audit"), HandlerName("audit")))
+	assert.NoError(t, hub.RegisterEventHandler(&testEvent{}, record("first"), HandlerName("first"), Before("urgent")))
+
+	_, err := Invoke(context.Background(), hub, func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) {
+		return in, ctx.Emit(&testEvent{})
+	}, &struct{}{})
+	assert.NoError(t, err)
+
+	// "urgent" has the highest priority but must wait for "first"; priority
+	// only decides between handlers that are eligible to run
+	assert.Equal(t, []string{"plain", "audit", "notify", "first", "urgent"}, order)
+
+	topo := hub.EventTopology()
+	assert.Equal(t, "audit", topo[0].Handlers[1].Name)
+}
+
+func TestEventHandlerOrdering_Cycle(t *testing.T) {
+	hub := newTestHub()
+
+	assert.NoError(t, hub.RegisterEventHandler(&testEvent{}, func(*testEvent) {}, HandlerName("a"), After("b")))
+	assert.ErrorIs(t, hub.RegisterEventHandler(&testEvent{}, func(*testEvent) {}, HandlerName("b"), After("a")), ErrHandlerCycle)
+
+	// the rejected handler is not registered
+	assert.Len(t, hub.EventTopology()[0].Handlers, 1)
+}
@@ -0,0 +1,103 @@
+package operator
+
+import (
+	"context"
+	"fmt"
+)
+
+// DispatchPhase determines when an event handler receives the events
+// dispatched to it, relative to the commit of the emitting operation. An
+// operation emits each event once, with OpContext.Emit(), and each handler
+// receives it in the phase it was registered for; see InPhase().
+type DispatchPhase int
+
+const (
+	// The handler is called before the operation commits, within its
+	// transaction; a handler error fails the operation.
+	BeforeCommit DispatchPhase = iota
+
+	// The handler is called once the operation has committed, as if by an
+	// AfterFunc; the operation's transaction is no longer available, and
+	// handler errors are passed to the hub's after func error handler.
+	AfterCommit
+
+	// The handler is called once the operation has committed, in a new
+	// operation with its own transaction, on the hub's worker pool, as for
+	// events emitted with OpContext.EmitAfterCommit(). Failures are retried,
+	// and dead-lettered, according to the hub's options.
+	Async
+)
+
+func (p DispatchPhase) String() string {
+	switch p {
+	case BeforeCommit:
+		return "before_commit"
+	case AfterCommit:
+		return "after_commit"
+	case Async:
+		return "async"
+	default:
+		return fmt.Sprintf("DispatchPhase(%d)", int(p))
+	}
+}
+
+// InPhase sets the phase in which the handler receives events. The default
+// is BeforeCommit. Ordering options apply among handlers of every phase, but
+// handlers of later phases are called after those of earlier ones. Batch
+// event handlers must be dispatched BeforeCommit.
+func InPhase(p DispatchPhase) HandlerOption {
+	return func(r *handlerRegistration) { r.phase = p }
+}
+
+// phasedEventHandler defers its handler's dispatch to a later phase.
+type phasedEventHandler[Tx Transaction] struct {
+	eventHandler[Tx]
+	phase DispatchPhase
+}
+
+func (h *phasedEventHandler[Tx]) Dispatch(op *OpContext[Tx], evt any) error {
+	switch h.phase {
+	case AfterCommit:
+		return op.AfterFunc(func(op *OpContext[Tx]) {
+			if err := h.eventHandler.Dispatch(op, evt); err != nil {
+				op.hub.opts.onAfterFuncError(fmt.Errorf("event handler %s failed (%w)", h.Name(), err))
+			}
+		})
+	case Async:
+		e := evt.(Event)
+		return op.enqueueFollowUp(&followUp{
+			run:     op.hub.asyncDispatch(h.eventHandler, e),
+			event:   e,
+			handler: h.Name(),
+		})
+	}
+	return h.eventHandler.Dispatch(op, evt)
+}
+
+// asyncDispatch returns a function dispatching evt to hnd in a new
+// operation.
+func (h *Hub[Tx]) asyncDispatch(hnd eventHandler[Tx], evt Event) func(context.Context) error {
+	return func(ctx context.Context) error {
+		_, err := Invoke(ctx, h, func(op *OpContext[Tx], evt *Event) (*struct{}, error) {
+			return &struct{}{}, hnd.Dispatch(op, *evt)
+		}, &evt)
+		return err
+	}
+}
+
+// asyncHandler returns the handler named name registered to receive evt in
+// the Async phase, if any.
+func (h *Hub[Tx]) asyncHandler(evt Event, name string) (eventHandler[Tx], bool) {
+	byType, byName := h.events.handlers(evt)
+	for _, hnds := range [2][]eventHandler[Tx]{byType, byName} {
+		for _, hnd := range hnds {
+			if f, ok := hnd.(*filteredEventHandler[Tx]); ok {
+				hnd = f.eventHandler
+			}
+			if p, ok := hnd.(*phasedEventHandler[Tx]); ok && p.phase == Async && p.Name() == name {
+				return p.eventHandler, true
+			}
+		}
+	}
+	return nil, false
+}
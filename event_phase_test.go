@@ -0,0 +1,106 @@
+package operator
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jaz303/operator/deadletter"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDispatchPhase(t *testing.T) {
+	var afterErr error
+	hub := newTestHub(WithAfterFuncErrorHandler(func(err error) { afterErr = err }))
+
+	var log []string
+	var async atomic.Int32
+	assert.NoError(t, hub.RegisterEventHandler(&testEvent{}, func(ctx *OpContext[*TxTest], evt *testEvent) error {
+		log = append(log, "after")
+		return assert.AnError
+	}, InPhase(AfterCommit), HandlerName("after")))
+	assert.NoError(t, hub.RegisterEventHandler(&testEvent{}, func(ctx *OpContext[*TxTest], evt *testEvent) {
+		tx, _ := ctx.Tx()
+		if tx != nil && evt.Val == 9 {
+			async.Add(1)
+		}
+	}, InPhase(Async)))
+	assert.NoError(t, hub.RegisterEventHandler(&testEvent{}, func(evt *testEvent) { log = append(log, "before") }))
+
+	var tx *TxTest
+	_, err := Invoke(context.Background(), hub, func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) {
+		tx, _ = ctx.Tx()
+		ctx.AfterFunc(func(*OpContext[*TxTest]) { log = append(log, "func") })
+		return in, ctx.Emit(&testEvent{Val: 9})
+	}, &struct{}{})
+	assert.NoError(t, err)
+	assert.True(t, tx.Committed)
+	assert.Equal(t, []string{"before", "func", "after"}, log)
+	assert.ErrorIs(t, afterErr, assert.AnError)
+	assert.ErrorContains(t, afterErr, "event handler after failed")
+	assert.Eventually(t, func() bool { return async.Load() == 1 }, time.Second, time.Millisecond)
+
+	phases := map[DispatchPhase]int{}
+	for _, h := range hub.EventTopology()[0].Handlers {
+		phases[h.Phase]++
+	}
+	assert.Equal(t, map[DispatchPhase]int{BeforeCommit: 1, AfterCommit: 1, Async: 1}, phases)
+}
+
+func TestDispatchPhase_RollBack(t *testing.T) {
+	hub := newTestHub()
+	var called atomic.Int32
+	for _, p := range []DispatchPhase{AfterCommit, Async} {
+		assert.NoError(t, hub.RegisterEventHandler(&testEvent{}, func(evt *testEvent) { called.Add(1) }, InPhase(p), HandlerName(p.String())))
+	}
+
+	_, err := Invoke(context.Background(), hub, func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) {
+		ctx.Emit(&testEvent{})
+		return nil, assert.AnError
+	}, &struct{}{})
+	assert.ErrorIs(t, err, assert.AnError)
+	assert.NoError(t, hub.Shutdown(context.Background()))
+	assert.Equal(t, int32(0), called.Load())
+}
+
+func TestDispatchPhase_DeadLetter(t *testing.T) {
+	store := deadletter.NewMemoryStore()
+	hub := newTestHub(WithDeadLetterSink(store), WithBackgroundErrorHandler(func(error) {}))
+
+	var sync, async atomic.Int32
+	var fail atomic.Bool
+	fail.Store(true)
+	assert.NoError(t, hub.RegisterEventHandler(&testEvent{}, func(evt *testEvent) { sync.Add(1) }))
+	assert.NoError(t, hub.RegisterEventHandler(&testEvent{}, func(evt *testEvent) error {
+		async.Add(1)
+		if fail.Load() {
+			return errors.New("handler failed")
+		}
+		return nil
+	}, InPhase(Async), HandlerName("async")))
+
+	_, err := Invoke(context.Background(), hub, emitTestEvent, &struct{}{})
+	assert.NoError(t, err)
+
+	var letters []deadletter.Letter
+	assert.Eventually(t, func() bool {
+		letters, _ = store.List(context.Background(), deadletter.Filter{})
+		return len(letters) == 1
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, "async", letters[0].Metadata["handler"])
+
+	// only the failed handler receives the requeued event
+	fail.Store(false)
+	assert.NoError(t, deadletter.Requeue(context.Background(), store, letters[0].ID, hub.RequeueDeadLetter))
+	assert.Eventually(t, func() bool { return async.Load() == 2 }, time.Second, time.Millisecond)
+	assert.Equal(t, int32(1), sync.Load())
+}
+
+func TestDispatchPhase_Batch(t *testing.T) {
+	hub := newTestHub()
+	assert.Panics(t, func() {
+		hub.RegisterBatchEventHandler(&testEvent{}, func(evts []*testEvent) {}, InPhase(Async))
+	})
+}
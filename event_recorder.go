@@ -0,0 +1,152 @@
+package operator
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"sync"
+)
+
+// RecordedEvent describes a single event dispatch captured by an
+// EventRecorder.
+type RecordedEvent struct {
+	// Sequence number of the operation that emitted the event, starting at 1.
+	// Events sharing an operation number were emitted by the same operation.
+	Operation int `json:"operation"`
+
+	// Depth is 0 for events emitted by the operation itself; events emitted
+	// by event handlers have a depth one greater than the event being handled.
+	Depth int `json:"depth"`
+
+	Name string `json:"name"`
+	Type string `json:"type"`
+
+	// Event as dispatched. It is not serialized, so is nil in recordings
+	// read back from JSON; use Payload instead.
+	Event Event `json:"-"`
+
+	// JSON encoding of the event, decoded through the hub's registered event
+	// types by Hub.DecodeEvent(Name, Version, Payload). Nil if the event
+	// could not be encoded.
+	Payload json.RawMessage `json:"payload"`
+
+	// Version of the event, as returned by EventVersion()
+	Version int `json:"version"`
+
+	// Results of each handler invoked for this event, in dispatch order.
+	Results []HandlerResult `json:"results"`
+}
+
+// HandlerResult is the outcome of a single event handler invocation.
+type HandlerResult struct {
+	Handler string `json:"handler"`
+	Error   string `json:"error,omitempty"`
+}
+
+// EventRecorder captures every event dispatched by a Hub, along with the
+// results of each handler. Recordings can be compared against golden files
+// to verify that a refactor emits the same events, or replayed against a
+// hub with Hub.ReplayEvents().
+type EventRecorder struct {
+	mu     sync.Mutex
+	opSeq  int
+	events []RecordedEvent
+	stop   func()
+}
+
+// Events returns a copy of the events recorded so far.
+func (r *EventRecorder) Events() []RecordedEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]RecordedEvent(nil), r.events...)
+}
+
+// Reset discards all recorded events.
+func (r *EventRecorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = nil
+	r.opSeq = 0
+}
+
+// Stop detaches the recorder from its hub. Events already recorded remain
+// available.
+func (r *EventRecorder) Stop() {
+	r.stop()
+}
+
+func (r *EventRecorder) record(opSeq *int, depth int, evt Event, results []HandlerResult) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if *opSeq == 0 {
+		r.opSeq++
+		*opSeq = r.opSeq
+	}
+
+	payload, _ := json.Marshal(evt)
+	r.events = append(r.events, RecordedEvent{
+		Operation: *opSeq,
+		Depth:     depth,
+		Name:      evt.EventName(),
+		Type:      reflect.TypeOf(evt).String(),
+		Event:     evt,
+		Payload:   payload,
+		Version:   EventVersion(evt),
+		Results:   results,
+	})
+}
+
+// RecordEvents begins recording all events dispatched by the hub, replacing
+// any active recorder. Recording continues until the returned recorder's
+// Stop() method is called.
+func (h *Hub[Tx]) RecordEvents() *EventRecorder {
+	rec := &EventRecorder{}
+	rec.stop = func() { h.recorder.CompareAndSwap(rec, nil) }
+	h.recorder.Store(rec)
+	return rec
+}
+
+// ReplayEvents re-dispatches a recorded event sequence against the hub.
+// Events are decoded from their payloads with DecodeEvent(), so recordings
+// read back from golden files replay as recorded, upcast if necessary; their
+// types must be registered with the hub, by registering handlers or with
+// RegisterEventType().
+//
+// Only events emitted directly by operations (depth 0) are replayed; events
+// that were emitted by handlers are expected to be re-emitted by the hub's
+// own handlers. Each recorded operation is replayed as a separate operation,
+// with its own transaction, so the replay is subject to the same commit and
+// rollback semantics as the original.
+func (h *Hub[Tx]) ReplayEvents(ctx context.Context, events []RecordedEvent) error {
+	var batch []Event
+	op := 0
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		_, err := Invoke(ctx, h, emitEvents[Tx], &batch)
+		batch = nil
+		return err
+	}
+
+	for _, re := range events {
+		if re.Depth != 0 {
+			continue
+		}
+		if re.Operation != op {
+			if err := flush(); err != nil {
+				return err
+			}
+			op = re.Operation
+		}
+		evt, err := h.DecodeEvent(re.Name, re.Version, re.Payload)
+		if err != nil {
+			return err
+		}
+		batch = append(batch, evt)
+	}
+
+	return flush()
+}
@@ -0,0 +1,117 @@
+package operator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type followUpEvent struct {
+	Val int
+}
+
+func (e *followUpEvent) EventName() string { return "followUpEvent" }
+
+func emitTestEvents(ctx *OpContext[*TxTest], in *[]int) (*struct{}, error) {
+	for _, v := range *in {
+		if err := ctx.Emit(&testEvent{Val: v}); err != nil {
+			return nil, err
+		}
+	}
+	return &struct{}{}, nil
+}
+
+func TestRecordEvents(t *testing.T) {
+	hub := newTestHub()
+	hub.RegisterEventHandler(&testEvent{}, func(ctx *OpContext[*TxTest], evt *testEvent) error {
+		return ctx.Emit(&followUpEvent{Val: evt.Val * 10})
+	})
+	hub.RegisterEventHandler(&followUpEvent{}, func(evt *followUpEvent) error {
+		if evt.Val > 100 {
+			return errors.New("too big")
+		}
+		return nil
+	})
+
+	rec := hub.RecordEvents()
+
+	_, err := Invoke(context.Background(), hub, emitTestEvents, &[]int{1, 2})
+	assert.Nil(t, err)
+
+	_, err = Invoke(context.Background(), hub, emitTestEvents, &[]int{20})
+	assert.NotNil(t, err)
+
+	rec.Stop()
+
+	_, err = Invoke(context.Background(), hub, emitTestEvents, &[]int{3})
+	assert.Nil(t, err)
+
+	events := rec.Events()
+	assert.Equal(t, 6, len(events))
+
+	type summary struct {
+		op, depth int
+		name      string
+		err       string
+	}
+	var actual []summary
+	for _, e := range events {
+		assert.Equal(t, 1, len(e.Results))
+		actual = append(actual, summary{e.Operation, e.Depth, e.Name, e.Results[0].Error})
+	}
+
+	assert.Equal(t, []summary{
+		{1, 0, "testEvent", ""},
+		{1, 0, "testEvent", ""},
+		{1, 1, "followUpEvent", ""},
+		{1, 1, "followUpEvent", ""},
+		{2, 0, "testEvent", ""},
+		{2, 1, "followUpEvent", "too big"},
+	}, actual)
+}
+
+func TestReplayEvents(t *testing.T) {
+	source := newTestHub()
+	rec := source.RecordEvents()
+	_, err := Invoke(context.Background(), source, emitTestEvents, &[]int{1, 2})
+	assert.Nil(t, err)
+	_, err = Invoke(context.Background(), source, emitTestEvents, &[]int{3})
+	assert.Nil(t, err)
+
+	var seen []int
+	target := newTestHub()
+	target.RegisterEventHandler(&testEvent{}, func(evt *testEvent) {
+		seen = append(seen, evt.Val)
+	})
+
+	assert.Nil(t, target.ReplayEvents(context.Background(), rec.Events()))
+	assert.Equal(t, []int{1, 2, 3}, seen)
+}
+
+func TestReplayEvents_FromJSON(t *testing.T) {
+	source := newTestHub()
+	rec := source.RecordEvents()
+	_, err := Invoke(context.Background(), source, emitTestEvents, &[]int{1, 2})
+	assert.Nil(t, err)
+
+	golden, err := json.Marshal(rec.Events())
+	assert.Nil(t, err)
+	assert.Contains(t, string(golden), `"payload":{"Val":1}`)
+
+	var events []RecordedEvent
+	assert.Nil(t, json.Unmarshal(golden, &events))
+	assert.Nil(t, events[0].Event)
+
+	var seen []int
+	target := newTestHub()
+	target.RegisterEventHandler(&testEvent{}, func(evt *testEvent) {
+		seen = append(seen, evt.Val)
+	})
+	assert.Nil(t, target.ReplayEvents(context.Background(), events))
+	assert.Equal(t, []int{1, 2}, seen)
+
+	assert.ErrorIs(t, newTestHub().ReplayEvents(context.Background(), events), ErrUnknownEvent)
+}
@@ -0,0 +1,39 @@
+package operator
+
+import (
+	"context"
+	"sync"
+)
+
+// Registration is a handle to an event handler registered with
+// Hub.AttachEventHandler(), through which the handler can be removed.
+type Registration struct {
+	once   sync.Once
+	remove func() error
+	err    error
+}
+
+// Remove removes the handler from the hub. Operations that have already begun
+// dispatching an event may still invoke the handler for that event. Calling
+// Remove more than once has no further effect.
+//
+// Returns ErrHubFrozen if the hub has been frozen.
+func (r *Registration) Remove() error {
+	r.once.Do(func() { r.err = r.remove() })
+	return r.err
+}
+
+// RemoveWhenDone arranges for the handler to be removed once ctx is done,
+// scoping the registration to the lifetime of ctx. It returns r.
+func (r *Registration) RemoveWhenDone(ctx context.Context) *Registration {
+	context.AfterFunc(ctx, func() { r.Remove() })
+	return r
+}
+
+// RemoveOnCleanup arranges for the handler to be removed when tb's cleanup
+// functions run, scoping the registration to a test. tb is typically a
+// *testing.T or *testing.B. It returns r.
+func (r *Registration) RemoveOnCleanup(tb interface{ Cleanup(func()) }) *Registration {
+	tb.Cleanup(func() { r.Remove() })
+	return r
+}
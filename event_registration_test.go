@@ -0,0 +1,66 @@
+package operator
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistration_Remove(t *testing.T) {
+	hub := newTestHub()
+
+	calls := 0
+	reg, err := hub.AttachEventHandler(&testEvent{}, func(*testEvent) { calls++ })
+	assert.NoError(t, err)
+
+	emit := func() {
+		_, err := Invoke(context.Background(), hub, benchEmitOperation, &struct{}{})
+		assert.NoError(t, err)
+	}
+
+	emit()
+	assert.NoError(t, reg.Remove())
+	assert.NoError(t, reg.Remove())
+	emit()
+	assert.Equal(t, 1, calls)
+	assert.Empty(t, hub.EventTopology())
+}
+
+func TestRegistration_RemoveWhenDone(t *testing.T) {
+	hub := newTestHub()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	reg, err := hub.AttachEventHandler(&testEvent{}, func(*testEvent) {})
+	assert.NoError(t, err)
+	reg.RemoveWhenDone(ctx)
+
+	assert.Len(t, hub.EventTopology(), 1)
+	cancel()
+	assert.Eventually(t, func() bool { return len(hub.EventTopology()) == 0 }, time.Second, time.Millisecond)
+}
+
+func TestRegistration_RemoveOnCleanup(t *testing.T) {
+	hub := newTestHub()
+
+	t.Run("scoped", func(t *testing.T) {
+		reg, err := hub.AttachEventHandler(&testEvent{}, func(*testEvent) {})
+		assert.NoError(t, err)
+		reg.RemoveOnCleanup(t)
+		assert.Len(t, hub.EventTopology(), 1)
+	})
+
+	assert.Empty(t, hub.EventTopology())
+}
+
+func TestRegistration_RemoveFrozen(t *testing.T) {
+	hub := newTestHub()
+
+	reg, err := hub.AttachEventHandler(&testEvent{}, func(*testEvent) {})
+	assert.NoError(t, err)
+	hub.Freeze()
+
+	assert.ErrorIs(t, reg.Remove(), ErrHubFrozen)
+	assert.Len(t, hub.EventTopology(), 1)
+}
@@ -0,0 +1,190 @@
+package operator
+
+import (
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+// eventRegistry maps event types and event names to their handlers.
+//
+// The registry is copy-on-write: registration builds a new table under a
+// mutex and publishes it atomically, while dispatch reads the current table
+// with a single atomic load. Tables are never mutated once published, so
+// dispatch takes no locks, and registering a handler while operations are in
+// flight is not a data race (though operations already dispatching may not
+// see the new handler).
+type eventRegistry[Tx Transaction] struct {
+	mu    sync.Mutex
+	table atomic.Pointer[eventTable[Tx]]
+}
+
+// eventKey identifies the events a set of handlers receives: either events
+// of a Go type (name is empty), or events with a given EventName() (ty is
+// nil).
+type eventKey struct {
+	ty   reflect.Type
+	name string
+}
+
+type eventTable[Tx Transaction] struct {
+	// handlers in dispatch order
+	handlers map[eventKey][]eventHandler[Tx]
+
+	// event names of registered types, and registered types by event name
+	names map[reflect.Type]string
+	types map[string]reflect.Type
+
+	// types registered with RegisterEventType(), which keep their names
+	// when their handlers are removed
+	explicit map[reflect.Type]bool
+
+	// registrations in registration order, from which handlers is derived
+	regs map[eventKey][]orderedHandler[Tx]
+
+	// true if any handlers are registered by name
+	named bool
+}
+
+func (r *eventRegistry[Tx]) load() *eventTable[Tx] {
+	if t := r.table.Load(); t != nil {
+		return t
+	}
+	return &eventTable[Tx]{}
+}
+
+// handlers returns the handlers registered for evt's type, and those
+// registered for its name. The returned slices must not be modified.
+func (r *eventRegistry[Tx]) handlers(evt Event) (byType, byName []eventHandler[Tx]) {
+	t := r.table.Load()
+	if t == nil {
+		return nil, nil
+	}
+	byType = t.handlers[eventKey{ty: reflect.TypeOf(evt)}]
+	if t.named {
+		byName = t.handlers[eventKey{name: evt.EventName()}]
+	}
+	return
+}
+
+// add registers reg for key. If a handler with the same name is already
+// registered for key, dup is called with it, and reg is not registered if dup
+// returns an error. Anonymous handlers are not duplicates of one another.
+func (r *eventRegistry[Tx]) add(key eventKey, name string, reg orderedHandler[Tx], dup func(prev *orderedHandler[Tx]) error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	old := r.load()
+	for i := range old.regs[key] {
+		if prev := &old.regs[key][i]; prev.name == reg.name && !(prev.anonymous && reg.anonymous) {
+			if err := dup(prev); err != nil {
+				return err
+			}
+			break
+		}
+	}
+
+	// copy rather than append so that slices held by readers of the old
+	// table are never written to
+	regs := make([]orderedHandler[Tx], len(old.regs[key]), len(old.regs[key])+1)
+	copy(regs, old.regs[key])
+	regs = append(regs, reg)
+
+	hnds, err := orderHandlers(regs)
+	if err != nil {
+		return err
+	}
+
+	next := old.clone()
+	next.handlers[key] = hnds
+	next.regs[key] = regs
+	if key.ty != nil {
+		next.names[key.ty] = name
+		next.types[name] = key.ty
+	} else {
+		next.named = true
+	}
+
+	r.table.Store(next)
+	return nil
+}
+
+// addType records ty, and its event name, without registering a handler.
+func (r *eventRegistry[Tx]) addType(ty reflect.Type, name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	next := r.load().clone()
+	next.names[ty] = name
+	next.types[name] = ty
+	next.explicit[ty] = true
+	r.table.Store(next)
+}
+
+// typeNamed returns the registered event type whose EventName() is name.
+func (r *eventRegistry[Tx]) typeNamed(name string) (reflect.Type, bool) {
+	ty, ok := r.load().types[name]
+	return ty, ok
+}
+
+// remove removes the handler registered via reg, if it is still registered.
+func (r *eventRegistry[Tx]) remove(key eventKey, reg *Registration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	old := r.load()
+	regs := make([]orderedHandler[Tx], 0, len(old.regs[key]))
+	for _, oh := range old.regs[key] {
+		if oh.registration != reg {
+			regs = append(regs, oh)
+		}
+	}
+	if len(regs) == len(old.regs[key]) {
+		return
+	}
+
+	// removing a handler can only relax ordering constraints
+	hnds, _ := orderHandlers(regs)
+
+	next := old.clone()
+	if len(regs) == 0 {
+		delete(next.handlers, key)
+		delete(next.regs, key)
+		if key.ty != nil && !next.explicit[key.ty] {
+			delete(next.types, next.names[key.ty])
+			delete(next.names, key.ty)
+		}
+	} else {
+		next.handlers[key] = hnds
+		next.regs[key] = regs
+	}
+
+	r.table.Store(next)
+}
+
+func (t *eventTable[Tx]) clone() *eventTable[Tx] {
+	next := &eventTable[Tx]{
+		handlers: make(map[eventKey][]eventHandler[Tx], len(t.handlers)+1),
+		names:    make(map[reflect.Type]string, len(t.names)+1),
+		types:    make(map[string]reflect.Type, len(t.types)+1),
+		regs:     make(map[eventKey][]orderedHandler[Tx], len(t.regs)+1),
+		explicit: make(map[reflect.Type]bool, len(t.explicit)+1),
+		named:    t.named,
+	}
+	for k, v := range t.handlers {
+		next.handlers[k] = v
+	}
+	for k, v := range t.names {
+		next.names[k] = v
+	}
+	for k, v := range t.types {
+		next.types[k] = v
+	}
+	for k, v := range t.regs {
+		next.regs[k] = v
+	}
+	for k, v := range t.explicit {
+		next.explicit[k] = v
+	}
+	return next
+}
@@ -121,3 +121,45 @@ func TestErrorReturn(t *testing.T) {
 		Val: 789,
 	}))
 }
+
+func TestNamedEventHandler(t *testing.T) {
+	hub := newTestHub()
+
+	type payload struct{ ID int }
+
+	var got []any
+	assert.NoError(t, hub.RegisterNamedEventHandler("user.created", func(ctx context.Context, p *payload) {
+		got = append(got, p.ID)
+	}))
+	assert.NoError(t, hub.RegisterNamedEventHandler("user.created", func(evt *NamedEvent) {
+		got = append(got, evt.Name)
+	}))
+	assert.NoError(t, hub.RegisterNamedEventHandler("testEvent", func(evt Event) {
+		got = append(got, evt.(*testEvent).Val)
+	}))
+	assert.NoError(t, hub.RegisterEventHandler(&testEvent{}, func(evt *testEvent) {
+		got = append(got, "typed")
+	}))
+
+	_, err := Invoke(context.Background(), hub, func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) {
+		if err := ctx.EmitNamed("user.created", &payload{ID: 7}); err != nil {
+			return nil, err
+		}
+		return in, ctx.Emit(&testEvent{Val: 3})
+	}, &struct{}{})
+	assert.NoError(t, err)
+
+	// handlers registered by type run before those registered by name
+	assert.Equal(t, []any{7, "user.created", "typed", 3}, got)
+}
+
+func TestNamedEventHandler_PayloadMismatch(t *testing.T) {
+	hub := newTestHub()
+
+	assert.NoError(t, hub.RegisterNamedEventHandler("n", func(p *testEvent) {}))
+
+	_, err := Invoke(context.Background(), hub, func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) {
+		return in, ctx.EmitNamed("n", "not a *testEvent")
+	}, &struct{}{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,118 @@
+// Package eventcodec serializes events for publication outside the process -
+// by outbox relays, message publishers and replay tooling - and decodes them
+// back into the Go types registered with a hub.
+//
+// Events are serialized with any codec.Codec (JSON by default; Protobuf via
+// codec/protocodec for events that are generated message types) into an
+// Envelope identifying the event by its EventName() and version:
+//
+//	s := eventcodec.New(hub)
+//	env, err := s.Encode(&UserCreated{ID: 1})
+//	...
+//	evt, err := s.Decode(env) // *UserCreated
+//
+// Decoding upcasts events serialized at older versions using the hub's
+// registered upcasters (see operator.Hub.RegisterUpcaster()).
+package eventcodec
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+
+	"github.com/jaz303/operator"
+	"github.com/jaz303/operator/codec"
+)
+
+var ErrUnknownEvent = operator.ErrUnknownEvent
+
+// Types resolves event names to their registered Go types, and migrates
+// serialized events between versions. *operator.Hub implements Types.
+type Types interface {
+	EventType(name string) (reflect.Type, bool)
+	Upcast(name string, version int, data []byte) ([]byte, int, error)
+}
+
+// Envelope is a serialized event.
+type Envelope struct {
+	// Event name, as returned by EventName()
+	Name string `json:"name"`
+
+	// Event version, as returned by operator.EventVersion()
+	Version int `json:"version"`
+
+	// Content type of Data
+	ContentType string `json:"content_type"`
+
+	Data []byte `json:"data"`
+}
+
+// Serializer encodes and decodes events.
+type Serializer struct {
+	types  Types
+	codecs *codec.Registry
+	encode codec.Codec
+}
+
+// New returns a Serializer resolving event types with types. Events are
+// encoded with the first of codecs, and may be decoded with any of them;
+// if no codecs are supplied, codec.JSON is used.
+func New(types Types, codecs ...codec.Codec) *Serializer {
+	if len(codecs) == 0 {
+		codecs = []codec.Codec{codec.JSON}
+	}
+	return &Serializer{
+		types:  types,
+		codecs: codec.NewRegistry(codecs...),
+		encode: codecs[0],
+	}
+}
+
+// Encode serializes evt.
+func (s *Serializer) Encode(evt operator.Event) (*Envelope, error) {
+	var buf bytes.Buffer
+	if err := s.encode.Encode(&buf, evt); err != nil {
+		return nil, fmt.Errorf("encoding event %s failed (%w)", evt.EventName(), err)
+	}
+	return &Envelope{
+		Name:        evt.EventName(),
+		Version:     operator.EventVersion(evt),
+		ContentType: s.encode.ContentType(),
+		Data:        buf.Bytes(),
+	}, nil
+}
+
+// Decode deserializes env into a new instance of the Go type registered for
+// its name, upcasting it to the current version first if necessary.
+//
+// Returns an error wrapping ErrUnknownEvent if no type is registered for the
+// event's name, or codec.ErrUnsupportedMediaType if none of the serializer's
+// codecs handles its content type.
+func (s *Serializer) Decode(env *Envelope) (operator.Event, error) {
+	ty, ok := s.types.EventType(env.Name)
+	if !ok {
+		return nil, fmt.Errorf("%w %s", ErrUnknownEvent, env.Name)
+	}
+	c, err := s.codecs.Find(env.ContentType)
+	if err != nil {
+		return nil, err
+	}
+
+	data, _, err := s.types.Upcast(env.Name, env.Version, env.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	ptr := ty.Kind() == reflect.Pointer
+	val := reflect.New(ty)
+	if ptr {
+		val = reflect.New(ty.Elem())
+	}
+	if err := c.Decode(bytes.NewReader(data), val.Interface()); err != nil {
+		return nil, fmt.Errorf("decoding event %s failed (%w)", env.Name, err)
+	}
+	if !ptr {
+		val = val.Elem()
+	}
+	return val.Interface().(operator.Event), nil
+}
@@ -0,0 +1,112 @@
+package eventcodec
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/jaz303/operator"
+	"github.com/jaz303/operator/codec"
+	"github.com/stretchr/testify/assert"
+)
+
+type nopTx struct{}
+
+func (nopTx) Commit(context.Context) error   { return nil }
+func (nopTx) Rollback(context.Context) error { return nil }
+
+type userCreated struct {
+	ID    int    `json:"id" xml:"id"`
+	Email string `json:"email" xml:"email"`
+}
+
+func (e *userCreated) EventName() string { return "user.created" }
+func (e *userCreated) EventVersion() int { return 2 }
+
+func newTestHub(t *testing.T) *operator.Hub[nopTx] {
+	hub := operator.NewHub(func(context.Context) (nopTx, error) { return nopTx{}, nil })
+	assert.NoError(t, hub.RegisterEventType(&userCreated{}))
+	assert.NoError(t, hub.RegisterUpcaster("user.created", 1, func(data []byte) ([]byte, error) {
+		return bytes.ReplaceAll(data, []byte(`"mail"`), []byte(`"email"`)), nil
+	}))
+	return hub
+}
+
+func TestRoundTrip(t *testing.T) {
+	s := New(newTestHub(t), codec.XML, codec.JSON)
+
+	env, err := s.Encode(&userCreated{ID: 1, Email: "a@example.com"})
+	assert.NoError(t, err)
+	assert.Equal(t, "user.created", env.Name)
+	assert.Equal(t, 2, env.Version)
+	assert.Equal(t, "application/xml", env.ContentType)
+
+	evt, err := s.Decode(env)
+	assert.NoError(t, err)
+	assert.Equal(t, &userCreated{ID: 1, Email: "a@example.com"}, evt)
+}
+
+func TestDecode_Upcast(t *testing.T) {
+	s := New(newTestHub(t))
+
+	evt, err := s.Decode(&Envelope{
+		Name:        "user.created",
+		Version:     1,
+		ContentType: "application/json",
+		Data:        []byte(`{"id":3,"mail":"b@example.com"}`),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, &userCreated{ID: 3, Email: "b@example.com"}, evt)
+}
+
+func TestDecode_Errors(t *testing.T) {
+	s := New(newTestHub(t))
+
+	_, err := s.Decode(&Envelope{Name: "missing", Version: 1, ContentType: "application/json"})
+	assert.ErrorIs(t, err, ErrUnknownEvent)
+
+	_, err = s.Decode(&Envelope{Name: "user.created", Version: 2, ContentType: "text/csv"})
+	assert.ErrorIs(t, err, codec.ErrUnsupportedMediaType)
+}
+
+func TestCloudEvent(t *testing.T) {
+	s := New(newTestHub(t))
+
+	ce, err := s.EncodeCloudEvent(&userCreated{ID: 5}, "/users", "evt-1")
+	assert.NoError(t, err)
+
+	data, err := json.Marshal(ce)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"specversion": "1.0",
+		"id": "evt-1",
+		"source": "/users",
+		"type": "user.created",
+		"datacontenttype": "application/json",
+		"eventversion": 2,
+		"data": {"id": 5, "email": ""}
+	}`, string(data))
+
+	parsed, err := ParseCloudEvent(data)
+	assert.NoError(t, err)
+	evt, err := s.DecodeCloudEvent(parsed)
+	assert.NoError(t, err)
+	assert.Equal(t, &userCreated{ID: 5}, evt)
+
+	_, err = ParseCloudEvent([]byte(`{"specversion":"1.0"}`))
+	assert.ErrorIs(t, err, ErrInvalidCloudEvent)
+}
+
+func TestCloudEvent_Binary(t *testing.T) {
+	s := New(newTestHub(t), codec.XML)
+
+	ce, err := s.EncodeCloudEvent(&userCreated{ID: 6}, "/users", "evt-2")
+	assert.NoError(t, err)
+	assert.Nil(t, ce.Data)
+	assert.NotEmpty(t, ce.DataBase64)
+
+	evt, err := s.DecodeCloudEvent(ce)
+	assert.NoError(t, err)
+	assert.Equal(t, &userCreated{ID: 6}, evt)
+}
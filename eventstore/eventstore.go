@@ -0,0 +1,246 @@
+// Package eventstore appends events to per-aggregate streams within an
+// operation's transaction, for applications practising event sourcing.
+//
+// Hub events are dispatched before commit, within the operation's
+// transaction, so a handler that appends them to a store commits or rolls
+// back with the operation's own writes. Persist registers such handlers for
+// events implementing Aggregated:
+//
+//	store := eventstore.New(hub, eventstore.NewSQL[*opsql.Tx]("event_streams"))
+//	eventstore.Persist(hub, store, &AccountOpened{}, &FundsDeposited{})
+//
+// Operations that enforce invariants over a stream should instead load it,
+// decide, and append with the version they loaded, so that concurrent writers
+// are rejected with an *operr.ConflictError:
+//
+//	acct := &Account{}
+//	version, err := store.Rehydrate(ctx, in.AccountID, acct)
+//	...
+//	_, err = store.Append(ctx, in.AccountID, version, &FundsWithdrawn{...})
+//
+// Events are serialized with an eventcodec.Serializer, so stored events
+// written at older versions are upcast by the hub's registered upcasters as
+// they are read. WithUpcaster adds a hook for migrations the hub's upcasters
+// cannot express, such as renaming an event.
+package eventstore
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/jaz303/operator"
+	"github.com/jaz303/operator/eventcodec"
+	"github.com/jaz303/operator/operr"
+)
+
+var ErrNotAggregated = errors.New("event does not implement Aggregated")
+
+// Expected versions with special meaning for Append.
+const (
+	// Any appends regardless of the stream's current version
+	Any int64 = -1
+
+	// NoStream appends only if the stream is empty
+	NoStream int64 = 0
+)
+
+// Aggregated is implemented by events belonging to an aggregate's stream.
+type Aggregated interface {
+	AggregateID() string
+}
+
+// Aggregate is state rebuilt by applying a stream's events in order.
+type Aggregate interface {
+	Apply(evt operator.Event) error
+}
+
+// Record is a stored event.
+type Record struct {
+	eventcodec.Envelope
+
+	// Stream (aggregate ID) to which the event belongs
+	Stream string
+
+	// Position of the event within its stream, starting at 1
+	Sequence int64
+
+	// Position of the event across all streams, assigned by the backend
+	// when the event is stored
+	Position int64
+
+	// ID of the operation that appended the event
+	OperationID string
+
+	RecordedAt time.Time
+}
+
+// Backend stores records. Backends perform their reads and writes within the
+// operation's transaction.
+type Backend[Tx operator.Transaction] interface {
+	// Append assigns sequence numbers to records, which follow the last
+	// in stream, and appends them. If expected is not Any, and the
+	// stream's current version - the sequence of its last record, or
+	// NoStream - differs, Append returns an error from ConflictError().
+	Append(ctx *operator.OpContext[Tx], stream string, expected int64, records []*Record) error
+
+	// Read returns the records of stream with sequence greater than after,
+	// in order.
+	Read(ctx *operator.OpContext[Tx], stream string, after int64) ([]*Record, error)
+
+	// ReadAll returns up to limit records from all streams with position
+	// greater than after, in order of position.
+	ReadAll(ctx *operator.OpContext[Tx], after int64, limit int) ([]*Record, error)
+}
+
+// ConflictError returns the error reported by backends when a stream's
+// current version is not the expected one.
+func ConflictError(stream string, current, expected int64) error {
+	return fmt.Errorf("appending to stream %s failed (%w)", stream, &operr.ConflictError{
+		Current:  strconv.FormatInt(current, 10),
+		Expected: strconv.FormatInt(expected, 10),
+	})
+}
+
+// Store appends events to, and reads them from, a Backend.
+type Store[Tx operator.Transaction] struct {
+	backend    Backend[Tx]
+	serializer *eventcodec.Serializer
+	upcast     func(rec *Record) error
+}
+
+// Option configures a Store.
+type Option func(s *storeOptions)
+
+type storeOptions struct {
+	serializer *eventcodec.Serializer
+	upcast     func(rec *Record) error
+}
+
+// WithSerializer sets the serializer used to encode and decode events. The
+// default serializes events as JSON, resolving types with the hub.
+func WithSerializer(s *eventcodec.Serializer) Option {
+	return func(o *storeOptions) { o.serializer = s }
+}
+
+// WithUpcaster sets a hook called with each record read, before it is
+// decoded, which may rewrite it in place; e.g. to rename an event, or to
+// change its content type. Upcasters registered with the hub are applied
+// afterwards, during decoding.
+func WithUpcaster(fn func(rec *Record) error) Option {
+	return func(o *storeOptions) { o.upcast = fn }
+}
+
+// New returns a Store that stores events in backend.
+func New[Tx operator.Transaction](hub *operator.Hub[Tx], backend Backend[Tx], opts ...Option) *Store[Tx] {
+	var o storeOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.serializer == nil {
+		o.serializer = eventcodec.New(hub)
+	}
+	return &Store[Tx]{backend: backend, serializer: o.serializer, upcast: o.upcast}
+}
+
+// Persist registers handlers on hub that append each of the given events,
+// when emitted, to the stream named by its AggregateID(), regardless of the
+// stream's version.
+//
+// Returns ErrNotAggregated if any of events does not implement Aggregated.
+func Persist[Tx operator.Transaction](hub *operator.Hub[Tx], s *Store[Tx], events ...operator.Event) error {
+	for _, evt := range events {
+		if _, ok := evt.(Aggregated); !ok {
+			return fmt.Errorf("%w: %s", ErrNotAggregated, evt.EventName())
+		}
+	}
+	for _, evt := range events {
+		err := hub.RegisterEventHandler(evt, func(ctx *operator.OpContext[Tx], evt operator.Event) error {
+			_, err := s.Append(ctx, evt.(Aggregated).AggregateID(), Any, evt)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Append appends events to stream, whose current version must be expected
+// (or Any), and returns the stream's new version. Events are appended within
+// the operation's transaction, and are not emitted.
+//
+// If the stream's version differs from expected, returns an error wrapping
+// an *operr.ConflictError.
+func (s *Store[Tx]) Append(ctx *operator.OpContext[Tx], stream string, expected int64, events ...operator.Event) (int64, error) {
+	records := make([]*Record, len(events))
+	for i, evt := range events {
+		env, err := s.serializer.Encode(evt)
+		if err != nil {
+			return 0, err
+		}
+		records[i] = &Record{
+			Envelope:    *env,
+			Stream:      stream,
+			OperationID: ctx.ID(),
+			RecordedAt:  ctx.Now(),
+		}
+	}
+	if err := s.backend.Append(ctx, stream, expected, records); err != nil {
+		return 0, err
+	}
+	if len(records) == 0 {
+		return expected, nil
+	}
+	return records[len(records)-1].Sequence, nil
+}
+
+// Load returns the events of stream with sequence greater than after, and
+// the stream's version: the sequence of its last event, or after if there
+// are none.
+func (s *Store[Tx]) Load(ctx *operator.OpContext[Tx], stream string, after int64) ([]operator.Event, int64, error) {
+	records, err := s.backend.Read(ctx, stream, after)
+	if err != nil {
+		return nil, 0, err
+	}
+	events := make([]operator.Event, len(records))
+	for i, rec := range records {
+		if events[i], err = s.Decode(rec); err != nil {
+			return nil, 0, err
+		}
+		after = rec.Sequence
+	}
+	return events, after, nil
+}
+
+// Rehydrate applies the events of stream to agg, in order, and returns the
+// stream's version, which is NoStream if it has no events.
+func (s *Store[Tx]) Rehydrate(ctx *operator.OpContext[Tx], stream string, agg Aggregate) (int64, error) {
+	events, version, err := s.Load(ctx, stream, NoStream)
+	if err != nil {
+		return 0, err
+	}
+	for _, evt := range events {
+		if err := agg.Apply(evt); err != nil {
+			return 0, err
+		}
+	}
+	return version, nil
+}
+
+// ReadAll returns up to limit records from all streams with position greater
+// than after, in order of position. Decode them with Decode.
+func (s *Store[Tx]) ReadAll(ctx *operator.OpContext[Tx], after int64, limit int) ([]*Record, error) {
+	return s.backend.ReadAll(ctx, after, limit)
+}
+
+// Decode decodes rec, applying the store's upcaster, if any, then the hub's.
+func (s *Store[Tx]) Decode(rec *Record) (operator.Event, error) {
+	if s.upcast != nil {
+		if err := s.upcast(rec); err != nil {
+			return nil, fmt.Errorf("upcasting record %s/%d failed (%w)", rec.Stream, rec.Sequence, err)
+		}
+	}
+	return s.serializer.Decode(&rec.Envelope)
+}
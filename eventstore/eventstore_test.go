@@ -0,0 +1,193 @@
+package eventstore
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jaz303/operator"
+	"github.com/jaz303/operator/operr"
+	"github.com/stretchr/testify/assert"
+)
+
+type nopTx struct{}
+
+func (nopTx) Commit(context.Context) error   { return nil }
+func (nopTx) Rollback(context.Context) error { return nil }
+
+type deposited struct {
+	Account string `json:"account"`
+	Amount  int    `json:"amount"`
+}
+
+func (e *deposited) EventName() string   { return "funds.deposited" }
+func (e *deposited) AggregateID() string { return e.Account }
+
+type withdrawn struct {
+	Account string `json:"account"`
+	Amount  int    `json:"amount"`
+}
+
+func (e *withdrawn) EventName() string   { return "funds.withdrawn" }
+func (e *withdrawn) AggregateID() string { return e.Account }
+
+type unaggregated struct{}
+
+func (e *unaggregated) EventName() string { return "unaggregated" }
+
+type account struct{ Balance int }
+
+func (a *account) Apply(evt operator.Event) error {
+	switch e := evt.(type) {
+	case *deposited:
+		a.Balance += e.Amount
+	case *withdrawn:
+		a.Balance -= e.Amount
+	}
+	return nil
+}
+
+func newStore(opts ...Option) (*operator.Hub[nopTx], *Store[nopTx]) {
+	hub := operator.NewHub(func(context.Context) (nopTx, error) { return nopTx{}, nil })
+	hub.RegisterEventType(&withdrawn{})
+	return hub, New(hub, NewMemory[nopTx](), opts...)
+}
+
+func run(hub *operator.Hub[nopTx], fn func(ctx *operator.OpContext[nopTx]) error) error {
+	_, err := operator.Invoke(context.Background(), hub, func(ctx *operator.OpContext[nopTx], in *struct{}) (*struct{}, error) {
+		return in, fn(ctx)
+	}, &struct{}{})
+	return err
+}
+
+func balance(t *testing.T, hub *operator.Hub[nopTx], s *Store[nopTx], stream string) (int, int64) {
+	acct := &account{}
+	var version int64
+	err := run(hub, func(ctx *operator.OpContext[nopTx]) (err error) {
+		version, err = s.Rehydrate(ctx, stream, acct)
+		return
+	})
+	assert.Nil(t, err)
+	return acct.Balance, version
+}
+
+func TestPersist(t *testing.T) {
+	hub, s := newStore()
+	assert.Nil(t, Persist(hub, s, &deposited{}))
+
+	err := run(hub, func(ctx *operator.OpContext[nopTx]) error {
+		ctx.Emit(&deposited{Account: "a", Amount: 10})
+		ctx.Emit(&deposited{Account: "b", Amount: 5})
+		return ctx.Emit(&deposited{Account: "a", Amount: 20})
+	})
+	assert.Nil(t, err)
+
+	err = run(hub, func(ctx *operator.OpContext[nopTx]) error {
+		ctx.Emit(&deposited{Account: "a", Amount: 100})
+		return errors.New("rolled back")
+	})
+	assert.NotNil(t, err)
+
+	bal, version := balance(t, hub, s, "a")
+	assert.Equal(t, 30, bal, "events of rolled back operations are discarded")
+	assert.Equal(t, int64(2), version)
+
+	bal, version = balance(t, hub, s, "b")
+	assert.Equal(t, 5, bal)
+	assert.Equal(t, int64(1), version)
+}
+
+func TestPersist_RequiresAggregated(t *testing.T) {
+	hub, s := newStore()
+	assert.ErrorIs(t, Persist(hub, s, &unaggregated{}), ErrNotAggregated)
+}
+
+func TestAppend_ExpectedVersion(t *testing.T) {
+	hub, s := newStore()
+	hub.RegisterEventType(&deposited{})
+
+	err := run(hub, func(ctx *operator.OpContext[nopTx]) error {
+		v, err := s.Append(ctx, "a", NoStream, &deposited{Account: "a", Amount: 50})
+		assert.Equal(t, int64(1), v)
+		if err != nil {
+			return err
+		}
+
+		acct := &account{}
+		v, err = s.Rehydrate(ctx, "a", acct)
+		assert.Equal(t, 50, acct.Balance, "an operation reads its own appends")
+		assert.Equal(t, int64(1), v)
+
+		v, err = s.Append(ctx, "a", v, &withdrawn{Account: "a", Amount: 20})
+		assert.Equal(t, int64(2), v)
+		return err
+	})
+	assert.Nil(t, err)
+
+	err = run(hub, func(ctx *operator.OpContext[nopTx]) error {
+		_, err := s.Append(ctx, "a", 1, &withdrawn{Account: "a", Amount: 20})
+		return err
+	})
+	var conflict *operr.ConflictError
+	assert.ErrorAs(t, err, &conflict)
+	assert.Equal(t, "2", conflict.Current)
+	assert.Equal(t, "1", conflict.Expected)
+	assert.Equal(t, 409, operr.StatusCode(err))
+
+	bal, version := balance(t, hub, s, "a")
+	assert.Equal(t, 30, bal)
+	assert.Equal(t, int64(2), version)
+}
+
+func TestReadAll(t *testing.T) {
+	hub, s := newStore()
+	Persist(hub, s, &deposited{})
+
+	for _, acct := range []string{"a", "b", "a"} {
+		assert.Nil(t, run(hub, func(ctx *operator.OpContext[nopTx]) error {
+			return ctx.Emit(&deposited{Account: acct, Amount: 1})
+		}))
+	}
+
+	var records []*Record
+	err := run(hub, func(ctx *operator.OpContext[nopTx]) (err error) {
+		records, err = s.ReadAll(ctx, 1, 10)
+		return
+	})
+	assert.Nil(t, err)
+	assert.Len(t, records, 2)
+	assert.Equal(t, int64(2), records[0].Position)
+	assert.Equal(t, "b", records[0].Stream)
+	assert.Equal(t, int64(3), records[1].Position)
+	assert.Equal(t, int64(2), records[1].Sequence)
+	assert.NotEmpty(t, records[1].OperationID)
+
+	evt, err := s.Decode(records[1])
+	assert.Nil(t, err)
+	assert.Equal(t, &deposited{Account: "a", Amount: 1}, evt)
+}
+
+func TestUpcaster(t *testing.T) {
+	hub, s := newStore(WithUpcaster(func(rec *Record) error {
+		if rec.Name == "funds.added" {
+			rec.Name = "funds.deposited"
+		}
+		return nil
+	}))
+	hub.RegisterEventType(&deposited{})
+	hub.RegisterEventType(&added{})
+
+	err := run(hub, func(ctx *operator.OpContext[nopTx]) error {
+		_, err := s.Append(ctx, "a", Any, &added{Account: "a", Amount: 7}, &deposited{Account: "a", Amount: 3})
+		return err
+	})
+	assert.Nil(t, err)
+
+	bal, _ := balance(t, hub, s, "a")
+	assert.Equal(t, 10, bal)
+}
+
+// added is the former name of deposited
+type added deposited
+
+func (e *added) EventName() string { return "funds.added" }
@@ -0,0 +1,134 @@
+package eventstore
+
+import (
+	"sync"
+
+	"github.com/jaz303/operator"
+)
+
+// Memory is an in-memory Backend, for tests and prototypes. Records appended
+// by an operation are visible to that operation immediately, and to others
+// once it commits; they are discarded if it rolls back.
+//
+// Versions are checked against committed records and the operation's own,
+// so concurrent operations appending to the same stream are not isolated
+// from one another as they are by a database backend.
+type Memory[Tx operator.Transaction] struct {
+	mu      sync.RWMutex
+	streams map[string][]*Record
+	all     []*Record
+}
+
+// NewMemory returns an empty Memory backend.
+func NewMemory[Tx operator.Transaction]() *Memory[Tx] {
+	return &Memory[Tx]{streams: map[string][]*Record{}}
+}
+
+// pendingRecords are the records appended by an operation, in order, by
+// backend, which are yet to be committed.
+type pendingRecords struct {
+	records map[any][]*Record
+}
+
+func (m *Memory[Tx]) Append(ctx *operator.OpContext[Tx], stream string, expected int64, records []*Record) error {
+	p, err := m.pending(ctx)
+	if err != nil {
+		return err
+	}
+
+	m.mu.RLock()
+	current := int64(len(m.streams[stream]))
+	m.mu.RUnlock()
+	for _, rec := range p.records[m] {
+		if rec.Stream == stream {
+			current++
+		}
+	}
+
+	if expected != Any && expected != current {
+		return ConflictError(stream, current, expected)
+	}
+	for _, rec := range records {
+		current++
+		rec.Sequence = current
+		p.records[m] = append(p.records[m], rec)
+	}
+	return nil
+}
+
+func (m *Memory[Tx]) Read(ctx *operator.OpContext[Tx], stream string, after int64) ([]*Record, error) {
+	m.mu.RLock()
+	records := copyRecords(m.streams[stream], after)
+	m.mu.RUnlock()
+
+	if p, ok := m.lookupPending(ctx); ok {
+		for _, rec := range p.records[m] {
+			if rec.Stream == stream && rec.Sequence > after {
+				cp := *rec
+				records = append(records, &cp)
+			}
+		}
+	}
+	return records, nil
+}
+
+func (m *Memory[Tx]) ReadAll(ctx *operator.OpContext[Tx], after int64, limit int) ([]*Record, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var records []*Record
+	if after < int64(len(m.all)) {
+		records = m.all[max(after, 0):]
+	}
+	if len(records) > limit {
+		records = records[:limit]
+	}
+	return copyRecords(records, 0), nil
+}
+
+// pending returns the operation's pending records for m, scheduling them to
+// be committed on first use.
+func (m *Memory[Tx]) pending(ctx *operator.OpContext[Tx]) (*pendingRecords, error) {
+	p, ok := m.lookupPending(ctx)
+	if !ok {
+		p = &pendingRecords{records: map[any][]*Record{}}
+		operator.Provide(ctx, p)
+	}
+	if _, ok := p.records[m]; !ok {
+		if err := ctx.AfterFunc(func(*operator.OpContext[Tx]) { m.commit(p.records[m]) }); err != nil {
+			return nil, err
+		}
+		p.records[m] = []*Record{}
+	}
+	return p, nil
+}
+
+func (m *Memory[Tx]) lookupPending(ctx *operator.OpContext[Tx]) (*pendingRecords, bool) {
+	p, _ := operator.Lookup[*pendingRecords](ctx)
+	return p, p != nil
+}
+
+func (m *Memory[Tx]) commit(records []*Record) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, rec := range records {
+		rec.Sequence = int64(len(m.streams[rec.Stream]) + 1)
+		rec.Position = int64(len(m.all) + 1)
+		m.streams[rec.Stream] = append(m.streams[rec.Stream], rec)
+		m.all = append(m.all, rec)
+	}
+}
+
+// copyRecords returns copies of the records with sequence greater than after,
+// so that callers, such as upcasters, may modify them.
+func copyRecords(records []*Record, after int64) []*Record {
+	var out []*Record
+	for _, rec := range records {
+		if rec.Sequence > after {
+			cp := *rec
+			out = append(out, &cp)
+		}
+	}
+	return out
+}
@@ -0,0 +1,134 @@
+package eventstore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/jaz303/operator"
+)
+
+// SQLTx is a transaction through which SQL queries its table. *opsql.Tx
+// implements SQLTx.
+type SQLTx interface {
+	operator.Transaction
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// Schema is the Postgres DDL for the table used by SQL, with %s in place of
+// the table's name.
+const Schema = `CREATE TABLE %s (
+	position     BIGSERIAL PRIMARY KEY,
+	stream       TEXT NOT NULL,
+	sequence     BIGINT NOT NULL,
+	name         TEXT NOT NULL,
+	version      INT NOT NULL,
+	content_type TEXT NOT NULL,
+	data         BYTEA NOT NULL,
+	operation_id TEXT NOT NULL,
+	recorded_at  TIMESTAMPTZ NOT NULL,
+	UNIQUE (stream, sequence)
+)`
+
+// SQL is a Backend storing records in a Postgres table created with Schema.
+// Appends to each stream are serialized with a transaction-level advisory
+// lock, so that concurrent writers are reported as conflicts rather than
+// unique constraint violations.
+//
+// Positions are allocated from a sequence, so a record may become visible
+// after others with greater positions, whose transactions committed first;
+// readers tailing the store by position, such as projections, must allow for
+// this.
+type SQL[Tx SQLTx] struct {
+	table string
+}
+
+// NewSQL returns a SQL backend using the named table.
+func NewSQL[Tx SQLTx](table string) *SQL[Tx] {
+	return &SQL[Tx]{table: table}
+}
+
+const recordColumns = "position, stream, sequence, name, version, content_type, data, operation_id, recorded_at"
+
+func (s *SQL[Tx]) Append(ctx *operator.OpContext[Tx], stream string, expected int64, records []*Record) error {
+	tx, err := ctx.Tx()
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtextextended($1, 0))", "eventstore:"+stream); err != nil {
+		return err
+	}
+
+	var current int64
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf("SELECT COALESCE(MAX(sequence), 0) FROM %s WHERE stream = $1", s.table), stream)
+	if err != nil {
+		return err
+	}
+	err = scanOne(rows, &current)
+	if err != nil {
+		return err
+	}
+	if expected != Any && expected != current {
+		return ConflictError(stream, current, expected)
+	}
+
+	insert := fmt.Sprintf(`INSERT INTO %s (stream, sequence, name, version, content_type, data, operation_id, recorded_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING position`, s.table)
+	for _, rec := range records {
+		current++
+		rec.Sequence = current
+		rows, err := tx.QueryContext(ctx, insert, rec.Stream, rec.Sequence, rec.Name, rec.Version, rec.ContentType, rec.Data, rec.OperationID, rec.RecordedAt)
+		if err != nil {
+			return err
+		}
+		if err := scanOne(rows, &rec.Position); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *SQL[Tx]) Read(ctx *operator.OpContext[Tx], stream string, after int64) ([]*Record, error) {
+	return s.query(ctx, fmt.Sprintf("SELECT %s FROM %s WHERE stream = $1 AND sequence > $2 ORDER BY sequence", recordColumns, s.table), stream, after)
+}
+
+func (s *SQL[Tx]) ReadAll(ctx *operator.OpContext[Tx], after int64, limit int) ([]*Record, error) {
+	return s.query(ctx, fmt.Sprintf("SELECT %s FROM %s WHERE position > $1 ORDER BY position LIMIT $2", recordColumns, s.table), after, limit)
+}
+
+func (s *SQL[Tx]) query(ctx *operator.OpContext[Tx], query string, args ...any) ([]*Record, error) {
+	tx, err := ctx.Tx()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []*Record
+	for rows.Next() {
+		rec := &Record{}
+		err := rows.Scan(&rec.Position, &rec.Stream, &rec.Sequence, &rec.Name, &rec.Version,
+			&rec.ContentType, &rec.Data, &rec.OperationID, &rec.RecordedAt)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, rec)
+	}
+	return records, rows.Err()
+}
+
+func scanOne(rows *sql.Rows, dest any) error {
+	defer rows.Close()
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		return sql.ErrNoRows
+	}
+	return rows.Scan(dest)
+}
@@ -0,0 +1,168 @@
+// Package health reports whether an application and its dependencies are
+// able to serve requests.
+//
+// A Checker always verifies that the hub's transaction provider can begin
+// and roll back a transaction; further checks are registered by name:
+//
+//	hc := health.New(hub, 2*time.Second)
+//	hc.Register("cache", func(ctx context.Context) error { return redis.Ping(ctx).Err() })
+//	mux.Handle("GET /healthz", hc.Handler())
+//
+// Reports take the form {"status":"ok","checks":{"transaction":{"status":"ok"},...}},
+// and are written with status 503 Service Unavailable if any check fails.
+package health
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jaz303/operator"
+	"github.com/jaz303/operator/httpbind"
+)
+
+const (
+	StatusOK   = "ok"
+	StatusDown = "down"
+)
+
+// TransactionCheck is the name of the check that begins and rolls back a
+// transaction.
+const TransactionCheck = "transaction"
+
+// Check reports the health of a dependency, returning nil if it is healthy.
+type Check func(ctx context.Context) error
+
+// Report is the outcome of running every check.
+type Report struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+// HTTPStatus implements httpbind.StatusCoder.
+func (r *Report) HTTPStatus() int {
+	if r.Status != StatusOK {
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusOK
+}
+
+// CheckResult is the outcome of a single check.
+type CheckResult struct {
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
+
+// Checker runs health checks against a hub.
+type Checker[Tx operator.Transaction] struct {
+	hub     *operator.Hub[Tx]
+	timeout time.Duration
+
+	mu     sync.RWMutex
+	checks map[string]Check
+}
+
+// New returns a Checker for hub, whose checks must each complete within
+// timeout; if timeout is not positive, 5 seconds is used.
+func New[Tx operator.Transaction](hub *operator.Hub[Tx], timeout time.Duration) *Checker[Tx] {
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	c := &Checker[Tx]{hub: hub, timeout: timeout, checks: map[string]Check{}}
+	c.checks[TransactionCheck] = hub.Ping
+	return c
+}
+
+// Register adds a named check. It panics if a check with the same name is
+// already registered.
+func (c *Checker[Tx]) Register(name string, check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.checks[name]; ok {
+		panic(fmt.Sprintf("health: duplicate check %q", name))
+	}
+	c.checks[name] = check
+}
+
+// Check runs every check concurrently, each bounded by the Checker's
+// timeout, and reports the results. The overall status is StatusOK only if
+// every check passed.
+func (c *Checker[Tx]) Check(ctx context.Context) *Report {
+	c.mu.RLock()
+	names := make([]string, 0, len(c.checks))
+	for name := range c.checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	checks := make([]Check, len(names))
+	for i, name := range names {
+		checks[i] = c.checks[name]
+	}
+	c.mu.RUnlock()
+
+	results := make([]CheckResult, len(names))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = c.run(ctx, check)
+		}()
+	}
+	wg.Wait()
+
+	report := &Report{Status: StatusOK, Checks: make(map[string]CheckResult, len(names))}
+	for i, name := range names {
+		report.Checks[name] = results[i]
+		if results[i].Status != StatusOK {
+			report.Status = StatusDown
+		}
+	}
+	return report
+}
+
+func (c *Checker[Tx]) run(ctx context.Context, check Check) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("check panicked: %v", r)
+			}
+		}()
+		done <- check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	res := CheckResult{Status: StatusOK, Duration: time.Since(start).String()}
+	if err != nil {
+		res.Status = StatusDown
+		res.Error = err.Error()
+	}
+	return res
+}
+
+// Operation is an operation reporting the health of the application. It
+// always succeeds; failing checks are reflected in the report.
+func (c *Checker[Tx]) Operation(ctx *operator.OpContext[Tx], _ *struct{}) (*Report, error) {
+	return c.Check(ctx), nil
+}
+
+// Handler returns an HTTP handler, bound with httpbind, that writes the
+// report as JSON.
+func (c *Checker[Tx]) Handler() http.Handler {
+	return http.HandlerFunc(httpbind.Bind(c.hub, c.Operation).Go)
+}
@@ -0,0 +1,82 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jaz303/operator"
+	"github.com/stretchr/testify/assert"
+)
+
+type nopTx struct{ rolledBack bool }
+
+func (t *nopTx) Commit(context.Context) error   { return nil }
+func (t *nopTx) Rollback(context.Context) error { t.rolledBack = true; return nil }
+
+func TestChecker(t *testing.T) {
+	var tx *nopTx
+	hub := operator.NewHub(func(context.Context) (*nopTx, error) {
+		tx = &nopTx{}
+		return tx, nil
+	})
+	c := New(hub, time.Second)
+	c.Register("cache", func(ctx context.Context) error { return nil })
+
+	report := c.Check(context.Background())
+
+	assert.Equal(t, StatusOK, report.Status)
+	assert.Equal(t, StatusOK, report.Checks[TransactionCheck].Status)
+	assert.Equal(t, StatusOK, report.Checks["cache"].Status)
+	assert.True(t, tx.rolledBack)
+	assert.Equal(t, http.StatusOK, report.HTTPStatus())
+}
+
+func TestChecker_Failures(t *testing.T) {
+	hub := operator.NewHub(func(context.Context) (*nopTx, error) { return nil, errors.New("db down") })
+	c := New(hub, 20*time.Millisecond)
+	c.Register("slow", func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	})
+	c.Register("panics", func(ctx context.Context) error { panic("oops") })
+
+	report := c.Check(context.Background())
+
+	assert.Equal(t, StatusDown, report.Status)
+	assert.Equal(t, CheckResult{Status: StatusDown, Error: "begin transaction failed (db down)", Duration: report.Checks[TransactionCheck].Duration}, report.Checks[TransactionCheck])
+	assert.Equal(t, context.DeadlineExceeded.Error(), report.Checks["slow"].Error)
+	assert.Equal(t, "check panicked: oops", report.Checks["panics"].Error)
+	assert.Equal(t, http.StatusServiceUnavailable, report.HTTPStatus())
+}
+
+func TestChecker_DuplicateName(t *testing.T) {
+	c := New(operator.NewHub(func(context.Context) (*nopTx, error) { return &nopTx{}, nil }), 0)
+	assert.Panics(t, func() { c.Register(TransactionCheck, func(context.Context) error { return nil }) })
+}
+
+func TestChecker_Handler(t *testing.T) {
+	hub := operator.NewHub(func(context.Context) (*nopTx, error) { return &nopTx{}, nil })
+	c := New(hub, time.Second)
+	c.Register("queue", func(ctx context.Context) error { return errors.New("backlogged") })
+
+	w := httptest.NewRecorder()
+	c.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	var body struct {
+		Status string `json:"status"`
+		Checks map[string]struct {
+			Status string `json:"status"`
+			Error  string `json:"error"`
+		} `json:"checks"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, StatusDown, body.Status)
+	assert.Equal(t, StatusOK, body.Checks[TransactionCheck].Status)
+	assert.Equal(t, "backlogged", body.Checks["queue"].Error)
+}
@@ -0,0 +1,49 @@
+package httpbind
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jaz303/operator"
+	"github.com/jaz303/operator/operr"
+	"github.com/stretchr/testify/assert"
+)
+
+type user string
+
+func (u user) Subject() string { return string(u) }
+
+func TestInvoker_WithAuth(t *testing.T) {
+	inv := Bind(newTestHub(), func(ctx *operator.OpContext[*nopTx], in *struct{}) (*string, error) {
+		s := ctx.Principal().Subject()
+		return &s, nil
+	}).WithAuth(func(r *http.Request) (operator.Principal, error) {
+		switch r.Header.Get("Authorization") {
+		case "":
+			return nil, errors.New("missing credentials")
+		case "banned":
+			return nil, operr.ErrAuthorizationFailed
+		default:
+			return user(r.Header.Get("Authorization")), nil
+		}
+	})
+
+	do := func(auth string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if auth != "" {
+			r.Header.Set("Authorization", auth)
+		}
+		w := httptest.NewRecorder()
+		inv.Go(w, r)
+		return w
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, do("").Code)
+	assert.Equal(t, http.StatusForbidden, do("banned").Code)
+
+	w := do("alice")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `"alice"`, w.Body.String())
+}
@@ -0,0 +1,82 @@
+package httpbind
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/jaz303/operator"
+	"github.com/jaz303/operator/codec"
+	"github.com/jaz303/operator/operr"
+)
+
+// BindBatch() creates an Invoker binding op to a bulk endpoint. Each
+// request's input is a batch of op's inputs, decoded from a JSON array by
+// default; op is invoked for each with operator.InvokeBatch(), so items
+// succeed or fail independently, and the outcome of each is written by
+// WriteMultiStatus().
+func BindBatch[Tx operator.Transaction, I any, O any](
+	hub *operator.Hub[Tx],
+	op func(*operator.OpContext[Tx], *I) (*O, error),
+) *Invoker[Tx, []I, operator.BatchResult[O]] {
+	return &Invoker[Tx, []I, operator.BatchResult[O]]{
+		hub: hub,
+		call: func(ctx context.Context, input *[]I) (*operator.BatchResult[O], error) {
+			return operator.InvokeBatch(ctx, hub, op, *input), nil
+		},
+
+		inputMapper:  ParseJSON[[]I],
+		outputMapper: WriteMultiStatus[O],
+		errorMapper:  operr.DefaultErrorMapper,
+	}
+}
+
+// MultiStatusItem is the JSON representation of the outcome of one item of
+// a batch; see WriteMultiStatus().
+type MultiStatusItem struct {
+	// HTTP status of the item
+	Status int `json:"status"`
+
+	// Output of the item, if it succeeded
+	Output any `json:"output,omitempty"`
+
+	// Error of the item, if it failed, and the field errors it wraps, if any
+	Error  string            `json:"error,omitempty"`
+	Fields operr.FieldErrors `json:"fields,omitempty"`
+}
+
+// WriteMultiStatus writes res as JSON with status 207 Multi-Status, as an
+// object whose "items" property holds a MultiStatusItem for each item of
+// the batch, in order. The status of a successful item is 200, unless its
+// output implements StatusCoder; that of a failed item is given by
+// operr.StatusCode().
+func WriteMultiStatus[O any](w http.ResponseWriter, res *operator.BatchResult[O]) {
+	items := []MultiStatusItem{}
+	if res != nil {
+		items = make([]MultiStatusItem, len(res.Items))
+		for i, item := range res.Items {
+			items[i] = multiStatusItem(item)
+		}
+	}
+	w.Header().Set("Content-Type", codec.JSON.ContentType())
+	w.WriteHeader(http.StatusMultiStatus)
+	codec.JSON.Encode(w, map[string]any{"items": items})
+}
+
+func multiStatusItem[O any](item operator.BatchItem[O]) MultiStatusItem {
+	if item.Err != nil {
+		out := MultiStatusItem{Status: operr.StatusCode(item.Err), Error: item.Err.Error()}
+		errors.As(item.Err, &out.Fields)
+		return out
+	}
+	out := MultiStatusItem{Status: http.StatusOK}
+	if item.Output != nil && !isEmpty(item.Output) {
+		out.Output = item.Output
+		if sc, ok := any(item.Output).(StatusCoder); ok && sc.HTTPStatus() != 0 {
+			out.Status = sc.HTTPStatus()
+		}
+	} else {
+		out.Status = http.StatusNoContent
+	}
+	return out
+}
@@ -0,0 +1,48 @@
+package httpbind
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jaz303/operator"
+	"github.com/jaz303/operator/operr"
+	"github.com/stretchr/testify/assert"
+)
+
+type importRow struct {
+	Name string `json:"name"`
+}
+
+func importItem(ctx *operator.OpContext[*nopTx], in *importRow) (*createdOutput, error) {
+	switch in.Name {
+	case "":
+		return nil, operr.FieldErrors{{Field: "name", Message: "is required"}}
+	case "taken":
+		return nil, fmt.Errorf("%w: name is taken", operr.ErrConflict)
+	case "skip":
+		return nil, nil
+	}
+	return &createdOutput{ID: in.Name}, nil
+}
+
+func TestBindBatch(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`[{"name":"a"},{"name":""},{"name":"taken"},{"name":"skip"}]`))
+	BindBatch(newTestHub(), importItem).Go(w, r)
+
+	assert.Equal(t, http.StatusMultiStatus, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"items": [
+		{"status": 201, "output": {"id": "a"}},
+		{"status": 400, "error": "invalid input: name: is required", "fields": [{"field": "name", "message": "is required"}]},
+		{"status": 409, "error": "version conflict: name is taken"},
+		{"status": 204}
+	]}`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	BindBatch(newTestHub(), importItem).Go(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{`)))
+	assert.NotEqual(t, http.StatusMultiStatus, w.Code)
+}
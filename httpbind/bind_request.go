@@ -0,0 +1,121 @@
+package httpbind
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+
+	"github.com/jaz303/operator/codec"
+	"github.com/jaz303/operator/operr"
+)
+
+// BindRequest is an input mapper that populates an *I from every part of the
+// request in a single pass:
+//
+//   - fields tagged `path:"name"` from path parameters (r.PathValue)
+//   - fields tagged `query:"name"` from the query string
+//   - fields tagged `header:"Name"` from request headers
+//   - all other fields from the request body, decoded according to its
+//     Content-Type (JSON if unspecified), honouring `json` tags
+//
+// Path, query and header values take precedence over the body, and fields
+// tagged `path` or `header` are never taken from the body, even when the
+// path parameter or header is absent. Values are
+// converted to the field's type; strings, bools, integers, floats,
+// time.Duration, encoding.TextUnmarshaler (including time.Time), pointers
+// (for optional values) and slices (for repeated values) of these are
+// supported.
+//
+// Conversion failures are aggregated and returned as operr.FieldErrors. The
+// body is decoded using the codecs registered in codec.Default; use
+// BindRequestWith to select them from another registry. I must be a struct;
+// otherwise an error wrapping ErrUnsupportedInputType is returned.
+func BindRequest[I any](r *http.Request) (*I, error) {
+	return BindRequestWith[I](codec.Default)(r)
+}
+
+// BindRequestWith returns an input mapper equivalent to BindRequest that
+// decodes the request body using the codecs registered in reg.
+func BindRequestWith[I any](reg *codec.Registry) func(r *http.Request) (*I, error) {
+	return func(r *http.Request) (*I, error) {
+		var out I
+		v := reflect.ValueOf(&out).Elem()
+		if err := checkStruct(v.Type()); err != nil {
+			return nil, err
+		}
+
+		var errs operr.FieldErrors
+		if err := decodeBody(r, reg, &out); err != nil {
+			if errors.Is(err, codec.ErrUnsupportedMediaType) {
+				return nil, err
+			}
+			errs = append(errs, bodyFieldError(err))
+		}
+
+		for _, fb := range planFields(v.Type(), []string{"path", "header"}) {
+			v.FieldByIndex(fb.index).SetZero()
+		}
+		errs = append(errs, bindFields(v, requestSources(r))...)
+
+		if len(errs) > 0 {
+			return nil, errs
+		}
+
+		return &out, nil
+	}
+}
+
+func requestSources(r *http.Request) map[string]valueSource {
+	query := r.URL.Query()
+	return map[string]valueSource{
+		"path": func(name string) []string {
+			if v := r.PathValue(name); v != "" {
+				return []string{v}
+			}
+			return nil
+		},
+		"query":  func(name string) []string { return query[name] },
+		"header": func(name string) []string { return r.Header.Values(name) },
+	}
+}
+
+// decodeBody decodes r's body, if any, into v using the codec registered in
+// reg for its Content-Type.
+func decodeBody(r *http.Request, reg *codec.Registry, v any) error {
+	if r.Body == nil || r.Body == http.NoBody || r.ContentLength == 0 {
+		return nil
+	}
+
+	ct := r.Header.Get("Content-Type")
+	if ct == "" {
+		ct = codec.JSON.ContentType()
+	}
+
+	c, err := reg.Find(ct)
+	if err != nil {
+		return err
+	}
+
+	if err := c.Decode(r.Body, v); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+
+	return nil
+}
+
+func bodyFieldError(err error) operr.FieldError {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return operr.FieldError{
+			Field:   typeErr.Field,
+			Source:  "body",
+			Message: "expected " + typeDescription(typeErr.Type),
+		}
+	}
+	return operr.FieldError{
+		Source:  "body",
+		Message: err.Error(),
+	}
+}
@@ -0,0 +1,161 @@
+package httpbind
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jaz303/operator/codec"
+	"github.com/jaz303/operator/operr"
+	"github.com/stretchr/testify/assert"
+)
+
+type bindRequestInput struct {
+	ID      int           `path:"id" json:"-"`
+	Page    *int          `query:"page" json:"-"`
+	Tags    []string      `query:"tag" json:"-"`
+	Since   time.Time     `query:"since" json:"-"`
+	Timeout time.Duration `query:"timeout" json:"-"`
+	Tenant  string        `header:"X-Tenant" json:"-"`
+	PIN     *int          `header:"X-Pin" op:"redact" json:"-"`
+	Name    string        `json:"name"`
+}
+
+func bindRequestFor(target string, body string, hdr map[string]string) (*bindRequestInput, error) {
+	var out *bindRequestInput
+	var err error
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /things/{id}", func(w http.ResponseWriter, r *http.Request) {
+		out, err = BindRequest[bindRequestInput](r)
+	})
+
+	req := httptest.NewRequest("POST", target, strings.NewReader(body))
+	for k, v := range hdr {
+		req.Header.Set(k, v)
+	}
+	mux.ServeHTTP(httptest.NewRecorder(), req)
+
+	return out, err
+}
+
+func TestBindRequest(t *testing.T) {
+	in, err := bindRequestFor("/things/12?page=3&tag=a&tag=b&since=2026-01-02T03:04:05Z&timeout=5s", `{"name":"widget"}`, map[string]string{
+		"X-Tenant": "acme",
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, 12, in.ID)
+	assert.Equal(t, 3, *in.Page)
+	assert.Equal(t, []string{"a", "b"}, in.Tags)
+	assert.Equal(t, time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC), in.Since)
+	assert.Equal(t, 5*time.Second, in.Timeout)
+	assert.Equal(t, "acme", in.Tenant)
+	assert.Equal(t, "widget", in.Name)
+}
+
+func TestBindRequest_OptionalFieldsAndEmptyBody(t *testing.T) {
+	in, err := bindRequestFor("/things/1", "", nil)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 1, in.ID)
+	assert.Nil(t, in.Page)
+	assert.Nil(t, in.Tags)
+}
+
+func TestBindRequest_AggregatesErrors(t *testing.T) {
+	_, err := bindRequestFor("/things/abc?page=x&since=yesterday", `{"name":1}`, nil)
+
+	var fieldErrs operr.FieldErrors
+	assert.ErrorAs(t, err, &fieldErrs)
+	assert.Equal(t, 400, operr.StatusCode(err))
+
+	fields := map[string]string{}
+	for _, fe := range fieldErrs {
+		fields[fe.Source+":"+fe.Field] = fe.Message
+	}
+
+	assert.Equal(t, map[string]string{
+		"body:name":   "expected string",
+		"path:id":     `invalid value "abc" for integer`,
+		"query:page":  `invalid value "x" for integer`,
+		"query:since": `invalid value "yesterday" for time (RFC 3339)`,
+	}, fields)
+}
+
+func TestBindRequest_RedactedFieldErrors(t *testing.T) {
+	_, err := bindRequestFor("/things/1", "", map[string]string{"X-Pin": "12ab"})
+
+	var fieldErrs operr.FieldErrors
+	assert.ErrorAs(t, err, &fieldErrs)
+	assert.Equal(t, operr.FieldErrors{
+		{Field: "X-Pin", Source: "header", Message: "invalid value for integer"},
+	}, fieldErrs)
+}
+
+func TestBindRequest_IgnoresBodyForPathAndHeaderFields(t *testing.T) {
+	type input struct {
+		Tenant string `header:"X-Tenant" json:"tenant"`
+		Name   string `json:"name"`
+	}
+	r := httptest.NewRequest("POST", "/", strings.NewReader(`{"tenant":"other","name":"widget"}`))
+	in, err := BindRequest[input](r)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "", in.Tenant)
+	assert.Equal(t, "widget", in.Name)
+}
+
+func TestBindRequestWith_UsesRegistry(t *testing.T) {
+	type input struct {
+		Name string `json:"name" xml:"name"`
+	}
+	newRequest := func() *http.Request {
+		r := httptest.NewRequest("POST", "/", strings.NewReader(`<input><name>widget</name></input>`))
+		r.Header.Set("Content-Type", "application/xml")
+		return r
+	}
+
+	in, err := BindRequest[input](newRequest())
+	assert.Nil(t, err)
+	assert.Equal(t, "widget", in.Name)
+
+	_, err = BindRequestWith[input](codec.NewRegistry(codec.JSON))(newRequest())
+	assert.ErrorIs(t, err, codec.ErrUnsupportedMediaType)
+}
+
+func TestBindRequest_UnsupportedInputType(t *testing.T) {
+	r := httptest.NewRequest("POST", "/", strings.NewReader(`{"name":"widget"}`))
+	_, err := BindRequest[map[string]string](r)
+	assert.ErrorIs(t, err, ErrUnsupportedInputType)
+
+	_, err = ParseQuery[[]string](httptest.NewRequest("GET", "/?x=1", nil))
+	assert.ErrorIs(t, err, ErrUnsupportedInputType)
+}
+
+func bindFirstIn(r *http.Request) (any, error) {
+	type In struct {
+		X bool `query:"x"`
+	}
+	return BindRequest[In](r)
+}
+
+func bindSecondIn(r *http.Request) (any, error) {
+	type In struct {
+		Name string
+		X    string `query:"x"`
+	}
+	return BindRequest[In](r)
+}
+
+func TestBindRequest_TypesWithSameName(t *testing.T) {
+	_, err := bindFirstIn(httptest.NewRequest("GET", "/?x=true", nil))
+	assert.Nil(t, err)
+
+	in, err := bindSecondIn(httptest.NewRequest("GET", "/?x=hello", nil))
+	assert.Nil(t, err)
+	assert.Equal(t, "hello", reflect.ValueOf(in).Elem().Field(1).String())
+}
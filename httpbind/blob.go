@@ -0,0 +1,73 @@
+package httpbind
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/jaz303/operator/blob"
+	"github.com/jaz303/operator/codec"
+)
+
+// BlobObject describes an operation result to be delivered via a blob.Store.
+type BlobObject struct {
+	// Key identifying the object in the store
+	Key string
+
+	// Object contents, streamed to the store. If Body implements io.Closer
+	// it is closed once the upload completes.
+	Body io.Reader
+
+	ContentType string
+}
+
+// BlobLink is the response body written by bindings configured with
+// WithBlobOutput().
+type BlobLink struct {
+	URL     string    `json:"url"`
+	Expires time.Time `json:"expires"`
+}
+
+// WithBlobOutput() configures the binding to deliver its output out-of-band.
+// Once the operation has committed, fn converts its output into a BlobObject
+// which is streamed to store, and the response is a JSON BlobLink holding a
+// signed URL, valid for ttl, from which the client can download the result.
+//
+// This is intended for large results, such as exports, that should not be
+// buffered in memory or written over the request connection. Upload failures
+// are passed to the error mapper. WithBlobOutput() takes precedence over any
+// configured output mapper.
+func (i *Invoker[Tx, I, O]) WithBlobOutput(store blob.Store, ttl time.Duration, fn func(o *O) (*BlobObject, error)) *Invoker[Tx, I, O] {
+	i.blobOutput = &blobOutput[O]{store: store, ttl: ttl, fn: fn}
+	return i
+}
+
+type blobOutput[O any] struct {
+	store blob.Store
+	ttl   time.Duration
+	fn    func(*O) (*BlobObject, error)
+}
+
+func (b *blobOutput[O]) write(w http.ResponseWriter, r *http.Request, o *O) error {
+	obj, err := b.fn(o)
+	if err != nil {
+		return err
+	}
+	if c, ok := obj.Body.(io.Closer); ok {
+		defer c.Close()
+	}
+
+	if err := b.store.Put(r.Context(), obj.Key, obj.Body, obj.ContentType); err != nil {
+		return fmt.Errorf("blob upload failed (%w)", err)
+	}
+
+	expires := time.Now().Add(b.ttl)
+	url, err := b.store.SignedURL(r.Context(), obj.Key, b.ttl)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", codec.JSON.ContentType())
+	return codec.JSON.Encode(w, &BlobLink{URL: url, Expires: expires})
+}
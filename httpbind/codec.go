@@ -0,0 +1,74 @@
+package httpbind
+
+import (
+	"net/http"
+
+	"github.com/jaz303/operator/codec"
+)
+
+// Decode parses r's Body into a *P using the codec registered in
+// codec.Default for the request's Content-Type. Requests with no
+// Content-Type are decoded as JSON.
+func Decode[P any](r *http.Request) (*P, error) {
+	return DecodeWith[P](codec.Default)(r)
+}
+
+// DecodeWith returns an input mapper that parses r's Body into a *P using
+// the codec registered in reg for the request's Content-Type.
+func DecodeWith[P any](reg *codec.Registry) func(r *http.Request) (*P, error) {
+	return func(r *http.Request) (*P, error) {
+		ct := r.Header.Get("Content-Type")
+		if ct == "" {
+			ct = codec.JSON.ContentType()
+		}
+		c, err := reg.Find(ct)
+		if err != nil {
+			return nil, err
+		}
+		var out P
+		if err := c.Decode(r.Body, &out); err != nil {
+			return nil, err
+		}
+		return &out, nil
+	}
+}
+
+// Encode returns an output mapper that writes a *T using the codec
+// registered in codec.Default for contentType. The codec is resolved when
+// Encode is called; it panics if no such codec is registered.
+func Encode[T any](contentType string) func(w http.ResponseWriter, val *T) {
+	c, err := codec.Default.Find(contentType)
+	if err != nil {
+		panic(err)
+	}
+	return EncodeWith[T](c)
+}
+
+// EncodeWith returns an output mapper that writes a *T using c. The status
+// code and headers may be customised by implementing StatusCoder,
+// HeaderSetter or Redirector on T. Nil and *operator.Empty values are written
+// as 204 No Content.
+func EncodeWith[T any](c codec.Codec) func(w http.ResponseWriter, val *T) {
+	return func(w http.ResponseWriter, val *T) {
+		if val == nil {
+			writeEncoded(w, c, nil)
+			return
+		}
+		writeEncoded(w, c, val)
+	}
+}
+
+func writeEncoded(w http.ResponseWriter, c codec.Codec, val any) {
+	if isEmpty(val) {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	status, body := PrepareResponse(w.Header(), val, http.StatusOK)
+	if !body {
+		w.WriteHeader(status)
+		return
+	}
+	w.Header().Set("Content-Type", c.ContentType())
+	w.WriteHeader(status)
+	c.Encode(w, val)
+}
@@ -0,0 +1,162 @@
+package httpbind
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// CompressionOptions configures response compression.
+type CompressionOptions struct {
+	// Responses smaller than MinSize bytes are sent uncompressed. Defaults to
+	// 1024.
+	MinSize int
+
+	// Compression level passed to compress/gzip or compress/flate, from
+	// flate.HuffmanOnly to flate.BestCompression. Defaults to
+	// flate.DefaultCompression if nil.
+	Level *int
+}
+
+// WithCompression() configures the binding to gzip- or deflate-compress
+// responses written by the output mapper, based on the request's
+// Accept-Encoding header. Responses below the configured minimum size, and
+// responses that already set a Content-Encoding, are sent as-is. It panics if
+// opts.Level is not a valid compression level.
+func (i *Invoker[Tx, I, O]) WithCompression(opts CompressionOptions) *Invoker[Tx, I, O] {
+	if opts.MinSize <= 0 {
+		opts.MinSize = 1024
+	}
+	level := flate.DefaultCompression
+	if opts.Level != nil {
+		level = *opts.Level
+	}
+	if level < flate.HuffmanOnly || level > flate.BestCompression {
+		panic(fmt.Sprintf("httpbind: invalid compression level %d", level))
+	}
+	opts.Level = &level
+	i.compression = &opts
+	return i
+}
+
+// negotiateEncoding returns the preferred supported encoding from an
+// Accept-Encoding header, or "" if the response should not be compressed.
+func negotiateEncoding(accept string) string {
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(accept, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name != "gzip" && name != "deflate" && name != "*" {
+			continue
+		}
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+		if name == "*" {
+			name = "gzip"
+		}
+		// prefer gzip over deflate at equal quality
+		if q > bestQ || (q == bestQ && name == "gzip") {
+			best, bestQ = name, q
+		}
+	}
+	return best
+}
+
+// compressWriter buffers the response until MinSize bytes have been written,
+// then either switches to compressed output or, if the response completes
+// first, writes it uncompressed.
+type compressWriter struct {
+	http.ResponseWriter
+	opts     *CompressionOptions
+	encoding string
+
+	status  int
+	buf     bytes.Buffer
+	enc     io.WriteCloser
+	passive bool
+}
+
+func newCompressWriter(w http.ResponseWriter, r *http.Request, opts *CompressionOptions) *compressWriter {
+	return &compressWriter{
+		ResponseWriter: w,
+		opts:           opts,
+		encoding:       negotiateEncoding(r.Header.Get("Accept-Encoding")),
+	}
+}
+
+func (c *compressWriter) WriteHeader(status int) {
+	if c.status != 0 {
+		return
+	}
+	c.status = status
+	if c.encoding == "" || status == http.StatusNoContent || status == http.StatusNotModified ||
+		c.Header().Get("Content-Encoding") != "" {
+		c.passive = true
+		c.ResponseWriter.WriteHeader(status)
+	}
+}
+
+func (c *compressWriter) Write(p []byte) (int, error) {
+	if c.status == 0 {
+		c.WriteHeader(http.StatusOK)
+	}
+	if c.passive {
+		return c.ResponseWriter.Write(p)
+	}
+	if c.enc != nil {
+		return c.enc.Write(p)
+	}
+
+	c.buf.Write(p)
+	if c.buf.Len() >= c.opts.MinSize {
+		if err := c.startCompression(); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+func (c *compressWriter) startCompression() error {
+	h := c.Header()
+	h.Set("Content-Encoding", c.encoding)
+	h.Add("Vary", "Accept-Encoding")
+	h.Del("Content-Length")
+	c.ResponseWriter.WriteHeader(c.status)
+
+	if c.encoding == "gzip" {
+		c.enc, _ = gzip.NewWriterLevel(c.ResponseWriter, *c.opts.Level)
+	} else {
+		c.enc, _ = flate.NewWriter(c.ResponseWriter, *c.opts.Level)
+	}
+
+	_, err := c.enc.Write(c.buf.Bytes())
+	c.buf.Reset()
+	return err
+}
+
+// Close flushes any buffered output. It must be called once the output
+// mapper has returned.
+func (c *compressWriter) Close() error {
+	switch {
+	case c.status == 0 || c.passive:
+		return nil
+	case c.enc != nil:
+		return c.enc.Close()
+	default:
+		c.ResponseWriter.WriteHeader(c.status)
+		_, err := c.ResponseWriter.Write(c.buf.Bytes())
+		return err
+	}
+}
@@ -0,0 +1,97 @@
+package httpbind
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jaz303/operator"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	assert.Equal(t, "gzip", negotiateEncoding("gzip, deflate, br"))
+	assert.Equal(t, "deflate", negotiateEncoding("gzip;q=0.5, deflate"))
+	assert.Equal(t, "gzip", negotiateEncoding("*"))
+	assert.Equal(t, "", negotiateEncoding("br"))
+	assert.Equal(t, "", negotiateEncoding("gzip;q=0"))
+	assert.Equal(t, "", negotiateEncoding(""))
+}
+
+func compressed(acceptEncoding string, body string) *httptest.ResponseRecorder {
+	return compressedAt(gzip.DefaultCompression, acceptEncoding, body)
+}
+
+func compressedAt(level int, acceptEncoding string, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Accept-Encoding", acceptEncoding)
+	w := httptest.NewRecorder()
+
+	cw := newCompressWriter(w, r, &CompressionOptions{MinSize: 16, Level: &level})
+	cw.Header().Set("Content-Type", "text/plain")
+	io.WriteString(cw, body)
+	cw.Close()
+
+	return w
+}
+
+func TestCompressWriter(t *testing.T) {
+	body := strings.Repeat("operator ", 10)
+	w := compressed("gzip", body)
+
+	assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+	assert.Equal(t, "Accept-Encoding", w.Header().Get("Vary"))
+
+	zr, err := gzip.NewReader(w.Body)
+	assert.NoError(t, err)
+	data, _ := io.ReadAll(zr)
+	assert.Equal(t, body, string(data))
+}
+
+func TestCompressWriter_NoCompression(t *testing.T) {
+	body := strings.Repeat("operator ", 10)
+	w := compressedAt(gzip.NoCompression, "gzip", body)
+
+	assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+	assert.Greater(t, w.Body.Len(), len(body), "stored uncompressed")
+
+	zr, err := gzip.NewReader(w.Body)
+	assert.NoError(t, err)
+	data, _ := io.ReadAll(zr)
+	assert.Equal(t, body, string(data))
+}
+
+func TestWithCompression_Level(t *testing.T) {
+	bind := func() *Invoker[*nopTx, struct{}, struct{}] {
+		return Bind(newTestHub(), func(ctx *operator.OpContext[*nopTx], in *struct{}) (*struct{}, error) {
+			return in, nil
+		})
+	}
+
+	inv := bind().WithCompression(CompressionOptions{})
+	assert.Equal(t, flate.DefaultCompression, *inv.compression.Level)
+
+	level := flate.NoCompression
+	inv = bind().WithCompression(CompressionOptions{Level: &level})
+	assert.Equal(t, flate.NoCompression, *inv.compression.Level)
+
+	level = flate.BestCompression + 1
+	assert.Panics(t, func() { bind().WithCompression(CompressionOptions{Level: &level}) })
+}
+
+func TestCompressWriter_BelowThreshold(t *testing.T) {
+	w := compressed("gzip", "small")
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+	assert.Equal(t, "small", w.Body.String())
+}
+
+func TestCompressWriter_NotAccepted(t *testing.T) {
+	body := strings.Repeat("operator ", 10)
+	w := compressed("identity", body)
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+	assert.Equal(t, body, w.Body.String())
+}
@@ -0,0 +1,116 @@
+package httpbind
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/jaz303/operator"
+)
+
+// Config holds defaults shared by a group of bindings. Unlike a Service, a
+// Config does not route requests; it only pre-configures Invokers, which can
+// be mounted on any router:
+//
+//	api := httpbind.Config{
+//		ContextPolicy: operator.Inherit(),
+//		ErrorMapper:   mapAPIError,
+//		Timeout:       5 * time.Second,
+//	}
+//	mux.HandleFunc("POST /users", httpbind.BindWith(api, hub, CreateUser).Go)
+//
+// Go does not permit methods with type parameters, so bindings are created
+// with the package-level BindWith(), BindTxWith() and BindFuncWith()
+// functions. Configs are values; derive variants with Merge().
+type Config struct {
+	// ContextPolicy derives each operation's context from the request's
+	// context; if nil, the Invoker default is used.
+	ContextPolicy operator.ContextPolicy
+
+	// ErrorMapper, if non-nil, replaces the default error mapper.
+	ErrorMapper func(w http.ResponseWriter, err error)
+
+	// OutputMapper, if non-nil, writes each operation's output, which is
+	// passed as a pointer, or nil.
+	OutputMapper func(w http.ResponseWriter, o any)
+
+	// Timeout, if positive, bounds the execution time of each operation; see
+	// Invoker.WithTimeout().
+	Timeout time.Duration
+
+	// Middleware wraps each binding's handler, as if added with
+	// Invoker.Use(); the first is outermost.
+	Middleware []func(next http.HandlerFunc) http.HandlerFunc
+}
+
+// Merge() returns a copy of c with the non-zero fields of o applied; o's
+// middleware runs inside c's.
+func (c Config) Merge(o Config) Config {
+	if o.ContextPolicy != nil {
+		c.ContextPolicy = o.ContextPolicy
+	}
+	if o.ErrorMapper != nil {
+		c.ErrorMapper = o.ErrorMapper
+	}
+	if o.OutputMapper != nil {
+		c.OutputMapper = o.OutputMapper
+	}
+	if o.Timeout > 0 {
+		c.Timeout = o.Timeout
+	}
+	c.Middleware = append(c.Middleware[:len(c.Middleware):len(c.Middleware)], o.Middleware...)
+	return c
+}
+
+// BindWith() is like Bind(), applying the defaults in cfg to the returned
+// Invoker, which can be further customised.
+func BindWith[Tx operator.Transaction, I any, O any](
+	cfg Config,
+	hub *operator.Hub[Tx],
+	op func(*operator.OpContext[Tx], *I) (*O, error),
+) *Invoker[Tx, I, O] {
+	return applyConfig(cfg, Bind(hub, op))
+}
+
+// BindTxWith() is like BindTx(), applying the defaults in cfg to the returned
+// Invoker, which can be further customised.
+func BindTxWith[Tx operator.Transaction, I any, O any](
+	cfg Config,
+	hub *operator.Hub[Tx],
+	op func(*operator.OpContext[Tx], Tx, *I) (*O, error),
+) *Invoker[Tx, I, O] {
+	return applyConfig(cfg, BindTx(hub, op))
+}
+
+// BindFuncWith() is like BindFunc(), applying the defaults in cfg to the
+// returned Invoker, which can be further customised.
+func BindFuncWith[Tx operator.Transaction, I any, O any](
+	cfg Config,
+	hub *operator.Hub[Tx],
+	fn func(ctx context.Context, input *I) (*O, error),
+) *Invoker[Tx, I, O] {
+	return applyConfig(cfg, BindFunc(hub, fn))
+}
+
+func applyConfig[Tx operator.Transaction, I any, O any](cfg Config, inv *Invoker[Tx, I, O]) *Invoker[Tx, I, O] {
+	if cfg.ContextPolicy != nil {
+		inv.WithContextPolicy(cfg.ContextPolicy)
+	}
+	if cfg.ErrorMapper != nil {
+		inv.WithErrorMapper(cfg.ErrorMapper)
+	}
+	if fn := cfg.OutputMapper; fn != nil {
+		inv.WithOutputMapper(func(w http.ResponseWriter, o *O) {
+			if o == nil {
+				fn(w, nil)
+				return
+			}
+			fn(w, o)
+		})
+	}
+	if cfg.Timeout > 0 {
+		inv.WithTimeout(cfg.Timeout)
+	}
+	inv.middleware = append(inv.middleware, cfg.Middleware...)
+	return inv
+}
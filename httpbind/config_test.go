@@ -0,0 +1,70 @@
+package httpbind
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/jaz303/operator"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBindWith(t *testing.T) {
+	var order []string
+	mw := func(name string) func(http.HandlerFunc) http.HandlerFunc {
+		return func(next http.HandlerFunc) http.HandlerFunc {
+			return func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name)
+				next(w, r)
+			}
+		}
+	}
+
+	base := Config{
+		ErrorMapper: func(w http.ResponseWriter, err error) { w.WriteHeader(http.StatusTeapot) },
+		OutputMapper: func(w http.ResponseWriter, o any) {
+			w.Header().Set("X-Output", strconv.Itoa(o.(*struct{ N int }).N))
+		},
+		Timeout:    time.Second,
+		Middleware: []func(http.HandlerFunc) http.HandlerFunc{mw("outer")},
+	}
+	cfg := base.Merge(Config{Middleware: []func(http.HandlerFunc) http.HandlerFunc{mw("inner")}})
+
+	var deadline bool
+	inv := BindWith(cfg, newTestHub(), func(ctx *operator.OpContext[*nopTx], in *struct{ N int }) (*struct{ N int }, error) {
+		_, deadline = ctx.Deadline()
+		return in, nil
+	})
+
+	w := httptest.NewRecorder()
+	inv.Go(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, []string{"outer", "inner"}, order)
+	assert.True(t, deadline)
+	assert.Equal(t, "0", w.Header().Get("X-Output"))
+	assert.Len(t, base.Middleware, 1, "Merge does not modify its receiver")
+
+	failing := BindWith(cfg, newTestHub(), func(ctx *operator.OpContext[*nopTx], in *struct{}) (*struct{}, error) {
+		return nil, errors.New("boom")
+	})
+	w = httptest.NewRecorder()
+	failing.Go(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusTeapot, w.Code)
+}
+
+func TestBindTxWith_ContextPolicy(t *testing.T) {
+	type ctxKey struct{}
+	var got any
+	inv := BindTxWith(Config{ContextPolicy: operator.Inherit()}, newTestHub(), func(ctx *operator.OpContext[*nopTx], tx *nopTx, in *struct{}) (*struct{}, error) {
+		got = ctx.Value(ctxKey{})
+		return in, nil
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	inv.Go(httptest.NewRecorder(), r.WithContext(context.WithValue(r.Context(), ctxKey{}, "v")))
+	assert.Equal(t, "v", got)
+}
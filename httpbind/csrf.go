@@ -0,0 +1,164 @@
+package httpbind
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/jaz303/operator/operr"
+)
+
+var ErrCSRFTokenInvalid = errors.New("missing or invalid CSRF token")
+
+// CSRFConfig configures cross-site request forgery protection using the
+// double-submit pattern: a random token is stored in a cookie, and state
+// changing requests must echo it back in a header or form field. If Secret is
+// set, tokens are additionally signed so that a cookie planted by a sibling
+// subdomain is rejected.
+//
+// Zero values are replaced with the defaults noted below.
+type CSRFConfig struct {
+	// Name of the cookie holding the token. Defaults to "csrf_token".
+	CookieName string
+
+	// Name of the request header carrying the token. Defaults to
+	// "X-CSRF-Token".
+	HeaderName string
+
+	// Name of the form field carrying the token, checked for URL-encoded form
+	// submissions only. Defaults to "csrf_token".
+	FormField string
+
+	// Key used to sign tokens; optional.
+	Secret []byte
+
+	// Attributes of the token cookie.
+	CookiePath string
+	Secure     bool
+	SameSite   http.SameSite
+}
+
+func (c CSRFConfig) withDefaults() CSRFConfig {
+	if c.CookieName == "" {
+		c.CookieName = "csrf_token"
+	}
+	if c.HeaderName == "" {
+		c.HeaderName = "X-CSRF-Token"
+	}
+	if c.FormField == "" {
+		c.FormField = "csrf_token"
+	}
+	if c.CookiePath == "" {
+		c.CookiePath = "/"
+	}
+	if c.SameSite == 0 {
+		c.SameSite = http.SameSiteLaxMode
+	}
+	return c
+}
+
+// WithCSRF() requires state-changing requests (anything other than GET, HEAD,
+// OPTIONS and TRACE) to carry a CSRF token matching the token cookie, as
+// described by cfg. The check happens before input mapping; failures are
+// passed to the error mapper as ErrCSRFTokenInvalid wrapped with
+// operr.ErrAuthorizationFailed (403).
+//
+// Tokens are issued to clients with CSRFConfig.IssueToken().
+func (i *Invoker[Tx, I, O]) WithCSRF(cfg CSRFConfig) *Invoker[Tx, I, O] {
+	cfg = cfg.withDefaults()
+	i.csrf = &cfg
+	return i
+}
+
+// IssueToken returns the CSRF token for the client making request r, setting
+// the token cookie on w if the client does not yet have a valid token. Render
+// the token into forms, or expose it to scripts, so that it can be submitted
+// with subsequent requests.
+func (c CSRFConfig) IssueToken(w http.ResponseWriter, r *http.Request) string {
+	c = c.withDefaults()
+	if cookie, err := r.Cookie(c.CookieName); err == nil && c.valid(cookie.Value) {
+		return cookie.Value
+	}
+
+	var buf [32]byte
+	rand.Read(buf[:])
+	token := base64.RawURLEncoding.EncodeToString(buf[:])
+	if len(c.Secret) > 0 {
+		token += "." + c.sign(token)
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     c.CookieName,
+		Value:    token,
+		Path:     c.CookiePath,
+		Secure:   c.Secure,
+		SameSite: c.SameSite,
+		HttpOnly: true,
+	})
+	return token
+}
+
+// Verify checks r's CSRF token, returning ErrCSRFTokenInvalid if the request
+// is state-changing and does not carry a valid token matching the cookie.
+func (c CSRFConfig) Verify(r *http.Request) error {
+	c = c.withDefaults()
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return nil
+	}
+
+	cookie, err := r.Cookie(c.CookieName)
+	if err != nil || !c.valid(cookie.Value) {
+		return ErrCSRFTokenInvalid
+	}
+
+	submitted := r.Header.Get(c.HeaderName)
+	if submitted == "" && isURLEncodedForm(r) {
+		r.Body = http.MaxBytesReader(nil, r.Body, DefaultMaxFormBytes)
+		if err := r.ParseForm(); err != nil {
+			return formError(err)
+		}
+		submitted = r.PostForm.Get(c.FormField)
+	}
+
+	if submitted == "" || subtle.ConstantTimeCompare([]byte(submitted), []byte(cookie.Value)) != 1 {
+		return ErrCSRFTokenInvalid
+	}
+	return nil
+}
+
+func (c CSRFConfig) valid(token string) bool {
+	if token == "" {
+		return false
+	}
+	if len(c.Secret) == 0 {
+		return true
+	}
+	value, sig, ok := strings.Cut(token, ".")
+	return ok && hmac.Equal([]byte(sig), []byte(c.sign(value)))
+}
+
+func (c CSRFConfig) sign(value string) string {
+	mac := hmac.New(sha256.New, c.Secret)
+	mac.Write([]byte(value))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func isURLEncodedForm(r *http.Request) bool {
+	ct, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return ct == "application/x-www-form-urlencoded"
+}
+
+func (i *Invoker[Tx, I, O]) checkCSRF(r *http.Request) error {
+	if err := i.csrf.Verify(r); err != nil {
+		return fmt.Errorf("%w: %w", operr.ErrAuthorizationFailed, err)
+	}
+	return nil
+}
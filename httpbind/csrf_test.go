@@ -0,0 +1,58 @@
+package httpbind
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/jaz303/operator"
+	"github.com/stretchr/testify/assert"
+)
+
+type commentForm struct {
+	Body string `form:"body"`
+}
+
+func TestInvoker_WithCSRF(t *testing.T) {
+	cfg := CSRFConfig{Secret: []byte("secret")}
+	inv := Bind(newTestHub(), func(ctx *operator.OpContext[*nopTx], in *commentForm) (*commentForm, error) {
+		return in, nil
+	}).WithInputMapper(ParseForm[commentForm]).WithCSRF(cfg)
+
+	issue := httptest.NewRecorder()
+	token := cfg.IssueToken(issue, httptest.NewRequest(http.MethodGet, "/", nil))
+	cookie := issue.Result().Cookies()[0]
+
+	post := func(form url.Values, header string, withCookie bool) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if header != "" {
+			r.Header.Set("X-CSRF-Token", header)
+		}
+		if withCookie {
+			r.AddCookie(cookie)
+		}
+		w := httptest.NewRecorder()
+		inv.Go(w, r)
+		return w
+	}
+
+	w := post(url.Values{"body": {"hi"}, "csrf_token": {token}}, "", true)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"Body":"hi"}`, w.Body.String())
+
+	assert.Equal(t, http.StatusOK, post(url.Values{"body": {"hi"}}, token, true).Code)
+	assert.Equal(t, http.StatusForbidden, post(url.Values{"body": {"hi"}}, "", true).Code)
+	assert.Equal(t, http.StatusForbidden, post(url.Values{"body": {"hi"}}, token, false).Code)
+	assert.Equal(t, http.StatusForbidden, post(url.Values{"body": {"hi"}}, "forged", true).Code)
+}
+
+func TestCSRFConfig_RejectsUnsignedCookie(t *testing.T) {
+	cfg := CSRFConfig{Secret: []byte("secret")}
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+	r.AddCookie(&http.Cookie{Name: "csrf_token", Value: "planted"})
+	r.Header.Set("X-CSRF-Token", "planted")
+	assert.ErrorIs(t, cfg.Verify(r), ErrCSRFTokenInvalid)
+}
@@ -0,0 +1,52 @@
+package httpbind
+
+import (
+	"net/http"
+
+	"github.com/jaz303/operator"
+	"github.com/jaz303/operator/codec"
+)
+
+// InFlightResponse is written by InFlightHandler().
+type InFlightResponse struct {
+	Operations []operator.InFlightOperation `json:"operations"`
+}
+
+// StatsResponse is written by StatsHandler().
+type StatsResponse struct {
+	Operations []operator.OperationStats `json:"operations"`
+}
+
+// StatsHandler() returns a debug handler that writes the statistics of
+// each operation invoked on hub, as listed by Hub.Stats(), as JSON. The hub
+// must be created with operator.WithOperationStats(). Like
+// InFlightHandler(), it should only be mounted on an internal or
+// authenticated route:
+//
+//	debug.Handle("GET /debug/stats", httpbind.StatsHandler(hub))
+func StatsHandler[Tx operator.Transaction](hub *operator.Hub[Tx]) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		stats := hub.Stats()
+		if stats == nil {
+			stats = []operator.OperationStats{}
+		}
+		writeEncoded(w, codec.JSON, &StatsResponse{Operations: stats})
+	})
+}
+
+// InFlightHandler() returns a debug handler that writes the operations in
+// progress on hub, as listed by Hub.InFlight(), as JSON. The hub must be
+// created with operator.WithInFlightTracking(). The listing may reveal
+// operation names and timings, so the handler should only be mounted on an
+// internal or authenticated route:
+//
+//	debug.Handle("GET /debug/operations", httpbind.InFlightHandler(hub))
+func InFlightHandler[Tx operator.Transaction](hub *operator.Hub[Tx]) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ops := hub.InFlight()
+		if ops == nil {
+			ops = []operator.InFlightOperation{}
+		}
+		writeEncoded(w, codec.JSON, &InFlightResponse{Operations: ops})
+	})
+}
@@ -0,0 +1,63 @@
+package httpbind
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jaz303/operator"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInFlightHandler(t *testing.T) {
+	hub := operator.NewHub(func(ctx context.Context) (*nopTx, error) {
+		return &nopTx{}, nil
+	}, operator.WithInFlightTracking())
+
+	var res InFlightResponse
+	_, err := operator.Invoke(context.Background(), hub, func(ctx *operator.OpContext[*nopTx], in *struct{}) (*struct{}, error) {
+		w := httptest.NewRecorder()
+		InFlightHandler(hub).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/debug/operations", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &res))
+		return in, nil
+	}, &struct{}{})
+	assert.Nil(t, err)
+
+	if assert.Len(t, res.Operations, 1) {
+		assert.Equal(t, "running", res.Operations[0].State)
+	}
+
+	w := httptest.NewRecorder()
+	InFlightHandler(hub).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/debug/operations", nil))
+	assert.JSONEq(t, `{"operations":[]}`, w.Body.String())
+}
+
+func TestStatsHandler(t *testing.T) {
+	hub := operator.NewHub(func(ctx context.Context) (*nopTx, error) {
+		return &nopTx{}, nil
+	}, operator.WithOperationStats(100))
+
+	op := func(ctx *operator.OpContext[*nopTx], in *struct{}) (*struct{}, error) { return in, nil }
+	assert.NoError(t, operator.RegisterOperation(hub, "ping", op))
+
+	w := httptest.NewRecorder()
+	StatsHandler(hub).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/debug/stats", nil))
+	assert.JSONEq(t, `{"operations":[]}`, w.Body.String())
+
+	_, err := operator.Invoke(context.Background(), hub, op, &struct{}{})
+	assert.NoError(t, err)
+
+	var res StatsResponse
+	w = httptest.NewRecorder()
+	StatsHandler(hub).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/debug/stats", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &res))
+	if assert.Len(t, res.Operations, 1) {
+		assert.Equal(t, "ping", res.Operations[0].Name)
+		assert.Equal(t, int64(1), res.Operations[0].Count)
+	}
+}
@@ -0,0 +1,103 @@
+package httpbind
+
+import (
+	"net/http"
+	"strings"
+)
+
+// ETagger can be implemented by an operation's output type to provide an
+// entity tag for the response, typically derived from a version number or
+// content hash. The value may be given with or without surrounding quotes,
+// and may be prefixed with W/ to indicate a weak validator.
+//
+// When the output implements ETagger, the binding sets the ETag response
+// header and, for GET and HEAD requests whose If-None-Match header matches
+// the tag, responds 304 Not Modified without invoking the output mapper.
+type ETagger interface {
+	ETag() string
+}
+
+// IfMatchSetter can be implemented by an operation's input type to receive
+// the entity tags listed in the request's If-Match header, after input
+// mapping. The operation can compare these with the current version of the
+// resource and return operr.ErrPreconditionFailed if none match, implementing
+// optimistic concurrency control for updates.
+//
+// SetIfMatch is not called if the request has no If-Match header. The tags are
+// passed with quotes removed; a wildcard is passed as "*".
+//
+// Inputs that embed concurrency.ExpectedVersion implement IfMatchSetter.
+type IfMatchSetter interface {
+	SetIfMatch(etags []string)
+}
+
+func forwardIfMatch(r *http.Request, input any) {
+	s, ok := input.(IfMatchSetter)
+	if !ok {
+		return
+	}
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		return
+	}
+	var tags []string
+	for _, t := range parseETags(header) {
+		tags = append(tags, strings.Trim(strings.TrimPrefix(t, "W/"), `"`))
+	}
+	s.SetIfMatch(tags)
+}
+
+// writeETag sets the ETag header for output and returns true if the request
+// has been satisfied with 304 Not Modified.
+func writeETag(w http.ResponseWriter, r *http.Request, output any) bool {
+	e, ok := output.(ETagger)
+	if !ok {
+		return false
+	}
+	tag := formatETag(e.ETag())
+	if tag == "" {
+		return false
+	}
+	w.Header().Set("ETag", tag)
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	for _, t := range parseETags(r.Header.Get("If-None-Match")) {
+		if t == "*" || weakMatch(t, tag) {
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
+
+func formatETag(tag string) string {
+	if tag == "" {
+		return ""
+	}
+	weak, opaque := "", tag
+	if rest, ok := strings.CutPrefix(tag, "W/"); ok {
+		weak, opaque = "W/", rest
+	}
+	if !strings.HasPrefix(opaque, `"`) {
+		opaque = `"` + opaque + `"`
+	}
+	return weak + opaque
+}
+
+func parseETags(header string) []string {
+	var out []string
+	for _, t := range strings.Split(header, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// weakMatch implements the weak comparison function of RFC 9110 section
+// 8.8.3.2, as required for If-None-Match.
+func weakMatch(a, b string) bool {
+	return strings.TrimPrefix(a, "W/") == strings.TrimPrefix(b, "W/")
+}
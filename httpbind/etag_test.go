@@ -0,0 +1,109 @@
+package httpbind
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jaz303/operator"
+	"github.com/jaz303/operator/concurrency"
+	"github.com/jaz303/operator/operr"
+	"github.com/stretchr/testify/assert"
+)
+
+type nopTx struct{}
+
+func (nopTx) Commit(context.Context) error   { return nil }
+func (nopTx) Rollback(context.Context) error { return nil }
+
+func newTestHub() *operator.Hub[*nopTx] {
+	return operator.NewHub(func(context.Context) (*nopTx, error) { return &nopTx{}, nil })
+}
+
+type versioned struct {
+	Version string `json:"version"`
+}
+
+func (v *versioned) ETag() string { return v.Version }
+
+type updateInput struct {
+	ifMatch []string
+}
+
+func (u *updateInput) SetIfMatch(tags []string) { u.ifMatch = tags }
+
+func TestInvoker_ETag(t *testing.T) {
+	inv := Bind(newTestHub(), func(ctx *operator.OpContext[*nopTx], in *struct{}) (*versioned, error) {
+		return &versioned{Version: "v2"}, nil
+	})
+
+	w := httptest.NewRecorder()
+	inv.Go(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"v2"`, w.Header().Get("ETag"))
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("If-None-Match", `"v1", W/"v2"`)
+	w = httptest.NewRecorder()
+	inv.Go(w, r)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Body.String())
+}
+
+func TestInvoker_IfMatch(t *testing.T) {
+	inv := Bind(newTestHub(), func(ctx *operator.OpContext[*nopTx], in *updateInput) (*versioned, error) {
+		if len(in.ifMatch) > 0 && in.ifMatch[0] != "v2" {
+			return nil, operr.ErrPreconditionFailed
+		}
+		return &versioned{Version: "v3"}, nil
+	})
+
+	r := httptest.NewRequest(http.MethodPut, "/", nil)
+	r.Header.Set("If-Match", `"v1"`)
+	w := httptest.NewRecorder()
+	inv.Go(w, r)
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+
+	r = httptest.NewRequest(http.MethodPut, "/", nil)
+	r.Header.Set("If-Match", `"v2"`)
+	w = httptest.NewRecorder()
+	inv.Go(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"v3"`, w.Header().Get("ETag"))
+}
+
+type versionedUpdate struct {
+	concurrency.ExpectedVersion
+	Name string
+}
+
+func TestInvoker_ExpectedVersion(t *testing.T) {
+	inv := Bind(newTestHub(), func(ctx *operator.OpContext[*nopTx], in *versionedUpdate) (*versioned, error) {
+		if err := in.Check(2); err != nil {
+			return nil, err
+		}
+		return &versioned{Version: "3"}, nil
+	}).WithInputMapper(Decode[versionedUpdate])
+
+	for _, tc := range []struct {
+		ifMatch, body string
+		status        int
+	}{
+		{`"1"`, `{}`, http.StatusPreconditionFailed},
+		{`"2"`, `{}`, http.StatusOK},
+		{``, `{"expectedVersion":"1"}`, http.StatusConflict},
+		{``, `{"expectedVersion":"2"}`, http.StatusOK},
+		{``, `{}`, http.StatusOK},
+	} {
+		r := httptest.NewRequest(http.MethodPut, "/", strings.NewReader(tc.body))
+		r.Header.Set("Content-Type", "application/json")
+		if tc.ifMatch != "" {
+			r.Header.Set("If-Match", tc.ifMatch)
+		}
+		w := httptest.NewRecorder()
+		inv.Go(w, r)
+		assert.Equal(t, tc.status, w.Code, "If-Match %s, body %s", tc.ifMatch, tc.body)
+	}
+}
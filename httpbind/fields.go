@@ -0,0 +1,238 @@
+package httpbind
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jaz303/operator"
+	"github.com/jaz303/operator/operr"
+)
+
+// A valueSource supplies the raw string values for a named field, e.g. from
+// the query string or request headers.
+type valueSource func(name string) []string
+
+// fieldBinding associates a struct field with a named value in one of the
+// request's value sources.
+type fieldBinding struct {
+	index  []int
+	source string
+	name   string
+
+	// true if the field is tagged `op:"redact"`, in which case its value is
+	// not echoed in error messages
+	redact bool
+}
+
+// ErrUnsupportedInputType is returned by input mappers that bind tagged
+// fields, such as BindRequest and ParseQuery, when their input type is not a
+// struct.
+var ErrUnsupportedInputType = errors.New("input type is not a struct")
+
+// checkStruct returns an error wrapping ErrUnsupportedInputType unless ty is
+// a struct type.
+func checkStruct(ty reflect.Type) error {
+	if ty.Kind() != reflect.Struct {
+		return fmt.Errorf("%w: %s", ErrUnsupportedInputType, ty)
+	}
+	return nil
+}
+
+var fieldPlans sync.Map // fieldPlanKey -> []fieldBinding
+
+type fieldPlanKey struct {
+	ty   reflect.Type
+	tags string
+}
+
+// planFields returns the bindings for each field of ty tagged with one of the
+// supplied source tags. Exported embedded structs are searched recursively.
+func planFields(ty reflect.Type, tags []string) []fieldBinding {
+	key := fieldPlanKey{ty: ty, tags: strings.Join(tags, ",")}
+	if plan, ok := fieldPlans.Load(key); ok {
+		return plan.([]fieldBinding)
+	}
+
+	var plan []fieldBinding
+	var walk func(ty reflect.Type, prefix []int)
+	walk = func(ty reflect.Type, prefix []int) {
+		for i := range ty.NumField() {
+			f := ty.Field(i)
+			index := append(append([]int(nil), prefix...), i)
+			if f.Anonymous && f.Type.Kind() == reflect.Struct {
+				walk(f.Type, index)
+				continue
+			} else if !f.IsExported() {
+				continue
+			}
+			for _, tag := range tags {
+				if name, ok := f.Tag.Lookup(tag); ok && name != "" && name != "-" {
+					name, _, _ = strings.Cut(name, ",")
+					plan = append(plan, fieldBinding{index: index, source: tag, name: name, redact: operator.RedactedField(f)})
+					break
+				}
+			}
+		}
+	}
+	walk(ty, nil)
+
+	fieldPlans.Store(key, plan)
+	return plan
+}
+
+// bindFields populates the tagged fields of the struct v from the given
+// sources, returning an error for each field that could not be converted.
+func bindFields(v reflect.Value, sources map[string]valueSource) operr.FieldErrors {
+	tags := make([]string, 0, len(sources))
+	for _, t := range []string{"path", "query", "header", "form"} {
+		if _, ok := sources[t]; ok {
+			tags = append(tags, t)
+		}
+	}
+
+	var errs operr.FieldErrors
+	for _, fb := range planFields(v.Type(), tags) {
+		f := v.FieldByIndex(fb.index)
+		if isFileField(f.Type()) {
+			continue
+		}
+		raw := sources[fb.source](fb.name)
+		if len(raw) == 0 {
+			continue
+		}
+		if err := setField(f, raw); err != nil {
+			errs = append(errs, operr.FieldError{
+				Field:   fb.name,
+				Source:  fb.source,
+				Message: fb.errorMessage(f, err),
+			})
+		}
+	}
+
+	return errs
+}
+
+// errorMessage describes err, the failure to set f; redacted fields' values
+// are omitted.
+func (fb *fieldBinding) errorMessage(f reflect.Value, err error) string {
+	if !fb.redact {
+		return err.Error()
+	}
+	ty := f.Type()
+	for ty.Kind() == reflect.Pointer || ty.Kind() == reflect.Slice {
+		ty = ty.Elem()
+	}
+	return "invalid value for " + typeDescription(ty)
+}
+
+var (
+	durationType        = reflect.TypeFor[time.Duration]()
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+)
+
+// setField converts raw to f's type and assigns it. Pointer fields are
+// allocated; slice fields receive one element per raw value; all other
+// fields receive the first raw value.
+func setField(f reflect.Value, raw []string) error {
+	if f.Kind() == reflect.Pointer && !f.Type().Implements(textUnmarshalerType) {
+		ptr := reflect.New(f.Type().Elem())
+		if err := setField(ptr.Elem(), raw); err != nil {
+			return err
+		}
+		f.Set(ptr)
+		return nil
+	}
+
+	if f.Kind() == reflect.Slice && !reflect.PointerTo(f.Type()).Implements(textUnmarshalerType) {
+		s := reflect.MakeSlice(f.Type(), len(raw), len(raw))
+		for i, r := range raw {
+			if err := setScalar(s.Index(i), r); err != nil {
+				return err
+			}
+		}
+		f.Set(s)
+		return nil
+	}
+
+	return setScalar(f, raw[0])
+}
+
+func setScalar(f reflect.Value, s string) error {
+	if f.CanAddr() {
+		if tu, ok := f.Addr().Interface().(encoding.TextUnmarshaler); ok {
+			if err := tu.UnmarshalText([]byte(s)); err != nil {
+				return fmt.Errorf("invalid value %q for %s", s, typeDescription(f.Type()))
+			}
+			return nil
+		}
+	}
+
+	if f.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", s)
+		}
+		f.SetInt(int64(d))
+		return nil
+	}
+
+	switch f.Kind() {
+	case reflect.String:
+		f.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", s)
+		}
+		f.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, f.Type().Bits())
+		if err != nil {
+			return numberError(s, f.Type(), err)
+		}
+		f.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, f.Type().Bits())
+		if err != nil {
+			return numberError(s, f.Type(), err)
+		}
+		f.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, f.Type().Bits())
+		if err != nil {
+			return numberError(s, f.Type(), err)
+		}
+		f.SetFloat(n)
+	default:
+		return fmt.Errorf("unsupported field type %s", f.Type())
+	}
+
+	return nil
+}
+
+func numberError(s string, ty reflect.Type, err error) error {
+	if errors.Is(err, strconv.ErrRange) {
+		return fmt.Errorf("value %q out of range for %s", s, typeDescription(ty))
+	}
+	return fmt.Errorf("invalid value %q for %s", s, typeDescription(ty))
+}
+
+func typeDescription(ty reflect.Type) string {
+	switch ty.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	}
+	if ty == reflect.TypeFor[time.Time]() {
+		return "time (RFC 3339)"
+	}
+	return ty.String()
+}
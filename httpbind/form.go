@@ -0,0 +1,156 @@
+package httpbind
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"reflect"
+
+	"github.com/jaz303/operator/operr"
+)
+
+// DefaultMaxFormBytes is the request body limit applied by ParseForm.
+const DefaultMaxFormBytes = 10 << 20
+
+const maxMultipartMemory = 32 << 20
+
+var (
+	fileHeaderType      = reflect.TypeFor[*multipart.FileHeader]()
+	fileHeaderSliceType = reflect.TypeFor[[]*multipart.FileHeader]()
+	readerType          = reflect.TypeFor[io.Reader]()
+)
+
+// ParseForm parses a URL-encoded form body (and the query string) into a *P,
+// populating fields tagged `form:"name"`. Field types are converted as
+// described for BindRequest. The request body is limited to
+// DefaultMaxFormBytes; use ParseFormLimit to change this.
+func ParseForm[P any](r *http.Request) (*P, error) {
+	return ParseFormLimit[P](DefaultMaxFormBytes)(r)
+}
+
+// ParseFormLimit returns an input mapper equivalent to ParseForm that limits
+// the request body to maxBytes.
+func ParseFormLimit[P any](maxBytes int64) func(r *http.Request) (*P, error) {
+	return func(r *http.Request) (*P, error) {
+		if err := checkStruct(reflect.TypeFor[P]()); err != nil {
+			return nil, err
+		}
+		r.Body = http.MaxBytesReader(nil, r.Body, maxBytes)
+		if err := r.ParseForm(); err != nil {
+			return nil, formError(err)
+		}
+
+		var out P
+		if errs := bindFields(reflect.ValueOf(&out).Elem(), formSources(r.Form)); len(errs) > 0 {
+			return nil, errs
+		}
+
+		return &out, nil
+	}
+}
+
+// ParseMultipart returns an input mapper that parses a multipart/form-data
+// body of at most maxBytes into a *P. Fields tagged `form:"name"` are
+// populated from form values as for ParseForm; uploaded files are assigned
+// to tagged fields of the following types:
+//
+//   - *multipart.FileHeader - the first file uploaded under name
+//   - []*multipart.FileHeader - all files uploaded under name
+//   - io.Reader - the contents of the first file uploaded under name
+//
+// Readers are closed automatically once read to EOF; they also implement
+// io.Closer so that operations that do not consume the entire file can
+// release it early.
+func ParseMultipart[P any](maxBytes int64) func(r *http.Request) (*P, error) {
+	return func(r *http.Request) (*P, error) {
+		if err := checkStruct(reflect.TypeFor[P]()); err != nil {
+			return nil, err
+		}
+		r.Body = http.MaxBytesReader(nil, r.Body, maxBytes)
+		if err := r.ParseMultipartForm(min(maxBytes, maxMultipartMemory)); err != nil {
+			return nil, formError(err)
+		}
+
+		var out P
+		v := reflect.ValueOf(&out).Elem()
+
+		errs := bindFields(v, formSources(r.MultipartForm.Value))
+		errs = append(errs, bindFiles(v, r.MultipartForm.File)...)
+		if len(errs) > 0 {
+			return nil, errs
+		}
+
+		return &out, nil
+	}
+}
+
+func formSources(values map[string][]string) map[string]valueSource {
+	return map[string]valueSource{
+		"form": func(name string) []string { return values[name] },
+	}
+}
+
+func isFileField(ty reflect.Type) bool {
+	return ty == fileHeaderType || ty == fileHeaderSliceType || ty == readerType
+}
+
+func bindFiles(v reflect.Value, files map[string][]*multipart.FileHeader) operr.FieldErrors {
+	var errs operr.FieldErrors
+	for _, fb := range planFields(v.Type(), []string{"form"}) {
+		f := v.FieldByIndex(fb.index)
+		if !isFileField(f.Type()) || len(files[fb.name]) == 0 {
+			continue
+		}
+
+		fhs := files[fb.name]
+		switch f.Type() {
+		case fileHeaderType:
+			f.Set(reflect.ValueOf(fhs[0]))
+		case fileHeaderSliceType:
+			f.Set(reflect.ValueOf(fhs))
+		case readerType:
+			file, err := fhs[0].Open()
+			if err != nil {
+				errs = append(errs, operr.FieldError{Field: fb.name, Source: "form", Message: err.Error()})
+				continue
+			}
+			f.Set(reflect.ValueOf(&autoCloseReader{File: file}))
+		}
+	}
+	return errs
+}
+
+func formError(err error) error {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return err
+	}
+	return operr.FieldErrors{{Source: "body", Message: fmt.Sprintf("malformed form: %s", err)}}
+}
+
+// autoCloseReader closes its underlying file upon reaching EOF.
+type autoCloseReader struct {
+	multipart.File
+	closed bool
+}
+
+func (r *autoCloseReader) Read(p []byte) (int, error) {
+	if r.closed {
+		return 0, io.EOF
+	}
+	n, err := r.File.Read(p)
+	if err == io.EOF {
+		r.Close()
+	}
+	return n, err
+}
+
+func (r *autoCloseReader) Close() error {
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+	return r.File.Close()
+}
@@ -0,0 +1,206 @@
+package httpbind
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jaz303/operator/operr"
+	"github.com/stretchr/testify/assert"
+)
+
+type formInput struct {
+	Name  string   `form:"name"`
+	Qty   int      `form:"qty"`
+	Tags  []string `form:"tag"`
+	Notes *string  `form:"notes"`
+	PIN   int      `form:"pin" op:"redact"`
+}
+
+func formRequest(target, body string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return r
+}
+
+func TestParseForm(t *testing.T) {
+	fragile := "fragile"
+	for _, tc := range []struct {
+		name   string
+		target string
+		body   string
+		want   *formInput
+		errs   operr.FieldErrors
+	}{
+		{
+			name: "body",
+			body: "name=widget&qty=3&tag=a&tag=b",
+			want: &formInput{Name: "widget", Qty: 3, Tags: []string{"a", "b"}},
+		},
+		{
+			name:   "query string",
+			target: "/?notes=fragile",
+			body:   "name=widget",
+			want:   &formInput{Name: "widget", Notes: &fragile},
+		},
+		{
+			name: "empty",
+			want: &formInput{},
+		},
+		{
+			name: "conversion errors",
+			body: "qty=lots&pin=12ab",
+			errs: operr.FieldErrors{
+				{Field: "qty", Source: "form", Message: `invalid value "lots" for integer`},
+				{Field: "pin", Source: "form", Message: "invalid value for integer"},
+			},
+		},
+		{
+			name: "malformed",
+			body: "name=%zz",
+			errs: operr.FieldErrors{
+				{Source: "body", Message: `malformed form: invalid URL escape "%zz"`},
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			target := "/"
+			if tc.target != "" {
+				target = tc.target
+			}
+			in, err := ParseForm[formInput](formRequest(target, tc.body))
+			if tc.errs != nil {
+				assert.Equal(t, tc.errs, err)
+				assert.Equal(t, http.StatusBadRequest, operr.StatusCode(err))
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want, in)
+		})
+	}
+}
+
+func TestParseFormLimit(t *testing.T) {
+	_, err := ParseFormLimit[formInput](16)(formRequest("/", "name="+strings.Repeat("x", 32)))
+	var tooLarge *http.MaxBytesError
+	assert.ErrorAs(t, err, &tooLarge)
+
+	in, err := ParseFormLimit[formInput](16)(formRequest("/", "name=widget"))
+	assert.NoError(t, err)
+	assert.Equal(t, "widget", in.Name)
+}
+
+type multipartInput struct {
+	Name   string                  `form:"name"`
+	Qty    int                     `form:"qty"`
+	Header *multipart.FileHeader   `form:"header"`
+	All    []*multipart.FileHeader `form:"all"`
+	Reader io.Reader               `form:"reader"`
+}
+
+type formFile struct {
+	field, filename, content string
+}
+
+func multipartRequest(t *testing.T, values map[string]string, files []formFile) *http.Request {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for k, v := range values {
+		assert.NoError(t, mw.WriteField(k, v))
+	}
+	for _, f := range files {
+		w, err := mw.CreateFormFile(f.field, f.filename)
+		assert.NoError(t, err)
+		io.WriteString(w, f.content)
+	}
+	assert.NoError(t, mw.Close())
+
+	r := httptest.NewRequest(http.MethodPost, "/", &body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	return r
+}
+
+func TestParseMultipart(t *testing.T) {
+	r := multipartRequest(t, map[string]string{"name": "widget", "qty": "2"}, []formFile{
+		{"header", "a.txt", "alpha"},
+		{"all", "b.txt", "bravo"},
+		{"all", "c.txt", "charlie"},
+		{"reader", "d.txt", "delta"},
+	})
+	in, err := ParseMultipart[multipartInput](1 << 20)(r)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, "widget", in.Name)
+	assert.Equal(t, 2, in.Qty)
+	assert.Equal(t, "a.txt", in.Header.Filename)
+	if assert.Len(t, in.All, 2) {
+		assert.Equal(t, "b.txt", in.All[0].Filename)
+		assert.Equal(t, "c.txt", in.All[1].Filename)
+	}
+	content, err := io.ReadAll(in.Reader)
+	assert.NoError(t, err)
+	assert.Equal(t, "delta", string(content))
+	assert.True(t, in.Reader.(*autoCloseReader).closed, "reader is closed at EOF")
+}
+
+func TestParseMultipart_Errors(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		req   func(t *testing.T) *http.Request
+		limit int64
+		check func(t *testing.T, err error)
+	}{
+		{
+			name:  "missing files",
+			req:   func(t *testing.T) *http.Request { return multipartRequest(t, map[string]string{"name": "widget"}, nil) },
+			limit: 1 << 20,
+			check: func(t *testing.T, err error) { assert.NoError(t, err) },
+		},
+		{
+			name:  "conversion error",
+			req:   func(t *testing.T) *http.Request { return multipartRequest(t, map[string]string{"qty": "two"}, nil) },
+			limit: 1 << 20,
+			check: func(t *testing.T, err error) {
+				assert.Equal(t, operr.FieldErrors{
+					{Field: "qty", Source: "form", Message: `invalid value "two" for integer`},
+				}, err)
+			},
+		},
+		{
+			name: "too large",
+			req: func(t *testing.T) *http.Request {
+				return multipartRequest(t, nil, []formFile{{"header", "a.txt", strings.Repeat("x", 4096)}})
+			},
+			limit: 1024,
+			check: func(t *testing.T, err error) {
+				var tooLarge *http.MaxBytesError
+				assert.ErrorAs(t, err, &tooLarge)
+			},
+		},
+		{
+			name: "not multipart",
+			req: func(t *testing.T) *http.Request {
+				return formRequest("/", "name=widget")
+			},
+			limit: 1 << 20,
+			check: func(t *testing.T, err error) {
+				var fieldErrs operr.FieldErrors
+				if assert.ErrorAs(t, err, &fieldErrs) && assert.Len(t, fieldErrs, 1) {
+					assert.Equal(t, "body", fieldErrs[0].Source)
+					assert.Contains(t, fieldErrs[0].Message, "malformed form")
+				}
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseMultipart[multipartInput](tc.limit)(tc.req(t))
+			tc.check(t, err)
+		})
+	}
+}
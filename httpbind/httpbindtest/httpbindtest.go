@@ -0,0 +1,108 @@
+// Package httpbindtest provides helpers for testing operations bound to HTTP
+// endpoints with httpbind, without needing to construct a mux or decode
+// responses by hand.
+package httpbindtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jaz303/operator"
+	"github.com/jaz303/operator/httpbind"
+)
+
+// Response is the decoded result of invoking a binding.
+type Response[O any] struct {
+	// HTTP status code written by the binding
+	Status int
+
+	// Response headers
+	Header http.Header
+
+	// Raw response body
+	Body []byte
+
+	// Decoded operation output; populated for 2xx responses with a
+	// non-empty body.
+	Output *O
+
+	// Error message extracted from the error mapper's response; populated
+	// for non-2xx responses written in the default error format.
+	Error string
+}
+
+// OK returns true if the response status is 2xx.
+func (r *Response[O]) OK() bool {
+	return r.Status >= 200 && r.Status < 300
+}
+
+// InvokeJSON invokes inv with a synthesized request and returns the decoded
+// response. body is encoded as JSON unless it is nil, a []byte, or a string,
+// in which case it is sent verbatim.
+//
+// Failure to construct the request or decode the response fails the test.
+func InvokeJSON[Tx operator.Transaction, I any, O any](t testing.TB, inv *httpbind.Invoker[Tx, I, O], method string, target string, body any) *Response[O] {
+	t.Helper()
+
+	var rdr io.Reader
+	switch b := body.(type) {
+	case nil:
+	case []byte:
+		rdr = bytes.NewReader(b)
+	case string:
+		rdr = bytes.NewReader([]byte(b))
+	default:
+		data, err := json.Marshal(b)
+		if err != nil {
+			t.Fatalf("httpbindtest: failed to encode request body: %s", err)
+		}
+		rdr = bytes.NewReader(data)
+	}
+
+	req := httptest.NewRequest(method, target, rdr)
+	if rdr != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	return Invoke(t, inv, req)
+}
+
+// Invoke invokes inv with the supplied request and returns the decoded
+// response.
+func Invoke[Tx operator.Transaction, I any, O any](t testing.TB, inv *httpbind.Invoker[Tx, I, O], req *http.Request) *Response[O] {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	inv.Go(w, req)
+
+	res := &Response[O]{
+		Status: w.Code,
+		Header: w.Header(),
+		Body:   w.Body.Bytes(),
+	}
+
+	if len(res.Body) == 0 {
+		return res
+	}
+
+	if res.OK() {
+		var out O
+		if err := json.Unmarshal(res.Body, &out); err != nil {
+			t.Fatalf("httpbindtest: failed to decode response body: %s", err)
+		}
+		res.Output = &out
+	} else {
+		var problem struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(res.Body, &problem) == nil {
+			res.Error = problem.Error
+		}
+	}
+
+	return res
+}
@@ -0,0 +1,70 @@
+package httpbindtest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jaz303/operator"
+	"github.com/jaz303/operator/httpbind"
+	"github.com/stretchr/testify/assert"
+)
+
+type nopTx struct{}
+
+func (nopTx) Commit(context.Context) error   { return nil }
+func (nopTx) Rollback(context.Context) error { return nil }
+
+type orderInput struct {
+	Item string `json:"item"`
+	Qty  int    `json:"qty"`
+}
+
+type order struct {
+	Item  string `json:"item"`
+	Total int    `json:"total"`
+}
+
+func newInvoker() *httpbind.Invoker[*nopTx, orderInput, order] {
+	hub := operator.NewHub(func(context.Context) (*nopTx, error) { return &nopTx{}, nil })
+	return httpbind.Bind(hub, func(ctx *operator.OpContext[*nopTx], in *orderInput) (*order, error) {
+		if in.Qty <= 0 {
+			return nil, errors.New("quantity must be positive")
+		}
+		return &order{Item: in.Item, Total: in.Qty * 5}, nil
+	}).WithInputMapper(httpbind.ParseJSON[orderInput])
+}
+
+func TestInvokeJSON(t *testing.T) {
+	inv := newInvoker()
+
+	for _, body := range []any{
+		&orderInput{Item: "widget", Qty: 2},
+		`{"item":"widget","qty":2}`,
+		[]byte(`{"item":"widget","qty":2}`),
+	} {
+		res := InvokeJSON(t, inv, http.MethodPost, "/orders", body)
+		assert.True(t, res.OK())
+		assert.Equal(t, http.StatusOK, res.Status)
+		assert.Equal(t, &order{Item: "widget", Total: 10}, res.Output)
+		assert.Equal(t, "", res.Error)
+	}
+}
+
+func TestInvokeJSON_Error(t *testing.T) {
+	res := InvokeJSON(t, newInvoker(), http.MethodPost, "/orders", &orderInput{Item: "widget"})
+	assert.False(t, res.OK())
+	assert.Equal(t, http.StatusInternalServerError, res.Status)
+	assert.Nil(t, res.Output)
+	assert.Contains(t, res.Error, "quantity must be positive")
+	assert.Equal(t, "application/json", res.Header.Get("Content-Type"))
+}
+
+func TestInvoke(t *testing.T) {
+	res := Invoke(t, newInvoker(), httptest.NewRequest(http.MethodPost, "/orders", nil))
+	assert.False(t, res.OK())
+	assert.NotEmpty(t, res.Body)
+	assert.NotEmpty(t, res.Error)
+}
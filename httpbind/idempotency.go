@@ -0,0 +1,321 @@
+package httpbind
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/jaz303/operator/operr"
+)
+
+// IdempotencyKeyHeader is the request header from which idempotency keys
+// are read by default.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// MaxIdempotentBodyBytes is the largest request body accepted, with an
+// idempotency key, by a binding with idempotency enabled; the body is held
+// in memory to be hashed. Larger bodies are rejected with 413 Request Entity
+// Too Large.
+const MaxIdempotentBodyBytes = 10 << 20
+
+// storedHeaders are the response headers stored with, and replayed from, an
+// idempotency record. Others - Set-Cookie, Content-Encoding, Date and so on -
+// describe the original exchange rather than the operation's result, so are
+// not repeated.
+var storedHeaders = []string{
+	"Cache-Control",
+	"Content-Language",
+	"Content-Location",
+	"Content-Type",
+	"Etag",
+	"Last-Modified",
+	"Location",
+}
+
+// StoredResponse is a serialized HTTP response held by an IdempotencyStore.
+type StoredResponse struct {
+	Status int         `json:"status"`
+	Header http.Header `json:"header"`
+	Body   []byte      `json:"body"`
+}
+
+// IdempotencyRecord is the entry held by an IdempotencyStore for an
+// idempotency key.
+type IdempotencyRecord struct {
+	// Hash of the method, path and body of the request that reserved the key
+	RequestHash string `json:"request_hash"`
+
+	// Response to the request, or nil while it is in progress
+	Response *StoredResponse `json:"response,omitempty"`
+}
+
+// IdempotencyStore persists the responses of successfully completed
+// operations, keyed by idempotency key. Reservations and responses should
+// both expire, so that a reservation whose request was abandoned does not
+// block its key indefinitely.
+type IdempotencyStore interface {
+	// Reserve atomically reserves key for the request with the given hash,
+	// unless it is already reserved, in which case the existing record is
+	// returned and the store is unchanged. Returns nil if key was reserved.
+	Reserve(ctx context.Context, key string, hash string) (*IdempotencyRecord, error)
+
+	// Put stores res against key, completing its reservation.
+	Put(ctx context.Context, key string, res *StoredResponse) error
+
+	// Release removes the reservation of key, whose request failed, so that
+	// the request can be retried.
+	Release(ctx context.Context, key string) error
+}
+
+// IdempotencyKey returns the value of r's Idempotency-Key header.
+func IdempotencyKey(r *http.Request) string {
+	return r.Header.Get(IdempotencyKeyHeader)
+}
+
+type idempotency struct {
+	store  IdempotencyStore
+	key    func(*http.Request) string
+	logger *slog.Logger
+}
+
+// serve invokes exec, unless the request's idempotency key is already
+// reserved. If the reserving request has completed its stored response is
+// written instead; if it is still in progress, or was a different request,
+// the request is rejected. Responses are stored only if exec reports
+// success; failures release the key, so that clients can retry them, as do
+// 304 Not Modified responses, which answer only a conditional request.
+//
+// exec must write its response uncompressed; if compression is non-nil,
+// successful and replayed responses are compressed as they are written.
+func (id *idempotency) serve(w http.ResponseWriter, r *http.Request, exec func(http.ResponseWriter, *http.Request) bool, onError func(http.ResponseWriter, error), compression *CompressionOptions) {
+	key := id.key(r)
+	if key == "" {
+		exec(w, r)
+		return
+	}
+
+	hash, err := requestHash(r)
+	if err != nil {
+		onError(w, fmt.Errorf("%w: %w", operr.ErrInputMappingFailed, err))
+		return
+	}
+
+	// reservations outlive the request, so must not be abandoned when the
+	// client disconnects
+	ctx := context.WithoutCancel(r.Context())
+	existing, err := id.store.Reserve(ctx, key, hash)
+	if err != nil {
+		onError(w, fmt.Errorf("idempotency store reserve failed (%w)", err))
+		return
+	} else if existing != nil {
+		switch {
+		case existing.RequestHash != hash:
+			onError(w, operr.ErrIdempotencyKeyReused)
+		case existing.Response == nil:
+			onError(w, operr.ErrIdempotencyKeyInProgress)
+		default:
+			writeCompressed(w, r, compression, func(w http.ResponseWriter) {
+				writeReplayedResponse(w, existing.Response)
+			})
+		}
+		return
+	}
+
+	buf := &bufferedResponse{header: http.Header{}}
+	ok := exec(buf, r)
+	res := &StoredResponse{
+		Status: buf.Status(),
+		Header: buf.header,
+		Body:   buf.body.Bytes(),
+	}
+	if !ok || res.Status == http.StatusNotModified {
+		id.release(ctx, key)
+	} else if err := id.store.Put(ctx, key, &StoredResponse{
+		Status: res.Status,
+		Header: storableHeader(res.Header),
+		Body:   res.Body,
+	}); err != nil {
+		// The operation has committed so its response must be delivered
+		// even if it can not be stored; the key is released rather than
+		// left reserved, so that a retry is not rejected as in progress.
+		id.logger.ErrorContext(ctx, "httpbind: idempotency store put failed", "key", key, "error", err)
+		id.release(ctx, key)
+	}
+	if !ok {
+		writeStoredResponse(w, res)
+		return
+	}
+	writeCompressed(w, r, compression, func(w http.ResponseWriter) {
+		writeStoredResponse(w, res)
+	})
+}
+
+// writeCompressed calls write with w, compressed according to compression
+// and r's Accept-Encoding if compression is non-nil.
+func writeCompressed(w http.ResponseWriter, r *http.Request, compression *CompressionOptions, write func(http.ResponseWriter)) {
+	if compression == nil {
+		write(w)
+		return
+	}
+	cw := newCompressWriter(w, r, compression)
+	defer cw.Close()
+	write(cw)
+}
+
+// release releases key, logging failure; the key then remains reserved,
+// rejecting retries as in progress, until its reservation expires.
+func (id *idempotency) release(ctx context.Context, key string) {
+	if err := id.store.Release(ctx, key); err != nil {
+		id.logger.ErrorContext(ctx, "httpbind: idempotency store release failed; key remains reserved until it expires", "key", key, "error", err)
+	}
+}
+
+// storableHeader returns the headers of h that are stored with a response.
+func storableHeader(h http.Header) http.Header {
+	out := http.Header{}
+	for _, k := range storedHeaders {
+		if vs := h.Values(k); len(vs) > 0 {
+			out[k] = append([]string(nil), vs...)
+		}
+	}
+	return out
+}
+
+// requestHash hashes r's method, path and body, of at most
+// MaxIdempotentBodyBytes, restoring the body so that it can be read again.
+func requestHash(r *http.Request) (string, error) {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s\n", r.Method, r.URL.RequestURI())
+	if r.Body != nil && r.Body != http.NoBody {
+		body, err := io.ReadAll(http.MaxBytesReader(nil, r.Body, MaxIdempotentBodyBytes))
+		if err != nil {
+			return "", fmt.Errorf("read body failed (%w)", err)
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		h.Write(body)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func writeStoredResponse(w http.ResponseWriter, res *StoredResponse) {
+	for k, vs := range res.Header {
+		w.Header()[k] = append([]string(nil), vs...)
+	}
+	w.WriteHeader(res.Status)
+	w.Write(res.Body)
+}
+
+// writeReplayedResponse writes res, a response stored by an earlier request,
+// with only those of its headers that are stored; stores may hold records
+// written before the allow-list was applied.
+func writeReplayedResponse(w http.ResponseWriter, res *StoredResponse) {
+	for k, vs := range storableHeader(res.Header) {
+		w.Header()[k] = vs
+	}
+	w.WriteHeader(res.Status)
+	w.Write(res.Body)
+}
+
+// bufferedResponse is an http.ResponseWriter that holds the entire response
+// in memory.
+type bufferedResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (b *bufferedResponse) Header() http.Header { return b.header }
+
+func (b *bufferedResponse) WriteHeader(status int) {
+	if b.status == 0 {
+		b.status = status
+	}
+}
+
+func (b *bufferedResponse) Write(p []byte) (int, error) {
+	if b.status == 0 {
+		b.status = http.StatusOK
+	}
+	return b.body.Write(p)
+}
+
+func (b *bufferedResponse) Status() int {
+	if b.status == 0 {
+		return http.StatusOK
+	}
+	return b.status
+}
+
+// MemoryIdempotencyStore is an in-process IdempotencyStore whose entries
+// expire after a fixed TTL. It is suitable for tests and single-instance
+// deployments.
+type MemoryIdempotencyStore struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[string]memoryIdempotencyEntry
+}
+
+type memoryIdempotencyEntry struct {
+	rec     IdempotencyRecord
+	expires time.Time
+}
+
+// NewMemoryIdempotencyStore returns an empty store whose entries expire after
+// ttl.
+func NewMemoryIdempotencyStore(ttl time.Duration) *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{
+		ttl:     ttl,
+		entries: map[string]memoryIdempotencyEntry{},
+	}
+}
+
+func (s *MemoryIdempotencyStore) Reserve(ctx context.Context, key string, hash string) (*IdempotencyRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for k, e := range s.entries {
+		if now.After(e.expires) {
+			delete(s.entries, k)
+		}
+	}
+
+	if e, ok := s.entries[key]; ok {
+		rec := e.rec
+		return &rec, nil
+	}
+	s.entries[key] = memoryIdempotencyEntry{rec: IdempotencyRecord{RequestHash: hash}, expires: now.Add(s.ttl)}
+	return nil, nil
+}
+
+func (s *MemoryIdempotencyStore) Put(ctx context.Context, key string, res *StoredResponse) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[key]
+	if !ok {
+		return fmt.Errorf("idempotency key %q is not reserved", key)
+	}
+	e.rec.Response = res
+	e.expires = time.Now().Add(s.ttl)
+	s.entries[key] = e
+	return nil
+}
+
+func (s *MemoryIdempotencyStore) Release(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.entries[key]; ok && e.rec.Response == nil {
+		delete(s.entries, key)
+	}
+	return nil
+}
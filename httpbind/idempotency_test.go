@@ -0,0 +1,273 @@
+package httpbind
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jaz303/operator"
+	"github.com/stretchr/testify/assert"
+)
+
+type idempotencyInput struct {
+	N     int
+	Fail  bool
+	Block bool
+}
+
+type failingIdempotencyStore struct {
+	*MemoryIdempotencyStore
+	reserveErr, putErr error
+}
+
+func (s *failingIdempotencyStore) Reserve(ctx context.Context, key string, hash string) (*IdempotencyRecord, error) {
+	if s.reserveErr != nil {
+		return nil, s.reserveErr
+	}
+	return s.MemoryIdempotencyStore.Reserve(ctx, key, hash)
+}
+
+func (s *failingIdempotencyStore) Put(ctx context.Context, key string, res *StoredResponse) error {
+	if s.putErr != nil {
+		return s.putErr
+	}
+	return s.MemoryIdempotencyStore.Put(ctx, key, res)
+}
+
+func TestInvoker_WithIdempotency(t *testing.T) {
+	store := &failingIdempotencyStore{MemoryIdempotencyStore: NewMemoryIdempotencyStore(time.Hour)}
+	var calls atomic.Int32
+	unblock, blocked := make(chan struct{}), make(chan struct{})
+	inv := Bind(newTestHub(), func(ctx *operator.OpContext[*nopTx], in *idempotencyInput) (*idempotencyInput, error) {
+		calls.Add(1)
+		if in.Block {
+			close(blocked)
+			<-unblock
+		}
+		if in.Fail {
+			return nil, errors.New("failed")
+		}
+		return in, nil
+	}).WithInputMapper(BindRequest[idempotencyInput]).WithIdempotency(store, nil)
+
+	do := func(key, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		r.Header.Set(IdempotencyKeyHeader, key)
+		w := httptest.NewRecorder()
+		inv.Go(w, r)
+		return w
+	}
+
+	// completed requests are replayed
+	w := do("a", `{"N":1}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	replay := do("a", `{"N":1}`)
+	assert.Equal(t, http.StatusOK, replay.Code)
+	assert.Equal(t, w.Body.String(), replay.Body.String())
+	assert.Equal(t, int32(1), calls.Load())
+
+	// the key may not be reused for a different request
+	assert.Equal(t, http.StatusUnprocessableEntity, do("a", `{"N":2}`).Code)
+	assert.Equal(t, int32(1), calls.Load())
+
+	// concurrent requests are rejected while the first is in progress
+	done := make(chan int)
+	go func() { done <- do("b", `{"Block":true}`).Code }()
+	<-blocked
+	assert.Equal(t, http.StatusConflict, do("b", `{"Block":true}`).Code)
+	close(unblock)
+	assert.Equal(t, http.StatusOK, <-done)
+	assert.Equal(t, int32(2), calls.Load())
+
+	// failures release the key
+	assert.Equal(t, http.StatusInternalServerError, do("c", `{"Fail":true}`).Code)
+	assert.Equal(t, http.StatusInternalServerError, do("c", `{"Fail":true}`).Code)
+	assert.Equal(t, int32(4), calls.Load())
+
+	// responses that can not be stored are delivered, and release the key
+	store.putErr = errors.New("put failed")
+	assert.Equal(t, http.StatusOK, do("d", `{"N":4}`).Code)
+	store.putErr = nil
+	assert.Equal(t, http.StatusOK, do("d", `{"N":4}`).Code)
+	assert.Equal(t, int32(6), calls.Load())
+
+	// store failures are not reported as bad input
+	store.reserveErr = errors.New("store down")
+	assert.Equal(t, http.StatusInternalServerError, do("e", `{"N":5}`).Code)
+	assert.Equal(t, int32(6), calls.Load())
+}
+
+type releaseFailingStore struct {
+	*MemoryIdempotencyStore
+}
+
+func (s releaseFailingStore) Release(ctx context.Context, key string) error {
+	return errors.New("release failed")
+}
+
+func TestInvoker_WithIdempotency_LogsReleaseFailure(t *testing.T) {
+	var logs bytes.Buffer
+	hub := operator.NewHub(func(context.Context) (*nopTx, error) { return &nopTx{}, nil },
+		operator.WithLogger(slog.New(slog.NewTextHandler(&logs, nil))))
+	inv := Bind(hub, func(ctx *operator.OpContext[*nopTx], in *struct{}) (*struct{}, error) {
+		return nil, errors.New("failed")
+	}).WithIdempotency(releaseFailingStore{NewMemoryIdempotencyStore(time.Hour)}, nil)
+
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+	r.Header.Set(IdempotencyKeyHeader, "k")
+	w := httptest.NewRecorder()
+	inv.Go(w, r)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Contains(t, logs.String(), "idempotency store release failed")
+	assert.Contains(t, logs.String(), "key=k")
+}
+
+func TestInvoker_WithIdempotency_LimitsBody(t *testing.T) {
+	var called bool
+	inv := Bind(newTestHub(), func(ctx *operator.OpContext[*nopTx], in *struct{}) (*struct{}, error) {
+		called = true
+		return in, nil
+	}).WithIdempotency(NewMemoryIdempotencyStore(time.Hour), nil)
+
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(strings.Repeat("x", MaxIdempotentBodyBytes+1)))
+	r.Header.Set(IdempotencyKeyHeader, "k")
+	w := httptest.NewRecorder()
+	inv.Go(w, r)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.False(t, called)
+}
+
+func TestInvoker_WithIdempotency_ReplaysAllowedHeaders(t *testing.T) {
+	store := NewMemoryIdempotencyStore(time.Hour)
+	inv := Bind(newTestHub(), func(ctx *operator.OpContext[*nopTx], in *struct{}) (*struct{}, error) {
+		return in, nil
+	}).WithOutputMapper(func(w http.ResponseWriter, out *struct{}) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Location", "/things/1")
+		w.Header().Set("Set-Cookie", "session=abc")
+		w.Header().Set("Content-Encoding", "gzip")
+		w.WriteHeader(http.StatusCreated)
+	}).WithIdempotency(store, nil)
+
+	do := func() *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/", nil)
+		r.Header.Set(IdempotencyKeyHeader, "k")
+		w := httptest.NewRecorder()
+		inv.Go(w, r)
+		return w
+	}
+
+	first := do()
+	assert.Equal(t, http.StatusCreated, first.Code)
+	assert.Equal(t, "session=abc", first.Header().Get("Set-Cookie"), "the original response is unfiltered")
+
+	rec, _ := store.Reserve(context.Background(), "k", "")
+	assert.Equal(t, http.Header{
+		"Content-Type": {"application/json"},
+		"Location":     {"/things/1"},
+	}, rec.Response.Header)
+
+	replay := do()
+	assert.Equal(t, http.StatusCreated, replay.Code)
+	assert.Equal(t, "/things/1", replay.Header().Get("Location"))
+	assert.Equal(t, "application/json", replay.Header().Get("Content-Type"))
+	assert.Empty(t, replay.Header().Get("Set-Cookie"))
+	assert.Empty(t, replay.Header().Get("Content-Encoding"))
+}
+
+func TestWriteReplayedResponse_FiltersStoredHeaders(t *testing.T) {
+	w := httptest.NewRecorder()
+	writeReplayedResponse(w, &StoredResponse{
+		Status: http.StatusOK,
+		Header: http.Header{"Content-Type": {"text/plain"}, "Set-Cookie": {"session=abc"}},
+		Body:   []byte("ok"),
+	})
+	assert.Equal(t, http.Header{"Content-Type": {"text/plain"}}, w.Header())
+	assert.Equal(t, "ok", w.Body.String())
+}
+
+func TestInvoker_WithIdempotency_Compression(t *testing.T) {
+	store := NewMemoryIdempotencyStore(time.Hour)
+	var calls atomic.Int32
+	inv := Bind(newTestHub(), func(ctx *operator.OpContext[*nopTx], in *struct{}) (*string, error) {
+		calls.Add(1)
+		s := strings.Repeat("operator ", 10)
+		return &s, nil
+	}).WithIdempotency(store, nil).WithCompression(CompressionOptions{MinSize: 1})
+
+	do := func(acceptEncoding string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/", nil)
+		r.Header.Set(IdempotencyKeyHeader, "a")
+		if acceptEncoding != "" {
+			r.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		w := httptest.NewRecorder()
+		inv.Go(w, r)
+		return w
+	}
+	decoded := func(w *httptest.ResponseRecorder) string {
+		if w.Header().Get("Content-Encoding") != "gzip" {
+			return w.Body.String()
+		}
+		zr, err := gzip.NewReader(w.Body)
+		assert.NoError(t, err)
+		data, _ := io.ReadAll(zr)
+		return string(data)
+	}
+
+	first := do("gzip")
+	assert.Equal(t, "gzip", first.Header().Get("Content-Encoding"))
+	assert.Equal(t, "Accept-Encoding", first.Header().Get("Vary"))
+	body := decoded(first)
+
+	// the response is stored uncompressed
+	rec, _ := store.Reserve(context.Background(), "a", "")
+	assert.Equal(t, body, string(rec.Response.Body))
+	assert.Empty(t, rec.Response.Header.Get("Content-Encoding"))
+
+	// and compressed for each replay as that request accepts
+	replay := do("gzip")
+	assert.Equal(t, "gzip", replay.Header().Get("Content-Encoding"))
+	assert.Equal(t, body, decoded(replay))
+
+	plain := do("")
+	assert.Empty(t, plain.Header().Get("Content-Encoding"))
+	assert.Equal(t, body, plain.Body.String())
+
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestInvoker_WithIdempotency_NotModifiedIsNotStored(t *testing.T) {
+	store := NewMemoryIdempotencyStore(time.Hour)
+	var calls atomic.Int32
+	inv := Bind(newTestHub(), func(ctx *operator.OpContext[*nopTx], in *struct{}) (*versioned, error) {
+		calls.Add(1)
+		return &versioned{Version: "v1"}, nil
+	}).WithIdempotency(store, nil)
+
+	do := func(ifNoneMatch string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set(IdempotencyKeyHeader, "a")
+		if ifNoneMatch != "" {
+			r.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		w := httptest.NewRecorder()
+		inv.Go(w, r)
+		return w
+	}
+
+	assert.Equal(t, http.StatusNotModified, do(`"v1"`).Code)
+	w := do("")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "v1")
+	assert.Equal(t, int32(2), calls.Load())
+}
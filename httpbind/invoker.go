@@ -2,12 +2,15 @@ package httpbind
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/jaz303/operator"
+	"github.com/jaz303/operator/codec"
 	"github.com/jaz303/operator/operr"
+	"github.com/jaz303/operator/tenancy"
 )
 
 // Bind() creates an an Invoker binding the operation to an HTTP endpoint
@@ -20,7 +23,6 @@ func Bind[Tx operator.Transaction, I any, O any](
 		hub: hub,
 		op:  op,
 
-		ctx:         func(r *http.Request) context.Context { return context.Background() },
 		errorMapper: operr.DefaultErrorMapper,
 	}
 }
@@ -35,7 +37,71 @@ func BindTx[Tx operator.Transaction, I any, O any](
 		hub:  hub,
 		txOp: op,
 
-		ctx:         func(r *http.Request) context.Context { return context.Background() },
+		errorMapper: operr.DefaultErrorMapper,
+	}
+}
+
+// BindNoInput() is like Bind(), for operations that take no input.
+func BindNoInput[Tx operator.Transaction, O any](
+	hub *operator.Hub[Tx],
+	op func(*operator.OpContext[Tx]) (*O, error),
+) *Invoker[Tx, operator.Empty, O] {
+	return &Invoker[Tx, operator.Empty, O]{
+		hub: hub,
+		call: func(ctx context.Context, _ *operator.Empty) (*O, error) {
+			return operator.InvokeNoInput(ctx, hub, op)
+		},
+
+		errorMapper: operr.DefaultErrorMapper,
+	}
+}
+
+// BindNoOutput() is like Bind(), for operations that produce no output.
+// Successful requests are answered with 204 No Content by the default
+// output mapper.
+func BindNoOutput[Tx operator.Transaction, I any](
+	hub *operator.Hub[Tx],
+	op func(*operator.OpContext[Tx], *I) error,
+) *Invoker[Tx, I, operator.Empty] {
+	return &Invoker[Tx, I, operator.Empty]{
+		hub: hub,
+		call: func(ctx context.Context, input *I) (*operator.Empty, error) {
+			return nil, operator.InvokeNoOutput(ctx, hub, op, input)
+		},
+
+		errorMapper: operr.DefaultErrorMapper,
+	}
+}
+
+// BindCommand() is like Bind(), for operations that take no input and
+// produce no output. Successful requests are answered with 204 No Content
+// by the default output mapper.
+func BindCommand[Tx operator.Transaction](
+	hub *operator.Hub[Tx],
+	op func(*operator.OpContext[Tx]) error,
+) *Invoker[Tx, operator.Empty, operator.Empty] {
+	return &Invoker[Tx, operator.Empty, operator.Empty]{
+		hub: hub,
+		call: func(ctx context.Context, _ *operator.Empty) (*operator.Empty, error) {
+			return nil, operator.InvokeCommand(ctx, hub, op)
+		},
+
+		errorMapper: operr.DefaultErrorMapper,
+	}
+}
+
+// BindFunc() is like Bind(), for a function that invokes operations on hub
+// itself, such as one starting them in the background. fn is called with
+// the operation context derived by the binding's context policy, carrying
+// the request's tenant and principal.
+func BindFunc[Tx operator.Transaction, I any, O any](
+	hub *operator.Hub[Tx],
+	fn func(ctx context.Context, input *I) (*O, error),
+) *Invoker[Tx, I, O] {
+	return &Invoker[Tx, I, O]{
+		hub:  hub,
+		call: fn,
+
 		errorMapper: operr.DefaultErrorMapper,
 	}
 }
@@ -48,23 +114,62 @@ type Invoker[Tx operator.Transaction, I any, O any] struct {
 	op   func(*operator.OpContext[Tx], *I) (*O, error)
 	txOp func(*operator.OpContext[Tx], Tx, *I) (*O, error)
 
-	ctx          func(r *http.Request) context.Context
+	// invokes an operation of another form; see BindNoInput()
+	call func(ctx context.Context, input *I) (*O, error)
+
+	ctx          func(r *http.Request) (context.Context, context.CancelFunc)
+	timeout      time.Duration
 	inputMapper  func(r *http.Request) (*I, error)
 	outputMapper func(w http.ResponseWriter, o *O)
 	errorMapper  func(w http.ResponseWriter, err error)
+	codecs       *codec.Registry
+	tenant       tenancy.Resolver
+	auth         func(r *http.Request) (operator.Principal, error)
+	authPolicy   func(r *http.Request) error
+	recording    *recording
+	idempotency  *idempotency
+	blobOutput   *blobOutput[O]
+	compression  *CompressionOptions
+	rateLimit    *rateLimit[I]
+	csrf         *CSRFConfig
+	tags         []string
+	middleware   []func(next http.HandlerFunc) http.HandlerFunc
+	requestInfo  *[]string
 }
 
 // WithContext() sets a static context for the operation
 func (i *Invoker[Tx, I, O]) WithContext(ctx context.Context) *Invoker[Tx, I, O] {
-	i.ctx = func(r *http.Request) context.Context { return ctx }
+	i.ctx = func(r *http.Request) (context.Context, context.CancelFunc) { return ctx, func() {} }
 	return i
 }
 
 // WithContextFn() sets fn as a context factory the operation.
-// This can be used, for an example, to use the HTTP request's context as the operation context.
-// (in most cases this is undesirable, however).
+// Prefer WithContextPolicy() unless the operation context must be derived
+// from something other than the request's context.
 func (i *Invoker[Tx, I, O]) WithContextFunc(fn func(*http.Request) context.Context) *Invoker[Tx, I, O] {
-	i.ctx = fn
+	i.ctx = func(r *http.Request) (context.Context, context.CancelFunc) { return fn(r), func() {} }
+	return i
+}
+
+// WithContextPolicy() sets the policy used to derive the operation context
+// from the HTTP request's context; see operator.Inherit(), operator.Detach()
+// and operator.InheritWithGrace(). The default is the hub's policy; see
+// operator.WithContextPolicy().
+func (i *Invoker[Tx, I, O]) WithContextPolicy(p operator.ContextPolicy) *Invoker[Tx, I, O] {
+	i.ctx = policyContext(p)
+	return i
+}
+
+// WithTimeout() bounds the operation's execution time. The operation context,
+// derived according to the binding's context policy, is given a deadline of d;
+// if the deadline passes before the operation commits, its transaction is
+// rolled back and the error mapper receives an error wrapping operr.ErrTimeout
+// (written as 504 Gateway Timeout by the default error mapper).
+//
+// Combine with WithContextPolicy(operator.Inherit()) to additionally honour
+// the request's own deadline and cancellation.
+func (i *Invoker[Tx, I, O]) WithTimeout(d time.Duration) *Invoker[Tx, I, O] {
+	i.timeout = d
 	return i
 }
 
@@ -80,6 +185,27 @@ func (i *Invoker[Tx, I, O]) WithOutputMapper(fn func(w http.ResponseWriter, o *O
 	return i
 }
 
+// WithNegotiatedIO() configures the binding to decode its input, and encode its
+// output, using the codecs registered in codec.Default; the input codec is
+// selected by the request's Content-Type, and the output codec by its Accept
+// header. Requests accepting no registered media type fail, before the operation
+// is invoked, with an error wrapping codec.ErrNotAcceptable.
+//
+// WithNegotiatedIO() replaces any previously configured input and output mappers.
+func (i *Invoker[Tx, I, O]) WithNegotiatedIO() *Invoker[Tx, I, O] {
+	return i.WithNegotiatedIOUsing(codec.Default)
+}
+
+// WithNegotiatedIOUsing() is equivalent to WithNegotiatedIO(), selecting
+// codecs from reg. To also bind path, query and header values, follow it with
+// WithInputMapper(BindRequestWith[I](reg)).
+func (i *Invoker[Tx, I, O]) WithNegotiatedIOUsing(reg *codec.Registry) *Invoker[Tx, I, O] {
+	i.codecs = reg
+	i.inputMapper = DecodeWith[I](reg)
+	i.outputMapper = nil
+	return i
+}
+
 // WithJSONOutputFunc() is a shortcut method for the common pattern of transforming an operation's output into JSON
 func (i *Invoker[Tx, I, O]) WithJSONOutputFunc(fn func(w http.ResponseWriter, o *O)) *Invoker[Tx, I, O] {
 	i.outputMapper = func(w http.ResponseWriter, o *O) {
@@ -92,51 +218,312 @@ func (i *Invoker[Tx, I, O]) WithJSONOutputFunc(fn func(w http.ResponseWriter, o
 // WithJSONOutput() is a shortcut method for the common pattern of writing an operation's output directly as JSON
 func (i *Invoker[Tx, I, O]) WithJSONOutput(fn func(w http.ResponseWriter, o *O) any) *Invoker[Tx, I, O] {
 	i.outputMapper = func(w http.ResponseWriter, o *O) {
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(fn(w, o))
+		writeEncoded(w, codec.JSON, fn(w, o))
 	}
 	return i
 }
 
+// WithAuthPolicy() registers a function that authorizes each request before its
+// input is mapped. If fn returns an error, the operation is not invoked and the
+// error is passed to the error mapper, wrapped with operr.ErrAuthorizationFailed.
+func (i *Invoker[Tx, I, O]) WithAuthPolicy(fn func(r *http.Request) error) *Invoker[Tx, I, O] {
+	i.authPolicy = fn
+	return i
+}
+
+// WithTenantResolver() registers a resolver that identifies the tenant to
+// which each request belongs before authentication. The tenant is available
+// to the operation via OpContext.Tenant(), to the auth function and input
+// mapper via operator.TenantFrom(r.Context()), and selects the tenant's
+// transaction provider if the hub was created with operator.PerTenant.
+//
+// Errors are passed to the error mapper; tenancy.ErrUnresolved maps to 400.
+func (i *Invoker[Tx, I, O]) WithTenantResolver(res tenancy.Resolver) *Invoker[Tx, I, O] {
+	i.tenant = res
+	return i
+}
+
+// WithAuth() registers a function that authenticates each request before
+// the operation is invoked. The resulting Principal is available to the
+// operation and its event handlers via OpContext.Principal(), and to the auth
+// policy and input mapper via operator.PrincipalFrom(r.Context()).
+//
+// Errors are passed to the error mapper wrapped with operr.ErrUnauthenticated
+// (401), unless they already wrap operr.ErrAuthorizationFailed (403).
+func (i *Invoker[Tx, I, O]) WithAuth(fn func(r *http.Request) (operator.Principal, error)) *Invoker[Tx, I, O] {
+	i.auth = fn
+	return i
+}
+
+// WithTags() attaches metadata tags to the binding. Tags have no effect on
+// request handling but are reported by Service.Routes().
+func (i *Invoker[Tx, I, O]) WithTags(tags ...string) *Invoker[Tx, I, O] {
+	i.tags = append(i.tags, tags...)
+	return i
+}
+
 // Register an error mapper for writing an error to the HTTP response.
 //
-// The error provided to the callback wraps both the source error, and one of either
-// operr.ErrInputMappingFailed or operr.ErrOperationFailed, to indicate in which phase
-// the error occurred.
+// The error provided to the callback wraps both the source error, and one of
+// operr.ErrAuthorizationFailed, operr.ErrInputMappingFailed or operr.ErrOperationFailed,
+// to indicate in which phase the error occurred.
 //
 // Since you will likely use the same error mapper for every operation, to avoid
-// registering the mapper each time, it is common to wrap Bind() and BindTx() to attach
-// your preferred handler automatically.
+// registering the mapper each time, bind related operations through a Service,
+// which applies its error mapper to every binding, or with BindWith() and a
+// shared Config.
 func (i *Invoker[Tx, I, O]) WithErrorMapper(fn func(w http.ResponseWriter, err error)) *Invoker[Tx, I, O] {
 	i.errorMapper = fn
 	return i
 }
 
+// Use() appends middleware wrapping the binding's handler, for per-endpoint
+// concerns such as request logging, header checks or response shaping.
+// Middleware runs in the order added, outermost first, before the request
+// is mapped to the operation's input; it may short-circuit the request by
+// not calling next.
+func (i *Invoker[Tx, I, O]) Use(mw ...func(next http.HandlerFunc) http.HandlerFunc) *Invoker[Tx, I, O] {
+	i.middleware = append(i.middleware, mw...)
+	return i
+}
+
+// WithRecorder() registers a recorder that captures every request/response
+// pair handled by this binding. Recordings can later be replayed against a
+// test hub using Replay().
+//
+// Each recording is passed through anonymizers before reaching rec;
+// Authorization, Cookie and Set-Cookie headers are always redacted. Request
+// bodies are limited to MaxRecordedBodyBytes. Errors returned by rec are
+// logged to the hub's logger.
+func (i *Invoker[Tx, I, O]) WithRecorder(rec Recorder, anonymizers ...Anonymizer) *Invoker[Tx, I, O] {
+	i.recording = &recording{recorder: rec, anonymizers: anonymizers}
+	return i
+}
+
+// WithIdempotency() enables idempotent request handling. keyFn extracts an
+// idempotency key from each request (IdempotencyKey() is used if keyFn is nil,
+// reading the Idempotency-Key header); requests without a key are handled
+// normally.
+//
+// The key is reserved in store before the operation is invoked and, once the
+// operation has committed and its output has been written, the response is
+// saved against it. A later request with the same key has the stored
+// response replayed - its status, body and content headers such as
+// Content-Type, Location and ETag, but not Set-Cookie or Content-Encoding -
+// without invoking the operation; it is rejected with
+// operr.ErrIdempotencyKeyInProgress (409) if the first request has not
+// completed, or operr.ErrIdempotencyKeyReused (422) if its method, path or
+// body differ. Failed operations release the key, so may be retried.
+// 304 Not Modified responses are not saved. With WithCompression(), the
+// response is saved uncompressed and compressed as each request accepts.
+// Requests with a key may have bodies of at most MaxIdempotentBodyBytes.
+// Store failures after the operation has run are logged to the hub's logger.
+func (i *Invoker[Tx, I, O]) WithIdempotency(store IdempotencyStore, keyFn func(*http.Request) string) *Invoker[Tx, I, O] {
+	if keyFn == nil {
+		keyFn = IdempotencyKey
+	}
+	i.idempotency = &idempotency{store: store, key: keyFn, logger: i.hub.Logger()}
+	return i
+}
+
 // Invoke the bound operation in the context of the supplied HTTP request
 func (i *Invoker[Tx, I, O]) Go(w http.ResponseWriter, r *http.Request) {
-	input, err := i.getInputMapper()(r)
+	if len(i.middleware) == 0 {
+		i.serve(w, r)
+		return
+	}
+	h := i.serve
+	for ix := len(i.middleware) - 1; ix >= 0; ix-- {
+		h = i.middleware[ix](h)
+	}
+	h(w, r)
+}
+
+func (i *Invoker[Tx, I, O]) serve(w http.ResponseWriter, r *http.Request) {
+	if i.recording != nil {
+		i.goRecorded(w, r)
+		return
+	}
+	i.invoke(w, r)
+}
+
+func (i *Invoker[Tx, I, O]) goRecorded(w http.ResponseWriter, r *http.Request) {
+	body, err := readBody(r, MaxRecordedBodyBytes)
 	if err != nil {
 		i.errorMapper(w, fmt.Errorf("%w: %w", operr.ErrInputMappingFailed, err))
 		return
 	}
 
+	capture := &responseCapture{ResponseWriter: w}
+	i.invoke(capture, r)
+
+	err = i.recording.record(&Recording{
+		Request: RecordedRequest{
+			Method: r.Method,
+			URL:    r.URL.RequestURI(),
+			Header: r.Header.Clone(),
+			Body:   string(body),
+		},
+		Response: RecordedResponse{
+			Status: capture.Status(),
+			Header: w.Header().Clone(),
+			Body:   capture.body.String(),
+		},
+	})
+	if err != nil {
+		i.hub.Logger().ErrorContext(r.Context(), "httpbind: recording failed", "method", r.Method, "url", r.URL.RequestURI(), "error", err)
+	}
+}
+
+func (i *Invoker[Tx, I, O]) invoke(w http.ResponseWriter, r *http.Request) {
+	if i.idempotency != nil {
+		// the stored response is compressed for each request that receives
+		// it, according to that request's Accept-Encoding
+		exec := func(w http.ResponseWriter, r *http.Request) bool { return i.execute(w, r, nil) }
+		i.idempotency.serve(w, r, exec, i.errorMapper, i.compression)
+		return
+	}
+	i.execute(w, r, i.compression)
+}
+
+// execute maps the request to the operation's input, invokes the operation,
+// and writes its output, compressed according to compression if non-nil,
+// returning true on success.
+func (i *Invoker[Tx, I, O]) execute(w http.ResponseWriter, r *http.Request, compression *CompressionOptions) bool {
+	var tenant string
+	if i.tenant != nil {
+		t, err := i.tenant(r)
+		if err != nil {
+			i.errorMapper(w, err)
+			return false
+		}
+		tenant = t
+		r = r.WithContext(operator.WithTenant(r.Context(), t))
+	}
+
+	var principal operator.Principal
+	if i.auth != nil {
+		p, err := i.auth(r)
+		if err != nil {
+			if !errors.Is(err, operr.ErrAuthorizationFailed) {
+				err = fmt.Errorf("%w: %w", operr.ErrUnauthenticated, err)
+			}
+			i.errorMapper(w, err)
+			return false
+		}
+		principal = p
+		r = r.WithContext(operator.WithPrincipal(r.Context(), p))
+	}
+
+	if i.authPolicy != nil {
+		if err := i.authPolicy(r); err != nil {
+			i.errorMapper(w, fmt.Errorf("%w: %w", operr.ErrAuthorizationFailed, err))
+			return false
+		}
+	}
+
+	if i.csrf != nil {
+		if err := i.checkCSRF(r); err != nil {
+			i.errorMapper(w, err)
+			return false
+		}
+	}
+
+	outputMapper := i.getOutputMapper()
+	if i.codecs != nil && i.outputMapper == nil {
+		enc, err := i.codecs.Negotiate(r.Header.Get("Accept"))
+		if err != nil {
+			i.errorMapper(w, fmt.Errorf("%w: %w", operr.ErrInputMappingFailed, err))
+			return false
+		}
+		outputMapper = EncodeWith[O](enc)
+	}
+
+	input, err := i.getInputMapper()(r)
+	if err != nil {
+		i.errorMapper(w, fmt.Errorf("%w: %w", operr.ErrInputMappingFailed, err))
+		return false
+	}
+	forwardIfMatch(r, input)
+
+	if i.rateLimit != nil {
+		if err := i.rateLimit.check(r, input); err != nil {
+			i.errorMapper(w, err)
+			return false
+		}
+	}
+
+	ctx, cancel := i.getContext(r)
+	defer cancel()
+	if tenant != "" {
+		ctx = operator.WithTenant(ctx, tenant)
+	}
+	if principal != nil {
+		ctx = operator.WithPrincipal(ctx, principal)
+	}
+	if i.requestInfo != nil {
+		ctx = operator.WithRequestInfo(ctx, NewRequestInfo(r, *i.requestInfo))
+	}
+
 	var output *O
-	if i.txOp != nil {
-		output, err = operator.InvokeTx(i.getContext(r), i.hub, i.txOp, input)
+	if i.call != nil {
+		output, err = i.call(ctx, input)
+	} else if i.txOp != nil {
+		output, err = operator.InvokeTx(ctx, i.hub, i.txOp, input)
 	} else {
-		output, err = operator.Invoke(i.getContext(r), i.hub, i.op, input)
+		output, err = operator.Invoke(ctx, i.hub, i.op, input)
 	}
 
 	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			err = fmt.Errorf("%w: %w", operr.ErrTimeout, err)
+		}
 		i.errorMapper(w, fmt.Errorf("%w: %w", operr.ErrOperationFailed, err))
-		return
+		return false
+	}
+
+	if output != nil && writeETag(w, r, output) {
+		return true
+	}
+
+	if compression != nil {
+		cw := newCompressWriter(w, r, compression)
+		defer cw.Close()
+		w = cw
 	}
 
-	i.getOutputMapper()(w, output)
+	if i.blobOutput != nil {
+		if err := i.blobOutput.write(w, r, output); err != nil {
+			i.errorMapper(w, fmt.Errorf("%w: %w", operr.ErrOperationFailed, err))
+			return false
+		}
+		return true
+	}
+
+	outputMapper(w, output)
+	return true
+}
+
+func (i *Invoker[Tx, I, O]) getContext(r *http.Request) (context.Context, context.CancelFunc) {
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if i.ctx != nil {
+		ctx, cancel = i.ctx(r)
+	} else {
+		ctx, cancel = i.hub.ContextPolicy()(r.Context())
+	}
+	if i.timeout <= 0 {
+		return ctx, cancel
+	}
+	ctx, cancelTimeout := context.WithTimeout(ctx, i.timeout)
+	return ctx, func() {
+		cancelTimeout()
+		cancel()
+	}
 }
 
-func (i *Invoker[Tx, I, O]) getContext(r *http.Request) context.Context {
-	return i.ctx(r)
+func policyContext(p operator.ContextPolicy) func(r *http.Request) (context.Context, context.CancelFunc) {
+	return func(r *http.Request) (context.Context, context.CancelFunc) { return p(r.Context()) }
 }
 
 func (i *Invoker[Tx, I, O]) getInputMapper() func(r *http.Request) (*I, error) {
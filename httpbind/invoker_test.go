@@ -0,0 +1,153 @@
+package httpbind
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jaz303/operator"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInvoker_Use(t *testing.T) {
+	called := false
+	inv := Bind(newTestHub(), func(ctx *operator.OpContext[*nopTx], in *struct{}) (*struct{}, error) {
+		called = true
+		return in, nil
+	}).Use(func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("X-Key") == "" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.Header().Set("X-Checked", "1")
+			next(w, r)
+		}
+	})
+
+	w := httptest.NewRecorder()
+	inv.Go(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.False(t, called)
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("X-Key", "k")
+	w = httptest.NewRecorder()
+	inv.Go(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "1", w.Header().Get("X-Checked"))
+	assert.True(t, called)
+}
+
+func TestInvoker_HubContextPolicy(t *testing.T) {
+	type ctxKey struct{}
+	hub := operator.NewHub(func(context.Context) (*nopTx, error) { return &nopTx{}, nil }, operator.WithContextPolicy(operator.Detach()))
+
+	var got any
+	var cancelled bool
+	inv := Bind(hub, func(ctx *operator.OpContext[*nopTx], in *struct{}) (*struct{}, error) {
+		got = ctx.Value(ctxKey{})
+		cancelled = ctx.Err() != nil
+		return in, nil
+	})
+
+	reqCtx, cancel := context.WithCancel(context.WithValue(context.Background(), ctxKey{}, "trace"))
+	cancel()
+	inv.Go(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil).WithContext(reqCtx))
+
+	assert.Equal(t, "trace", got)
+	assert.False(t, cancelled)
+}
+
+func TestInvoker_WithRequestInfo(t *testing.T) {
+	var got *operator.RequestInfo
+	inv := Bind(newTestHub(), func(ctx *operator.OpContext[*nopTx], in *struct{}) (*struct{}, error) {
+		got = ctx.Request()
+		return in, nil
+	}).WithRequestInfo("X-Request-Id")
+
+	r := httptest.NewRequest(http.MethodPost, "/users?x=1", nil)
+	r.RemoteAddr = "10.0.0.1:1234"
+	r.Header.Set("User-Agent", "test/1.0")
+	r.Header.Set("X-Request-Id", "abc")
+	r.Header.Set("Authorization", "secret")
+	inv.Go(httptest.NewRecorder(), r)
+
+	assert.Equal(t, &operator.RequestInfo{
+		Method:    http.MethodPost,
+		Path:      "/users",
+		RemoteIP:  "10.0.0.1",
+		UserAgent: "test/1.0",
+		Header:    map[string][]string{"X-Request-Id": {"abc"}},
+	}, got)
+}
+
+func TestBindNoInputNoOutput(t *testing.T) {
+	hub := newTestHub()
+
+	w := httptest.NewRecorder()
+	BindNoInput(hub, func(ctx *operator.OpContext[*nopTx]) (*struct{ N int }, error) {
+		return &struct{ N int }{3}, nil
+	}).Go(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"N":3}`, w.Body.String())
+
+	var got string
+	w = httptest.NewRecorder()
+	BindNoOutput(hub, func(ctx *operator.OpContext[*nopTx], in *string) error {
+		got = *in
+		return nil
+	}).WithInputMapper(func(r *http.Request) (*string, error) {
+		s := r.URL.Query().Get("q")
+		return &s, nil
+	}).Go(w, httptest.NewRequest(http.MethodPost, "/?q=x", nil))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Empty(t, w.Body.String())
+	assert.Equal(t, "x", got)
+
+	called := false
+	w = httptest.NewRecorder()
+	BindCommand(hub, func(ctx *operator.OpContext[*nopTx]) error {
+		called = true
+		return nil
+	}).Go(w, httptest.NewRequest(http.MethodPost, "/", nil))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.True(t, called)
+
+	w = httptest.NewRecorder()
+	BindCommand(hub, func(ctx *operator.OpContext[*nopTx]) error {
+		return operator.ErrForbidden
+	}).Go(w, httptest.NewRequest(http.MethodPost, "/", nil))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestBindFunc(t *testing.T) {
+	hub := operator.NewHub(func(context.Context) (*nopTx, error) { return &nopTx{}, nil }, operator.WithContextPolicy(operator.Detach()))
+
+	var cancelled bool
+	inv := BindFunc(hub, func(ctx context.Context, in *struct{ N int }) (*string, error) {
+		cancelled = ctx.Err() != nil
+		if in.N < 0 {
+			return nil, operator.ErrForbidden
+		}
+		p, _ := operator.PrincipalFrom(ctx)
+		s := p.Subject()
+		return &s, nil
+	}).WithAuth(func(r *http.Request) (operator.Principal, error) {
+		return user("alice"), nil
+	}).WithInputMapper(ParseJSON[struct{ N int }])
+
+	reqCtx, cancel := context.WithCancel(context.Background())
+	cancel()
+	w := httptest.NewRecorder()
+	inv.Go(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"N":1}`)).WithContext(reqCtx))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `"alice"`, w.Body.String())
+	assert.False(t, cancelled, "the hub's context policy applies")
+
+	w = httptest.NewRecorder()
+	inv.Go(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"N":-1}`)))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
@@ -0,0 +1,87 @@
+package httpbind
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+
+	"github.com/jaz303/operator"
+	"github.com/jaz303/operator/codec"
+	"github.com/jaz303/operator/operr"
+)
+
+// PathValue returns the path parameter name of r (see http.Request.PathValue)
+// converted to a T; any type supported by BindRequest may be used. A missing
+// or unconvertible value is reported as operr.FieldErrors, which the default
+// error mapper writes as 400 Bad Request.
+//
+//	id, err := httpbind.PathValue[int64](r, "id")
+func PathValue[T any](r *http.Request, name string) (T, error) {
+	var out T
+	raw := r.PathValue(name)
+	if raw == "" {
+		return out, operr.FieldErrors{{Field: name, Source: "path", Message: "required"}}
+	}
+	if err := setField(reflect.ValueOf(&out).Elem(), []string{raw}); err != nil {
+		return out, operr.FieldErrors{{Field: name, Source: "path", Message: err.Error()}}
+	}
+	return out, nil
+}
+
+// JSONWithPath returns an input mapper that parses r's body, if any, into an
+// *I as JSON, then sets fields of the result from path parameters. params
+// maps each path parameter name to the name of the field it populates:
+//
+//	mux.HandleFunc("PUT /users/{id}", httpbind.Bind(hub, UpdateUser).
+//		WithInputMapper(httpbind.JSONWithPath[UpdateUserInput](map[string]string{"id": "ID"})).
+//		Go)
+//
+// Fields populated from path parameters are never set from the body, as
+// with BindRequest, so are left zero if their parameter is empty.
+// Conversion failures, and malformed bodies, are aggregated and returned as
+// operr.FieldErrors.
+// JSONWithPath panics if I has no exported field named by params.
+func JSONWithPath[I any](params map[string]string) func(r *http.Request) (*I, error) {
+	ty := reflect.TypeFor[I]()
+	fields := make([]fieldBinding, 0, len(params))
+	for param, field := range params {
+		f, ok := ty.FieldByName(field)
+		if !ok || !f.IsExported() {
+			panic(fmt.Sprintf("httpbind: %s has no exported field %s for path parameter %s", ty, field, param))
+		}
+		fields = append(fields, fieldBinding{index: f.Index, source: "path", name: param, redact: operator.RedactedField(f)})
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i].name < fields[j].name })
+
+	return func(r *http.Request) (*I, error) {
+		var out I
+		var errs operr.FieldErrors
+
+		if r.Body != nil && r.Body != http.NoBody {
+			if err := codec.JSON.Decode(r.Body, &out); err != nil && !errors.Is(err, io.EOF) {
+				errs = append(errs, bodyFieldError(err))
+			}
+		}
+
+		v := reflect.ValueOf(&out).Elem()
+		for _, fb := range fields {
+			f := v.FieldByIndex(fb.index)
+			f.SetZero()
+			raw := r.PathValue(fb.name)
+			if raw == "" {
+				continue
+			}
+			if err := setField(f, []string{raw}); err != nil {
+				errs = append(errs, operr.FieldError{Field: fb.name, Source: fb.source, Message: fb.errorMessage(f, err)})
+			}
+		}
+
+		if len(errs) > 0 {
+			return nil, errs
+		}
+		return &out, nil
+	}
+}
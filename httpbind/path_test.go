@@ -0,0 +1,87 @@
+package httpbind
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jaz303/operator/operr"
+	"github.com/stretchr/testify/assert"
+)
+
+func serveAt(pattern, target, body string, fn func(r *http.Request)) {
+	mux := http.NewServeMux()
+	mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) { fn(r) })
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPut, target, strings.NewReader(body)))
+}
+
+func TestPathValue(t *testing.T) {
+	serveAt("PUT /users/{id}/{slug}", "/users/42/abc", "", func(r *http.Request) {
+		id, err := PathValue[int64](r, "id")
+		assert.NoError(t, err)
+		assert.Equal(t, int64(42), id)
+
+		_, err = PathValue[int](r, "slug")
+		assert.Equal(t, operr.FieldErrors{{Field: "slug", Source: "path", Message: `invalid value "abc" for integer`}}, err)
+
+		_, err = PathValue[string](r, "missing")
+		assert.Equal(t, operr.FieldErrors{{Field: "missing", Source: "path", Message: "required"}}, err)
+	})
+}
+
+type updateUserInput struct {
+	ID    int    `json:"-"`
+	Org   string `json:"-"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+func TestJSONWithPath(t *testing.T) {
+	mapper := JSONWithPath[updateUserInput](map[string]string{"id": "ID", "org": "Org"})
+
+	serveAt("PUT /orgs/{org}/users/{id}", "/orgs/acme/users/7", `{"name":"Ann","email":"ann@example.com"}`, func(r *http.Request) {
+		in, err := mapper(r)
+		assert.NoError(t, err)
+		assert.Equal(t, &updateUserInput{ID: 7, Org: "acme", Name: "Ann", Email: "ann@example.com"}, in)
+	})
+
+	serveAt("PUT /orgs/{org}/users/{id}", "/orgs/acme/users/x", `{"name":1}`, func(r *http.Request) {
+		_, err := mapper(r)
+		assert.Equal(t, operr.FieldErrors{
+			{Field: "name", Source: "body", Message: "expected string"},
+			{Field: "id", Source: "path", Message: `invalid value "x" for integer`},
+		}, err)
+	})
+
+	serveAt("PUT /orgs/{org}/users/{id}", "/orgs/acme/users/1", "", func(r *http.Request) {
+		in, err := mapper(r)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, in.ID)
+	})
+}
+
+func TestJSONWithPath_IgnoresBodyForPathFields(t *testing.T) {
+	type input struct {
+		Org  string `json:"org"`
+		Name string `json:"name"`
+	}
+	mapper := JSONWithPath[input](map[string]string{"org": "Org"})
+
+	serveAt("PUT /orgs/{org}", "/orgs/acme", `{"org":"evil","name":"Ann"}`, func(r *http.Request) {
+		in, err := mapper(r)
+		assert.NoError(t, err)
+		assert.Equal(t, &input{Org: "acme", Name: "Ann"}, in)
+	})
+
+	// the parameter is empty, but the body still can not set the field
+	serveAt("PUT /users", "/users", `{"org":"evil","name":"Ann"}`, func(r *http.Request) {
+		in, err := mapper(r)
+		assert.NoError(t, err)
+		assert.Equal(t, &input{Name: "Ann"}, in)
+	})
+}
+
+func TestJSONWithPath_UnknownField(t *testing.T) {
+	assert.Panics(t, func() { JSONWithPath[updateUserInput](map[string]string{"id": "Missing"}) })
+}
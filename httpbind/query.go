@@ -0,0 +1,36 @@
+package httpbind
+
+import (
+	"net/http"
+	"reflect"
+)
+
+// ParseQuery parses r's query string into a *P, populating fields tagged
+// `query:"name"`, and ignoring the request body; it suits list and filter
+// endpoints:
+//
+//	type ListUsersInput struct {
+//		Limit  int        `query:"limit"`
+//		Roles  []string   `query:"role"`
+//		Active *bool      `query:"active"`
+//		Since  time.Time  `query:"since"`
+//	}
+//
+// Field types are converted as described for BindRequest: pointer fields are
+// left nil, and slice fields empty, if the parameter is absent. Conversion
+// failures are aggregated and returned as operr.FieldErrors, which the
+// default error mapper writes as 400 Bad Request.
+func ParseQuery[P any](r *http.Request) (*P, error) {
+	var out P
+	if err := checkStruct(reflect.TypeFor[P]()); err != nil {
+		return nil, err
+	}
+	query := r.URL.Query()
+	sources := map[string]valueSource{
+		"query": func(name string) []string { return query[name] },
+	}
+	if errs := bindFields(reflect.ValueOf(&out).Elem(), sources); len(errs) > 0 {
+		return nil, errs
+	}
+	return &out, nil
+}
@@ -0,0 +1,48 @@
+package httpbind
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jaz303/operator/operr"
+	"github.com/stretchr/testify/assert"
+)
+
+type listInput struct {
+	Limit  int       `query:"limit"`
+	Roles  []string  `query:"role"`
+	Active *bool     `query:"active"`
+	Since  time.Time `query:"since"`
+	Name   string    `json:"name"`
+}
+
+func TestParseQuery(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/users?limit=10&role=admin&role=owner&active=true&since=2026-01-02T00:00:00Z", nil)
+
+	in, err := ParseQuery[listInput](r)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 10, in.Limit)
+	assert.Equal(t, []string{"admin", "owner"}, in.Roles)
+	assert.True(t, *in.Active)
+	assert.Equal(t, time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC), in.Since)
+}
+
+func TestParseQuery_Absent(t *testing.T) {
+	in, err := ParseQuery[listInput](httptest.NewRequest(http.MethodGet, "/users", nil))
+
+	assert.NoError(t, err)
+	assert.Equal(t, &listInput{}, in)
+}
+
+func TestParseQuery_Errors(t *testing.T) {
+	_, err := ParseQuery[listInput](httptest.NewRequest(http.MethodGet, "/users?limit=lots&active=maybe", nil))
+
+	assert.Equal(t, operr.FieldErrors{
+		{Field: "limit", Source: "query", Message: `invalid value "lots" for integer`},
+		{Field: "active", Source: "query", Message: `invalid boolean "maybe"`},
+	}, err)
+	assert.Equal(t, http.StatusBadRequest, operr.StatusCode(err))
+}
@@ -0,0 +1,61 @@
+package httpbind
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/jaz303/operator"
+	"github.com/jaz303/operator/operr"
+	"github.com/jaz303/operator/ratelimit"
+)
+
+type rateLimit[I any] struct {
+	limiter ratelimit.Limiter
+	key     func(r *http.Request, input *I) string
+}
+
+// WithRateLimit() limits the rate at which the operation may be invoked.
+// keyFn is called after input mapping and returns the key against which the
+// request is counted - e.g. the client IP, the principal, or a field of the
+// parsed input. Requests exceeding the limit are passed to the error mapper
+// as an *operr.RateLimitError, which the default error mapper writes as 429
+// Too Many Requests with a Retry-After header.
+//
+// See ByClientIP and ByPrincipal for common key functions.
+func (i *Invoker[Tx, I, O]) WithRateLimit(limiter ratelimit.Limiter, keyFn func(r *http.Request, input *I) string) *Invoker[Tx, I, O] {
+	i.rateLimit = &rateLimit[I]{limiter: limiter, key: keyFn}
+	return i
+}
+
+func (l *rateLimit[I]) check(r *http.Request, input *I) error {
+	ok, retryAfter, err := l.limiter.Allow(r.Context(), l.key(r, input))
+	if err != nil {
+		return fmt.Errorf("rate limiter failed (%w)", err)
+	} else if !ok {
+		return &operr.RateLimitError{RetryAfter: retryAfter}
+	}
+	return nil
+}
+
+// ByClientIP is a rate limit key function that keys requests by the
+// client's IP address, as given by r.RemoteAddr. Applications behind a proxy
+// should rewrite RemoteAddr from a trusted forwarding header before the
+// request reaches the binding.
+func ByClientIP[I any](r *http.Request, input *I) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// ByPrincipal is a rate limit key function that keys requests by the subject
+// of the principal established by WithAuth(), falling back to the client IP
+// for unauthenticated requests.
+func ByPrincipal[I any](r *http.Request, input *I) string {
+	if p, ok := operator.PrincipalFrom(r.Context()); ok && p != nil {
+		return "principal:" + p.Subject()
+	}
+	return "ip:" + ByClientIP(r, input)
+}
@@ -0,0 +1,33 @@
+package httpbind
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jaz303/operator"
+	"github.com/jaz303/operator/ratelimit"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInvoker_WithRateLimit(t *testing.T) {
+	inv := Bind(newTestHub(), func(ctx *operator.OpContext[*nopTx], in *struct{}) (*struct{}, error) {
+		return in, nil
+	}).WithRateLimit(ratelimit.NewTokenBucket(0.5, 1), ByClientIP)
+
+	do := func(addr string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = addr
+		w := httptest.NewRecorder()
+		inv.Go(w, r)
+		return w
+	}
+
+	assert.Equal(t, http.StatusOK, do("10.0.0.1:1234").Code)
+
+	w := do("10.0.0.1:5678")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "2", w.Header().Get("Retry-After"))
+
+	assert.Equal(t, http.StatusOK, do("10.0.0.2:1234").Code)
+}
@@ -0,0 +1,240 @@
+package httpbind
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Recording is a captured request/response pair, suitable for persisting
+// and later replaying against an operation binding.
+type Recording struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest is the request half of a Recording.
+type RecordedRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+// RecordedResponse is the response half of a Recording.
+type RecordedResponse struct {
+	Status int         `json:"status"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+// Recorder receives recordings captured by an Invoker.
+type Recorder interface {
+	Record(rec *Recording) error
+}
+
+// Anonymizer mutates a Recording in place before it is passed to a
+// Recorder, typically to strip credentials or personal data.
+type Anonymizer func(rec *Recording)
+
+// RedactHeaders returns an Anonymizer that replaces the values of the named
+// request and response headers with a fixed placeholder.
+func RedactHeaders(names ...string) Anonymizer {
+	return func(rec *Recording) {
+		for _, n := range names {
+			if rec.Request.Header.Get(n) != "" {
+				rec.Request.Header.Set(n, "REDACTED")
+			}
+			if rec.Response.Header.Get(n) != "" {
+				rec.Response.Header.Set(n, "REDACTED")
+			}
+		}
+	}
+}
+
+// MaxRecordedBodyBytes is the largest request body accepted by a binding
+// with a Recorder, which holds the body in memory to record it. Larger
+// bodies are rejected with 413 Request Entity Too Large.
+const MaxRecordedBodyBytes = 10 << 20
+
+// defaultAnonymizer redacts credentials from every recording.
+var defaultAnonymizer = RedactHeaders("Authorization", "Cookie", "Set-Cookie")
+
+// recording configures an Invoker's Recorder; see Invoker.WithRecorder().
+type recording struct {
+	recorder    Recorder
+	anonymizers []Anonymizer
+}
+
+// record anonymizes rec and passes it to the recorder.
+func (c *recording) record(rec *Recording) error {
+	defaultAnonymizer(rec)
+	for _, fn := range c.anonymizers {
+		fn(rec)
+	}
+	return c.recorder.Record(rec)
+}
+
+// FileRecorder is a Recorder that writes each recording to its own JSON
+// file in a directory.
+type FileRecorder struct {
+	dir string
+
+	mu  sync.Mutex
+	seq int
+}
+
+// NewFileRecorder returns a FileRecorder writing to dir, which is created
+// if it does not exist.
+func NewFileRecorder(dir string) *FileRecorder {
+	return &FileRecorder{dir: dir}
+}
+
+func (r *FileRecorder) Record(rec *Recording) error {
+	data, err := json.MarshalIndent(rec, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(r.dir, 0o755); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.seq++
+	name := fmt.Sprintf("%d-%04d.json", time.Now().UnixNano(), r.seq)
+	r.mu.Unlock()
+
+	return os.WriteFile(filepath.Join(r.dir, name), data, 0o644)
+}
+
+// LoadRecordings reads all recordings written to dir by a FileRecorder,
+// in the order they were recorded.
+func LoadRecordings(dir string) ([]*Recording, error) {
+	names, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+
+	out := make([]*Recording, 0, len(names))
+	for _, n := range names {
+		data, err := os.ReadFile(n)
+		if err != nil {
+			return nil, err
+		}
+		var rec Recording
+		if err := json.Unmarshal(data, &rec); err != nil {
+			return nil, fmt.Errorf("%s: %w", n, err)
+		}
+		out = append(out, &rec)
+	}
+
+	return out, nil
+}
+
+// ReplayMismatch is returned by Replay when the replayed response differs
+// from the recorded one.
+type ReplayMismatch struct {
+	Recording *Recording
+	Status    int
+	Body      string
+}
+
+func (e *ReplayMismatch) Error() string {
+	exp := e.Recording.Response
+	if exp.Status != e.Status {
+		return fmt.Sprintf("%s %s: expected status %d, got %d", e.Recording.Request.Method, e.Recording.Request.URL, exp.Status, e.Status)
+	}
+	return fmt.Sprintf("%s %s: response body mismatch\nexpected: %s\nactual:   %s", e.Recording.Request.Method, e.Recording.Request.URL, exp.Body, e.Body)
+}
+
+// Replay re-issues the recorded request against h (typically an Invoker's Go
+// method, bound to a test hub) and compares the response status and body
+// with those recorded. JSON bodies are compared structurally.
+//
+// Returns a *ReplayMismatch if the responses differ.
+func Replay(h http.Handler, rec *Recording) error {
+	req := httptest.NewRequest(rec.Request.Method, rec.Request.URL, strings.NewReader(rec.Request.Body))
+	for k, vs := range rec.Request.Header {
+		for _, v := range vs {
+			req.Header.Add(k, v)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+
+	if w.Code != rec.Response.Status || !bodiesEqual(rec.Response.Body, w.Body.String()) {
+		return &ReplayMismatch{
+			Recording: rec,
+			Status:    w.Code,
+			Body:      w.Body.String(),
+		}
+	}
+
+	return nil
+}
+
+func bodiesEqual(expected, actual string) bool {
+	if expected == actual {
+		return true
+	}
+	var e, a any
+	if json.Unmarshal([]byte(expected), &e) != nil || json.Unmarshal([]byte(actual), &a) != nil {
+		return false
+	}
+	return reflect.DeepEqual(e, a)
+}
+
+// responseCapture is an http.ResponseWriter that forwards to an underlying
+// writer while retaining a copy of the status and body.
+type responseCapture struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (c *responseCapture) WriteHeader(status int) {
+	if c.status == 0 {
+		c.status = status
+	}
+	c.ResponseWriter.WriteHeader(status)
+}
+
+func (c *responseCapture) Write(p []byte) (int, error) {
+	if c.status == 0 {
+		c.status = http.StatusOK
+	}
+	c.body.Write(p)
+	return c.ResponseWriter.Write(p)
+}
+
+func (c *responseCapture) Status() int {
+	if c.status == 0 {
+		return http.StatusOK
+	}
+	return c.status
+}
+
+// readBody consumes r's body, of at most maxBytes, and replaces it with an
+// equivalent reader so that it may be read again by the input mapper.
+func readBody(r *http.Request, maxBytes int64) ([]byte, error) {
+	if r.Body == nil {
+		return nil, nil
+	}
+	data, err := io.ReadAll(http.MaxBytesReader(nil, r.Body, maxBytes))
+	r.Body.Close()
+	r.Body = io.NopCloser(bytes.NewReader(data))
+	return data, err
+}
@@ -0,0 +1,124 @@
+package httpbind
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jaz303/operator"
+	"github.com/stretchr/testify/assert"
+)
+
+type greetInput struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+type greeting struct {
+	Message string `json:"message"`
+}
+
+func greet(ctx *operator.OpContext[*nopTx], in *greetInput) (*greeting, error) {
+	return &greeting{Message: "hello " + in.Name}, nil
+}
+
+type memRecorder struct {
+	recs []*Recording
+	err  error
+}
+
+func (m *memRecorder) Record(rec *Recording) error {
+	m.recs = append(m.recs, rec)
+	return m.err
+}
+
+func TestRecorder_RecordThenReplay(t *testing.T) {
+	dir := t.TempDir()
+	inv := Bind(newTestHub(), greet).
+		WithInputMapper(ParseJSON[greetInput]).
+		WithRecorder(NewFileRecorder(dir))
+
+	for _, name := range []string{"alice", "bob"} {
+		r := httptest.NewRequest(http.MethodPost, "/greet?v=1", strings.NewReader(`{"name":"`+name+`"}`))
+		r.Header.Set("Authorization", "Bearer secret")
+		w := httptest.NewRecorder()
+		inv.Go(w, r)
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+
+	recs, err := LoadRecordings(dir)
+	assert.NoError(t, err)
+	if !assert.Len(t, recs, 2) {
+		return
+	}
+	assert.Equal(t, http.MethodPost, recs[0].Request.Method)
+	assert.Equal(t, "/greet?v=1", recs[0].Request.URL)
+	assert.Equal(t, `{"name":"alice"}`, recs[0].Request.Body)
+	assert.Equal(t, "REDACTED", recs[0].Request.Header.Get("Authorization"))
+	assert.Equal(t, http.StatusOK, recs[0].Response.Status)
+	assert.JSONEq(t, `{"message":"hello alice"}`, recs[0].Response.Body)
+	assert.JSONEq(t, `{"message":"hello bob"}`, recs[1].Response.Body)
+
+	replayed := Bind(newTestHub(), greet).WithInputMapper(ParseJSON[greetInput])
+	for _, rec := range recs {
+		assert.NoError(t, Replay(http.HandlerFunc(replayed.Go), rec))
+	}
+
+	changed := Bind(newTestHub(), func(ctx *operator.OpContext[*nopTx], in *greetInput) (*greeting, error) {
+		return &greeting{Message: "hi " + in.Name}, nil
+	}).WithInputMapper(ParseJSON[greetInput])
+	var mismatch *ReplayMismatch
+	assert.ErrorAs(t, Replay(http.HandlerFunc(changed.Go), recs[0]), &mismatch)
+	assert.Equal(t, http.StatusOK, mismatch.Status)
+	assert.JSONEq(t, `{"message":"hi alice"}`, mismatch.Body)
+}
+
+func TestRecorder_AnonymizesBeforeRecording(t *testing.T) {
+	rec := &memRecorder{}
+	inv := Bind(newTestHub(), greet).
+		WithInputMapper(ParseJSON[greetInput]).
+		WithRecorder(rec, func(rec *Recording) {
+			rec.Request.Body = strings.ReplaceAll(rec.Request.Body, "alice@example.com", "user@example.com")
+		})
+
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name":"alice","email":"alice@example.com"}`))
+	r.Header.Set("Cookie", "session=abc")
+	inv.Go(httptest.NewRecorder(), r)
+
+	if assert.Len(t, rec.recs, 1) {
+		assert.Equal(t, `{"name":"alice","email":"user@example.com"}`, rec.recs[0].Request.Body)
+		assert.Equal(t, "REDACTED", rec.recs[0].Request.Header.Get("Cookie"))
+	}
+}
+
+func TestRecorder_LogsErrors(t *testing.T) {
+	var logs bytes.Buffer
+	hub := operator.NewHub(func(context.Context) (*nopTx, error) { return &nopTx{}, nil },
+		operator.WithLogger(slog.New(slog.NewTextHandler(&logs, nil))))
+	inv := Bind(hub, greet).
+		WithInputMapper(ParseJSON[greetInput]).
+		WithRecorder(&memRecorder{err: assert.AnError})
+
+	w := httptest.NewRecorder()
+	inv.Go(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name":"alice"}`)))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, logs.String(), "httpbind: recording failed")
+	assert.Contains(t, logs.String(), assert.AnError.Error())
+}
+
+func TestRecorder_LimitsBody(t *testing.T) {
+	rec := &memRecorder{}
+	inv := Bind(newTestHub(), greet).
+		WithInputMapper(ParseJSON[greetInput]).
+		WithRecorder(rec)
+
+	body := `{"name":"` + strings.Repeat("x", MaxRecordedBodyBytes) + `"}`
+	w := httptest.NewRecorder()
+	inv.Go(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Empty(t, rec.recs)
+}
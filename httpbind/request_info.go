@@ -0,0 +1,43 @@
+package httpbind
+
+import (
+	"net"
+	"net/http"
+	"net/textproto"
+
+	"github.com/jaz303/operator"
+)
+
+// NewRequestInfo describes r as an operator.RequestInfo, capturing only the
+// named headers. The remote IP is taken from r.RemoteAddr; applications
+// behind a proxy should rewrite RemoteAddr from a trusted forwarding header
+// before the request reaches the binding.
+func NewRequestInfo(r *http.Request, headers []string) *operator.RequestInfo {
+	ri := &operator.RequestInfo{
+		Method:    r.Method,
+		Path:      r.URL.Path,
+		RemoteIP:  r.RemoteAddr,
+		UserAgent: r.UserAgent(),
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		ri.RemoteIP = host
+	}
+	for _, name := range headers {
+		if v := r.Header.Values(name); len(v) > 0 {
+			if ri.Header == nil {
+				ri.Header = map[string][]string{}
+			}
+			ri.Header[textproto.CanonicalMIMEHeaderKey(name)] = v
+		}
+	}
+	return ri
+}
+
+// WithRequestInfo() attaches an operator.RequestInfo describing each request
+// to the operation's context, from which operations, event handlers and
+// middleware can retrieve it with OpContext.Request() or
+// operator.RequestInfoFrom(). Only the named headers are captured.
+func (i *Invoker[Tx, I, O]) WithRequestInfo(headers ...string) *Invoker[Tx, I, O] {
+	i.requestInfo = &headers
+	return i
+}
@@ -0,0 +1,75 @@
+package httpbind
+
+import (
+	"net/http"
+
+	"github.com/jaz303/operator"
+)
+
+// StatusCoder can be implemented by an operation's output type to override
+// the HTTP status code written by the default output mappers.
+type StatusCoder interface {
+	HTTPStatus() int
+}
+
+// HeaderSetter can be implemented by an operation's output type to add
+// headers to the response written by the default output mappers.
+type HeaderSetter interface {
+	SetHTTPHeaders(h http.Header)
+}
+
+// Redirector can be implemented by an operation's output type to have the
+// default output mappers respond with a redirect instead of a body. The
+// status is 303 See Other unless the output also implements StatusCoder.
+type Redirector interface {
+	RedirectURL() string
+}
+
+// PrepareResponse applies any headers provided by val to h, and returns the
+// status code that should be written for val, defaulting to status. The
+// returned bool is false if val is a redirect and no body should be written.
+//
+// PrepareResponse is used by the default output mappers to honour the
+// StatusCoder, HeaderSetter and Redirector interfaces, and may be used by
+// custom output mappers to do likewise. If val is an operator.Result, the
+// interfaces implemented by its output are honoured.
+func PrepareResponse(h http.Header, val any, status int) (int, bool) {
+	if r, ok := val.(operator.ResultInfo); ok {
+		val = r.ResultOutput()
+	}
+	if val == nil {
+		return status, true
+	}
+
+	if hs, ok := val.(HeaderSetter); ok {
+		hs.SetHTTPHeaders(h)
+	}
+
+	body := true
+	if r, ok := val.(Redirector); ok {
+		if url := r.RedirectURL(); url != "" {
+			h.Set("Location", url)
+			status = http.StatusSeeOther
+			body = false
+		}
+	}
+
+	if sc, ok := val.(StatusCoder); ok {
+		if s := sc.HTTPStatus(); s != 0 {
+			status = s
+		}
+	}
+
+	return status, body
+}
+
+// isEmpty returns true if val represents the absence of output and should be
+// written as 204 No Content.
+func isEmpty(val any) bool {
+	switch val.(type) {
+	case nil, *operator.Empty, operator.Empty:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,72 @@
+package httpbind
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jaz303/operator"
+	"github.com/stretchr/testify/assert"
+)
+
+type createdOutput struct {
+	ID string `json:"id"`
+}
+
+func (o *createdOutput) HTTPStatus() int { return http.StatusCreated }
+
+func (o *createdOutput) SetHTTPHeaders(h http.Header) { h.Set("Location", "/items/"+o.ID) }
+
+type redirectOutput struct{ To string }
+
+func (o redirectOutput) RedirectURL() string { return o.To }
+
+func TestWriteJSON_StatusAndHeaders(t *testing.T) {
+	w := httptest.NewRecorder()
+	WriteJSON(w, &createdOutput{ID: "42"})
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "/items/42", w.Header().Get("Location"))
+	assert.JSONEq(t, `{"id":"42"}`, w.Body.String())
+}
+
+func TestWriteJSON_Redirect(t *testing.T) {
+	w := httptest.NewRecorder()
+	WriteJSON(w, &redirectOutput{To: "/done"})
+
+	assert.Equal(t, http.StatusSeeOther, w.Code)
+	assert.Equal(t, "/done", w.Header().Get("Location"))
+	assert.Empty(t, w.Body.String())
+
+}
+
+func TestWriteJSON_Empty(t *testing.T) {
+	w := httptest.NewRecorder()
+	WriteJSON(w, &operator.Empty{})
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Empty(t, w.Body.String())
+
+	w = httptest.NewRecorder()
+	WriteJSON[redirectOutput](w, nil)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Empty(t, w.Body.String())
+}
+
+func TestWriteJSON_Result(t *testing.T) {
+	w := httptest.NewRecorder()
+	res := operator.NewResult(&createdOutput{ID: "42"}).WarnAbout("rows[3]", "duplicate_row", "row 3 duplicates row 1").Set("imported", 9)
+	WriteJSON(w, res)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "/items/42", w.Header().Get("Location"))
+	assert.JSONEq(t, `{
+		"output": {"id": "42"},
+		"warnings": [{"code": "duplicate_row", "message": "row 3 duplicates row 1", "target": "rows[3]"}],
+		"metadata": {"imported": 9}
+	}`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	WriteJSON(w, operator.NewResult[createdOutput](nil).Warn("", "nothing to do"))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"output": null, "warnings": [{"message": "nothing to do"}]}`, w.Body.String())
+}
@@ -0,0 +1,205 @@
+package httpbind
+
+import (
+	"net/http"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/jaz303/operator"
+	"github.com/jaz303/operator/tenancy"
+)
+
+// Service groups related operation bindings under a common route prefix,
+// applying shared defaults - error mapper, context policy, tenant resolution,
+// authentication, auth policy and metadata tags - to each binding.
+//
+// Defaults are copied to each binding as it is registered, so must be set
+// before the first call to Handle() or HandleTx(); setting a default later
+// panics.
+//
+// A Service is an http.Handler; mount it on your router at its prefix.
+type Service[Tx operator.Transaction] struct {
+	hub    *operator.Hub[Tx]
+	prefix string
+
+	errorMapper func(w http.ResponseWriter, err error)
+	ctxPolicy   operator.ContextPolicy
+	tenant      tenancy.Resolver
+	auth        func(r *http.Request) (operator.Principal, error)
+	authPolicy  func(r *http.Request) error
+	tags        []string
+
+	mu     sync.Mutex
+	mux    *http.ServeMux
+	routes []serviceRoute
+}
+
+// Route describes a binding registered with a Service.
+type Route struct {
+	Method    string
+	Path      string
+	Operation string
+	Input     reflect.Type
+	Output    reflect.Type
+	Tags      []string
+}
+
+type serviceRoute struct {
+	route Route
+	tags  func() []string
+}
+
+// NewService() returns a Service whose bindings are mounted beneath prefix.
+func NewService[Tx operator.Transaction](hub *operator.Hub[Tx], prefix string) *Service[Tx] {
+	return &Service[Tx]{
+		hub:    hub,
+		prefix: strings.TrimSuffix(prefix, "/"),
+		mux:    http.NewServeMux(),
+	}
+}
+
+// WithErrorMapper() sets the error mapper applied to the service's bindings.
+func (s *Service[Tx]) WithErrorMapper(fn func(w http.ResponseWriter, err error)) *Service[Tx] {
+	s.checkUnbound()
+	s.errorMapper = fn
+	return s
+}
+
+// WithContextPolicy() sets the context policy applied to the service's bindings.
+func (s *Service[Tx]) WithContextPolicy(p operator.ContextPolicy) *Service[Tx] {
+	s.checkUnbound()
+	s.ctxPolicy = p
+	return s
+}
+
+// WithTenantResolver() sets the tenant resolver applied to the service's bindings.
+func (s *Service[Tx]) WithTenantResolver(res tenancy.Resolver) *Service[Tx] {
+	s.checkUnbound()
+	s.tenant = res
+	return s
+}
+
+// WithAuth() sets the authentication function applied to the service's bindings.
+func (s *Service[Tx]) WithAuth(fn func(r *http.Request) (operator.Principal, error)) *Service[Tx] {
+	s.checkUnbound()
+	s.auth = fn
+	return s
+}
+
+// WithAuthPolicy() sets the auth policy applied to the service's bindings.
+func (s *Service[Tx]) WithAuthPolicy(fn func(r *http.Request) error) *Service[Tx] {
+	s.checkUnbound()
+	s.authPolicy = fn
+	return s
+}
+
+// WithTags() sets metadata tags applied to the service's bindings.
+func (s *Service[Tx]) WithTags(tags ...string) *Service[Tx] {
+	s.checkUnbound()
+	s.tags = append(s.tags, tags...)
+	return s
+}
+
+func (s *Service[Tx]) checkUnbound() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.routes) > 0 {
+		panic("httpbind: service defaults must be set before bindings are registered")
+	}
+}
+
+// Routes() returns a description of each binding registered with the service.
+func (s *Service[Tx]) Routes() []Route {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make([]Route, len(s.routes))
+	for ix, r := range s.routes {
+		out[ix] = r.route
+		out[ix].Tags = r.tags()
+	}
+	return out
+}
+
+func (s *Service[Tx]) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Handle() binds op to the route described by pattern, which takes the form
+// "METHOD /path" as understood by http.ServeMux; the path is relative to the
+// service's prefix. As with http.ServeMux, a path ending in a slash matches
+// every path beneath it, so bind the prefix itself with "/{$}" rather than
+// "/". The service's defaults are applied to the returned Invoker,
+// which can be further customised.
+func Handle[Tx operator.Transaction, I any, O any](
+	s *Service[Tx],
+	pattern string,
+	op func(*operator.OpContext[Tx], *I) (*O, error),
+) *Invoker[Tx, I, O] {
+	inv := Bind(s.hub, op)
+	s.register(pattern, funcName(op), reflect.TypeFor[I](), reflect.TypeFor[O](), inv.Go, func() []string { return inv.tags })
+	return configureInvoker(s, inv)
+}
+
+// HandleTx() binds the transactional operation op to the route described by
+// pattern; see Handle().
+func HandleTx[Tx operator.Transaction, I any, O any](
+	s *Service[Tx],
+	pattern string,
+	op func(*operator.OpContext[Tx], Tx, *I) (*O, error),
+) *Invoker[Tx, I, O] {
+	inv := BindTx(s.hub, op)
+	s.register(pattern, funcName(op), reflect.TypeFor[I](), reflect.TypeFor[O](), inv.Go, func() []string { return inv.tags })
+	return configureInvoker(s, inv)
+}
+
+func (s *Service[Tx]) register(pattern string, opName string, in, out reflect.Type, h http.HandlerFunc, tags func() []string) {
+	method, path, ok := strings.Cut(pattern, " ")
+	if !ok {
+		method, path = "", pattern
+	}
+	path = s.prefix + "/" + strings.TrimPrefix(strings.TrimSpace(path), "/")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.mux.HandleFunc(strings.TrimSpace(method+" "+path), h)
+	s.routes = append(s.routes, serviceRoute{
+		route: Route{
+			Method:    method,
+			Path:      path,
+			Operation: opName,
+			Input:     in,
+			Output:    out,
+		},
+		tags: tags,
+	})
+}
+
+func configureInvoker[Tx operator.Transaction, I any, O any](s *Service[Tx], inv *Invoker[Tx, I, O]) *Invoker[Tx, I, O] {
+	if s.errorMapper != nil {
+		inv.WithErrorMapper(s.errorMapper)
+	}
+	if s.ctxPolicy != nil {
+		inv.WithContextPolicy(s.ctxPolicy)
+	}
+	if s.tenant != nil {
+		inv.WithTenantResolver(s.tenant)
+	}
+	if s.auth != nil {
+		inv.WithAuth(s.auth)
+	}
+	if s.authPolicy != nil {
+		inv.WithAuthPolicy(s.authPolicy)
+	}
+	return inv.WithTags(s.tags...)
+}
+
+func funcName(fn any) string {
+	if f := runtime.FuncForPC(reflect.ValueOf(fn).Pointer()); f != nil {
+		return f.Name()
+	}
+	return ""
+}
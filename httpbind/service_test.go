@@ -0,0 +1,109 @@
+package httpbind
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/jaz303/operator"
+	"github.com/stretchr/testify/assert"
+)
+
+type serviceInput struct {
+	ID string `path:"id"`
+}
+
+type serviceOutput struct {
+	ID string `json:"id"`
+}
+
+func getServiceItem(ctx *operator.OpContext[*nopTx], in *serviceInput) (*serviceOutput, error) {
+	return &serviceOutput{ID: in.ID}, nil
+}
+
+func TestService_Prefix(t *testing.T) {
+	var created int
+	s := NewService(newTestHub(), "/users/")
+	Handle(s, "POST /{$}", func(ctx *operator.OpContext[*nopTx], in *struct{}) (*struct{}, error) {
+		created++
+		return in, nil
+	})
+	Handle(s, "GET /{id}", getServiceItem).WithInputMapper(BindRequest[serviceInput])
+
+	for _, tc := range []struct {
+		method, path string
+		code         int
+		body         string
+	}{
+		{http.MethodPost, "/users/", http.StatusOK, ""},
+		{http.MethodPost, "/users/42/orders", http.StatusNotFound, ""},
+		{http.MethodPost, "/orders/", http.StatusNotFound, ""},
+		{http.MethodGet, "/users/42", http.StatusOK, `{"id":"42"}`},
+		{http.MethodGet, "/42", http.StatusNotFound, ""},
+	} {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest(tc.method, tc.path, nil))
+		assert.Equal(t, tc.code, w.Code, "%s %s", tc.method, tc.path)
+		if tc.body != "" {
+			assert.JSONEq(t, tc.body, w.Body.String())
+		}
+	}
+	assert.Equal(t, 1, created)
+}
+
+func TestService_Defaults(t *testing.T) {
+	var mapped error
+	s := NewService(newTestHub(), "/items").
+		WithErrorMapper(func(w http.ResponseWriter, err error) {
+			mapped = err
+			w.WriteHeader(http.StatusTeapot)
+		}).
+		WithAuthPolicy(func(r *http.Request) error {
+			if r.Header.Get("Authorization") == "" {
+				return errors.New("unauthenticated")
+			}
+			return nil
+		})
+	Handle(s, "GET /{id}", getServiceItem).WithInputMapper(BindRequest[serviceInput])
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/items/1", nil))
+	assert.Equal(t, http.StatusTeapot, w.Code)
+	assert.ErrorContains(t, mapped, "unauthenticated")
+
+	r := httptest.NewRequest(http.MethodGet, "/items/1", nil)
+	r.Header.Set("Authorization", "Bearer x")
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestService_LateDefaultsPanic(t *testing.T) {
+	s := NewService(newTestHub(), "/items")
+	Handle(s, "GET /{id}", getServiceItem).WithInputMapper(BindRequest[serviceInput])
+
+	assert.Panics(t, func() { s.WithErrorMapper(func(http.ResponseWriter, error) {}) })
+	assert.Panics(t, func() { s.WithTags("late") })
+}
+
+func TestService_Routes(t *testing.T) {
+	s := NewService(newTestHub(), "/items").WithTags("items")
+	Handle(s, "GET /{id}", getServiceItem).WithTags("read")
+	Handle(s, "/{$}", func(ctx *operator.OpContext[*nopTx], in *struct{}) (*struct{}, error) { return in, nil })
+
+	routes := s.Routes()
+	if assert.Len(t, routes, 2) {
+		assert.Equal(t, "GET", routes[0].Method)
+		assert.Equal(t, "/items/{id}", routes[0].Path)
+		assert.Contains(t, routes[0].Operation, "getServiceItem")
+		assert.Equal(t, reflect.TypeFor[serviceInput](), routes[0].Input)
+		assert.Equal(t, reflect.TypeFor[serviceOutput](), routes[0].Output)
+		assert.Equal(t, []string{"items", "read"}, routes[0].Tags)
+
+		assert.Equal(t, "", routes[1].Method)
+		assert.Equal(t, "/items/{$}", routes[1].Path)
+		assert.Equal(t, []string{"items"}, routes[1].Tags)
+	}
+}
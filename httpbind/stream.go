@@ -0,0 +1,95 @@
+package httpbind
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+)
+
+// Streamer is implemented by operation outputs that are written to the
+// response as a raw byte stream rather than encoded, for example CSV exports
+// or generated PDFs.
+//
+// Stream returns the response body, its content type, and its length in
+// bytes, or -1 if the length is unknown. If body implements io.Closer it is
+// closed once the response has been written.
+type Streamer interface {
+	Stream() (body io.Reader, contentType string, length int64)
+}
+
+// FileStreamer is a Streamer that should be downloaded by the client as a
+// file with the given name.
+type FileStreamer interface {
+	Streamer
+	Filename() string
+}
+
+// WriteStream writes an output implementing Streamer to w. The status code and
+// headers may be customised by implementing StatusCoder or HeaderSetter.
+//
+// Usage: inv.WithOutputMapper(httpbind.WriteStream[ExportOutput])
+func WriteStream[T any, PT interface {
+	*T
+	Streamer
+}](w http.ResponseWriter, val *T) {
+	if val == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	writeStream(w, PT(val), "")
+}
+
+// WriteFile writes an output implementing FileStreamer to w, with a
+// Content-Disposition header instructing the client to save the response as
+// an attachment.
+func WriteFile[T any, PT interface {
+	*T
+	FileStreamer
+}](w http.ResponseWriter, val *T) {
+	if val == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	writeStream(w, PT(val), PT(val).Filename())
+}
+
+// WriteBytes returns an output mapper that writes a byte slice output
+// verbatim with the given content type.
+func WriteBytes(contentType string) func(w http.ResponseWriter, val *[]byte) {
+	return func(w http.ResponseWriter, val *[]byte) {
+		if val == nil {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Length", strconv.Itoa(len(*val)))
+		w.WriteHeader(http.StatusOK)
+		w.Write(*val)
+	}
+}
+
+func writeStream(w http.ResponseWriter, s Streamer, filename string) {
+	body, contentType, length := s.Stream()
+	if c, ok := body.(io.Closer); ok {
+		defer c.Close()
+	}
+
+	h := w.Header()
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	h.Set("Content-Type", contentType)
+	if length >= 0 {
+		h.Set("Content-Length", strconv.FormatInt(length, 10))
+	}
+	if filename != "" {
+		h.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	}
+
+	status, _ := PrepareResponse(h, s, http.StatusOK)
+	w.WriteHeader(status)
+	if body != nil {
+		io.Copy(w, body)
+	}
+}
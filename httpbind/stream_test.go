@@ -0,0 +1,50 @@
+package httpbind
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type csvExport struct {
+	data string
+}
+
+func (e *csvExport) Stream() (io.Reader, string, int64) {
+	return strings.NewReader(e.data), "text/csv", int64(len(e.data))
+}
+
+func (e *csvExport) Filename() string { return "report 1.csv" }
+
+func TestWriteStream(t *testing.T) {
+	mapper := WriteStream[csvExport]
+	w := httptest.NewRecorder()
+	mapper(w, &csvExport{data: "a,b\n"})
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/csv", w.Header().Get("Content-Type"))
+	assert.Equal(t, "4", w.Header().Get("Content-Length"))
+	assert.Empty(t, w.Header().Get("Content-Disposition"))
+	assert.Equal(t, "a,b\n", w.Body.String())
+}
+
+func TestWriteFile(t *testing.T) {
+	w := httptest.NewRecorder()
+	WriteFile[csvExport](w, &csvExport{data: "a,b\n"})
+
+	assert.Equal(t, `attachment; filename="report 1.csv"`, w.Header().Get("Content-Disposition"))
+	assert.Equal(t, "a,b\n", w.Body.String())
+}
+
+func TestWriteBytes(t *testing.T) {
+	w := httptest.NewRecorder()
+	data := []byte("%PDF-1.7")
+	WriteBytes("application/pdf")(w, &data)
+
+	assert.Equal(t, "application/pdf", w.Header().Get("Content-Type"))
+	assert.Equal(t, "%PDF-1.7", w.Body.String())
+}
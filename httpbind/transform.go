@@ -1,14 +1,15 @@
 package httpbind
 
 import (
-	"encoding/json"
 	"net/http"
+
+	"github.com/jaz303/operator/codec"
 )
 
 // ParseJSON parses r's Body into a *P
 func ParseJSON[P any](r *http.Request) (*P, error) {
 	var out P
-	if err := json.NewDecoder(r.Body).Decode(&out); err != nil {
+	if err := codec.JSON.Decode(r.Body, &out); err != nil {
 		return nil, err
 	}
 	return &out, nil
@@ -26,10 +27,10 @@ func IndirectJSONInput[P any, I any](t func(*P) (*I, error)) func(r *http.Reques
 	}
 }
 
-// WriteJSON writes a *T to w as JSON
+// WriteJSON writes a *T to w as JSON. Nil and *operator.Empty values are
+// written as 204 No Content.
 func WriteJSON[T any](w http.ResponseWriter, val *T) {
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(val)
+	EncodeWith[T](codec.JSON)(w, val)
 }
 
 // Transform returns a function that reads input from an HTTP request as an *I
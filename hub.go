@@ -2,7 +2,12 @@ package operator
 
 import (
 	"context"
+	"fmt"
+	"log/slog"
 	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // A Hub is the central object through which operations are invoked, comprising
@@ -12,15 +17,46 @@ import (
 // operations.
 type Hub[Tx Transaction] struct {
 	beginTransaction TransactionProvider[Tx]
-	eventHandlers    map[reflect.Type][]eventHandler[Tx]
+	events           eventRegistry[Tx]
+	operations       operationRegistry
+	providers        map[reflect.Type]func(*OpContext[Tx]) (any, error)
+	upcasters        map[upcasterKey]Upcaster
+	attributes       map[any]any
+	recorder         atomic.Pointer[EventRecorder]
+	locks            LockProvider[Tx]
+	admission        *admissionQueue
+
+	opts    hubOptions
+	workers *workerPool
+	frozen  atomic.Bool
+	life    lifecycle
+	flights sync.Map // *flight -> struct{}
+	stats   sync.Map // operation name -> *opStats
+	subs    subscriptions
 }
 
-// NewHub() returns a hub configured with a transaction provider.
-func NewHub[Tx Transaction](transactionProvider TransactionProvider[Tx]) *Hub[Tx] {
-	return &Hub[Tx]{
+// NewHub() returns a hub configured with a transaction provider and any
+// number of options.
+func NewHub[Tx Transaction](transactionProvider TransactionProvider[Tx], opts ...HubOption) *Hub[Tx] {
+	o := defaultHubOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+	o.finalize()
+
+	h := &Hub[Tx]{
 		beginTransaction: transactionProvider,
-		eventHandlers:    map[reflect.Type][]eventHandler[Tx]{},
+		providers:        map[reflect.Type]func(*OpContext[Tx]) (any, error){},
+		upcasters:        map[upcasterKey]Upcaster{},
+		attributes:       map[any]any{},
+		opts:             o,
+		workers:          newWorkerPool(o.workers),
+		life:             lifecycle{idle: make(chan struct{}, 1)},
 	}
+	if o.admission != nil {
+		h.admission = newAdmissionQueue(o.admission)
+	}
+	return h
 }
 
 // RegisterEventHandler() registers a handler to handle events whose
@@ -41,26 +77,264 @@ func NewHub[Tx Transaction](transactionProvider TransactionProvider[Tx]) *Hub[Tx
 // error, the transaction aborts and is rolled back - this is by design;
 // event handlers are not intended for "fire and forget" use - use AfterFunc()
 // for that.
-func (h *Hub[Tx]) RegisterEventHandler(event Event, hnd any) {
+//
+// By default, handlers for an event type are dispatched in registration
+// order. Use the HandlerName(), Priority(), After() and Before() options to
+// make ordering explicit, e.g.:
+//
+//	hub.RegisterEventHandler(&UserCreated{}, sendWelcome, operator.After("audit"))
+//
+// Handlers are invoked with reflection unless a dispatcher has been
+// registered for their type with RegisterDispatcher(); "operator-gen
+// handlers" generates dispatchers for a program's handlers.
+//
+// A handler registered under a name already registered for the event type -
+// the same function, or another handler given the same name with
+// HandlerName() - is treated according to the hub's DuplicateHandlerPolicy;
+// see WithDuplicateHandlers().
+//
+// Returns ErrHubFrozen if the hub has been frozen, ErrHandlerCycle if the
+// handler's ordering constraints conflict with those of the handlers already
+// registered for the event type, or ErrDuplicateHandler if the handler is a
+// duplicate that the hub rejects; in each case the handler is not
+// registered.
+func (h *Hub[Tx]) RegisterEventHandler(event Event, hnd any, opts ...HandlerOption) error {
+	_, err := h.attachEventHandler(callSite(0), event, hnd, opts)
+	return err
+}
+
+// AttachEventHandler() is like RegisterEventHandler(), but returns a
+// *Registration through which the handler can later be removed.
+func (h *Hub[Tx]) AttachEventHandler(event Event, hnd any, opts ...HandlerOption) (*Registration, error) {
+	return h.attachEventHandler(callSite(0), event, hnd, opts)
+}
+
+func (h *Hub[Tx]) attachEventHandler(site string, event Event, hnd any, opts []HandlerOption) (*Registration, error) {
+	if h.frozen.Load() {
+		return nil, ErrHubFrozen
+	}
 	ty := reflect.TypeOf(event)
-	h.eventHandlers[ty] = append(h.eventHandlers[ty], makeEventHandler[Tx](ty, hnd))
+	eh := makeEventHandler[Tx](ty, hnd)
+	if _, static := eh.(*staticEventHandler[Tx]); h.opts.staticDispatch && !static {
+		panic(fmt.Errorf("no dispatcher registered for event handler %s of type %T", eh.Name(), hnd))
+	}
+	return h.attach(site, eventKey{ty: ty}, event.EventName(), hnd, eh, opts)
+}
+
+// RegisterNamedEventHandler() registers a handler to handle events whose
+// EventName() is name, regardless of their Go type. This allows handlers to
+// be registered for events whose concrete type is not known at the
+// registration site, such as those emitted with OpContext.EmitNamed().
+//
+// hnd takes the same forms as for RegisterEventHandler(), with any event
+// parameter type E. Events assignable to E are passed as-is; otherwise, the
+// Payload of a *NamedEvent is passed. If neither is assignable to E, the
+// handler fails.
+//
+// Handlers registered by name are dispatched after those registered for the
+// event's type; ordering options apply among handlers registered for the
+// same name.
+func (h *Hub[Tx]) RegisterNamedEventHandler(name string, hnd any, opts ...HandlerOption) error {
+	_, err := h.attachNamedEventHandler(callSite(0), name, hnd, opts)
+	return err
+}
+
+// AttachNamedEventHandler() is like RegisterNamedEventHandler(), but returns
+// a *Registration through which the handler can later be removed.
+func (h *Hub[Tx]) AttachNamedEventHandler(name string, hnd any, opts ...HandlerOption) (*Registration, error) {
+	return h.attachNamedEventHandler(callSite(0), name, hnd, opts)
+}
+
+func (h *Hub[Tx]) attachNamedEventHandler(site string, name string, hnd any, opts []HandlerOption) (*Registration, error) {
+	if h.frozen.Load() {
+		return nil, ErrHubFrozen
+	}
+	return h.attach(site, eventKey{name: name}, name, hnd, makeNamedEventHandler[Tx](hnd), opts)
+}
+
+func (h *Hub[Tx]) attach(site string, key eventKey, name string, fn any, hnd eventHandler[Tx], opts []HandlerOption) (*Registration, error) {
+	reg := orderedHandler[Tx]{hnd: hnd, fn: reflect.ValueOf(fn).Pointer(), site: site}
+	_, reg.static = hnd.(*staticEventHandler[Tx])
+	_, reg.batch = hnd.(*batchEventHandler[Tx])
+	if p, ok := hnd.(*payloadEventHandler[Tx]); ok {
+		reg.param = p.evtParameterType
+	}
+	for _, opt := range opts {
+		opt(&reg.handlerRegistration)
+	}
+	if reg.name == "" {
+		reg.name = reg.hnd.Name()
+		reg.anonymous = funcLiteralName.MatchString(reg.name)
+	} else {
+		reg.hnd = &namedEventHandler[Tx]{eventHandler: reg.hnd, name: reg.name}
+	}
+	if reg.phase != BeforeCommit {
+		if reg.batch {
+			panic(fmt.Errorf("batch event handler %s must be dispatched %s", reg.name, BeforeCommit))
+		}
+		reg.hnd = &phasedEventHandler[Tx]{eventHandler: reg.hnd, phase: reg.phase}
+	}
+	if len(reg.ops) > 0 || len(reg.exceptOps) > 0 {
+		reg.hnd = &filteredEventHandler[Tx]{eventHandler: reg.hnd, ops: reg.ops, exceptOps: reg.exceptOps}
+	}
+	reg.registration = &Registration{}
+	reg.registration.remove = func() error {
+		if h.frozen.Load() {
+			return ErrHubFrozen
+		}
+		h.events.remove(key, reg.registration)
+		return nil
+	}
+	dup := func(prev *orderedHandler[Tx]) error { return h.checkDuplicate(describeEvent(key, name), prev, &reg) }
+	if err := h.events.add(key, name, reg, dup); err != nil {
+		return nil, err
+	}
+	return reg.registration, nil
+}
+
+// Freeze() ends the hub's registration phase. Once frozen, attempts to
+// register or remove event handlers, or to register operations, policies or
+// providers, fail with ErrHubFrozen, guaranteeing that the hub's registries
+// are no longer mutated and may safely be read by concurrent operations
+// without locking.
+//
+// Call Freeze() once the application has finished configuring the hub, before
+// serving requests. Freezing is irreversible; subsequent calls have no effect.
+func (h *Hub[Tx]) Freeze() {
+	h.frozen.Store(true)
+}
+
+// Frozen() returns true if the hub has been frozen.
+func (h *Hub[Tx]) Frozen() bool {
+	return h.frozen.Load()
+}
+
+// SetAttribute() associates value with key on the hub. Attributes hold
+// hub-wide configuration for integrations, such as the default error mapper
+// of a binding package, so that it need only be configured once. As with
+// context values, key should be of an unexported type defined by the package
+// that owns the attribute.
+//
+// Returns ErrHubFrozen if the hub has been frozen.
+func (h *Hub[Tx]) SetAttribute(key any, value any) error {
+	if h.frozen.Load() {
+		return ErrHubFrozen
+	}
+	h.attributes[key] = value
+	return nil
+}
+
+// Attribute() returns the value associated with key by SetAttribute(), if
+// any.
+func (h *Hub[Tx]) Attribute(key any) (any, bool) {
+	v, ok := h.attributes[key]
+	return v, ok
+}
+
+// ContextPolicy() returns the policy with which bindings derive operation
+// contexts from request contexts; see WithContextPolicy().
+func (h *Hub[Tx]) ContextPolicy() ContextPolicy {
+	return h.opts.contextPolicy
+}
+
+// Logger() returns the hub's logger; see WithLogger(). Bindings use it to
+// report failures that cannot be returned to the client.
+func (h *Hub[Tx]) Logger() *slog.Logger {
+	return h.opts.logger
+}
+
+// Ping() begins a transaction with the hub's transaction provider and
+// immediately rolls it back, returning any error. No operation is invoked, so
+// middleware, tracing and metrics are not involved; it is intended for health
+// checks. Providers that depend on the context, such as PerTenant(), see ctx.
+func (h *Hub[Tx]) Ping(ctx context.Context) error {
+	tx, err := h.beginTransaction(ctx)
+	if err != nil {
+		return fmt.Errorf("begin transaction failed (%w)", err)
+	}
+	return tx.Rollback(ctx)
 }
 
 // Begin a new operation and returns its context.
 // User code will usually not call BeginOperation directly; use Invoke().
 func (h *Hub[Tx]) BeginOperation(ctx context.Context) *OpContext[Tx] {
+	return h.beginOperation(ctx, "")
+}
+
+func (h *Hub[Tx]) beginOperation(ctx context.Context, name string) *OpContext[Tx] {
 	return &OpContext[Tx]{
 		Context: ctx,
 
 		hub:              h,
 		beginTransaction: h.beginTransaction,
+
+		id:   newOperationID(),
+		name: name,
+		now:  h.opts.clock.Now(),
 	}
 }
 
-func (h *Hub[Tx]) dispatchEvent(op *OpContext[Tx], evt Event) error {
-	for _, hnd := range h.eventHandlers[reflect.TypeOf(evt)] {
-		if err := hnd.Dispatch(op, evt); err != nil {
-			return err
+func (h *Hub[Tx]) runBackground(ctx context.Context, fu *followUp, attempt int) {
+	h.workers.submit(ctx, func() {
+		err := fu.run(context.WithValue(ctx, workerKey{}, true))
+		if err == nil {
+			h.exit()
+			return
+		} else if attempt < h.opts.retryAttempts {
+			delay := retryDelay(h.opts.retryBackoff, attempt)
+			time.AfterFunc(delay, func() { h.runBackground(ctx, fu, attempt+1) })
+			return
+		}
+		h.opts.onBackgroundError(err)
+		h.deadLetter(ctx, fu, attempt, err)
+		h.exit()
+	})
+}
+
+// maxRetryBackoff caps the delay between attempts at background work; see
+// WithBackgroundRetry().
+const maxRetryBackoff = time.Minute
+
+// retryDelay returns the delay before retrying background work that has
+// failed attempt times: backoff, doubled after each failed attempt, up to
+// maxRetryBackoff or backoff, whichever is greater.
+func retryDelay(backoff time.Duration, attempt int) time.Duration {
+	delay := backoff
+	for i := 1; i < attempt && delay < maxRetryBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, max(backoff, maxRetryBackoff))
+}
+
+func (h *Hub[Tx]) dispatchEvent(op *OpContext[Tx], evt Event, depth int) error {
+	byType, byName := h.events.handlers(evt)
+
+	rec := h.recorder.Load()
+	if rec == nil {
+		for _, hnds := range [2][]eventHandler[Tx]{byType, byName} {
+			for _, hnd := range hnds {
+				if err := hnd.Dispatch(op, evt); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	var results []HandlerResult
+	defer func() { rec.record(&op.recordSeq, depth, evt, results) }()
+
+	for _, hnds := range [2][]eventHandler[Tx]{byType, byName} {
+		for _, hnd := range hnds {
+			err := hnd.Dispatch(op, evt)
+			res := HandlerResult{Handler: hnd.Name()}
+			if err != nil {
+				res.Error = err.Error()
+			}
+			results = append(results, res)
+			if err != nil {
+				return err
+			}
 		}
 	}
 	return nil
@@ -0,0 +1,288 @@
+package operator
+
+import (
+	"context"
+	"log/slog"
+	"reflect"
+	"runtime"
+	"time"
+
+	"github.com/jaz303/operator/deadletter"
+)
+
+// HubOption configures a Hub; pass options to NewHub().
+type HubOption func(o *hubOptions)
+
+type hubOptions struct {
+	logger            *slog.Logger
+	tracer            Tracer
+	metrics           Metrics
+	middleware        []Middleware
+	clock             Clock
+	ids               IDGenerator
+	contextPolicy     ContextPolicy
+	workers           int
+	retryAttempts     int
+	retryBackoff      time.Duration
+	deadLetters       deadletter.Sink
+	txWarnThreshold   time.Duration
+	trackInFlight     bool
+	staticDispatch    bool
+	duplicateHandlers DuplicateHandlerPolicy
+	cascade           CascadeLimits
+	dedup             map[reflect.Type]bool
+	txObservers       []TransactionObserver
+	checkpoints       CheckpointStore
+	admission         *AdmissionControl
+	trackStats        bool
+	statsWindow       int
+	slowThreshold     time.Duration
+	onBackgroundError func(error)
+	onAfterFuncError  func(error)
+}
+
+func defaultHubOptions() hubOptions {
+	return hubOptions{
+		logger:        slog.Default(),
+		clock:         systemClock{},
+		ids:           UUIDv7(),
+		contextPolicy: Background(),
+		workers:       runtime.GOMAXPROCS(0),
+		retryAttempts: 1,
+		cascade:       CascadeLimits{MaxDepth: 32, MaxEvents: 10000},
+	}
+}
+
+// Middleware wraps the invocation of every operation on a hub. info
+// identifies the operation; next runs the operation, including the commit or
+// rollback of its transaction, and returns its error. Middleware may derive a
+// new context from ctx and pass it to next, in which case it becomes the
+// operation's context.
+type Middleware func(ctx context.Context, info OperationInfo, next func(ctx context.Context) error) error
+
+// Tracer starts a span for each operation invoked on a hub. The returned
+// function is called with the operation's error when it completes.
+type Tracer interface {
+	StartOperation(ctx context.Context, info OperationInfo) (context.Context, func(err error))
+}
+
+// Metrics receives a measurement for each operation invoked on a hub.
+type Metrics interface {
+	ObserveOperation(info OperationInfo, duration time.Duration, err error)
+}
+
+// TxStats describes the transaction of a single operation.
+type TxStats struct {
+	// Time from the transaction beginning until it was committed or rolled
+	// back, including the commit or rollback itself
+	Held time.Duration
+
+	// Time taken to commit; zero if the transaction was rolled back
+	Commit time.Duration
+
+	// True if the transaction committed successfully
+	Committed bool
+}
+
+// TxMetrics may be implemented by a Metrics to additionally receive
+// statistics for each operation that began a transaction. Transactions are
+// begun lazily, so operations that never call OpContext.Tx() are not
+// reported.
+type TxMetrics interface {
+	ObserveTransaction(info OperationInfo, stats TxStats)
+}
+
+// Clock is the hub's source of the current time.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+// WithLogger sets the logger used by the hub's default error handlers. The
+// default is slog.Default().
+func WithLogger(l *slog.Logger) HubOption {
+	return func(o *hubOptions) { o.logger = l }
+}
+
+// WithTracer sets the tracer used to create a span for each operation.
+func WithTracer(t Tracer) HubOption {
+	return func(o *hubOptions) { o.tracer = t }
+}
+
+// WithMetrics sets the recipient of per-operation measurements.
+func WithMetrics(m Metrics) HubOption {
+	return func(o *hubOptions) { o.metrics = m }
+}
+
+// WithMiddleware appends middleware to be run around every operation. The
+// first middleware is outermost; tracing and metrics, if configured, wrap all
+// middleware.
+func WithMiddleware(mw ...Middleware) HubOption {
+	return func(o *hubOptions) { o.middleware = append(o.middleware, mw...) }
+}
+
+// WithTxWarnThreshold logs a warning, via the hub's logger, for each
+// operation that holds its transaction for longer than d. Long-held
+// transactions are a common source of lock contention.
+func WithTxWarnThreshold(d time.Duration) HubOption {
+	return func(o *hubOptions) { o.txWarnThreshold = d }
+}
+
+// WithContextPolicy sets the policy with which bindings derive each
+// operation's context from the request's context, unless configured
+// otherwise per binding. The default is Background(); Detach() propagates
+// request-scoped values, such as trace IDs, without cancellation.
+func WithContextPolicy(p ContextPolicy) HubOption {
+	return func(o *hubOptions) { o.contextPolicy = p }
+}
+
+// WithClock sets the hub's clock, which determines OpContext.Now() and is
+// used for timing. The default is the system clock.
+func WithClock(c Clock) HubOption {
+	return func(o *hubOptions) { o.clock = c }
+}
+
+// WithWorkers sets the number of goroutines used to execute background work
+// such as follow-up operations. The default is runtime.GOMAXPROCS(0).
+func WithWorkers(n int) HubOption {
+	return func(o *hubOptions) { o.workers = n }
+}
+
+// WithBackgroundRetry makes up to attempts attempts at background work -
+// events emitted with EmitAfterCommit(), and follow-up operations scheduled
+// with InvokeAfterCommit() - before reporting it as failed. The delay before
+// each retry starts at backoff and doubles after each failed attempt, up to
+// one minute. By default, background work is attempted once.
+func WithBackgroundRetry(attempts int, backoff time.Duration) HubOption {
+	return func(o *hubOptions) {
+		o.retryAttempts = max(attempts, 1)
+		o.retryBackoff = backoff
+	}
+}
+
+// WithDeadLetterSink sets a sink to receive background work that has failed
+// on every attempt. Letters produced by the hub have the source
+// DeadLetterSource and can be requeued with Hub.RequeueDeadLetter().
+func WithDeadLetterSink(sink deadletter.Sink) HubOption {
+	return func(o *hubOptions) { o.deadLetters = sink }
+}
+
+// WithStaticDispatch requires every handler registered with
+// Hub.RegisterEventHandler() to have a dispatcher, registered with
+// RegisterDispatcher(), so that a handler missed by "operator-gen handlers"
+// is reported at registration rather than silently dispatched with
+// reflection. Registering such a handler panics. Handlers registered by name
+// are unaffected.
+func WithStaticDispatch() HubOption {
+	return func(o *hubOptions) { o.staticDispatch = true }
+}
+
+// WithBackgroundErrorHandler sets a function to be called when work executed
+// by the hub's worker pool - such as follow-up operations scheduled with
+// InvokeAfterCommit() - fails. By default, errors are logged.
+func WithBackgroundErrorHandler(fn func(error)) HubOption {
+	return func(o *hubOptions) { o.onBackgroundError = fn }
+}
+
+// WithAfterFuncErrorHandler sets a function to be called when an AfterFunc
+// panics. The operation has already committed so the panic cannot affect its
+// outcome; it is recovered and reported as an error wrapping ErrRecovered. By
+// default, errors are logged.
+func WithAfterFuncErrorHandler(fn func(error)) HubOption {
+	return func(o *hubOptions) { o.onAfterFuncError = fn }
+}
+
+func (o *hubOptions) finalize() {
+	if o.onBackgroundError == nil {
+		logger := o.logger
+		o.onBackgroundError = func(err error) {
+			logger.Error("operator: background operation failed", "error", err)
+		}
+	}
+	if o.onAfterFuncError == nil {
+		logger := o.logger
+		o.onAfterFuncError = func(err error) {
+			logger.Error("operator: after func failed", "error", err)
+		}
+	}
+}
+
+// intercepts returns true if operations must be run via Hub.intercept().
+func (o *hubOptions) intercepts() bool {
+	return len(o.middleware) > 0 || o.tracer != nil || o.times()
+}
+
+// times returns true if the duration of operations must be measured.
+func (o *hubOptions) times() bool {
+	return o.metrics != nil || o.trackStats || o.slowThreshold > 0
+}
+
+// observesTx returns true if transaction statistics must be collected.
+func (o *hubOptions) observesTx() bool {
+	_, ok := o.metrics.(TxMetrics)
+	return ok || o.txWarnThreshold > 0 || len(o.txObservers) > 0
+}
+
+// observeTx reports the statistics of op's transaction, which was committed,
+// or rolled back, with error err.
+func (h *Hub[Tx]) observeTx(op *OpContext[Tx], commit bool, stats TxStats, err error) {
+	for _, obs := range h.opts.txObservers {
+		if commit {
+			obs.OnCommit(op, op, stats, err)
+		} else {
+			obs.OnRollback(op, op, stats, err)
+		}
+	}
+	if m, ok := h.opts.metrics.(TxMetrics); ok {
+		m.ObserveTransaction(op, stats)
+	}
+	if t := h.opts.txWarnThreshold; t > 0 && stats.Held > t {
+		h.opts.logger.Warn("operator: transaction held longer than threshold",
+			"operation", op.Name(), "operation_id", op.ID(),
+			"held", stats.Held, "commit", stats.Commit, "committed", stats.Committed)
+	}
+}
+
+// intercept runs fn, which invokes the operation represented by op, wrapped
+// in the hub's middleware, tracing and metrics.
+func (h *Hub[Tx]) intercept(op *OpContext[Tx], fn func() error) error {
+	o := &h.opts
+	if !o.intercepts() {
+		return fn()
+	}
+
+	call := func(ctx context.Context) error {
+		op.Context = ctx
+		return fn()
+	}
+	for i := len(o.middleware) - 1; i >= 0; i-- {
+		mw, next := o.middleware[i], call
+		call = func(ctx context.Context) error { return mw(ctx, op, next) }
+	}
+
+	if o.tracer != nil {
+		next := call
+		call = func(ctx context.Context) error {
+			ctx, end := o.tracer.StartOperation(ctx, op)
+			err := next(ctx)
+			end(err)
+			return err
+		}
+	}
+
+	if !o.times() {
+		return call(op.Context)
+	}
+
+	start := o.clock.Now()
+	err := call(op.Context)
+	end := o.clock.Now()
+	if o.metrics != nil {
+		o.metrics.ObserveOperation(op, end.Sub(start), err)
+	}
+	h.observeStats(op, end, end.Sub(start), err)
+	return err
+}
@@ -0,0 +1,336 @@
+package operator
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func auditTestEvent(evt *testEvent) {}
+
+func TestEventTopology(t *testing.T) {
+	hub := newTestHub()
+	hub.RegisterEventHandler(&testEvent{}, auditTestEvent)
+	hub.RegisterEventHandler(&testEvent{}, func(evt *testEvent) {}, Destination("queue:tests"))
+	hub.RegisterEventHandler(&followUpEvent{}, func(evt *followUpEvent) {})
+
+	topo := hub.EventTopology()
+	assert.Equal(t, 2, len(topo))
+
+	assert.Equal(t, "followUpEvent", topo[0].Name)
+	assert.Equal(t, "*operator.followUpEvent", topo[0].Type)
+	assert.Equal(t, 1, len(topo[0].Handlers))
+
+	assert.Equal(t, "testEvent", topo[1].Name)
+	assert.Equal(t, 2, len(topo[1].Handlers))
+	assert.Equal(t, "github.com/jaz303/operator.auditTestEvent", topo[1].Handlers[0].Name)
+	assert.Equal(t, "github.com/jaz303/operator.TestEventTopology.func1", topo[1].Handlers[1].Name)
+	assert.Empty(t, topo[1].Handlers[0].Destination)
+	assert.Equal(t, "queue:tests", topo[1].Handlers[1].Destination)
+}
+
+func TestEventHandlers(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	hub := newTestHub()
+	_, file, line, _ := runtime.Caller(0)
+	all := hub.Subscribe(ctx, nil)
+	hub.RegisterNamedEventHandler("testEvent", func(evt *NamedEvent) {}, HandlerName("relay"))
+	hub.Subscribe(ctx, &testEvent{})
+	hub.RegisterEventHandler(&testEvent{}, auditTestEvent, Priority(-1), ForOps("users.*"))
+	hub.RegisterEventHandler(&testEvent{}, func(evt *testEvent) {}, HandlerName("first"), Before("github.com/jaz303/operator.auditTestEvent"))
+	hub.RegisterEventHandler(&dispatchEvent{}, dispatchHandler)
+	assert.NotNil(t, all)
+
+	site := func(n int) string { return fmt.Sprintf("%s:%d", file, line+n) }
+	assert.Equal(t, []EventHandlerInfo{
+		{Event: "dispatchEvent", Type: "*operator.dispatchEvent", HandlerInfo: HandlerInfo{
+			Name: "github.com/jaz303/operator.dispatchHandler", Site: site(6), Static: true}},
+		{Event: "testEvent", Type: "*operator.testEvent", HandlerInfo: HandlerInfo{
+			Name: "first", Site: site(5), Before: []string{"github.com/jaz303/operator.auditTestEvent"}}},
+		{Event: "testEvent", Type: "*operator.testEvent", HandlerInfo: HandlerInfo{
+			Name: "github.com/jaz303/operator.auditTestEvent", Site: site(4), Priority: -1, Ops: []string{"users.*"}}},
+		{Event: "testEvent", HandlerInfo: HandlerInfo{Name: "relay", Site: site(2)}},
+		{Event: "testEvent", Type: "*operator.testEvent", HandlerInfo: HandlerInfo{Site: site(3), Async: true}},
+		{HandlerInfo: HandlerInfo{Site: site(1), Async: true}},
+	}, hub.EventHandlers())
+}
+
+type testTracer struct {
+	spans []string
+}
+
+func (t *testTracer) StartOperation(ctx context.Context, info OperationInfo) (context.Context, func(error)) {
+	t.spans = append(t.spans, "start "+info.Name())
+	return context.WithValue(ctx, ctxKey{}, "traced"), func(err error) {
+		t.spans = append(t.spans, fmt.Sprintf("end %v", err))
+	}
+}
+
+type testMetrics struct {
+	observed []string
+}
+
+func (m *testMetrics) ObserveOperation(info OperationInfo, d time.Duration, err error) {
+	m.observed = append(m.observed, fmt.Sprintf("%s %v", info.Name(), err))
+}
+
+func TestHubOptions_MiddlewareTracingMetrics(t *testing.T) {
+	var calls []string
+	mw := func(label string) Middleware {
+		return func(ctx context.Context, info OperationInfo, next func(context.Context) error) error {
+			calls = append(calls, label+" before")
+			err := next(ctx)
+			calls = append(calls, label+" after")
+			return err
+		}
+	}
+
+	tracer := &testTracer{}
+	metrics := &testMetrics{}
+	hub := newTestHub(WithMiddleware(mw("a"), mw("b")), WithTracer(tracer), WithMetrics(metrics))
+
+	_, err := Invoke(context.Background(), hub, namedTestOperation, &struct{}{})
+	assert.Nil(t, err)
+
+	_, err = Invoke(context.Background(), hub, func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) {
+		calls = append(calls, "op")
+		assert.Equal(t, "traced", ctx.Value(ctxKey{}))
+		return nil, assert.AnError
+	}, &struct{}{})
+	assert.ErrorIs(t, err, assert.AnError)
+
+	assert.Equal(t, []string{"a before", "b before", "b after", "a after"}, calls[:4])
+	assert.Equal(t, []string{"a before", "b before", "op", "b after", "a after"}, calls[4:])
+	assert.Equal(t, "start operator.namedTestOperation", tracer.spans[0])
+	assert.Equal(t, "end <nil>", tracer.spans[1])
+	assert.Equal(t, fmt.Sprintf("end %v", assert.AnError), tracer.spans[3])
+	assert.Equal(t, "operator.namedTestOperation <nil>", metrics.observed[0])
+}
+
+func TestHubOptions_AfterFuncErrorHandler(t *testing.T) {
+	var reported error
+	hub := newTestHub(WithAfterFuncErrorHandler(func(err error) { reported = err }))
+
+	_, err := Invoke(context.Background(), hub, func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) {
+		ctx.AfterFunc(func(*OpContext[*TxTest]) { panic("boom") })
+		return in, nil
+	}, &struct{}{})
+
+	assert.Nil(t, err)
+	assert.ErrorIs(t, reported, ErrRecovered)
+}
+
+func TestFreeze(t *testing.T) {
+	hub := newTestHub()
+	assert.Nil(t, hub.RegisterEventHandler(&testEvent{}, auditTestEvent))
+
+	hub.Freeze()
+	assert.True(t, hub.Frozen())
+
+	assert.ErrorIs(t, hub.RegisterEventHandler(&testEvent{}, auditTestEvent), ErrHubFrozen)
+	assert.ErrorIs(t, RegisterOperation(hub, "named", namedTestOperation), ErrHubFrozen)
+	assert.ErrorIs(t, hub.RequirePermission("named", "admin"), ErrHubFrozen)
+	assert.ErrorIs(t, RegisterProvider(hub, func(*OpContext[*TxTest]) (*testRepo, error) { return nil, nil }), ErrHubFrozen)
+
+	_, err := Invoke(context.Background(), hub, namedTestOperation, &struct{}{})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(hub.EventTopology()[0].Handlers))
+}
+
+func TestRegisterEventHandler_ConcurrentWithDispatch(t *testing.T) {
+	hub := newTestHub()
+	hub.RegisterEventHandler(&testEvent{}, func(evt *testEvent) {})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range 100 {
+			hub.RegisterEventHandler(&followUpEvent{}, func(evt *followUpEvent) {})
+		}
+	}()
+
+	for range 100 {
+		_, err := Invoke(context.Background(), hub, benchEmitOperation, &struct{}{})
+		assert.Nil(t, err)
+	}
+	<-done
+
+	assert.Equal(t, 100, len(hub.EventTopology()[0].Handlers))
+}
+
+func TestRegisterOperation_ConcurrentWithInvoke(t *testing.T) {
+	hub := newTestHub()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := range 100 {
+			op := func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) { return in, nil }
+			assert.NoError(t, RegisterOperation(hub, fmt.Sprintf("op.%d", i), WithTimeout(op, time.Duration(i+1))))
+			assert.NoError(t, hub.SetPolicy("operator.benchNoopOperation", func(context.Context, Principal) error { return nil }))
+		}
+	}()
+
+	for range 100 {
+		_, err := Invoke(context.Background(), hub, benchNoopOperation, &struct{}{})
+		assert.NoError(t, err)
+	}
+	<-done
+
+	assert.Len(t, hub.Operations(), 100)
+}
+
+type stepClock struct {
+	now time.Time
+}
+
+// Now advances the clock by 10ms on each call.
+func (c *stepClock) Now() time.Time {
+	c.now = c.now.Add(10 * time.Millisecond)
+	return c.now
+}
+
+type testTxMetrics struct {
+	testMetrics
+	tx []TxStats
+}
+
+func (m *testTxMetrics) ObserveTransaction(info OperationInfo, stats TxStats) {
+	m.tx = append(m.tx, stats)
+}
+
+func TestHubOptions_TxMetrics(t *testing.T) {
+	metrics := &testTxMetrics{}
+	var logs bytes.Buffer
+	hub := newTestHub(
+		WithMetrics(metrics),
+		WithClock(&stepClock{}),
+		WithTxWarnThreshold(15*time.Millisecond),
+		WithLogger(slog.New(slog.NewTextHandler(&logs, nil))),
+	)
+
+	withTx := func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) {
+		_, err := ctx.Tx()
+		return in, err
+	}
+	withoutTx := func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) {
+		return in, nil
+	}
+	failing := func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) {
+		ctx.Tx()
+		return nil, errors.New("failed")
+	}
+
+	Invoke(context.Background(), hub, withTx, &struct{}{})
+	Invoke(context.Background(), hub, withoutTx, &struct{}{})
+	Invoke(context.Background(), hub, failing, &struct{}{})
+
+	// the clock advances 10ms at begin, and before and after commit/rollback
+	assert.Equal(t, []TxStats{
+		{Held: 20 * time.Millisecond, Commit: 10 * time.Millisecond, Committed: true},
+		{Held: 20 * time.Millisecond},
+	}, metrics.tx)
+	assert.Equal(t, 2, strings.Count(logs.String(), "transaction held longer than threshold"))
+}
+
+type testTxObserver struct {
+	calls []string
+}
+
+func (o *testTxObserver) OnBegin(ctx context.Context, info OperationInfo, d time.Duration, err error) {
+	o.calls = append(o.calls, fmt.Sprintf("begin %s %v", d, err))
+}
+
+func (o *testTxObserver) OnCommit(ctx context.Context, info OperationInfo, stats TxStats, err error) {
+	o.calls = append(o.calls, fmt.Sprintf("commit %s %s %v", stats.Held, stats.Commit, err))
+}
+
+func (o *testTxObserver) OnRollback(ctx context.Context, info OperationInfo, stats TxStats, err error) {
+	o.calls = append(o.calls, fmt.Sprintf("rollback %s %v", stats.Held, err))
+}
+
+func TestHubOptions_TransactionObserver(t *testing.T) {
+	obs := &testTxObserver{}
+	fail := false
+	hub := NewHub(func(ctx context.Context) (*TxTest, error) {
+		if fail {
+			return nil, assert.AnError
+		}
+		return &TxTest{}, nil
+	}, WithTransactionObserver(obs), WithClock(&stepClock{}))
+
+	withTx := func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) {
+		_, err := ctx.Tx()
+		return in, err
+	}
+	Invoke(context.Background(), hub, withTx, &struct{}{})
+	Invoke(context.Background(), hub, func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) {
+		ctx.Tx()
+		return nil, errors.New("failed")
+	}, &struct{}{})
+	fail = true
+	Invoke(context.Background(), hub, withTx, &struct{}{})
+
+	// the clock advances 10ms before and after begin, at the start of the
+	// transaction, and before and after commit/rollback
+	assert.Equal(t, []string{
+		"begin 10ms <nil>",
+		"commit 20ms 10ms <nil>",
+		"begin 10ms <nil>",
+		"rollback 20ms <nil>",
+		"begin 10ms " + assert.AnError.Error(),
+	}, obs.calls)
+}
+
+func TestHubAttributes(t *testing.T) {
+	type attrKey struct{}
+	hub := newTestHub()
+
+	_, ok := hub.Attribute(attrKey{})
+	assert.False(t, ok)
+
+	assert.NoError(t, hub.SetAttribute(attrKey{}, "value"))
+	v, ok := hub.Attribute(attrKey{})
+	assert.True(t, ok)
+	assert.Equal(t, "value", v)
+
+	hub.Freeze()
+	assert.ErrorIs(t, hub.SetAttribute(attrKey{}, "other"), ErrHubFrozen)
+}
+
+func TestHubPing(t *testing.T) {
+	var tx *TxTest
+	hub := NewHub(func(ctx context.Context) (*TxTest, error) {
+		tx = &TxTest{}
+		return tx, nil
+	})
+	assert.NoError(t, hub.Ping(context.Background()))
+	assert.True(t, tx.RolledBack)
+	assert.False(t, tx.Committed)
+
+	failing := NewHub(func(ctx context.Context) (*TxTest, error) { return nil, errors.New("db down") })
+	assert.EqualError(t, failing.Ping(context.Background()), "begin transaction failed (db down)")
+}
+
+func TestRetryDelay(t *testing.T) {
+	assert.Equal(t, time.Second, retryDelay(time.Second, 1))
+	assert.Equal(t, 2*time.Second, retryDelay(time.Second, 2))
+	assert.Equal(t, 32*time.Second, retryDelay(time.Second, 6))
+	assert.Equal(t, maxRetryBackoff, retryDelay(time.Second, 7))
+	assert.Equal(t, maxRetryBackoff, retryDelay(time.Second, 100))
+	assert.Equal(t, time.Duration(0), retryDelay(0, 100))
+
+	// a backoff above the maximum is not doubled
+	assert.Equal(t, time.Hour, retryDelay(time.Hour, 1))
+	assert.Equal(t, time.Hour, retryDelay(time.Hour, 5))
+}
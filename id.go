@@ -0,0 +1,70 @@
+package operator
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// IDGenerator generates entity IDs for OpContext.NewID(). Implementations
+// must be safe for concurrent use.
+type IDGenerator interface {
+	NewID() string
+}
+
+// WithIDGenerator sets the hub's ID generator. The default is UUIDv7().
+func WithIDGenerator(g IDGenerator) HubOption {
+	return func(o *hubOptions) { o.ids = g }
+}
+
+// NewID() returns a new entity ID from the hub's IDGenerator.
+func (o *OpContext[T]) NewID() string { return o.hub.opts.ids.NewID() }
+
+// UUIDv7() returns an IDGenerator producing version 7 UUIDs (RFC 9562) in
+// their canonical string form. Version 7 UUIDs begin with a millisecond
+// timestamp, so IDs sort approximately by creation time and index well.
+func UUIDv7() IDGenerator { return uuidV7{} }
+
+type uuidV7 struct{}
+
+func (uuidV7) NewID() string {
+	var u [16]byte
+	var ms [8]byte
+	binary.BigEndian.PutUint64(ms[:], uint64(time.Now().UnixMilli()))
+	copy(u[:6], ms[2:])
+	rand.Read(u[6:])
+	u[6] = u[6]&0x0f | 0x70 // version 7
+	u[8] = u[8]&0x3f | 0x80 // variant 10
+
+	var buf [36]byte
+	hex.Encode(buf[0:8], u[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], u[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], u[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], u[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], u[10:])
+	return string(buf[:])
+}
+
+// SequentialIDs is an IDGenerator producing the IDs prefix1, prefix2, and so
+// on, for reproducible tests.
+type SequentialIDs struct {
+	prefix string
+	next   atomic.Uint64
+}
+
+// NewSequentialIDs() returns a SequentialIDs whose IDs begin with prefix.
+func NewSequentialIDs(prefix string) *SequentialIDs {
+	return &SequentialIDs{prefix: prefix}
+}
+
+// NewID implements IDGenerator.
+func (s *SequentialIDs) NewID() string {
+	return s.prefix + strconv.FormatUint(s.next.Add(1), 10)
+}
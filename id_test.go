@@ -0,0 +1,46 @@
+package operator
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUUIDv7(t *testing.T) {
+	g := UUIDv7()
+	before := time.Now().UnixMilli()
+	a, b := g.NewID(), g.NewID()
+
+	assert.Regexp(t, regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`), a)
+	assert.NotEqual(t, a, b)
+
+	var ms int64
+	for _, c := range a[:8] + a[9:13] {
+		ms = ms<<4 | int64(hexDigit(c))
+	}
+	assert.InDelta(t, before, ms, 1000)
+}
+
+func hexDigit(c rune) int {
+	if c >= 'a' {
+		return int(c-'a') + 10
+	}
+	return int(c - '0')
+}
+
+func TestOpContextNewID(t *testing.T) {
+	hub := newTestHub(WithIDGenerator(NewSequentialIDs("user-")))
+
+	var ids []string
+	for range 2 {
+		_, err := Invoke(context.Background(), hub, func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) {
+			ids = append(ids, ctx.NewID())
+			return in, nil
+		}, &struct{}{})
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, []string{"user-1", "user-2"}, ids)
+}
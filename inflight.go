@@ -0,0 +1,97 @@
+package operator
+
+import (
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// InFlightOperation describes an operation in progress on a hub; see
+// Hub.InFlight().
+type InFlightOperation struct {
+	// Unique ID of the invocation, as returned by OpContext.ID()
+	ID string `json:"id"`
+
+	// Operation name
+	Name string `json:"name"`
+
+	// Time at which the operation began, according to the hub's clock
+	Started time.Time `json:"started"`
+
+	// Phase of the operation: "running", "dispatching-events",
+	// "after-commit", or, while it unwinds, "succeeded", "failed" or
+	// "rolled-back"
+	State string `json:"state"`
+
+	// True if the operation has begun a transaction that has not yet been
+	// committed or rolled back
+	TxOpen bool `json:"txOpen"`
+}
+
+var stateNames = [...]string{
+	stateActive:         "running",
+	stateDispatchEvents: "dispatching-events",
+	stateInvokeAfter:    "after-commit",
+	stateSuccess:        "succeeded",
+	stateFailed:         "failed",
+	stateRolledback:     "rolled-back",
+}
+
+// flight is the record of an in-flight operation. It is updated by the
+// operation's goroutine and read by Hub.InFlight(), so its mutable fields
+// are atomic.
+type flight struct {
+	id      string
+	name    string
+	started time.Time
+	state   atomic.Int32
+	txOpen  atomic.Bool
+}
+
+// WithInFlightTracking makes the hub record each operation while it is in
+// progress, so that it can be listed by Hub.InFlight(). Tracking is disabled
+// by default as it adds a small cost to every invocation.
+func WithInFlightTracking() HubOption {
+	return func(o *hubOptions) { o.trackInFlight = true }
+}
+
+// InFlight() returns a snapshot of the operations in progress on the hub,
+// oldest first, for diagnosing slow operations and long-held transactions.
+// Returns nil unless the hub was created with WithInFlightTracking().
+func (h *Hub[Tx]) InFlight() []InFlightOperation {
+	if !h.opts.trackInFlight {
+		return nil
+	}
+	out := []InFlightOperation{}
+	h.flights.Range(func(key, _ any) bool {
+		f := key.(*flight)
+		out = append(out, InFlightOperation{
+			ID:      f.id,
+			Name:    f.name,
+			Started: f.started,
+			State:   stateNames[f.state.Load()],
+			TxOpen:  f.txOpen.Load(),
+		})
+		return true
+	})
+	sort.Slice(out, func(i, j int) bool { return out[i].Started.Before(out[j].Started) })
+	return out
+}
+
+// takeOff records the start of opCtx's operation, if tracking is enabled.
+func (h *Hub[Tx]) takeOff(opCtx *OpContext[Tx]) {
+	if !h.opts.trackInFlight {
+		return
+	}
+	f := &flight{id: opCtx.id, name: opCtx.name, started: opCtx.now}
+	f.state.Store(int32(opCtx.state))
+	opCtx.flight = f
+	h.flights.Store(f, struct{}{})
+}
+
+// land records the end of opCtx's operation.
+func (h *Hub[Tx]) land(opCtx *OpContext[Tx]) {
+	if opCtx.flight != nil {
+		h.flights.Delete(opCtx.flight)
+	}
+}
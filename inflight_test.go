@@ -0,0 +1,66 @@
+package operator
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInFlight(t *testing.T) {
+	clock := &manualClock{now: time.Unix(1000, 0)}
+	hub := newTestHub(WithInFlightTracking(), WithClock(clock))
+
+	ready, release := make(chan struct{}), make(chan struct{})
+	var id string
+	go Invoke(context.Background(), hub, func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) {
+		id = ctx.ID()
+		if _, err := ctx.Tx(); err != nil {
+			return nil, err
+		}
+		close(ready)
+		<-release
+		return in, nil
+	}, &struct{}{})
+	<-ready
+
+	ops := hub.InFlight()
+	if assert.Len(t, ops, 1) {
+		assert.Equal(t, id, ops[0].ID)
+		assert.Contains(t, ops[0].Name, "TestInFlight")
+		assert.Equal(t, time.Unix(1000, 0), ops[0].Started)
+		assert.Equal(t, "running", ops[0].State)
+		assert.True(t, ops[0].TxOpen)
+	}
+
+	close(release)
+	assert.Eventually(t, func() bool { return len(hub.InFlight()) == 0 }, time.Second, time.Millisecond)
+}
+
+func TestInFlight_AfterCommit(t *testing.T) {
+	hub := newTestHub(WithInFlightTracking())
+
+	var ops []InFlightOperation
+	_, err := InvokeTx(context.Background(), hub, func(ctx *OpContext[*TxTest], tx *TxTest, in *struct{}) (*struct{}, error) {
+		return in, ctx.AfterFunc(func(*OpContext[*TxTest]) { ops = hub.InFlight() })
+	}, &struct{}{})
+	assert.Nil(t, err)
+
+	if assert.Len(t, ops, 1) {
+		assert.Equal(t, "after-commit", ops[0].State)
+		assert.False(t, ops[0].TxOpen)
+	}
+}
+
+func TestInFlight_Disabled(t *testing.T) {
+	hub := newTestHub()
+
+	var ops []InFlightOperation
+	_, err := Invoke(context.Background(), hub, func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) {
+		ops = hub.InFlight()
+		return in, nil
+	}, &struct{}{})
+	assert.Nil(t, err)
+	assert.Nil(t, ops)
+}
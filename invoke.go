@@ -12,22 +12,10 @@ var ErrRecovered = errors.New("operation recovered from panic")
 //
 // The supplied *Hub is used as a transaction provider and event dispatcher.
 //
-// Returns the operation's output on success, or error on failure.
+// Returns the operation's output on success, or error on failure. Once the
+// hub's Shutdown() has been called, returns ErrShuttingDown.
 func Invoke[Tx Transaction, I any, O any](ctx context.Context, hub *Hub[Tx], op Operation[Tx, I, O], input *I) (*O, error) {
-	opCtx := hub.BeginOperation(ctx)
-
-	output, err := invokeWithRecover(func() (*O, error) {
-		return op(opCtx, input)
-	})
-
-	if err != nil {
-		opCtx.rollback()
-		return nil, err
-	} else if err := opCtx.commit(); err != nil {
-		return nil, fmt.Errorf("commit operation failed (%s)", err)
-	}
-
-	return output, nil
+	return invoke[Tx, *I, *O](ctx, hub, op, op, input)
 }
 
 // InvokeTx() begins a transaction then executes the supplied operation with the
@@ -38,37 +26,130 @@ func Invoke[Tx Transaction, I any, O any](ctx context.Context, hub *Hub[Tx], op
 //
 // Returns the operation's output on success, or error on failure.
 func InvokeTx[Tx Transaction, I any, O any](ctx context.Context, hub *Hub[Tx], op TxOperation[Tx, I, O], input *I) (*O, error) {
-	opCtx := hub.BeginOperation(ctx)
+	return invoke(ctx, hub, op, func(opCtx *OpContext[Tx], input *I) (*O, error) {
+		tx, err := opCtx.Tx()
+		if err != nil {
+			return nil, err
+		}
+		return op(opCtx, tx, input)
+	}, input)
+}
 
-	tx, err := opCtx.Tx()
-	if err != nil {
-		return nil, err
+// invoke begins an operation for op, and runs it via run, committing or
+// rolling back according to the outcome. I and O are the types of the
+// operation's input and output parameters: pointers for Operation, and
+// values for OperationV.
+func invoke[Tx Transaction, I any, O any](ctx context.Context, hub *Hub[Tx], op any, run func(*OpContext[Tx], I) (O, error), input I) (O, error) {
+	name, info := hub.lookupOperation(op)
+	return runOperation(hub.beginOperation(ctx, name), info, run, input)
+}
+
+// runOperation runs an operation, via run, in opCtx, wrapped in the hub's
+// middleware, tracing and metrics.
+func runOperation[Tx Transaction, I any, O any](opCtx *OpContext[Tx], info *operationInfo, run func(*OpContext[Tx], I) (O, error), input I) (O, error) {
+	hub := opCtx.hub
+	if !hub.enter(opCtx.Context) {
+		var zero O
+		return zero, ErrShuttingDown
 	}
+	defer hub.exit()
+	hub.takeOff(opCtx)
+	defer hub.land(opCtx)
 
-	output, err := invokeWithRecover(func() (*O, error) {
-		return op(opCtx, tx, input)
+	if !hub.opts.intercepts() {
+		return execute(opCtx, info, run, input)
+	}
+
+	var output O
+	opCtx.io.input = input
+	err := hub.intercept(opCtx, func() (err error) {
+		output, err = execute(opCtx, info, run, input)
+		if err == nil {
+			opCtx.io.output = output
+		}
+		return
 	})
+	return output, err
+}
+
+func execute[Tx Transaction, I any, O any](opCtx *OpContext[Tx], info *operationInfo, run func(*OpContext[Tx], I) (O, error), input I) (O, error) {
+	var zero O
+	if err := opCtx.authorize(info); err != nil {
+		return zero, err
+	}
+
+	// the operation's own limit is waited for first, so that invocations
+	// queued behind it do not hold admission slots other operations could
+	// use
+	if info != nil && info.bulkhead != nil {
+		release, err := info.bulkhead.acquire(opCtx, info.name)
+		if err != nil {
+			return zero, err
+		}
+		defer release()
+	}
+
+	if q := opCtx.hub.admission; q != nil {
+		release, err := q.acquire(opCtx, opCtx.name)
+		if err != nil {
+			return zero, err
+		}
+		defer release()
+	}
+	defer opCtx.unlock(0)
 
+	out, err := invokeWithRecover(run, opCtx, input)
 	if err != nil {
 		opCtx.rollback()
-		return nil, err
+		return zero, err
 	} else if err := opCtx.commit(); err != nil {
-		return nil, fmt.Errorf("commit operation failed (%s)", err)
+		return zero, fmt.Errorf("commit operation failed (%w)", err)
 	}
 
-	return output, nil
+	return out, nil
+}
+
+// InvokeAfterCommit() schedules op to be invoked with the given input once the
+// operation represented by ctx has committed. The follow-up runs in a new
+// operation, with its own transaction, on the hub's worker pool; its output is
+// discarded and any error is reported to the hub's background error handler
+// (and, if configured, its dead letter sink).
+// If the current operation fails, the follow-up is never invoked.
+//
+// InvokeAfterCommit() may be called from operations, event handlers, and
+// AfterFuncs.
+func InvokeAfterCommit[Tx Transaction, I any, O any](ctx *OpContext[Tx], op Operation[Tx, I, O], input *I) error {
+	name, _ := ctx.hub.lookupOperation(op)
+	return ctx.enqueueFollowUp(&followUp{
+		run: func(c context.Context) error {
+			_, err := Invoke(c, ctx.hub, op, input)
+			return err
+		},
+		operation: name,
+		input:     input,
+	})
+}
+
+// emitEvents is an operation that emits each of its input events.
+func emitEvents[Tx Transaction](ctx *OpContext[Tx], events *[]Event) (*struct{}, error) {
+	for _, evt := range *events {
+		if err := ctx.Emit(evt); err != nil {
+			return nil, err
+		}
+	}
+	return &struct{}{}, nil
 }
 
-func invokeWithRecover[O any](fn func() (*O, error)) (out *O, err error) {
+func invokeWithRecover[A any, B any, R any](fn func(A, B) (R, error), a A, b B) (out R, err error) {
 	defer func() {
 		if r := recover(); r != nil {
 			if e, ok := r.(error); ok {
 				err = fmt.Errorf("%w: %w", ErrRecovered, e)
 			} else {
-				err = fmt.Errorf("%w: %v", ErrRecovered, r)
+				err = fmt.Errorf("%w: %v", ErrRecovered, Redact(r))
 			}
 		}
 	}()
-	out, err = fn()
+	out, err = fn(a, b)
 	return
 }
@@ -0,0 +1,47 @@
+package operator
+
+import "context"
+
+// BatchItem is the outcome of invoking an operation for one item of a batch.
+type BatchItem[O any] struct {
+	// Output of the operation, if it succeeded
+	Output *O
+
+	// Error of the operation, if it failed
+	Err error
+}
+
+// BatchResult holds the outcome of each item of a batch, in the order of
+// the batch's inputs.
+type BatchResult[O any] struct {
+	Items []BatchItem[O]
+}
+
+// Failed() returns the number of items that failed.
+func (r *BatchResult[O]) Failed() int {
+	n := 0
+	for _, item := range r.Items {
+		if item.Err != nil {
+			n++
+		}
+	}
+	return n
+}
+
+// InvokeBatch() invokes op once for each of inputs, in order, each in an
+// operation of its own, with its own transaction, so that each item
+// succeeds or fails independently of the others. Use it for bulk endpoints
+// that report the outcome of each item, rather than failing as a whole.
+//
+// Items are not invoked once ctx is done; they fail with ctx's error.
+func InvokeBatch[Tx Transaction, I any, O any](ctx context.Context, hub *Hub[Tx], op Operation[Tx, I, O], inputs []I) *BatchResult[O] {
+	res := &BatchResult[O]{Items: make([]BatchItem[O], len(inputs))}
+	for i := range inputs {
+		if err := ctx.Err(); err != nil {
+			res.Items[i].Err = err
+			continue
+		}
+		res.Items[i].Output, res.Items[i].Err = Invoke(ctx, hub, op, &inputs[i])
+	}
+	return res
+}
@@ -0,0 +1,78 @@
+package operator
+
+import (
+	"context"
+	"fmt"
+)
+
+// chunking counts the units of work done by an operation invoked with
+// InvokeChunkedTx() since its last commit.
+type chunking struct {
+	size int
+	done int
+}
+
+// InvokeChunkedTx() is like InvokeTx(), but commits op's work in chunks of
+// size units, rather than in a single transaction. The operation calls
+// OpContext.Flush() after each unit of work - an imported row, say - and
+// every size'th call commits the transaction and begins another, which
+// Flush() returns:
+//
+//	func Import(ctx *OpContext[Tx], tx Tx, in *ImportInput) (*ImportOutput, error) {
+//		for _, row := range in.Rows {
+//			// ... insert row using tx
+//			var err error
+//			if tx, err = ctx.Flush(); err != nil {
+//				return nil, err
+//			}
+//		}
+//		return &ImportOutput{}, nil
+//	}
+//
+// Events emitted during a chunk are dispatched, and its AfterFuncs and
+// follow-ups run, as it commits; as with OpContext.Checkpoint(), values and
+// transaction-scoped locks do not carry over to the next chunk. The final
+// chunk commits when op returns. If
+// op fails, only the current chunk is rolled back; earlier chunks remain
+// committed. To resume from the last committed chunk after a failure, use
+// InvokeResumable() and OpContext.Checkpoint() instead.
+//
+// Panics if size is less than 1.
+func InvokeChunkedTx[Tx Transaction, I any, O any](ctx context.Context, hub *Hub[Tx], op TxOperation[Tx, I, O], size int, input *I) (*O, error) {
+	if size <= 0 {
+		panic(fmt.Errorf("chunk size must be at least 1"))
+	}
+	name, info := hub.lookupOperation(op)
+	opCtx := hub.beginOperation(ctx, name)
+	opCtx.chunk = &chunking{size: size}
+	return runOperation(opCtx, info, func(opCtx *OpContext[Tx], input *I) (*O, error) {
+		tx, err := opCtx.Tx()
+		if err != nil {
+			return nil, err
+		}
+		return op(opCtx, tx, input)
+	}, input)
+}
+
+// Flush() records that the operation has completed a unit of work and
+// returns its transaction. If the operation was invoked with
+// InvokeChunkedTx() and the unit completes a chunk, the transaction is
+// first committed, along with the chunk's events and AfterFuncs, and the
+// returned transaction is a new one; the operation must use it in place of
+// the old. If the commit fails the operation can not continue, and must
+// return the error.
+//
+// Otherwise Flush() is equivalent to Tx(), so operations can be written to
+// support chunking but invoked either way.
+func (o *OpContext[T]) Flush() (T, error) {
+	if c := o.chunk; c != nil {
+		if c.done++; c.done >= c.size {
+			c.done = 0
+			if err := o.commitChunk(false); err != nil {
+				var zero T
+				return zero, fmt.Errorf("commit chunk failed (%w)", err)
+			}
+		}
+	}
+	return o.Tx()
+}
@@ -0,0 +1,39 @@
+package operator
+
+import "context"
+
+// NoInputOperation is an operation that takes no input, such as a report or
+// a scheduled job.
+type NoInputOperation[Tx Transaction, O any] func(ctx *OpContext[Tx]) (*O, error)
+
+// NoOutputOperation is an operation that produces no output, such as a
+// command whose only effects are on the database.
+type NoOutputOperation[Tx Transaction, I any] func(ctx *OpContext[Tx], input *I) error
+
+// Command is an operation that takes no input and produces no output, such
+// as "rebuild the search index".
+type Command[Tx Transaction] func(ctx *OpContext[Tx]) error
+
+// InvokeNoInput() is like Invoke(), for operations that take no input.
+func InvokeNoInput[Tx Transaction, O any](ctx context.Context, hub *Hub[Tx], op NoInputOperation[Tx, O]) (*O, error) {
+	return invoke(ctx, hub, op, func(opCtx *OpContext[Tx], _ *Empty) (*O, error) {
+		return op(opCtx)
+	}, nil)
+}
+
+// InvokeNoOutput() is like Invoke(), for operations that produce no output.
+func InvokeNoOutput[Tx Transaction, I any](ctx context.Context, hub *Hub[Tx], op NoOutputOperation[Tx, I], input *I) error {
+	_, err := invoke(ctx, hub, op, func(opCtx *OpContext[Tx], input *I) (*Empty, error) {
+		return nil, op(opCtx, input)
+	}, input)
+	return err
+}
+
+// InvokeCommand() is like Invoke(), for operations that take no input and
+// produce no output.
+func InvokeCommand[Tx Transaction](ctx context.Context, hub *Hub[Tx], op Command[Tx]) error {
+	_, err := invoke(ctx, hub, op, func(opCtx *OpContext[Tx], _ *Empty) (*Empty, error) {
+		return nil, op(opCtx)
+	}, nil)
+	return err
+}
@@ -0,0 +1,343 @@
+package operator
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInvoke_CommitsTransaction(t *testing.T) {
+	hub := newTestHub()
+
+	var tx *TxTest
+	_, err := Invoke(context.Background(), hub, func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) {
+		tx, _ = ctx.Tx()
+		return in, nil
+	}, &struct{}{})
+
+	assert.Nil(t, err)
+	assert.True(t, tx.Committed)
+	assert.False(t, tx.RolledBack)
+}
+
+func TestInvoke_RollsBackOnError(t *testing.T) {
+	hub := newTestHub()
+
+	var tx *TxTest
+	_, err := Invoke(context.Background(), hub, func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) {
+		tx, _ = ctx.Tx()
+		return nil, assert.AnError
+	}, &struct{}{})
+
+	assert.ErrorIs(t, err, assert.AnError)
+	assert.False(t, tx.Committed)
+	assert.True(t, tx.RolledBack)
+}
+
+func TestInvoke_RollsBackWhenContextDone(t *testing.T) {
+	hub := newTestHub()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var tx *TxTest
+	_, err := Invoke(ctx, hub, func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) {
+		tx, _ = ctx.Tx()
+		cancel()
+		return in, nil
+	}, &struct{}{})
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.False(t, tx.Committed)
+	assert.True(t, tx.RolledBack)
+}
+
+// valueTx is a transaction used by value; it is not comparable.
+type valueTx struct {
+	log  *[]string
+	tags []string
+}
+
+func (t valueTx) Commit(ctx context.Context) error {
+	*t.log = append(*t.log, "commit")
+	return nil
+}
+
+func (t valueTx) Rollback(ctx context.Context) error {
+	*t.log = append(*t.log, "rollback")
+	return nil
+}
+
+type queryTx interface {
+	Transaction
+	Query() string
+}
+
+func (t valueTx) Query() string { return "query" }
+
+func TestInvoke_ValueTransaction(t *testing.T) {
+	var log []string
+	hub := NewHub(func(ctx context.Context) (valueTx, error) {
+		log = append(log, "begin")
+		return valueTx{log: &log}, nil
+	})
+	_, err := InvokeTx(context.Background(), hub, func(ctx *OpContext[valueTx], tx valueTx, in *struct{}) (*struct{}, error) {
+		// the transaction is not begun again
+		_, err := ctx.Tx()
+		return in, err
+	}, &struct{}{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"begin", "commit"}, log)
+}
+
+func TestInvoke_InterfaceTransaction(t *testing.T) {
+	var log []string
+	hub := NewHub(func(ctx context.Context) (queryTx, error) {
+		log = append(log, "begin")
+		return valueTx{log: &log}, nil
+	})
+	_, err := Invoke(context.Background(), hub, func(ctx *OpContext[queryTx], in *struct{}) (*struct{}, error) {
+		tx, err := ctx.Tx()
+		if err != nil {
+			return nil, err
+		}
+		log = append(log, tx.Query())
+		return nil, assert.AnError
+	}, &struct{}{})
+	assert.ErrorIs(t, err, assert.AnError)
+	assert.Equal(t, []string{"begin", "query", "rollback"}, log)
+
+	// operations that never begin a transaction neither commit nor roll back
+	log = nil
+	_, err = Invoke(context.Background(), hub, func(ctx *OpContext[queryTx], in *struct{}) (*struct{}, error) { return in, nil }, &struct{}{})
+	assert.NoError(t, err)
+	assert.Empty(t, log)
+}
+
+// ctxTx records the operation and tenant of the contexts it is called with.
+type ctxTx struct {
+	log *[]string
+}
+
+func describeCtx(ctx context.Context) string {
+	op, _ := OperationFrom(ctx)
+	tenant, _ := TenantFrom(ctx)
+	return op.ID() + " " + tenant
+}
+
+func (t ctxTx) Commit(ctx context.Context) error {
+	*t.log = append(*t.log, "commit "+describeCtx(ctx))
+	return nil
+}
+
+func (t ctxTx) Rollback(ctx context.Context) error {
+	*t.log = append(*t.log, "rollback "+describeCtx(ctx))
+	return nil
+}
+
+func TestInvoke_TransactionContext(t *testing.T) {
+	var log []string
+	hub := NewHub(func(ctx context.Context) (ctxTx, error) {
+		log = append(log, "begin "+describeCtx(ctx))
+		return ctxTx{log: &log}, nil
+	})
+
+	var id string
+	op := func(ctx *OpContext[ctxTx], tx ctxTx, in *bool) (*struct{}, error) {
+		id = ctx.ID()
+		if *in {
+			return nil, assert.AnError
+		}
+		return &struct{}{}, nil
+	}
+	ctx := WithTenant(context.Background(), "acme")
+
+	_, err := InvokeTx(ctx, hub, op, new(bool))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"begin " + id + " acme", "commit " + id + " acme"}, log)
+
+	log = nil
+	fail := true
+	_, err = InvokeTx(ctx, hub, op, &fail)
+	assert.ErrorIs(t, err, assert.AnError)
+	assert.Equal(t, []string{"begin " + id + " acme", "rollback " + id + " acme"}, log)
+}
+
+func doubleV(ctx *OpContext[*TxTest], in int) (int, error) {
+	if in < 0 {
+		return 0, assert.AnError
+	}
+	return in * 2, nil
+}
+
+func TestInvokeV(t *testing.T) {
+	hub := newTestHub()
+
+	out, err := InvokeV(context.Background(), hub, doubleV, 21)
+	assert.NoError(t, err)
+	assert.Equal(t, 42, out)
+
+	out, err = InvokeV(context.Background(), hub, doubleV, -1)
+	assert.ErrorIs(t, err, assert.AnError)
+	assert.Zero(t, out)
+
+	_, err = InvokeV(context.Background(), hub, func(ctx *OpContext[*TxTest], in string) (string, error) {
+		panic("oops")
+	}, "")
+	assert.ErrorIs(t, err, ErrRecovered)
+}
+
+func TestInvokeTxV(t *testing.T) {
+	hub := newTestHub()
+
+	var committed *TxTest
+	out, err := InvokeTxV(context.Background(), hub, func(ctx *OpContext[*TxTest], tx *TxTest, in string) ([]string, error) {
+		committed = tx
+		return []string{in}, nil
+	}, "a")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a"}, out)
+	assert.True(t, committed.Committed)
+}
+
+func TestRegisterOperationV(t *testing.T) {
+	hub := newTestHub()
+	var got int
+	assert.NoError(t, RegisterOperationV(hub, "double", func(ctx *OpContext[*TxTest], in int) (int, error) {
+		got = in * 2
+		assert.Equal(t, "double", ctx.Name())
+		return got, nil
+	}))
+
+	assert.NoError(t, hub.InvokeJSON(context.Background(), "double", []byte("4")))
+	assert.Equal(t, 8, got)
+	assert.ErrorIs(t, hub.InvokeJSON(context.Background(), "double", []byte(`"x"`)), ErrInvalidInput)
+}
+
+func countUsers(ctx *OpContext[*TxTest]) (*int, error) {
+	n := 3
+	return &n, nil
+}
+
+func TestInvokeNoInput(t *testing.T) {
+	var name string
+	hub := newTestHub(WithMiddleware(func(ctx context.Context, info OperationInfo, next func(ctx context.Context) error) error {
+		name = info.Name()
+		return next(ctx)
+	}))
+	out, err := InvokeNoInput(context.Background(), hub, countUsers)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, *out)
+	assert.Equal(t, "operator.countUsers", name)
+}
+
+func TestInvokeNoOutput(t *testing.T) {
+	hub := newTestHub()
+	var tx *TxTest
+	err := InvokeNoOutput(context.Background(), hub, func(ctx *OpContext[*TxTest], in *string) error {
+		tx, _ = ctx.Tx()
+		if *in == "" {
+			return assert.AnError
+		}
+		return nil
+	}, new(string))
+	assert.ErrorIs(t, err, assert.AnError)
+	assert.True(t, tx.RolledBack)
+}
+
+func TestInvokeCommand(t *testing.T) {
+	hub := newTestHub()
+	var tx *TxTest
+	err := InvokeCommand(context.Background(), hub, func(ctx *OpContext[*TxTest]) error {
+		tx, _ = ctx.Tx()
+		return ctx.Emit(&testEvent{})
+	})
+	assert.NoError(t, err)
+	assert.True(t, tx.Committed)
+}
+
+func TestInvokeBatch(t *testing.T) {
+	hub := newTestHub()
+	var txs []*TxTest
+	res := InvokeBatch(context.Background(), hub, func(ctx *OpContext[*TxTest], in *int) (*int, error) {
+		tx, _ := ctx.Tx()
+		txs = append(txs, tx)
+		if *in < 0 {
+			return nil, assert.AnError
+		}
+		out := *in * 2
+		return &out, nil
+	}, []int{1, -1, 3})
+
+	assert.Equal(t, 1, res.Failed())
+	assert.Equal(t, 2, *res.Items[0].Output)
+	assert.ErrorIs(t, res.Items[1].Err, assert.AnError)
+	assert.Equal(t, 6, *res.Items[2].Output)
+	assert.Equal(t, []bool{true, false, true}, []bool{txs[0].Committed, txs[1].Committed, txs[2].Committed})
+	assert.True(t, txs[1].RolledBack)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	res = InvokeBatch(ctx, hub, identity, []int{1})
+	assert.ErrorIs(t, res.Items[0].Err, context.Canceled)
+}
+
+func identity(ctx *OpContext[*TxTest], in *int) (*int, error) { return in, nil }
+
+func TestInvokeChunkedTx(t *testing.T) {
+	var txs []*TxTest
+	hub := NewHub(func(ctx context.Context) (*TxTest, error) {
+		tx := &TxTest{}
+		txs = append(txs, tx)
+		return tx, nil
+	})
+
+	var events []int
+	assert.NoError(t, hub.RegisterEventHandler(&chunkEvent{}, func(ctx *OpContext[*TxTest], evt *chunkEvent) error {
+		events = append(events, evt.Next)
+		return nil
+	}))
+
+	var used []*TxTest
+	op := func(ctx *OpContext[*TxTest], tx *TxTest, in *importRows) (*struct{}, error) {
+		for i := 0; i < in.Rows; i++ {
+			if i == in.FailAt {
+				return nil, errors.New("bad row")
+			}
+			used = append(used, tx)
+			ctx.Emit(&chunkEvent{Next: i})
+			var err error
+			if tx, err = ctx.Flush(); err != nil {
+				return nil, err
+			}
+		}
+		return &struct{}{}, nil
+	}
+
+	_, err := InvokeChunkedTx(context.Background(), hub, op, 2, &importRows{Rows: 5, FailAt: -1})
+	assert.NoError(t, err)
+	assert.Equal(t, []int{0, 1, 2, 3, 4}, events)
+	if assert.Len(t, txs, 3) {
+		assert.Equal(t, []*TxTest{txs[0], txs[0], txs[1], txs[1], txs[2]}, used)
+		for _, tx := range txs {
+			assert.True(t, tx.Committed)
+		}
+	}
+
+	txs, used, events = nil, nil, nil
+	_, err = InvokeChunkedTx(context.Background(), hub, op, 2, &importRows{Rows: 5, FailAt: 3})
+	assert.EqualError(t, err, "bad row")
+	assert.Equal(t, []int{0, 1}, events, "events of the failed chunk are not dispatched")
+	if assert.Len(t, txs, 2) {
+		assert.True(t, txs[0].Committed)
+		assert.True(t, txs[1].RolledBack)
+	}
+
+	txs = nil
+	_, err = InvokeTx(context.Background(), hub, op, &importRows{Rows: 3, FailAt: -1})
+	assert.NoError(t, err)
+	assert.Len(t, txs, 1, "Flush does not commit unless chunked")
+
+	assert.Panics(t, func() { InvokeChunkedTx(context.Background(), hub, op, 0, &importRows{}) })
+}
@@ -0,0 +1,64 @@
+package operator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// OperationV is an operation taking its input, and returning its output, by
+// value. Small inputs and outputs can then be passed without allocating
+// them on the heap, and without &struct{}{} literals at each call site.
+// OperationV supports the same hub features as Operation; invoke it with
+// InvokeV().
+type OperationV[Tx Transaction, I any, O any] func(ctx *OpContext[Tx], input I) (O, error)
+
+// TxOperationV is a TxOperation taking its input, and returning its output,
+// by value; see OperationV.
+type TxOperationV[Tx Transaction, I any, O any] func(ctx *OpContext[Tx], tx Tx, input I) (O, error)
+
+// InvokeV() is like Invoke(), but for operations taking their input, and
+// returning their output, by value. The zero O is returned on failure.
+func InvokeV[Tx Transaction, I any, O any](ctx context.Context, hub *Hub[Tx], op OperationV[Tx, I, O], input I) (O, error) {
+	return invoke[Tx, I, O](ctx, hub, op, op, input)
+}
+
+// InvokeTxV() is like InvokeTx(), but for operations taking their input,
+// and returning their output, by value.
+func InvokeTxV[Tx Transaction, I any, O any](ctx context.Context, hub *Hub[Tx], op TxOperationV[Tx, I, O], input I) (O, error) {
+	return invoke(ctx, hub, op, func(opCtx *OpContext[Tx], input I) (O, error) {
+		tx, err := opCtx.Tx()
+		if err != nil {
+			var zero O
+			return zero, err
+		}
+		return op(opCtx, tx, input)
+	}, input)
+}
+
+// RegisterOperationV() assigns an explicit name to op; see
+// RegisterOperation().
+func RegisterOperationV[Tx Transaction, I any, O any](hub *Hub[Tx], name string, op OperationV[Tx, I, O]) error {
+	return hub.registerOperation(op, name, reflect.TypeFor[I](), reflect.TypeFor[O](), func(ctx context.Context, data []byte) error {
+		var input I
+		if err := json.Unmarshal(data, &input); err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidInput, err)
+		}
+		_, err := InvokeV(ctx, hub, op, input)
+		return err
+	})
+}
+
+// RegisterTxOperationV() assigns an explicit name to op; see
+// RegisterOperation().
+func RegisterTxOperationV[Tx Transaction, I any, O any](hub *Hub[Tx], name string, op TxOperationV[Tx, I, O]) error {
+	return hub.registerOperation(op, name, reflect.TypeFor[I](), reflect.TypeFor[O](), func(ctx context.Context, data []byte) error {
+		var input I
+		if err := json.Unmarshal(data, &input); err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidInput, err)
+		}
+		_, err := InvokeTxV(ctx, hub, op, input)
+		return err
+	})
+}
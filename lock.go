@@ -0,0 +1,160 @@
+package operator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+var (
+	// ErrNoLockProvider is returned by OpContext.Lock() if the hub has no
+	// LockProvider.
+	ErrNoLockProvider = errors.New("no lock provider")
+
+	// ErrLockOrder is returned by OpContext.Lock() when asked to lock a key
+	// that sorts before a key the operation already holds. Two operations
+	// acquiring the same keys in different orders can deadlock, so keys must
+	// be locked in ascending order - most simply, by locking every key in a
+	// single call.
+	ErrLockOrder = errors.New("locks must be acquired in ascending key order")
+)
+
+// Unlock releases a lock acquired from a LockProvider.
+type Unlock func(ctx context.Context) error
+
+// LockProvider acquires exclusive locks on behalf of operations; see
+// OpContext.Lock().
+type LockProvider[Tx Transaction] interface {
+	// Lock blocks until key is locked for the operation ctx, or ctx is done.
+	// Providers whose locks are released with the operation's transaction -
+	// such as Postgres transaction-level advisory locks - may begin it with
+	// ctx.Tx() and return a nil Unlock.
+	Lock(ctx *OpContext[Tx], key string) (Unlock, error)
+}
+
+// SetLockProvider() sets the provider used by OpContext.Lock().
+//
+// Returns ErrHubFrozen if the hub has been frozen.
+func (h *Hub[Tx]) SetLockProvider(p LockProvider[Tx]) error {
+	if h.frozen.Load() {
+		return ErrHubFrozen
+	}
+	h.locks = p
+	return nil
+}
+
+type heldLock struct {
+	key    string
+	unlock Unlock
+}
+
+// Lock() acquires exclusive locks on keys, using the hub's LockProvider,
+// blocking until they are available or the operation's context is done. The
+// locks are held until the operation completes, after its transaction has
+// committed or rolled back, so operations that lock the same key - an
+// aggregate ID, say - are serialized.
+//
+// Keys are locked in ascending order, and keys the operation already holds
+// are skipped. Locking a key that sorts before one already held returns
+// ErrLockOrder, without waiting, as it could deadlock with an operation
+// locking the same keys in the opposite order. If any key cannot be locked,
+// those locked by this call are released.
+func (o *OpContext[T]) Lock(keys ...string) error {
+	if o.state != stateActive {
+		return ErrInvalidState
+	}
+	p := o.hub.locks
+	if p == nil {
+		return ErrNoLockProvider
+	}
+
+	keys = slices.Clone(keys)
+	slices.Sort(keys)
+	keys = slices.Compact(keys)
+	keys = slices.DeleteFunc(keys, o.holdsLock)
+	if len(keys) == 0 {
+		return nil
+	}
+	if n := len(o.locks); n > 0 && keys[0] < o.locks[n-1].key {
+		return fmt.Errorf("%w: %q after %q", ErrLockOrder, keys[0], o.locks[n-1].key)
+	}
+
+	held := len(o.locks)
+	for _, key := range keys {
+		unlock, err := p.Lock(o, key)
+		if err != nil {
+			o.unlock(held)
+			return fmt.Errorf("lock %q failed (%w)", key, err)
+		}
+		o.locks = append(o.locks, heldLock{key: key, unlock: unlock})
+	}
+	return nil
+}
+
+func (o *OpContext[T]) holdsLock(key string) bool {
+	for _, l := range o.locks {
+		if l.key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// unlock releases, in reverse order, the locks held from index from onwards.
+// Errors are reported to the hub's background error handler.
+func (o *OpContext[T]) unlock(from int) {
+	if len(o.locks) <= from {
+		return
+	}
+	ctx := context.WithoutCancel(o.Context)
+	for i := len(o.locks) - 1; i >= from; i-- {
+		if fn := o.locks[i].unlock; fn != nil {
+			if err := fn(ctx); err != nil {
+				o.hub.opts.onBackgroundError(fmt.Errorf("unlock %q failed (%w)", o.locks[i].key, err))
+			}
+		}
+	}
+	o.locks = o.locks[:from]
+}
+
+// LocalLocks is a LockProvider whose locks are held in memory, and so only
+// serialize operations within a single process. It is suitable for tests,
+// and for applications that run a single instance.
+type LocalLocks[Tx Transaction] struct {
+	mu   sync.Mutex
+	held map[string]chan struct{}
+}
+
+// NewLocalLocks() returns an empty LocalLocks.
+func NewLocalLocks[Tx Transaction]() *LocalLocks[Tx] {
+	return &LocalLocks[Tx]{held: map[string]chan struct{}{}}
+}
+
+// Lock implements LockProvider.
+func (l *LocalLocks[Tx]) Lock(ctx *OpContext[Tx], key string) (Unlock, error) {
+	for {
+		l.mu.Lock()
+		wait, ok := l.held[key]
+		if !ok {
+			done := make(chan struct{})
+			l.held[key] = done
+			l.mu.Unlock()
+			return func(context.Context) error {
+				l.mu.Lock()
+				delete(l.held, key)
+				l.mu.Unlock()
+				close(done)
+				return nil
+			}, nil
+		}
+		l.mu.Unlock()
+
+		select {
+		case <-wait:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
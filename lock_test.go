@@ -0,0 +1,109 @@
+package operator
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLock_Serializes(t *testing.T) {
+	hub := newTestHub()
+	assert.Nil(t, hub.SetLockProvider(NewLocalLocks[*TxTest]()))
+
+	var running, peak atomic.Int32
+	op := func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) {
+		if err := ctx.Lock("account:1"); err != nil {
+			return nil, err
+		}
+		n := running.Add(1)
+		for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {
+		}
+		time.Sleep(time.Millisecond)
+		running.Add(-1)
+		return in, nil
+	}
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := Invoke(context.Background(), hub, op, &struct{}{})
+			assert.Nil(t, err)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), peak.Load())
+}
+
+func TestLock_ReleasedOnFailure(t *testing.T) {
+	hub := newTestHub()
+	assert.Nil(t, hub.SetLockProvider(NewLocalLocks[*TxTest]()))
+
+	_, err := Invoke(context.Background(), hub, func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) {
+		assert.Nil(t, ctx.Lock("k"))
+		return nil, assert.AnError
+	}, &struct{}{})
+	assert.ErrorIs(t, err, assert.AnError)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	_, err = Invoke(ctx, hub, func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) {
+		return in, ctx.Lock("k")
+	}, &struct{}{})
+	assert.Nil(t, err)
+}
+
+func TestLock_Order(t *testing.T) {
+	hub := newTestHub()
+	assert.Nil(t, hub.SetLockProvider(NewLocalLocks[*TxTest]()))
+
+	_, err := Invoke(context.Background(), hub, func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) {
+		assert.Nil(t, ctx.Lock("b", "a", "b"))
+		assert.Equal(t, []string{"a", "b"}, []string{ctx.locks[0].key, ctx.locks[1].key})
+		assert.Nil(t, ctx.Lock("a", "c"), "held keys are skipped")
+		assert.ErrorIs(t, ctx.Lock("aa"), ErrLockOrder)
+		assert.Len(t, ctx.locks, 3)
+		return in, nil
+	}, &struct{}{})
+	assert.Nil(t, err)
+}
+
+func TestLock_ContextDone(t *testing.T) {
+	hub := newTestHub()
+	locks := NewLocalLocks[*TxTest]()
+	assert.Nil(t, hub.SetLockProvider(locks))
+
+	held, release := make(chan struct{}), make(chan struct{})
+	go Invoke(context.Background(), hub, func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) {
+		assert.Nil(t, ctx.Lock("k"))
+		close(held)
+		<-release
+		return in, nil
+	}, &struct{}{})
+	<-held
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := Invoke(ctx, hub, func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) {
+		return in, ctx.Lock("j", "k")
+	}, &struct{}{})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	locks.mu.Lock()
+	_, ok := locks.held["j"]
+	locks.mu.Unlock()
+	assert.False(t, ok, "partially acquired locks are released")
+}
+
+func TestLock_NoProvider(t *testing.T) {
+	_, err := Invoke(context.Background(), newTestHub(), func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) {
+		return in, ctx.Lock("k")
+	}, &struct{}{})
+	assert.ErrorIs(t, err, ErrNoLockProvider)
+}
@@ -2,6 +2,10 @@ package operator
 
 import (
 	"context"
+	"maps"
+	"reflect"
+	"slices"
+	"time"
 )
 
 // TODO: per-operation cache?
@@ -36,11 +40,157 @@ type OpContext[T Transaction] struct {
 	hub              *Hub[T]
 	beginTransaction TransactionProvider[T]
 
+	id    string
+	name  string
+	now   time.Time
 	state int
 
-	activeTx T
-	events   []Event
-	after    []AfterFunc[T]
+	activeTx  T
+	txActive  bool
+	events    []queuedEvent
+	after     []AfterFunc[T]
+	followUps []*followUp
+	eventBuf  [2]queuedEvent
+	afterBuf  [1]AfterFunc[T]
+	txBegan   time.Time
+	values    map[reflect.Type]any
+	locks     []heldLock
+
+	// events dispatched, retained for subscribers only if there are any
+	published []Event
+
+	// input and output, recorded only while intercepted
+	io opPayloads
+
+	// record of the operation while in flight; nil unless the hub tracks
+	// in-flight operations
+	flight *flight
+
+	// true if the operation is a shadow invocation, which is always rolled
+	// back
+	shadow bool
+
+	// depth assigned to events emitted in the current state; incremented
+	// as each level of handler-emitted events is dispatched
+	emitDepth int
+	recordSeq int
+
+	// number of events emitted, the event being dispatched, and a copy of
+	// it referred to by the events its handlers emit, made on first use
+	emitted     int
+	dispatching queuedEvent
+	cause       *queuedEvent
+
+	// error failing the operation, once its events exceed the hub's limits
+	cascadeErr error
+
+	// keys of queued events being deduplicated; see DedupEvent
+	queuedKeys map[dedupKey]struct{}
+
+	// events dispatched to batch handlers, pending their calls
+	batches []eventBatch[T]
+
+	// saved state of an operation invoked with InvokeResumable()
+	checkpoint *checkpoint
+
+	// progress through the current chunk of an operation invoked with
+	// InvokeChunkedTx()
+	chunk *chunking
+
+	// if non-nil, the OpContext only asks an operation created by a wrapper
+	// to describe itself; see describeWrapper()
+	describe *namedOp
+
+	// arbitrates the commits of an operation run by WithTimeout() with the
+	// enclosing operation; see commitChunk()
+	guard commitGuard
+}
+
+// child returns a copy of the operation for running part of it - on another
+// goroutine, with its own context and transaction provider - as by
+// WithTimeout(). The copy shares everything but the work it records: events,
+// AfterFuncs and follow-ups start empty, values, locks and deduplication keys
+// are cloned, and the operation is not tracked in flight. adopt() takes its
+// work back.
+func (o *OpContext[T]) child(ctx context.Context, begin TransactionProvider[T]) *OpContext[T] {
+	c := *o
+	c.Context = ctx
+	c.beginTransaction = begin
+	c.flight = nil
+	c.events, c.eventBuf = nil, [2]queuedEvent{}
+	c.after, c.afterBuf = nil, [1]AfterFunc[T]{}
+	c.followUps = nil
+	c.batches = nil
+	c.published = nil
+	c.values = maps.Clone(o.values)
+	c.locks = slices.Clone(o.locks)
+	c.queuedKeys = maps.Clone(o.queuedKeys)
+	return &c
+}
+
+// adopt takes over the transaction and the work recorded by child, made by
+// child() and since returned. The operation's own context, transaction
+// provider and flight record are kept, and the child's events, AfterFuncs,
+// follow-ups and batches are appended to its own.
+func (o *OpContext[T]) adopt(child *OpContext[T]) {
+	parent := *o
+	*o = *child
+	o.Context = parent.Context
+	o.beginTransaction = parent.beginTransaction
+	o.guard = parent.guard
+	o.state = parent.state
+	o.io = parent.io
+	o.flight = parent.flight
+	if o.flight != nil && o.isTransactionActive() {
+		o.flight.txOpen.Store(true)
+	}
+	// restore the buffers the operation's own events and AfterFuncs may
+	// occupy, before appending to them
+	o.eventBuf, o.afterBuf = parent.eventBuf, parent.afterBuf
+	o.events = append(parent.events, child.events...)
+	o.after = append(parent.after, child.after...)
+	o.followUps = append(parent.followUps, child.followUps...)
+	o.batches = append(parent.batches, child.batches...)
+	o.published = append(parent.published, child.published...)
+}
+
+type queuedEvent struct {
+	evt   Event
+	depth int
+
+	// event whose handler emitted this one, if any
+	cause *queuedEvent
+}
+
+// Return the unique ID of this operation invocation.
+func (o *OpContext[T]) ID() string { return o.id }
+
+// Return the operation's name. Unless otherwise specified, an operation is
+// named after the function that implements it, qualified by its package name.
+func (o *OpContext[T]) Name() string { return o.name }
+
+// Now() returns the operation's time: the time, according to the hub's clock,
+// at which the operation began. Use it in preference to time.Now() so that
+// everything an operation records - timestamps on rows, audit entries and
+// events - agrees, and so that tests can control it with WithClock().
+func (o *OpContext[T]) Now() time.Time { return o.now }
+
+func (o *OpContext[T]) payloads() *opPayloads { return &o.io }
+
+// Shadow() returns true if this operation is the candidate of
+// InvokeShadow(). Its transaction is always rolled back, and its AfterFuncs
+// and follow-ups never run, but operations and event handlers that have other
+// side effects - calling external services, say - should check Shadow() and
+// skip them.
+func (o *OpContext[T]) Shadow() bool { return o.shadow }
+
+// Value implements context.Context, additionally exposing the operation's
+// OperationInfo to OperationFrom().
+func (o *OpContext[T]) Value(key any) any {
+	if key == (operationInfoKey{}) {
+		return o
+	}
+	return o.Context.Value(key)
 }
 
 // Return the operation's transaction, creating a new transaction if not
@@ -48,24 +198,81 @@ type OpContext[T Transaction] struct {
 func (o *OpContext[T]) Tx() (T, error) {
 	var zero T
 	if !o.isTransactionActive() {
-		tx, err := o.beginTransaction(o.Context)
+		var start time.Time
+		observed := o.hub != nil && len(o.hub.opts.txObservers) > 0
+		if observed {
+			start = o.hub.opts.clock.Now()
+		}
+		tx, err := o.beginTransaction(o)
+		if observed {
+			o.hub.observeBegin(o, o.hub.opts.clock.Now().Sub(start), err)
+		}
 		if err != nil {
 			return zero, err
 		}
-		o.activeTx = tx
+		o.activeTx, o.txActive = tx, true
+		if o.flight != nil {
+			o.flight.txOpen.Store(true)
+		}
+		if o.hub != nil && o.hub.opts.observesTx() {
+			o.txBegan = o.hub.opts.clock.Now()
+		}
 	}
 	return o.activeTx, nil
 }
 
 // Register an event to be dispatched upon completion of the operation.
+// Events may be emitted by the operation itself, and by event handlers.
+//
+// Returns an error wrapping ErrCascadeLimit or ErrEventCycle, and fails the
+// operation, if the event exceeds the hub's CascadeLimits.
 func (o *OpContext[T]) Emit(evt Event) error {
-	if o.state <= stateDispatchEvents {
+	if o.state > stateDispatchEvents {
 		return ErrInvalidState
 	}
-	o.events = append(o.events, evt)
+	if o.cascadeErr != nil {
+		return o.cascadeErr
+	}
+	if o.hub != nil {
+		if key, ok := o.hub.dedupKeyOf(evt); ok {
+			if _, queued := o.queuedKeys[key]; queued {
+				return nil
+			}
+			if o.queuedKeys == nil {
+				o.queuedKeys = map[dedupKey]struct{}{}
+			}
+			o.queuedKeys[key] = struct{}{}
+		}
+	}
+	qe := queuedEvent{evt: evt, depth: o.emitDepth}
+	if o.state == stateDispatchEvents {
+		if o.cause == nil {
+			c := o.dispatching
+			o.cause = &c
+		}
+		qe.cause = o.cause
+	}
+	if o.hub != nil {
+		o.emitted++
+		if err := checkCascade(&o.hub.opts.cascade, &qe, o.emitted); err != nil {
+			o.cascadeErr = err
+			return err
+		}
+	}
+	if o.events == nil {
+		o.events = o.eventBuf[:0]
+	}
+	o.events = append(o.events, qe)
 	return nil
 }
 
+// Register an event, identified by name and carrying payload, to be dispatched
+// upon completion of the operation. The event is dispatched as a *NamedEvent
+// to handlers registered with Hub.RegisterNamedEventHandler().
+func (o *OpContext[T]) EmitNamed(name string, payload any) error {
+	return o.Emit(&NamedEvent{Name: name, Payload: payload})
+}
+
 // Register a function to be invoked upon completion of the operation.
 // The callback is invoked after the transaction (if any) is committed.
 // After callbacks can be registered by the main operation, as well as
@@ -74,50 +281,156 @@ func (o *OpContext[T]) AfterFunc(fn AfterFunc[T]) error {
 	if o.state != stateActive && o.state != stateDispatchEvents {
 		return ErrInvalidState
 	}
+	if o.after == nil {
+		o.after = o.afterBuf[:0]
+	}
 	o.after = append(o.after, fn)
 	return nil
 }
 
+// Register an event to be dispatched once the operation has committed.
+// The event is dispatched in a new operation, with its own transaction, by
+// the hub's worker pool; handler failures are reported to the hub's background
+// error handler and do not affect this operation.
+//
+// Unlike Emit(), EmitAfterCommit() may be called from an AfterFunc.
+func (o *OpContext[T]) EmitAfterCommit(evt Event) error {
+	return o.enqueueFollowUp(&followUp{
+		run: func(ctx context.Context) error {
+			_, err := Invoke(ctx, o.hub, emitEvents[T], &[]Event{evt})
+			return err
+		},
+		event: evt,
+	})
+}
+
+func (o *OpContext[T]) enqueueFollowUp(fu *followUp) error {
+	if o.state > stateInvokeAfter {
+		return ErrInvalidState
+	}
+	o.followUps = append(o.followUps, fu)
+	return nil
+}
+
+func (o *OpContext[T]) setState(state int) {
+	o.state = state
+	if o.flight != nil {
+		o.flight.state.Store(int32(state))
+	}
+}
+
 func (o *OpContext[T]) commit() error {
+	return o.commitChunk(true)
+}
+
+// commitChunk dispatches the operation's events and commits its
+// transaction, then runs its AfterFuncs and submits its follow-ups. Unless
+// final is true the operation then continues, its next call to Tx()
+// beginning a new transaction; see OpContext.Checkpoint().
+func (o *OpContext[T]) commitChunk(final bool) error {
 	if o.state != stateActive {
 		return ErrInvalidState
 	}
+	if o.guard != nil && o.guard.abandoned() {
+		// the enclosing operation has timed out, and rolls back the
+		// transaction itself
+		o.setState(stateFailed)
+		return ErrTimeout
+	}
 
-	o.state = stateDispatchEvents
+	o.setState(stateDispatchEvents)
 	if err := o.dispatchEvents(); err != nil {
-		o.state = stateFailed
+		o.setState(stateFailed)
 		if o.isTransactionActive() {
-			_ = o.activeTx.Rollback(o.Context)
+			_ = o.endTx(false, o.activeTx.Rollback, o)
 			// TODO: return appropriate error
 		}
 		return err
 	}
 
-	if o.isTransactionActive() {
-		txErr := o.activeTx.Commit(o.Context)
-		if txErr != nil {
-			o.state = stateFailed
-			return txErr
+	// The operation's context may have been cancelled, or its deadline
+	// exceeded, while the operation or its event handlers were running;
+	// the work must not be committed in this case, even if the operation
+	// itself did not observe the cancellation.
+	if err := o.Context.Err(); err != nil {
+		o.setState(stateFailed)
+		if o.isTransactionActive() {
+			_ = o.endTx(false, o.activeTx.Rollback, context.WithoutCancel(o))
+		}
+		return err
+	}
+
+	if o.shadow {
+		o.setState(stateRolledback)
+		var err error
+		if o.isTransactionActive() {
+			err = o.endTx(false, o.activeTx.Rollback, o)
+		}
+		if err == nil && !final {
+			o.resetChunk()
 		}
+		return err
+	}
+
+	var txErr error
+	if o.guard != nil {
+		txErr = o.guard.commit(o.commitTx)
+	} else {
+		txErr = o.commitTx()
+	}
+	if txErr != nil {
+		o.setState(stateFailed)
+		return txErr
 	}
 
-	o.state = stateInvokeAfter
+	o.setState(stateInvokeAfter)
 	o.invokeAfterFuncs()
+	if len(o.published) > 0 {
+		o.hub.subs.publish(o.published)
+	}
 
-	o.state = stateSuccess
+	o.setState(stateSuccess)
+	o.submitFollowUps()
+	if !final {
+		o.resetChunk()
+	}
 
 	return nil
 }
 
+// commitTx commits the operation's transaction, if active.
+func (o *OpContext[T]) commitTx() error {
+	if !o.isTransactionActive() {
+		return nil
+	}
+	return o.endTx(true, o.activeTx.Commit, o)
+}
+
+// resetChunk returns a committed operation to the active state, without a
+// transaction, so that it can continue with its next chunk of work.
+func (o *OpContext[T]) resetChunk() {
+	var zero T
+	o.activeTx, o.txActive, o.txBegan = zero, false, time.Time{}
+	o.after, o.published = nil, nil
+	o.emitDepth, o.emitted = 0, 0
+
+	// Values may be bound to the committed transaction - such as
+	// repositories constructed by providers - and locks without an Unlock
+	// were released along with it, so neither carries over to the next chunk.
+	o.values = nil
+	o.locks = slices.DeleteFunc(o.locks, func(l heldLock) bool { return l.unlock == nil })
+	o.setState(stateActive)
+}
+
 func (o *OpContext[T]) rollback() error {
 	if o.state != stateActive {
 		return ErrInvalidState
 	}
 
-	o.state = stateRolledback
+	o.setState(stateRolledback)
 
 	if o.isTransactionActive() {
-		return o.activeTx.Rollback(o.Context)
+		return o.endTx(false, o.activeTx.Rollback, o)
 	}
 
 	return nil
@@ -125,22 +438,97 @@ func (o *OpContext[T]) rollback() error {
 
 func (o *OpContext[T]) invokeAfterFuncs() {
 	for _, fn := range o.after {
-		fn(o)
+		_, err := invokeWithRecover(runAfterFunc[T], fn, o)
+		if err != nil {
+			o.hub.opts.onAfterFuncError(err)
+		}
+	}
+}
+
+func runAfterFunc[T Transaction](fn AfterFunc[T], o *OpContext[T]) (*struct{}, error) {
+	fn(o)
+	return nil, nil
+}
+
+func (o *OpContext[T]) submitFollowUps() {
+	if len(o.followUps) == 0 {
+		return
 	}
+	ctx := context.WithValue(context.WithoutCancel(o.Context), backgroundKey{}, true)
+	for _, fu := range o.followUps {
+		o.hub.life.active.Add(1)
+		o.hub.runBackground(ctx, fu, 1)
+	}
+	o.followUps = nil
 }
 
 func (o *OpContext[T]) dispatchEvents() error {
+	if o.cascadeErr != nil {
+		return o.cascadeErr
+	}
+	for {
+		if err := o.dispatchQueue(); err != nil {
+			return err
+		}
+		if len(o.batches) == 0 {
+			return nil
+		}
+		if err := o.flushBatches(); err != nil {
+			return err
+		}
+	}
+}
+
+func (o *OpContext[T]) dispatchQueue() error {
 	for len(o.events) > 0 {
-		evt := o.events[0]
+		qe := o.events[0]
 		o.events = o.events[1:]
-		if err := o.hub.dispatchEvent(o, evt); err != nil {
+		o.emitDepth = qe.depth + 1
+		o.dispatching, o.cause = qe, nil
+		if len(o.queuedKeys) > 0 {
+			if key, ok := o.hub.dedupKeyOf(qe.evt); ok {
+				delete(o.queuedKeys, key)
+			}
+		}
+		err := o.hub.dispatchEvent(o, qe.evt, qe.depth)
+		if o.cascadeErr != nil {
+			// the handler may have ignored the error
+			return o.cascadeErr
+		} else if err != nil {
 			return err
 		}
+		if o.hub.subs.active() {
+			o.published = append(o.published, qe.evt)
+		}
 	}
 	return nil
 }
 
+// endTx commits or rolls back the operation's transaction by calling fn,
+// reporting the transaction's statistics if the hub observes transactions.
+func (o *OpContext[T]) endTx(commit bool, fn func(context.Context) error, ctx context.Context) error {
+	if o.flight != nil {
+		defer o.flight.txOpen.Store(false)
+	}
+	if o.txBegan.IsZero() {
+		return fn(ctx)
+	}
+	clock := o.hub.opts.clock
+	start := clock.Now()
+	err := fn(ctx)
+	end := clock.Now()
+
+	stats := TxStats{
+		Held:      end.Sub(o.txBegan),
+		Committed: commit && err == nil,
+	}
+	if commit {
+		stats.Commit = end.Sub(start)
+	}
+	o.hub.observeTx(o, commit, stats, err)
+	return err
+}
+
 func (o *OpContext[T]) isTransactionActive() bool {
-	var zero T
-	return o.activeTx != zero
+	return o.txActive
 }
@@ -0,0 +1,299 @@
+package operator
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEmit_FromAfterFunc_Fails(t *testing.T) {
+	hub := newTestHub()
+
+	var emitErr error
+	_, err := Invoke(context.Background(), hub, func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) {
+		ctx.AfterFunc(func(ctx *OpContext[*TxTest]) {
+			emitErr = ctx.Emit(&testEvent{})
+		})
+		return in, nil
+	}, &struct{}{})
+
+	assert.Nil(t, err)
+	assert.ErrorIs(t, emitErr, ErrInvalidState)
+}
+
+func TestEmitAfterCommit_FromAfterFunc(t *testing.T) {
+	hub := newTestHub()
+
+	received := make(chan int, 1)
+	hub.RegisterEventHandler(&testEvent{}, func(evt *testEvent) {
+		received <- evt.Val
+	})
+
+	_, err := Invoke(context.Background(), hub, func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) {
+		ctx.AfterFunc(func(ctx *OpContext[*TxTest]) {
+			assert.Nil(t, ctx.EmitAfterCommit(&testEvent{Val: 42}))
+		})
+		return in, nil
+	}, &struct{}{})
+	assert.Nil(t, err)
+
+	select {
+	case v := <-received:
+		assert.Equal(t, 42, v)
+	case <-time.After(time.Second):
+		t.Fatal("after-commit event was not dispatched")
+	}
+}
+
+func TestInvokeAfterCommit_NotInvokedOnFailure(t *testing.T) {
+	hub := newTestHub()
+
+	invoked := make(chan struct{}, 1)
+	followUp := func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) {
+		invoked <- struct{}{}
+		return in, nil
+	}
+
+	_, err := Invoke(context.Background(), hub, func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) {
+		assert.Nil(t, InvokeAfterCommit(ctx, followUp, in))
+		return nil, assert.AnError
+	}, &struct{}{})
+	assert.ErrorIs(t, err, assert.AnError)
+
+	_, err = Invoke(context.Background(), hub, func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) {
+		assert.Nil(t, InvokeAfterCommit(ctx, followUp, in))
+		return in, nil
+	}, &struct{}{})
+	assert.Nil(t, err)
+
+	select {
+	case <-invoked:
+	case <-time.After(time.Second):
+		t.Fatal("follow-up operation was not invoked")
+	}
+
+	select {
+	case <-invoked:
+		t.Fatal("follow-up operation of failed operation was invoked")
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func namedTestOperation(ctx *OpContext[*TxTest], in *struct{}) (*OperationInfo, error) {
+	info, _ := OperationFrom(context.WithValue(ctx, ctxKey{}, 1))
+	return &info, nil
+}
+
+func TestOperationInfo(t *testing.T) {
+	hub := newTestHub()
+
+	out, err := Invoke(context.Background(), hub, namedTestOperation, &struct{}{})
+	assert.Nil(t, err)
+
+	info := *out
+	assert.Equal(t, "operator.namedTestOperation", info.Name())
+	assert.Equal(t, 32, len(info.ID()))
+
+	out2, _ := Invoke(context.Background(), hub, namedTestOperation, &struct{}{})
+	assert.NotEqual(t, info.ID(), (*out2).ID())
+
+	_, ok := OperationFrom(context.Background())
+	assert.False(t, ok)
+}
+
+type testPrincipal string
+
+func (p testPrincipal) Subject() string { return string(p) }
+
+func TestPrincipal(t *testing.T) {
+	hub := newTestHub()
+
+	var handlerSubject string
+	hub.RegisterEventHandler(&testEvent{}, func(ctx *OpContext[*TxTest], evt *testEvent) {
+		handlerSubject = ctx.Principal().Subject()
+	})
+
+	ctx := WithPrincipal(context.Background(), testPrincipal("alice"))
+	out, err := Invoke(ctx, hub, func(ctx *OpContext[*TxTest], in *struct{}) (*string, error) {
+		s := ctx.Principal().Subject()
+		return &s, ctx.Emit(&testEvent{})
+	}, &struct{}{})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "alice", *out)
+	assert.Equal(t, "alice", handlerSubject)
+
+	Invoke(context.Background(), hub, func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) {
+		assert.Nil(t, ctx.Principal())
+		return nil, nil
+	}, &struct{}{})
+}
+
+func TestOpContextNow(t *testing.T) {
+	clock := &manualClock{now: time.Unix(1000, 0)}
+	hub := newTestHub(WithClock(clock))
+
+	var opTime, handlerTime time.Time
+	hub.RegisterEventHandler(&testEvent{}, func(ctx *OpContext[*TxTest], evt *testEvent) error {
+		handlerTime = ctx.Now()
+		return nil
+	})
+
+	_, err := Invoke(context.Background(), hub, func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) {
+		opTime = ctx.Now()
+		clock.now = clock.now.Add(time.Minute)
+		assert.Equal(t, opTime, ctx.Now(), "operation time is captured once")
+		return in, ctx.Emit(&testEvent{})
+	}, &struct{}{})
+	assert.Nil(t, err)
+	assert.Equal(t, time.Unix(1000, 0), opTime)
+	assert.Equal(t, opTime, handlerTime)
+}
+
+// fill sets v, and everything it contains, to a non-zero value, so that a
+// field that is not propagated is detectable.
+func fill(t *testing.T, v reflect.Value) {
+	v = reflect.NewAt(v.Type(), unsafe.Pointer(v.UnsafeAddr())).Elem()
+	switch v.Kind() {
+	case reflect.Bool:
+		v.SetBool(true)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v.SetInt(1)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		v.SetUint(1)
+	case reflect.String:
+		v.SetString("x")
+	case reflect.Pointer:
+		v.Set(reflect.New(v.Type().Elem()))
+	case reflect.Func:
+		v.Set(reflect.MakeFunc(v.Type(), func([]reflect.Value) []reflect.Value { return nil }))
+	case reflect.Map:
+		v.Set(reflect.MakeMap(v.Type()))
+	case reflect.Slice:
+		v.Set(reflect.MakeSlice(v.Type(), 1, 1))
+		fill(t, v.Index(0))
+	case reflect.Array:
+		for i := range v.Len() {
+			fill(t, v.Index(i))
+		}
+	case reflect.Struct:
+		if v.Type() == reflect.TypeFor[time.Time]() {
+			v.Set(reflect.ValueOf(time.Unix(1, 0)))
+			return
+		}
+		for i := range v.NumField() {
+			fill(t, v.Field(i))
+		}
+	case reflect.Interface:
+		for _, c := range []any{context.Background(), assert.AnError, &testEvent{}, &timeoutRun[*TxTest]{}} {
+			if reflect.TypeOf(c).Implements(v.Type()) {
+				v.Set(reflect.ValueOf(c))
+				return
+			}
+		}
+		t.Fatalf("fill: no value for %s", v.Type())
+	default:
+		t.Fatalf("fill: unsupported kind %s", v.Kind())
+	}
+}
+
+// same returns true if a and b hold the same values, comparing pointers,
+// maps and functions by identity.
+func same(a, b reflect.Value) bool {
+	if a.Kind() != b.Kind() {
+		return false
+	}
+	switch a.Kind() {
+	case reflect.Pointer, reflect.Func, reflect.Map:
+		return a.Pointer() == b.Pointer()
+	case reflect.Interface:
+		if a.IsNil() || b.IsNil() {
+			return a.IsNil() == b.IsNil()
+		}
+		return a.Elem().Type() == b.Elem().Type() && same(a.Elem(), b.Elem())
+	case reflect.Slice, reflect.Array:
+		if a.Len() != b.Len() {
+			return false
+		}
+		for i := range a.Len() {
+			if !same(a.Index(i), b.Index(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Struct:
+		for i := range a.NumField() {
+			if !same(a.Field(i), b.Field(i)) {
+				return false
+			}
+		}
+		return true
+	default:
+		return a.Equal(b)
+	}
+}
+
+func TestOpContext_Child(t *testing.T) {
+	// fields child() does not share with the operation; every other field
+	// must be propagated
+	reset := map[string]bool{
+		"Context": true, "beginTransaction": true, "flight": true,
+		"events": true, "eventBuf": true, "after": true, "afterBuf": true,
+		"followUps": true, "batches": true, "published": true,
+	}
+	cloned := map[string]bool{"values": true, "locks": true, "queuedKeys": true}
+
+	var parent OpContext[*TxTest]
+	fill(t, reflect.ValueOf(&parent).Elem())
+	ctx := context.WithValue(context.Background(), reflect.TypeFor[int](), 1)
+	child := parent.child(ctx, func(context.Context) (*TxTest, error) { return nil, nil })
+
+	p, c := reflect.ValueOf(&parent).Elem(), reflect.ValueOf(child).Elem()
+	for i := range p.NumField() {
+		name := p.Type().Field(i).Name
+		switch {
+		case name == "Context":
+			assert.Equal(t, ctx, child.Context)
+		case name == "beginTransaction":
+			assert.NotNil(t, child.beginTransaction)
+		case reset[name]:
+			assert.True(t, c.Field(i).IsZero(), "%s should be reset", name)
+		case cloned[name]:
+			assert.Equal(t, p.Field(i).Len(), c.Field(i).Len(), "%s should be cloned", name)
+			assert.NotEqual(t, p.Field(i).Pointer(), c.Field(i).Pointer(), "%s should be cloned", name)
+		default:
+			assert.True(t, same(p.Field(i), c.Field(i)), "%s is not propagated to the child", name)
+		}
+	}
+}
+
+func TestOpContext_Adopt(t *testing.T) {
+	// fields the operation keeps when adopting a child; every other field
+	// must be taken from the child
+	kept := map[string]bool{
+		"Context": true, "beginTransaction": true, "state": true, "io": true,
+		"flight": true, "eventBuf": true, "afterBuf": true, "guard": true,
+	}
+
+	var parent, child OpContext[*TxTest]
+	parent.Context = context.Background()
+	fill(t, reflect.ValueOf(&child).Elem())
+	parent.adopt(&child)
+
+	p, c := reflect.ValueOf(&parent).Elem(), reflect.ValueOf(&child).Elem()
+	for i := range p.NumField() {
+		name := p.Type().Field(i).Name
+		switch {
+		case name == "Context":
+			assert.Equal(t, context.Background(), parent.Context)
+		case kept[name]:
+			assert.True(t, p.Field(i).IsZero(), "%s should be kept", name)
+		default:
+			assert.True(t, same(p.Field(i), c.Field(i)), "%s is not adopted from the child", name)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+// Package opclient is the runtime for Go clients generated by operator-gen
+// (see package codegen), which call operations bound with httpbind over HTTP.
+//
+//	c := api.NewClient(opclient.New("https://users.internal"))
+//	user, err := c.GetUser(ctx, &api.GetUserInput{ID: 1})
+//	if errors.Is(err, operr.ErrForbidden) {
+//		...
+//	}
+//
+// Error responses are returned as *Error, which matches the operr sentinel
+// errors that map to its status code, and unwraps to operr.FieldErrors when
+// the response reports invalid fields.
+package opclient
+
+import (
+	"bytes"
+	"context"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+
+	"github.com/jaz303/operator/operr"
+)
+
+// Client sends requests to an API.
+type Client struct {
+	// URL to which request paths are appended
+	BaseURL string
+
+	// HTTP client used to send requests; http.DefaultClient if nil
+	HTTPClient *http.Client
+
+	// Headers sent with every request
+	Header http.Header
+}
+
+// New returns a Client for the API at baseURL.
+func New(baseURL string) *Client {
+	return &Client{BaseURL: strings.TrimSuffix(baseURL, "/")}
+}
+
+// Request is a request to an operation.
+type Request struct {
+	Method string
+	Path   string
+	Query  url.Values
+	Header http.Header
+
+	// Value encoded as the JSON request body; no body is sent if nil, or
+	// for GET and HEAD requests
+	Body any
+}
+
+// Error is an error response.
+type Error struct {
+	StatusCode int
+
+	// Error message, and invalid fields, reported by the server
+	Message string
+	Fields  operr.FieldErrors
+
+	// Raw response body
+	Body []byte
+}
+
+func (e *Error) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("%d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+	}
+	return fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// Is reports whether target is an error that the server maps to e's status
+// code, such as operr.ErrConflict for 409 Conflict. Errors mapped to 500
+// Internal Server Error are not matched.
+func (e *Error) Is(target error) bool {
+	status := operr.StatusCode(target)
+	return status != http.StatusInternalServerError && status == e.StatusCode
+}
+
+// Unwrap returns the invalid fields reported by the server, if any.
+func (e *Error) Unwrap() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+	return e.Fields
+}
+
+// Do sends req, decoding a successful response's JSON body into out, if
+// non-nil. Non-2xx responses are returned as *Error.
+func (c *Client) Do(ctx context.Context, req *Request, out any) error {
+	u := c.BaseURL + req.Path
+	if len(req.Query) > 0 {
+		u += "?" + req.Query.Encode()
+	}
+
+	var body io.Reader
+	sendBody := req.Body != nil && req.Method != http.MethodGet && req.Method != http.MethodHead
+	if sendBody {
+		data, err := json.Marshal(req.Body)
+		if err != nil {
+			return fmt.Errorf("encoding request failed (%w)", err)
+		}
+		body = bytes.NewReader(data)
+	}
+
+	r, err := http.NewRequestWithContext(ctx, req.Method, u, body)
+	if err != nil {
+		return err
+	}
+	for k, v := range c.Header {
+		r.Header[k] = v
+	}
+	for k, v := range req.Header {
+		r.Header[k] = v
+	}
+	if sendBody {
+		r.Header.Set("Content-Type", "application/json")
+	}
+	r.Header.Set("Accept", "application/json")
+
+	hc := c.HTTPClient
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	res, err := hc.Do(r)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		e := &Error{StatusCode: res.StatusCode, Body: data}
+		var payload struct {
+			Error  string            `json:"error"`
+			Fields operr.FieldErrors `json:"fields"`
+		}
+		if json.Unmarshal(data, &payload) == nil {
+			e.Message, e.Fields = payload.Error, payload.Fields
+		}
+		return e
+	}
+	if out == nil || len(bytes.TrimSpace(data)) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("decoding response failed (%w)", err)
+	}
+	return nil
+}
+
+// Values formats v as parameter values: nil pointers produce none, slices
+// one per element, encoding.TextMarshalers their text, and other values
+// their fmt.Sprint form.
+func Values(v any) []string {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil
+		}
+		if _, ok := rv.Interface().(encoding.TextMarshaler); ok {
+			break
+		}
+		rv = rv.Elem()
+	}
+	if !rv.IsValid() {
+		return nil
+	}
+	if rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() != reflect.Uint8 {
+		out := make([]string, 0, rv.Len())
+		for i := range rv.Len() {
+			out = append(out, Values(rv.Index(i).Interface())...)
+		}
+		return out
+	}
+	if tm, ok := rv.Interface().(encoding.TextMarshaler); ok {
+		text, err := tm.MarshalText()
+		if err != nil {
+			return nil
+		}
+		return []string{string(text)}
+	}
+	return []string{fmt.Sprint(rv.Interface())}
+}
+
+// PathValue formats v as a path parameter, escaping it unless wildcard is
+// true, in which case each of its segments is escaped.
+func PathValue(v any, wildcard bool) string {
+	var s string
+	if vals := Values(v); len(vals) > 0 {
+		s = vals[0]
+	}
+	if !wildcard {
+		return url.PathEscape(s)
+	}
+	segments := strings.Split(s, "/")
+	for i, seg := range segments {
+		segments[i] = url.PathEscape(seg)
+	}
+	return strings.Join(segments, "/")
+}
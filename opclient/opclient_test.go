@@ -0,0 +1,75 @@
+package opclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jaz303/operator/operr"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDo(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		assert.Equal(t, "PUT", r.Method)
+		assert.Equal(t, "/users/a%2Fb", r.URL.EscapedPath())
+		assert.Equal(t, "x=1&x=2", r.URL.RawQuery)
+		assert.Equal(t, "token", r.Header.Get("Authorization"))
+		assert.Equal(t, "v1", r.Header.Get("If-Match"))
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.Equal(t, `{"name":"bob"}`, string(body))
+		w.Write([]byte(`{"id":1}`))
+	}))
+	defer srv.Close()
+
+	c := New(srv.URL + "/")
+	c.Header = http.Header{"Authorization": {"token"}}
+	var out struct {
+		ID int `json:"id"`
+	}
+	err := c.Do(context.Background(), &Request{
+		Method: "PUT",
+		Path:   "/users/" + PathValue("a/b", false),
+		Query:  map[string][]string{"x": Values([]int{1, 2})},
+		Header: http.Header{"If-Match": {"v1"}},
+		Body:   map[string]string{"name": "bob"},
+	}, &out)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, out.ID)
+}
+
+func TestDo_Error(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		operr.DefaultErrorMapper(w, operr.FieldErrors{{Field: "email", Message: "is required"}})
+	}))
+	defer srv.Close()
+
+	err := New(srv.URL).Do(context.Background(), &Request{Method: "GET", Path: "/"}, nil)
+	var e *Error
+	assert.True(t, errors.As(err, &e))
+	assert.Equal(t, http.StatusBadRequest, e.StatusCode)
+
+	var fields operr.FieldErrors
+	assert.True(t, errors.As(err, &fields))
+	assert.Equal(t, "email", fields[0].Field)
+
+	assert.False(t, errors.Is(err, operr.ErrConflict))
+	assert.True(t, errors.Is(&Error{StatusCode: http.StatusConflict}, operr.ErrConflict))
+	assert.False(t, errors.Is(&Error{StatusCode: http.StatusInternalServerError}, errors.New("x")))
+}
+
+func TestValues(t *testing.T) {
+	var nilPtr *int
+	n := 3
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	assert.Nil(t, Values(nilPtr))
+	assert.Equal(t, []string{"3"}, Values(&n))
+	assert.Equal(t, []string{"a", "b"}, Values([]string{"a", "b"}))
+	assert.Equal(t, []string{"2024-01-02T03:04:05Z"}, Values(ts))
+	assert.Equal(t, "a/b%20c", PathValue("a/b c", true))
+}
@@ -0,0 +1,164 @@
+// Package opdebug lets operators inspect a running service: the statistics
+// and in-flight operations of a hub, its event handlers, and the depth of its
+// admission and background queues. The same snapshot is available as an
+// expvar variable and from an HTTP handler protected by a token:
+//
+//	opdebug.Publish("operator", hub)
+//	mux.Handle("/debug/operator/", http.StripPrefix("/debug/operator",
+//		opdebug.Handler(hub, os.Getenv("DEBUG_TOKEN"))))
+//
+// Statistics and in-flight operations are only collected by hubs created
+// with operator.WithOperationStats() and operator.WithInFlightTracking()
+// respectively. Application-specific gauges, such as the depth of an outbox
+// table, may be added with WithValue.
+package opdebug
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"expvar"
+	"net/http"
+	"strings"
+
+	"github.com/jaz303/operator"
+	"github.com/jaz303/operator/codec"
+)
+
+// Snapshot describes the state of a hub at an instant.
+type Snapshot struct {
+	// Statistics for each operation invoked on the hub
+	Operations []operator.OperationStats `json:"operations"`
+
+	// Operations in progress
+	InFlight []operator.InFlightOperation `json:"in_flight"`
+
+	// Event handlers and subscriptions registered with the hub
+	Events []operator.EventHandlerInfo `json:"events"`
+
+	// Admission queue, by priority class, if the hub has admission control
+	Admission []operator.AdmissionStats `json:"admission,omitempty"`
+
+	// Background work queued and in progress
+	Background operator.BackgroundStats `json:"background"`
+
+	// Values added with WithValue
+	Values map[string]any `json:"values,omitempty"`
+}
+
+type config struct {
+	values map[string]func(ctx context.Context) any
+}
+
+// Option configures the values collected in a Snapshot.
+type Option func(c *config)
+
+// WithValue adds the value returned by fn, under name, to each Snapshot;
+// e.g. the number of unpublished rows in an outbox table. fn is called each
+// time a snapshot is collected, so should be quick.
+func WithValue(name string, fn func(ctx context.Context) any) Option {
+	return func(c *config) { c.values[name] = fn }
+}
+
+func configure(opts []Option) *config {
+	c := &config{values: map[string]func(context.Context) any{}}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Collect returns a snapshot of hub.
+func Collect[Tx operator.Transaction](ctx context.Context, hub *operator.Hub[Tx], opts ...Option) *Snapshot {
+	return collect(ctx, configure(opts), hub)
+}
+
+func collect[Tx operator.Transaction](ctx context.Context, c *config, hub *operator.Hub[Tx]) *Snapshot {
+	s := &Snapshot{
+		Operations: orEmpty(hub.Stats()),
+		InFlight:   orEmpty(hub.InFlight()),
+		Events:     orEmpty(hub.EventHandlers()),
+		Admission:  hub.AdmissionStats(),
+		Background: hub.BackgroundStats(),
+		Values:     make(map[string]any, len(c.values)),
+	}
+	for name, fn := range c.values {
+		s.Values[name] = fn(ctx)
+	}
+	return s
+}
+
+func orEmpty[T any](s []T) []T {
+	if s == nil {
+		return []T{}
+	}
+	return s
+}
+
+// Publish publishes a snapshot of hub as the expvar variable name, served
+// at /debug/vars by the expvar package's handler. Like expvar.Publish, it
+// panics if name is already in use.
+func Publish[Tx operator.Transaction](name string, hub *operator.Hub[Tx], opts ...Option) {
+	c := configure(opts)
+	expvar.Publish(name, expvar.Func(func() any {
+		return collect(context.Background(), c, hub)
+	}))
+}
+
+// Handler returns an HTTP handler serving snapshots of hub as JSON. Requests
+// must carry token as a bearer token ("Authorization: Bearer <token>"), and
+// are otherwise rejected with 401 Unauthorized. The handler serves the
+// complete snapshot at its root, and each part of it at /operations,
+// /in_flight, /events, /admission, /background and /values, relative to
+// the path at which it is mounted; strip any prefix with http.StripPrefix.
+//
+// Handler panics if token is empty.
+func Handler[Tx operator.Transaction](hub *operator.Hub[Tx], token string, opts ...Option) http.Handler {
+	if token == "" {
+		panic(errors.New("opdebug token must not be empty"))
+	}
+	c := configure(opts)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !authorized(r, token) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="opdebug"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		s := collect(r.Context(), c, hub)
+		var body any
+		switch strings.TrimSuffix(r.URL.Path, "/") {
+		case "":
+			body = s
+		case "/operations":
+			body = s.Operations
+		case "/in_flight":
+			body = s.InFlight
+		case "/events":
+			body = s.Events
+		case "/admission":
+			body = orEmpty(s.Admission)
+		case "/background":
+			body = s.Background
+		case "/values":
+			body = s.Values
+		default:
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Type", codec.JSON.ContentType())
+		w.Header().Set("Cache-Control", "no-store")
+		codec.JSON.Encode(w, body)
+	})
+}
+
+func authorized(r *http.Request, token string) bool {
+	got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1
+}
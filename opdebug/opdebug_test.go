@@ -0,0 +1,110 @@
+package opdebug
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/jaz303/operator"
+	"github.com/stretchr/testify/assert"
+)
+
+type nopTx struct{}
+
+func (nopTx) Commit(context.Context) error   { return nil }
+func (nopTx) Rollback(context.Context) error { return nil }
+
+type pinged struct{}
+
+func (*pinged) EventName() string { return "pinged" }
+
+func newTestHub(t *testing.T) *operator.Hub[nopTx] {
+	hub := operator.NewHub(func(context.Context) (nopTx, error) { return nopTx{}, nil },
+		operator.WithOperationStats(100), operator.WithInFlightTracking())
+	op := func(ctx *operator.OpContext[nopTx], in *struct{}) (*struct{}, error) {
+		return in, ctx.Emit(&pinged{})
+	}
+	assert.NoError(t, operator.RegisterOperation(hub, "ping", op))
+	assert.NoError(t, hub.RegisterEventHandler(&pinged{}, func(*pinged) {}, operator.HandlerName("audit")))
+	_, err := operator.Invoke(context.Background(), hub, op, &struct{}{})
+	assert.NoError(t, err)
+	return hub
+}
+
+func get(h http.Handler, path, token string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, path, nil)
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestHandler(t *testing.T) {
+	hub := newTestHub(t)
+	h := Handler(hub, "s3cret", WithValue("outbox", func(context.Context) any { return 3 }))
+
+	w := get(h, "/", "s3cret")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+
+	var s Snapshot
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &s))
+	if assert.Len(t, s.Operations, 1) {
+		assert.Equal(t, "ping", s.Operations[0].Name)
+		assert.Equal(t, int64(1), s.Operations[0].Count)
+	}
+	assert.Empty(t, s.InFlight)
+	if assert.Len(t, s.Events, 1) {
+		assert.Equal(t, "pinged", s.Events[0].Event)
+		assert.Equal(t, "audit", s.Events[0].Name)
+	}
+	assert.Nil(t, s.Admission)
+	assert.Equal(t, float64(3), s.Values["outbox"])
+	assert.Positive(t, s.Background.Workers)
+
+	w = get(h, "/operations", "s3cret")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var ops []operator.OperationStats
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &ops))
+	assert.Len(t, ops, 1)
+
+	assert.JSONEq(t, `[]`, get(h, "/admission", "s3cret").Body.String())
+	assert.JSONEq(t, `{"outbox":3}`, get(h, "/values/", "s3cret").Body.String())
+	assert.Equal(t, http.StatusNotFound, get(h, "/missing", "s3cret").Code)
+}
+
+func TestHandler_Unauthorized(t *testing.T) {
+	h := Handler(newTestHub(t), "s3cret")
+
+	assert.Equal(t, http.StatusUnauthorized, get(h, "/", "").Code)
+	assert.Equal(t, http.StatusUnauthorized, get(h, "/", "wrong").Code)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+	r.Header.Set("Authorization", "Bearer s3cret")
+	h.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+
+	assert.Panics(t, func() { Handler(newTestHub(t), "") })
+}
+
+// published counts TestPublish runs; expvar names can not be reused within
+// a process, e.g. with go test -count=2.
+var published atomic.Int32
+
+func TestPublish(t *testing.T) {
+	name := fmt.Sprintf("%s_%d", t.Name(), published.Add(1))
+	Publish(name, newTestHub(t))
+
+	var s Snapshot
+	assert.NoError(t, json.Unmarshal([]byte(expvar.Get(name).String()), &s))
+	assert.Len(t, s.Operations, 1)
+	assert.Panics(t, func() { Publish(name, newTestHub(t)) })
+}
@@ -0,0 +1,235 @@
+package operator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// ErrForbidden is returned by Invoke() when the principal on whose behalf an
+// operation is invoked does not satisfy the operation's policies.
+var ErrForbidden = errors.New("forbidden")
+
+var (
+	// ErrUnknownOperation is returned by Hub.InvokeJSON() when no operation
+	// is registered with the given name.
+	ErrUnknownOperation = errors.New("unknown operation")
+
+	// ErrInvalidInput is returned by Hub.InvokeJSON() when the input cannot
+	// be decoded.
+	ErrInvalidInput = errors.New("invalid operation input")
+
+	// ErrOperationRegistered is returned by RegisterOperation() when the
+	// operation, or the name, is already registered.
+	ErrOperationRegistered = errors.New("operation already registered")
+)
+
+// Policy authorizes an invocation of an operation. p is the principal on
+// whose behalf the operation is being invoked, or nil if the invocation is
+// unauthenticated. A non-nil error prevents the operation from running.
+type Policy func(ctx context.Context, p Principal) error
+
+// PermissionHolder can be implemented by a Principal to support
+// Hub.RequirePermission().
+type PermissionHolder interface {
+	HasPermission(permission string) bool
+}
+
+// operationInfo holds the hub's configuration for a single operation.
+type operationInfo struct {
+	name     string
+	input    reflect.Type
+	output   reflect.Type
+	policies []Policy
+	bulkhead *bulkhead
+
+	// where the operation was registered
+	site string
+
+	// invokes the operation with JSON-encoded input, for InvokeJSON() and
+	// requeueing dead letters; set for registered operations only
+	invokeJSON func(ctx context.Context, input []byte) error
+}
+
+// RegisterOperation() assigns an explicit name to op. The name is reported
+// by OpContext.Name() and used to refer to the operation when configuring the
+// hub, e.g. with RequirePermission(). Operations that are not registered are
+// named after their Go function; see OperationInfo.
+//
+// Operations created by wrappers such as WithTimeout() are identified by the
+// wrapper and its arguments, so an operation registered as
+// WithTimeout(op, time.Second) keeps its name, and policies, when the
+// wrapper is built again with the same arguments.
+//
+// Operations may be registered, and configured, while others are being
+// invoked; invocations already in progress are unaffected.
+//
+// Returns an error wrapping ErrOperationRegistered if op or name is already
+// registered, or ErrHubFrozen if the hub has been frozen.
+func RegisterOperation[Tx Transaction, I any, O any](hub *Hub[Tx], name string, op Operation[Tx, I, O]) error {
+	return hub.registerOperation(op, name, reflect.TypeFor[I](), reflect.TypeFor[O](), func(ctx context.Context, data []byte) error {
+		input := new(I)
+		if err := json.Unmarshal(data, input); err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidInput, err)
+		}
+		_, err := Invoke(ctx, hub, op, input)
+		return err
+	})
+}
+
+// RegisterTxOperation() assigns an explicit name to op; see RegisterOperation().
+func RegisterTxOperation[Tx Transaction, I any, O any](hub *Hub[Tx], name string, op TxOperation[Tx, I, O]) error {
+	return hub.registerOperation(op, name, reflect.TypeFor[I](), reflect.TypeFor[O](), func(ctx context.Context, data []byte) error {
+		input := new(I)
+		if err := json.Unmarshal(data, input); err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidInput, err)
+		}
+		_, err := InvokeTx(ctx, hub, op, input)
+		return err
+	})
+}
+
+func (h *Hub[Tx]) registerOperation(op any, name string, in, out reflect.Type, invokeJSON func(context.Context, []byte) error) error {
+	if h.frozen.Load() {
+		return ErrHubFrozen
+	}
+	key := operationKey[Tx](op)
+	site := callSite(1)
+	return h.operations.update(name, func(t *operationTable, info *operationInfo) error {
+		if existing, ok := t.names[key]; ok {
+			return fmt.Errorf("%w: operation %q is already registered as %q", ErrOperationRegistered, name, existing)
+		}
+		if info.input != nil {
+			return fmt.Errorf("%w: operation %q", ErrOperationRegistered, name)
+		}
+		info.input, info.output, info.invokeJSON = in, out, invokeJSON
+		info.site = site
+		t.names[key] = name
+		return nil
+	})
+}
+
+// InvokeJSON() invokes the operation registered as name with input decoded
+// from JSON, discarding its output. It allows operations to be invoked by
+// name from sources that carry no Go type information, such as message
+// queues and job runners.
+//
+// Returns an error wrapping ErrUnknownOperation if no operation is registered
+// as name, or ErrInvalidInput if input cannot be decoded; otherwise returns
+// the operation's error.
+func (h *Hub[Tx]) InvokeJSON(ctx context.Context, name string, input []byte) error {
+	info := h.operations.lookup(name)
+	if info == nil || info.invokeJSON == nil {
+		return fmt.Errorf("%w %s", ErrUnknownOperation, name)
+	}
+	return info.invokeJSON(ctx, input)
+}
+
+// OperationDescriptor describes an operation registered with
+// RegisterOperation() or RegisterTxOperation().
+type OperationDescriptor struct {
+	Name string
+
+	// Types of the operation's input and output, with the pointer removed;
+	// i.e. I and O
+	Input  reflect.Type
+	Output reflect.Type
+
+	// File and line at which the operation was registered
+	Site string
+
+	// Number of policies the operation is subject to; see SetPolicy()
+	Policies int
+}
+
+// Operations() returns the operations registered with the hub, ordered by
+// name, for use by schema generation and documentation tooling.
+func (h *Hub[Tx]) Operations() []OperationDescriptor {
+	infos := h.operations.load().infos
+	out := make([]OperationDescriptor, 0, len(infos))
+	for name, info := range infos {
+		if info.input != nil {
+			out = append(out, OperationDescriptor{
+				Name:     name,
+				Input:    info.input,
+				Output:   info.output,
+				Site:     info.site,
+				Policies: len(info.policies),
+			})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// SetPolicy() adds a policy that must be satisfied before the named operation
+// is invoked. An operation may have multiple policies; all must pass. The
+// name is either the operation's registered name or, for operations that have
+// not been registered, the name derived from its Go function.
+//
+// Returns ErrHubFrozen if the hub has been frozen.
+func (h *Hub[Tx]) SetPolicy(operation string, policy Policy) error {
+	if h.frozen.Load() {
+		return ErrHubFrozen
+	}
+	return h.operations.update(operation, func(_ *operationTable, info *operationInfo) error {
+		info.policies = append(info.policies, policy)
+		return nil
+	})
+}
+
+// RequirePermission() requires the principal invoking the named operation to
+// hold each of the given permissions. The principal must implement
+// PermissionHolder; unauthenticated invocations, and principals that do not
+// implement PermissionHolder, are rejected with ErrForbidden.
+//
+// Returns ErrHubFrozen if the hub has been frozen.
+func (h *Hub[Tx]) RequirePermission(operation string, permissions ...string) error {
+	return h.SetPolicy(operation, func(ctx context.Context, p Principal) error {
+		holder, ok := p.(PermissionHolder)
+		if !ok {
+			return fmt.Errorf("%w: operation %s requires an authorized principal", ErrForbidden, operation)
+		}
+		for _, perm := range permissions {
+			if !holder.HasPermission(perm) {
+				return fmt.Errorf("%w: operation %s requires permission %q", ErrForbidden, operation, perm)
+			}
+		}
+		return nil
+	})
+}
+
+// lookupOperation returns the name and configuration for op. info is nil if
+// op has neither been registered nor configured (e.g. with SetPolicy()).
+func (h *Hub[Tx]) lookupOperation(op any) (string, *operationInfo) {
+	key, name := funcKey(op), ""
+	if w, ok := describeWrapper[Tx](op); ok {
+		key, name = opKey{wrapper: w.key}, w.name
+	}
+	t := h.operations.load()
+	if registered, ok := t.names[key]; ok {
+		name = registered
+	} else if name == "" {
+		name = shortName(op)
+	}
+	return name, t.infos[name]
+}
+
+// authorize evaluates the operation's policies against its principal.
+func (o *OpContext[T]) authorize(info *operationInfo) error {
+	if info == nil {
+		return nil
+	}
+	for _, policy := range info.policies {
+		if err := policy(o, o.Principal()); err != nil {
+			if !errors.Is(err, ErrForbidden) {
+				err = fmt.Errorf("%w: %w", ErrForbidden, err)
+			}
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package operator
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// OperationInfo identifies an in-progress operation. It is implemented by
+// every *OpContext[Tx], and can be retrieved from any context derived from
+// an OpContext using OperationFrom(), allowing code that is not generic over
+// the transaction type (loggers, database adapters etc.) to attribute work
+// to the operation that performed it.
+type OperationInfo interface {
+	// Unique identifier of this operation invocation
+	ID() string
+
+	// Name of the operation
+	Name() string
+}
+
+type operationInfoKey struct{}
+
+// OperationFrom returns the operation with which ctx is associated, if any.
+func OperationFrom(ctx context.Context) (OperationInfo, bool) {
+	op, ok := ctx.Value(operationInfoKey{}).(OperationInfo)
+	return op, ok
+}
+
+func newOperationID() string {
+	var buf [16]byte
+	rand.Read(buf[:])
+	return hex.EncodeToString(buf[:])
+}
+
+// operationName derives a name for an operation function from its
+// fully-qualified Go name, stripping the package path; e.g. the function
+// CreateUser in package github.com/acme/app/users is named "users.CreateUser".
+//
+// Operations returned by wrappers such as WithTimeout() are named after the
+// operations they wrap, e.g. "WithTimeout(users.CreateUser)".
+func operationName[Tx Transaction](fn any) string {
+	if w, ok := describeWrapper[Tx](fn); ok {
+		return w.name
+	}
+	return shortName(fn)
+}
+
+func shortName(fn any) string {
+	name := funcName(reflect.ValueOf(fn))
+	if ix := strings.LastIndex(name, "/"); ix >= 0 {
+		name = name[ix+1:]
+	}
+	return name
+}
+
+// operationKey identifies op for registration. Functions are identified by
+// their code and type, so every evaluation of a function, method value or
+// function literal is the same operation. Operations created by wrappers are
+// identified by the wrapper and its arguments, so that WithTimeout(op, d)
+// is the same operation each time it is built, but differs from
+// WithTimeout(op, 2*d).
+func operationKey[Tx Transaction](op any) opKey {
+	if w, ok := describeWrapper[Tx](op); ok {
+		return opKey{wrapper: w.key}
+	}
+	return funcKey(op)
+}
+
+// opKey identifies an operation; see operationKey().
+type opKey struct {
+	code    uintptr
+	typ     reflect.Type
+	wrapper string
+}
+
+func funcKey(fn any) opKey {
+	v := reflect.ValueOf(fn)
+	return opKey{code: v.Pointer(), typ: v.Type()}
+}
+
+func (k opKey) String() string {
+	if k.typ == nil {
+		return k.wrapper
+	}
+	return fmt.Sprintf("%s@%#x", k.typ, k.code)
+}
+
+// namedOp describes an operation created by a wrapper; see wrapOperation().
+type namedOp struct {
+	// identifies the wrapper and its arguments; see operationKey()
+	key string
+
+	// name of the operation unless registered; see operationName()
+	name string
+}
+
+// wrapOperation returns run as an operation created by the wrapper named
+// wrapper from the operations wrapped, and configured with args. The
+// operation carries its description, which describeWrapper() retrieves.
+func wrapOperation[Tx Transaction, I any, O any](wrapper string, wrapped []any, args []any, run Operation[Tx, I, O]) Operation[Tx, I, O] {
+	names := make([]string, len(wrapped))
+	keys := make([]string, 0, len(wrapped)+len(args))
+	for i, op := range wrapped {
+		names[i] = operationName[Tx](op)
+		keys = append(keys, operationKey[Tx](op).String())
+	}
+	for _, a := range args {
+		if reflect.TypeOf(a).Kind() == reflect.Func {
+			keys = append(keys, funcKey(a).String())
+		} else {
+			keys = append(keys, fmt.Sprint(a))
+		}
+	}
+	w := &namedOp{
+		key:  wrapper + "(" + strings.Join(keys, ", ") + ")",
+		name: wrapper + "(" + strings.Join(names, ", ") + ")",
+	}
+	return func(ctx *OpContext[Tx], input *I) (*O, error) {
+		if ctx.describe != nil {
+			*ctx.describe = *w
+			return nil, nil
+		}
+		return run(ctx, input)
+	}
+}
+
+var wrapperFuncPrefix = reflect.TypeFor[namedOp]().PkgPath() + ".wrapOperation["
+
+// describeWrapper returns the description of op, if it was created by
+// wrapOperation(), by invoking it with an OpContext that asks for it.
+func describeWrapper[Tx Transaction](op any) (namedOp, bool) {
+	v := reflect.ValueOf(op)
+	if v.Kind() != reflect.Func || v.IsNil() || !strings.HasPrefix(funcName(v), wrapperFuncPrefix) {
+		return namedOp{}, false
+	}
+	if v.Type().NumIn() != 2 || v.Type().In(0) != reflect.TypeFor[*OpContext[Tx]]() {
+		return namedOp{}, false
+	}
+	w := new(namedOp)
+	v.Call([]reflect.Value{reflect.ValueOf(&OpContext[Tx]{describe: w}), reflect.Zero(v.Type().In(1))})
+	return *w, true
+}
@@ -0,0 +1,77 @@
+package operator
+
+import (
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// operationRegistry maps operation names to their configuration, and
+// registered operations to their names.
+//
+// Like the event registry, it is copy-on-write: registration and
+// configuration build a new table under a mutex and publish it atomically,
+// while invocations read the current table with a single atomic load.
+// Tables, and the configurations they hold, are never mutated once
+// published, so operations may be registered and configured while others are
+// being invoked (though invocations already in progress may not see the
+// change).
+type operationRegistry struct {
+	mu    sync.Mutex
+	table atomic.Pointer[operationTable]
+}
+
+type operationTable struct {
+	// configuration by operation name
+	infos map[string]*operationInfo
+
+	// names of registered operations; see operationKey()
+	names map[opKey]string
+}
+
+func (r *operationRegistry) load() *operationTable {
+	if t := r.table.Load(); t != nil {
+		return t
+	}
+	return &operationTable{}
+}
+
+// lookup returns the configuration for the named operation, or nil if it
+// has been neither registered nor configured.
+func (r *operationRegistry) lookup(name string) *operationInfo {
+	return r.load().infos[name]
+}
+
+// update calls fn with a copy of the table and of the configuration for the
+// named operation, which it may modify, and publishes them unless fn returns
+// an error.
+func (r *operationRegistry) update(name string, fn func(t *operationTable, info *operationInfo) error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	old := r.load()
+	info := &operationInfo{name: name}
+	if prev := old.infos[name]; prev != nil {
+		*info = *prev
+		// clip so that appending does not write to the published slice
+		info.policies = slices.Clip(info.policies)
+	}
+
+	next := &operationTable{
+		infos: make(map[string]*operationInfo, len(old.infos)+1),
+		names: make(map[opKey]string, len(old.names)+1),
+	}
+	for k, v := range old.infos {
+		next.infos[k] = v
+	}
+	for k, v := range old.names {
+		next.names[k] = v
+	}
+	if err := fn(next, info); err != nil {
+		return err
+	}
+	next.infos[name] = info
+
+	r.table.Store(next)
+	return nil
+}
@@ -0,0 +1,168 @@
+package operator
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"runtime"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type permittedPrincipal []string
+
+func (p permittedPrincipal) Subject() string { return "test" }
+
+func (p permittedPrincipal) HasPermission(perm string) bool { return slices.Contains(p, perm) }
+
+func deleteUser(ctx *OpContext[*TxTest], in *struct{}) (*string, error) {
+	name := ctx.Name()
+	return &name, nil
+}
+
+func TestRegisterOperation(t *testing.T) {
+	hub := newTestHub()
+	RegisterOperation(hub, "users.delete", deleteUser)
+
+	out, err := Invoke(context.Background(), hub, deleteUser, &struct{}{})
+	assert.Nil(t, err)
+	assert.Equal(t, "users.delete", *out)
+
+	assert.ErrorIs(t, RegisterOperation(hub, "users.remove", deleteUser), ErrOperationRegistered)
+	assert.ErrorIs(t, RegisterOperation(hub, "users.delete", func(ctx *OpContext[*TxTest], in *struct{}) (*string, error) { return nil, nil }), ErrOperationRegistered)
+
+	hub.Freeze()
+	assert.ErrorIs(t, RegisterOperation(hub, "users.purge", namedTestOperation), ErrHubFrozen)
+}
+
+type userService struct{}
+
+func (userService) Delete(ctx *OpContext[*TxTest], in *struct{}) (*string, error) {
+	return deleteUser(ctx, in)
+}
+
+func TestRegisterOperation_Wrapped(t *testing.T) {
+	hub := newTestHub()
+	short := WithTimeout(deleteUser, time.Second)
+	long := WithTimeout(deleteUser, time.Minute)
+	assert.NoError(t, RegisterOperation(hub, "users.delete.short", short))
+	assert.NoError(t, RegisterOperation(hub, "users.delete.long", long))
+
+	out, err := Invoke(context.Background(), hub, short, &struct{}{})
+	assert.NoError(t, err)
+	assert.Equal(t, "users.delete.short", *out)
+	out, err = Invoke(context.Background(), hub, long, &struct{}{})
+	assert.NoError(t, err)
+	assert.Equal(t, "users.delete.long", *out)
+
+	// wrappers built again with the same arguments are the same operation
+	out, err = Invoke(context.Background(), hub, WithTimeout(deleteUser, time.Second), &struct{}{})
+	assert.NoError(t, err)
+	assert.Equal(t, "users.delete.short", *out)
+	assert.ErrorIs(t, RegisterOperation(hub, "users.delete.again", WithTimeout(deleteUser, time.Minute)), ErrOperationRegistered)
+
+	// unregistered wrappers are named after the operations they wrap
+	out, err = Invoke(context.Background(), hub, WithTimeout(deleteUser, time.Hour), &struct{}{})
+	assert.NoError(t, err)
+	assert.Equal(t, "WithTimeout(operator.deleteUser)", *out)
+	out, err = Invoke(context.Background(), hub, Compose(deleteUser, func(s *string) (*struct{}, error) { return &struct{}{}, nil }, deleteUser), &struct{}{})
+	assert.NoError(t, err)
+	assert.Equal(t, "Compose(operator.deleteUser, operator.deleteUser)", *out)
+
+	// method values are identified by method, not by receiver
+	svc := userService{}
+	assert.NoError(t, RegisterOperation(hub, "users.remove", svc.Delete))
+	out, err = Invoke(context.Background(), hub, svc.Delete, &struct{}{})
+	assert.NoError(t, err)
+	assert.Equal(t, "users.remove", *out)
+}
+
+func TestRequirePermission_RebuiltWrapper(t *testing.T) {
+	hub := newTestHub()
+	assert.NoError(t, hub.RequirePermission("users.delete", "admin"))
+	assert.NoError(t, RegisterOperation(hub, "users.delete", WithTimeout(deleteUser, time.Second)))
+
+	_, err := Invoke(context.Background(), hub, WithTimeout(deleteUser, time.Second), &struct{}{})
+	assert.ErrorIs(t, err, ErrForbidden)
+
+	out, err := Invoke(WithPrincipal(context.Background(), permittedPrincipal{"admin"}), hub, WithTimeout(deleteUser, time.Second), &struct{}{})
+	assert.NoError(t, err)
+	assert.Equal(t, "users.delete", *out)
+
+	// other arguments make another operation
+	_, err = Invoke(context.Background(), hub, WithTimeout(deleteUser, time.Minute), &struct{}{})
+	assert.NoError(t, err)
+}
+
+func TestRequirePermission(t *testing.T) {
+	hub := newTestHub()
+	hub.RequirePermission("users.delete", "admin")
+	RegisterOperation(hub, "users.delete", deleteUser)
+
+	invoke := func(ctx context.Context) error {
+		_, err := Invoke(ctx, hub, deleteUser, &struct{}{})
+		return err
+	}
+
+	assert.ErrorIs(t, invoke(context.Background()), ErrForbidden)
+	assert.ErrorIs(t, invoke(WithPrincipal(context.Background(), testPrincipal("alice"))), ErrForbidden)
+	assert.ErrorIs(t, invoke(WithPrincipal(context.Background(), permittedPrincipal{"user"})), ErrForbidden)
+	assert.Nil(t, invoke(WithPrincipal(context.Background(), permittedPrincipal{"user", "admin"})))
+}
+
+func TestSetPolicy_UnregisteredOperation(t *testing.T) {
+	hub := newTestHub()
+
+	op := func(ctx *OpContext[*TxTest], tx *TxTest, in *struct{}) (*struct{}, error) {
+		return in, nil
+	}
+	hub.SetPolicy("operator.namedTestOperation", func(ctx context.Context, p Principal) error {
+		return assert.AnError
+	})
+
+	_, err := Invoke(context.Background(), hub, namedTestOperation, &struct{}{})
+	assert.ErrorIs(t, err, ErrForbidden)
+	assert.ErrorIs(t, err, assert.AnError)
+
+	_, err = InvokeTx(context.Background(), hub, op, &struct{}{})
+	assert.Nil(t, err)
+}
+
+func TestHubInvokeJSON(t *testing.T) {
+	hub := newTestHub()
+	var got int
+	assert.NoError(t, RegisterOperation(hub, "set", func(ctx *OpContext[*TxTest], in *struct{ N int }) (*struct{}, error) {
+		got = in.N
+		return &struct{}{}, nil
+	}))
+
+	assert.Nil(t, hub.InvokeJSON(context.Background(), "set", []byte(`{"N":3}`)))
+	assert.Equal(t, 3, got)
+	assert.ErrorIs(t, hub.InvokeJSON(context.Background(), "set", []byte(`{`)), ErrInvalidInput)
+	assert.ErrorIs(t, hub.InvokeJSON(context.Background(), "missing", nil), ErrUnknownOperation)
+}
+
+func TestHubOperations(t *testing.T) {
+	hub := newTestHub()
+	type out struct{ ID int }
+	RegisterOperation(hub, "b", func(ctx *OpContext[*TxTest], in *struct{ N int }) (*out, error) { return nil, nil })
+	_, file, line, _ := runtime.Caller(0)
+	RegisterTxOperation(hub, "a", func(ctx *OpContext[*TxTest], tx *TxTest, in *string) (*int, error) { return nil, nil })
+	hub.SetPolicy("unregistered", func(context.Context, Principal) error { return nil })
+	hub.SetPolicy("a", func(context.Context, Principal) error { return nil })
+
+	ops := hub.Operations()
+	assert.Len(t, ops, 2)
+	assert.Equal(t, OperationDescriptor{
+		Name:     "a",
+		Input:    reflect.TypeFor[string](),
+		Output:   reflect.TypeFor[int](),
+		Site:     fmt.Sprintf("%s:%d", file, line+1),
+		Policies: 1,
+	}, ops[0])
+	assert.Equal(t, "b", ops[1].Name)
+	assert.Equal(t, reflect.TypeFor[out](), ops[1].Output)
+}
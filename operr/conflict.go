@@ -0,0 +1,29 @@
+package operr
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrConflict = errors.New("version conflict")
+
+// ConflictError is returned when an update is based on a version of a
+// resource other than the current one; see the concurrency package. It is
+// mapped to 409 Conflict by StatusCode() or, if the expected version was
+// given by an If-Match header, to 412 Precondition Failed.
+type ConflictError struct {
+	Current  string
+	Expected string
+
+	// True if Expected was given by an If-Match header, in which case the
+	// error also matches ErrPreconditionFailed
+	IfMatch bool
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%s; expected version %s, current version is %s", ErrConflict, e.Expected, e.Current)
+}
+
+func (e *ConflictError) Is(target error) bool {
+	return target == ErrConflict || (e.IfMatch && target == ErrPreconditionFailed)
+}
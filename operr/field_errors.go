@@ -0,0 +1,36 @@
+package operr
+
+import (
+	"strings"
+)
+
+// FieldError describes a problem with a single input field.
+type FieldError struct {
+	// Name of the field, as it appears in the request
+	Field string `json:"field"`
+
+	// Where the field was read from, e.g. "query", "path", "header", "body"
+	Source string `json:"source,omitempty"`
+
+	Message string `json:"message"`
+}
+
+func (e FieldError) Error() string {
+	if e.Source != "" {
+		return e.Source + " " + e.Field + ": " + e.Message
+	}
+	return e.Field + ": " + e.Message
+}
+
+// FieldErrors aggregates problems with multiple input fields. It is mapped
+// to 400 Bad Request by StatusCode(), and the default error mapper includes
+// the individual errors in its response.
+type FieldErrors []FieldError
+
+func (e FieldErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, fe := range e {
+		msgs[i] = fe.Error()
+	}
+	return "invalid input: " + strings.Join(msgs, "; ")
+}
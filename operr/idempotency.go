@@ -0,0 +1,15 @@
+package operr
+
+import "errors"
+
+var (
+	// ErrIdempotencyKeyInProgress is returned when a request's idempotency
+	// key is reserved by a request that has not yet completed. It is mapped
+	// to 409 Conflict by StatusCode().
+	ErrIdempotencyKeyInProgress = errors.New("idempotency key in use by a request in progress")
+
+	// ErrIdempotencyKeyReused is returned when a request's idempotency key
+	// was used by a request with a different method, path or body. It is
+	// mapped to 422 Unprocessable Entity by StatusCode().
+	ErrIdempotencyKeyReused = errors.New("idempotency key reused for a different request")
+)
@@ -1,20 +1,91 @@
 package operr
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"math"
 	"net/http"
+	"strconv"
+
+	"github.com/jaz303/operator"
+	"github.com/jaz303/operator/codec"
+	"github.com/jaz303/operator/tenancy"
 )
 
 var (
-	ErrInputMappingFailed = errors.New("input mapping failed")
-	ErrOperationFailed    = errors.New("operation failed")
+	ErrUnauthenticated     = errors.New("authentication required")
+	ErrAuthorizationFailed = errors.New("authorization failed")
+	ErrInputMappingFailed  = errors.New("input mapping failed")
+	ErrOperationFailed     = errors.New("operation failed")
+	ErrPreconditionFailed  = errors.New("precondition failed")
+
+	// ErrForbidden is returned when an operation's policies reject its
+	// principal; see operator.Hub.SetPolicy().
+	ErrForbidden = operator.ErrForbidden
+
+	// ErrUnavailable is returned when an operation is rejected because a
+	// dependency is failing; see operator.WithCircuitBreaker().
+	ErrUnavailable = operator.ErrUnavailable
+
+	// ErrTimeout is returned when an operation, or the request invoking it,
+	// exceeds its deadline; see operator.WithTimeout().
+	ErrTimeout = operator.ErrTimeout
+
+	// ErrShuttingDown is returned when an operation is invoked on a hub that
+	// is shutting down; see operator.Hub.Shutdown().
+	ErrShuttingDown = operator.ErrShuttingDown
 )
 
+// StatusCode returns the HTTP status code appropriate for err.
+func StatusCode(err error) int {
+	var fieldErrs FieldErrors
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &fieldErrs):
+		return http.StatusBadRequest
+	case errors.As(err, &tooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, tenancy.ErrUnresolved):
+		return http.StatusBadRequest
+	case errors.Is(err, codec.ErrUnsupportedMediaType):
+		return http.StatusUnsupportedMediaType
+	case errors.Is(err, codec.ErrNotAcceptable):
+		return http.StatusNotAcceptable
+	case errors.Is(err, ErrUnauthenticated):
+		return http.StatusUnauthorized
+	case errors.Is(err, ErrAuthorizationFailed), errors.Is(err, ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, ErrPreconditionFailed):
+		return http.StatusPreconditionFailed
+	case errors.Is(err, ErrConflict), errors.Is(err, ErrIdempotencyKeyInProgress):
+		return http.StatusConflict
+	case errors.Is(err, ErrIdempotencyKeyReused):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, ErrRateLimited):
+		return http.StatusTooManyRequests
+	case errors.Is(err, ErrUnavailable), errors.Is(err, ErrShuttingDown):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, ErrTimeout), errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
 func DefaultErrorMapper(w http.ResponseWriter, err error) {
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusInternalServerError)
-	json.NewEncoder(w).Encode(map[string]any{
+	var rateLimited *RateLimitError
+	if errors.As(err, &rateLimited) {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(rateLimited.RetryAfter.Seconds()))))
+	}
+	w.WriteHeader(StatusCode(err))
+	body := map[string]any{
 		"error": err.Error(),
-	})
+	}
+	var fieldErrs FieldErrors
+	if errors.As(err, &fieldErrs) {
+		body["fields"] = fieldErrs
+	}
+	json.NewEncoder(w).Encode(body)
 }
@@ -0,0 +1,536 @@
+package opgraph
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const operatorPath = "github.com/jaz303/operator"
+
+// Analyze loads the packages matching patterns, as understood by "go list",
+// relative to dir, and returns the graph of the operations and event handlers
+// they define, and the events those emit. Test files are not analyzed.
+//
+// Operations are the functions passed where an operator.Operation or
+// operator.TxOperation is expected - to operator.Invoke(), say, or an HTTP
+// binding - and are named as the hub names them: by the name given to
+// RegisterOperation(), or otherwise after the Go function. Handlers are the
+// functions passed to Hub.RegisterEventHandler() and the like, named by
+// their HandlerName() option or after the Go function.
+//
+// The events a function emits are those passed to OpContext.Emit(),
+// EmitAfterCommit() and EmitNamed() in its body, or in the bodies of the
+// functions of the analyzed packages that it calls, directly or indirectly.
+// Events whose type or name is only known at run time - emitted or
+// registered as an operator.Event interface value, say - are omitted. Event
+// names are taken from EventName() methods that return a constant;
+// otherwise, events are named after their Go type.
+func Analyze(dir string, patterns ...string) (*Graph, error) {
+	pkgs, err := listPackages(dir, patterns)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &analyzer{
+		fset:       token.NewFileSet(),
+		units:      map[string]*unit{},
+		eventNames: map[string]string{},
+		opNames:    map[string]string{},
+	}
+	imp := importer.ForCompiler(a.fset, "source", nil)
+	for _, lp := range pkgs {
+		if err := a.analyzePackage(imp, lp); err != nil {
+			return nil, fmt.Errorf("package %s: %w", lp.ImportPath, err)
+		}
+	}
+	return a.graph(), nil
+}
+
+type listedPackage struct {
+	Dir        string
+	ImportPath string
+	Name       string
+	GoFiles    []string
+	Error      *struct{ Err string }
+}
+
+func listPackages(dir string, patterns []string) ([]listedPackage, error) {
+	cmd := exec.Command("go", append([]string{"list", "-e", "-json=Dir,ImportPath,Name,GoFiles,Error", "--"}, patterns...)...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	data, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("go list failed (%w): %s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+
+	var pkgs []listedPackage
+	dec := json.NewDecoder(bytes.NewReader(data))
+	for {
+		var p listedPackage
+		if err := dec.Decode(&p); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		if p.Error != nil {
+			return nil, fmt.Errorf("package %s: %s", p.ImportPath, p.Error.Err)
+		}
+		pkgs = append(pkgs, p)
+	}
+	return pkgs, nil
+}
+
+// unit is a function body: a declared function, or a function literal. Units
+// are identified by the name the runtime gives the function, which is the
+// default name of operations and handlers.
+type unit struct {
+	emits []emit
+	calls []string
+}
+
+type emit struct {
+	event       Node
+	eventName   string // full name of the event's EventName() method
+	afterCommit bool
+}
+
+type handlerReg struct {
+	event     Node
+	eventName string
+	name      string // from HandlerName(), if any
+	unit      func() string
+}
+
+type analyzer struct {
+	fset *token.FileSet
+
+	units map[string]*unit
+
+	// constant results of EventName() methods, by the methods' full names
+	eventNames map[string]string
+
+	// operations, by unit, and the names they are registered with, if any
+	ops      []func() string
+	opNames  map[string]string
+	opLits   []func() (string, string)
+	handlers []handlerReg
+}
+
+func (a *analyzer) analyzePackage(imp types.Importer, lp listedPackage) error {
+	var files []*ast.File
+	for _, name := range lp.GoFiles {
+		f, err := parser.ParseFile(a.fset, filepath.Join(lp.Dir, name), nil, parser.SkipObjectResolution)
+		if err != nil {
+			return err
+		}
+		files = append(files, f)
+	}
+
+	info := &types.Info{
+		Types:      map[ast.Expr]types.TypeAndValue{},
+		Defs:       map[*ast.Ident]types.Object{},
+		Uses:       map[*ast.Ident]types.Object{},
+		Selections: map[*ast.SelectorExpr]*types.Selection{},
+	}
+	conf := types.Config{Importer: imp}
+	pkg, err := conf.Check(lp.ImportPath, a.fset, files, info)
+	if err != nil {
+		return err
+	}
+
+	w := &walker{analyzer: a, info: info, lits: map[*ast.FuncLit]string{}, counts: map[string]int{}}
+	// the runtime names function literals in package-level variable
+	// initializers pkg.glob..func1 and so on
+	glob := &unit{}
+	globPrefix := runtimePath(pkg) + ".glob..func"
+	for _, f := range files {
+		for _, decl := range f.Decls {
+			switch decl := decl.(type) {
+			case *ast.FuncDecl:
+				fn, _ := info.Defs[decl.Name].(*types.Func)
+				if fn == nil || decl.Body == nil {
+					continue
+				}
+				a.recordEventName(info, fn, decl)
+				w.walk(runtimeName(fn), decl.Body)
+			case *ast.GenDecl:
+				w.walkInto(globPrefix, glob, decl)
+			}
+		}
+	}
+	return nil
+}
+
+// recordEventName records the result of decl, if it is an EventName() method
+// returning a constant.
+func (a *analyzer) recordEventName(info *types.Info, fn *types.Func, decl *ast.FuncDecl) {
+	if fn.Name() != "EventName" || decl.Recv == nil || len(decl.Body.List) != 1 {
+		return
+	}
+	ret, ok := decl.Body.List[0].(*ast.ReturnStmt)
+	if !ok || len(ret.Results) != 1 {
+		return
+	}
+	if v := info.Types[ret.Results[0]].Value; v != nil && v.Kind() == constant.String {
+		a.eventNames[fn.FullName()] = constant.StringVal(v)
+	}
+}
+
+// walker finds the operations, handlers and emitted events of a package.
+type walker struct {
+	*analyzer
+	info *types.Info
+
+	// names of the function literals walked, and the number named with each
+	// prefix
+	lits   map[*ast.FuncLit]string
+	counts map[string]int
+
+	// function literals registered as operations or handlers, which are not
+	// considered to be called by the enclosing function
+	registered map[*ast.FuncLit]bool
+}
+
+// walk walks the body of the unit name.
+func (w *walker) walk(name string, body ast.Node) {
+	u := &unit{}
+	w.units[name] = u
+	w.walkInto(name+".func", u, body)
+}
+
+// walkInto walks n, recording its emits and calls in u. Function literals
+// are named prefix1, prefix2 etc., in the order the runtime names them.
+func (w *walker) walkInto(prefix string, u *unit, n ast.Node) {
+	ast.Inspect(n, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			w.counts[prefix]++
+			name := prefix + strconv.Itoa(w.counts[prefix])
+			w.lits[n] = name
+			w.walk(name, n.Body)
+			if !w.registered[n] {
+				u.calls = append(u.calls, name)
+			}
+			return false
+		case *ast.CallExpr:
+			w.call(u, n)
+		}
+		return true
+	})
+}
+
+func (w *walker) call(u *unit, call *ast.CallExpr) {
+	fn := w.callee(call)
+	if fn == nil {
+		return
+	}
+	if name := runtimeName(fn); name != "" {
+		u.calls = append(u.calls, name)
+	}
+
+	if fn.Pkg() == nil || fn.Pkg().Path() != operatorPath {
+		w.operationArgs(call)
+		return
+	}
+	switch fn.Name() {
+	case "Emit", "EmitAfterCommit":
+		if isMethodOf(fn, "OpContext") && len(call.Args) == 1 {
+			if evt, key, ok := w.event(call.Args[0]); ok {
+				u.emits = append(u.emits, emit{event: evt, eventName: key, afterCommit: fn.Name() == "EmitAfterCommit"})
+			}
+		}
+	case "EmitNamed":
+		if isMethodOf(fn, "OpContext") && len(call.Args) == 2 {
+			if name, ok := w.constString(call.Args[0]); ok {
+				u.emits = append(u.emits, emit{event: Node{ID: EventID(name, ""), Kind: Event, Name: name}})
+			}
+		}
+	case "RegisterEventHandler", "AttachEventHandler", "RegisterBatchEventHandler", "AttachBatchEventHandler":
+		if isMethodOf(fn, "Hub") && len(call.Args) >= 2 {
+			if evt, key, ok := w.event(call.Args[0]); ok {
+				w.handler(call, evt, key)
+			}
+		}
+	case "RegisterNamedEventHandler", "AttachNamedEventHandler":
+		if isMethodOf(fn, "Hub") && len(call.Args) >= 2 {
+			if name, ok := w.constString(call.Args[0]); ok {
+				w.handler(call, Node{ID: EventID(name, ""), Kind: Event, Name: name}, "")
+			}
+		}
+	case "RegisterOperation", "RegisterTxOperation":
+		if len(call.Args) == 3 {
+			name, ok := w.constString(call.Args[1])
+			unit := w.unitOf(call.Args[2])
+			if ok && unit != nil {
+				w.opLits = append(w.opLits, func() (string, string) { return unit(), name })
+			}
+		}
+	}
+	w.operationArgs(call)
+}
+
+// operationArgs records the arguments of call that are passed as
+// operations.
+func (w *walker) operationArgs(call *ast.CallExpr) {
+	sig, ok := w.info.TypeOf(call.Fun).(*types.Signature)
+	if !ok {
+		return
+	}
+	for i, arg := range call.Args {
+		var param types.Type
+		switch {
+		case i < sig.Params().Len()-1 || (i < sig.Params().Len() && !sig.Variadic()):
+			param = sig.Params().At(i).Type()
+		case sig.Variadic() && call.Ellipsis == token.NoPos:
+			param = sig.Params().At(sig.Params().Len() - 1).Type().(*types.Slice).Elem()
+		}
+		if named, ok := param.(*types.Named); ok && named.Obj().Pkg() != nil && named.Obj().Pkg().Path() == operatorPath &&
+			(named.Obj().Name() == "Operation" || named.Obj().Name() == "TxOperation") {
+			if unit := w.unitOf(arg); unit != nil {
+				w.ops = append(w.ops, unit)
+			}
+		}
+	}
+}
+
+// handler records the registration by call of a handler for evt.
+func (w *walker) handler(call *ast.CallExpr, evt Node, eventName string) {
+	unit := w.unitOf(call.Args[1])
+	if unit == nil {
+		return
+	}
+	reg := handlerReg{event: evt, eventName: eventName, unit: unit}
+	for _, opt := range call.Args[2:] {
+		c, ok := opt.(*ast.CallExpr)
+		if !ok || len(c.Args) != 1 {
+			continue
+		}
+		if fn := w.callee(c); fn != nil && fn.Pkg() != nil && fn.Pkg().Path() == operatorPath && fn.Name() == "HandlerName" {
+			reg.name, _ = w.constString(c.Args[0])
+		}
+	}
+	w.handlers = append(w.handlers, reg)
+}
+
+// unitOf returns a function returning the name of the unit of the function
+// expression e, or nil if it does not denote a known function. Function
+// literals are named once walked, so the name is resolved lazily.
+func (w *walker) unitOf(e ast.Expr) func() string {
+	switch e := ast.Unparen(e).(type) {
+	case *ast.FuncLit:
+		if w.registered == nil {
+			w.registered = map[*ast.FuncLit]bool{}
+		}
+		w.registered[e] = true
+		return func() string { return w.lits[e] }
+	case *ast.Ident:
+		if fn, ok := w.info.Uses[e].(*types.Func); ok {
+			name := runtimeName(fn)
+			return func() string { return name }
+		}
+	case *ast.SelectorExpr:
+		if sel := w.info.Selections[e]; sel != nil && sel.Kind() == types.MethodVal {
+			// method values are wrapped in a function named with a -fm
+			// suffix, which calls the method
+			if fn, ok := sel.Obj().(*types.Func); ok && runtimeName(fn) != "" {
+				name := runtimeName(fn) + "-fm"
+				w.units[name] = &unit{calls: []string{runtimeName(fn)}}
+				return func() string { return name }
+			}
+		} else if fn, ok := w.info.Uses[e.Sel].(*types.Func); ok {
+			name := runtimeName(fn)
+			return func() string { return name }
+		}
+	}
+	return nil
+}
+
+// callee returns the function or method called by call, if it is static.
+func (w *walker) callee(call *ast.CallExpr) *types.Func {
+	fun := ast.Unparen(call.Fun)
+	if ix, ok := fun.(*ast.IndexExpr); ok {
+		fun = ix.X
+	} else if ix, ok := fun.(*ast.IndexListExpr); ok {
+		fun = ix.X
+	}
+	var id *ast.Ident
+	switch f := fun.(type) {
+	case *ast.Ident:
+		id = f
+	case *ast.SelectorExpr:
+		id = f.Sel
+	default:
+		return nil
+	}
+	fn, _ := w.info.Uses[id].(*types.Func)
+	return fn
+}
+
+// event returns the node of the event of e's static type, and the full name
+// of its EventName() method, if the type is concrete.
+func (w *walker) event(e ast.Expr) (Node, string, bool) {
+	t := w.info.TypeOf(e)
+	if t == nil || types.IsInterface(t) {
+		return Node{}, "", false
+	}
+	typ := types.TypeString(t, func(p *types.Package) string { return p.Name() })
+	var key string
+	if m, _, _ := types.LookupFieldOrMethod(t, true, nil, "EventName"); m != nil {
+		key = m.(*types.Func).FullName()
+	}
+	return Node{ID: EventID("", typ), Kind: Event, Name: typ, Type: typ}, key, true
+}
+
+func (w *walker) constString(e ast.Expr) (string, bool) {
+	if v := w.info.Types[e].Value; v != nil && v.Kind() == constant.String {
+		return constant.StringVal(v), true
+	}
+	return "", false
+}
+
+func isMethodOf(fn *types.Func, typeName string) bool {
+	recv := fn.Type().(*types.Signature).Recv()
+	if recv == nil {
+		return false
+	}
+	t := recv.Type()
+	if p, ok := t.(*types.Pointer); ok {
+		t = p.Elem()
+	}
+	named, ok := t.(*types.Named)
+	return ok && named.Obj().Name() == typeName
+}
+
+// runtimePath returns the path by which the runtime qualifies the names of
+// pkg's functions.
+func runtimePath(pkg *types.Package) string {
+	if pkg.Name() == "main" {
+		return "main"
+	}
+	return pkg.Path()
+}
+
+// runtimeName returns the name the runtime gives fn, or the empty string
+// for interface methods.
+func runtimeName(fn *types.Func) string {
+	if fn.Pkg() == nil {
+		return ""
+	}
+	path := runtimePath(fn.Pkg())
+	sig := fn.Type().(*types.Signature)
+	recv := sig.Recv()
+	if recv == nil {
+		if sig.TypeParams().Len() > 0 {
+			return path + "." + fn.Name() + "[...]"
+		}
+		return path + "." + fn.Name()
+	}
+	t := recv.Type()
+	ptr := false
+	if p, ok := t.(*types.Pointer); ok {
+		t, ptr = p.Elem(), true
+	}
+	named, ok := t.(*types.Named)
+	if !ok || types.IsInterface(named) {
+		return ""
+	}
+	name := named.Obj().Name()
+	if named.TypeParams().Len() > 0 || named.TypeArgs().Len() > 0 {
+		name += "[...]"
+	}
+	if ptr {
+		return path + ".(*" + name + ")." + fn.Name()
+	}
+	return path + "." + name + "." + fn.Name()
+}
+
+// operationName returns the name of the operation implemented by the
+// function named fn, if it is not registered with a name; see
+// operator.RegisterOperation().
+func operationName(fn string) string {
+	if ix := strings.LastIndex(fn, "/"); ix >= 0 {
+		return fn[ix+1:]
+	}
+	return fn
+}
+
+// graph returns the graph of the analyzed packages.
+func (a *analyzer) graph() *Graph {
+	for _, lit := range a.opLits {
+		unit, name := lit()
+		a.opNames[unit] = name
+		a.ops = append(a.ops, func() string { return unit })
+	}
+
+	g := &Graph{}
+	emitted := map[string][]emit{}
+	for _, op := range a.ops {
+		unit := op()
+		name, ok := a.opNames[unit]
+		if !ok {
+			name = operationName(unit)
+		}
+		id := g.AddNode(Node{ID: OperationID(name), Kind: Operation, Name: name})
+		a.addEmits(g, id, a.emits(unit, emitted))
+	}
+	for _, h := range a.handlers {
+		unit := h.unit()
+		name := h.name
+		if name == "" {
+			name = unit
+		}
+		evt := g.AddNode(a.resolve(h.event, h.eventName))
+		id := g.AddNode(Node{ID: HandlerID(name), Kind: Handler, Name: name})
+		g.AddEdge(Edge{From: evt, To: id})
+		a.addEmits(g, id, a.emits(unit, emitted))
+	}
+	return g
+}
+
+func (a *analyzer) addEmits(g *Graph, from string, emits []emit) {
+	for _, e := range emits {
+		to := g.AddNode(a.resolve(e.event, e.eventName))
+		g.AddEdge(Edge{From: from, To: to, AfterCommit: e.afterCommit})
+	}
+}
+
+// resolve names evt after the result of its EventName() method, if known.
+func (a *analyzer) resolve(evt Node, eventName string) Node {
+	if name, ok := a.eventNames[eventName]; ok {
+		evt.Name = name
+	}
+	return evt
+}
+
+// emits returns the events emitted by the unit name, and the units it calls,
+// memoized in memo.
+func (a *analyzer) emits(name string, memo map[string][]emit) []emit {
+	if out, ok := memo[name]; ok {
+		return out
+	}
+	memo[name] = nil // guards against recursion
+	u := a.units[name]
+	if u == nil {
+		return nil
+	}
+	out := append([]emit(nil), u.emits...)
+	for _, c := range u.calls {
+		out = append(out, a.emits(c, memo)...)
+	}
+	memo[name] = out
+	return out
+}
@@ -0,0 +1,226 @@
+// Package opgraph describes how an application's operations, events and event
+// handlers relate - which operations emit which events, which handlers
+// receive them, and which events those handlers emit in turn - and renders
+// the result as a Mermaid or Graphviz (DOT) graph.
+//
+// Graphs are built from a hub with FromHub, which knows every registered
+// operation and handler but not what they emit, and from source with
+// Analyze, which finds the events emitted by each operation and handler but
+// sees only the registrations it can resolve statically. Merge the two for
+// the most complete picture. The operator-graph command (see
+// cmd/operator-graph) renders the graph of a program's source.
+package opgraph
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+
+	"github.com/jaz303/operator"
+)
+
+// Kind is the kind of a node.
+type Kind int
+
+const (
+	Operation Kind = iota
+	Event
+	Handler
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Operation:
+		return "operation"
+	case Event:
+		return "event"
+	case Handler:
+		return "handler"
+	default:
+		return fmt.Sprintf("Kind(%d)", int(k))
+	}
+}
+
+// Node is an operation, event or handler.
+type Node struct {
+	// Unique identifier of the node; see OperationID, EventID and
+	// HandlerID
+	ID   string
+	Kind Kind
+
+	// Name of the operation, event or handler. Events found by Analyze are
+	// named after their Go type if their EventName() cannot be determined
+	Name string
+
+	// Go type of an event, as formatted by reflect; empty for events known
+	// only by name
+	Type string
+
+	// True for handlers that receive events once the emitting operation has
+	// committed, i.e. subscriptions and handlers dispatched after commit
+	Async bool
+}
+
+// Edge connects an operation or handler to an event it emits, or an event to
+// a handler that receives it.
+type Edge struct {
+	From, To string
+
+	// True for events emitted with OpContext.EmitAfterCommit()
+	AfterCommit bool
+}
+
+// Graph is a set of nodes, and the edges between them. The zero value is an
+// empty graph.
+type Graph struct {
+	nodes map[string]*Node
+	edges map[Edge]struct{}
+}
+
+// OperationID returns the ID of the operation named name.
+func OperationID(name string) string { return "operation:" + name }
+
+// EventID returns the ID of the event of Go type typ, or if typ is empty,
+// the event named name.
+func EventID(name, typ string) string {
+	if typ != "" {
+		return "event:" + typ
+	}
+	return "event:name:" + name
+}
+
+// HandlerID returns the ID of the handler named name.
+func HandlerID(name string) string { return "handler:" + name }
+
+// AddNode adds n to the graph, returning its ID. If a node with the same ID
+// exists, n's non-empty fields are merged into it.
+func (g *Graph) AddNode(n Node) string {
+	if g.nodes == nil {
+		g.nodes = map[string]*Node{}
+	}
+	if old, ok := g.nodes[n.ID]; ok {
+		if n.Name != "" && (old.Name == "" || old.Name == old.Type) {
+			old.Name = n.Name
+		}
+		old.Async = old.Async || n.Async
+		return n.ID
+	}
+	g.nodes[n.ID] = &n
+	return n.ID
+}
+
+// AddEdge adds e to the graph. Both of its nodes must have been added.
+func (g *Graph) AddEdge(e Edge) {
+	if g.edges == nil {
+		g.edges = map[Edge]struct{}{}
+	}
+	g.edges[e] = struct{}{}
+}
+
+// Merge adds the nodes and edges of other to g.
+func (g *Graph) Merge(other *Graph) {
+	for _, n := range other.nodes {
+		g.AddNode(*n)
+	}
+	for e := range other.edges {
+		g.AddEdge(e)
+	}
+}
+
+// Nodes returns the graph's nodes, ordered by kind and name.
+func (g *Graph) Nodes() []Node {
+	out := make([]Node, 0, len(g.nodes))
+	for _, n := range g.nodes {
+		out = append(out, *n)
+	}
+	slices.SortFunc(out, func(a, b Node) int {
+		return cmp.Or(cmp.Compare(a.Kind, b.Kind), cmp.Compare(a.Name, b.Name), cmp.Compare(a.ID, b.ID))
+	})
+	return out
+}
+
+// Edges returns the graph's edges, ordered by the IDs of their nodes.
+func (g *Graph) Edges() []Edge {
+	out := make([]Edge, 0, len(g.edges))
+	for e := range g.edges {
+		out = append(out, e)
+	}
+	slices.SortFunc(out, func(a, b Edge) int {
+		return cmp.Or(cmp.Compare(a.From, b.From), cmp.Compare(a.To, b.To), compareBool(a.AfterCommit, b.AfterCommit))
+	})
+	return out
+}
+
+// FromHub returns the graph of the operations registered with hub, and its
+// event handlers and subscriptions. Subscriptions to every event are
+// omitted.
+func FromHub[Tx operator.Transaction](hub *operator.Hub[Tx]) *Graph {
+	g := &Graph{}
+	for _, op := range hub.Operations() {
+		g.AddNode(Node{ID: OperationID(op.Name), Kind: Operation, Name: op.Name})
+	}
+	for _, h := range hub.EventHandlers() {
+		if h.Event == "" {
+			continue
+		}
+		evt := g.AddNode(Node{ID: EventID(h.Event, h.Type), Kind: Event, Name: h.Event, Type: h.Type})
+		name := h.Name
+		if name == "" {
+			name = "subscription at " + h.Site
+		}
+		hnd := g.AddNode(Node{ID: HandlerID(name), Kind: Handler, Name: name, Async: h.Async || h.Phase != operator.BeforeCommit})
+		g.AddEdge(Edge{From: evt, To: hnd})
+	}
+	return g
+}
+
+func compareBool(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case a:
+		return 1
+	default:
+		return -1
+	}
+}
+
+// resolveNames returns a copy of g in which the handlers of each event known
+// only by name are also connected to the events of any Go type with that
+// name. Events known only by name that nothing emits, and that have such a
+// counterpart, are then redundant, and are removed.
+func (g *Graph) resolveNames() *Graph {
+	out := &Graph{}
+	out.Merge(g)
+	for _, n := range g.nodes {
+		if n.Kind != Event || n.Type != "" {
+			continue
+		}
+		var typed []*Node
+		for _, m := range g.nodes {
+			if m.Kind == Event && m.Type != "" && m.Name == n.Name {
+				typed = append(typed, m)
+			}
+		}
+		emitted := false
+		for e := range g.edges {
+			if e.To == n.ID {
+				emitted = true
+			}
+			if e.From == n.ID {
+				for _, m := range typed {
+					out.AddEdge(Edge{From: m.ID, To: e.To})
+				}
+			}
+		}
+		if len(typed) > 0 && !emitted {
+			delete(out.nodes, n.ID)
+			for e := range out.edges {
+				if e.From == n.ID {
+					delete(out.edges, e)
+				}
+			}
+		}
+	}
+	return out
+}
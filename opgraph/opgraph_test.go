@@ -0,0 +1,86 @@
+package opgraph
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/jaz303/operator"
+	"github.com/jaz303/operator/opgraph/testdata/app"
+	"github.com/stretchr/testify/assert"
+)
+
+const appPath = "github.com/jaz303/operator/opgraph/testdata/app"
+
+func TestFromHub(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	hub := operator.NewHub(func(ctx context.Context) (app.Tx, error) { return app.Tx{}, nil })
+	app.Setup(hub, &app.UserIndexed{})
+	hub.Subscribe(ctx, &app.WelcomeSent{})
+
+	g := FromHub(hub)
+	var ids []string
+	for _, n := range g.Nodes() {
+		ids = append(ids, n.ID)
+	}
+	assert.Equal(t, []string{
+		"operation:users.create",
+		"event:name:audit",
+		"event:*app.UserCreated",
+		"event:name:user.created",
+		"event:*app.UserIndexed",
+		"event:*app.WelcomeSent",
+		"handler:" + appPath + ".(*mailer).sendWelcome-fm",
+		"handler:" + appPath + ".Setup.func2",
+		"handler:" + appPath + ".Setup.func3",
+		"handler:" + appPath + ".Setup.func4",
+		"handler:index",
+	}, ids[:11])
+	assert.True(t, g.Nodes()[11].Async)
+}
+
+func TestAnalyze(t *testing.T) {
+	if testing.Short() {
+		t.Skip("type-checks dependencies from source")
+	}
+
+	g, err := Analyze(".", "./testdata/app")
+	assert.Nil(t, err)
+
+	var mermaid bytes.Buffer
+	assert.Nil(t, g.Mermaid(&mermaid))
+	assert.Equal(t, `flowchart LR
+    n0["app.Run.func1"]
+    n1["users.create"]
+    n2(["audit"])
+    n3(["user.created (*app.UserCreated)"])
+    n4(["user.indexed (app.UserIndexed)"])
+    n5(["welcome.sent (*app.WelcomeSent)"])
+    n6[/"`+appPath+`.(*mailer).sendWelcome-fm"/]
+    n7[/"`+appPath+`.Setup.func2"/]
+    n8[/"`+appPath+`.Setup.func3"/]
+    n9[/"index"/]
+    n3 --> n6
+    n3 --> n7
+    n3 --> n9
+    n2 --> n8
+    n6 -. after commit .-> n5
+    n9 --> n4
+    n0 --> n2
+    n1 --> n3
+`, mermaid.String())
+
+	// handlers found statically are named as the hub names them
+	hub := operator.NewHub(func(ctx context.Context) (app.Tx, error) { return app.Tx{}, nil })
+	app.Setup(hub, &app.UserIndexed{})
+	merged := FromHub(hub)
+	merged.Merge(g)
+	assert.Equal(t, len(g.Nodes())+2, len(merged.Nodes()))
+
+	var dot bytes.Buffer
+	assert.Nil(t, merged.DOT(&dot))
+	assert.Contains(t, dot.String(), "digraph operator {\n    rankdir=LR;\n    n0 [label=\"app.Run.func1\", shape=box];\n")
+	assert.Contains(t, dot.String(), "[style=dashed, label=\"after commit\"];\n")
+}
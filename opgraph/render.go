@@ -0,0 +1,90 @@
+package opgraph
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Mermaid writes the graph to w as a Mermaid flowchart. Operations are drawn
+// as rectangles, events as stadiums and handlers as parallelograms; events
+// emitted after commit, and subscriptions, are connected by dotted lines.
+//
+// Handlers registered by event name receive every event with that name, so
+// are drawn connected to the events of any Go type with the name.
+func (g *Graph) Mermaid(w io.Writer) error {
+	r := g.resolveNames()
+	ids := r.ids()
+	b := bufio.NewWriter(w)
+	b.WriteString("flowchart LR\n")
+	for _, n := range r.Nodes() {
+		label := strings.ReplaceAll(n.label(), `"`, "#quot;")
+		switch n.Kind {
+		case Operation:
+			fmt.Fprintf(b, "    %s[\"%s\"]\n", ids[n.ID], label)
+		case Event:
+			fmt.Fprintf(b, "    %s([\"%s\"])\n", ids[n.ID], label)
+		default:
+			fmt.Fprintf(b, "    %s[/\"%s\"/]\n", ids[n.ID], label)
+		}
+	}
+	for _, e := range r.Edges() {
+		arrow := "-->"
+		if e.AfterCommit {
+			arrow = "-. after commit .->"
+		} else if r.nodes[e.To].Async {
+			arrow = "-.->"
+		}
+		fmt.Fprintf(b, "    %s %s %s\n", ids[e.From], arrow, ids[e.To])
+	}
+	return b.Flush()
+}
+
+// DOT writes the graph to w in the Graphviz DOT language, drawn as by
+// Mermaid.
+func (g *Graph) DOT(w io.Writer) error {
+	r := g.resolveNames()
+	ids := r.ids()
+	b := bufio.NewWriter(w)
+	b.WriteString("digraph operator {\n    rankdir=LR;\n")
+	for _, n := range r.Nodes() {
+		shape := "box"
+		switch n.Kind {
+		case Event:
+			shape = "oval"
+		case Handler:
+			shape = "parallelogram"
+		}
+		fmt.Fprintf(b, "    %s [label=%s, shape=%s];\n", ids[n.ID], strconv.Quote(n.label()), shape)
+	}
+	for _, e := range r.Edges() {
+		attrs := ""
+		if e.AfterCommit {
+			attrs = ` [style=dashed, label="after commit"]`
+		} else if r.nodes[e.To].Async {
+			attrs = " [style=dashed]"
+		}
+		fmt.Fprintf(b, "    %s -> %s%s;\n", ids[e.From], ids[e.To], attrs)
+	}
+	b.WriteString("}\n")
+	return b.Flush()
+}
+
+// ids assigns the graph's nodes identifiers for rendering, in the order of
+// Nodes().
+func (g *Graph) ids() map[string]string {
+	ids := map[string]string{}
+	for i, n := range g.Nodes() {
+		ids[n.ID] = fmt.Sprintf("n%d", i)
+	}
+	return ids
+}
+
+func (n *Node) label() string {
+	if n.Type != "" && n.Name != n.Type {
+		return fmt.Sprintf("%s (%s)", n.Name, n.Type)
+	}
+	return n.Name
+}
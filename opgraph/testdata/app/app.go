@@ -0,0 +1,60 @@
+package app
+
+import (
+	"context"
+
+	"github.com/jaz303/operator"
+)
+
+type Tx struct{}
+
+func (Tx) Commit(context.Context) error   { return nil }
+func (Tx) Rollback(context.Context) error { return nil }
+
+type UserCreated struct{ ID int }
+
+func (*UserCreated) EventName() string { return "user.created" }
+
+type WelcomeSent struct{ ID int }
+
+func (*WelcomeSent) EventName() string { return "welcome." + "sent" }
+
+type UserIndexed struct{}
+
+func (UserIndexed) EventName() string { return "user.indexed" }
+
+type CreateUserInput struct{ Name string }
+
+type Empty struct{}
+
+func CreateUser(ctx *operator.OpContext[Tx], in *CreateUserInput) (*Empty, error) {
+	return nil, created(ctx, 1)
+}
+
+func created(ctx *operator.OpContext[Tx], id int) error {
+	return ctx.Emit(&UserCreated{ID: id})
+}
+
+type mailer struct{}
+
+func (m *mailer) sendWelcome(ctx *operator.OpContext[Tx], evt *UserCreated) error {
+	return ctx.EmitAfterCommit(&WelcomeSent{ID: evt.ID})
+}
+
+func Setup(hub *operator.Hub[Tx], evt operator.Event) {
+	m := &mailer{}
+	operator.RegisterOperation(hub, "users.create", CreateUser)
+	hub.RegisterEventHandler(&UserCreated{}, m.sendWelcome)
+	hub.RegisterEventHandler(&UserCreated{}, func(ctx *operator.OpContext[Tx], evt *UserCreated) error {
+		return ctx.Emit(UserIndexed{})
+	}, operator.HandlerName("index"))
+	hub.RegisterNamedEventHandler("user.created", func(evt *operator.NamedEvent) {})
+	hub.RegisterNamedEventHandler("audit", func(evt *operator.NamedEvent) {})
+	hub.RegisterEventHandler(evt, func(evt operator.Event) {})
+}
+
+func Run(ctx context.Context, hub *operator.Hub[Tx]) {
+	operator.Invoke(ctx, hub, func(ctx *operator.OpContext[Tx], in *Empty) (*Empty, error) {
+		return in, ctx.EmitNamed("audit", "ran")
+	}, &Empty{})
+}
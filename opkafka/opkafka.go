@@ -0,0 +1,187 @@
+// Package opkafka connects a hub to Kafka. Export produces events to Kafka
+// once the operation that emitted them has committed, and a Runner invokes
+// registered operations for records consumed from topics, committing each
+// record's offset only once its operation has committed.
+//
+// The package does not depend on a Kafka client. Adapt a client to Producer
+// and Client; with franz-go, for example, Producer can wrap
+// kgo.Client.ProduceSync, within BeginTransaction and EndTransaction when the
+// client is transactional.
+//
+//	e := opkafka.NewExporter(producer, eventcodec.New(hub))
+//	opkafka.Export(hub, e, &OrderPlaced{}, &OrderShipped{})
+//
+//	r := opkafka.NewRunner(hub, client, opkafka.WithDeadLetterSink(store))
+//	r.Route("shipments", "orders.Ship")
+//	go r.Run(ctx)
+package opkafka
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strconv"
+
+	"github.com/jaz303/operator"
+	"github.com/jaz303/operator/eventcodec"
+)
+
+// Headers set on records produced by Export.
+const (
+	HeaderEventName    = "event-name"
+	HeaderEventVersion = "event-version"
+	HeaderContentType  = "content-type"
+)
+
+// Record is a Kafka record to be produced.
+type Record struct {
+	Topic   string
+	Key     []byte
+	Value   []byte
+	Headers map[string]string
+}
+
+// Producer writes records to Kafka, returning once every record has been
+// acknowledged. Producers backed by a transactional Kafka producer should
+// write each call's records in a single Kafka transaction, so that consumers
+// reading committed records see all of an operation's events or none.
+type Producer interface {
+	Produce(ctx context.Context, records []*Record) error
+}
+
+// ProducerFunc adapts a function to the Producer interface.
+type ProducerFunc func(ctx context.Context, records []*Record) error
+
+func (fn ProducerFunc) Produce(ctx context.Context, records []*Record) error { return fn(ctx, records) }
+
+// Keyer can be implemented by events to set the key of their records, which
+// determines their partition; records of events with the same key are
+// consumed in order. Events that do not implement Keyer have no key.
+type Keyer interface {
+	PartitionKey() string
+}
+
+// Exporter produces serialized events to Kafka.
+type Exporter struct {
+	producer   Producer
+	serializer *eventcodec.Serializer
+	topic      func(event string) string
+	onError    func(records []*Record, err error)
+}
+
+// ExporterOption configures an Exporter.
+type ExporterOption func(e *Exporter)
+
+// WithTopic sets the function mapping event names to topics. By default
+// events are produced to a topic named after the event.
+func WithTopic(fn func(event string) string) ExporterOption {
+	return func(e *Exporter) { e.topic = fn }
+}
+
+// WithExportErrorHandler sets a function to be called when records cannot
+// be produced. The default logs the error with slog.Default().
+func WithExportErrorHandler(fn func(records []*Record, err error)) ExporterOption {
+	return func(e *Exporter) { e.onError = fn }
+}
+
+// NewExporter returns an Exporter producing events serialized with s via p.
+func NewExporter(p Producer, s *eventcodec.Serializer, opts ...ExporterOption) *Exporter {
+	e := &Exporter{
+		producer:   p,
+		serializer: s,
+		topic:      func(event string) string { return event },
+		onError: func(records []*Record, err error) {
+			slog.Error("kafka export failed", "records", len(records), "error", err)
+		},
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// Export marks events' types for export: it registers an event handler on
+// hub for each, which serializes matching events as they are emitted. Once
+// the emitting operation has committed, its exported events are produced
+// together in a single call to the Producer, in the order they were
+// dispatched. If serialization fails, the operation fails.
+//
+// Each record's value is the serialized event, and its headers identify the
+// event's name, version and content type. Each handler's destination,
+// reported by Hub.EventTopology(), is "kafka:" followed by the event's topic.
+//
+// Export is best-effort: the operation has already committed when records
+// are produced, so failures are reported to the Exporter's error handler.
+// Where delivery must be guaranteed, write events to an outbox within the
+// operation's transaction and relay them to Kafka instead.
+func Export[Tx operator.Transaction](hub *operator.Hub[Tx], e *Exporter, events ...operator.Event) error {
+	for _, evt := range events {
+		err := hub.RegisterEventHandler(evt, func(ctx *operator.OpContext[Tx], evt operator.Event) error {
+			rec, err := e.record(evt)
+			if err != nil {
+				return err
+			}
+			b, err := batchFor(ctx)
+			if err != nil {
+				return err
+			}
+			b.records[e] = append(b.records[e], rec)
+			return nil
+		}, operator.Destination("kafka:"+e.topic(evt.EventName())))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *Exporter) record(evt operator.Event) (*Record, error) {
+	env, err := e.serializer.Encode(evt)
+	if err != nil {
+		return nil, err
+	}
+	rec := &Record{
+		Topic: e.topic(env.Name),
+		Value: env.Data,
+		Headers: map[string]string{
+			HeaderEventName:    env.Name,
+			HeaderEventVersion: strconv.Itoa(env.Version),
+			HeaderContentType:  env.ContentType,
+		},
+	}
+	if k, ok := evt.(Keyer); ok {
+		rec.Key = []byte(k.PartitionKey())
+	}
+	return rec, nil
+}
+
+// batch collects an operation's exported records, by Exporter.
+type batch struct {
+	records map[*Exporter][]*Record
+}
+
+// batchFor returns the operation's batch, creating it, and scheduling it to
+// be produced after commit, on first use.
+func batchFor[Tx operator.Transaction](ctx *operator.OpContext[Tx]) (*batch, error) {
+	b, err := operator.Lookup[*batch](ctx)
+	if err == nil {
+		return b, nil
+	} else if !errors.Is(err, operator.ErrNoProvider) {
+		return nil, err
+	}
+	b = &batch{records: map[*Exporter][]*Record{}}
+	if err := ctx.AfterFunc(func(ctx *operator.OpContext[Tx]) { b.produce(ctx) }); err != nil {
+		return nil, err
+	}
+	operator.Provide(ctx, b)
+	return b, nil
+}
+
+func (b *batch) produce(ctx context.Context) {
+	ctx = context.WithoutCancel(ctx)
+	for e, records := range b.records {
+		if err := e.producer.Produce(ctx, records); err != nil {
+			e.onError(records, err)
+		}
+	}
+}
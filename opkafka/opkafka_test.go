@@ -0,0 +1,180 @@
+package opkafka
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jaz303/operator"
+	"github.com/jaz303/operator/deadletter"
+	"github.com/jaz303/operator/eventcodec"
+	"github.com/stretchr/testify/assert"
+)
+
+type nopTx struct{}
+
+func (nopTx) Commit(context.Context) error   { return nil }
+func (nopTx) Rollback(context.Context) error { return nil }
+
+func newHub() *operator.Hub[*nopTx] {
+	return operator.NewHub(func(context.Context) (*nopTx, error) { return &nopTx{}, nil })
+}
+
+type orderPlaced struct {
+	ID string `json:"id"`
+}
+
+func (*orderPlaced) EventName() string      { return "orderPlaced" }
+func (e *orderPlaced) PartitionKey() string { return e.ID }
+
+type orderShipped struct{}
+
+func (*orderShipped) EventName() string { return "orderShipped" }
+
+func TestExport(t *testing.T) {
+	hub := newHub()
+	var produced [][]*Record
+	e := NewExporter(ProducerFunc(func(ctx context.Context, records []*Record) error {
+		produced = append(produced, records)
+		return nil
+	}), eventcodec.New(hub), WithTopic(func(event string) string { return "orders." + event }))
+	assert.Nil(t, Export(hub, e, &orderPlaced{}, &orderShipped{}))
+	var destinations []string
+	for _, evt := range hub.EventTopology() {
+		for _, h := range evt.Handlers {
+			destinations = append(destinations, h.Destination)
+		}
+	}
+	assert.ElementsMatch(t, []string{"kafka:orders.orderPlaced", "kafka:orders.orderShipped"}, destinations)
+
+	_, err := operator.Invoke(context.Background(), hub, func(ctx *operator.OpContext[*nopTx], in *struct{}) (*struct{}, error) {
+		ctx.Emit(&orderPlaced{ID: "o1"})
+		return nil, errors.New("rolled back")
+	}, &struct{}{})
+	assert.Error(t, err)
+	assert.Len(t, produced, 0)
+
+	_, err = operator.Invoke(context.Background(), hub, func(ctx *operator.OpContext[*nopTx], in *struct{}) (*struct{}, error) {
+		ctx.Emit(&orderPlaced{ID: "o2"})
+		ctx.Emit(&orderShipped{})
+		return in, nil
+	}, &struct{}{})
+	assert.Nil(t, err)
+
+	if assert.Len(t, produced, 1, "an operation's records are produced together") && assert.Len(t, produced[0], 2) {
+		rec := produced[0][0]
+		assert.Equal(t, "orders.orderPlaced", rec.Topic)
+		assert.Equal(t, []byte("o2"), rec.Key)
+		assert.JSONEq(t, `{"id":"o2"}`, string(rec.Value))
+		assert.Equal(t, map[string]string{
+			HeaderEventName:    "orderPlaced",
+			HeaderEventVersion: "1",
+			HeaderContentType:  "application/json",
+		}, rec.Headers)
+		assert.Equal(t, "orders.orderShipped", produced[0][1].Topic)
+		assert.Nil(t, produced[0][1].Key)
+	}
+}
+
+type fakeClient struct {
+	mu        sync.Mutex
+	batches   [][]*Message
+	committed []int64
+}
+
+func (c *fakeClient) Poll(ctx context.Context) ([]*Message, error) {
+	c.mu.Lock()
+	if len(c.batches) > 0 {
+		b := c.batches[0]
+		c.batches = c.batches[1:]
+		c.mu.Unlock()
+		return b, nil
+	}
+	c.mu.Unlock()
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (c *fakeClient) Commit(ctx context.Context, msgs []*Message) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, m := range msgs {
+		c.committed = append(c.committed, m.Offset)
+	}
+	return nil
+}
+
+type shipInput struct {
+	ID string `json:"id"`
+}
+
+func TestRunner(t *testing.T) {
+	hub := newHub()
+	var shipped []string
+	var attempts int
+	assert.Nil(t, operator.RegisterOperation(hub, "orders.Ship", func(ctx *operator.OpContext[*nopTx], in *shipInput) (*struct{}, error) {
+		if in.ID == "bad" {
+			attempts++
+			return nil, errors.New("cannot ship")
+		}
+		shipped = append(shipped, in.ID)
+		return &struct{}{}, nil
+	}))
+
+	client := &fakeClient{batches: [][]*Message{
+		{
+			{Topic: "shipments", Offset: 1, Value: []byte(`{"id":"a"}`)},
+			{Topic: "shipments", Offset: 2, Value: []byte(`{"id":"bad"}`)},
+			{Topic: "shipments", Offset: 3, Value: []byte(`{`)},
+			{Topic: "unknown", Offset: 4, Value: []byte(`{}`)},
+			{Topic: "shipments", Offset: 5, Value: []byte(`{"id":"b"}`)},
+		},
+	}}
+	store := deadletter.NewMemoryStore()
+	r := NewRunner(hub, client, WithRetry(2, time.Millisecond), WithDeadLetterSink(store),
+		WithRunnerErrorHandler(func(*Message, error) {}))
+	r.Route("shipments", "orders.Ship")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- r.Run(ctx) }()
+
+	assert.Eventually(t, func() bool {
+		client.mu.Lock()
+		defer client.mu.Unlock()
+		return len(client.committed) == 5
+	}, time.Second, time.Millisecond)
+	cancel()
+	assert.Nil(t, <-done)
+
+	assert.Equal(t, []string{"a", "b"}, shipped)
+	assert.Equal(t, 2, attempts)
+	letters, err := store.List(context.Background(), deadletter.Filter{})
+	assert.Nil(t, err)
+	assert.Len(t, letters, 3)
+}
+
+func TestRunner_StopsWithoutDeadLetterSink(t *testing.T) {
+	hub := newHub()
+	assert.Nil(t, operator.RegisterOperation(hub, "fail", func(ctx *operator.OpContext[*nopTx], in *struct{}) (*struct{}, error) {
+		return nil, errors.New("boom")
+	}))
+	assert.Nil(t, operator.RegisterOperation(hub, "ok", func(ctx *operator.OpContext[*nopTx], in *struct{}) (*struct{}, error) {
+		return in, nil
+	}))
+
+	client := &fakeClient{batches: [][]*Message{{
+		{Topic: "ok", Offset: 1, Value: []byte(`{}`)},
+		{Topic: "fail", Offset: 2, Value: []byte(`{}`)},
+		{Topic: "ok", Offset: 3, Value: []byte(`{}`)},
+	}}}
+	r := NewRunner(hub, client, WithRetry(1, 0), WithRunnerErrorHandler(func(*Message, error) {}))
+	r.Route("ok", "ok")
+	r.Route("fail", "fail")
+
+	err := r.Run(context.Background())
+	assert.EqualError(t, err, "boom")
+	assert.Equal(t, []int64{1}, client.committed, "offsets are committed up to the failed record")
+}
@@ -0,0 +1,196 @@
+package opkafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jaz303/operator"
+	"github.com/jaz303/operator/deadletter"
+)
+
+// DeadLetterSource is the source of dead letters produced by a Runner.
+const DeadLetterSource = "opkafka"
+
+// ErrNoRoute is returned by a Runner for records from a topic with no route.
+var ErrNoRoute = errors.New("no route for topic")
+
+// Message is a record consumed from Kafka.
+type Message struct {
+	Topic     string
+	Partition int32
+	Offset    int64
+	Key       []byte
+	Value     []byte
+	Headers   map[string]string
+}
+
+// Client consumes records as a member of a consumer group.
+type Client interface {
+	// Poll blocks until records are available, or ctx is done, and returns
+	// them in offset order within each partition.
+	Poll(ctx context.Context) ([]*Message, error)
+
+	// Commit commits the group's offsets past msgs.
+	Commit(ctx context.Context, msgs []*Message) error
+}
+
+// Runner consumes records with a Client and invokes the operation routed to
+// each record's topic, with the record's value as JSON input (see
+// operator.Hub.InvokeJSON). Records are handled one at a time, in order, and
+// an offset is committed only after the record's operation has committed, so
+// delivery is at-least-once and operations should be idempotent.
+type Runner[Tx operator.Transaction] struct {
+	hub    *operator.Hub[Tx]
+	client Client
+	opts   runnerOptions
+
+	mu     sync.RWMutex
+	routes map[string]string
+}
+
+// RunnerOption configures a Runner.
+type RunnerOption func(o *runnerOptions)
+
+type runnerOptions struct {
+	attempts    int
+	backoff     time.Duration
+	deadLetters deadletter.Sink
+	onError     func(msg *Message, err error)
+}
+
+// WithRetry makes up to attempts attempts to handle each record. The delay
+// before each retry starts at backoff and doubles after each failed attempt.
+// By default, each record is attempted 3 times, starting with a delay of
+// 100ms.
+func WithRetry(attempts int, backoff time.Duration) RunnerOption {
+	return func(o *runnerOptions) {
+		o.attempts = max(attempts, 1)
+		o.backoff = backoff
+	}
+}
+
+// WithDeadLetterSink sets a sink to receive records that could not be
+// handled, after which the runner moves on. Without a sink, Run returns the
+// error instead, leaving the record's offset uncommitted so that the record
+// is redelivered when consumption resumes.
+func WithDeadLetterSink(sink deadletter.Sink) RunnerOption {
+	return func(o *runnerOptions) { o.deadLetters = sink }
+}
+
+// WithRunnerErrorHandler sets a function to be called when a record cannot
+// be handled. The default logs the error with slog.Default().
+func WithRunnerErrorHandler(fn func(msg *Message, err error)) RunnerOption {
+	return func(o *runnerOptions) { o.onError = fn }
+}
+
+// NewRunner returns a Runner invoking operations on hub for records consumed
+// with client.
+func NewRunner[Tx operator.Transaction](hub *operator.Hub[Tx], client Client, opts ...RunnerOption) *Runner[Tx] {
+	o := runnerOptions{
+		attempts: 3,
+		backoff:  100 * time.Millisecond,
+		onError: func(msg *Message, err error) {
+			slog.Error("kafka record failed", "topic", msg.Topic, "partition", msg.Partition, "offset", msg.Offset, "error", err)
+		},
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &Runner[Tx]{hub: hub, client: client, opts: o, routes: map[string]string{}}
+}
+
+// Route routes records from topic to the operation registered as operation.
+func (r *Runner[Tx]) Route(topic, operation string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.routes[topic] = operation
+}
+
+// Run consumes records until ctx is done, returning nil, or until a record
+// can be neither handled nor dead-lettered, or the client fails, returning
+// the error.
+func (r *Runner[Tx]) Run(ctx context.Context) error {
+	for {
+		msgs, err := r.client.Poll(ctx)
+		if ctx.Err() != nil {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("poll failed (%w)", err)
+		}
+
+		done := 0
+		for _, msg := range msgs {
+			if err = r.handle(ctx, msg); err != nil {
+				break
+			}
+			done++
+		}
+
+		if done > 0 {
+			if cerr := r.client.Commit(context.WithoutCancel(ctx), msgs[:done]); cerr != nil {
+				return errors.Join(err, fmt.Errorf("commit failed (%w)", cerr))
+			}
+		}
+		if ctx.Err() != nil {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
+
+// handle handles msg, retrying and dead-lettering as configured. A nil result
+// means msg's offset may be committed.
+func (r *Runner[Tx]) handle(ctx context.Context, msg *Message) error {
+	r.mu.RLock()
+	op, ok := r.routes[msg.Topic]
+	r.mu.RUnlock()
+
+	var err error
+	attempt := 0
+	if !ok {
+		err = fmt.Errorf("%w %s", ErrNoRoute, msg.Topic)
+	} else {
+		for attempt = 1; ; attempt++ {
+			err = r.hub.InvokeJSON(ctx, op, msg.Value)
+			if err == nil {
+				return nil
+			}
+			if attempt >= r.opts.attempts || errors.Is(err, operator.ErrInvalidInput) {
+				break
+			}
+			select {
+			case <-time.After(r.opts.backoff << (attempt - 1)):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+
+	r.opts.onError(msg, err)
+	if r.opts.deadLetters == nil || ctx.Err() != nil {
+		return err
+	}
+	letter := &deadletter.Letter{
+		Source:      DeadLetterSource,
+		Name:        op,
+		ContentType: msg.Headers[HeaderContentType],
+		Data:        msg.Value,
+		Metadata: map[string]string{
+			"topic":     msg.Topic,
+			"partition": strconv.Itoa(int(msg.Partition)),
+			"offset":    strconv.FormatInt(msg.Offset, 10),
+		},
+		Error:    err.Error(),
+		Attempts: attempt,
+	}
+	if dlErr := r.opts.deadLetters.Put(context.WithoutCancel(ctx), letter); dlErr != nil {
+		return errors.Join(err, fmt.Errorf("dead letter failed (%w)", dlErr))
+	}
+	return nil
+}
@@ -0,0 +1,199 @@
+package opnats
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"github.com/jaz303/operator"
+)
+
+// ErrNoRoute is returned by Consumer.Dispatch for messages whose subject
+// matches no route.
+var ErrNoRoute = errors.New("no route for subject")
+
+// Msg is a received message; jetstream.Msg implements Msg. Messages received
+// with core NATS, which are not acknowledged, can be adapted with NewMsg.
+type Msg interface {
+	Subject() string
+	Data() []byte
+	Ack() error
+	Nak() error
+}
+
+// Terminator is implemented by messages that can be rejected permanently, so
+// that they are never redelivered; jetstream.Msg implements Terminator.
+type Terminator interface {
+	Term() error
+}
+
+// NewMsg returns a Msg with the given subject and data. ack and nak are
+// called to acknowledge the message, and may be nil.
+//
+//	nc.Subscribe("orders.>", func(m *nats.Msg) {
+//		c.Dispatch(ctx, opnats.NewMsg(m.Subject, m.Data, nil, nil))
+//	})
+func NewMsg(subject string, data []byte, ack, nak func() error) Msg {
+	return &msg{subject: subject, data: data, ack: ack, nak: nak}
+}
+
+type msg struct {
+	subject  string
+	data     []byte
+	ack, nak func() error
+}
+
+func (m *msg) Subject() string { return m.subject }
+func (m *msg) Data() []byte    { return m.data }
+func (m *msg) Ack() error      { return call(m.ack) }
+func (m *msg) Nak() error      { return call(m.nak) }
+
+func call(fn func() error) error {
+	if fn == nil {
+		return nil
+	}
+	return fn()
+}
+
+// Consumer invokes hub operations for incoming messages, routed by subject.
+type Consumer[Tx operator.Transaction] struct {
+	hub     *operator.Hub[Tx]
+	onError func(subject string, err error)
+
+	mu     sync.RWMutex
+	routes []route
+}
+
+type route struct {
+	pattern []string
+	invoke  func(ctx context.Context, data []byte) (decoded bool, err error)
+}
+
+// ConsumerOption configures a Consumer.
+type ConsumerOption func(c *consumerOptions)
+
+type consumerOptions struct {
+	onError func(subject string, err error)
+}
+
+// WithConsumerErrorHandler sets a function to be called when a message
+// cannot be handled or acknowledged. The default logs the error with
+// slog.Default().
+func WithConsumerErrorHandler(fn func(subject string, err error)) ConsumerOption {
+	return func(c *consumerOptions) { c.onError = fn }
+}
+
+// NewConsumer returns a Consumer invoking operations on hub.
+func NewConsumer[Tx operator.Transaction](hub *operator.Hub[Tx], opts ...ConsumerOption) *Consumer[Tx] {
+	o := consumerOptions{
+		onError: func(subject string, err error) {
+			slog.Error("nats message failed", "subject", subject, "error", err)
+		},
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &Consumer[Tx]{hub: hub, onError: o.onError}
+}
+
+// Handle routes messages whose subject matches subject to op, which receives
+// the message's data decoded as JSON. Subjects may contain the NATS wildcards
+// "*", matching a single token, and ">", matching one or more trailing
+// tokens. Routes are tried in the order they were added.
+func Handle[Tx operator.Transaction, I any, O any](c *Consumer[Tx], subject string, op operator.Operation[Tx, I, O]) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.routes = append(c.routes, route{
+		pattern: strings.Split(subject, "."),
+		invoke: func(ctx context.Context, data []byte) (bool, error) {
+			input := new(I)
+			if err := json.Unmarshal(data, input); err != nil {
+				return false, err
+			}
+			_, err := operator.Invoke(ctx, c.hub, op, input)
+			return true, err
+		},
+	})
+}
+
+// Dispatch invokes the operation routed to msg's subject. The message is
+// acknowledged once the operation has committed, and negatively acknowledged,
+// for redelivery, if it fails. Messages that can never succeed - those whose
+// subject has no route, or whose data cannot be decoded - are terminated if
+// msg implements Terminator, and negatively acknowledged otherwise.
+//
+// An operation may commit but its acknowledgement be lost, so operations
+// should be idempotent. Errors are reported to the error handler and
+// returned.
+func (c *Consumer[Tx]) Dispatch(ctx context.Context, msg Msg) error {
+	err := c.dispatch(ctx, msg)
+	if err != nil {
+		c.onError(msg.Subject(), err)
+	}
+	return err
+}
+
+func (c *Consumer[Tx]) dispatch(ctx context.Context, msg Msg) error {
+	subject := msg.Subject()
+	r, ok := c.route(subject)
+	if !ok {
+		return reject(msg, fmt.Errorf("%w %s", ErrNoRoute, subject))
+	}
+
+	decoded, err := r.invoke(ctx, msg.Data())
+	switch {
+	case !decoded:
+		return reject(msg, fmt.Errorf("decoding message failed (%w)", err))
+	case err != nil:
+		if nakErr := msg.Nak(); nakErr != nil {
+			return errors.Join(err, fmt.Errorf("nak failed (%w)", nakErr))
+		}
+		return err
+	}
+	if err := msg.Ack(); err != nil {
+		return fmt.Errorf("ack failed (%w)", err)
+	}
+	return nil
+}
+
+func (c *Consumer[Tx]) route(subject string) (route, bool) {
+	tokens := strings.Split(subject, ".")
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, r := range c.routes {
+		if matchSubject(r.pattern, tokens) {
+			return r, true
+		}
+	}
+	return route{}, false
+}
+
+// reject rejects msg permanently if possible, returning err.
+func reject(msg Msg, err error) error {
+	var rejectErr error
+	if t, ok := msg.(Terminator); ok {
+		rejectErr = t.Term()
+	} else {
+		rejectErr = msg.Nak()
+	}
+	if rejectErr != nil {
+		return errors.Join(err, fmt.Errorf("reject failed (%w)", rejectErr))
+	}
+	return err
+}
+
+func matchSubject(pattern, tokens []string) bool {
+	for i, p := range pattern {
+		if p == ">" {
+			return len(tokens) > i
+		}
+		if i >= len(tokens) || (p != "*" && p != tokens[i]) {
+			return false
+		}
+	}
+	return len(pattern) == len(tokens)
+}
@@ -0,0 +1,132 @@
+// Package opnats connects a hub to NATS: Publish sends events to NATS
+// subjects once the operation that emitted them has committed, and a
+// Consumer invokes operations for incoming messages, acknowledging each
+// message only once its operation has committed.
+//
+// The package depends only on small interfaces satisfied by the NATS client
+// library, so it does not import it. A *nats.Conn is a Conn, and a
+// jetstream.Msg is a Msg:
+//
+//	nc, _ := nats.Connect(nats.DefaultURL)
+//	p := opnats.NewPublisher(nc, eventcodec.New(hub), opnats.WithSubjectPrefix("events."))
+//	opnats.Publish(hub, p, &OrderPlaced{})
+//
+//	c := opnats.NewConsumer(hub)
+//	opnats.Handle(c, "orders.*.ship", ShipOrder)
+//	cons.Consume(func(msg jetstream.Msg) { c.Dispatch(context.Background(), msg) })
+//
+// To publish with JetStream, adapt its Publish method with ConnFunc.
+package opnats
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+
+	"github.com/jaz303/operator"
+	"github.com/jaz303/operator/bridge"
+	"github.com/jaz303/operator/eventcodec"
+)
+
+// Conn publishes messages to NATS subjects; *nats.Conn implements Conn.
+type Conn interface {
+	Publish(subject string, data []byte) error
+}
+
+// ConnFunc adapts a function to the Conn interface, e.g. to publish with
+// JetStream:
+//
+//	opnats.ConnFunc(func(subject string, data []byte) error {
+//		_, err := js.Publish(ctx, subject, data)
+//		return err
+//	})
+type ConnFunc func(subject string, data []byte) error
+
+func (fn ConnFunc) Publish(subject string, data []byte) error { return fn(subject, data) }
+
+// Publisher sends serialized events to NATS subjects derived from their
+// names. It also implements bridge.Publisher, so it can be used with
+// bridge.New and cloudevents.PublisherSink.
+type Publisher struct {
+	conn       Conn
+	serializer *eventcodec.Serializer
+	prefix     string
+	onError    func(subject string, err error)
+}
+
+// PublisherOption configures a Publisher.
+type PublisherOption func(p *Publisher)
+
+// WithSubjectPrefix sets a prefix prepended to event names to form subjects;
+// e.g. with the prefix "events.", UserCreated is published to
+// "events.UserCreated". By default there is no prefix.
+func WithSubjectPrefix(prefix string) PublisherOption {
+	return func(p *Publisher) { p.prefix = prefix }
+}
+
+// WithPublishErrorHandler sets a function to be called when an event
+// published by Publish cannot be sent. The default logs the error with
+// slog.Default().
+func WithPublishErrorHandler(fn func(subject string, err error)) PublisherOption {
+	return func(p *Publisher) { p.onError = fn }
+}
+
+// NewPublisher returns a Publisher sending events serialized with s via conn.
+func NewPublisher(conn Conn, s *eventcodec.Serializer, opts ...PublisherOption) *Publisher {
+	p := &Publisher{
+		conn:       conn,
+		serializer: s,
+		onError: func(subject string, err error) {
+			slog.Error("nats publish failed", "subject", subject, "error", err)
+		},
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Subject returns the subject to which events named name are published.
+func (p *Publisher) Subject(name string) string { return p.prefix + name }
+
+// Publish implements bridge.Publisher, sending msg's data verbatim.
+func (p *Publisher) Publish(ctx context.Context, msg *bridge.Message) error {
+	return p.conn.Publish(p.Subject(msg.Name), msg.Data)
+}
+
+// Publish registers an event handler on hub for each of events' types, which
+// publishes matching events via p once the emitting operation has committed.
+// Each message's data is the event's eventcodec.Envelope, as JSON, so that
+// consumers can decode it with the same serializer. Each handler's
+// destination, reported by Hub.EventTopology(), is "nats:" followed by the
+// event's subject. Events are serialized
+// when emitted; if serialization fails, the operation fails.
+//
+// Publishing is best-effort: the operation has already committed, so errors
+// are reported to the Publisher's error handler. Use a bridge.Bridge with
+// bridge.Forward, or a transactional outbox, where delivery must be
+// guaranteed.
+func Publish[Tx operator.Transaction](hub *operator.Hub[Tx], p *Publisher, events ...operator.Event) error {
+	for _, evt := range events {
+		err := hub.RegisterEventHandler(evt, func(ctx *operator.OpContext[Tx], evt operator.Event) error {
+			env, err := p.serializer.Encode(evt)
+			if err != nil {
+				return err
+			}
+			data, err := json.Marshal(env)
+			if err != nil {
+				return err
+			}
+			subject := p.Subject(env.Name)
+			return ctx.AfterFunc(func(*operator.OpContext[Tx]) {
+				if err := p.conn.Publish(subject, data); err != nil {
+					p.onError(subject, err)
+				}
+			})
+		}, operator.Destination("nats:"+p.Subject(evt.EventName())))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
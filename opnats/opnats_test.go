@@ -0,0 +1,152 @@
+package opnats
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/jaz303/operator"
+	"github.com/jaz303/operator/bridge"
+	"github.com/jaz303/operator/eventcodec"
+	"github.com/stretchr/testify/assert"
+)
+
+type nopTx struct{}
+
+func (nopTx) Commit(context.Context) error   { return nil }
+func (nopTx) Rollback(context.Context) error { return nil }
+
+func newHub() *operator.Hub[*nopTx] {
+	return operator.NewHub(func(context.Context) (*nopTx, error) { return &nopTx{}, nil })
+}
+
+type orderPlaced struct {
+	ID int `json:"id"`
+}
+
+func (*orderPlaced) EventName() string { return "orderPlaced" }
+
+type published struct {
+	subject string
+	data    []byte
+}
+
+func TestPublish(t *testing.T) {
+	hub := newHub()
+	var sent []published
+	conn := ConnFunc(func(subject string, data []byte) error {
+		sent = append(sent, published{subject, data})
+		return nil
+	})
+	p := NewPublisher(conn, eventcodec.New(hub), WithSubjectPrefix("events."))
+	assert.Nil(t, Publish(hub, p, &orderPlaced{}))
+	if topo := hub.EventTopology(); assert.Len(t, topo, 1) && assert.Len(t, topo[0].Handlers, 1) {
+		assert.Equal(t, "nats:events.orderPlaced", topo[0].Handlers[0].Destination)
+	}
+
+	_, err := operator.Invoke(context.Background(), hub, func(ctx *operator.OpContext[*nopTx], in *struct{}) (*struct{}, error) {
+		ctx.Emit(&orderPlaced{ID: 1})
+		ctx.AfterFunc(func(*operator.OpContext[*nopTx]) { assert.Len(t, sent, 0) })
+		return nil, errors.New("rolled back")
+	}, &struct{}{})
+	assert.Error(t, err)
+
+	_, err = operator.Invoke(context.Background(), hub, func(ctx *operator.OpContext[*nopTx], in *struct{}) (*struct{}, error) {
+		return in, ctx.Emit(&orderPlaced{ID: 2})
+	}, &struct{}{})
+	assert.Nil(t, err)
+
+	if assert.Len(t, sent, 1) {
+		assert.Equal(t, "events.orderPlaced", sent[0].subject)
+		var env eventcodec.Envelope
+		assert.Nil(t, json.Unmarshal(sent[0].data, &env))
+		assert.Equal(t, "orderPlaced", env.Name)
+		assert.JSONEq(t, `{"id":2}`, string(env.Data))
+	}
+
+	assert.Nil(t, p.Publish(context.Background(), &bridge.Message{Name: "raw", Data: []byte(`{}`)}))
+	assert.Equal(t, "events.raw", sent[1].subject)
+}
+
+type testMsg struct {
+	subject string
+	data    string
+	acks    []string
+	term    bool
+}
+
+func (m *testMsg) Subject() string { return m.subject }
+func (m *testMsg) Data() []byte    { return []byte(m.data) }
+func (m *testMsg) Ack() error      { m.acks = append(m.acks, "ack"); return nil }
+func (m *testMsg) Nak() error      { m.acks = append(m.acks, "nak"); return nil }
+
+type terminableMsg struct{ *testMsg }
+
+func (m terminableMsg) Term() error { m.acks = append(m.acks, "term"); return nil }
+
+type shipInput struct {
+	ID int `json:"id"`
+}
+
+func TestConsumer(t *testing.T) {
+	hub := newHub()
+	var errs []error
+	c := NewConsumer(hub, WithConsumerErrorHandler(func(subject string, err error) { errs = append(errs, err) }))
+
+	var shipped []int
+	Handle(c, "orders.*.ship", func(ctx *operator.OpContext[*nopTx], in *shipInput) (*struct{}, error) {
+		if in.ID < 0 {
+			return nil, errors.New("invalid order")
+		}
+		shipped = append(shipped, in.ID)
+		return &struct{}{}, nil
+	})
+	Handle(c, "audit.>", func(ctx *operator.OpContext[*nopTx], in *struct{}) (*struct{}, error) {
+		return in, nil
+	})
+
+	ok := &testMsg{subject: "orders.eu.ship", data: `{"id":1}`}
+	assert.Nil(t, c.Dispatch(context.Background(), ok))
+	assert.Equal(t, []string{"ack"}, ok.acks)
+	assert.Equal(t, []int{1}, shipped)
+
+	failed := &testMsg{subject: "orders.eu.ship", data: `{"id":-1}`}
+	assert.Error(t, c.Dispatch(context.Background(), failed))
+	assert.Equal(t, []string{"nak"}, failed.acks)
+
+	malformed := terminableMsg{&testMsg{subject: "orders.eu.ship", data: `{`}}
+	assert.Error(t, c.Dispatch(context.Background(), malformed))
+	assert.Equal(t, []string{"term"}, malformed.acks)
+
+	unrouted := &testMsg{subject: "orders.ship", data: `{}`}
+	assert.ErrorIs(t, c.Dispatch(context.Background(), unrouted), ErrNoRoute)
+	assert.Equal(t, []string{"nak"}, unrouted.acks)
+
+	audit := &testMsg{subject: "audit.a.b", data: `{}`}
+	assert.Nil(t, c.Dispatch(context.Background(), audit))
+	assert.Equal(t, []string{"ack"}, audit.acks)
+
+	assert.Len(t, errs, 3)
+
+	core := NewMsg("audit.x", []byte(`{}`), nil, nil)
+	assert.Nil(t, c.Dispatch(context.Background(), core))
+}
+
+func TestMatchSubject(t *testing.T) {
+	for _, tc := range []struct {
+		pattern, subject string
+		match            bool
+	}{
+		{"a.b", "a.b", true},
+		{"a.b", "a.c", false},
+		{"a.*", "a.b", true},
+		{"a.*", "a.b.c", false},
+		{"a.>", "a.b.c", true},
+		{"a.>", "a", false},
+		{"a.b.c", "a.b", false},
+	} {
+		assert.Equal(t, tc.match, matchSubject(strings.Split(tc.pattern, "."), strings.Split(tc.subject, ".")), "%s %s", tc.pattern, tc.subject)
+	}
+}
@@ -0,0 +1,96 @@
+package opsql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// fakeDriver is a database/sql driver that records the statements executed
+// on its connections, bracketed by BEGIN and COMMIT/ROLLBACK, and fails any
+// statement containing fail.
+type fakeDriver struct {
+	mu   sync.Mutex
+	log  []string
+	fail string
+}
+
+func newFakeDB() (*sql.DB, *fakeDriver) {
+	d := &fakeDriver{}
+	return sql.OpenDB(d), d
+}
+
+func (d *fakeDriver) Connect(context.Context) (driver.Conn, error) { return &fakeConn{d: d}, nil }
+func (d *fakeDriver) Driver() driver.Driver                        { return d }
+func (d *fakeDriver) Open(string) (driver.Conn, error)             { return &fakeConn{d: d}, nil }
+
+func (d *fakeDriver) record(s string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.log = append(d.log, s)
+}
+
+func (d *fakeDriver) statements() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]string(nil), d.log...)
+}
+
+type fakeConn struct {
+	d *fakeDriver
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("fakeConn: Prepare not supported")
+}
+
+func (c *fakeConn) Close() error { return nil }
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	c.d.record("BEGIN")
+	return fakeTx{c.d}, nil
+}
+
+func (c *fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if err := c.exec(query, args); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(0), nil
+}
+
+func (c *fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if err := c.exec(query, args); err != nil {
+		return nil, err
+	}
+	return fakeRows{}, nil
+}
+
+func (c *fakeConn) exec(query string, args []driver.NamedValue) error {
+	s := query
+	for _, a := range args {
+		s += fmt.Sprintf(" [%v]", a.Value)
+	}
+	c.d.record(s)
+	if c.d.fail != "" && strings.Contains(query, c.d.fail) {
+		return errors.New("fakeConn: statement failed")
+	}
+	return nil
+}
+
+type fakeTx struct {
+	d *fakeDriver
+}
+
+func (t fakeTx) Commit() error   { t.d.record("COMMIT"); return nil }
+func (t fakeTx) Rollback() error { t.d.record("ROLLBACK"); return nil }
+
+type fakeRows struct{}
+
+func (fakeRows) Columns() []string              { return nil }
+func (fakeRows) Close() error                   { return nil }
+func (fakeRows) Next(dest []driver.Value) error { return io.EOF }
@@ -0,0 +1,22 @@
+package opsql
+
+import "github.com/jaz303/operator"
+
+// AdvisoryLocks is an operator.LockProvider backed by Postgres
+// transaction-level advisory locks. Keys are hashed to 64-bit lock IDs with
+// hashtextextended(), so distinct keys may, rarely, contend. Locks are
+// released by Postgres when the operation's transaction commits or rolls
+// back; locking begins the transaction if necessary.
+//
+//	hub.SetLockProvider(opsql.AdvisoryLocks{})
+type AdvisoryLocks struct{}
+
+// Lock implements operator.LockProvider.
+func (AdvisoryLocks) Lock(ctx *operator.OpContext[*Tx], key string) (operator.Unlock, error) {
+	tx, err := ctx.Tx()
+	if err != nil {
+		return nil, err
+	}
+	_, err = tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtextextended($1, 0))", key)
+	return nil, err
+}
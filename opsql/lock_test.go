@@ -0,0 +1,27 @@
+package opsql
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jaz303/operator"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdvisoryLocks(t *testing.T) {
+	db, d := newFakeDB()
+	hub := operator.NewHub(Provider(db, Config{}))
+	assert.NoError(t, hub.SetLockProvider(AdvisoryLocks{}))
+
+	_, err := operator.Invoke(context.Background(), hub, func(ctx *operator.OpContext[*Tx], in *struct{}) (*struct{}, error) {
+		return in, ctx.Lock("account:2", "account:1")
+	}, &struct{}{})
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"BEGIN",
+		"SELECT pg_advisory_xact_lock(hashtextextended($1, 0)) [account:1]",
+		"SELECT pg_advisory_xact_lock(hashtextextended($1, 0)) [account:2]",
+		"COMMIT",
+	}, d.statements())
+}
@@ -0,0 +1,154 @@
+// Package opsql adapts database/sql transactions for use with operator,
+// attributing every query executed within an operation to that operation.
+//
+// Queries issued through a *Tx using an operation's context (i.e. the
+// *operator.OpContext, or any context derived from it) can be prefixed with
+// an SQL comment identifying the operation, in sqlcommenter format, so that
+// slow query logs and pg_stat_activity show which operation issued them;
+// they can additionally be reported to a QueryLogger.
+//
+// Transactions can also be configured from the operation's context as they
+// begin, setting Postgres parameters - the tenant, say - on which row-level
+// security policies depend; see Setting.
+package opsql
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"net/url"
+	"time"
+
+	"github.com/jaz303/operator"
+)
+
+// Config controls query tagging and logging.
+type Config struct {
+	// If true, each query is prefixed with an SQL comment identifying the
+	// operation that issued it.
+	Comment bool
+
+	// If non-nil, Logger is called after each query completes.
+	Logger QueryLogger
+
+	// Options passed to BeginTx
+	TxOptions *sql.TxOptions
+
+	// Settings applied to each transaction as it begins; see Setting.
+	Settings []Setting
+}
+
+// QueryInfo describes an executed query.
+type QueryInfo struct {
+	OperationID string
+	Operation   string
+	Query       string
+	Args        []any
+	Duration    time.Duration
+	Err         error
+}
+
+// QueryLogger receives details of each query executed through a Tx.
+type QueryLogger func(ctx context.Context, q *QueryInfo)
+
+// SlogLogger returns a QueryLogger that logs queries to l at debug level,
+// or at error level for queries that fail. Query arguments are not logged.
+func SlogLogger(l *slog.Logger) QueryLogger {
+	return func(ctx context.Context, q *QueryInfo) {
+		level := slog.LevelDebug
+		attrs := []slog.Attr{
+			slog.String("operation", q.Operation),
+			slog.String("operation_id", q.OperationID),
+			slog.String("query", q.Query),
+			slog.Duration("duration", q.Duration),
+		}
+		if q.Err != nil {
+			level = slog.LevelError
+			attrs = append(attrs, slog.Any("error", q.Err))
+		}
+		l.LogAttrs(ctx, level, "sql query", attrs...)
+	}
+}
+
+// Provider returns an operator.TransactionProvider that begins transactions
+// on db.
+func Provider(db *sql.DB, cfg Config) operator.TransactionProvider[*Tx] {
+	return func(ctx context.Context) (*Tx, error) {
+		tx, err := db.BeginTx(ctx, cfg.TxOptions)
+		if err != nil {
+			return nil, err
+		}
+		if err := applySettings(ctx, tx, cfg.Settings); err != nil {
+			_ = tx.Rollback()
+			return nil, err
+		}
+		return &Tx{Tx: tx, cfg: cfg}, nil
+	}
+}
+
+// Tx adapts *sql.Tx to operator.Transaction. Its context-accepting query
+// methods tag and log queries according to its Config.
+type Tx struct {
+	*sql.Tx
+	cfg Config
+}
+
+func (t *Tx) Commit(ctx context.Context) error   { return t.Tx.Commit() }
+func (t *Tx) Rollback(ctx context.Context) error { return t.Tx.Rollback() }
+
+func (t *Tx) ExecContext(ctx context.Context, query string, args ...any) (res sql.Result, err error) {
+	defer t.log(ctx, query, args, time.Now(), &err)
+	return t.Tx.ExecContext(ctx, t.tag(ctx, query), args...)
+}
+
+func (t *Tx) QueryContext(ctx context.Context, query string, args ...any) (rows *sql.Rows, err error) {
+	defer t.log(ctx, query, args, time.Now(), &err)
+	return t.Tx.QueryContext(ctx, t.tag(ctx, query), args...)
+}
+
+func (t *Tx) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	var err error
+	defer t.log(ctx, query, args, time.Now(), &err)
+	row := t.Tx.QueryRowContext(ctx, t.tag(ctx, query), args...)
+	err = row.Err()
+	return row
+}
+
+func (t *Tx) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return t.Tx.PrepareContext(ctx, t.tag(ctx, query))
+}
+
+func (t *Tx) tag(ctx context.Context, query string) string {
+	if !t.cfg.Comment {
+		return query
+	}
+	return Tag(ctx, query)
+}
+
+func (t *Tx) log(ctx context.Context, query string, args []any, start time.Time, err *error) {
+	if t.cfg.Logger == nil {
+		return
+	}
+	q := &QueryInfo{
+		Query:    query,
+		Args:     args,
+		Duration: time.Since(start),
+		Err:      *err,
+	}
+	if op, ok := operator.OperationFrom(ctx); ok {
+		q.OperationID = op.ID()
+		q.Operation = op.Name()
+	}
+	t.cfg.Logger(ctx, q)
+}
+
+// Tag prefixes query with an sqlcommenter-style comment identifying the
+// operation associated with ctx. If ctx is not associated with an operation,
+// query is returned unchanged.
+func Tag(ctx context.Context, query string) string {
+	op, ok := operator.OperationFrom(ctx)
+	if !ok {
+		return query
+	}
+	return "/*operation='" + url.QueryEscape(op.Name()) + "',operation_id='" + url.QueryEscape(op.ID()) + "'*/ " + query
+}
@@ -0,0 +1,104 @@
+package opsql
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/jaz303/operator"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTag(t *testing.T) {
+	assert.Equal(t, "SELECT 1", Tag(context.Background(), "SELECT 1"))
+
+	hub := operator.NewHub(Provider(nil, Config{}))
+	var tagged, want string
+	_, err := operator.Invoke(context.Background(), hub, func(ctx *operator.OpContext[*Tx], in *struct{}) (*struct{}, error) {
+		tagged = Tag(ctx, "SELECT 1")
+		want = "/*operation='" + ctx.Name() + "',operation_id='" + ctx.ID() + "'*/ SELECT 1"
+		return in, nil
+	}, &struct{}{})
+	assert.NoError(t, err)
+	assert.Equal(t, want, tagged)
+}
+
+func TestProvider_CommentAndLogger(t *testing.T) {
+	db, d := newFakeDB()
+	var logged []*QueryInfo
+	hub := operator.NewHub(Provider(db, Config{
+		Comment: true,
+		Logger:  func(ctx context.Context, q *QueryInfo) { logged = append(logged, q) },
+	}))
+
+	var id, name string
+	_, err := operator.Invoke(context.Background(), hub, func(ctx *operator.OpContext[*Tx], in *struct{}) (*struct{}, error) {
+		id, name = ctx.ID(), ctx.Name()
+		tx, err := ctx.Tx()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := tx.ExecContext(ctx, "UPDATE t SET x = $1", 1); err != nil {
+			return nil, err
+		}
+		rows, err := tx.QueryContext(ctx, "SELECT x FROM t")
+		if err != nil {
+			return nil, err
+		}
+		rows.Close()
+		return in, tx.QueryRowContext(ctx, "SELECT 2").Err()
+	}, &struct{}{})
+	assert.NoError(t, err)
+
+	prefix := "/*operation='" + name + "',operation_id='" + id + "'*/ "
+	assert.Equal(t, []string{
+		"BEGIN",
+		prefix + "UPDATE t SET x = $1 [1]",
+		prefix + "SELECT x FROM t",
+		prefix + "SELECT 2",
+		"COMMIT",
+	}, d.statements())
+
+	if assert.Len(t, logged, 3) {
+		assert.Equal(t, "UPDATE t SET x = $1", logged[0].Query, "logged queries are untagged")
+		assert.Equal(t, []any{1}, logged[0].Args)
+		assert.Equal(t, id, logged[0].OperationID)
+		assert.Equal(t, name, logged[0].Operation)
+		assert.Equal(t, "SELECT 2", logged[2].Query)
+	}
+}
+
+func TestProvider_LogsErrors(t *testing.T) {
+	db, d := newFakeDB()
+	d.fail = "boom"
+	var logged *QueryInfo
+	tx, err := Provider(db, Config{
+		Logger: func(ctx context.Context, q *QueryInfo) { logged = q },
+	})(context.Background())
+	assert.NoError(t, err)
+	defer tx.Rollback(context.Background())
+
+	_, err = tx.ExecContext(context.Background(), "SELECT boom")
+	assert.Error(t, err)
+	if assert.NotNil(t, logged) {
+		assert.Equal(t, err, logged.Err)
+		assert.Equal(t, "", logged.OperationID)
+	}
+}
+
+func TestSlogLogger(t *testing.T) {
+	var buf bytes.Buffer
+	log := SlogLogger(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+
+	log(context.Background(), &QueryInfo{Operation: "PlaceOrder", OperationID: "1", Query: "SELECT $1", Args: []any{"secret"}})
+	assert.Contains(t, buf.String(), "level=DEBUG")
+	assert.Contains(t, buf.String(), "operation=PlaceOrder")
+	assert.Contains(t, buf.String(), `query="SELECT $1"`)
+	assert.NotContains(t, buf.String(), "secret", "arguments are not logged")
+
+	buf.Reset()
+	log(context.Background(), &QueryInfo{Query: "SELECT 1", Err: assert.AnError})
+	assert.Contains(t, buf.String(), "level=ERROR")
+	assert.Contains(t, buf.String(), "error=")
+}
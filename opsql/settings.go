@@ -0,0 +1,86 @@
+package opsql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/jaz303/operator"
+)
+
+// Setting is a Postgres run-time parameter set, for the duration of each
+// transaction, from the context of the operation beginning it - typically
+// to scope the operation's queries with row-level security policies:
+//
+//	cfg := opsql.Config{Settings: []opsql.Setting{
+//		opsql.TenantSetting("app.tenant_id"),
+//		opsql.PrincipalSetting("app.user_id"),
+//	}}
+//
+//	CREATE POLICY tenant_isolation ON orders
+//	    USING (tenant_id = current_setting('app.tenant_id')::uuid);
+//
+// Settings are applied with set_config(name, value, true), the equivalent
+// of SET LOCAL, in a single statement, so revert when the transaction
+// commits or rolls back and never leak to other users of a pooled
+// connection. Custom parameters must have a dotted name.
+type Setting struct {
+	// Name of the parameter, e.g. "app.tenant_id"
+	Name string
+
+	// Value returns the parameter's value, and false if the parameter should
+	// be left unset for the operation whose context is ctx.
+	Value func(ctx context.Context) (string, bool)
+}
+
+// TenantSetting sets the parameter name to the operation's tenant; see
+// operator.WithTenant().
+func TenantSetting(name string) Setting {
+	return Setting{Name: name, Value: operator.TenantFrom}
+}
+
+// PrincipalSetting sets the parameter name to the subject of the operation's
+// principal; see operator.WithPrincipal().
+func PrincipalSetting(name string) Setting {
+	return Setting{Name: name, Value: func(ctx context.Context) (string, bool) {
+		p, ok := operator.PrincipalFrom(ctx)
+		if !ok || p == nil {
+			return "", false
+		}
+		return p.Subject(), true
+	}}
+}
+
+// OperationSetting sets the parameter name to the name of the operation, e.g.
+// for use as application_name.
+func OperationSetting(name string) Setting {
+	return Setting{Name: name, Value: func(ctx context.Context) (string, bool) {
+		op, ok := operator.OperationFrom(ctx)
+		if !ok {
+			return "", false
+		}
+		return op.Name(), true
+	}}
+}
+
+// applySettings applies settings to tx, which was begun with ctx.
+func applySettings(ctx context.Context, tx *sql.Tx, settings []Setting) error {
+	var calls []string
+	var args []any
+	for _, s := range settings {
+		v, ok := s.Value(ctx)
+		if !ok {
+			continue
+		}
+		calls = append(calls, fmt.Sprintf("set_config($%d, $%d, true)", len(args)+1, len(args)+2))
+		args = append(args, s.Name, v)
+	}
+	if len(calls) == 0 {
+		return nil
+	}
+	if _, err := tx.ExecContext(ctx, "SELECT "+strings.Join(calls, ", "), args...); err != nil {
+		return fmt.Errorf("applying settings failed (%w)", err)
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package opsql
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jaz303/operator"
+	"github.com/stretchr/testify/assert"
+)
+
+type testPrincipal string
+
+func (p testPrincipal) Subject() string { return string(p) }
+
+var rlsSettings = []Setting{
+	TenantSetting("app.tenant_id"),
+	PrincipalSetting("app.user_id"),
+}
+
+func TestSettings(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		tenant    string
+		principal operator.Principal
+		want      []string
+	}{
+		{"both", "acme", testPrincipal("u1"), []string{
+			"BEGIN",
+			"SELECT set_config($1, $2, true), set_config($3, $4, true) [app.tenant_id] [acme] [app.user_id] [u1]",
+		}},
+		{"no tenant", "", testPrincipal("u1"), []string{
+			"BEGIN",
+			"SELECT set_config($1, $2, true) [app.user_id] [u1]",
+		}},
+		{"no principal", "acme", nil, []string{
+			"BEGIN",
+			"SELECT set_config($1, $2, true) [app.tenant_id] [acme]",
+		}},
+		{"neither", "", nil, []string{"BEGIN"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			db, d := newFakeDB()
+			ctx := context.Background()
+			if tc.tenant != "" {
+				ctx = operator.WithTenant(ctx, tc.tenant)
+			}
+			if tc.principal != nil {
+				ctx = operator.WithPrincipal(ctx, tc.principal)
+			}
+
+			tx, err := Provider(db, Config{Settings: rlsSettings})(ctx)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want, d.statements())
+			assert.NoError(t, tx.Rollback(ctx))
+		})
+	}
+}
+
+func TestPrincipalSetting_Nil(t *testing.T) {
+	_, ok := PrincipalSetting("app.user_id").Value(operator.WithPrincipal(context.Background(), nil))
+	assert.False(t, ok)
+}
+
+func TestSettings_AppliedInsideOperationTx(t *testing.T) {
+	db, d := newFakeDB()
+	hub := operator.NewHub(Provider(db, Config{Settings: rlsSettings}))
+
+	ctx := operator.WithPrincipal(operator.WithTenant(context.Background(), "acme"), testPrincipal("u1"))
+	_, err := operator.Invoke(ctx, hub, func(ctx *operator.OpContext[*Tx], in *struct{}) (*struct{}, error) {
+		tx, err := ctx.Tx()
+		if err != nil {
+			return nil, err
+		}
+		_, err = tx.ExecContext(ctx, "DELETE FROM orders")
+		return in, err
+	}, &struct{}{})
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"BEGIN",
+		"SELECT set_config($1, $2, true), set_config($3, $4, true) [app.tenant_id] [acme] [app.user_id] [u1]",
+		"DELETE FROM orders",
+		"COMMIT",
+	}, d.statements())
+}
+
+func TestSettings_FailureRollsBack(t *testing.T) {
+	db, d := newFakeDB()
+	d.fail = "set_config"
+
+	ctx := operator.WithTenant(context.Background(), "acme")
+	tx, err := Provider(db, Config{Settings: rlsSettings})(ctx)
+	assert.Nil(t, tx)
+	assert.ErrorContains(t, err, "applying settings failed")
+	assert.Equal(t, []string{
+		"BEGIN",
+		"SELECT set_config($1, $2, true) [app.tenant_id] [acme]",
+		"ROLLBACK",
+	}, d.statements())
+}
+
+func TestOperationSetting(t *testing.T) {
+	db, d := newFakeDB()
+	hub := operator.NewHub(Provider(db, Config{Settings: []Setting{OperationSetting("application_name")}}))
+
+	var name string
+	_, err := operator.Invoke(context.Background(), hub, func(ctx *operator.OpContext[*Tx], in *struct{}) (*struct{}, error) {
+		name = ctx.Name()
+		_, err := ctx.Tx()
+		return in, err
+	}, &struct{}{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"BEGIN",
+		"SELECT set_config($1, $2, true) [application_name] [" + name + "]",
+		"COMMIT",
+	}, d.statements())
+}
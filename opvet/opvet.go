@@ -0,0 +1,169 @@
+// Package opvet checks that the event handlers registered with a hub have
+// signatures the hub accepts. Without it, a mismatched handler is reported
+// only by a panic when the handler is registered, or by an error when an
+// event is dispatched to it.
+//
+// The checks run as a vet tool (see cmd/operator-vet):
+//
+//	go install github.com/jaz303/operator/cmd/operator-vet
+//	go vet -vettool=$(which operator-vet) ./...
+//
+// Calls to Hub.RegisterEventHandler() and Hub.AttachEventHandler() are
+// checked to pass a handler taking one of the forms described by
+// RegisterEventHandler(), whose event parameter accepts the registered
+// event; calls to Hub.RegisterNamedEventHandler() and
+// Hub.AttachNamedEventHandler() are checked to pass a handler of one of
+// those forms.
+//
+// This module does not depend on golang.org/x/tools, so opvet does not
+// export an analysis.Analyzer; one is a thin wrapper around Run:
+//
+//	var Analyzer = &analysis.Analyzer{
+//		Name: "operatorhandlers",
+//		Doc:  "check the signatures of operator event handlers",
+//		Run: func(pass *analysis.Pass) (any, error) {
+//			for _, d := range opvet.Run(pass.Files, pass.TypesInfo) {
+//				pass.Reportf(d.Pos, "%s", d.Message)
+//			}
+//			return nil, nil
+//		},
+//	}
+package opvet
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+)
+
+const operatorPath = "github.com/jaz303/operator"
+
+// Diagnostic is a problem found by Run.
+type Diagnostic struct {
+	Pos     token.Pos
+	Message string
+}
+
+// Run checks the event handler registrations in files, whose types are
+// recorded in info.Types, and returns the problems found.
+func Run(files []*ast.File, info *types.Info) []Diagnostic {
+	var out []Diagnostic
+	for _, f := range files {
+		ast.Inspect(f, func(n ast.Node) bool {
+			if call, ok := n.(*ast.CallExpr); ok {
+				out = append(out, checkCall(info, call)...)
+			}
+			return true
+		})
+	}
+	return out
+}
+
+func checkCall(info *types.Info, call *ast.CallExpr) []Diagnostic {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return nil
+	}
+	var named bool
+	switch sel.Sel.Name {
+	case "RegisterEventHandler", "AttachEventHandler":
+	case "RegisterNamedEventHandler", "AttachNamedEventHandler":
+		named = true
+	default:
+		return nil
+	}
+	hub := hubType(info.TypeOf(sel.X))
+	if hub == nil || len(call.Args) < 2 {
+		return nil
+	}
+
+	var event types.Type
+	if !named {
+		// the event's dynamic type is only known if its static type is
+		// concrete
+		if t := info.TypeOf(call.Args[0]); t != nil && !types.IsInterface(t) {
+			event = t
+		}
+	}
+
+	hnd := call.Args[1]
+	if msg := checkHandler(hub, info.TypeOf(hnd), event); msg != "" {
+		return []Diagnostic{{Pos: hnd.Pos(), Message: msg}}
+	}
+	return nil
+}
+
+// hubType returns t as an instantiated *operator.Hub, or nil if it is not
+// one.
+func hubType(t types.Type) *types.Named {
+	ptr, ok := t.(*types.Pointer)
+	if !ok {
+		return nil
+	}
+	named, ok := ptr.Elem().(*types.Named)
+	if !ok {
+		return nil
+	}
+	obj := named.Obj()
+	if obj.Pkg() == nil || obj.Pkg().Path() != operatorPath || obj.Name() != "Hub" || named.TypeArgs().Len() != 1 {
+		return nil
+	}
+	return named
+}
+
+var errorType = types.Universe.Lookup("error").Type().Underlying().(*types.Interface)
+
+// checkHandler returns a message describing the problem with handlers of
+// type t registered with hub for events of type event, which is nil if
+// unknown, or the empty string if there is none. The rules are those of
+// operator.makeEventHandler.
+func checkHandler(hub *types.Named, t, event types.Type) string {
+	if t == nil || types.IsInterface(t) {
+		// a handler of interface type is only checked when it is registered
+		return ""
+	}
+	sig, ok := t.Underlying().(*types.Signature)
+	if !ok {
+		return fmt.Sprintf("event handler must be a function, not %s", t)
+	}
+
+	switch res := sig.Results(); res.Len() {
+	case 0:
+	case 1:
+		if !types.Implements(res.At(0).Type(), errorType) {
+			return fmt.Sprintf("event handler return type %s does not implement error", res.At(0).Type())
+		}
+	default:
+		return "event handler must return 0..1 values"
+	}
+
+	params := sig.Params()
+	if params.Len() < 1 || params.Len() > 2 {
+		return "event handler must declare 1..2 parameters"
+	}
+	if params.Len() == 2 {
+		ctx := opContextType(hub)
+		if ctx != nil && !types.AssignableTo(ctx, params.At(0).Type()) {
+			return fmt.Sprintf("%s is not assignable to event handler context parameter %s", ctx, params.At(0).Type())
+		}
+	}
+	if evt := params.At(params.Len() - 1).Type(); event != nil && !types.AssignableTo(event, evt) {
+		return fmt.Sprintf("event type %s is not assignable to event handler parameter %s", event, evt)
+	}
+	return ""
+}
+
+// opContextType returns *operator.OpContext[Tx] for the *operator.Hub[Tx]
+// hub.
+func opContextType(hub *types.Named) types.Type {
+	obj, ok := hub.Obj().Pkg().Scope().Lookup("OpContext").(*types.TypeName)
+	if !ok {
+		return nil
+	}
+	inst, err := types.Instantiate(nil, obj.Type(), []types.Type{hub.TypeArgs().At(0)}, false)
+	if err != nil {
+		return nil
+	}
+	return types.NewPointer(inst)
+}
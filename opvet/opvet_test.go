@@ -0,0 +1,42 @@
+package opvet
+
+import (
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRun(t *testing.T) {
+	if testing.Short() {
+		t.Skip("type-checks dependencies from source")
+	}
+
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, filepath.Join("testdata", "bad", "bad.go"), nil, 0)
+	assert.Nil(t, err)
+	info := &types.Info{Types: map[ast.Expr]types.TypeAndValue{}}
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	_, err = conf.Check("github.com/jaz303/operator/opvet/testdata/bad", fset, []*ast.File{f}, info)
+	assert.Nil(t, err)
+
+	var got []string
+	for _, d := range Run([]*ast.File{f}, info) {
+		got = append(got, fmt.Sprintf("%d: %s", fset.Position(d.Pos).Line, d.Message))
+	}
+	assert.Equal(t, []string{
+		"32: event type *github.com/jaz303/operator/opvet/testdata/bad.Created is not assignable to event handler parameter *github.com/jaz303/operator/opvet/testdata/bad.Deleted",
+		"33: *github.com/jaz303/operator.OpContext[github.com/jaz303/operator/opvet/testdata/bad.Tx] is not assignable to event handler context parameter string",
+		"34: event handler return type int does not implement error",
+		"35: event handler must declare 1..2 parameters",
+		"36: event handler must be a function, not string",
+		"37: event handler must return 0..1 values",
+		"43: *github.com/jaz303/operator.OpContext[T] is not assignable to event handler context parameter *github.com/jaz303/operator.OpContext[github.com/jaz303/operator/opvet/testdata/bad.Tx]",
+	}, got)
+}
@@ -0,0 +1,44 @@
+package bad
+
+import (
+	"context"
+
+	"github.com/jaz303/operator"
+)
+
+type Tx struct{}
+
+func (Tx) Commit(context.Context) error   { return nil }
+func (Tx) Rollback(context.Context) error { return nil }
+
+type Created struct{}
+
+func (*Created) EventName() string { return "created" }
+
+type Deleted struct{}
+
+func (*Deleted) EventName() string { return "deleted" }
+
+type myErr struct{}
+
+func (*myErr) Error() string { return "" }
+
+func Register(hub *operator.Hub[Tx], evt operator.Event) {
+	hub.RegisterEventHandler(&Created{}, func(ctx *operator.OpContext[Tx], e *Created) error { return nil })
+	hub.RegisterEventHandler(&Created{}, func(ctx context.Context, e operator.Event) {})
+	hub.RegisterEventHandler(&Created{}, func(e *Created) *myErr { return nil })
+	hub.RegisterEventHandler(evt, func(e *Deleted) {})
+
+	hub.RegisterEventHandler(&Created{}, func(e *Deleted) {})                           // want: event type
+	hub.RegisterEventHandler(&Created{}, func(ctx string, e *Created) {})               // want: context
+	hub.RegisterEventHandler(&Created{}, func(e *Created) int { return 0 })             // want: return type
+	hub.RegisterEventHandler(&Created{}, func() {})                                     // want: parameters
+	hub.AttachEventHandler(&Created{}, "not a func")                                    // want: function
+	hub.RegisterNamedEventHandler("x", func(e *Deleted) (int, error) { return 0, nil }) // want: values
+	hub.RegisterNamedEventHandler("x", func(e *Deleted) {})
+}
+
+func Generic[T operator.Transaction](hub *operator.Hub[T]) {
+	hub.RegisterEventHandler(&Created{}, func(ctx *operator.OpContext[T], e *Created) {})
+	hub.RegisterEventHandler(&Created{}, func(ctx *operator.OpContext[Tx], e *Created) {}) // want: context
+}
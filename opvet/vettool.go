@@ -0,0 +1,213 @@
+package opvet
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Name is the name under which the checks report diagnostics.
+const Name = "operatorhandlers"
+
+// Main runs the checks as a vet tool, implementing the protocol by which
+// "go vet -vettool" invokes it: the go command first runs the tool with
+// -V=full and -flags to identify it, and then once per package with the path
+// of a JSON file describing the package. Diagnostics are printed to standard
+// error, or with -json, to standard output as JSON.
+func Main() {
+	prog := filepath.Base(os.Args[0])
+	fs := flag.NewFlagSet(prog, flag.ExitOnError)
+	version := fs.String("V", "", "print version and exit")
+	printFlags := fs.Bool("flags", false, "print flags as JSON and exit")
+	jsonOutput := fs.Bool("json", false, "print diagnostics as JSON")
+	fs.Int("c", -1, "ignored")
+	fs.Bool("fix", false, "ignored")
+	fs.Bool("diff", false, "ignored")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: go vet -vettool=$(which %s) [packages]\n", prog)
+		os.Exit(2)
+	}
+	fs.Parse(os.Args[1:])
+
+	switch {
+	case *version != "":
+		id, err := executableID()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", prog, err)
+			os.Exit(1)
+		}
+		fmt.Printf("%s version devel buildID=%s\n", prog, id)
+		return
+	case *printFlags:
+		fmt.Println("[]")
+		return
+	case fs.NArg() != 1 || !strings.HasSuffix(fs.Arg(0), ".cfg"):
+		fs.Usage()
+	}
+
+	cfg, diags, err := checkConfig(fs.Arg(0))
+	if *jsonOutput {
+		// map of package -> analyzer -> diagnostics or error
+		var result any = diags
+		if err != nil {
+			result = map[string]string{"error": err.Error()}
+		} else if len(diags) == 0 {
+			return
+		}
+		if cfg == nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", prog, err)
+			os.Exit(1)
+		}
+		out := os.Stdout
+		if cfg.Stdout != "" {
+			if out, err = os.Create(cfg.Stdout); err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", prog, err)
+				os.Exit(1)
+			}
+			defer out.Close()
+		}
+		json.NewEncoder(out).Encode(map[string]map[string]any{cfg.ImportPath: {Name: result}})
+		return
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", prog, err)
+		os.Exit(1)
+	}
+	for _, d := range diags {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", d.Posn, d.Message)
+	}
+	if len(diags) > 0 {
+		os.Exit(1)
+	}
+}
+
+// executableID returns a hash of the running executable, by which the go
+// command caches the tool's results.
+func executableID() (string, error) {
+	path, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+// vetConfig describes a package to check; it is written by the go command.
+type vetConfig struct {
+	Compiler                  string
+	Dir                       string
+	ImportPath                string
+	GoVersion                 string
+	GoFiles                   []string
+	ImportMap                 map[string]string
+	PackageFile               map[string]string
+	VetxOnly                  bool
+	VetxOutput                string
+	SucceedOnTypecheckFailure bool
+
+	// File to which output for the go command is written, rather than
+	// standard output
+	Stdout string
+}
+
+// jsonDiagnostic is a diagnostic in the form printed by vet tools.
+type jsonDiagnostic struct {
+	Posn    string `json:"posn"`
+	Message string `json:"message"`
+}
+
+// checkConfig checks the package described by the vet config file at path,
+// returning the config and the package's diagnostics.
+func checkConfig(path string) (*vetConfig, []jsonDiagnostic, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	cfg := &vetConfig{}
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, nil, fmt.Errorf("reading %s failed (%w)", path, err)
+	}
+	diags, err := check(cfg)
+	return cfg, diags, err
+}
+
+func check(cfg *vetConfig) ([]jsonDiagnostic, error) {
+	// the tool records no facts about packages, but the go command expects
+	// their file to be written
+	if cfg.VetxOutput != "" {
+		if err := os.WriteFile(cfg.VetxOutput, nil, 0o666); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.VetxOnly {
+		return nil, nil
+	}
+
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, name := range cfg.GoFiles {
+		f, err := parser.ParseFile(fset, name, nil, parser.SkipObjectResolution)
+		if err != nil {
+			if cfg.SucceedOnTypecheckFailure {
+				return nil, nil
+			}
+			return nil, err
+		}
+		files = append(files, f)
+	}
+
+	imp := importer.ForCompiler(fset, cfg.Compiler, func(path string) (io.ReadCloser, error) {
+		file, ok := cfg.PackageFile[path]
+		if !ok {
+			return nil, fmt.Errorf("no export data for package %q", path)
+		}
+		return os.Open(file)
+	})
+	conf := types.Config{
+		Importer:  importerFunc(func(path string) (*types.Package, error) { return imp.Import(mapImport(cfg.ImportMap, path)) }),
+		GoVersion: cfg.GoVersion,
+		Sizes:     types.SizesFor(cfg.Compiler, os.Getenv("GOARCH")),
+	}
+	info := &types.Info{Types: map[ast.Expr]types.TypeAndValue{}}
+	if _, err := conf.Check(cfg.ImportPath, fset, files, info); err != nil {
+		if cfg.SucceedOnTypecheckFailure {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var out []jsonDiagnostic
+	for _, d := range Run(files, info) {
+		out = append(out, jsonDiagnostic{Posn: fset.Position(d.Pos).String(), Message: d.Message})
+	}
+	return out, nil
+}
+
+func mapImport(m map[string]string, path string) string {
+	if p, ok := m[path]; ok {
+		return p
+	}
+	return path
+}
+
+type importerFunc func(path string) (*types.Package, error)
+
+func (f importerFunc) Import(path string) (*types.Package, error) { return f(path) }
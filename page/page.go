@@ -0,0 +1,105 @@
+// Package page provides a standard shape for paginated list operations: a
+// PageRequest extracted from query parameters, and a PageResult[T] output
+// envelope that renders as JSON and advertises adjacent pages with Link
+// headers.
+//
+// Both offset-based (limit/offset) and cursor-based (limit/cursor) pagination
+// are supported; an operation uses whichever it prefers.
+package page
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/jaz303/operator/operr"
+)
+
+// Config determines the limits applied when parsing a PageRequest.
+type Config struct {
+	// Limit used when the request does not specify one
+	DefaultLimit int
+
+	// Largest limit a request may specify
+	MaxLimit int
+}
+
+// Default is the configuration used by the package-level Parse and Input
+// functions.
+var Default = Config{DefaultLimit: 20, MaxLimit: 100}
+
+// PageRequest identifies the page of results requested by a client. It can be
+// obtained with Parse(), or embedded in an input type populated by
+// httpbind.BindRequest, in which case defaults must be applied by the
+// operation.
+type PageRequest struct {
+	Limit  int    `query:"limit" json:"limit"`
+	Offset int    `query:"offset" json:"offset,omitempty"`
+	Cursor string `query:"cursor" json:"cursor,omitempty"`
+
+	// URL of the request, used to construct Link headers
+	url *url.URL
+}
+
+// Parse extracts a PageRequest from r's limit, offset and cursor query
+// parameters, applying c's default and maximum limit. Invalid values are
+// returned as operr.FieldErrors.
+func (c Config) Parse(r *http.Request) (PageRequest, error) {
+	q := r.URL.Query()
+	out := PageRequest{
+		Limit:  c.DefaultLimit,
+		Cursor: q.Get("cursor"),
+		url:    r.URL,
+	}
+
+	var errs operr.FieldErrors
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			errs = append(errs, operr.FieldError{Field: "limit", Source: "query", Message: "must be a positive integer"})
+		} else if c.MaxLimit > 0 && n > c.MaxLimit {
+			errs = append(errs, operr.FieldError{Field: "limit", Source: "query", Message: "must not exceed " + strconv.Itoa(c.MaxLimit)})
+		} else {
+			out.Limit = n
+		}
+	}
+	if v := q.Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			errs = append(errs, operr.FieldError{Field: "offset", Source: "query", Message: "must be a non-negative integer"})
+		} else {
+			out.Offset = n
+		}
+	}
+	if out.Cursor != "" && out.Offset != 0 {
+		errs = append(errs, operr.FieldError{Field: "cursor", Source: "query", Message: "cannot be combined with offset"})
+	}
+
+	if len(errs) > 0 {
+		return out, errs
+	}
+	return out, nil
+}
+
+// Parse extracts a PageRequest from r using the Default configuration.
+func Parse(r *http.Request) (PageRequest, error) {
+	return Default.Parse(r)
+}
+
+// Input returns an input mapper that parses the request's PageRequest using
+// the Default configuration and passes it, along with the request, to fn to
+// produce the operation's input.
+func Input[I any](fn func(r *http.Request, p PageRequest) (*I, error)) func(r *http.Request) (*I, error) {
+	return InputWith(Default, fn)
+}
+
+// InputWith is like Input but parses the PageRequest using c.
+func InputWith[I any](c Config, fn func(r *http.Request, p PageRequest) (*I, error)) func(r *http.Request) (*I, error) {
+	return func(r *http.Request) (*I, error) {
+		p, err := c.Parse(r)
+		if err != nil {
+			return nil, err
+		}
+		return fn(r, p)
+	}
+}
@@ -0,0 +1,53 @@
+package page
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jaz303/operator/operr"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	p, err := Parse(httptest.NewRequest(http.MethodGet, "/items", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, 20, p.Limit)
+	assert.Equal(t, 0, p.Offset)
+
+	p, err = Parse(httptest.NewRequest(http.MethodGet, "/items?limit=5&offset=10", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, 5, p.Limit)
+	assert.Equal(t, 10, p.Offset)
+
+	_, err = Parse(httptest.NewRequest(http.MethodGet, "/items?limit=500&offset=-1", nil))
+	var fieldErrs operr.FieldErrors
+	assert.ErrorAs(t, err, &fieldErrs)
+	assert.Len(t, fieldErrs, 2)
+}
+
+func TestPageResult_OffsetLinks(t *testing.T) {
+	req, _ := Parse(httptest.NewRequest(http.MethodGet, "/items?limit=2&offset=2&q=x", nil))
+	res := NewResult(req, []int{3, 4}).WithTotal(5)
+
+	h := http.Header{}
+	res.SetHTTPHeaders(h)
+	assert.Equal(t, `</items?limit=2&offset=4&q=x>; rel="next", </items?limit=2&q=x>; rel="prev"`, h.Get("Link"))
+
+	req, _ = Parse(httptest.NewRequest(http.MethodGet, "/items?limit=2&offset=4", nil))
+	h = http.Header{}
+	NewResult(req, []int{5}).WithTotal(5).SetHTTPHeaders(h)
+	assert.Equal(t, `</items?limit=2&offset=2>; rel="prev"`, h.Get("Link"))
+}
+
+func TestPageResult_CursorLinks(t *testing.T) {
+	req, _ := Parse(httptest.NewRequest(http.MethodGet, "/items?cursor=abc", nil))
+
+	h := http.Header{}
+	NewResult(req, []int{1}).WithNextCursor("def").SetHTTPHeaders(h)
+	assert.Equal(t, `</items?cursor=def&limit=20>; rel="next"`, h.Get("Link"))
+
+	h = http.Header{}
+	NewResult(req, []int{1}).SetHTTPHeaders(h)
+	assert.Empty(t, h.Get("Link"))
+}
@@ -0,0 +1,108 @@
+package page
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// PageResult is the output envelope for a page of results.
+//
+// PageResult implements httpbind.HeaderSetter: when the result was created by
+// NewResult from a PageRequest obtained with Parse, the default output mappers
+// add a Link header with rel="next" and rel="prev" URLs as appropriate.
+type PageResult[T any] struct {
+	Items []T `json:"items"`
+
+	// Total number of items across all pages, if known
+	Total *int `json:"total,omitempty"`
+
+	// Cursor identifying the next page; empty if this is the last page or
+	// the operation uses offset pagination
+	NextCursor string `json:"next_cursor,omitempty"`
+
+	req PageRequest
+}
+
+// NewResult returns a PageResult containing items in response to req.
+// Use WithTotal and WithNextCursor to supply additional details.
+func NewResult[T any](req PageRequest, items []T) *PageResult[T] {
+	if items == nil {
+		items = []T{}
+	}
+	return &PageResult[T]{Items: items, req: req}
+}
+
+// WithTotal sets the total number of items across all pages.
+func (p *PageResult[T]) WithTotal(total int) *PageResult[T] {
+	p.Total = &total
+	return p
+}
+
+// WithNextCursor sets the cursor identifying the next page.
+func (p *PageResult[T]) WithNextCursor(cursor string) *PageResult[T] {
+	p.NextCursor = cursor
+	return p
+}
+
+// SetHTTPHeaders adds a Link header advertising adjacent pages.
+func (p *PageResult[T]) SetHTTPHeaders(h http.Header) {
+	if p.req.url == nil {
+		return
+	}
+
+	var links []string
+	if next, ok := p.next(); ok {
+		links = append(links, `<`+next+`>; rel="next"`)
+	}
+	if prev, ok := p.prev(); ok {
+		links = append(links, `<`+prev+`>; rel="prev"`)
+	}
+	if len(links) > 0 {
+		h.Set("Link", strings.Join(links, ", "))
+	}
+}
+
+func (p *PageResult[T]) next() (string, bool) {
+	if p.NextCursor != "" {
+		return p.link(func(q url.Values) {
+			q.Del("offset")
+			q.Set("cursor", p.NextCursor)
+		}), true
+	}
+	if p.req.Cursor != "" {
+		return "", false
+	}
+
+	next := p.req.Offset + p.req.Limit
+	if p.Total != nil && next >= *p.Total {
+		return "", false
+	} else if p.Total == nil && len(p.Items) < p.req.Limit {
+		return "", false
+	}
+	return p.link(func(q url.Values) { q.Set("offset", strconv.Itoa(next)) }), true
+}
+
+func (p *PageResult[T]) prev() (string, bool) {
+	if p.req.Cursor != "" || p.req.Offset == 0 {
+		return "", false
+	}
+	prev := max(p.req.Offset-p.req.Limit, 0)
+	return p.link(func(q url.Values) {
+		if prev == 0 {
+			q.Del("offset")
+		} else {
+			q.Set("offset", strconv.Itoa(prev))
+		}
+	}), true
+}
+
+func (p *PageResult[T]) link(fn func(q url.Values)) string {
+	u := *p.req.url
+	q := u.Query()
+	q.Set("limit", strconv.Itoa(p.req.Limit))
+	fn(q)
+	u.RawQuery = q.Encode()
+	return u.RequestURI()
+}
@@ -0,0 +1,32 @@
+package operator
+
+import "context"
+
+// Principal is the authenticated identity on whose behalf an operation is
+// invoked. Applications typically define their own principal type carrying
+// roles, scopes or tenant information, and recover it with a type assertion.
+type Principal interface {
+	// Stable identifier of the principal, e.g. a user ID
+	Subject() string
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying p. Bindings use this to make
+// the authenticated principal available to the operation they invoke.
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFrom returns the principal carried by ctx, if any.
+func PrincipalFrom(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
+
+// Principal returns the principal on whose behalf the operation was invoked,
+// or nil if the operation is unauthenticated.
+func (o *OpContext[T]) Principal() Principal {
+	p, _ := PrincipalFrom(o)
+	return p
+}
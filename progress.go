@@ -0,0 +1,41 @@
+package operator
+
+import "context"
+
+// Progress describes how far a long-running operation has got. Total is zero
+// when the amount of work is unknown.
+type Progress struct {
+	Done    int64  `json:"done"`
+	Total   int64  `json:"total,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// ProgressSink receives progress reported by operations. ctx is the reporting
+// operation's context, so OperationFrom() identifies the operation.
+// Implementations must not block, as they are called synchronously from
+// OpContext.ReportProgress().
+type ProgressSink interface {
+	ReportProgress(ctx context.Context, p Progress)
+}
+
+// ProgressFunc adapts a function to the ProgressSink interface.
+type ProgressFunc func(ctx context.Context, p Progress)
+
+func (fn ProgressFunc) ReportProgress(ctx context.Context, p Progress) { fn(ctx, p) }
+
+type progressKey struct{}
+
+// WithProgressSink() returns a copy of ctx in which operations, and any
+// operations they invoke in turn, report progress to sink.
+func WithProgressSink(ctx context.Context, sink ProgressSink) context.Context {
+	return context.WithValue(ctx, progressKey{}, sink)
+}
+
+// ReportProgress() reports that done of total units of work have been
+// completed, with an optional human-readable message. It is a no-op unless
+// the operation was invoked with a context carrying a ProgressSink.
+func (o *OpContext[T]) ReportProgress(done, total int64, msg string) {
+	if sink, ok := o.Context.Value(progressKey{}).(ProgressSink); ok {
+		sink.ReportProgress(o, Progress{Done: done, Total: total, Message: msg})
+	}
+}
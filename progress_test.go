@@ -0,0 +1,35 @@
+package operator
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReportProgress(t *testing.T) {
+	hub := newTestHub()
+
+	var got []Progress
+	var names []string
+	sink := ProgressFunc(func(ctx context.Context, p Progress) {
+		op, _ := OperationFrom(ctx)
+		names = append(names, op.Name())
+		got = append(got, p)
+	})
+
+	op := func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) {
+		ctx.ReportProgress(1, 2, "halfway")
+		ctx.ReportProgress(2, 2, "")
+		return in, nil
+	}
+	RegisterOperation(hub, "import", op)
+
+	_, err := Invoke(WithProgressSink(context.Background(), sink), hub, op, &struct{}{})
+	assert.Nil(t, err)
+	assert.Equal(t, []Progress{{Done: 1, Total: 2, Message: "halfway"}, {Done: 2, Total: 2}}, got)
+	assert.Equal(t, []string{"import", "import"}, names)
+
+	_, err = Invoke(context.Background(), hub, op, &struct{}{})
+	assert.Nil(t, err, "reporting without a sink is a no-op")
+}
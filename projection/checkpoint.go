@@ -0,0 +1,68 @@
+package projection
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// MemoryCheckpoints stores checkpoints in memory, for tests and for
+// projections that are rebuilt whenever the process starts.
+type MemoryCheckpoints struct {
+	mu        sync.Mutex
+	positions map[string]int64
+}
+
+// NewMemoryCheckpoints returns an empty MemoryCheckpoints.
+func NewMemoryCheckpoints() *MemoryCheckpoints {
+	return &MemoryCheckpoints{positions: map[string]int64{}}
+}
+
+func (m *MemoryCheckpoints) Load(ctx context.Context, projection string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.positions[projection], nil
+}
+
+func (m *MemoryCheckpoints) Save(ctx context.Context, projection string, position int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.positions[projection] = position
+	return nil
+}
+
+// CheckpointSchema is the Postgres DDL for the table used by SQLCheckpoints,
+// with %s in place of the table's name.
+const CheckpointSchema = `CREATE TABLE %s (
+	projection TEXT PRIMARY KEY,
+	position   BIGINT NOT NULL
+)`
+
+// SQLCheckpoints stores checkpoints in a Postgres table created with
+// CheckpointSchema.
+type SQLCheckpoints struct {
+	db    *sql.DB
+	table string
+}
+
+// NewSQLCheckpoints returns a SQLCheckpoints using the named table in db.
+func NewSQLCheckpoints(db *sql.DB, table string) *SQLCheckpoints {
+	return &SQLCheckpoints{db: db, table: table}
+}
+
+func (s *SQLCheckpoints) Load(ctx context.Context, projection string) (int64, error) {
+	var position int64
+	err := s.db.QueryRowContext(ctx, fmt.Sprintf("SELECT position FROM %s WHERE projection = $1", s.table), projection).Scan(&position)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	return position, err
+}
+
+func (s *SQLCheckpoints) Save(ctx context.Context, projection string, position int64) error {
+	_, err := s.db.ExecContext(ctx, fmt.Sprintf(`INSERT INTO %s (projection, position) VALUES ($1, $2)
+		ON CONFLICT (projection) DO UPDATE SET position = EXCLUDED.position`, s.table), projection, position)
+	return err
+}
@@ -0,0 +1,467 @@
+// Package projection maintains read models by tailing the events stored in
+// an eventstore.Store and passing them to projection handlers, which are
+// ordinary hub operations taking an event as input.
+//
+//	balances := projection.New[*opsql.Tx]("balances")
+//	projection.On(balances, ApplyDeposit)  // Operation[*opsql.Tx, Deposited, struct{}]
+//	projection.On(balances, ApplyWithdrawal)
+//	balances.OnReset(TruncateBalances)
+//
+//	r := projection.NewRunner(hub, store, projection.NewSQLCheckpoints(db, "projection_checkpoints"))
+//	r.Add(balances)
+//	go r.Run(ctx)
+//
+// Each projection tracks its position in the store with a checkpoint, which
+// is saved after each batch of events has been handled. Every event is
+// handled by its own operation, in its own transaction; if a handler fails,
+// the projection's checkpoint is advanced past the events handled so far, and
+// the projection retries from the failed event after a backoff, while other
+// projections continue. Delivery is therefore at-least-once, and handlers
+// should be idempotent; the stored record being handled is available to
+// them with RecordFrom.
+//
+// Stores such as eventstore.SQL allocate positions before their
+// transactions commit, so a record may become visible after others with
+// greater positions. A projection therefore stops at a gap in the positions
+// it reads, until the missing record is committed or - as positions are also
+// lost to transactions that roll back - the gap has persisted for the
+// runner's gap timeout; see WithGapTimeout.
+//
+// Rebuild resets a projection to the start of the store, to rebuild its read
+// model from zero after its handlers change.
+package projection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jaz303/operator"
+	"github.com/jaz303/operator/eventstore"
+)
+
+var ErrUnknownProjection = errors.New("unknown projection")
+
+// Projection maps events to the operations that project them.
+type Projection[Tx operator.Transaction] struct {
+	name     string
+	handlers map[string]handler[Tx]
+	reset    func(ctx context.Context, hub *operator.Hub[Tx]) error
+}
+
+type handler[Tx operator.Transaction] struct {
+	event  operator.Event
+	invoke func(ctx context.Context, hub *operator.Hub[Tx], evt operator.Event) error
+}
+
+// New returns an empty projection with the given name, which identifies its
+// checkpoint.
+func New[Tx operator.Transaction](name string) *Projection[Tx] {
+	return &Projection[Tx]{name: name, handlers: map[string]handler[Tx]{}}
+}
+
+// Name returns the projection's name.
+func (p *Projection[Tx]) Name() string { return p.name }
+
+// On registers op to handle the events of type *I, which must implement
+// operator.Event. Events for which no operation is registered are skipped.
+//
+// Panics if *I does not implement operator.Event, or if an operation is
+// already registered for its events.
+func On[Tx operator.Transaction, I any, O any](p *Projection[Tx], op operator.Operation[Tx, I, O]) *Projection[Tx] {
+	evt, ok := any(new(I)).(operator.Event)
+	if !ok {
+		panic(fmt.Errorf("projection %s: %T does not implement operator.Event", p.name, new(I)))
+	}
+	name := evt.EventName()
+	if _, exists := p.handlers[name]; exists {
+		panic(fmt.Errorf("projection %s: handler for event %s already registered", p.name, name))
+	}
+	p.handlers[name] = handler[Tx]{
+		event: evt,
+		invoke: func(ctx context.Context, hub *operator.Hub[Tx], evt operator.Event) error {
+			in, ok := any(evt).(*I)
+			if !ok {
+				return fmt.Errorf("projection %s: event %s decoded as %T, not %T", p.name, name, evt, in)
+			}
+			_, err := operator.Invoke(ctx, hub, op, in)
+			return err
+		},
+	}
+	return p
+}
+
+// OnReset sets an operation to be invoked when the projection is rebuilt,
+// before its checkpoint is reset, to clear its read model.
+func (p *Projection[Tx]) OnReset(op operator.Operation[Tx, struct{}, struct{}]) *Projection[Tx] {
+	p.reset = func(ctx context.Context, hub *operator.Hub[Tx]) error {
+		_, err := operator.Invoke(ctx, hub, op, &struct{}{})
+		return err
+	}
+	return p
+}
+
+type recordKey struct{}
+
+// RecordFrom returns the stored record being handled by the projection
+// handler whose context is ctx, if any.
+func RecordFrom(ctx context.Context) (*eventstore.Record, bool) {
+	rec, ok := ctx.Value(recordKey{}).(*eventstore.Record)
+	return rec, ok
+}
+
+// Checkpoints stores the position of each projection in the event store.
+type Checkpoints interface {
+	// Load returns the projection's position, which is 0 if it has none.
+	Load(ctx context.Context, projection string) (int64, error)
+
+	Save(ctx context.Context, projection string, position int64) error
+}
+
+// Status reports the state of a projection.
+type Status struct {
+	Name string
+
+	// Position of the last event handled
+	Position int64
+
+	// Consecutive failures, and the most recent error, if the projection
+	// is failing
+	Failures  int
+	LastError error
+}
+
+// Runner runs projections.
+type Runner[Tx operator.Transaction] struct {
+	hub         *operator.Hub[Tx]
+	store       *eventstore.Store[Tx]
+	checkpoints Checkpoints
+	opts        runnerOptions
+
+	mu          sync.RWMutex
+	projections map[string]*running[Tx]
+}
+
+// running is the state of a projection added to a runner. mu is held while
+// the projection handles a batch, or is rebuilt.
+type running[Tx operator.Transaction] struct {
+	*Projection[Tx]
+
+	mu       sync.Mutex
+	position int64
+	loaded   bool
+	failures int
+	lastErr  error
+
+	// position at which the projection is waiting for a gap to fill, and
+	// when it began waiting
+	gapAt    int64
+	gapSince time.Time
+}
+
+// Option configures a Runner.
+type Option func(o *runnerOptions)
+
+type runnerOptions struct {
+	batchSize  int
+	poll       time.Duration
+	backoff    time.Duration
+	maxBackoff time.Duration
+	gapTimeout time.Duration
+	onError    func(projection string, err error)
+}
+
+// WithBatchSize sets the number of events read, and handled, between
+// checkpoints. The default is 100.
+func WithBatchSize(n int) Option {
+	return func(o *runnerOptions) { o.batchSize = max(n, 1) }
+}
+
+// WithPollInterval sets the delay before a projection that has handled all
+// stored events checks for more. The default is 1 second.
+func WithPollInterval(d time.Duration) Option {
+	return func(o *runnerOptions) { o.poll = d }
+}
+
+// WithBackoff sets the delay before a failed projection is retried, which
+// doubles after each consecutive failure up to max. The default is 1 second,
+// up to 1 minute.
+func WithBackoff(initial, max time.Duration) Option {
+	return func(o *runnerOptions) { o.backoff, o.maxBackoff = initial, max }
+}
+
+// WithGapTimeout sets how long a projection waits at a gap in positions for
+// the missing record to be committed before skipping it. It should exceed
+// the longest transaction that appends to the store. The default is 10
+// seconds.
+func WithGapTimeout(d time.Duration) Option {
+	return func(o *runnerOptions) { o.gapTimeout = d }
+}
+
+// WithErrorHandler sets a function to be called when a projection fails.
+// The default logs the error with slog.Default().
+func WithErrorHandler(fn func(projection string, err error)) Option {
+	return func(o *runnerOptions) { o.onError = fn }
+}
+
+// NewRunner returns a Runner projecting the events in store, and recording
+// projections' positions in checkpoints.
+func NewRunner[Tx operator.Transaction](hub *operator.Hub[Tx], store *eventstore.Store[Tx], checkpoints Checkpoints, opts ...Option) *Runner[Tx] {
+	o := runnerOptions{
+		batchSize:  100,
+		poll:       time.Second,
+		backoff:    time.Second,
+		maxBackoff: time.Minute,
+		gapTimeout: 10 * time.Second,
+		onError: func(projection string, err error) {
+			slog.Error("projection failed", "projection", projection, "error", err)
+		},
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &Runner[Tx]{
+		hub:         hub,
+		store:       store,
+		checkpoints: checkpoints,
+		opts:        o,
+		projections: map[string]*running[Tx]{},
+	}
+}
+
+// Add adds p to the runner, registering the types of the events it handles
+// with the hub so that they can be decoded. Projections added while Run is
+// running are not run until it is next called.
+//
+// Panics if a projection with the same name has been added. Returns
+// operator.ErrHubFrozen if the hub has been frozen.
+func (r *Runner[Tx]) Add(p *Projection[Tx]) error {
+	for _, h := range p.handlers {
+		if err := r.hub.RegisterEventType(h.event); err != nil {
+			return err
+		}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.projections[p.name]; exists {
+		panic(fmt.Errorf("projection %s already added", p.name))
+	}
+	r.projections[p.name] = &running[Tx]{Projection: p}
+	return nil
+}
+
+// Run runs each projection in its own goroutine until ctx is done, then
+// returns nil.
+func (r *Runner[Tx]) Run(ctx context.Context) error {
+	r.mu.RLock()
+	var wg sync.WaitGroup
+	for _, p := range r.projections {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.run(ctx, p)
+		}()
+	}
+	r.mu.RUnlock()
+	wg.Wait()
+	return nil
+}
+
+func (r *Runner[Tx]) run(ctx context.Context, p *running[Tx]) {
+	backoff := r.opts.backoff
+	for {
+		delay := r.opts.poll
+		if err := r.catchUp(ctx, p); ctx.Err() != nil {
+			return
+		} else if err != nil {
+			r.opts.onError(p.name, err)
+			delay = backoff
+			backoff = min(backoff*2, r.opts.maxBackoff)
+		} else {
+			backoff = r.opts.backoff
+		}
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// CatchUp handles all events stored after the named projection's position,
+// returning when there are none left, when it reaches a gap in positions
+// that has yet to time out, or when the projection fails.
+func (r *Runner[Tx]) CatchUp(ctx context.Context, projection string) error {
+	p, err := r.projection(projection)
+	if err != nil {
+		return err
+	}
+	return r.catchUp(ctx, p)
+}
+
+func (r *Runner[Tx]) catchUp(ctx context.Context, p *running[Tx]) error {
+	for {
+		more, err := r.batch(ctx, p)
+		if err != nil || !more {
+			return err
+		}
+	}
+}
+
+// batch handles the next batch of events for p, and reports whether there
+// may be more to handle immediately.
+func (r *Runner[Tx]) batch(ctx context.Context, p *running[Tx]) (bool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	more, err := r.handleBatch(ctx, p)
+	if err != nil {
+		p.failures++
+		p.lastErr = err
+	} else {
+		p.failures, p.lastErr = 0, nil
+	}
+	return more, err
+}
+
+func (r *Runner[Tx]) handleBatch(ctx context.Context, p *running[Tx]) (bool, error) {
+	if !p.loaded {
+		pos, err := r.checkpoints.Load(ctx, p.name)
+		if err != nil {
+			return false, fmt.Errorf("loading checkpoint failed (%w)", err)
+		}
+		p.position, p.loaded = pos, true
+	}
+
+	records, err := operator.Invoke(ctx, r.hub, readRecords[Tx], &readInput[Tx]{
+		store: r.store,
+		after: p.position,
+		limit: r.opts.batchSize,
+	})
+	if err != nil {
+		return false, fmt.Errorf("reading events failed (%w)", err)
+	}
+
+	start := p.position
+	more := len(*records) == r.opts.batchSize
+	for _, rec := range *records {
+		if rec.Position > p.position+1 && !r.skipGap(p) {
+			more = false
+			break
+		}
+		if err = r.handle(ctx, p, rec); err != nil {
+			break
+		}
+		p.position = rec.Position
+	}
+
+	if p.position != start {
+		if serr := r.checkpoints.Save(context.WithoutCancel(ctx), p.name, p.position); serr != nil {
+			p.loaded = false
+			err = errors.Join(err, fmt.Errorf("saving checkpoint failed (%w)", serr))
+		}
+	}
+	return more, err
+}
+
+// skipGap reports whether p may skip the gap in positions following its
+// position, having waited for the runner's gap timeout.
+func (r *Runner[Tx]) skipGap(p *running[Tx]) bool {
+	if r.opts.gapTimeout <= 0 {
+		return true
+	}
+	if p.gapAt != p.position || p.gapSince.IsZero() {
+		p.gapAt, p.gapSince = p.position, time.Now()
+		return false
+	}
+	if time.Since(p.gapSince) < r.opts.gapTimeout {
+		return false
+	}
+	p.gapSince = time.Time{}
+	return true
+}
+
+// handle passes rec to p's handler for its event, if any.
+func (r *Runner[Tx]) handle(ctx context.Context, p *running[Tx], rec *eventstore.Record) error {
+	evt, err := r.store.Decode(rec)
+	if errors.Is(err, operator.ErrUnknownEvent) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("decoding event at position %d failed (%w)", rec.Position, err)
+	}
+	h, ok := p.handlers[evt.EventName()]
+	if !ok {
+		return nil
+	}
+	if err := h.invoke(context.WithValue(ctx, recordKey{}, rec), r.hub, evt); err != nil {
+		return fmt.Errorf("handling event %s at position %d failed (%w)", evt.EventName(), rec.Position, err)
+	}
+	return nil
+}
+
+type readInput[Tx operator.Transaction] struct {
+	store *eventstore.Store[Tx]
+	after int64
+	limit int
+}
+
+// readRecords is the operation through which runners read from the store.
+func readRecords[Tx operator.Transaction](ctx *operator.OpContext[Tx], in *readInput[Tx]) (*[]*eventstore.Record, error) {
+	records, err := in.store.ReadAll(ctx, in.after, in.limit)
+	return &records, err
+}
+
+// Rebuild invokes the named projection's reset operation, if any, and resets
+// its checkpoint, so that it handles all stored events again. If the runner
+// is running, the projection begins rebuilding immediately; otherwise call
+// CatchUp.
+func (r *Runner[Tx]) Rebuild(ctx context.Context, projection string) error {
+	p, err := r.projection(projection)
+	if err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.reset != nil {
+		if err := p.reset(ctx, r.hub); err != nil {
+			return fmt.Errorf("resetting projection %s failed (%w)", p.name, err)
+		}
+	}
+	if err := r.checkpoints.Save(ctx, p.name, 0); err != nil {
+		p.loaded = false
+		return fmt.Errorf("saving checkpoint failed (%w)", err)
+	}
+	p.position, p.loaded, p.failures, p.lastErr = 0, true, 0, nil
+	return nil
+}
+
+// Status returns the status of each projection, ordered by name.
+func (r *Runner[Tx]) Status() []Status {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	out := make([]Status, 0, len(r.projections))
+	for _, p := range r.projections {
+		p.mu.Lock()
+		out = append(out, Status{Name: p.name, Position: p.position, Failures: p.failures, LastError: p.lastErr})
+		p.mu.Unlock()
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+func (r *Runner[Tx]) projection(name string) (*running[Tx], error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	p, ok := r.projections[name]
+	if !ok {
+		return nil, fmt.Errorf("%w %s", ErrUnknownProjection, name)
+	}
+	return p, nil
+}
@@ -0,0 +1,268 @@
+package projection
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jaz303/operator"
+	"github.com/jaz303/operator/eventstore"
+	"github.com/stretchr/testify/assert"
+)
+
+type nopTx struct{}
+
+func (nopTx) Commit(context.Context) error   { return nil }
+func (nopTx) Rollback(context.Context) error { return nil }
+
+type deposited struct {
+	Account string `json:"account"`
+	Amount  int    `json:"amount"`
+}
+
+func (e *deposited) EventName() string   { return "funds.deposited" }
+func (e *deposited) AggregateID() string { return e.Account }
+
+type opened struct {
+	Account string `json:"account"`
+}
+
+func (e *opened) EventName() string   { return "account.opened" }
+func (e *opened) AggregateID() string { return e.Account }
+
+// balances is a read model
+type balances struct {
+	mu        sync.Mutex
+	totals    map[string]int
+	positions []int64
+	failAt    int
+}
+
+func (b *balances) projection() *Projection[nopTx] {
+	p := New[nopTx]("balances")
+	On(p, func(ctx *operator.OpContext[nopTx], in *deposited) (*struct{}, error) {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if in.Amount == b.failAt {
+			return nil, errors.New("projection failed")
+		}
+		rec, _ := RecordFrom(ctx)
+		b.positions = append(b.positions, rec.Position)
+		b.totals[in.Account] += in.Amount
+		return &struct{}{}, nil
+	})
+	p.OnReset(func(ctx *operator.OpContext[nopTx], in *struct{}) (*struct{}, error) {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		b.totals, b.positions = map[string]int{}, nil
+		return in, nil
+	})
+	return p
+}
+
+func setup(t *testing.T, opts ...Option) (*operator.Hub[nopTx], *Runner[nopTx], *MemoryCheckpoints) {
+	hub := operator.NewHub(func(context.Context) (nopTx, error) { return nopTx{}, nil })
+	store := eventstore.New(hub, eventstore.NewMemory[nopTx]())
+	assert.Nil(t, eventstore.Persist(hub, store, &deposited{}, &opened{}))
+	cp := NewMemoryCheckpoints()
+	return hub, NewRunner(hub, store, cp, opts...), cp
+}
+
+func emit(t *testing.T, hub *operator.Hub[nopTx], events ...operator.Event) {
+	_, err := operator.Invoke(context.Background(), hub, func(ctx *operator.OpContext[nopTx], in *struct{}) (*struct{}, error) {
+		for _, evt := range events {
+			if err := ctx.Emit(evt); err != nil {
+				return nil, err
+			}
+		}
+		return in, nil
+	}, &struct{}{})
+	assert.Nil(t, err)
+}
+
+func TestCatchUp(t *testing.T) {
+	hub, r, cp := setup(t, WithBatchSize(2))
+	b := &balances{totals: map[string]int{}}
+	assert.Nil(t, r.Add(b.projection()))
+
+	emit(t, hub, &opened{Account: "a"}, &deposited{Account: "a", Amount: 10})
+	emit(t, hub, &deposited{Account: "b", Amount: 5}, &deposited{Account: "a", Amount: 1}, &opened{Account: "c"})
+
+	assert.Nil(t, r.CatchUp(context.Background(), "balances"))
+	assert.Equal(t, map[string]int{"a": 11, "b": 5}, b.totals)
+	assert.Equal(t, []int64{2, 3, 4}, b.positions, "unhandled events are skipped")
+
+	pos, _ := cp.Load(context.Background(), "balances")
+	assert.Equal(t, int64(5), pos)
+
+	emit(t, hub, &deposited{Account: "b", Amount: 2})
+	assert.Nil(t, r.CatchUp(context.Background(), "balances"))
+	assert.Equal(t, map[string]int{"a": 11, "b": 7}, b.totals)
+
+	assert.ErrorIs(t, r.CatchUp(context.Background(), "nope"), ErrUnknownProjection)
+}
+
+func TestFailureIsolation(t *testing.T) {
+	hub, r, cp := setup(t)
+	failing := &balances{totals: map[string]int{}, failAt: 2}
+	healthy := &balances{totals: map[string]int{}}
+
+	p := failing.projection()
+	p.name = "failing"
+	assert.Nil(t, r.Add(p))
+	assert.Nil(t, r.Add(healthy.projection()))
+
+	emit(t, hub, &deposited{Account: "a", Amount: 1}, &deposited{Account: "a", Amount: 2}, &deposited{Account: "a", Amount: 3})
+
+	err := r.CatchUp(context.Background(), "failing")
+	assert.ErrorContains(t, err, "projection failed")
+	assert.Nil(t, r.CatchUp(context.Background(), "balances"))
+
+	assert.Equal(t, map[string]int{"a": 1}, failing.totals)
+	assert.Equal(t, map[string]int{"a": 6}, healthy.totals)
+
+	pos, _ := cp.Load(context.Background(), "failing")
+	assert.Equal(t, int64(1), pos, "checkpoint advances past handled events")
+
+	status := r.Status()
+	assert.Equal(t, "balances", status[0].Name)
+	assert.Equal(t, 0, status[0].Failures)
+	assert.Equal(t, "failing", status[1].Name)
+	assert.Equal(t, 1, status[1].Failures)
+	assert.ErrorContains(t, status[1].LastError, "projection failed")
+
+	failing.failAt = 0
+	assert.Nil(t, r.CatchUp(context.Background(), "failing"))
+	assert.Equal(t, map[string]int{"a": 6}, failing.totals)
+	assert.Equal(t, 0, r.Status()[1].Failures)
+}
+
+func TestRebuild(t *testing.T) {
+	hub, r, cp := setup(t)
+	b := &balances{totals: map[string]int{}}
+	assert.Nil(t, r.Add(b.projection()))
+
+	emit(t, hub, &deposited{Account: "a", Amount: 1}, &deposited{Account: "b", Amount: 2})
+	assert.Nil(t, r.CatchUp(context.Background(), "balances"))
+
+	assert.Nil(t, r.Rebuild(context.Background(), "balances"))
+	assert.Empty(t, b.totals)
+	pos, _ := cp.Load(context.Background(), "balances")
+	assert.Equal(t, int64(0), pos)
+
+	assert.Nil(t, r.CatchUp(context.Background(), "balances"))
+	assert.Equal(t, map[string]int{"a": 1, "b": 2}, b.totals)
+	assert.Equal(t, []int64{1, 2}, b.positions)
+}
+
+func TestRun(t *testing.T) {
+	var mu sync.Mutex
+	var failures []string
+	hub, r, _ := setup(t, WithPollInterval(time.Millisecond), WithBackoff(time.Millisecond, time.Millisecond),
+		WithErrorHandler(func(projection string, err error) {
+			mu.Lock()
+			failures = append(failures, projection)
+			mu.Unlock()
+		}))
+	b := &balances{totals: map[string]int{}, failAt: 13}
+	assert.Nil(t, r.Add(b.projection()))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- r.Run(ctx) }()
+
+	emit(t, hub, &deposited{Account: "a", Amount: 1}, &deposited{Account: "a", Amount: 13}, &deposited{Account: "a", Amount: 2})
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(failures) > 1
+	}, time.Second, time.Millisecond, "failing projections are retried")
+
+	b.mu.Lock()
+	b.failAt = 0
+	b.mu.Unlock()
+	assert.Eventually(t, func() bool {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		return b.totals["a"] == 16
+	}, time.Second, time.Millisecond)
+
+	cancel()
+	assert.Nil(t, <-done)
+}
+
+func TestOn_RequiresEvent(t *testing.T) {
+	assert.Panics(t, func() {
+		On(New[nopTx]("p"), func(ctx *operator.OpContext[nopTx], in *struct{}) (*struct{}, error) { return in, nil })
+	})
+}
+
+// lateBackend hides records, as though the transactions that appended them
+// had yet to commit.
+type lateBackend struct {
+	*eventstore.Memory[nopTx]
+
+	mu     sync.Mutex
+	hidden map[int64]bool
+}
+
+func (b *lateBackend) ReadAll(ctx *operator.OpContext[nopTx], after int64, limit int) ([]*eventstore.Record, error) {
+	records, err := b.Memory.ReadAll(ctx, after, limit)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var visible []*eventstore.Record
+	for _, rec := range records {
+		if !b.hidden[rec.Position] {
+			visible = append(visible, rec)
+		}
+	}
+	return visible, err
+}
+
+func (b *lateBackend) hide(positions ...int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.hidden = map[int64]bool{}
+	for _, pos := range positions {
+		b.hidden[pos] = true
+	}
+}
+
+func TestCatchUp_LateCommits(t *testing.T) {
+	hub := operator.NewHub(func(context.Context) (nopTx, error) { return nopTx{}, nil })
+	backend := &lateBackend{Memory: eventstore.NewMemory[nopTx]()}
+	store := eventstore.New(hub, backend)
+	assert.Nil(t, eventstore.Persist(hub, store, &deposited{}, &opened{}))
+	r := NewRunner(hub, store, NewMemoryCheckpoints(), WithGapTimeout(20*time.Millisecond))
+	b := &balances{totals: map[string]int{}}
+	assert.Nil(t, r.Add(b.projection()))
+
+	// appends to streams a and b interleave; b's commits after a's later
+	// append, which has a greater position
+	emit(t, hub, &deposited{Account: "a", Amount: 1})
+	emit(t, hub, &deposited{Account: "b", Amount: 2})
+	emit(t, hub, &deposited{Account: "a", Amount: 3})
+	backend.hide(2)
+
+	assert.Nil(t, r.CatchUp(context.Background(), "balances"))
+	assert.Equal(t, []int64{1}, b.positions, "waits at the gap")
+	assert.Equal(t, int64(1), r.Status()[0].Position)
+
+	backend.hide()
+	assert.Nil(t, r.CatchUp(context.Background(), "balances"))
+	assert.Equal(t, []int64{1, 2, 3}, b.positions, "handles the late record once committed")
+	assert.Equal(t, map[string]int{"a": 4, "b": 2}, b.totals)
+
+	// positions lost to rolled back transactions are skipped after the
+	// gap timeout
+	emit(t, hub, &deposited{Account: "b", Amount: 4})
+	emit(t, hub, &deposited{Account: "a", Amount: 5})
+	backend.hide(4)
+	assert.Nil(t, r.CatchUp(context.Background(), "balances"))
+	assert.Equal(t, []int64{1, 2, 3}, b.positions)
+	time.Sleep(30 * time.Millisecond)
+	assert.Nil(t, r.CatchUp(context.Background(), "balances"))
+	assert.Equal(t, []int64{1, 2, 3, 5}, b.positions)
+}
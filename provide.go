@@ -0,0 +1,114 @@
+package operator
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+var ErrNoProvider = errors.New("no value or provider registered for type")
+
+// RegisterProvider() registers fn as the hub's provider of values of type T.
+// When an operation or event handler calls Use[T]() and no T has been
+// provided for the current operation, fn is called to construct one, and the
+// result is retained for the remainder of the operation. This allows
+// per-operation services - such as a repository bound to the operation's
+// transaction - to be shared without threading them through every signature:
+//
+//	operator.RegisterProvider(hub, func(ctx *operator.OpContext[*sql.Tx]) (*UserRepo, error) {
+//		tx, err := ctx.Tx()
+//		if err != nil {
+//			return nil, err
+//		}
+//		return &UserRepo{tx: tx}, nil
+//	})
+//
+// Returns ErrHubFrozen if the hub has been frozen.
+func RegisterProvider[T any, Tx Transaction](hub *Hub[Tx], fn func(ctx *OpContext[Tx]) (T, error)) error {
+	if hub.frozen.Load() {
+		return ErrHubFrozen
+	}
+	hub.providers[reflect.TypeFor[T]()] = func(ctx *OpContext[Tx]) (any, error) {
+		return fn(ctx)
+	}
+	return nil
+}
+
+// Provide() stores v in the operation's value store, where it can be
+// retrieved with Use[T]() by the operation and its event handlers, replacing
+// any existing T.
+func Provide[T any, Tx Transaction](ctx *OpContext[Tx], v T) {
+	if ctx.values == nil {
+		ctx.values = map[reflect.Type]any{}
+	}
+	ctx.values[reflect.TypeFor[T]()] = v
+}
+
+// Lookup() returns the operation's T, constructing it with the hub's
+// registered provider if it has not already been provided. Returns
+// ErrNoProvider if there is neither a value nor a provider for T.
+func Lookup[T any, Tx Transaction](ctx *OpContext[Tx]) (T, error) {
+	var zero T
+	ty := reflect.TypeFor[T]()
+
+	if v, ok := ctx.values[ty]; ok {
+		return v.(T), nil
+	}
+
+	provider, ok := ctx.hub.providers[ty]
+	if !ok {
+		return zero, fmt.Errorf("%w %s", ErrNoProvider, ty)
+	}
+
+	v, err := provider(ctx)
+	if err != nil {
+		return zero, fmt.Errorf("provider for %s failed (%w)", ty, err)
+	}
+
+	Provide(ctx, v.(T))
+	return v.(T), nil
+}
+
+// Use() is like Lookup() but panics on failure. Panics raised within an
+// operation are recovered and the operation fails with ErrRecovered; event
+// handlers should prefer Lookup() and return the error.
+func Use[T any, Tx Transaction](ctx *OpContext[Tx]) T {
+	v, err := Lookup[T](ctx)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// Repo() registers factory as the hub's constructor for repositories of type
+// R, bound to the operation's transaction. It formalizes the
+// repository-per-transaction pattern:
+//
+//	operator.Repo(hub, func(tx *sql.Tx) *UserRepo { return &UserRepo{tx: tx} })
+//
+//	func CreateUser(ctx *operator.OpContext[*sql.Tx], in *CreateUserInput) (*User, error) {
+//		users, err := operator.RepoFrom[*UserRepo](ctx)
+//		...
+//	}
+//
+// Repo() is a RegisterProvider() whose provider begins the operation's
+// transaction, so Provide(), Lookup() and Use() may equally be used with R.
+//
+// Returns ErrHubFrozen if the hub has been frozen.
+func Repo[Tx Transaction, R any](hub *Hub[Tx], factory func(tx Tx) R) error {
+	return RegisterProvider(hub, func(ctx *OpContext[Tx]) (R, error) {
+		tx, err := ctx.Tx()
+		if err != nil {
+			var zero R
+			return zero, err
+		}
+		return factory(tx), nil
+	})
+}
+
+// RepoFrom() returns the operation's R, constructing it against the
+// operation's transaction on first use and caching it for the remainder of
+// the operation. Returns ErrNoProvider if no factory is registered for R.
+func RepoFrom[R any, Tx Transaction](ctx *OpContext[Tx]) (R, error) {
+	return Lookup[R](ctx)
+}
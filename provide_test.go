@@ -0,0 +1,95 @@
+package operator
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testRepo struct {
+	tx *TxTest
+}
+
+type requestID string
+
+func TestProvideAndUse(t *testing.T) {
+	hub := newTestHub()
+
+	constructed := 0
+	RegisterProvider(hub, func(ctx *OpContext[*TxTest]) (*testRepo, error) {
+		constructed++
+		tx, err := ctx.Tx()
+		return &testRepo{tx: tx}, err
+	})
+
+	var handlerRepo *testRepo
+	var handlerID requestID
+	hub.RegisterEventHandler(&testEvent{}, func(ctx *OpContext[*TxTest], evt *testEvent) error {
+		repo, err := Lookup[*testRepo](ctx)
+		handlerRepo = repo
+		handlerID = Use[requestID](ctx)
+		return err
+	})
+
+	out, err := Invoke(context.Background(), hub, func(ctx *OpContext[*TxTest], in *struct{}) (*testRepo, error) {
+		Provide(ctx, requestID("abc"))
+		repo := Use[*testRepo](ctx)
+		tx, _ := ctx.Tx()
+		assert.Same(t, tx, repo.tx)
+		return repo, ctx.Emit(&testEvent{})
+	}, &struct{}{})
+
+	assert.Nil(t, err)
+	assert.Same(t, out, handlerRepo)
+	assert.Equal(t, requestID("abc"), handlerID)
+	assert.Equal(t, 1, constructed)
+
+	Invoke(context.Background(), hub, func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) {
+		Use[*testRepo](ctx)
+		return in, nil
+	}, &struct{}{})
+	assert.Equal(t, 2, constructed)
+}
+
+func TestUse_NoProvider(t *testing.T) {
+	hub := newTestHub()
+
+	_, err := Invoke(context.Background(), hub, func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) {
+		Use[*testRepo](ctx)
+		return in, nil
+	}, &struct{}{})
+
+	assert.ErrorIs(t, err, ErrRecovered)
+	assert.ErrorIs(t, err, ErrNoProvider)
+}
+
+func TestRepo(t *testing.T) {
+	hub := newTestHub()
+
+	constructed := 0
+	assert.NoError(t, Repo(hub, func(tx *TxTest) *testRepo {
+		constructed++
+		return &testRepo{tx: tx}
+	}))
+
+	var repos []*testRepo
+	_, err := Invoke(context.Background(), hub, func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) {
+		for range 2 {
+			repo, err := RepoFrom[*testRepo](ctx)
+			if err != nil {
+				return nil, err
+			}
+			repos = append(repos, repo)
+		}
+		return in, nil
+	}, &struct{}{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, constructed)
+	assert.Same(t, repos[0], repos[1])
+	assert.True(t, repos[0].tx.Committed)
+
+	hub.Freeze()
+	assert.ErrorIs(t, Repo(hub, func(tx *TxTest) *testRepo { return nil }), ErrHubFrozen)
+}
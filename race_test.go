@@ -0,0 +1,6 @@
+//go:build race
+
+package operator
+
+// The race detector instruments allocations; see TestAllocationBudget.
+const raceEnabled = true
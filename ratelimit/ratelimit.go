@@ -0,0 +1,97 @@
+// Package ratelimit defines the limiter interface used by the bindings'
+// rate limiting options, along with an in-process token bucket
+// implementation. Distributed limiters (e.g. Redis-backed) implement Limiter
+// in their own packages.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Limiter decides whether a request identified by key may proceed.
+type Limiter interface {
+	// Allow consumes one unit of key's allowance. If the allowance is
+	// exhausted it returns false along with the time after which a retry
+	// may succeed.
+	Allow(ctx context.Context, key string) (ok bool, retryAfter time.Duration, err error)
+}
+
+// TokenBucket is a Limiter permitting, per key, a sustained rate of requests
+// with bursts of up to a fixed size.
+type TokenBucket struct {
+	rate  float64 // tokens per second
+	burst float64
+	now   func() time.Time
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	calls   int
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewTokenBucket returns a TokenBucket allowing rate requests per second per
+// key, with bursts of up to burst requests.
+func NewTokenBucket(rate float64, burst int) *TokenBucket {
+	return &TokenBucket{
+		rate:    rate,
+		burst:   float64(burst),
+		now:     time.Now,
+		buckets: map[string]*bucket{},
+	}
+}
+
+// Every converts an interval between requests into a rate suitable for
+// NewTokenBucket; e.g. Every(time.Minute) is one request per minute.
+func Every(interval time.Duration) float64 {
+	return 1 / interval.Seconds()
+}
+
+func (l *TokenBucket) Allow(ctx context.Context, key string) (bool, time.Duration, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	} else {
+		b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+		b.last = now
+	}
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0, nil
+	}
+
+	wait := time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	return false, wait, nil
+}
+
+// sweep periodically discards buckets that have refilled completely, and are
+// therefore indistinguishable from new buckets. It must be called with l.mu
+// held.
+func (l *TokenBucket) sweep(now time.Time) {
+	l.calls++
+	if l.calls < 1024 {
+		return
+	}
+	l.calls = 0
+
+	full := time.Duration(l.burst / l.rate * float64(time.Second))
+	for k, b := range l.buckets {
+		if now.Sub(b.last) >= full {
+			delete(l.buckets, k)
+		}
+	}
+}
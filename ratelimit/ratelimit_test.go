@@ -0,0 +1,37 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenBucket(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := NewTokenBucket(1, 2)
+	l.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	for range 2 {
+		ok, _, _ := l.Allow(ctx, "a")
+		assert.True(t, ok)
+	}
+
+	ok, retry, _ := l.Allow(ctx, "a")
+	assert.False(t, ok)
+	assert.Equal(t, time.Second, retry)
+
+	ok, _, _ = l.Allow(ctx, "b")
+	assert.True(t, ok, "keys are limited independently")
+
+	now = now.Add(500 * time.Millisecond)
+	ok, retry, _ = l.Allow(ctx, "a")
+	assert.False(t, ok)
+	assert.Equal(t, 500*time.Millisecond, retry)
+
+	now = now.Add(500 * time.Millisecond)
+	ok, _, _ = l.Allow(ctx, "a")
+	assert.True(t, ok)
+}
@@ -0,0 +1,231 @@
+package operator
+
+import (
+	"log/slog"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// RedactedText replaces the value of redacted string fields.
+const RedactedText = "[REDACTED]"
+
+// Redactor may be implemented by values that control their own redaction.
+// Redact returns a representation of the value that is safe to log; when the
+// value is a struct field, the representation is used only if it is
+// assignable to the field, otherwise the field is zeroed.
+type Redactor interface {
+	Redact() any
+}
+
+// Redact() returns a copy of v that is safe to log, record or report: struct
+// fields tagged `op:"redact"` are replaced by RedactedText if they are
+// strings, and zeroed otherwise, and values implementing Redactor are
+// replaced by the result of their Redact() method. Pointers, slices, arrays
+// and maps are followed, and copied where they contain something to redact;
+// v itself is never modified.
+//
+//	type Login struct {
+//		Email    string
+//		Password string `op:"redact"`
+//	}
+//
+// Every integration that logs, traces or reports operation inputs and
+// outputs does so via Redact(), so that sensitive values are treated
+// identically throughout.
+func Redact(v any) any {
+	if v == nil {
+		return nil
+	} else if r, ok := v.(Redactor); ok {
+		return r.Redact()
+	}
+	rv := reflect.ValueOf(v)
+	if !needsRedaction(rv.Type()) {
+		return v
+	}
+	return redactValue(rv).Interface()
+}
+
+// RedactedField() returns true if f is tagged `op:"redact"`.
+func RedactedField(f reflect.StructField) bool {
+	tag, ok := f.Tag.Lookup("op")
+	if !ok {
+		return false
+	}
+	for opt := range strings.SplitSeq(tag, ",") {
+		if opt == "redact" {
+			return true
+		}
+	}
+	return false
+}
+
+// Redacted() returns a slog.LogValuer that logs Redact(v), e.g.
+//
+//	logger.Info("signing in", "input", operator.Redacted(input))
+func Redacted(v any) slog.LogValuer { return redacted{v} }
+
+type redacted struct{ v any }
+
+func (r redacted) LogValue() slog.Value { return slog.AnyValue(Redact(r.v)) }
+
+// OperationInput() returns the redacted input of the operation identified by
+// info, for use by tracers, metrics and middleware. It returns nil if info
+// does not identify an operation invoked on a hub.
+func OperationInput(info OperationInfo) any {
+	if p, ok := info.(payloads); ok {
+		return Redact(p.payloads().input)
+	}
+	return nil
+}
+
+// OperationOutput() returns the redacted output of the operation identified
+// by info, once it has completed successfully; e.g. by a middleware, after
+// calling next. It returns nil if the operation has not completed, or failed.
+func OperationOutput(info OperationInfo) any {
+	if p, ok := info.(payloads); ok {
+		return Redact(p.payloads().output)
+	}
+	return nil
+}
+
+type payloads interface {
+	payloads() *opPayloads
+}
+
+// opPayloads holds an operation's input and output while it is intercepted.
+// Both are pointers, or nil.
+type opPayloads struct {
+	input  any
+	output any
+}
+
+var (
+	redactorType  = reflect.TypeFor[Redactor]()
+	redactionPlan sync.Map // reflect.Type -> bool
+)
+
+// needsRedaction returns true if values of type t may contain something to
+// redact.
+func needsRedaction(t reflect.Type) bool {
+	if r, ok := redactionPlan.Load(t); ok {
+		return r.(bool)
+	}
+	r := typeNeedsRedaction(t, map[reflect.Type]bool{})
+	redactionPlan.Store(t, r)
+	return r
+}
+
+func typeNeedsRedaction(t reflect.Type, seen map[reflect.Type]bool) bool {
+	if t.Implements(redactorType) {
+		return true
+	} else if seen[t] {
+		return false
+	}
+	seen[t] = true
+
+	switch t.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Array:
+		return typeNeedsRedaction(t.Elem(), seen)
+	case reflect.Map:
+		return typeNeedsRedaction(t.Key(), seen) || typeNeedsRedaction(t.Elem(), seen)
+	case reflect.Interface:
+		// the dynamic value might need redacting
+		return true
+	case reflect.Struct:
+		for i := range t.NumField() {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			} else if RedactedField(f) || typeNeedsRedaction(f.Type, seen) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// redactValue returns a redacted copy of v, with the same type.
+func redactValue(v reflect.Value) reflect.Value {
+	t := v.Type()
+	if !needsRedaction(t) {
+		return v
+	}
+
+	if t.Implements(redactorType) && v.CanInterface() {
+		if t.Kind() == reflect.Pointer && v.IsNil() {
+			return v
+		}
+		r := reflect.ValueOf(v.Interface().(Redactor).Redact())
+		if r.IsValid() && r.Type().AssignableTo(t) {
+			out := reflect.New(t).Elem()
+			out.Set(r)
+			return out
+		}
+		return reflect.Zero(t)
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return v
+		}
+		out := reflect.New(t.Elem())
+		out.Elem().Set(redactValue(v.Elem()))
+		return out
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		out := reflect.New(t).Elem()
+		out.Set(redactValue(v.Elem()))
+		return out
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		out := reflect.MakeSlice(t, v.Len(), v.Len())
+		for i := range v.Len() {
+			out.Index(i).Set(redactValue(v.Index(i)))
+		}
+		return out
+	case reflect.Array:
+		out := reflect.New(t).Elem()
+		for i := range v.Len() {
+			out.Index(i).Set(redactValue(v.Index(i)))
+		}
+		return out
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		out := reflect.MakeMapWithSize(t, v.Len())
+		for it := v.MapRange(); it.Next(); {
+			out.SetMapIndex(redactValue(it.Key()), redactValue(it.Value()))
+		}
+		return out
+	case reflect.Struct:
+		out := reflect.New(t).Elem()
+		out.Set(v)
+		for i := range t.NumField() {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			} else if RedactedField(f) {
+				mask(out.Field(i))
+			} else {
+				out.Field(i).Set(redactValue(v.Field(i)))
+			}
+		}
+		return out
+	}
+	return v
+}
+
+func mask(f reflect.Value) {
+	if f.Kind() == reflect.String {
+		f.SetString(RedactedText)
+	} else {
+		f.SetZero()
+	}
+}
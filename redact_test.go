@@ -0,0 +1,112 @@
+package operator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type redactCard struct {
+	Number string `op:"redact"`
+	Expiry string
+}
+
+type redactLogin struct {
+	Email    string
+	Password string `op:"redact"`
+	PIN      int    `op:"redact,omitempty"`
+	Card     *redactCard
+	Cards    []redactCard
+	Extra    map[string]any
+	Token    redactToken
+}
+
+type redactToken string
+
+func (redactToken) Redact() any { return redactToken("tok_***") }
+
+func TestRedact(t *testing.T) {
+	in := &redactLogin{
+		Email:    "a@example.com",
+		Password: "hunter2",
+		PIN:      1234,
+		Card:     &redactCard{Number: "4242", Expiry: "12/30"},
+		Cards:    []redactCard{{Number: "5555", Expiry: "01/31"}},
+		Extra:    map[string]any{"card": redactCard{Number: "1111"}},
+		Token:    "tok_secret",
+	}
+
+	out := Redact(in).(*redactLogin)
+
+	assert.Equal(t, &redactLogin{
+		Email:    "a@example.com",
+		Password: RedactedText,
+		Card:     &redactCard{Number: RedactedText, Expiry: "12/30"},
+		Cards:    []redactCard{{Number: RedactedText, Expiry: "01/31"}},
+		Extra:    map[string]any{"card": redactCard{Number: RedactedText}},
+		Token:    "tok_***",
+	}, out)
+
+	// the original is untouched
+	assert.Equal(t, "hunter2", in.Password)
+	assert.Equal(t, "4242", in.Card.Number)
+	assert.Equal(t, "5555", in.Cards[0].Number)
+}
+
+func TestRedact_NothingToRedact(t *testing.T) {
+	type plain struct{ Name string }
+	in := &plain{Name: "x"}
+
+	assert.Same(t, in, Redact(in))
+	assert.Equal(t, "s", Redact("s"))
+	assert.Nil(t, Redact(nil))
+}
+
+func TestRedacted_LogValue(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	logger.Info("signing in", "input", Redacted(&redactLogin{Email: "a@example.com", Password: "hunter2"}))
+
+	var rec map[string]any
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &rec))
+	input := rec["input"].(map[string]any)
+	assert.Equal(t, "a@example.com", input["Email"])
+	assert.Equal(t, RedactedText, input["Password"])
+	assert.NotContains(t, buf.String(), "hunter2")
+}
+
+func TestOperationInputOutput(t *testing.T) {
+	var input, output any
+	hub := newTestHub(WithMiddleware(func(ctx context.Context, info OperationInfo, next func(context.Context) error) error {
+		input = OperationInput(info)
+		assert.Nil(t, OperationOutput(info))
+		err := next(ctx)
+		output = OperationOutput(info)
+		return err
+	}))
+
+	_, err := Invoke(context.Background(), hub, func(ctx *OpContext[*TxTest], in *redactLogin) (*redactCard, error) {
+		return &redactCard{Number: "4242", Expiry: "12/30"}, nil
+	}, &redactLogin{Email: "a@example.com", Password: "hunter2"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, &redactLogin{Email: "a@example.com", Password: RedactedText, Token: "tok_***"}, input)
+	assert.Equal(t, &redactCard{Number: RedactedText, Expiry: "12/30"}, output)
+}
+
+func TestRecoveredPanicIsRedacted(t *testing.T) {
+	hub := newTestHub()
+
+	_, err := Invoke(context.Background(), hub, func(ctx *OpContext[*TxTest], in *redactLogin) (*struct{}, error) {
+		panic(*in)
+	}, &redactLogin{Password: "hunter2"})
+
+	assert.True(t, errors.Is(err, ErrRecovered))
+	assert.NotContains(t, err.Error(), "hunter2")
+}
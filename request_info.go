@@ -0,0 +1,52 @@
+package operator
+
+import (
+	"context"
+	"strings"
+)
+
+// RequestInfo describes the inbound request that triggered an operation.
+// Bindings attach it when configured to do so, making it available to the
+// operation, its event handlers, and middleware, without it being part of
+// the operation's input.
+type RequestInfo struct {
+	Method    string
+	Path      string
+	RemoteIP  string
+	UserAgent string
+
+	// Request headers selected by the binding's allow-list, keyed by
+	// canonical header name
+	Header map[string][]string
+}
+
+// Get returns the first value of the named header, if it was captured.
+func (ri *RequestInfo) Get(name string) string {
+	for k, v := range ri.Header {
+		if len(v) > 0 && strings.EqualFold(k, name) {
+			return v[0]
+		}
+	}
+	return ""
+}
+
+type requestInfoKey struct{}
+
+// WithRequestInfo returns a copy of ctx carrying ri.
+func WithRequestInfo(ctx context.Context, ri *RequestInfo) context.Context {
+	return context.WithValue(ctx, requestInfoKey{}, ri)
+}
+
+// RequestInfoFrom returns the request information carried by ctx, if any.
+func RequestInfoFrom(ctx context.Context) (*RequestInfo, bool) {
+	ri, ok := ctx.Value(requestInfoKey{}).(*RequestInfo)
+	return ri, ok
+}
+
+// Request returns information about the request that triggered the
+// operation, or nil if there is none; e.g. because the operation was not
+// invoked by a binding configured to attach it.
+func (o *OpContext[T]) Request() *RequestInfo {
+	ri, _ := RequestInfoFrom(o)
+	return ri
+}
@@ -0,0 +1,40 @@
+package operator
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestInfo(t *testing.T) {
+	hub := newTestHub()
+	ri := &RequestInfo{
+		Method:   "POST",
+		Path:     "/users",
+		RemoteIP: "10.0.0.1",
+		Header:   map[string][]string{"X-Request-Id": {"abc"}},
+	}
+
+	var got *RequestInfo
+	assert.NoError(t, hub.RegisterEventHandler(&testEvent{}, func(ctx *OpContext[*TxTest], evt *testEvent) {
+		got = ctx.Request()
+	}))
+
+	_, err := Invoke(WithRequestInfo(context.Background(), ri), hub, func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) {
+		return in, ctx.Emit(&testEvent{})
+	}, &struct{}{})
+
+	assert.NoError(t, err)
+	assert.Same(t, ri, got)
+	assert.Equal(t, "abc", got.Get("x-request-id"))
+	assert.Equal(t, "", got.Get("Missing"))
+}
+
+func TestRequestInfo_Absent(t *testing.T) {
+	_, err := Invoke(context.Background(), newTestHub(), func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) {
+		assert.Nil(t, ctx.Request())
+		return in, nil
+	}, &struct{}{})
+	assert.NoError(t, err)
+}
@@ -0,0 +1,104 @@
+package operator
+
+// Warning is a non-fatal problem encountered by an operation that otherwise
+// succeeded - an item of a bulk import that was skipped, say.
+type Warning struct {
+	// Machine-readable code identifying the kind of problem, e.g.
+	// "duplicate_row"
+	Code string `json:"code,omitempty"`
+
+	// Human-readable description of the problem
+	Message string `json:"message"`
+
+	// What the warning concerns, if not the operation as a whole; e.g. the
+	// index of an item, or the path of an input field
+	Target string `json:"target,omitempty"`
+}
+
+// Result is an operation output carrying non-fatal warnings and metadata
+// alongside the output itself, for operations that can succeed with caveats,
+// such as partially-successful bulk operations. Operations return it as
+// their output, i.e. Operation[Tx, I, Result[O]].
+//
+// The HTTP bindings encode a Result as an envelope with "output", "warnings"
+// and "metadata" properties, honouring the interfaces the output implements
+// (StatusCoder and the like). Middleware, tracers and metrics can retrieve
+// the warnings with OperationWarnings().
+type Result[O any] struct {
+	Output   *O             `json:"output"`
+	Warnings []Warning      `json:"warnings,omitempty"`
+	Metadata map[string]any `json:"metadata,omitempty"`
+}
+
+// NewResult() returns a Result wrapping out.
+func NewResult[O any](out *O) *Result[O] {
+	return &Result[O]{Output: out}
+}
+
+// Warn() adds a warning with the given code and message to the result,
+// returning the result.
+func (r *Result[O]) Warn(code, message string) *Result[O] {
+	r.Warnings = append(r.Warnings, Warning{Code: code, Message: message})
+	return r
+}
+
+// WarnAbout() adds a warning concerning target to the result, returning the
+// result.
+func (r *Result[O]) WarnAbout(target, code, message string) *Result[O] {
+	r.Warnings = append(r.Warnings, Warning{Code: code, Message: message, Target: target})
+	return r
+}
+
+// Set() sets the metadata value for key, returning the result.
+func (r *Result[O]) Set(key string, value any) *Result[O] {
+	if r.Metadata == nil {
+		r.Metadata = map[string]any{}
+	}
+	r.Metadata[key] = value
+	return r
+}
+
+// ResultOutput() returns the wrapped output, or nil if there is none.
+func (r *Result[O]) ResultOutput() any {
+	if r == nil || r.Output == nil {
+		return nil
+	}
+	return r.Output
+}
+
+// ResultWarnings() returns the result's warnings.
+func (r *Result[O]) ResultWarnings() []Warning {
+	if r == nil {
+		return nil
+	}
+	return r.Warnings
+}
+
+// ResultMetadata() returns the result's metadata.
+func (r *Result[O]) ResultMetadata() map[string]any {
+	if r == nil {
+		return nil
+	}
+	return r.Metadata
+}
+
+// ResultInfo is implemented by every *Result[O], allowing integrations to
+// inspect results without knowing their output type.
+type ResultInfo interface {
+	ResultOutput() any
+	ResultWarnings() []Warning
+	ResultMetadata() map[string]any
+}
+
+// OperationWarnings() returns the warnings of the operation identified by
+// info, once it has completed successfully with a Result; e.g. in a
+// middleware, after calling next, to log or audit them. It returns nil if
+// the operation has not completed, failed, or has no warnings.
+func OperationWarnings(info OperationInfo) []Warning {
+	if p, ok := info.(payloads); ok {
+		if r, ok := p.payloads().output.(ResultInfo); ok {
+			return r.ResultWarnings()
+		}
+	}
+	return nil
+}
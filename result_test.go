@@ -0,0 +1,42 @@
+package operator
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOperationWarnings(t *testing.T) {
+	var warnings []Warning
+	hub := newTestHub(WithMiddleware(func(ctx context.Context, info OperationInfo, next func(ctx context.Context) error) error {
+		assert.Nil(t, OperationWarnings(info))
+		err := next(ctx)
+		warnings = OperationWarnings(info)
+		return err
+	}))
+
+	out, err := Invoke(context.Background(), hub, func(ctx *OpContext[*TxTest], in *[]int) (*Result[int], error) {
+		n := 0
+		res := NewResult(&n)
+		for i, v := range *in {
+			if v < 0 {
+				res.WarnAbout(fmt.Sprint(i), "negative", "skipped negative value")
+				continue
+			}
+			n += v
+		}
+		return res.Set("count", len(*in)), nil
+	}, &[]int{1, -2, 3})
+	assert.NoError(t, err)
+	assert.Equal(t, 4, *out.Output)
+	assert.Equal(t, map[string]any{"count": 3}, out.Metadata)
+	assert.Equal(t, []Warning{{Code: "negative", Message: "skipped negative value", Target: "1"}}, warnings)
+
+	_, err = Invoke(context.Background(), hub, func(ctx *OpContext[*TxTest], in *struct{}) (*Result[int], error) {
+		return nil, nil
+	}, &struct{}{})
+	assert.NoError(t, err)
+	assert.Nil(t, warnings)
+}
@@ -0,0 +1,159 @@
+// Package saga chains operations into a workflow whose completed steps are
+// undone by compensating operations if a later step fails.
+//
+// Each step runs in its own operation, and so its own transaction. Steps
+// share a state value of type S, from which they derive their input and into
+// which they record their output:
+//
+//	checkout := saga.New[*sql.Tx, Checkout](hub).
+//		Step("reserve",
+//			saga.Invoke(ReserveInventory, reserveInput, recordReservation),
+//			saga.Invoke(ReleaseInventory, releaseInput, nil)).
+//		Step("charge",
+//			saga.Invoke(ChargeCard, chargeInput, recordCharge),
+//			saga.Invoke(RefundCharge, refundInput, nil)).
+//		Step("ship", saga.Invoke(CreateShipment, shipmentInput, nil), nil)
+//
+//	err := checkout.Run(ctx, &Checkout{OrderID: id})
+//
+// If "ship" fails, "charge" and then "reserve" are compensated. Compensations
+// run even if ctx has been cancelled, and should be idempotent.
+package saga
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jaz303/operator"
+)
+
+var ErrSagaFailed = errors.New("saga failed")
+
+// Action is the work performed by a step, or its compensation.
+type Action[Tx operator.Transaction, S any] func(ctx context.Context, hub *operator.Hub[Tx], state *S) error
+
+// Invoke returns an action that invokes op with the input returned by input,
+// then passes its output to output, if non-nil.
+func Invoke[Tx operator.Transaction, S any, I any, O any](op operator.Operation[Tx, I, O], input func(*S) *I, output func(*S, *O)) Action[Tx, S] {
+	return func(ctx context.Context, hub *operator.Hub[Tx], state *S) error {
+		out, err := operator.Invoke(ctx, hub, op, input(state))
+		if err == nil && output != nil {
+			output(state, out)
+		}
+		return err
+	}
+}
+
+// InvokeTx is like Invoke, for a TxOperation.
+func InvokeTx[Tx operator.Transaction, S any, I any, O any](op operator.TxOperation[Tx, I, O], input func(*S) *I, output func(*S, *O)) Action[Tx, S] {
+	return func(ctx context.Context, hub *operator.Hub[Tx], state *S) error {
+		out, err := operator.InvokeTx(ctx, hub, op, input(state))
+		if err == nil && output != nil {
+			output(state, out)
+		}
+		return err
+	}
+}
+
+type step[Tx operator.Transaction, S any] struct {
+	name       string
+	action     Action[Tx, S]
+	compensate Action[Tx, S]
+}
+
+// Saga is a sequence of steps. A Saga is immutable once built, and may be run
+// concurrently with different states.
+type Saga[Tx operator.Transaction, S any] struct {
+	hub   *operator.Hub[Tx]
+	steps []step[Tx, S]
+}
+
+// New returns an empty saga that invokes operations on hub.
+func New[Tx operator.Transaction, S any](hub *operator.Hub[Tx]) *Saga[Tx, S] {
+	return &Saga[Tx, S]{hub: hub}
+}
+
+// Step returns a copy of s with a step appended. If a later step fails,
+// compensate is run to undo action; compensate may be nil if the step needs
+// no compensation, e.g. because it is the last.
+func (s *Saga[Tx, S]) Step(name string, action Action[Tx, S], compensate Action[Tx, S]) *Saga[Tx, S] {
+	steps := make([]step[Tx, S], len(s.steps), len(s.steps)+1)
+	copy(steps, s.steps)
+	return &Saga[Tx, S]{
+		hub:   s.hub,
+		steps: append(steps, step[Tx, S]{name: name, action: action, compensate: compensate}),
+	}
+}
+
+// Run runs each step in order. If a step fails, the compensations of the
+// steps that completed before it are run in reverse order, and an *Error is
+// returned. A failing compensation does not prevent the remaining
+// compensations from running.
+func (s *Saga[Tx, S]) Run(ctx context.Context, state *S) error {
+	for i, st := range s.steps {
+		if err := ctx.Err(); err != nil {
+			return s.compensate(ctx, state, i, st.name, err)
+		}
+		if err := st.action(ctx, s.hub, state); err != nil {
+			return s.compensate(ctx, state, i, st.name, err)
+		}
+	}
+	return nil
+}
+
+func (s *Saga[Tx, S]) compensate(ctx context.Context, state *S, failed int, name string, err error) error {
+	sagaErr := &Error{Step: name, Err: err}
+	ctx = context.WithoutCancel(ctx)
+	for i := failed - 1; i >= 0; i-- {
+		st := s.steps[i]
+		if st.compensate == nil {
+			continue
+		}
+		if cerr := st.compensate(ctx, s.hub, state); cerr != nil {
+			sagaErr.CompensationErrors = append(sagaErr.CompensationErrors, &StepError{Step: st.name, Err: cerr})
+		} else {
+			sagaErr.Compensated = append(sagaErr.Compensated, st.name)
+		}
+	}
+	return sagaErr
+}
+
+// Error reports a failed saga.
+type Error struct {
+	// Name of the step that failed, and its error
+	Step string
+	Err  error
+
+	// Steps that were compensated successfully, in the order their
+	// compensations ran
+	Compensated []string
+
+	// Compensations that failed; the effects of these steps remain
+	CompensationErrors []*StepError
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("%s: step %s failed (%s)", ErrSagaFailed, e.Step, e.Err)
+	if len(e.CompensationErrors) > 0 {
+		errs := make([]string, len(e.CompensationErrors))
+		for i, ce := range e.CompensationErrors {
+			errs[i] = ce.Error()
+		}
+		msg += "; compensation failed: " + strings.Join(errs, "; ")
+	}
+	return msg
+}
+
+func (e *Error) Unwrap() []error { return []error{ErrSagaFailed, e.Err} }
+
+// StepError is the failure of a single step's compensation.
+type StepError struct {
+	Step string
+	Err  error
+}
+
+func (e *StepError) Error() string { return fmt.Sprintf("step %s: %s", e.Step, e.Err) }
+
+func (e *StepError) Unwrap() error { return e.Err }
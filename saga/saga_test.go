@@ -0,0 +1,117 @@
+package saga
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jaz303/operator"
+	"github.com/stretchr/testify/assert"
+)
+
+type testTx struct {
+	log *[]string
+}
+
+func (tx testTx) Commit(context.Context) error   { *tx.log = append(*tx.log, "commit"); return nil }
+func (tx testTx) Rollback(context.Context) error { *tx.log = append(*tx.log, "rollback"); return nil }
+
+type checkout struct {
+	Reservation string
+	Charge      string
+	FailShip    bool
+	FailRefund  bool
+}
+
+type empty struct{}
+
+func reserve(ctx *operator.OpContext[testTx], tx testTx, in *empty) (*string, error) {
+	s := "res-1"
+	return &s, nil
+}
+
+func release(ctx *operator.OpContext[testTx], tx testTx, in *string) (*empty, error) {
+	*tx.log = append(*tx.log, "release "+*in)
+	return &empty{}, nil
+}
+
+func charge(ctx *operator.OpContext[testTx], in *empty) (*string, error) {
+	s := "ch-1"
+	return &s, nil
+}
+
+func refund(ctx *operator.OpContext[testTx], in *checkout) (*empty, error) {
+	if in.FailRefund {
+		return nil, errors.New("gateway down")
+	}
+	return &empty{}, nil
+}
+
+func ship(ctx *operator.OpContext[testTx], in *checkout) (*empty, error) {
+	if in.FailShip {
+		return nil, errors.New("no couriers")
+	}
+	return &empty{}, nil
+}
+
+func newSaga(log *[]string) *Saga[testTx, checkout] {
+	hub := operator.NewHub(func(context.Context) (testTx, error) { return testTx{log: log}, nil })
+	noInput := func(*checkout) *empty { return &empty{} }
+	self := func(c *checkout) *checkout { return c }
+
+	return New[testTx, checkout](hub).
+		Step("reserve",
+			InvokeTx(reserve, noInput, func(c *checkout, out *string) { c.Reservation = *out }),
+			InvokeTx(release, func(c *checkout) *string { return &c.Reservation }, nil)).
+		Step("charge",
+			Invoke(charge, noInput, func(c *checkout, out *string) { c.Charge = *out }),
+			Invoke(refund, self, nil)).
+		Step("ship", Invoke(ship, self, nil), nil)
+}
+
+func TestRun(t *testing.T) {
+	var log []string
+	state := &checkout{}
+
+	assert.NoError(t, newSaga(&log).Run(context.Background(), state))
+	assert.Equal(t, "res-1", state.Reservation)
+	assert.Equal(t, "ch-1", state.Charge)
+	assert.Equal(t, []string{"commit"}, log)
+}
+
+func TestRun_Compensates(t *testing.T) {
+	var log []string
+	err := newSaga(&log).Run(context.Background(), &checkout{FailShip: true})
+
+	var sagaErr *Error
+	assert.ErrorAs(t, err, &sagaErr)
+	assert.ErrorIs(t, err, ErrSagaFailed)
+	assert.Equal(t, "ship", sagaErr.Step)
+	assert.Equal(t, []string{"charge", "reserve"}, sagaErr.Compensated)
+	assert.Empty(t, sagaErr.CompensationErrors)
+
+	// each step, and each compensation, commits its own transaction
+	assert.Equal(t, []string{"commit", "release res-1", "commit"}, log)
+}
+
+func TestRun_CompensationFails(t *testing.T) {
+	var log []string
+	err := newSaga(&log).Run(context.Background(), &checkout{FailShip: true, FailRefund: true})
+
+	var sagaErr *Error
+	assert.ErrorAs(t, err, &sagaErr)
+	assert.Equal(t, []string{"reserve"}, sagaErr.Compensated)
+	assert.Len(t, sagaErr.CompensationErrors, 1)
+	assert.Equal(t, "charge", sagaErr.CompensationErrors[0].Step)
+	assert.Contains(t, err.Error(), "gateway down")
+}
+
+func TestRun_Cancelled(t *testing.T) {
+	var log []string
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := newSaga(&log).Run(ctx, &checkout{})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Empty(t, log)
+}
@@ -0,0 +1,102 @@
+package schema
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// Comments holds the doc comments of Go types and their fields, parsed from
+// source.
+type Comments struct {
+	docs map[string]string
+}
+
+// NewComments returns an empty Comments.
+func NewComments() *Comments {
+	return &Comments{docs: map[string]string{}}
+}
+
+// AddDir parses the non-test Go files in dir, the source of the package
+// whose import path is pkgPath, and records the doc comments of its types
+// and struct fields. A field's trailing line comment is used if it has no
+// doc comment.
+func (c *Comments) AddDir(pkgPath, dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	fset := token.NewFileSet()
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, parser.ParseComments|parser.SkipObjectResolution)
+		if err != nil {
+			return err
+		}
+		c.addFile(pkgPath, f)
+	}
+	return nil
+}
+
+func (c *Comments) addFile(pkgPath string, f *ast.File) {
+	for _, decl := range f.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			ts := spec.(*ast.TypeSpec)
+			doc := ts.Doc
+			if doc == nil && len(gen.Specs) == 1 {
+				doc = gen.Doc
+			}
+			key := pkgPath + "." + ts.Name.Name
+			c.set(key, doc)
+
+			st, ok := ts.Type.(*ast.StructType)
+			if !ok {
+				continue
+			}
+			for _, field := range st.Fields.List {
+				doc := field.Doc
+				if doc == nil {
+					doc = field.Comment
+				}
+				for _, name := range field.Names {
+					c.set(key+"."+name.Name, doc)
+				}
+			}
+		}
+	}
+}
+
+func (c *Comments) set(key string, doc *ast.CommentGroup) {
+	if text := strings.TrimSpace(doc.Text()); text != "" {
+		c.docs[key] = text
+	}
+}
+
+// Type returns the doc comment of the named type t, if known.
+func (c *Comments) Type(t reflect.Type) string {
+	return c.docs[typeKey(t)]
+}
+
+// Field returns the doc comment of the named field of struct type t, if
+// known.
+func (c *Comments) Field(t reflect.Type, field string) string {
+	return c.docs[typeKey(t)+"."+field]
+}
+
+// typeKey returns the key under which t's comments are recorded; type
+// arguments are removed from the names of generic types.
+func typeKey(t reflect.Type) string {
+	name, _, _ := strings.Cut(t.Name(), "[")
+	return t.PkgPath() + "." + name
+}
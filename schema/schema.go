@@ -0,0 +1,377 @@
+// Package schema generates JSON Schema (draft 2020-12) documents describing
+// the input and output types of a hub's registered operations, for client
+// code generation, documentation and contract tests.
+//
+//	doc := schema.Operations(hub)
+//	json.NewEncoder(os.Stdout).Encode(doc)
+//
+// Schemas follow encoding/json's rules: fields are named by their json tags,
+// embedded structs are flattened, and fields without omitempty or omitzero
+// are required. Constraints are taken from go-playground/validator style
+// `validate` tags (required, min, max, len, gt, gte, lt, lte, oneof, email,
+// url, uri, uuid). Named types are emitted once, as definitions referenced
+// with $ref, so recursive types are supported.
+//
+// Descriptions are taken from doc comments, which are not available to a
+// running program; parse them from source with Comments and pass them with
+// WithComments.
+package schema
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/jaz303/operator"
+)
+
+// Dialect is the JSON Schema dialect of generated documents.
+const Dialect = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema is a JSON Schema.
+type Schema struct {
+	Schema      string `json:"$schema,omitempty"`
+	Ref         string `json:"$ref,omitempty"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+
+	Type   string `json:"type,omitempty"`
+	Format string `json:"format,omitempty"`
+	Enum   []any  `json:"enum,omitempty"`
+
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+
+	Minimum          *float64 `json:"minimum,omitempty"`
+	Maximum          *float64 `json:"maximum,omitempty"`
+	ExclusiveMinimum *float64 `json:"exclusiveMinimum,omitempty"`
+	ExclusiveMaximum *float64 `json:"exclusiveMaximum,omitempty"`
+	MinLength        *int     `json:"minLength,omitempty"`
+	MaxLength        *int     `json:"maxLength,omitempty"`
+	MinItems         *int     `json:"minItems,omitempty"`
+	MaxItems         *int     `json:"maxItems,omitempty"`
+
+	ContentEncoding string `json:"contentEncoding,omitempty"`
+
+	Defs map[string]*Schema `json:"$defs,omitempty"`
+
+	// Names of properties in the order of the Go struct's fields, for
+	// generators that wish to preserve it; an extension keyword, ignored by
+	// validators
+	PropertyOrder []string `json:"x-order,omitempty"`
+}
+
+// Document describes the operations registered with a hub.
+type Document struct {
+	Schema     string      `json:"$schema"`
+	Operations []Operation `json:"operations"`
+
+	// Named types referenced by the operations' schemas
+	Defs map[string]*Schema `json:"$defs,omitempty"`
+}
+
+// Operation describes a single operation's input and output.
+type Operation struct {
+	Name   string  `json:"name"`
+	Input  *Schema `json:"input"`
+	Output *Schema `json:"output"`
+
+	// Go types of the input and output
+	InputType  reflect.Type `json:"-"`
+	OutputType reflect.Type `json:"-"`
+}
+
+// Option configures schema generation.
+type Option func(g *Generator)
+
+// WithComments describes types and fields with their doc comments.
+func WithComments(c *Comments) Option {
+	return func(g *Generator) { g.comments = c }
+}
+
+// Operations returns a document describing the input and output of each
+// operation registered with hub.
+func Operations[Tx operator.Transaction](hub *operator.Hub[Tx], opts ...Option) *Document {
+	g := NewGenerator(opts...)
+	doc := &Document{Schema: Dialect, Operations: []Operation{}}
+	for _, op := range hub.Operations() {
+		doc.Operations = append(doc.Operations, Operation{
+			Name:       op.Name,
+			Input:      g.Schema(op.Input),
+			Output:     g.Schema(op.Output),
+			InputType:  op.Input,
+			OutputType: op.Output,
+		})
+	}
+	doc.Defs = g.Defs()
+	return doc
+}
+
+// For returns a standalone document describing t.
+func For(t reflect.Type, opts ...Option) *Schema {
+	g := NewGenerator(opts...)
+	s := g.Schema(t)
+	if s.Ref != "" {
+		def := *g.defs[strings.TrimPrefix(s.Ref, "#/$defs/")]
+		s = &def
+	}
+	s.Schema = Dialect
+	s.Defs = g.Defs()
+	return s
+}
+
+// Generator generates schemas, accumulating definitions of the named types
+// they reference.
+type Generator struct {
+	comments *Comments
+	defs     map[string]*Schema
+	names    map[reflect.Type]string
+}
+
+// NewGenerator returns a Generator with no definitions.
+func NewGenerator(opts ...Option) *Generator {
+	g := &Generator{defs: map[string]*Schema{}, names: map[reflect.Type]string{}}
+	for _, opt := range opts {
+		opt(g)
+	}
+	return g
+}
+
+// Defs returns the definitions of the named types referenced by the schemas
+// generated so far, keyed by DefName.
+func (g *Generator) Defs() map[string]*Schema {
+	if len(g.defs) == 0 {
+		return nil
+	}
+	return g.defs
+}
+
+var (
+	timeType          = reflect.TypeFor[time.Time]()
+	durationType      = reflect.TypeFor[time.Duration]()
+	rawMessageType    = reflect.TypeFor[json.RawMessage]()
+	jsonMarshalerType = reflect.TypeFor[json.Marshaler]()
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+)
+
+// Schema returns the schema for t. Named struct types are defined in the
+// generator's definitions and referenced.
+func (g *Generator) Schema(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case durationType:
+		return &Schema{Type: "integer", Description: "duration in nanoseconds"}
+	case rawMessageType:
+		return &Schema{}
+	}
+
+	if implements(t, jsonMarshalerType) {
+		return &Schema{}
+	} else if implements(t, textMarshalerType) {
+		return &Schema{Type: "string"}
+	}
+
+	if t.Kind() == reflect.Struct && t.Name() != "" {
+		return g.ref(t)
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 && !implements(t.Elem(), textMarshalerType) {
+			return &Schema{Type: "string", ContentEncoding: "base64"}
+		}
+		return &Schema{Type: "array", Items: g.Schema(t.Elem())}
+	case reflect.Array:
+		n := t.Len()
+		return &Schema{Type: "array", Items: g.Schema(t.Elem()), MinItems: &n, MaxItems: &n}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.Schema(t.Elem())}
+	case reflect.Struct:
+		return g.object(t)
+	default:
+		// interfaces, and types that cannot be encoded as JSON
+		return &Schema{}
+	}
+}
+
+// ref defines t, if it has not already been defined, and returns a reference
+// to its definition.
+func (g *Generator) ref(t reflect.Type) *Schema {
+	name, ok := g.names[t]
+	if !ok {
+		name = g.defName(t)
+		g.names[t] = name
+		def := &Schema{}
+		g.defs[name] = def
+		*def = *g.object(t)
+		def.Title = name
+		if g.comments != nil {
+			def.Description = g.comments.Type(t)
+		}
+	}
+	return &Schema{Ref: "#/$defs/" + name}
+}
+
+// defName returns a definition name for t that is not used by another type:
+// DefName(t), or failing that, DefName(t) prefixed with its package's name.
+func (g *Generator) defName(t reflect.Type) string {
+	name := DefName(t)
+	if _, taken := g.defs[name]; !taken {
+		return name
+	}
+	pkg := t.PkgPath()
+	pkg = pkg[strings.LastIndex(pkg, "/")+1:]
+	base := DefName(t)
+	name = exportedName(pkg) + base
+	for i := 2; ; i++ {
+		if _, taken := g.defs[name]; !taken {
+			return name
+		}
+		name = exportedName(pkg) + base + strconv.Itoa(i)
+	}
+}
+
+// DefName returns the definition name for the named type t: its Go name, or
+// for instantiated generic types, its name followed by those of its type
+// arguments; e.g. Page[users.User] is named PageUser.
+func DefName(t reflect.Type) string {
+	var b strings.Builder
+	for _, part := range strings.FieldsFunc(t.Name(), func(r rune) bool { return strings.ContainsRune("[], *", r) }) {
+		part = part[strings.LastIndex(part, ".")+1:]
+		b.WriteString(exportedName(part))
+	}
+	return b.String()
+}
+
+func exportedName(s string) string {
+	if s == "" {
+		return s
+	}
+	r := []rune(s)
+	r[0] = unicode.ToUpper(r[0])
+	return string(r)
+}
+
+func implements(t, iface reflect.Type) bool {
+	return t.Implements(iface) || reflect.PointerTo(t).Implements(iface)
+}
+
+// object returns the schema for the struct type t.
+func (g *Generator) object(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	g.fields(s, t, map[string]bool{})
+	if len(s.Properties) == 0 {
+		s.Properties = nil
+	}
+	return s
+}
+
+// fields adds the properties for the fields of struct type t to s, including
+// those of embedded structs, which shallower fields take precedence over.
+func (g *Generator) fields(s *Schema, t reflect.Type, seen map[string]bool) {
+	var embedded []reflect.Type
+	for i := range t.NumField() {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+
+		ft := f.Type
+		for ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			embedded = append(embedded, ft)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		var prop *Schema
+		if hasOption(opts, "string") && isScalar(ft) {
+			prop = &Schema{Type: "string"}
+		} else {
+			prop = g.Schema(f.Type)
+		}
+		if g.comments != nil {
+			if doc := g.comments.Field(t, f.Name); doc != "" {
+				prop = describe(prop, doc)
+			}
+		}
+
+		required := !hasOption(opts, "omitempty") && !hasOption(opts, "omitzero")
+		if applyValidation(prop, ft, f.Tag.Get("validate")) {
+			required = true
+		}
+
+		s.Properties[name] = prop
+		s.PropertyOrder = append(s.PropertyOrder, name)
+		if required {
+			s.Required = append(s.Required, name)
+		}
+	}
+	for _, et := range embedded {
+		g.fields(s, et, seen)
+	}
+}
+
+// describe sets the description of prop, which must not be modified if it
+// is a reference.
+func describe(prop *Schema, doc string) *Schema {
+	if prop.Ref != "" {
+		return &Schema{Ref: prop.Ref, Description: doc}
+	}
+	prop.Description = doc
+	return prop
+}
+
+func hasOption(opts, opt string) bool {
+	for opts != "" {
+		var o string
+		o, opts, _ = strings.Cut(opts, ",")
+		if o == opt {
+			return true
+		}
+	}
+	return false
+}
+
+func isScalar(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.String:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,144 @@
+package schema
+
+import (
+	"context"
+	"encoding/json"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jaz303/operator"
+	"github.com/stretchr/testify/assert"
+)
+
+type nopTx struct{}
+
+func (nopTx) Commit(context.Context) error   { return nil }
+func (nopTx) Rollback(context.Context) error { return nil }
+
+// Audit records who changed an entity.
+type Audit struct {
+	UpdatedBy string    `json:"updatedBy"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// CreateUser is the input to users.Create.
+type CreateUser struct {
+	Audit
+
+	// Email address, which must be unique
+	Email string   `json:"email" validate:"required,email"`
+	Name  string   `json:"name,omitempty" validate:"max=100"`
+	Age   int      `json:"age,omitempty" validate:"gte=18,lt=150"`
+	Role  string   `json:"role" validate:"oneof=admin member"`
+	Tags  []string `json:"tags,omitempty" validate:"max=5,dive,max=10"`
+	Count int64    `json:"count,string"`
+	Raw   []byte   `json:"raw,omitempty"`
+	Skip  string   `json:"-"`
+	Meta  map[string]any
+	Ptr   *int `json:"ptr,omitempty"`
+
+	internal string
+}
+
+type User struct {
+	ID      int     `json:"id"`
+	Manager *User   `json:"manager,omitempty"` // line comment
+	Reports []*User `json:"reports"`
+}
+
+type Page[T any] struct {
+	Items []T `json:"items"`
+}
+
+func createUser(ctx *operator.OpContext[nopTx], in *CreateUser) (*User, error) { return nil, nil }
+func listUsers(ctx *operator.OpContext[nopTx], in *struct{}) (*Page[User], error) {
+	return nil, nil
+}
+
+func ptr[T any](v T) *T { return &v }
+
+func TestOperations(t *testing.T) {
+	hub := operator.NewHub(func(context.Context) (nopTx, error) { return nopTx{}, nil })
+	operator.RegisterOperation(hub, "users.Create", createUser)
+	operator.RegisterOperation(hub, "users.List", listUsers)
+
+	doc := Operations(hub)
+	assert.Equal(t, Dialect, doc.Schema)
+	assert.Len(t, doc.Operations, 2)
+
+	create := doc.Operations[0]
+	assert.Equal(t, "users.Create", create.Name)
+	assert.Equal(t, &Schema{Ref: "#/$defs/CreateUser"}, create.Input)
+	assert.Equal(t, &Schema{Ref: "#/$defs/User"}, create.Output)
+	assert.Equal(t, reflect.TypeFor[CreateUser](), create.InputType)
+
+	list := doc.Operations[1]
+	assert.Equal(t, &Schema{Type: "object"}, list.Input)
+	assert.Equal(t, &Schema{Ref: "#/$defs/PageUser"}, list.Output)
+
+	in := doc.Defs["CreateUser"]
+	assert.Equal(t, "object", in.Type)
+	assert.Equal(t, []string{"email", "role", "count", "Meta", "updatedBy", "updatedAt"}, in.Required)
+	assert.Equal(t, &Schema{Type: "string", Format: "email"}, in.Properties["email"])
+	assert.Equal(t, &Schema{Type: "string", MaxLength: ptr(100)}, in.Properties["name"])
+	assert.Equal(t, &Schema{Type: "integer", Minimum: ptr(18.0), ExclusiveMaximum: ptr(150.0)}, in.Properties["age"])
+	assert.Equal(t, []any{"admin", "member"}, in.Properties["role"].Enum)
+	assert.Equal(t, &Schema{Type: "array", Items: &Schema{Type: "string"}, MaxItems: ptr(5)}, in.Properties["tags"])
+	assert.Equal(t, &Schema{Type: "string"}, in.Properties["count"])
+	assert.Equal(t, &Schema{Type: "string", ContentEncoding: "base64"}, in.Properties["raw"])
+	assert.Equal(t, &Schema{Type: "object", AdditionalProperties: &Schema{}}, in.Properties["Meta"])
+	assert.Equal(t, &Schema{Type: "integer"}, in.Properties["ptr"])
+	assert.Equal(t, &Schema{Type: "string", Format: "date-time"}, in.Properties["updatedAt"])
+	assert.NotContains(t, in.Properties, "Skip")
+	assert.NotContains(t, in.Properties, "internal")
+
+	user := doc.Defs["User"]
+	assert.Equal(t, &Schema{Ref: "#/$defs/User"}, user.Properties["manager"], "recursive types are referenced")
+	assert.Equal(t, []string{"id", "reports"}, user.Required)
+
+	page := doc.Defs["PageUser"]
+	assert.Equal(t, &Schema{Type: "array", Items: &Schema{Ref: "#/$defs/User"}}, page.Properties["items"])
+
+	_, err := json.Marshal(doc)
+	assert.Nil(t, err)
+}
+
+func TestFor(t *testing.T) {
+	s := For(reflect.TypeFor[*User]())
+	assert.Equal(t, Dialect, s.Schema)
+	assert.Equal(t, "object", s.Type)
+	assert.Equal(t, "User", s.Title)
+	assert.Contains(t, s.Defs, "User")
+}
+
+func TestComments(t *testing.T) {
+	c := NewComments()
+	f, err := parser.ParseFile(token.NewFileSet(), "schema_test.go", nil, parser.ParseComments)
+	assert.Nil(t, err)
+	c.addFile("github.com/jaz303/operator/schema", f)
+
+	assert.Equal(t, "CreateUser is the input to users.Create.", c.Type(reflect.TypeFor[CreateUser]()))
+	assert.Equal(t, "Email address, which must be unique", c.Field(reflect.TypeFor[CreateUser](), "Email"))
+
+	doc := For(reflect.TypeFor[CreateUser](), WithComments(c))
+	assert.Equal(t, "CreateUser is the input to users.Create.", doc.Description)
+	assert.Equal(t, "Email address, which must be unique", doc.Properties["email"].Description)
+
+	user := For(reflect.TypeFor[User](), WithComments(c))
+	assert.Equal(t, &Schema{Ref: "#/$defs/User", Description: "line comment"}, user.Properties["manager"])
+}
+
+func TestDefName_Collision(t *testing.T) {
+	g := NewGenerator()
+	type Time struct{ X int }
+	assert.Equal(t, &Schema{Ref: "#/$defs/Time"}, g.Schema(reflect.TypeFor[Time]()))
+	assert.Equal(t, &Schema{Ref: "#/$defs/Time"}, g.Schema(reflect.TypeFor[Time]()))
+	assert.Equal(t, &Schema{Type: "string", Format: "date-time"}, g.Schema(reflect.TypeFor[time.Time]()))
+
+	type Other struct{ X int }
+	g.defs["Other"] = &Schema{}
+	assert.Equal(t, &Schema{Ref: "#/$defs/SchemaOther"}, g.Schema(reflect.TypeFor[Other]()))
+}
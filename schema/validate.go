@@ -0,0 +1,101 @@
+package schema
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// applyValidation adds the constraints expressed by tag, a validator-style
+// validate tag, to prop, which describes a value of type t. Returns true if
+// the tag marks the field as required. Unrecognised rules are ignored, as
+// are the rules following dive, which apply to elements.
+func applyValidation(prop *Schema, t reflect.Type, tag string) (required bool) {
+	if tag == "" || tag == "-" {
+		return false
+	}
+	for _, rule := range strings.Split(tag, ",") {
+		name, param, _ := strings.Cut(rule, "=")
+		switch name {
+		case "dive":
+			return required
+		case "required":
+			required = true
+		case "email":
+			prop.Format = "email"
+		case "url", "uri":
+			prop.Format = "uri"
+		case "uuid", "uuid4", "uuid7":
+			prop.Format = "uuid"
+		case "oneof":
+			for _, v := range strings.Fields(param) {
+				prop.Enum = append(prop.Enum, enumValue(t, v))
+			}
+		case "len":
+			bound(prop, t, param, true, true)
+		case "min", "gte":
+			bound(prop, t, param, true, false)
+		case "max", "lte":
+			bound(prop, t, param, false, true)
+		case "gt":
+			if n, err := strconv.ParseFloat(param, 64); err == nil && isNumber(t) {
+				prop.ExclusiveMinimum = &n
+			}
+		case "lt":
+			if n, err := strconv.ParseFloat(param, 64); err == nil && isNumber(t) {
+				prop.ExclusiveMaximum = &n
+			}
+		}
+	}
+	return required
+}
+
+// bound applies a min and/or max rule to prop: a length for strings and
+// collections, and a value for numbers.
+func bound(prop *Schema, t reflect.Type, param string, lower, upper bool) {
+	switch t.Kind() {
+	case reflect.String, reflect.Slice, reflect.Array, reflect.Map:
+		n, err := strconv.Atoi(param)
+		if err != nil {
+			return
+		}
+		minp, maxp := &prop.MinLength, &prop.MaxLength
+		if t.Kind() != reflect.String {
+			minp, maxp = &prop.MinItems, &prop.MaxItems
+		}
+		if lower {
+			*minp = &n
+		}
+		if upper {
+			*maxp = &n
+		}
+	default:
+		if !isNumber(t) {
+			return
+		}
+		n, err := strconv.ParseFloat(param, 64)
+		if err != nil {
+			return
+		}
+		if lower {
+			prop.Minimum = &n
+		}
+		if upper {
+			prop.Maximum = &n
+		}
+	}
+}
+
+func isNumber(t reflect.Type) bool {
+	return isScalar(t) && t.Kind() != reflect.String && t.Kind() != reflect.Bool
+}
+
+// enumValue converts an oneof value to the JSON type of t.
+func enumValue(t reflect.Type, v string) any {
+	if isNumber(t) {
+		if n, err := strconv.ParseFloat(v, 64); err == nil {
+			return n
+		}
+	}
+	return v
+}
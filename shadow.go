@@ -0,0 +1,82 @@
+package operator
+
+import (
+	"context"
+	"reflect"
+)
+
+// Comparator configures how InvokeShadow() compares the results of its
+// primary and candidate operations.
+type Comparator[O any] struct {
+	// Equal reports whether the outputs of two successful invocations
+	// match. The default is reflect.DeepEqual.
+	Equal func(primary, candidate *O) bool
+
+	// OnMismatch is called when the results differ. It is called
+	// synchronously, before InvokeShadow() returns.
+	OnMismatch func(ctx context.Context, m *Mismatch)
+}
+
+// Mismatch describes differing results of a primary operation and its
+// candidate replacement. The input and outputs are redacted with Redact(), so
+// that mismatches can be logged safely.
+type Mismatch struct {
+	// Names of the operations
+	Primary   string
+	Candidate string
+
+	Input any
+
+	PrimaryOutput   any
+	PrimaryErr      error
+	CandidateOutput any
+	CandidateErr    error
+}
+
+// InvokeShadow() invokes primary with input and returns its result, like
+// Invoke(), additionally invoking candidate - typically a rewrite of primary -
+// with the same input, so that it can be validated against real traffic.
+//
+// The candidate runs first, in an operation of its own that is always rolled
+// back: its AfterFuncs and follow-ups never run, and OpContext.Shadow()
+// returns true, so both operations observe the same state. Its result never
+// affects the caller. Results differ if exactly one operation fails, or if
+// both succeed and their outputs are not equal; differing results are
+// reported to cmp.OnMismatch.
+//
+// Neither operation may modify its input.
+func InvokeShadow[Tx Transaction, I any, O any](ctx context.Context, hub *Hub[Tx], primary Operation[Tx, I, O], candidate Operation[Tx, I, O], input *I, cmp Comparator[O]) (*O, error) {
+	candidateName, info := hub.lookupOperation(candidate)
+	shadow := hub.beginOperation(ctx, candidateName)
+	shadow.shadow = true
+	candidateOut, candidateErr := runOperation(shadow, info, candidate, input)
+
+	out, err := Invoke(ctx, hub, primary, input)
+
+	equal := cmp.Equal
+	if equal == nil {
+		equal = func(a, b *O) bool { return reflect.DeepEqual(a, b) }
+	}
+
+	var matched bool
+	if err != nil || candidateErr != nil {
+		matched = (err == nil) == (candidateErr == nil)
+	} else {
+		matched = equal(out, candidateOut)
+	}
+
+	if !matched && cmp.OnMismatch != nil {
+		primaryName, _ := hub.lookupOperation(primary)
+		cmp.OnMismatch(ctx, &Mismatch{
+			Primary:         primaryName,
+			Candidate:       candidateName,
+			Input:           Redact(input),
+			PrimaryOutput:   Redact(out),
+			PrimaryErr:      err,
+			CandidateOutput: Redact(candidateOut),
+			CandidateErr:    candidateErr,
+		})
+	}
+
+	return out, err
+}
@@ -0,0 +1,118 @@
+package operator
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type shadowInput struct {
+	N      int
+	Secret string `op:"redact"`
+}
+
+func TestInvokeShadow(t *testing.T) {
+	hub := newTestHub()
+
+	var candidateTx, primaryTx *TxTest
+	var handled []bool
+	var afterRan bool
+	assert.NoError(t, hub.RegisterEventHandler(&testEvent{}, func(ctx *OpContext[*TxTest], evt Event) error {
+		handled = append(handled, ctx.Shadow())
+		return nil
+	}))
+
+	primary := func(ctx *OpContext[*TxTest], in *shadowInput) (*int, error) {
+		primaryTx, _ = ctx.Tx()
+		out := in.N * 2
+		return &out, nil
+	}
+	candidate := func(ctx *OpContext[*TxTest], in *shadowInput) (*int, error) {
+		candidateTx, _ = ctx.Tx()
+		ctx.Emit(&testEvent{})
+		ctx.AfterFunc(func(*OpContext[*TxTest]) { afterRan = true })
+		out := in.N + in.N
+		return &out, nil
+	}
+
+	var mismatches []*Mismatch
+	out, err := InvokeShadow(context.Background(), hub, primary, candidate, &shadowInput{N: 2}, Comparator[int]{
+		OnMismatch: func(ctx context.Context, m *Mismatch) { mismatches = append(mismatches, m) },
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 4, *out)
+	assert.Empty(t, mismatches)
+
+	assert.True(t, primaryTx.Committed)
+	assert.False(t, candidateTx.Committed)
+	assert.True(t, candidateTx.RolledBack)
+	assert.Equal(t, []bool{true}, handled)
+	assert.False(t, afterRan)
+}
+
+func TestInvokeShadow_Mismatch(t *testing.T) {
+	hub := newTestHub()
+
+	primary := func(ctx *OpContext[*TxTest], in *shadowInput) (*int, error) {
+		out := in.N * 2
+		return &out, nil
+	}
+	assert.NoError(t, RegisterOperation(hub, "double", primary))
+	wrong := func(ctx *OpContext[*TxTest], in *shadowInput) (*int, error) {
+		out := in.N * 3
+		return &out, nil
+	}
+	assert.NoError(t, RegisterOperation(hub, "double.v2", wrong))
+	failing := func(ctx *OpContext[*TxTest], in *shadowInput) (*int, error) {
+		return nil, errors.New("boom")
+	}
+
+	var mismatches []*Mismatch
+	cmp := Comparator[int]{
+		OnMismatch: func(ctx context.Context, m *Mismatch) { mismatches = append(mismatches, m) },
+	}
+
+	out, err := InvokeShadow(context.Background(), hub, primary, wrong, &shadowInput{N: 2, Secret: "s3cret"}, cmp)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, *out)
+
+	out, err = InvokeShadow(context.Background(), hub, primary, failing, &shadowInput{N: 1}, cmp)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, *out)
+
+	if assert.Len(t, mismatches, 2) {
+		four, six := 4, 6
+		assert.Equal(t, &Mismatch{
+			Primary:         "double",
+			Candidate:       "double.v2",
+			Input:           &shadowInput{N: 2, Secret: RedactedText},
+			PrimaryOutput:   &four,
+			CandidateOutput: &six,
+		}, mismatches[0])
+		assert.EqualError(t, mismatches[1].CandidateErr, "boom")
+	}
+}
+
+func TestInvokeShadow_CustomEqual(t *testing.T) {
+	hub := newTestHub()
+
+	primary := func(ctx *OpContext[*TxTest], in *shadowInput) (*int, error) {
+		return &in.N, nil
+	}
+	candidate := func(ctx *OpContext[*TxTest], in *shadowInput) (*int, error) {
+		out := in.N + 1
+		return &out, nil
+	}
+
+	mismatched := false
+	_, err := InvokeShadow(context.Background(), hub, primary, candidate, &shadowInput{N: 1}, Comparator[int]{
+		Equal:      func(a, b *int) bool { return *b-*a <= 1 },
+		OnMismatch: func(ctx context.Context, m *Mismatch) { mismatched = true },
+	})
+
+	assert.NoError(t, err)
+	assert.False(t, mismatched)
+}
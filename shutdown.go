@@ -0,0 +1,107 @@
+package operator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrShuttingDown is returned by Invoke() once Hub.Shutdown() has been
+// called.
+var ErrShuttingDown = errors.New("hub is shutting down")
+
+// lifecycle tracks a hub's in-flight work so that it can be drained on
+// shutdown.
+type lifecycle struct {
+	closing atomic.Bool
+
+	// operations in progress, plus background work that has been scheduled
+	// but has not yet succeeded or exhausted its retries
+	active atomic.Int64
+
+	// signalled when active drops to zero while closing
+	idle chan struct{}
+
+	mu    sync.Mutex
+	hooks []func(context.Context) error
+	once  sync.Once
+}
+
+// backgroundKey marks the contexts of background work, which may begin
+// operations while the hub is draining.
+type backgroundKey struct{}
+
+// OnShutdown() registers fn to be called by Shutdown() once the hub's
+// operations and background work have drained. Subsystems that deliver the
+// results of committed operations asynchronously - webhook dispatchers,
+// event publishers and so on - register their Close methods here, so that
+// they stop only once nothing more can be sent to them. Functions are
+// called in the reverse of the order in which they were registered.
+func (h *Hub[Tx]) OnShutdown(fn func(ctx context.Context) error) {
+	h.life.mu.Lock()
+	defer h.life.mu.Unlock()
+	h.life.hooks = append(h.life.hooks, fn)
+}
+
+// Shutdown() stops the hub accepting new operations, which fail with
+// ErrShuttingDown, then waits for operations in progress to complete and for
+// background work - follow-up operations and events emitted after commit,
+// including their retries - to drain. Background work may still invoke
+// operations while draining. Finally, the functions registered with
+// OnShutdown() are called.
+//
+// If ctx is done before draining completes, the OnShutdown() functions are
+// called regardless, with ctx, and ctx's error is returned along with any
+// of theirs. Shutdown() is irreversible; the OnShutdown() functions are only
+// called by the first call.
+func (h *Hub[Tx]) Shutdown(ctx context.Context) error {
+	h.life.closing.Store(true)
+
+	var errs []error
+	for n := h.life.active.Load(); n > 0; n = h.life.active.Load() {
+		select {
+		case <-h.life.idle:
+		case <-ctx.Done():
+			errs = append(errs, fmt.Errorf("%w: %d operations or background tasks still in progress", ctx.Err(), n))
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	h.life.once.Do(func() {
+		h.life.mu.Lock()
+		hooks := h.life.hooks
+		h.life.mu.Unlock()
+		for i := len(hooks) - 1; i >= 0; i-- {
+			if err := hooks[i](ctx); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	})
+
+	return errors.Join(errs...)
+}
+
+// enter records the start of an operation, returning false if the hub is
+// shutting down and ctx does not belong to background work.
+func (h *Hub[Tx]) enter(ctx context.Context) bool {
+	h.life.active.Add(1)
+	if h.life.closing.Load() && ctx.Value(backgroundKey{}) == nil {
+		h.exit()
+		return false
+	}
+	return true
+}
+
+// exit records the end of an operation or background task.
+func (h *Hub[Tx]) exit() {
+	if h.life.active.Add(-1) == 0 && h.life.closing.Load() {
+		select {
+		case h.life.idle <- struct{}{}:
+		default:
+		}
+	}
+}
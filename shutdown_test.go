@@ -0,0 +1,115 @@
+package operator
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShutdown_WaitsForOperations(t *testing.T) {
+	hub := newTestHub()
+
+	started, release := make(chan struct{}), make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		_, err := Invoke(context.Background(), hub, func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) {
+			close(started)
+			<-release
+			return in, nil
+		}, &struct{}{})
+		done <- err
+	}()
+	<-started
+
+	shutdown := make(chan error, 1)
+	go func() { shutdown <- hub.Shutdown(context.Background()) }()
+
+	assert.Eventually(t, func() bool {
+		_, err := Invoke(context.Background(), hub, func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) {
+			return in, nil
+		}, &struct{}{})
+		return errors.Is(err, ErrShuttingDown)
+	}, time.Second, time.Millisecond)
+
+	select {
+	case <-shutdown:
+		t.Fatal("Shutdown returned before operation completed")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(release)
+	assert.Nil(t, <-done)
+	assert.Nil(t, <-shutdown)
+}
+
+func TestShutdown_DrainsBackgroundWork(t *testing.T) {
+	hub := newTestHub(WithBackgroundRetry(3, time.Millisecond))
+
+	var attempts, chained atomic.Int32
+	chain := func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) {
+		chained.Add(1)
+		return in, nil
+	}
+	flaky := func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) {
+		if attempts.Add(1) < 3 {
+			return nil, assert.AnError
+		}
+		return in, InvokeAfterCommit(ctx, chain, in)
+	}
+
+	var closed atomic.Bool
+	var order []string
+	hub.OnShutdown(func(ctx context.Context) error {
+		order = append(order, "first")
+		return nil
+	})
+	hub.OnShutdown(func(ctx context.Context) error {
+		order = append(order, "second")
+		closed.Store(chained.Load() == 1)
+		return assert.AnError
+	})
+
+	_, err := Invoke(context.Background(), hub, func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) {
+		return in, InvokeAfterCommit(ctx, flaky, in)
+	}, &struct{}{})
+	assert.Nil(t, err)
+
+	assert.ErrorIs(t, hub.Shutdown(context.Background()), assert.AnError)
+	assert.Equal(t, int32(3), attempts.Load())
+	assert.True(t, closed.Load(), "hooks run after background work drains")
+	assert.Equal(t, []string{"second", "first"}, order)
+
+	assert.Nil(t, hub.Shutdown(context.Background()), "hooks run once")
+	assert.Len(t, order, 2)
+}
+
+func TestShutdown_Deadline(t *testing.T) {
+	hub := newTestHub()
+
+	release := make(chan struct{})
+	defer close(release)
+	started := make(chan struct{})
+	go Invoke(context.Background(), hub, func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) {
+		close(started)
+		<-release
+		return in, nil
+	}, &struct{}{})
+	<-started
+
+	var hooked bool
+	hub.OnShutdown(func(ctx context.Context) error {
+		hooked = true
+		return nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := hub.Shutdown(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.ErrorContains(t, err, "1 operations")
+	assert.True(t, hooked)
+}
@@ -0,0 +1,318 @@
+// Package ssebroadcast forwards hub events to browsers as server-sent
+// events, once the operation that emitted them has committed.
+//
+// A Broadcaster subscribes to the hub (see operator.Hub.Subscribe) and is
+// mounted as an http.Handler; each request becomes a long-lived event stream.
+// Events are serialized once with an eventcodec.Serializer, which should use
+// a text codec such as JSON, and written to every client whose subscription
+// accepts them:
+//
+//	b := ssebroadcast.New(hub, eventcodec.New(hub),
+//		ssebroadcast.WithEvents(&OrderUpdated{}),
+//		ssebroadcast.WithSubscription(func(r *http.Request) (ssebroadcast.Match, error) {
+//			user, err := authenticate(r)
+//			if err != nil {
+//				return nil, err
+//			}
+//			return func(evt operator.Event) bool {
+//				return evt.(*OrderUpdated).CustomerID == user.ID
+//			}, nil
+//		}))
+//	mux.Handle("GET /events", b)
+//
+// Each message's event field is the event's name and its data is the
+// serialized event. Delivery is best-effort: events are dropped rather than
+// delaying operations, and clients that fall behind are disconnected, to
+// reconnect as EventSource does automatically.
+package ssebroadcast
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jaz303/operator"
+	"github.com/jaz303/operator/eventcodec"
+	"github.com/jaz303/operator/operr"
+)
+
+var ErrClosed = errors.New("sse broadcaster is closed")
+
+// Match reports whether a client should receive evt.
+type Match func(evt operator.Event) bool
+
+// SubscriptionFunc is called when a client connects to decide which events it
+// receives. A nil Match receives every event; an error rejects the
+// connection, and is written with operr.DefaultErrorMapper.
+type SubscriptionFunc func(r *http.Request) (Match, error)
+
+// Broadcaster is an http.Handler streaming hub events to connected clients.
+type Broadcaster struct {
+	serializer *eventcodec.Serializer
+	events     []operator.Event
+	subscribe  SubscriptionFunc
+	buffer     int
+	clientBuf  int
+	heartbeat  time.Duration
+	onError    func(error)
+
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu      sync.Mutex
+	closed  bool
+	seq     uint64
+	clients map[*client]struct{}
+}
+
+type client struct {
+	match  Match
+	frames chan []byte
+
+	// closed by the broadcaster to disconnect the client
+	evicted chan struct{}
+}
+
+// Option configures a Broadcaster.
+type Option func(b *Broadcaster)
+
+// WithEvents limits the broadcast to events of the same types as events. By
+// default, every event is broadcast.
+func WithEvents(events ...operator.Event) Option {
+	return func(b *Broadcaster) { b.events = append(b.events, events...) }
+}
+
+// WithSubscription sets the function deciding which events each client
+// receives. By default, clients receive every broadcast event.
+func WithSubscription(fn SubscriptionFunc) Option {
+	return func(b *Broadcaster) { b.subscribe = fn }
+}
+
+// WithBuffer sets the number of committed events that may await
+// serialization; further events are dropped. The default is 256.
+func WithBuffer(n int) Option {
+	return func(b *Broadcaster) { b.buffer = n }
+}
+
+// WithClientBuffer sets the number of messages that may await writing to each
+// client; a client that falls further behind is disconnected. The default is
+// 64.
+func WithClientBuffer(n int) Option {
+	return func(b *Broadcaster) { b.clientBuf = n }
+}
+
+// WithHeartbeat sets the interval at which a comment is written to idle
+// streams, to stop proxies closing them. The default is 30 seconds; zero
+// disables heartbeats.
+func WithHeartbeat(d time.Duration) Option {
+	return func(b *Broadcaster) { b.heartbeat = d }
+}
+
+// WithErrorHandler sets a function to be called when an event cannot be
+// serialized. The default logs the error with slog.Default().
+func WithErrorHandler(fn func(error)) Option {
+	return func(b *Broadcaster) { b.onError = fn }
+}
+
+// New returns a Broadcaster of hub's events, serialized with s. The
+// Broadcaster is closed by hub.Shutdown, or may be closed with Close.
+func New[Tx operator.Transaction](hub *operator.Hub[Tx], s *eventcodec.Serializer, opts ...Option) *Broadcaster {
+	b := &Broadcaster{
+		serializer: s,
+		buffer:     256,
+		clientBuf:  64,
+		heartbeat:  30 * time.Second,
+		onError: func(err error) {
+			slog.Error("sse broadcast failed", "error", err)
+		},
+		done:    make(chan struct{}),
+		clients: map[*client]struct{}{},
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	b.cancel = cancel
+
+	var wg sync.WaitGroup
+	subscribe := func(evt operator.Event) {
+		ch := hub.Subscribe(ctx, evt, operator.SubscribeBuffer(b.buffer))
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for evt := range ch {
+				b.broadcast(evt)
+			}
+		}()
+	}
+	if len(b.events) == 0 {
+		subscribe(nil)
+	}
+	for _, evt := range b.events {
+		subscribe(evt)
+	}
+	go func() {
+		wg.Wait()
+		close(b.done)
+	}()
+
+	hub.OnShutdown(b.Close)
+	return b
+}
+
+// Clients returns the number of connected clients.
+func (b *Broadcaster) Clients() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.clients)
+}
+
+// Close stops the broadcast and disconnects every client. It is safe to call
+// more than once.
+func (b *Broadcaster) Close(ctx context.Context) error {
+	b.mu.Lock()
+	if !b.closed {
+		b.closed = true
+		b.cancel()
+		for c := range b.clients {
+			b.evict(c)
+		}
+	}
+	b.mu.Unlock()
+
+	select {
+	case <-b.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// ServeHTTP streams events to the client until it disconnects, falls behind,
+// or the Broadcaster is closed.
+func (b *Broadcaster) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rc := http.NewResponseController(w)
+
+	var match Match
+	if b.subscribe != nil {
+		m, err := b.subscribe(r)
+		if err != nil {
+			operr.DefaultErrorMapper(w, err)
+			return
+		}
+		match = m
+	}
+
+	c := &client{
+		match:   match,
+		frames:  make(chan []byte, b.clientBuf),
+		evicted: make(chan struct{}),
+	}
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		operr.DefaultErrorMapper(w, fmt.Errorf("%w: %w", operr.ErrUnavailable, ErrClosed))
+		return
+	}
+	b.clients[c] = struct{}{}
+	b.mu.Unlock()
+	defer b.remove(c)
+
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	var heartbeat <-chan time.Time
+	if b.heartbeat > 0 {
+		t := time.NewTicker(b.heartbeat)
+		defer t.Stop()
+		heartbeat = t.C
+	}
+
+	for {
+		var frame []byte
+		select {
+		case frame = <-c.frames:
+		case <-heartbeat:
+			frame = []byte(": heartbeat\n\n")
+		case <-c.evicted:
+			return
+		case <-r.Context().Done():
+			return
+		}
+		if _, err := w.Write(frame); err != nil {
+			return
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func (b *Broadcaster) broadcast(evt operator.Event) {
+	var frame []byte
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for c := range b.clients {
+		if c.match != nil && !c.match(evt) {
+			continue
+		}
+		if frame == nil {
+			env, err := b.serializer.Encode(evt)
+			if err != nil {
+				b.onError(err)
+				return
+			}
+			b.seq++
+			frame = formatFrame(b.seq, env)
+		}
+		select {
+		case c.frames <- frame:
+		default:
+			b.evict(c)
+		}
+	}
+}
+
+// evict disconnects c; b.mu must be held.
+func (b *Broadcaster) evict(c *client) {
+	delete(b.clients, c)
+	close(c.evicted)
+}
+
+func (b *Broadcaster) remove(c *client) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.clients[c]; ok {
+		b.evict(c)
+	}
+}
+
+// formatFrame formats env as a server-sent event message.
+func formatFrame(id uint64, env *eventcodec.Envelope) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("id: ")
+	buf.WriteString(strconv.FormatUint(id, 10))
+	buf.WriteString("\nevent: ")
+	buf.WriteString(env.Name)
+	buf.WriteByte('\n')
+	for _, line := range bytes.Split(bytes.TrimRight(env.Data, "\n"), []byte("\n")) {
+		buf.WriteString("data: ")
+		buf.Write(bytes.TrimSuffix(line, []byte("\r")))
+		buf.WriteByte('\n')
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes()
+}
@@ -0,0 +1,141 @@
+package ssebroadcast
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jaz303/operator"
+	"github.com/jaz303/operator/eventcodec"
+	"github.com/jaz303/operator/operr"
+	"github.com/stretchr/testify/assert"
+)
+
+type nopTx struct{}
+
+func (nopTx) Commit(context.Context) error   { return nil }
+func (nopTx) Rollback(context.Context) error { return nil }
+
+type orderUpdated struct {
+	Customer string `json:"customer"`
+}
+
+func (*orderUpdated) EventName() string { return "orderUpdated" }
+
+type ignored struct{}
+
+func (*ignored) EventName() string { return "ignored" }
+
+func newHub() *operator.Hub[*nopTx] {
+	return operator.NewHub(func(context.Context) (*nopTx, error) { return &nopTx{}, nil })
+}
+
+func emit(t *testing.T, hub *operator.Hub[*nopTx], events ...operator.Event) {
+	_, err := operator.Invoke(context.Background(), hub, func(ctx *operator.OpContext[*nopTx], in *struct{}) (*struct{}, error) {
+		for _, evt := range events {
+			ctx.Emit(evt)
+		}
+		return in, nil
+	}, &struct{}{})
+	assert.Nil(t, err)
+}
+
+func connect(t *testing.T, url, customer string) *bufio.Reader {
+	req, _ := http.NewRequest(http.MethodGet, url+"?customer="+customer, nil)
+	res, err := http.DefaultClient.Do(req)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	t.Cleanup(func() { res.Body.Close() })
+	assert.Equal(t, "text/event-stream", res.Header.Get("Content-Type"))
+	return bufio.NewReader(res.Body)
+}
+
+func readMessage(t *testing.T, r *bufio.Reader) string {
+	var lines []string
+	for {
+		line, err := r.ReadString('\n')
+		if !assert.Nil(t, err) {
+			t.FailNow()
+		}
+		if line == "\n" {
+			return strings.Join(lines, "")
+		}
+		lines = append(lines, line)
+	}
+}
+
+func TestBroadcaster(t *testing.T) {
+	hub := newHub()
+	b := New(hub, eventcodec.New(hub),
+		WithEvents(&orderUpdated{}),
+		WithSubscription(func(r *http.Request) (Match, error) {
+			customer := r.URL.Query().Get("customer")
+			if customer == "" {
+				return nil, operr.ErrUnauthenticated
+			}
+			return func(evt operator.Event) bool {
+				return evt.(*orderUpdated).Customer == customer
+			}, nil
+		}))
+	srv := httptest.NewServer(b)
+	defer srv.Close()
+
+	res, err := http.Get(srv.URL)
+	assert.Nil(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+
+	alice := connect(t, srv.URL, "alice")
+	bob := connect(t, srv.URL, "bob")
+	assert.Eventually(t, func() bool { return b.Clients() == 2 }, time.Second, time.Millisecond)
+
+	emit(t, hub, &ignored{}, &orderUpdated{Customer: "bob"}, &orderUpdated{Customer: "alice"})
+
+	assert.Equal(t, "id: 1\nevent: orderUpdated\ndata: {\"customer\":\"bob\"}\n", readMessage(t, bob))
+	assert.Equal(t, "id: 2\nevent: orderUpdated\ndata: {\"customer\":\"alice\"}\n", readMessage(t, alice))
+
+	assert.Nil(t, hub.Shutdown(context.Background()))
+	_, err = alice.ReadString('\n')
+	assert.Error(t, err, "stream ends when the broadcaster is closed")
+	assert.Equal(t, 0, b.Clients())
+}
+
+func TestBroadcaster_SlowClient(t *testing.T) {
+	hub := newHub()
+	b := New(hub, eventcodec.New(hub), WithClientBuffer(1), WithHeartbeat(0))
+	defer b.Close(context.Background())
+
+	c := &client{frames: make(chan []byte, 1), evicted: make(chan struct{})}
+	b.mu.Lock()
+	b.clients[c] = struct{}{}
+	b.mu.Unlock()
+
+	emit(t, hub, &orderUpdated{}, &orderUpdated{})
+	select {
+	case <-c.evicted:
+	case <-time.After(time.Second):
+		t.Fatal("slow client was not disconnected")
+	}
+	assert.Equal(t, 0, b.Clients())
+}
+
+func TestBroadcaster_Closed(t *testing.T) {
+	hub := newHub()
+	b := New(hub, eventcodec.New(hub))
+	assert.Nil(t, b.Close(context.Background()))
+	assert.Nil(t, b.Close(context.Background()))
+
+	w := httptest.NewRecorder()
+	b.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+}
+
+func TestFormatFrame(t *testing.T) {
+	frame := formatFrame(7, &eventcodec.Envelope{Name: "e", Data: []byte("a\r\nb\n")})
+	assert.Equal(t, "id: 7\nevent: e\ndata: a\ndata: b\n\n", string(frame))
+}
@@ -0,0 +1,148 @@
+package operator
+
+import (
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// OperationStats summarizes the invocations of an operation; see
+// Hub.Stats().
+type OperationStats struct {
+	Name string `json:"name"`
+
+	// Number of invocations, and of failed invocations, since the hub was
+	// created
+	Count    int64 `json:"count"`
+	Failures int64 `json:"failures"`
+
+	// Latency percentiles, and the fraction that failed, of the most recent
+	// invocations, up to the hub's stats window
+	P50         time.Duration `json:"p50"`
+	P95         time.Duration `json:"p95"`
+	P99         time.Duration `json:"p99"`
+	FailureRate float64       `json:"failure_rate"`
+
+	// Error of the most recent failed invocation, and the time at which it
+	// failed, according to the hub's clock
+	LastError     string    `json:"last_error,omitempty"`
+	LastErrorTime time.Time `json:"last_error_time,omitzero"`
+}
+
+// WithOperationStats makes the hub maintain statistics for each operation,
+// summarizing the latency and failure rate of its window most recent
+// invocations, so that they can be listed by Hub.Stats(). Statistics are
+// disabled by default as they add a small cost to every invocation.
+func WithOperationStats(window int) HubOption {
+	return func(o *hubOptions) {
+		o.trackStats = true
+		o.statsWindow = window
+	}
+}
+
+// WithSlowOperationThreshold logs a warning, via the hub's logger, for each
+// operation that takes longer than d, including its redacted input; see
+// Redact().
+func WithSlowOperationThreshold(d time.Duration) HubOption {
+	return func(o *hubOptions) { o.slowThreshold = d }
+}
+
+// Stats() returns statistics for each operation invoked on the hub, ordered
+// by name. Returns nil unless the hub was created with
+// WithOperationStats().
+func (h *Hub[Tx]) Stats() []OperationStats {
+	if !h.opts.trackStats {
+		return nil
+	}
+	out := []OperationStats{}
+	h.stats.Range(func(key, value any) bool {
+		out = append(out, value.(*opStats).snapshot(key.(string)))
+		return true
+	})
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// opStats records the invocations of one operation. samples is a ring of the
+// most recent invocations, next being the index of the oldest once full.
+type opStats struct {
+	mu            sync.Mutex
+	count         int64
+	failures      int64
+	samples       []opSample
+	next          int
+	lastError     string
+	lastErrorTime time.Time
+}
+
+type opSample struct {
+	duration time.Duration
+	failed   bool
+}
+
+// observeStats records an invocation of op, which ended at end, having
+// taken d, with error err, and logs it if it was slow.
+func (h *Hub[Tx]) observeStats(op *OpContext[Tx], end time.Time, d time.Duration, err error) {
+	o := &h.opts
+	if o.trackStats {
+		s, ok := h.stats.Load(op.name)
+		if !ok {
+			s, _ = h.stats.LoadOrStore(op.name, &opStats{})
+		}
+		s.(*opStats).record(max(o.statsWindow, 1), d, err, end)
+	}
+	if t := o.slowThreshold; t > 0 && d > t {
+		o.logger.Warn("operator: operation slower than threshold",
+			"operation", op.Name(), "operation_id", op.ID(),
+			"duration", d, "error", err, "input", OperationInput(op))
+	}
+}
+
+func (s *opStats) record(window int, d time.Duration, err error, at time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.count++
+	sample := opSample{duration: d, failed: err != nil}
+	if err != nil {
+		s.failures++
+		s.lastError, s.lastErrorTime = err.Error(), at
+	}
+	if len(s.samples) < window {
+		s.samples = append(s.samples, sample)
+	} else {
+		s.samples[s.next] = sample
+		s.next = (s.next + 1) % window
+	}
+}
+
+func (s *opStats) snapshot(name string) OperationStats {
+	s.mu.Lock()
+	out := OperationStats{
+		Name:          name,
+		Count:         s.count,
+		Failures:      s.failures,
+		LastError:     s.lastError,
+		LastErrorTime: s.lastErrorTime,
+	}
+	durations := make([]time.Duration, len(s.samples))
+	failed := 0
+	for i, sample := range s.samples {
+		durations[i] = sample.duration
+		if sample.failed {
+			failed++
+		}
+	}
+	s.mu.Unlock()
+
+	if len(durations) == 0 {
+		return out
+	}
+	slices.Sort(durations)
+	percentile := func(p int) time.Duration {
+		return durations[(len(durations)*p+99)/100-1]
+	}
+	out.P50, out.P95, out.P99 = percentile(50), percentile(95), percentile(99)
+	out.FailureRate = float64(failed) / float64(len(durations))
+	return out
+}
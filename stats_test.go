@@ -0,0 +1,80 @@
+package operator
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type statsInput struct {
+	Millis   int
+	Fail     bool
+	Password string `op:"redact"`
+}
+
+func TestHub_Stats(t *testing.T) {
+	clock := &manualClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	hub := newTestHub(WithClock(clock), WithOperationStats(10))
+
+	op := func(ctx *OpContext[*TxTest], in *statsInput) (*struct{}, error) {
+		clock.now = clock.now.Add(time.Duration(in.Millis) * time.Millisecond)
+		if in.Fail {
+			return nil, errors.New("failed")
+		}
+		return &struct{}{}, nil
+	}
+	assert.NoError(t, RegisterOperation(hub, "timed", op))
+	assert.NoError(t, RegisterOperation(hub, "other", func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) { return in, nil }))
+
+	// 20 invocations taking 1..20ms; the window holds the last 10
+	for i := 1; i <= 20; i++ {
+		Invoke(context.Background(), hub, op, &statsInput{Millis: i, Fail: i%5 == 0})
+	}
+	failedAt := clock.now
+
+	stats := hub.Stats()
+	if assert.Len(t, stats, 1, "operations not yet invoked are not listed") {
+		assert.Equal(t, OperationStats{
+			Name:          "timed",
+			Count:         20,
+			Failures:      4,
+			P50:           15 * time.Millisecond,
+			P95:           20 * time.Millisecond,
+			P99:           20 * time.Millisecond,
+			FailureRate:   0.2,
+			LastError:     "failed",
+			LastErrorTime: failedAt,
+		}, stats[0])
+	}
+
+	assert.Nil(t, newTestHub().Stats())
+	assert.EqualError(t, newTestHub(WithOperationStats(0), WithSlowOperationThreshold(-time.Second)).Validate(),
+		"operation stats window 0 must be at least 1\nslow operation threshold -1s is negative")
+}
+
+func TestHub_SlowOperationLog(t *testing.T) {
+	var logs bytes.Buffer
+	hub := newTestHub(
+		WithClock(&stepClock{}),
+		WithSlowOperationThreshold(5*time.Millisecond),
+		WithLogger(slog.New(slog.NewTextHandler(&logs, nil))),
+	)
+
+	op := func(ctx *OpContext[*TxTest], in *statsInput) (*struct{}, error) {
+		return &struct{}{}, nil
+	}
+	assert.NoError(t, RegisterOperation(hub, "slow", op))
+	_, err := Invoke(context.Background(), hub, op, &statsInput{Millis: 1, Password: "hunter2"})
+	assert.NoError(t, err)
+
+	assert.Equal(t, 1, strings.Count(logs.String(), "operation slower than threshold"))
+	assert.Contains(t, logs.String(), "operation=slow")
+	assert.Contains(t, logs.String(), RedactedText)
+	assert.NotContains(t, logs.String(), "hunter2")
+}
@@ -0,0 +1,167 @@
+package operator
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+// SubscribeOption configures a subscription; pass options to
+// Hub.Subscribe().
+type SubscribeOption func(s *subscriber)
+
+// SubscribeBuffer sets the capacity of the subscription's channel. The
+// default is 64.
+func SubscribeBuffer(n int) SubscribeOption {
+	return func(s *subscriber) { s.buffer = n }
+}
+
+// BlockWhenFull makes operations wait for space in the subscription's channel
+// when it is full, rather than dropping the event. A slow subscriber then
+// delays every operation that emits a matching event, so this should only be
+// used by consumers that must not miss events and keep up with them.
+func BlockWhenFull() SubscribeOption {
+	return func(s *subscriber) { s.block = true }
+}
+
+// OnDrop sets a function to be called with each event dropped because the
+// subscription's channel was full.
+func OnDrop(fn func(Event)) SubscribeOption {
+	return func(s *subscriber) { s.onDrop = fn }
+}
+
+// Subscribe() returns a channel receiving events of the same type as event,
+// or every event if event is nil, once the operation that emitted them has
+// committed. Unlike event handlers, subscribers cannot affect the operation,
+// and may come and go after the hub is frozen; they suit consumers such as
+// server-sent event broadcasters and cache invalidators.
+//
+// By default, events are dropped if the channel is full; see
+// BlockWhenFull(). The subscription ends, and the channel is closed, when ctx
+// is done. Events emitted with EmitAfterCommit() are delivered once their
+// operation commits, and events of shadow operations are never delivered.
+func (h *Hub[Tx]) Subscribe(ctx context.Context, event Event, opts ...SubscribeOption) <-chan Event {
+	s := &subscriber{buffer: 64, done: ctx.Done(), site: callSite(0)}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.ch = make(chan Event, s.buffer)
+
+	var ty reflect.Type
+	if event != nil {
+		ty = reflect.TypeOf(event)
+		s.name = event.EventName()
+	}
+	h.subs.add(ty, s)
+	context.AfterFunc(ctx, func() {
+		h.subs.remove(ty, s)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		close(s.ch)
+	})
+	return s.ch
+}
+
+type subscriber struct {
+	ch     chan Event
+	done   <-chan struct{}
+	buffer int
+	block  bool
+	onDrop func(Event)
+
+	// name of the events subscribed to, and where the subscription was made
+	name string
+	site string
+
+	// held for reading while sending, and for writing to close ch
+	mu sync.RWMutex
+}
+
+func (s *subscriber) send(evt Event) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	select {
+	case <-s.done:
+		return
+	default:
+	}
+	if s.block {
+		select {
+		case s.ch <- evt:
+		case <-s.done:
+		}
+		return
+	}
+	select {
+	case s.ch <- evt:
+	default:
+		if s.onDrop != nil {
+			s.onDrop(evt)
+		}
+	}
+}
+
+// subscriptions is a copy-on-write registry of subscribers, by event type;
+// subscribers to every event have a nil type.
+type subscriptions struct {
+	mu    sync.Mutex
+	table atomic.Pointer[map[reflect.Type][]*subscriber]
+}
+
+// active reports whether there are any subscribers.
+func (s *subscriptions) active() bool {
+	t := s.table.Load()
+	return t != nil && len(*t) > 0
+}
+
+func (s *subscriptions) add(ty reflect.Type, sub *subscriber) {
+	s.update(func(t map[reflect.Type][]*subscriber) {
+		t[ty] = append(t[ty][:len(t[ty]):len(t[ty])], sub)
+	})
+}
+
+func (s *subscriptions) remove(ty reflect.Type, sub *subscriber) {
+	s.update(func(t map[reflect.Type][]*subscriber) {
+		var rest []*subscriber
+		for _, x := range t[ty] {
+			if x != sub {
+				rest = append(rest, x)
+			}
+		}
+		if len(rest) == 0 {
+			delete(t, ty)
+		} else {
+			t[ty] = rest
+		}
+	})
+}
+
+func (s *subscriptions) update(fn func(map[reflect.Type][]*subscriber)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t := map[reflect.Type][]*subscriber{}
+	if old := s.table.Load(); old != nil {
+		for k, v := range *old {
+			t[k] = v
+		}
+	}
+	fn(t)
+	s.table.Store(&t)
+}
+
+// publish delivers events to their subscribers.
+func (s *subscriptions) publish(events []Event) {
+	t := s.table.Load()
+	if t == nil {
+		return
+	}
+	for _, evt := range events {
+		for _, sub := range (*t)[reflect.TypeOf(evt)] {
+			sub.send(evt)
+		}
+		for _, sub := range (*t)[nil] {
+			sub.send(evt)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package operator
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type otherEvent struct{}
+
+func (*otherEvent) EventName() string { return "otherEvent" }
+
+func TestSubscribe(t *testing.T) {
+	hub := newTestHub()
+	hub.Freeze()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	typed := hub.Subscribe(ctx, &testEvent{})
+	all := hub.Subscribe(ctx, nil)
+
+	_, err := Invoke(context.Background(), hub, func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) {
+		ctx.Emit(&testEvent{Val: 1})
+		ctx.Emit(&otherEvent{})
+		ctx.AfterFunc(func(*OpContext[*TxTest]) {
+			assert.Len(t, typed, 0, "events are published after AfterFuncs")
+		})
+		return in, nil
+	}, &struct{}{})
+	assert.Nil(t, err)
+
+	_, err = Invoke(context.Background(), hub, func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) {
+		ctx.Emit(&testEvent{Val: 2})
+		return nil, assert.AnError
+	}, &struct{}{})
+	assert.ErrorIs(t, err, assert.AnError)
+
+	assert.Equal(t, &testEvent{Val: 1}, <-typed)
+	assert.Equal(t, &testEvent{Val: 1}, <-all)
+	assert.Equal(t, &otherEvent{}, <-all)
+	assert.Len(t, typed, 0, "events of failed operations are not published")
+
+	cancel()
+	assert.Eventually(t, func() bool {
+		select {
+		case _, ok := <-typed:
+			return !ok
+		default:
+			return false
+		}
+	}, time.Second, time.Millisecond)
+	assert.False(t, hub.subs.active())
+}
+
+func TestSubscribe_Overflow(t *testing.T) {
+	hub := newTestHub()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var dropped []Event
+	drop := hub.Subscribe(ctx, &testEvent{}, SubscribeBuffer(1), OnDrop(func(evt Event) { dropped = append(dropped, evt) }))
+	block := hub.Subscribe(ctx, &testEvent{}, SubscribeBuffer(1), BlockWhenFull())
+
+	emit := func(v int) {
+		_, err := Invoke(context.Background(), hub, func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) {
+			return in, ctx.Emit(&testEvent{Val: v})
+		}, &struct{}{})
+		assert.Nil(t, err)
+	}
+
+	emit(1)
+	done := make(chan struct{})
+	go func() {
+		emit(2)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("operation did not block on full subscription")
+	case <-time.After(20 * time.Millisecond):
+	}
+	assert.Equal(t, &testEvent{Val: 1}, <-block)
+	<-done
+	assert.Equal(t, &testEvent{Val: 2}, <-block)
+
+	assert.Equal(t, &testEvent{Val: 1}, <-drop)
+	assert.Equal(t, []Event{&testEvent{Val: 2}}, dropped)
+}
@@ -0,0 +1,162 @@
+// Package temporalbind exposes hub operations as Temporal activities, so that
+// long-running workflows reuse the same operations, transaction lifecycle,
+// events and middleware as the API layer.
+//
+// Activity returns a function suitable for registration with a Temporal
+// worker. Progress reported by the operation with OpContext.ReportProgress()
+// is recorded as an activity heartbeat:
+//
+//	w.RegisterActivityWithOptions(
+//		temporalbind.Activity(hub, users.Import,
+//			temporalbind.WithHeartbeat(activity.RecordHeartbeat),
+//			temporalbind.WithHeartbeatInterval(10*time.Second)),
+//		activity.RegisterOptions{Name: "users.Import"})
+//
+// The package does not depend on the Temporal SDK; heartbeats are recorded by
+// the function passed to WithHeartbeat, and errors may be classified as
+// retryable or not with WithErrorMapper. Cancelling the activity cancels the
+// operation's context, rolling back its transaction.
+package temporalbind
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/jaz303/operator"
+)
+
+// HeartbeatFunc records an activity heartbeat carrying details. It has the
+// signature of the Temporal SDK's activity.RecordHeartbeat.
+type HeartbeatFunc func(ctx context.Context, details ...any)
+
+type config struct {
+	heartbeat HeartbeatFunc
+	interval  time.Duration
+	mapError  func(error) error
+}
+
+// Option configures an activity.
+type Option func(c *config)
+
+// WithHeartbeat records a heartbeat with fn, whose single detail is the
+// operator.Progress reported, each time the operation reports progress.
+// Without it, progress is discarded.
+func WithHeartbeat(fn HeartbeatFunc) Option {
+	return func(c *config) { c.heartbeat = fn }
+}
+
+// WithHeartbeatInterval additionally records a heartbeat every d while the
+// operation runs, repeating the most recently reported progress, so that
+// operations which report progress infrequently (or not at all) do not
+// exceed the activity's heartbeat timeout. It has no effect without
+// WithHeartbeat.
+func WithHeartbeatInterval(d time.Duration) Option {
+	return func(c *config) { c.interval = d }
+}
+
+// WithErrorMapper passes each error returned by the operation through fn
+// before returning it to Temporal; e.g. to wrap validation and authorization
+// failures with temporal.NewNonRetryableApplicationError so they are not
+// retried.
+func WithErrorMapper(fn func(error) error) Option {
+	return func(c *config) { c.mapError = fn }
+}
+
+// Activity returns an activity function that invokes op.
+func Activity[Tx operator.Transaction, I any, O any](hub *operator.Hub[Tx], op operator.Operation[Tx, I, O], opts ...Option) func(ctx context.Context, input *I) (*O, error) {
+	cfg := configure(opts)
+	return func(ctx context.Context, input *I) (*O, error) {
+		ctx, stop := cfg.start(ctx)
+		defer stop()
+		out, err := operator.Invoke(ctx, hub, op, input)
+		return out, cfg.wrap(err)
+	}
+}
+
+// ActivityTx is like Activity, for a TxOperation.
+func ActivityTx[Tx operator.Transaction, I any, O any](hub *operator.Hub[Tx], op operator.TxOperation[Tx, I, O], opts ...Option) func(ctx context.Context, input *I) (*O, error) {
+	cfg := configure(opts)
+	return func(ctx context.Context, input *I) (*O, error) {
+		ctx, stop := cfg.start(ctx)
+		defer stop()
+		out, err := operator.InvokeTx(ctx, hub, op, input)
+		return out, cfg.wrap(err)
+	}
+}
+
+func configure(opts []Option) *config {
+	cfg := &config{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+func (c *config) wrap(err error) error {
+	if err != nil && c.mapError != nil {
+		return c.mapError(err)
+	}
+	return err
+}
+
+// start returns a copy of ctx whose progress is recorded as heartbeats, and
+// a function that stops heartbeating.
+func (c *config) start(ctx context.Context) (context.Context, func()) {
+	if c.heartbeat == nil {
+		return ctx, func() {}
+	}
+
+	hb := &heartbeater{ctx: ctx, record: c.heartbeat}
+	ctx = operator.WithProgressSink(ctx, hb)
+	if c.interval <= 0 {
+		return ctx, func() {}
+	}
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		t := time.NewTicker(c.interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-t.C:
+				hb.repeat()
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ctx, func() {
+		close(done)
+		wg.Wait()
+	}
+}
+
+// heartbeater records progress as heartbeats against the activity's own
+// context, which carries the activity environment.
+type heartbeater struct {
+	ctx    context.Context
+	record HeartbeatFunc
+
+	mu   sync.Mutex
+	last operator.Progress
+}
+
+func (h *heartbeater) ReportProgress(_ context.Context, p operator.Progress) {
+	h.mu.Lock()
+	h.last = p
+	h.mu.Unlock()
+	h.record(h.ctx, p)
+}
+
+func (h *heartbeater) repeat() {
+	h.mu.Lock()
+	p := h.last
+	h.mu.Unlock()
+	h.record(h.ctx, p)
+}
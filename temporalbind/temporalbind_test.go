@@ -0,0 +1,106 @@
+package temporalbind
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jaz303/operator"
+	"github.com/stretchr/testify/assert"
+)
+
+type nopTx struct{ rolledBack *bool }
+
+func (nopTx) Commit(context.Context) error { return nil }
+func (tx nopTx) Rollback(context.Context) error {
+	if tx.rolledBack != nil {
+		*tx.rolledBack = true
+	}
+	return nil
+}
+
+type ctxKey struct{}
+
+type importInput struct{ Rows int }
+type importOutput struct{ Imported int }
+
+func importRows(ctx *operator.OpContext[nopTx], tx nopTx, in *importInput) (*importOutput, error) {
+	for i := 1; i <= in.Rows; i++ {
+		ctx.ReportProgress(int64(i), int64(in.Rows), "")
+	}
+	return &importOutput{Imported: in.Rows}, nil
+}
+
+func TestActivity_HeartbeatsProgress(t *testing.T) {
+	hub := operator.NewHub(func(context.Context) (nopTx, error) { return nopTx{}, nil })
+
+	var beats []any
+	heartbeat := func(ctx context.Context, details ...any) {
+		assert.Equal(t, "activity", ctx.Value(ctxKey{}), "heartbeat receives the activity context")
+		beats = append(beats, details...)
+	}
+
+	act := ActivityTx(hub, importRows, WithHeartbeat(heartbeat))
+	out, err := act(context.WithValue(context.Background(), ctxKey{}, "activity"), &importInput{Rows: 2})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, out.Imported)
+	assert.Equal(t, []any{
+		operator.Progress{Done: 1, Total: 2},
+		operator.Progress{Done: 2, Total: 2},
+	}, beats)
+}
+
+func TestActivity_HeartbeatInterval(t *testing.T) {
+	hub := operator.NewHub(func(context.Context) (nopTx, error) { return nopTx{}, nil })
+
+	var mu sync.Mutex
+	var beats []operator.Progress
+	heartbeat := func(ctx context.Context, details ...any) {
+		mu.Lock()
+		beats = append(beats, details[0].(operator.Progress))
+		mu.Unlock()
+	}
+
+	act := Activity(hub, func(ctx *operator.OpContext[nopTx], in *struct{}) (*struct{}, error) {
+		ctx.ReportProgress(1, 0, "started")
+		time.Sleep(50 * time.Millisecond)
+		return in, nil
+	}, WithHeartbeat(heartbeat), WithHeartbeatInterval(5*time.Millisecond))
+
+	_, err := act(context.Background(), &struct{}{})
+	assert.Nil(t, err)
+
+	mu.Lock()
+	n := len(beats)
+	assert.Greater(t, n, 2)
+	for _, b := range beats {
+		assert.Equal(t, operator.Progress{Done: 1, Message: "started"}, b)
+	}
+	mu.Unlock()
+
+	time.Sleep(20 * time.Millisecond)
+	mu.Lock()
+	assert.Equal(t, n, len(beats), "heartbeats stop when the activity returns")
+	mu.Unlock()
+}
+
+func TestActivity_ErrorMapperAndRollback(t *testing.T) {
+	var rolledBack bool
+	hub := operator.NewHub(func(context.Context) (nopTx, error) { return nopTx{rolledBack: &rolledBack}, nil })
+
+	errFailed := errors.New("failed")
+	errNonRetryable := errors.New("non-retryable")
+
+	act := ActivityTx(hub, func(ctx *operator.OpContext[nopTx], tx nopTx, in *struct{}) (*struct{}, error) {
+		return nil, errFailed
+	}, WithErrorMapper(func(err error) error {
+		return errors.Join(errNonRetryable, err)
+	}))
+
+	_, err := act(context.Background(), &struct{}{})
+	assert.ErrorIs(t, err, errFailed)
+	assert.ErrorIs(t, err, errNonRetryable)
+	assert.True(t, rolledBack)
+}
@@ -0,0 +1,74 @@
+// Package tenancy provides resolvers that identify the tenant an inbound HTTP
+// request belongs to. Bindings accept a Resolver via WithTenantResolver; the
+// resolved tenant is available to operations via OpContext.Tenant(), and
+// selects the tenant's transaction provider when the hub is configured with
+// operator.PerTenant.
+package tenancy
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// ErrUnresolved is returned by resolvers that cannot determine a tenant.
+var ErrUnresolved = errors.New("tenant could not be resolved")
+
+// Resolver identifies the tenant to which r belongs.
+type Resolver func(r *http.Request) (string, error)
+
+// FromHeader returns a Resolver that reads the tenant from the named request
+// header.
+func FromHeader(name string) Resolver {
+	return func(r *http.Request) (string, error) {
+		if t := r.Header.Get(name); t != "" {
+			return t, nil
+		}
+		return "", ErrUnresolved
+	}
+}
+
+// FromSubdomain returns a Resolver that takes the tenant from the leftmost
+// label of the request's host when it is a subdomain of baseDomain, e.g. with
+// baseDomain "example.com" a request to acme.example.com resolves to "acme".
+func FromSubdomain(baseDomain string) Resolver {
+	suffix := "." + strings.TrimPrefix(strings.ToLower(baseDomain), ".")
+	return func(r *http.Request) (string, error) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		sub, ok := strings.CutSuffix(strings.ToLower(host), suffix)
+		if !ok || sub == "" || strings.Contains(sub, ".") {
+			return "", ErrUnresolved
+		}
+		return sub, nil
+	}
+}
+
+// FromPathValue returns a Resolver that reads the tenant from the named path
+// parameter, as matched by http.ServeMux.
+func FromPathValue(name string) Resolver {
+	return func(r *http.Request) (string, error) {
+		if t := r.PathValue(name); t != "" {
+			return t, nil
+		}
+		return "", ErrUnresolved
+	}
+}
+
+// First returns a Resolver that tries each of resolvers in turn, returning
+// the first tenant resolved.
+func First(resolvers ...Resolver) Resolver {
+	return func(r *http.Request) (string, error) {
+		for _, res := range resolvers {
+			if t, err := res(r); err == nil {
+				return t, nil
+			} else if !errors.Is(err, ErrUnresolved) {
+				return "", err
+			}
+		}
+		return "", ErrUnresolved
+	}
+}
@@ -0,0 +1,35 @@
+package tenancy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFromSubdomain(t *testing.T) {
+	res := FromSubdomain("example.com")
+
+	r := httptest.NewRequest(http.MethodGet, "http://acme.example.com:8080/", nil)
+	tenant, err := res(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "acme", tenant)
+
+	for _, host := range []string{"example.com", "a.b.example.com", "acme.example.org"} {
+		_, err := res(httptest.NewRequest(http.MethodGet, "http://"+host+"/", nil))
+		assert.ErrorIs(t, err, ErrUnresolved, host)
+	}
+}
+
+func TestFirst(t *testing.T) {
+	res := First(FromHeader("X-Tenant"), FromSubdomain("example.com"))
+
+	r := httptest.NewRequest(http.MethodGet, "http://acme.example.com/", nil)
+	tenant, _ := res(r)
+	assert.Equal(t, "acme", tenant)
+
+	r.Header.Set("X-Tenant", "globex")
+	tenant, _ = res(r)
+	assert.Equal(t, "globex", tenant)
+}
@@ -0,0 +1,79 @@
+package operator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrNoTenant is returned by a PerTenant transaction provider when the
+// operation was not invoked on behalf of a tenant.
+var ErrNoTenant = errors.New("no tenant")
+
+type tenantKey struct{}
+
+// WithTenant returns a copy of ctx carrying the tenant identifier. Bindings
+// use this to scope the operation they invoke to the tenant resolved from the
+// inbound request.
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// TenantFrom returns the tenant carried by ctx, if any.
+func TenantFrom(ctx context.Context) (string, bool) {
+	t, ok := ctx.Value(tenantKey{}).(string)
+	return t, ok
+}
+
+// Tenant returns the tenant on whose behalf the operation was invoked, or ""
+// if the operation is not tenant-scoped.
+func (o *OpContext[T]) Tenant() string {
+	t, _ := TenantFrom(o)
+	return t
+}
+
+// PerTenant returns a TransactionProvider that begins each transaction using
+// the provider for the operation's tenant, allowing each tenant's operations
+// to run against its own database or schema:
+//
+//	hub := operator.NewHub(operator.PerTenant(func(tenant string) (operator.TransactionProvider[*sql.Tx], error) {
+//		db, err := shards.Open(tenant)
+//		...
+//	}))
+//
+// fn is called once per tenant, the first time that tenant begins a
+// transaction; the resulting provider is cached. Failed lookups are not
+// cached. Operations invoked without a tenant fail with ErrNoTenant when they
+// begin a transaction.
+func PerTenant[Tx Transaction](fn func(tenant string) (TransactionProvider[Tx], error)) TransactionProvider[Tx] {
+	var mu sync.Mutex
+	providers := map[string]TransactionProvider[Tx]{}
+
+	lookup := func(tenant string) (TransactionProvider[Tx], error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if p, ok := providers[tenant]; ok {
+			return p, nil
+		}
+		p, err := fn(tenant)
+		if err != nil {
+			return nil, fmt.Errorf("tenant %s: %w", tenant, err)
+		}
+		providers[tenant] = p
+		return p, nil
+	}
+
+	return func(ctx context.Context) (Tx, error) {
+		var zero Tx
+		tenant, ok := TenantFrom(ctx)
+		if !ok || tenant == "" {
+			return zero, ErrNoTenant
+		}
+		p, err := lookup(tenant)
+		if err != nil {
+			return zero, err
+		}
+		return p(ctx)
+	}
+}
@@ -0,0 +1,45 @@
+package operator
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type tenantTx struct {
+	TxTest
+	tenant string
+}
+
+func TestPerTenant(t *testing.T) {
+	var lookups []string
+	hub := NewHub(PerTenant(func(tenant string) (TransactionProvider[*tenantTx], error) {
+		lookups = append(lookups, tenant)
+		if tenant == "unknown" {
+			return nil, errors.New("no such shard")
+		}
+		return func(ctx context.Context) (*tenantTx, error) {
+			return &tenantTx{tenant: tenant}, nil
+		}, nil
+	}))
+
+	op := func(ctx *OpContext[*tenantTx], tx *tenantTx, in *struct{}) (*string, error) {
+		assert.Equal(t, ctx.Tenant(), tx.tenant)
+		return &tx.tenant, nil
+	}
+
+	for _, tenant := range []string{"acme", "globex", "acme"} {
+		out, err := InvokeTx(WithTenant(context.Background(), tenant), hub, op, &struct{}{})
+		assert.Nil(t, err)
+		assert.Equal(t, tenant, *out)
+	}
+	assert.Equal(t, []string{"acme", "globex"}, lookups)
+
+	_, err := InvokeTx(context.Background(), hub, op, &struct{}{})
+	assert.ErrorIs(t, err, ErrNoTenant)
+
+	_, err = InvokeTx(WithTenant(context.Background(), "unknown"), hub, op, &struct{}{})
+	assert.ErrorContains(t, err, "no such shard")
+}
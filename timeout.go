@@ -0,0 +1,190 @@
+package operator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrTimeout is returned by operations that exceed their deadline; see
+// WithTimeout().
+var ErrTimeout = errors.New("operation timed out")
+
+// WithTimeout() returns an operation that runs op with a deadline of d. If
+// op has not returned by the deadline, its transaction is rolled back - which,
+// for most database drivers, interrupts a query in progress - and the
+// operation fails with an error wrapping ErrTimeout and
+// context.DeadlineExceeded, without waiting for op to return.
+//
+// To make this safe, op runs on a separate goroutine with its own view of the
+// operation: the events, AfterFuncs, follow-ups, locks and values it records
+// are adopted by the enclosing operation only if op returns in time, and are
+// discarded otherwise. op holds the locks already held by the enclosing
+// operation, and may call Flush() and Checkpoint() if it was invoked with
+// InvokeChunkedTx() or InvokeResumable(); once the deadline has passed they
+// fail with ErrTimeout, so that an abandoned op can not commit. The
+// transaction is shared, so the Transaction's Rollback method must be safe
+// to call while op is using it; if op begins a transaction after the
+// deadline has passed, it is rolled back immediately.
+// An abandoned op continues until it next checks its context or uses its
+// transaction, so it should do both.
+func WithTimeout[Tx Transaction, I any, O any](op Operation[Tx, I, O], d time.Duration) Operation[Tx, I, O] {
+	return wrapOperation("WithTimeout", []any{op}, []any{d}, func(ctx *OpContext[Tx], input *I) (*O, error) {
+		if ctx.state != stateActive {
+			return nil, ErrInvalidState
+		}
+
+		runCtx, cancel := context.WithTimeout(ctx.Context, d)
+		defer cancel()
+
+		t := &timeoutRun[Tx]{parent: ctx, inherited: ctx.isTransactionActive()}
+		child := ctx.child(runCtx, t.begin)
+		child.guard = t
+		held := len(child.locks)
+
+		type result struct {
+			out *O
+			err error
+		}
+		done := make(chan result, 1)
+		go func() {
+			out, err := invokeWithRecover(op, child, input)
+			if t.finish() {
+				// release only the locks op acquired; those it inherited
+				// are still held by the enclosing operation
+				child.unlock(held)
+			}
+			done <- result{out, err}
+		}()
+
+		select {
+		case res := <-done:
+			ctx.adopt(child)
+			return res.out, res.err
+		case <-runCtx.Done():
+		}
+
+		tx, own, inherited, ok := t.abandon()
+		if !ok {
+			// op returned as the deadline passed
+			res := <-done
+			ctx.adopt(child)
+			return res.out, res.err
+		}
+		if own {
+			_ = tx.Rollback(context.WithoutCancel(ctx))
+		}
+		if inherited {
+			_ = ctx.endTx(false, ctx.activeTx.Rollback, context.WithoutCancel(ctx))
+		} else if ctx.flight != nil {
+			// op committed the transaction as a chunk
+			ctx.flight.txOpen.Store(false)
+		}
+		var zero Tx
+		ctx.activeTx, ctx.txActive, ctx.txBegan = zero, false, time.Time{}
+
+		if err := ctx.Context.Err(); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %w", ErrTimeout, runCtx.Err())
+	})
+}
+
+// timeoutRun arbitrates ownership of a transaction begun by an operation run
+// by WithTimeout(), between the operation's goroutine and the enclosing
+// operation.
+type timeoutRun[Tx Transaction] struct {
+	parent *OpContext[Tx]
+
+	mu    sync.Mutex
+	tx    Tx
+	began bool
+
+	// true until op commits the enclosing operation's transaction, which
+	// was active when op was invoked
+	inherited bool
+
+	finished bool
+
+	// true once the enclosing operation has given up on op
+	gaveUp bool
+}
+
+// commitGuard is consulted by OpContext.commitChunk() when an operation run
+// by WithTimeout() calls Flush() or Checkpoint(), so that it does not commit
+// once the enclosing operation has given up on it, and the enclosing
+// operation does not roll back what it has committed.
+type commitGuard interface {
+	// abandoned reports whether the enclosing operation has given up on
+	// the operation.
+	abandoned() bool
+
+	// commit runs commitTx, unless the operation has been abandoned, in
+	// which case it returns ErrTimeout.
+	commit(commitTx func() error) error
+}
+
+func (t *timeoutRun[Tx]) begin(ctx context.Context) (Tx, error) {
+	tx, err := t.parent.beginTransaction(ctx)
+	if err != nil {
+		return tx, err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.gaveUp {
+		_ = tx.Rollback(context.WithoutCancel(ctx))
+		var zero Tx
+		return zero, ErrTimeout
+	}
+	t.tx, t.began = tx, true
+	return tx, nil
+}
+
+// finish records that the operation has returned, reporting whether it was
+// abandoned.
+func (t *timeoutRun[Tx]) finish() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.finished = true
+	return t.gaveUp
+}
+
+func (t *timeoutRun[Tx]) abandoned() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.gaveUp
+}
+
+// commit runs commitTx unless the operation has been abandoned, holding off
+// abandonment until it returns. Once committed, neither the transaction op
+// began nor the one it inherited is rolled back if op is later abandoned.
+func (t *timeoutRun[Tx]) commit(commitTx func() error) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.gaveUp {
+		return ErrTimeout
+	}
+	if err := commitTx(); err != nil {
+		return err
+	}
+	var zero Tx
+	t.tx, t.began, t.inherited = zero, false, false
+	return nil
+}
+
+// abandon records that the enclosing operation has given up on the
+// operation, returning the uncommitted transaction it began, if any, and
+// whether the enclosing operation's transaction is still uncommitted.
+// Returns false, and does not abandon the operation, if it has already
+// returned.
+func (t *timeoutRun[Tx]) abandon() (tx Tx, began bool, inherited bool, ok bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.finished {
+		return tx, false, false, false
+	}
+	t.gaveUp = true
+	return t.tx, t.began, t.inherited, true
+}
@@ -0,0 +1,290 @@
+package operator
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithTimeout_Exceeded(t *testing.T) {
+	hub := newTestHub()
+	var dispatched bool
+	hub.RegisterEventHandler(&testEvent{}, func(evt *testEvent) error {
+		dispatched = true
+		return nil
+	})
+
+	var tx *TxTest
+	release, returned := make(chan struct{}), make(chan struct{})
+	slow := WithTimeout(func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) {
+		defer close(returned)
+		tx, _ = ctx.Tx()
+		ctx.Emit(&testEvent{})
+		<-release
+		return in, nil
+	}, 10*time.Millisecond)
+
+	start := time.Now()
+	_, err := Invoke(context.Background(), hub, slow, &struct{}{})
+	assert.ErrorIs(t, err, ErrTimeout)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), time.Second)
+
+	close(release)
+	<-returned
+	assert.True(t, tx.RolledBack)
+	assert.False(t, tx.Committed)
+	assert.False(t, dispatched)
+}
+
+func TestWithTimeout_Completed(t *testing.T) {
+	hub := newTestHub()
+	var dispatched bool
+	hub.RegisterEventHandler(&testEvent{}, func(evt *testEvent) error {
+		dispatched = true
+		return nil
+	})
+
+	var tx *TxTest
+	var after, deadline bool
+	op := WithTimeout(func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) {
+		tx, _ = ctx.Tx()
+		_, deadline = ctx.Deadline()
+		ctx.Emit(&testEvent{})
+		ctx.AfterFunc(func(*OpContext[*TxTest]) { after = true })
+		Provide(ctx, 42)
+		return in, nil
+	}, time.Second)
+
+	_, err := Invoke(context.Background(), hub, Compose(op, func(in *struct{}) (*struct{}, error) { return in, nil },
+		func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) {
+			v, err := Lookup[int](ctx)
+			assert.Equal(t, 42, v)
+			return in, err
+		}), &struct{}{})
+	assert.Nil(t, err)
+	assert.True(t, deadline)
+	assert.True(t, tx.Committed)
+	assert.True(t, dispatched)
+	assert.True(t, after)
+}
+
+func TestWithTimeout_SharedTransaction(t *testing.T) {
+	hub := newTestHub()
+
+	release := make(chan struct{})
+	defer close(release)
+	slow := WithTimeout(func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) {
+		<-release
+		return in, nil
+	}, 10*time.Millisecond)
+
+	var tx *TxTest
+	_, err := InvokeTx(context.Background(), hub, func(ctx *OpContext[*TxTest], t *TxTest, in *struct{}) (*struct{}, error) {
+		tx = t
+		return slow(ctx, in)
+	}, &struct{}{})
+	assert.ErrorIs(t, err, ErrTimeout)
+	assert.True(t, tx.RolledBack)
+	assert.False(t, tx.Committed)
+}
+
+func TestWithTimeout_Cancelled(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	slow := WithTimeout(func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) {
+		<-release
+		return in, nil
+	}, time.Second)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	_, err := Invoke(ctx, newTestHub(), slow, &struct{}{})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.NotErrorIs(t, err, ErrTimeout)
+}
+
+func TestWithTimeout_LocksAndChunks(t *testing.T) {
+	var txs []*TxTest
+	hub := NewHub(func(ctx context.Context) (*TxTest, error) {
+		tx := &TxTest{}
+		txs = append(txs, tx)
+		return tx, nil
+	})
+	assert.NoError(t, hub.SetLockProvider(NewLocalLocks[*TxTest]()))
+
+	inner := WithTimeout(func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) {
+		// already held by the enclosing operation
+		if err := ctx.Lock("a"); err != nil {
+			return nil, err
+		}
+		for range 3 {
+			if _, err := ctx.Flush(); err != nil {
+				return nil, err
+			}
+		}
+		return in, ctx.Lock("b")
+	}, time.Second)
+
+	var held []string
+	_, err := InvokeChunkedTx(context.Background(), hub, func(ctx *OpContext[*TxTest], tx *TxTest, in *struct{}) (*struct{}, error) {
+		if err := ctx.Lock("a"); err != nil {
+			return nil, err
+		}
+		out, err := inner(ctx, in)
+		for _, l := range ctx.locks {
+			held = append(held, l.key)
+		}
+		return out, err
+	}, 2, &struct{}{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, held)
+	if assert.Len(t, txs, 2, "Flush() commits chunks within WithTimeout()") {
+		assert.True(t, txs[0].Committed)
+		assert.True(t, txs[1].Committed)
+	}
+}
+
+func TestWithTimeout_EventDedup(t *testing.T) {
+	hub := newTestHub(WithEventDedup(&testEvent{}))
+	var dispatched int
+	assert.NoError(t, hub.RegisterEventHandler(&testEvent{}, func(evt *testEvent) { dispatched++ }))
+
+	inner := WithTimeout(func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) {
+		ctx.Emit(&testEvent{})
+		return in, ctx.Emit(&testEvent{})
+	}, time.Second)
+	_, err := Invoke(context.Background(), hub, func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) {
+		ctx.Emit(&testEvent{})
+		return inner(ctx, in)
+	}, &struct{}{})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, dispatched)
+}
+
+func TestWithTimeout_CascadeLimits(t *testing.T) {
+	hub := newTestHub(WithCascadeLimits(CascadeLimits{MaxEvents: 3}))
+
+	emitTwo := WithTimeout(func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) {
+		for range 2 {
+			if err := ctx.Emit(&testEvent{}); err != nil {
+				return nil, err
+			}
+		}
+		return in, nil
+	}, time.Second)
+
+	var innerErr error
+	_, err := Invoke(context.Background(), hub, func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) {
+		ctx.Emit(&testEvent{})
+		// the limit counts events emitted before WithTimeout()
+		_, innerErr = emitTwo(ctx, in)
+		// and events emitted within it
+		ctx.Emit(&testEvent{})
+		return in, nil
+	}, &struct{}{})
+	assert.NoError(t, innerErr)
+	assert.ErrorIs(t, err, ErrCascadeLimit)
+	assert.ErrorContains(t, err, "more than 3 events emitted")
+}
+
+func TestWithTimeout_CheckpointAfterDeadline(t *testing.T) {
+	var txs []*TxTest
+	hub := NewHub(func(ctx context.Context) (*TxTest, error) {
+		tx := &TxTest{}
+		txs = append(txs, tx)
+		return tx, nil
+	}, WithCheckpointStore(NewMemoryCheckpointStore()))
+
+	var after bool
+	var checkpointErr error
+	release, returned := make(chan struct{}), make(chan struct{})
+	slow := WithTimeout(func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) {
+		defer close(returned)
+		if _, err := ctx.Tx(); err != nil {
+			return nil, err
+		}
+		ctx.AfterFunc(func(*OpContext[*TxTest]) { after = true })
+		<-release
+		checkpointErr = ctx.Checkpoint(1)
+		return in, checkpointErr
+	}, 10*time.Millisecond)
+
+	_, err := InvokeResumable(context.Background(), hub, slow, "k", &struct{}{})
+	assert.ErrorIs(t, err, ErrTimeout)
+
+	close(release)
+	<-returned
+	assert.ErrorIs(t, checkpointErr, ErrTimeout)
+	assert.False(t, after, "AfterFuncs do not run behind the enclosing operation's back")
+	if assert.Len(t, txs, 1) {
+		assert.True(t, txs[0].RolledBack)
+		assert.False(t, txs[0].Committed)
+	}
+}
+
+func TestWithTimeout_FlushAfterDeadline(t *testing.T) {
+	var txs []*TxTest
+	hub := NewHub(func(ctx context.Context) (*TxTest, error) {
+		tx := &TxTest{}
+		txs = append(txs, tx)
+		return tx, nil
+	})
+
+	var flushErr error
+	release, returned := make(chan struct{}), make(chan struct{})
+	slow := WithTimeout(func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) {
+		defer close(returned)
+		<-release
+		_, flushErr = ctx.Flush()
+		return in, flushErr
+	}, 10*time.Millisecond)
+
+	_, err := InvokeChunkedTx(context.Background(), hub, func(ctx *OpContext[*TxTest], tx *TxTest, in *struct{}) (*struct{}, error) {
+		return slow(ctx, in)
+	}, 1, &struct{}{})
+	assert.ErrorIs(t, err, ErrTimeout)
+
+	close(release)
+	<-returned
+	assert.ErrorIs(t, flushErr, ErrTimeout)
+	if assert.Len(t, txs, 1) {
+		assert.True(t, txs[0].RolledBack)
+		assert.False(t, txs[0].Committed)
+	}
+}
+
+func TestWithTimeout_ChunkCommittedBeforeDeadline(t *testing.T) {
+	var txs []*TxTest
+	hub := NewHub(func(ctx context.Context) (*TxTest, error) {
+		tx := &TxTest{}
+		txs = append(txs, tx)
+		return tx, nil
+	})
+
+	release, returned := make(chan struct{}), make(chan struct{})
+	slow := WithTimeout(func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) {
+		defer close(returned)
+		if _, err := ctx.Flush(); err != nil {
+			return nil, err
+		}
+		<-release
+		return in, nil
+	}, 10*time.Millisecond)
+
+	_, err := InvokeChunkedTx(context.Background(), hub, func(ctx *OpContext[*TxTest], tx *TxTest, in *struct{}) (*struct{}, error) {
+		return slow(ctx, in)
+	}, 1, &struct{}{})
+	assert.ErrorIs(t, err, ErrTimeout)
+
+	close(release)
+	<-returned
+	if assert.Len(t, txs, 2) {
+		assert.True(t, txs[0].Committed)
+		assert.False(t, txs[0].RolledBack, "the committed chunk is not rolled back")
+		assert.True(t, txs[1].RolledBack)
+		assert.False(t, txs[1].Committed)
+	}
+}
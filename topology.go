@@ -0,0 +1,174 @@
+package operator
+
+import "sort"
+
+// EventInfo describes an event type registered with a hub, and the handlers
+// that will receive it.
+type EventInfo struct {
+	// Event name, as returned by EventName()
+	Name string `json:"name"`
+
+	// Go type of the event; empty for handlers registered by event name
+	Type string `json:"type"`
+
+	// Registered handlers, in dispatch order
+	Handlers []HandlerInfo `json:"handlers"`
+}
+
+// HandlerInfo describes a registered event handler.
+type HandlerInfo struct {
+	// Fully-qualified name of the handler function, or the name given by
+	// HandlerName(); empty for subscriptions
+	Name string `json:"name"`
+
+	// File and line at which the handler was registered
+	Site string `json:"site"`
+
+	// Ordering constraints; see Priority(), After() and Before()
+	Priority int      `json:"priority"`
+	After    []string `json:"after,omitempty"`
+	Before   []string `json:"before,omitempty"`
+
+	// Operation name patterns; see ForOps() and ExceptOps()
+	Ops       []string `json:"ops,omitempty"`
+	ExceptOps []string `json:"except_ops,omitempty"`
+
+	// True if the handler is dispatched by a dispatcher registered with
+	// RegisterDispatcher(), rather than with reflection
+	Static bool `json:"static"`
+
+	// True for handlers registered with RegisterBatchEventHandler()
+	Batch bool `json:"batch"`
+
+	// Phase in which the handler receives events; see InPhase()
+	Phase DispatchPhase `json:"phase"`
+
+	// True for subscriptions made with Subscribe(), which receive events
+	// once the emitting operation has committed. Event handlers are invoked
+	// in their Phase.
+	Async bool `json:"async"`
+
+	// Where the handler sends events, such as a message broker subject or
+	// topic; see Destination()
+	Destination string `json:"destination,omitempty"`
+}
+
+// EventHandlerInfo describes an event handler, or subscription, and the
+// events it receives.
+type EventHandlerInfo struct {
+	// Event name, as returned by EventName(); empty for subscriptions to
+	// every event
+	Event string `json:"event"`
+
+	// Go type of the event; empty for handlers registered by event name, and
+	// subscriptions to every event
+	Type string `json:"type"`
+
+	HandlerInfo
+}
+
+// EventTopology() returns a description of every event type and event name
+// registered with the hub, ordered by event name, for use by diagnostics and documentation
+// tooling.
+func (h *Hub[Tx]) EventTopology() []EventInfo {
+	table := h.events.load()
+	out := make([]EventInfo, 0, len(table.handlers))
+	for key, regs := range table.regs {
+		info := EventInfo{
+			Name:     key.name,
+			Handlers: make([]HandlerInfo, 0, len(regs)),
+		}
+		if key.ty != nil {
+			info.Name = table.names[key.ty]
+			info.Type = key.ty.String()
+		}
+		ordered, _ := orderRegistrations(regs)
+		for _, r := range ordered {
+			info.Handlers = append(info.Handlers, r.info())
+		}
+		out = append(out, info)
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Name != out[j].Name {
+			return out[i].Name < out[j].Name
+		}
+		return out[i].Type < out[j].Type
+	})
+
+	return out
+}
+
+// EventHandlers() returns a description of every event handler and
+// subscription registered with the hub, for use by admin pages and
+// documentation describing what happens when an event is emitted. The result
+// is ordered by event name; the entries for each name are in the order in
+// which an event is delivered to them: handlers registered for event types,
+// in dispatch order, then those registered by name, then subscriptions.
+// Subscriptions to every event come last.
+func (h *Hub[Tx]) EventHandlers() []EventHandlerInfo {
+	type entry struct {
+		EventHandlerInfo
+		rank int
+	}
+	var entries []entry
+	for _, evt := range h.EventTopology() {
+		rank := 0
+		if evt.Type == "" {
+			rank = 1
+		}
+		for _, hnd := range evt.Handlers {
+			entries = append(entries, entry{EventHandlerInfo{Event: evt.Name, Type: evt.Type, HandlerInfo: hnd}, rank})
+		}
+	}
+	if t := h.subs.table.Load(); t != nil {
+		for ty, subs := range *t {
+			for _, s := range subs {
+				e := entry{EventHandlerInfo{Event: s.name, HandlerInfo: HandlerInfo{Site: s.site, Async: true}}, 2}
+				if ty != nil {
+					e.Type = ty.String()
+				} else {
+					e.rank = 3
+				}
+				entries = append(entries, e)
+			}
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if (a.rank == 3) != (b.rank == 3) {
+			return b.rank == 3
+		}
+		if a.Event != b.Event {
+			return a.Event < b.Event
+		}
+		if a.rank != b.rank {
+			return a.rank < b.rank
+		}
+		return a.Type < b.Type
+	})
+
+	out := make([]EventHandlerInfo, len(entries))
+	for i, e := range entries {
+		out[i] = e.EventHandlerInfo
+	}
+	return out
+}
+
+func (r *orderedHandler[Tx]) info() HandlerInfo {
+	return HandlerInfo{
+		Name:      r.name,
+		Site:      r.site,
+		Priority:  r.priority,
+		After:     r.after,
+		Before:    r.before,
+		Ops:       r.ops,
+		ExceptOps: r.exceptOps,
+		Static:    r.static,
+		Batch:     r.batch,
+		Phase:     r.phase,
+
+		Destination: r.destination,
+	}
+}
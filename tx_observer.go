@@ -0,0 +1,43 @@
+package operator
+
+import (
+	"context"
+	"time"
+)
+
+// TransactionObserver is notified as each operation's transaction begins,
+// commits and rolls back, so that a database adapter, or the application,
+// can log slow commits, record metrics and trace transactions in the same
+// way whichever driver provides the hub's transactions. Transactions are
+// begun lazily, so operations that never call OpContext.Tx() are not
+// observed.
+//
+// ctx is the operation's context. Observers are called synchronously, by
+// the goroutine running the operation, so must be quick; one that needs to
+// relate the calls for a transaction, to end a span say, can key them by
+// info.ID().
+type TransactionObserver interface {
+	// OnBegin() is called once the transaction provider has returned, with
+	// the time it took to begin the transaction and its error, if any.
+	OnBegin(ctx context.Context, info OperationInfo, d time.Duration, err error)
+
+	// OnCommit() is called once the transaction has been committed, with the
+	// commit's error, if any.
+	OnCommit(ctx context.Context, info OperationInfo, stats TxStats, err error)
+
+	// OnRollback() is called once the transaction has been rolled back, with
+	// the rollback's error, if any.
+	OnRollback(ctx context.Context, info OperationInfo, stats TxStats, err error)
+}
+
+// WithTransactionObserver adds an observer of every operation's
+// transaction. Observers are called in the order in which they were added.
+func WithTransactionObserver(obs TransactionObserver) HubOption {
+	return func(o *hubOptions) { o.txObservers = append(o.txObservers, obs) }
+}
+
+func (h *Hub[Tx]) observeBegin(op *OpContext[Tx], d time.Duration, err error) {
+	for _, obs := range h.opts.txObservers {
+		obs.OnBegin(op, op, d, err)
+	}
+}
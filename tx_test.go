@@ -2,7 +2,16 @@ package operator
 
 import "context"
 
-type TxTest struct{}
+type TxTest struct {
+	Committed  bool
+	RolledBack bool
+}
 
-func (t *TxTest) Commit(ctx context.Context) error   { return nil }
-func (t *TxTest) Rollback(ctx context.Context) error { return nil }
+func (t *TxTest) Commit(ctx context.Context) error   { t.Committed = true; return nil }
+func (t *TxTest) Rollback(ctx context.Context) error { t.RolledBack = true; return nil }
+
+func newTestHub(opts ...HubOption) *Hub[*TxTest] {
+	return NewHub(func(ctx context.Context) (*TxTest, error) {
+		return &TxTest{}, nil
+	}, opts...)
+}
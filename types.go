@@ -8,6 +8,7 @@ import (
 var (
 	ErrInvalidState               = errors.New("invalid state")
 	ErrEventHandlerCoercionFailed = errors.New("failed to create event handler")
+	ErrHubFrozen                  = errors.New("hub is frozen")
 )
 
 // Operation represents a single operation with defined input/output parameters.
@@ -17,15 +18,30 @@ type Operation[Tx Transaction, I any, O any] func(ctx *OpContext[Tx], input *I)
 // Use TxOperation to reduce boilerplate if your operation is guaranteed to start a transaction.
 type TxOperation[Tx Transaction, I any, O any] func(ctx *OpContext[Tx], tx Tx, input *I) (*O, error)
 
+// Empty is the output type for operations that produce no meaningful output.
+// HTTP bindings respond to operations returning *Empty (or a nil output) with
+// 204 No Content.
+type Empty struct{}
+
 // AfterFunc is a function that runs after an operation has successfully completed.
 type AfterFunc[Tx Transaction] func(*OpContext[Tx])
 
-// TransactionProvider is a transaction factory
+// TransactionProvider is a transaction factory. It is called with the
+// context of the operation beginning the transaction, which carries the
+// values of the context passed to Invoke() - the tenant and principal, say -
+// and the operation itself; see OperationFrom(). Providers can use them to
+// configure the transaction, e.g. setting application_name or row-level
+// security variables.
 type TransactionProvider[Tx Transaction] func(context.Context) (Tx, error)
 
+// Transaction is a unit of work begun by a TransactionProvider. Any type
+// with these methods will do, including interfaces such as pgx.Tx and
+// non-pointer structs; the hub tracks whether an operation has begun its
+// transaction separately, so never compares transactions.
+//
+// Commit() and Rollback() are likewise called with the operation's context,
+// or, if it is done, one that is never cancelled but carries the same values.
 type Transaction interface {
-	comparable
-
 	Commit(context.Context) error
 	Rollback(context.Context) error
 }
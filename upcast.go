@@ -0,0 +1,143 @@
+package operator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+var (
+	ErrUnknownEvent       = errors.New("unknown event")
+	ErrNoUpcaster         = errors.New("no upcaster registered")
+	ErrUpcasterRegistered = errors.New("upcaster already registered")
+	ErrFutureEventVersion = errors.New("event version is newer than its type")
+)
+
+// Versioned may be implemented by events whose shape changes over time.
+// Events that do not implement Versioned are at version 1.
+type Versioned interface {
+	EventVersion() int
+}
+
+// EventVersion() returns evt's version.
+func EventVersion(evt Event) int {
+	if v, ok := evt.(Versioned); ok {
+		return v.EventVersion()
+	}
+	return 1
+}
+
+// Upcaster migrates the serialized form of an event from one version to the
+// next, e.g. by renaming or restructuring JSON fields.
+type Upcaster func(data []byte) ([]byte, error)
+
+type upcasterKey struct {
+	name string
+	from int
+}
+
+// RegisterEventType() registers event's type with the hub, so that
+// serialized events with the same name can be decoded by DecodeEvent(),
+// without registering a handler. Types with registered handlers need not be
+// registered separately.
+//
+// Returns ErrHubFrozen if the hub has been frozen.
+func (h *Hub[Tx]) RegisterEventType(event Event) error {
+	if h.frozen.Load() {
+		return ErrHubFrozen
+	}
+	h.events.addType(reflect.TypeOf(event), event.EventName())
+	return nil
+}
+
+// EventType() returns the Go type registered for events named name, either
+// with RegisterEventType() or by registering a handler.
+func (h *Hub[Tx]) EventType(name string) (reflect.Type, bool) {
+	return h.events.typeNamed(name)
+}
+
+// RegisterUpcaster() registers fn to migrate serialized events named name
+// from version from to version from+1. Events that have been serialized -
+// by an outbox, a message publisher, or an EventRecorder - can then be
+// decoded after the event's Go type has changed shape, so long as an
+// upcaster is registered for each version between the serialized one and the
+// current one.
+//
+// Returns an error wrapping ErrUpcasterRegistered if an upcaster is already
+// registered for name and from, or ErrHubFrozen if the hub has been frozen.
+func (h *Hub[Tx]) RegisterUpcaster(name string, from int, fn Upcaster) error {
+	if h.frozen.Load() {
+		return ErrHubFrozen
+	}
+	key := upcasterKey{name: name, from: from}
+	if _, exists := h.upcasters[key]; exists {
+		return fmt.Errorf("%w for event %s version %d", ErrUpcasterRegistered, name, from)
+	}
+	h.upcasters[key] = fn
+	return nil
+}
+
+// Upcast() migrates data, the serialized form of the event named name at
+// version, to the version of the Go type currently registered for name.
+// Returns the migrated data, and its version.
+//
+// Returns ErrUnknownEvent if no type is registered for name,
+// ErrFutureEventVersion if version is newer than the registered type's, or
+// ErrNoUpcaster if an upcaster required for the migration is missing.
+func (h *Hub[Tx]) Upcast(name string, version int, data []byte) ([]byte, int, error) {
+	ty, ok := h.events.typeNamed(name)
+	if !ok {
+		return nil, 0, fmt.Errorf("%w %s", ErrUnknownEvent, name)
+	}
+	target := EventVersion(newEvent(ty))
+	if version > target {
+		return nil, 0, fmt.Errorf("%w: event %s version %d, type %s is version %d", ErrFutureEventVersion, name, version, ty, target)
+	}
+
+	for version < target {
+		fn, ok := h.upcasters[upcasterKey{name: name, from: version}]
+		if !ok {
+			return nil, 0, fmt.Errorf("%w for event %s version %d", ErrNoUpcaster, name, version)
+		}
+		var err error
+		if data, err = fn(data); err != nil {
+			return nil, 0, fmt.Errorf("upcasting event %s from version %d failed (%w)", name, version, err)
+		}
+		version++
+	}
+
+	return data, version, nil
+}
+
+// DecodeEvent() decodes data, the JSON serialization of the event named name
+// at version, into a new instance of the Go type registered for name,
+// upcasting it first if necessary.
+func (h *Hub[Tx]) DecodeEvent(name string, version int, data []byte) (Event, error) {
+	data, _, err := h.Upcast(name, version, data)
+	if err != nil {
+		return nil, err
+	}
+	ty, _ := h.events.typeNamed(name)
+	ptr := ty.Kind() == reflect.Pointer
+	val := reflect.New(ty)
+	if ptr {
+		val = reflect.New(ty.Elem())
+	}
+	if err := json.Unmarshal(data, val.Interface()); err != nil {
+		return nil, fmt.Errorf("decoding event %s failed (%w)", name, err)
+	}
+	if !ptr {
+		val = val.Elem()
+	}
+	return val.Interface().(Event), nil
+}
+
+// newEvent returns a new, zero, event of type ty; if ty is a pointer type,
+// the pointer refers to a newly allocated value.
+func newEvent(ty reflect.Type) Event {
+	if ty.Kind() == reflect.Pointer {
+		return reflect.New(ty.Elem()).Interface().(Event)
+	}
+	return reflect.Zero(ty).Interface().(Event)
+}
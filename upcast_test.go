@@ -0,0 +1,85 @@
+package operator
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type renamedEvent struct {
+	FullName string `json:"full_name"`
+}
+
+func (e *renamedEvent) EventName() string { return "renamed" }
+func (e *renamedEvent) EventVersion() int { return 3 }
+
+func TestDecodeEvent_Upcast(t *testing.T) {
+	hub := newTestHub()
+	assert.NoError(t, hub.RegisterEventType(&renamedEvent{}))
+	assert.NoError(t, hub.RegisterUpcaster("renamed", 1, func(data []byte) ([]byte, error) {
+		return bytes.ReplaceAll(data, []byte(`"name"`), []byte(`"fullname"`)), nil
+	}))
+	assert.NoError(t, hub.RegisterUpcaster("renamed", 2, func(data []byte) ([]byte, error) {
+		return bytes.ReplaceAll(data, []byte(`"fullname"`), []byte(`"full_name"`)), nil
+	}))
+
+	evt, err := hub.DecodeEvent("renamed", 1, []byte(`{"name":"Ada"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, &renamedEvent{FullName: "Ada"}, evt)
+
+	evt, err = hub.DecodeEvent("renamed", 3, []byte(`{"full_name":"Grace"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, &renamedEvent{FullName: "Grace"}, evt)
+}
+
+func TestDecodeEvent_Errors(t *testing.T) {
+	hub := newTestHub()
+	assert.NoError(t, hub.RegisterEventHandler(&renamedEvent{}, func(*renamedEvent) {}))
+	assert.NoError(t, hub.RegisterUpcaster("renamed", 2, func(data []byte) ([]byte, error) { return data, nil }))
+
+	_, err := hub.DecodeEvent("missing", 1, []byte(`{}`))
+	assert.ErrorIs(t, err, ErrUnknownEvent)
+
+	_, err = hub.DecodeEvent("renamed", 1, []byte(`{}`))
+	assert.ErrorIs(t, err, ErrNoUpcaster)
+
+	_, err = hub.DecodeEvent("renamed", 4, []byte(`{}`))
+	assert.ErrorIs(t, err, ErrFutureEventVersion)
+}
+
+func TestRegisterUpcaster_Duplicate(t *testing.T) {
+	hub := newTestHub()
+	upcast := func(data []byte) ([]byte, error) { return data, nil }
+	assert.NoError(t, hub.RegisterUpcaster("renamed", 1, upcast))
+	assert.ErrorIs(t, hub.RegisterUpcaster("renamed", 1, upcast), ErrUpcasterRegistered)
+
+	hub.Freeze()
+	assert.ErrorIs(t, hub.RegisterUpcaster("renamed", 2, upcast), ErrHubFrozen)
+}
+
+func TestRegisterEventType_SurvivesHandlerRemoval(t *testing.T) {
+	hub := newTestHub()
+	assert.NoError(t, hub.RegisterEventType(&renamedEvent{}))
+	reg, err := hub.AttachEventHandler(&renamedEvent{}, func(*renamedEvent) {})
+	assert.NoError(t, err)
+	assert.NoError(t, reg.Remove())
+
+	_, ok := hub.EventType("renamed")
+	assert.True(t, ok)
+	evt, err := hub.DecodeEvent("renamed", 3, []byte(`{"full_name":"Ada"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, &renamedEvent{FullName: "Ada"}, evt)
+
+	// types known only through their handlers are forgotten with them
+	reg, err = hub.AttachEventHandler(&testEvent{}, func(*testEvent) {})
+	assert.NoError(t, err)
+	assert.NoError(t, reg.Remove())
+	_, ok = hub.EventType((&testEvent{}).EventName())
+	assert.False(t, ok)
+}
+
+func TestEventVersion(t *testing.T) {
+	assert.Equal(t, 1, EventVersion(&testEvent{}))
+	assert.Equal(t, 3, EventVersion(&renamedEvent{}))
+}
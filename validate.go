@@ -0,0 +1,187 @@
+package operator
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+)
+
+// Validate() checks the hub's configuration, returning an error joining
+// every problem found (see errors.Join), or nil if there are none. It checks
+// that:
+//
+//   - the hub has a transaction provider, and its options are usable
+//   - no middleware is nil
+//   - no two handlers for an event share a name, whether by registering the
+//     same function twice or by HandlerName(), unless the hub's
+//     DuplicateHandlerPolicy is AllowDuplicateHandlers
+//   - the names referred to by After() and Before() are registered for the
+//     same event, and the ordering constraints have no cycles
+//   - handlers registered by name accept events of the Go type registered
+//     under that name, if any
+//   - upcasters are registered for every version between the first upcaster
+//     registered for an event and the event's current version
+//
+// Handler signatures are checked when handlers are registered, so are valid
+// by construction. Call Validate() once the hub is configured - in main(),
+// before serving requests, and in tests - to report problems at startup
+// rather than when an operation fails.
+func (h *Hub[Tx]) Validate() error {
+	var errs []error
+	errs = append(errs, h.validateOptions()...)
+	errs = append(errs, h.validateHandlers()...)
+	errs = append(errs, h.validateUpcasters()...)
+	return errors.Join(errs...)
+}
+
+func (h *Hub[Tx]) validateOptions() []error {
+	var errs []error
+	if h.beginTransaction == nil {
+		errs = append(errs, errors.New("hub has no transaction provider"))
+	}
+	o := &h.opts
+	if o.logger == nil {
+		errs = append(errs, errors.New("hub has no logger"))
+	}
+	if o.clock == nil {
+		errs = append(errs, errors.New("hub has no clock"))
+	}
+	if o.ids == nil {
+		errs = append(errs, errors.New("hub has no ID generator"))
+	}
+	if o.contextPolicy == nil {
+		errs = append(errs, errors.New("hub has no context policy"))
+	}
+	if o.workers < 1 {
+		errs = append(errs, fmt.Errorf("hub has %d workers, must have at least 1", o.workers))
+	}
+	if o.retryBackoff < 0 {
+		errs = append(errs, fmt.Errorf("background retry backoff %s is negative", o.retryBackoff))
+	}
+	if o.txWarnThreshold < 0 {
+		errs = append(errs, fmt.Errorf("transaction warning threshold %s is negative", o.txWarnThreshold))
+	}
+	if ac := o.admission; ac != nil {
+		if ac.MaxConcurrent < 1 {
+			errs = append(errs, fmt.Errorf("admission control allows %d concurrent operations, must allow at least 1", ac.MaxConcurrent))
+		}
+		if ac.MaxQueue < 0 {
+			errs = append(errs, fmt.Errorf("admission queue length %d is negative", ac.MaxQueue))
+		}
+		for c := range PriorityClass(numPriorityClasses) {
+			if limit, ok := ac.ClassLimits[c]; ok && limit < 1 {
+				errs = append(errs, fmt.Errorf("admission limit for %s priority is %d, must be at least 1", c, limit))
+			}
+		}
+	}
+	if o.trackStats && o.statsWindow < 1 {
+		errs = append(errs, fmt.Errorf("operation stats window %d must be at least 1", o.statsWindow))
+	}
+	if o.slowThreshold < 0 {
+		errs = append(errs, fmt.Errorf("slow operation threshold %s is negative", o.slowThreshold))
+	}
+	for i, mw := range o.middleware {
+		if mw == nil {
+			errs = append(errs, fmt.Errorf("middleware %d is nil", i))
+		}
+	}
+	return errs
+}
+
+func (h *Hub[Tx]) validateHandlers() []error {
+	table := h.events.load()
+
+	keys := make([]eventKey, 0, len(table.regs))
+	for key := range table.regs {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return table.describe(keys[i]) < table.describe(keys[j]) })
+
+	var errs []error
+	for _, key := range keys {
+		regs := table.regs[key]
+		event := table.describe(key)
+
+		names := make(map[string]bool, len(regs))
+		for i := range regs {
+			r := &regs[i]
+			if names[r.name] && h.opts.duplicateHandlers != AllowDuplicateHandlers {
+				j := slices.IndexFunc(regs[:i], func(o orderedHandler[Tx]) bool { return o.name == r.name && !(o.anonymous && r.anonymous) })
+				if j >= 0 {
+					errs = append(errs, duplicateHandlerError(event, &regs[j], r))
+				}
+			}
+			names[r.name] = true
+		}
+		for _, r := range regs {
+			for _, refs := range [2][]string{r.after, r.before} {
+				for _, n := range refs {
+					if !names[n] {
+						errs = append(errs, fmt.Errorf("handler %s for %s is ordered relative to unknown handler %s", r.name, event, n))
+					}
+				}
+			}
+			if key.ty == nil && r.param != nil {
+				if ty, ok := table.types[key.name]; ok && !ty.AssignableTo(r.param) && !reflect.TypeFor[*NamedEvent]().AssignableTo(r.param) {
+					errs = append(errs, fmt.Errorf("handler %s for %s does not accept events of type %s", r.name, event, ty))
+				}
+			}
+		}
+		if _, err := orderHandlers(regs); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", event, err))
+		}
+	}
+	return errs
+}
+
+// describe returns a description of the events identified by key, for use in
+// error messages.
+func (t *eventTable[Tx]) describe(key eventKey) string {
+	return describeEvent(key, t.names[key.ty])
+}
+
+// describeEvent returns a description of the events identified by key, whose
+// event name is name.
+func describeEvent(key eventKey, name string) string {
+	if key.ty != nil {
+		return fmt.Sprintf("event %s (%s)", name, key.ty)
+	}
+	return fmt.Sprintf("event %s", key.name)
+}
+
+func (h *Hub[Tx]) validateUpcasters() []error {
+	versions := map[string][]int{}
+	for key := range h.upcasters {
+		versions[key.name] = append(versions[key.name], key.from)
+	}
+	names := make([]string, 0, len(versions))
+	for name := range versions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var errs []error
+	for _, name := range names {
+		ty, ok := h.events.typeNamed(name)
+		if !ok {
+			errs = append(errs, fmt.Errorf("upcasters are registered for event %s, which has no registered type", name))
+			continue
+		}
+		target := EventVersion(newEvent(ty))
+		from := versions[name]
+		sort.Ints(from)
+		for v := from[0]; v < target; v++ {
+			if _, ok := h.upcasters[upcasterKey{name: name, from: v}]; !ok {
+				errs = append(errs, fmt.Errorf("%w for event %s version %d", ErrNoUpcaster, name, v))
+			}
+		}
+		for _, v := range from {
+			if v >= target {
+				errs = append(errs, fmt.Errorf("upcaster for event %s version %d is beyond its current version %d", name, v, target))
+			}
+		}
+	}
+	return errs
+}
@@ -0,0 +1,55 @@
+package operator
+
+import (
+	"log/slog"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	hub := newTestHub()
+	assert.NoError(t, hub.RegisterEventHandler(&testEvent{}, auditTestEvent, Before("second")))
+	assert.NoError(t, hub.RegisterEventHandler(&testEvent{}, func(evt *testEvent) {}, HandlerName("second"), After("github.com/jaz303/operator.auditTestEvent")))
+	assert.NoError(t, hub.RegisterNamedEventHandler("renamed", func(evt *NamedEvent) {}))
+	assert.NoError(t, hub.RegisterEventType(&renamedEvent{}))
+	assert.NoError(t, hub.RegisterUpcaster("renamed", 1, func(data []byte) ([]byte, error) { return data, nil }))
+	assert.NoError(t, hub.RegisterUpcaster("renamed", 2, func(data []byte) ([]byte, error) { return data, nil }))
+	assert.NoError(t, hub.Validate())
+}
+
+func TestValidate_Errors(t *testing.T) {
+	hub := newTestHub(WithMiddleware(nil), WithTxWarnThreshold(-time.Second), WithLogger(slog.New(slog.DiscardHandler)))
+	hub.RegisterEventHandler(&testEvent{}, auditTestEvent)
+	hub.RegisterEventHandler(&testEvent{}, auditTestEvent)
+	hub.RegisterEventHandler(&testEvent{}, func(evt *testEvent) {}, HandlerName("late"), After("missing"))
+	hub.RegisterNamedEventHandler("testEvent", func(evt *followUpEvent) {})
+	hub.RegisterEventType(&renamedEvent{})
+	hub.RegisterUpcaster("renamed", 1, func(data []byte) ([]byte, error) { return data, nil })
+	hub.RegisterUpcaster("renamed", 3, func(data []byte) ([]byte, error) { return data, nil })
+	hub.RegisterUpcaster("unknown", 1, func(data []byte) ([]byte, error) { return data, nil })
+
+	err := hub.Validate()
+	assert.ErrorIs(t, err, ErrNoUpcaster)
+	assert.ErrorIs(t, err, ErrDuplicateHandler)
+	_, file, _, _ := runtime.Caller(0)
+	assert.Equal(t, "transaction warning threshold -1s is negative\n"+
+		"middleware 0 is nil\n"+
+		"handler github.com/jaz303/operator.TestValidate_Errors.func2 for event testEvent does not accept events of type *operator.testEvent\n"+
+		"duplicate event handler: handler github.com/jaz303/operator.auditTestEvent for event testEvent (*operator.testEvent) registered at validate_test.go:27 was already registered at validate_test.go:26\n"+
+		"handler late for event testEvent (*operator.testEvent) is ordered relative to unknown handler missing\n"+
+		"no upcaster registered for event renamed version 2\n"+
+		"upcaster for event renamed version 3 is beyond its current version 3\n"+
+		"upcasters are registered for event unknown, which has no registered type", strings.ReplaceAll(err.Error(), file, "validate_test.go"))
+}
+
+func TestValidate_Options(t *testing.T) {
+	hub := NewHub[*TxTest](nil, WithWorkers(0), WithClock(nil), WithBackgroundRetry(2, -time.Second))
+	assert.Equal(t, "hub has no transaction provider\n"+
+		"hub has no clock\n"+
+		"hub has 0 workers, must have at least 1\n"+
+		"background retry backoff -1s is negative", hub.Validate().Error())
+}
@@ -0,0 +1,69 @@
+package webhook
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/jaz303/operator/deadletter"
+)
+
+// Option configures a Dispatcher.
+type Option func(d *Dispatcher)
+
+// WithStore sets the store in which delivery attempts are recorded. The
+// default is a MemoryStore retaining the last 100 attempts per endpoint.
+func WithStore(s Store) Option {
+	return func(d *Dispatcher) { d.store = s }
+}
+
+// WithHTTPClient sets the client used to make deliveries. The default is
+// http.DefaultClient.
+func WithHTTPClient(c *http.Client) Option {
+	return func(d *Dispatcher) { d.client = c }
+}
+
+// WithSource sets the source attribute of delivered CloudEvents. The default
+// is "operator".
+func WithSource(source string) Option {
+	return func(d *Dispatcher) { d.source = source }
+}
+
+// WithWorkers sets the number of deliveries made concurrently. The default is
+// 4.
+func WithWorkers(n int) Option {
+	return func(d *Dispatcher) { d.workers = max(n, 1) }
+}
+
+// WithQueueSize sets the number of deliveries that may await a worker.
+// Deliveries enqueued while the queue is full are dropped, reported to the
+// error handler with ErrQueueFull and sent to the dead letter sink, if any.
+// The default is 1024.
+func WithQueueSize(n int) Option {
+	return func(d *Dispatcher) { d.queueSize = n }
+}
+
+// WithRetry sets the maximum number of attempts made for each delivery, and
+// the backoff between attempts, which starts at initial and doubles after
+// each failed attempt up to max. The default is 5 attempts, backing off from
+// 1 second up to 1 minute.
+func WithRetry(attempts int, initial, max time.Duration) Option {
+	return func(d *Dispatcher) {
+		d.maxAttempts = attempts
+		d.backoff = initial
+		d.maxBackoff = max
+	}
+}
+
+// WithErrorHandler sets a function to be called when a delivery is abandoned,
+// either because its attempts are exhausted or because it could not be
+// queued. The default logs the error with slog.Default().
+func WithErrorHandler(fn func(dl *Delivery, err error)) Option {
+	return func(d *Dispatcher) { d.onError = fn }
+}
+
+// WithDeadLetterSink sets a sink to receive deliveries whose attempts are
+// exhausted, and those that could not be queued. Letters have the source DeadLetterSource and can be requeued
+// with Dispatcher.Requeue().
+func WithDeadLetterSink(sink deadletter.Sink) Option {
+	return func(d *Dispatcher) { d.deadLetters = sink }
+}
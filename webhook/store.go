@@ -0,0 +1,60 @@
+package webhook
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Delivery records a single attempt to deliver an event to an endpoint.
+type Delivery struct {
+	// ID of the delivered event; shared by every attempt, and by deliveries
+	// of the same event to other endpoints
+	ID         string        `json:"id"`
+	EndpointID string        `json:"endpoint_id"`
+	Event      string        `json:"event"`
+	Attempt    int           `json:"attempt"`
+	Time       time.Time     `json:"time"`
+	Duration   time.Duration `json:"duration"`
+	StatusCode int           `json:"status_code,omitempty"`
+	Succeeded  bool          `json:"succeeded"`
+	Error      string        `json:"error,omitempty"`
+}
+
+// Store records delivery attempts, providing a delivery log for each
+// endpoint.
+type Store interface {
+	RecordDelivery(ctx context.Context, dl *Delivery) error
+	Deliveries(ctx context.Context, endpointID string) ([]Delivery, error)
+}
+
+// MemoryStore is a Store retaining the most recent attempts for each endpoint
+// in memory.
+type MemoryStore struct {
+	mu    sync.Mutex
+	limit int
+	log   map[string][]Delivery
+}
+
+// NewMemoryStore returns a store retaining up to limit attempts per endpoint.
+func NewMemoryStore(limit int) *MemoryStore {
+	return &MemoryStore{limit: limit, log: map[string][]Delivery{}}
+}
+
+func (s *MemoryStore) RecordDelivery(ctx context.Context, dl *Delivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	log := append(s.log[dl.EndpointID], *dl)
+	if len(log) > s.limit {
+		log = log[len(log)-s.limit:]
+	}
+	s.log[dl.EndpointID] = log
+	return nil
+}
+
+// Deliveries returns the retained attempts for the endpoint, oldest first.
+func (s *MemoryStore) Deliveries(ctx context.Context, endpointID string) ([]Delivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Delivery(nil), s.log[endpointID]...), nil
+}
@@ -0,0 +1,373 @@
+// Package webhook delivers hub events to HTTP endpoints registered by
+// applications, once the operation that emitted them has committed.
+//
+// Each endpoint subscribes to one or more event names and has a secret with
+// which deliveries are signed. Deliveries are made in the background, retried
+// with exponential backoff, and every attempt is recorded in a Store:
+//
+//	d := webhook.New(eventcodec.New(hub), webhook.WithStore(store))
+//	defer d.Close(context.Background())
+//	d.Register(webhook.Endpoint{
+//		ID:     "acme",
+//		URL:    "https://acme.example.com/hooks",
+//		Secret: secret,
+//		Events: []string{"user.created"},
+//	})
+//	webhook.Dispatch(hub, d, &UserCreated{}, &UserDeleted{})
+//
+// Request bodies are CloudEvents in structured JSON mode. Receivers should
+// check the signature with Verify().
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/jaz303/operator"
+	"github.com/jaz303/operator/deadletter"
+	"github.com/jaz303/operator/eventcodec"
+)
+
+var (
+	ErrQueueFull = errors.New("webhook queue is full")
+	ErrClosed    = errors.New("webhook dispatcher is closed")
+
+	ErrNotRequeueable = errors.New("dead letter cannot be requeued")
+)
+
+const (
+	HeaderID        = "Webhook-Id"
+	HeaderEvent     = "Webhook-Event"
+	HeaderSignature = "Webhook-Signature"
+)
+
+// Endpoint is a destination for webhook deliveries.
+type Endpoint struct {
+	// Unique identifier of the endpoint
+	ID string
+
+	URL string
+
+	// Secret with which deliveries to this endpoint are signed
+	Secret []byte
+
+	// Names of events delivered to this endpoint; if empty, every event
+	// dispatched is delivered
+	Events []string
+}
+
+func (e *Endpoint) subscribes(name string) bool {
+	return len(e.Events) == 0 || slices.Contains(e.Events, name)
+}
+
+// Dispatcher delivers events to registered endpoints.
+type Dispatcher struct {
+	serializer  *eventcodec.Serializer
+	client      *http.Client
+	store       Store
+	source      string
+	workers     int
+	queueSize   int
+	maxAttempts int
+	backoff     time.Duration
+	maxBackoff  time.Duration
+	onError     func(*Delivery, error)
+	deadLetters deadletter.Sink
+
+	mu        sync.RWMutex
+	endpoints map[string]Endpoint
+	closed    bool
+	queue     chan *job
+	wg        sync.WaitGroup
+	stop      chan struct{}
+	stopOnce  sync.Once
+}
+
+type job struct {
+	endpoint Endpoint
+	event    string
+	id       string
+	body     []byte
+}
+
+// New returns a dispatcher that serializes events with s. Call Close() to
+// stop the dispatcher once it is no longer needed.
+func New(s *eventcodec.Serializer, opts ...Option) *Dispatcher {
+	d := &Dispatcher{
+		serializer:  s,
+		client:      http.DefaultClient,
+		store:       NewMemoryStore(100),
+		source:      "operator",
+		workers:     4,
+		queueSize:   1024,
+		maxAttempts: 5,
+		backoff:     time.Second,
+		maxBackoff:  time.Minute,
+		onError: func(dl *Delivery, err error) {
+			slog.Error("webhook delivery failed", "endpoint", dl.EndpointID, "event", dl.Event, "id", dl.ID, "error", err)
+		},
+		endpoints: map[string]Endpoint{},
+		stop:      make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	d.queue = make(chan *job, d.queueSize)
+	for range d.workers {
+		d.wg.Add(1)
+		go d.run()
+	}
+	return d
+}
+
+// Register adds ep to the dispatcher, replacing any endpoint with the same ID.
+func (d *Dispatcher) Register(ep Endpoint) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.endpoints[ep.ID] = ep
+}
+
+// Unregister removes the endpoint with the given ID. Deliveries already
+// queued for the endpoint are still attempted.
+func (d *Dispatcher) Unregister(id string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.endpoints, id)
+}
+
+// Dispatch registers an event handler on hub for each of events' types, which
+// delivers matching events to subscribed endpoints once the emitting
+// operation has committed. Events are serialized when emitted; if
+// serialization fails, the operation fails. Each event's time is that of the
+// emitting operation; see operator.OpContext.Now.
+//
+// d is closed by hub.Shutdown once the hub's operations have drained.
+func Dispatch[Tx operator.Transaction](hub *operator.Hub[Tx], d *Dispatcher, events ...operator.Event) error {
+	for _, evt := range events {
+		err := hub.RegisterEventHandler(evt, func(ctx *operator.OpContext[Tx], evt operator.Event) error {
+			id := newID()
+			ce, err := d.serializer.EncodeCloudEvent(evt, d.source, id)
+			if err != nil {
+				return err
+			}
+			now := ctx.Now().UTC()
+			ce.Time = &now
+			body, err := json.Marshal(ce)
+			if err != nil {
+				return err
+			}
+			return ctx.AfterFunc(func(*operator.OpContext[Tx]) { d.Enqueue(evt.EventName(), id, body) })
+		})
+		if err != nil {
+			return err
+		}
+	}
+	hub.OnShutdown(d.Close)
+	return nil
+}
+
+// Enqueue queues body, the serialized event named event with the given ID,
+// for delivery to each subscribed endpoint, without blocking. Deliveries that
+// can not be queued, because the queue is full or the dispatcher is closed,
+// are reported to the error handler and sent to the dead letter sink, if
+// any, with no attempts made.
+func (d *Dispatcher) Enqueue(event, id string, body []byte) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	for _, ep := range d.endpoints {
+		if !ep.subscribes(event) {
+			continue
+		}
+		j := &job{endpoint: ep, event: event, id: id, body: body}
+		if d.closed {
+			d.onError(j.delivery(0), ErrClosed)
+			d.deadLetter(j, 0, ErrClosed)
+			continue
+		}
+		select {
+		case d.queue <- j:
+		default:
+			d.onError(j.delivery(0), ErrQueueFull)
+			d.deadLetter(j, 0, ErrQueueFull)
+		}
+	}
+}
+
+// Close stops accepting events and waits for queued deliveries, including
+// their retries, to finish. If ctx is done first, pending retries are
+// abandoned and ctx's error is returned.
+func (d *Dispatcher) Close(ctx context.Context) error {
+	d.mu.Lock()
+	if !d.closed {
+		d.closed = true
+		close(d.queue)
+	}
+	d.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		d.stopOnce.Do(func() { close(d.stop) })
+		<-done
+		return ctx.Err()
+	}
+}
+
+func (d *Dispatcher) run() {
+	defer d.wg.Done()
+	for j := range d.queue {
+		d.deliver(j)
+	}
+}
+
+func (d *Dispatcher) deliver(j *job) {
+	wait := d.backoff
+	for attempt := 1; ; attempt++ {
+		dl := j.delivery(attempt)
+		err := d.attempt(j, dl)
+		if err == nil {
+			return
+		} else if attempt >= d.maxAttempts {
+			d.onError(dl, err)
+			d.deadLetter(j, attempt, err)
+			return
+		}
+
+		select {
+		case <-time.After(wait):
+		case <-d.stop:
+			err = fmt.Errorf("%w: %w", ErrClosed, err)
+			d.onError(dl, err)
+			d.deadLetter(j, attempt, err)
+			return
+		}
+		wait = min(wait*2, d.maxBackoff)
+	}
+}
+
+// attempt makes a single delivery attempt, recording it in the store.
+func (d *Dispatcher) attempt(j *job, dl *Delivery) (err error) {
+	start := time.Now()
+	defer func() {
+		dl.Duration = time.Since(start)
+		dl.Succeeded = err == nil
+		if err != nil {
+			dl.Error = err.Error()
+		}
+		if serr := d.store.RecordDelivery(context.Background(), dl); serr != nil {
+			slog.Error("recording webhook delivery failed", "endpoint", dl.EndpointID, "id", dl.ID, "error", serr)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, j.endpoint.URL, bytes.NewReader(j.body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", eventcodec.CloudEventsContentType)
+	req.Header.Set(HeaderID, j.id)
+	req.Header.Set(HeaderEvent, j.event)
+	req.Header.Set(HeaderSignature, Sign(j.endpoint.Secret, start, j.body))
+
+	res, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	dl.StatusCode = res.StatusCode
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("endpoint responded %s", res.Status)
+	}
+	return nil
+}
+
+// DeadLetterSource is the source of dead letters produced by a dispatcher.
+const DeadLetterSource = "webhook"
+
+// Dead letter metadata keys
+const (
+	metaEndpoint = "endpoint"
+	metaEventID  = "event_id"
+)
+
+func (d *Dispatcher) deadLetter(j *job, attempts int, err error) {
+	if d.deadLetters == nil {
+		return
+	}
+	l := &deadletter.Letter{
+		ID:          j.id + "/" + j.endpoint.ID,
+		Source:      DeadLetterSource,
+		Name:        j.event,
+		ContentType: eventcodec.CloudEventsContentType,
+		Data:        j.body,
+		Metadata:    map[string]string{metaEndpoint: j.endpoint.ID, metaEventID: j.id},
+		Error:       err.Error(),
+		Attempts:    attempts,
+	}
+	if perr := d.deadLetters.Put(context.Background(), l); perr != nil {
+		slog.Error("storing webhook dead letter failed", "endpoint", j.endpoint.ID, "id", j.id, "error", perr)
+	}
+}
+
+// Requeue queues the delivery described by l, a dead letter produced by this
+// dispatcher, for another round of attempts. The letter's endpoint must still
+// be registered.
+//
+// Use deadletter.Requeue() to requeue a letter and remove it from its store.
+func (d *Dispatcher) Requeue(ctx context.Context, l *deadletter.Letter) error {
+	if l.Source != DeadLetterSource {
+		return fmt.Errorf("%w: source is %q", ErrNotRequeueable, l.Source)
+	}
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	ep, ok := d.endpoints[l.Metadata[metaEndpoint]]
+	if !ok {
+		return fmt.Errorf("%w: endpoint %q is not registered", ErrNotRequeueable, l.Metadata[metaEndpoint])
+	} else if d.closed {
+		return ErrClosed
+	}
+	select {
+	case d.queue <- &job{endpoint: ep, event: l.Name, id: l.Metadata[metaEventID], body: l.Data}:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+func (j *job) delivery(attempt int) *Delivery {
+	return &Delivery{
+		ID:         j.id,
+		EndpointID: j.endpoint.ID,
+		Event:      j.event,
+		Attempt:    attempt,
+		Time:       time.Now(),
+	}
+}
+
+func newID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
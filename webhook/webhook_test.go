@@ -0,0 +1,210 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jaz303/operator"
+	"github.com/jaz303/operator/deadletter"
+	"github.com/jaz303/operator/eventcodec"
+	"github.com/stretchr/testify/assert"
+)
+
+type nopTx struct{}
+
+func (nopTx) Commit(context.Context) error   { return nil }
+func (nopTx) Rollback(context.Context) error { return nil }
+
+type userCreated struct {
+	ID int `json:"id"`
+}
+
+func (e *userCreated) EventName() string { return "user.created" }
+
+type userDeleted struct{}
+
+func (e *userDeleted) EventName() string { return "user.deleted" }
+
+func emit(t *testing.T, hub *operator.Hub[nopTx], evt operator.Event) {
+	_, err := operator.Invoke(context.Background(), hub, func(ctx *operator.OpContext[nopTx], in *struct{}) (*struct{}, error) {
+		return in, ctx.Emit(evt)
+	}, &struct{}{})
+	assert.NoError(t, err)
+}
+
+func TestDispatch(t *testing.T) {
+	secret := []byte("s3cret")
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		assert.NoError(t, Verify(secret, r.Header.Get(HeaderSignature), body, time.Minute))
+		assert.Equal(t, "user.created", r.Header.Get(HeaderEvent))
+		assert.NotEmpty(t, r.Header.Get(HeaderID))
+
+		// fail the first attempt
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	hub := operator.NewHub(func(context.Context) (nopTx, error) { return nopTx{}, nil })
+	store := NewMemoryStore(10)
+	d := New(eventcodec.New(hub), WithStore(store), WithRetry(3, time.Millisecond, 5*time.Millisecond))
+	d.Register(Endpoint{ID: "a", URL: srv.URL, Secret: secret, Events: []string{"user.created"}})
+	assert.NoError(t, Dispatch(hub, d, &userCreated{}, &userDeleted{}))
+
+	emit(t, hub, &userCreated{ID: 1})
+	emit(t, hub, &userDeleted{})
+	assert.NoError(t, d.Close(context.Background()))
+
+	assert.Equal(t, int32(2), calls.Load())
+	log, err := store.Deliveries(context.Background(), "a")
+	assert.NoError(t, err)
+	assert.Len(t, log, 2)
+	assert.Equal(t, 1, log[0].Attempt)
+	assert.False(t, log[0].Succeeded)
+	assert.Equal(t, http.StatusServiceUnavailable, log[0].StatusCode)
+	assert.Equal(t, 2, log[1].Attempt)
+	assert.True(t, log[1].Succeeded)
+	assert.Equal(t, log[0].ID, log[1].ID)
+}
+
+func TestDispatch_AttemptsExhausted(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	var mu sync.Mutex
+	var failed []*Delivery
+
+	hub := operator.NewHub(func(context.Context) (nopTx, error) { return nopTx{}, nil })
+	d := New(eventcodec.New(hub),
+		WithRetry(3, time.Millisecond, time.Millisecond),
+		WithErrorHandler(func(dl *Delivery, err error) {
+			mu.Lock()
+			failed = append(failed, dl)
+			mu.Unlock()
+		}))
+	d.Register(Endpoint{ID: "a", URL: srv.URL})
+	assert.NoError(t, Dispatch(hub, d, &userCreated{}))
+
+	emit(t, hub, &userCreated{ID: 1})
+	assert.NoError(t, d.Close(context.Background()))
+
+	assert.Len(t, failed, 1)
+	assert.Equal(t, 3, failed[0].Attempt)
+	assert.Equal(t, "a", failed[0].EndpointID)
+}
+
+func TestVerify(t *testing.T) {
+	secret := []byte("s3cret")
+	body := []byte(`{}`)
+
+	sig := Sign(secret, time.Now(), body)
+	assert.NoError(t, Verify(secret, sig, body, time.Minute))
+	assert.ErrorIs(t, Verify([]byte("other"), sig, body, time.Minute), ErrInvalidSignature)
+	assert.ErrorIs(t, Verify(secret, sig, []byte(`{"x":1}`), time.Minute), ErrInvalidSignature)
+	assert.ErrorIs(t, Verify(secret, "garbage", body, time.Minute), ErrInvalidSignature)
+
+	old := Sign(secret, time.Now().Add(-time.Hour), body)
+	assert.ErrorIs(t, Verify(secret, old, body, time.Minute), ErrInvalidSignature)
+	assert.NoError(t, Verify(secret, old, body, 0))
+}
+
+func TestDispatch_DeadLetter(t *testing.T) {
+	var healthy atomic.Bool
+	var delivered atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		delivered.Add(1)
+	}))
+	defer srv.Close()
+
+	letters := deadletter.NewMemoryStore()
+	hub := operator.NewHub(func(context.Context) (nopTx, error) { return nopTx{}, nil })
+	d := New(eventcodec.New(hub),
+		WithRetry(2, time.Millisecond, time.Millisecond),
+		WithDeadLetterSink(letters),
+		WithErrorHandler(func(*Delivery, error) {}))
+	d.Register(Endpoint{ID: "a", URL: srv.URL})
+	assert.NoError(t, Dispatch(hub, d, &userCreated{}))
+
+	emit(t, hub, &userCreated{ID: 1})
+
+	var list []deadletter.Letter
+	assert.Eventually(t, func() bool {
+		list, _ = letters.List(context.Background(), deadletter.Filter{Source: DeadLetterSource})
+		return len(list) == 1
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, "user.created", list[0].Name)
+	assert.Equal(t, 2, list[0].Attempts)
+
+	healthy.Store(true)
+	assert.NoError(t, deadletter.Requeue(context.Background(), letters, list[0].ID, d.Requeue))
+	assert.NoError(t, d.Close(context.Background()))
+	assert.Equal(t, int32(1), delivered.Load())
+
+	list, _ = letters.List(context.Background(), deadletter.Filter{})
+	assert.Empty(t, list)
+}
+
+func TestEnqueue_DeadLettersUnqueued(t *testing.T) {
+	received, release := make(chan struct{}, 1), make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- struct{}{}
+		<-release
+	}))
+	defer srv.Close()
+
+	var mu sync.Mutex
+	var errs []error
+	letters := deadletter.NewMemoryStore()
+	hub := operator.NewHub(func(context.Context) (nopTx, error) { return nopTx{}, nil })
+	d := New(eventcodec.New(hub),
+		WithWorkers(1),
+		WithQueueSize(1),
+		WithDeadLetterSink(letters),
+		WithErrorHandler(func(dl *Delivery, err error) {
+			mu.Lock()
+			errs = append(errs, err)
+			mu.Unlock()
+		}))
+	d.Register(Endpoint{ID: "a", URL: srv.URL})
+
+	d.Enqueue("user.created", "1", []byte(`{}`))
+	<-received
+	d.Enqueue("user.created", "2", []byte(`{}`))
+	d.Enqueue("user.created", "3", []byte(`{}`))
+	close(release)
+	assert.NoError(t, d.Close(context.Background()))
+	d.Enqueue("user.created", "4", []byte(`{}`))
+
+	mu.Lock()
+	assert.Equal(t, []error{ErrQueueFull, ErrClosed}, errs)
+	mu.Unlock()
+
+	list, err := letters.List(context.Background(), deadletter.Filter{Source: DeadLetterSource})
+	assert.NoError(t, err)
+	if assert.Len(t, list, 2) {
+		ids := map[string]string{}
+		for _, l := range list {
+			assert.Equal(t, 0, l.Attempts)
+			ids[l.ID] = l.Error
+		}
+		assert.Equal(t, map[string]string{
+			"3/a": ErrQueueFull.Error(),
+			"4/a": ErrClosed.Error(),
+		}, ids)
+	}
+}
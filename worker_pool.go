@@ -0,0 +1,117 @@
+package operator
+
+import (
+	"context"
+	"sync"
+)
+
+// workerPool executes background work on behalf of a hub using a fixed
+// number of goroutines, started on first use.
+type workerPool struct {
+	size  int
+	once  sync.Once
+	tasks chan func()
+
+	// tasks submitted by workers while tasks was full
+	mu       sync.Mutex
+	overflow []func()
+}
+
+// workerKey marks the contexts of work running on a worker.
+type workerKey struct{}
+
+func newWorkerPool(size int) *workerPool {
+	if size < 1 {
+		size = 1
+	}
+	return &workerPool{
+		size:  size,
+		tasks: make(chan func(), size*64),
+	}
+}
+
+// submit queues fn for execution, blocking if the queue is full - unless
+// ctx is that of work running on a worker, such as an operation submitting
+// its follow-ups, as every worker could then be blocked waiting for the
+// others. fn is instead held in an unbounded overflow, which workers empty
+// before taking further tasks from the queue.
+func (p *workerPool) submit(ctx context.Context, fn func()) {
+	p.once.Do(p.start)
+	if ctx.Value(workerKey{}) == nil {
+		p.tasks <- fn
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.overflow) == 0 {
+		select {
+		case p.tasks <- fn:
+			return
+		default:
+		}
+	}
+	// the queue was full, so a worker will check the overflow once it has
+	// run one of the queued tasks
+	p.overflow = append(p.overflow, fn)
+}
+
+func (p *workerPool) start() {
+	for range p.size {
+		go func() {
+			for fn := range p.tasks {
+				fn()
+				for fn := p.popOverflow(); fn != nil; fn = p.popOverflow() {
+					fn()
+				}
+			}
+		}()
+	}
+}
+
+func (p *workerPool) popOverflow() func() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.overflow) == 0 {
+		return nil
+	}
+	fn := p.overflow[0]
+	p.overflow[0] = nil
+	p.overflow = p.overflow[1:]
+	return fn
+}
+
+func (p *workerPool) queued() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.tasks) + len(p.overflow)
+}
+
+// BackgroundStats describes the hub's background work; see
+// Hub.BackgroundStats().
+type BackgroundStats struct {
+	// Number of goroutines executing background work
+	Workers int `json:"workers"`
+
+	// Number of tasks - follow-up operations, asynchronous event dispatch
+	// etc. - waiting for a worker, and the number that may wait before
+	// further submissions block. Tasks submitted by background work itself
+	// never block, so Queued may exceed Capacity.
+	Queued   int `json:"queued"`
+	Capacity int `json:"capacity"`
+
+	// Number of operations and background tasks in progress, including
+	// those queued
+	Active int64 `json:"active"`
+}
+
+// BackgroundStats() returns a snapshot of the hub's background work, for
+// diagnosing a backlog of follow-ups and asynchronous event handlers.
+func (h *Hub[Tx]) BackgroundStats() BackgroundStats {
+	return BackgroundStats{
+		Workers:  h.workers.size,
+		Queued:   h.workers.queued(),
+		Capacity: cap(h.workers.tasks),
+		Active:   h.life.active.Load(),
+	}
+}
@@ -0,0 +1,41 @@
+package operator
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkerPool_Saturated(t *testing.T) {
+	hub := newTestHub(WithWorkers(1))
+
+	var leaves atomic.Int32
+	leaf := func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) {
+		leaves.Add(1)
+		return in, nil
+	}
+	// run on the only worker, submitting more follow-ups than the queue holds
+	fanOut := func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) {
+		for range 200 {
+			if err := InvokeAfterCommit(ctx, leaf, in); err != nil {
+				return nil, err
+			}
+		}
+		return in, nil
+	}
+	root := func(ctx *OpContext[*TxTest], in *struct{}) (*struct{}, error) {
+		return in, InvokeAfterCommit(ctx, fanOut, in)
+	}
+
+	_, err := Invoke(context.Background(), hub, root, &struct{}{})
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	assert.NoError(t, hub.Shutdown(ctx))
+	assert.Equal(t, int32(200), leaves.Load())
+	assert.Zero(t, hub.BackgroundStats().Queued)
+}